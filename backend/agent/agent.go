@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -28,6 +29,7 @@ import (
 
 	"isc.org/stork"
 	agentapi "isc.org/stork/api"
+	storkutil "isc.org/stork/util"
 )
 
 // Global Stork Agent state.
@@ -37,6 +39,7 @@ type StorkAgent struct {
 	HTTPClient     *HTTPClient // to communicate with Kea Control Agent and named statistics-channel
 	server         *grpc.Server
 	logTailer      *logTailer
+	keaLogEvents   *keaLogEventCollector
 	keaInterceptor *keaInterceptor
 	shutdownOnce   sync.Once
 	hookManager    *HookManager
@@ -53,6 +56,7 @@ func NewStorkAgent(settings *cli.Context, appMonitor AppMonitor, httpClient *HTT
 		AppMonitor:     appMonitor,
 		HTTPClient:     httpClient,
 		logTailer:      logTailer,
+		keaLogEvents:   newKeaLogEventCollector(),
 		keaInterceptor: newKeaInterceptor(),
 		hookManager:    hookManager,
 	}
@@ -390,6 +394,101 @@ func (sa *StorkAgent) TailTextFile(ctx context.Context, in *agentapi.TailTextFil
 	return response, nil
 }
 
+// Streams the lines of the specified file, typically a log file. In the
+// follow mode, the lines appended to the file are streamed until the
+// server cancels the call or the agent is shut down.
+func (sa *StorkAgent) FollowTextFile(in *agentapi.FollowTextFileReq, stream agentapi.Agent_FollowTextFileServer) error {
+	err := sa.logTailer.follow(stream.Context(), in, stream.Send)
+	if err != nil {
+		return stream.Send(&agentapi.FollowTextFileRsp{
+			Status: &agentapi.Status{
+				Code:    agentapi.Status_ERROR,
+				Message: fmt.Sprintf("%s", err),
+			},
+		})
+	}
+	return nil
+}
+
+// Sends a command to Kea CA and returns the response body.
+func (sa *StorkAgent) sendCommandToKeaCA(ctrl *AccessPoint, request string) ([]byte, error) {
+	caURL := storkutil.HostWithPortURL(ctrl.Address, ctrl.Port, ctrl.UseSecureProtocol)
+	httpRsp, err := sa.HTTPClient.Call(caURL, bytes.NewBuffer([]byte(request)))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to send command to Kea: %s", caURL)
+	}
+	body, err := io.ReadAll(httpRsp.Body)
+	httpRsp.Body.Close()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read Kea response body received from %s", caURL)
+	}
+	return body, nil
+}
+
+// Returns the lease statistics computed from the memfile lease files of
+// all monitored Kea apps. The lease file locations are fetched from the
+// Kea DHCP servers' configurations.
+func (sa *StorkAgent) GetLeaseFileStats(ctx context.Context, in *agentapi.GetLeaseFileStatsReq) (*agentapi.GetLeaseFileStatsRsp, error) {
+	response := &agentapi.GetLeaseFileStatsRsp{
+		Status: &agentapi.Status{
+			Code: agentapi.Status_OK, // all ok
+		},
+	}
+	for _, bound := range leaseAgeBounds {
+		response.AgeBounds = append(response.AgeBounds, int64(bound.Seconds()))
+	}
+
+	now := time.Now()
+	var lastErr error
+	for _, app := range sa.AppMonitor.GetApps() {
+		if app.GetBaseApp().Type != AppTypeKea {
+			continue
+		}
+		files, err := detectKeaLeaseFiles(sa, app)
+		if err != nil {
+			log.WithError(err).Warn("Failed to detect Kea lease files")
+			lastErr = err
+			continue
+		}
+		paths := make([]string, 0, len(files))
+		for path := range files {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			leaseFile := &agentapi.LeaseFileStats{
+				Path:   path,
+				Family: int64(files[path]),
+			}
+			stats, err := collectLeaseFileStats(path, files[path], now)
+			if err != nil {
+				leaseFile.Error = err.Error()
+			} else {
+				leaseFile.Subnets = convertLeaseFileStatsToProto(stats)
+			}
+			response.LeaseFiles = append(response.LeaseFiles, leaseFile)
+		}
+	}
+	if len(response.LeaseFiles) == 0 && lastErr != nil {
+		response.Status.Code = agentapi.Status_ERROR
+		response.Status.Message = fmt.Sprintf("Failed to detect Kea lease files: %s", lastErr)
+	}
+	return response, nil
+}
+
+// Returns the notable messages found in the followed Kea log files. The
+// events acknowledged by the server are dropped.
+func (sa *StorkAgent) GetKeaLogEvents(ctx context.Context, in *agentapi.GetKeaLogEventsReq) (*agentapi.GetKeaLogEventsRsp, error) {
+	generation, events := sa.keaLogEvents.getEvents(in.Generation, in.LastSequence)
+	return &agentapi.GetKeaLogEventsRsp{
+		Status: &agentapi.Status{
+			Code: agentapi.Status_OK,
+		},
+		Generation: generation,
+		Events:     events,
+	}, nil
+}
+
 // Starts the gRPC and HTTP listeners.
 func (sa *StorkAgent) Serve() error {
 	// Install gRPC API handlers.
@@ -402,6 +501,9 @@ func (sa *StorkAgent) Serve() error {
 		return errors.Wrapf(err, "failed to listen on: %s", addr)
 	}
 
+	// Start following the Kea log files.
+	sa.keaLogEvents.start()
+
 	// Start serving gRPC
 	log.WithFields(log.Fields{
 		"address": lis.Addr(),
@@ -427,8 +529,13 @@ func (sa *StorkAgent) Shutdown(reload bool) {
 				Error("Closing Hook Manager failed")
 		}
 
+		// The graceful stop waits for the streams following the files.
+		sa.logTailer.stop()
+
 		if sa.server != nil {
 			sa.server.GracefulStop()
 		}
+
+		sa.keaLogEvents.stop()
 	})
 }
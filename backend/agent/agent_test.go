@@ -14,6 +14,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"github.com/urfave/cli/v2"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/security/advancedtls"
 	"gopkg.in/h2non/gock.v1"
 
@@ -71,6 +72,7 @@ func setupAgentTestWithHooks(calloutCarriers []hooks.CalloutCarrier) (*StorkAgen
 		AppMonitor:     &fam,
 		HTTPClient:     httpClient,
 		logTailer:      newLogTailer(),
+		keaLogEvents:   newKeaLogEventCollector(),
 		keaInterceptor: newKeaInterceptor(),
 		hookManager:    NewHookManager(),
 	}
@@ -622,6 +624,57 @@ func TestTailTextFile(t *testing.T) {
 	require.Equal(t, "in testing TailTextFile", rsp.Lines[2])
 }
 
+// Fake stream used to test the FollowTextFile call.
+type fakeFollowTextFileServer struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses []*agentapi.FollowTextFileRsp
+}
+
+// Returns the stream context.
+func (s *fakeFollowTextFileServer) Context() context.Context {
+	return s.ctx
+}
+
+// Records the sent response.
+func (s *fakeFollowTextFileServer) Send(rsp *agentapi.FollowTextFileRsp) error {
+	s.responses = append(s.responses, rsp)
+	return nil
+}
+
+// Test that the lines of the text file can be streamed.
+func TestFollowTextFile(t *testing.T) {
+	sa, ctx := setupAgentTest()
+
+	sb := testutil.NewSandbox()
+	defer sb.Close()
+	filename, err := sb.Write("kea.log", "This is a file\nwhich is used\nin testing FollowTextFile\n")
+	require.NoError(t, err)
+
+	stream := &fakeFollowTextFileServer{ctx: ctx}
+	req := &agentapi.FollowTextFileReq{
+		Path:   filename,
+		Offset: 200,
+		Filter: "used|testing",
+	}
+
+	// The file is not allowed.
+	err = sa.FollowTextFile(req, stream)
+	require.NoError(t, err)
+	require.Len(t, stream.responses, 1)
+	require.Equal(t, agentapi.Status_ERROR, stream.responses[0].Status.Code)
+	require.Contains(t, stream.responses[0].Status.Message, "access forbidden")
+
+	sa.logTailer.allow(filename)
+	stream.responses = nil
+	err = sa.FollowTextFile(req, stream)
+	require.NoError(t, err)
+	require.Len(t, stream.responses, 1)
+	require.Equal(t, agentapi.Status_OK, stream.responses[0].Status.Code)
+	require.Equal(t, []string{"which is used", "in testing FollowTextFile"}, stream.responses[0].Lines)
+	require.EqualValues(t, 55, stream.responses[0].Position)
+}
+
 // Checks if getRootCertificates:
 // - returns an error if the cert file doesn't exist.
 func TestGetRootCertificatesForMissingOrInvalidFiles(t *testing.T) {
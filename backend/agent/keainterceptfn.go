@@ -6,11 +6,12 @@ import (
 
 // Intercept callback function for config-get. It records log files
 // found in the daemon's configuration, making them accessible by the
-// log viewer.
+// log viewer, and starts following them for the notable messages.
 func icptConfigGetLoggers(agent *StorkAgent, response *keactrl.Response) error {
 	paths := collectKeaAllowedLogs(response)
 	for _, p := range paths {
 		agent.logTailer.allow(p)
+		agent.keaLogEvents.follow(p)
 	}
 	return nil
 }
@@ -74,6 +74,11 @@ func TestIcptConfigGetLoggers(t *testing.T) {
 	require.False(t, sa.logTailer.allowed("stdout"))
 	require.False(t, sa.logTailer.allowed("stderr"))
 	require.False(t, sa.logTailer.allowed("syslog:1"))
+
+	// The log files should be followed for the notable messages.
+	require.Contains(t, sa.keaLogEvents.files, "/tmp/kea-dhcp4.log")
+	require.Contains(t, sa.keaLogEvents.files, "/tmp/kea-dhcp4-allocations.log")
+	require.NotContains(t, sa.keaLogEvents.files, "syslog:1")
 }
 
 // Test that the result code is changed if the reservation-get-page command
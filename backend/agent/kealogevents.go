@@ -0,0 +1,297 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	agentapi "isc.org/stork/api"
+)
+
+// Interval between the subsequent reads of the followed Kea log files.
+const keaLogFollowInterval = 2 * time.Second
+
+// Maximum number of bytes read from a single log file in one pass. The
+// remaining data are read in the next passes.
+const maxKeaLogReadSize = 1024 * 1024
+
+// Maximum number of the events buffered by the agent until they are
+// fetched by the server. The oldest events are dropped when the limit
+// is exceeded.
+const maxKeaLogEvents = 1000
+
+// Time window in which the repeated occurrences of the throttled message
+// in the same log file are suppressed.
+const keaLogEventThrottleWindow = time.Minute
+
+// Matches the log lines produced by Kea with the default output pattern,
+// e.g.:
+//
+// 2022-05-10 12:00:00.123 WARN  [kea-dhcp4.alloc-engine/1234.139] ALLOC_ENGINE_V4_ALLOC_FAIL [hwtype=1 ...] failed to allocate an IPv4 address
+//
+// The Kea versions earlier than 1.9 don't log the thread ID.
+var keaLogLinePattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d+)\s+(DEBUG|INFO|WARN|ERROR|FATAL)\s+\[([^/\]]+)(?:/[^\]]*)?\]\s+([A-Z][A-Z0-9_]+)\s*(.*)$`)
+
+// Kea severities in the ascending order.
+var keaLogSeverities = map[string]int{
+	"DEBUG": 0,
+	"INFO":  1,
+	"WARN":  2,
+	"ERROR": 3,
+	"FATAL": 4,
+}
+
+// Message parsed from the Kea log line.
+type keaLogMessage struct {
+	timestamp string
+	severity  string
+	logger    string
+	messageID string
+	text      string
+}
+
+// Parses the Kea log line. It returns false if the line doesn't look like
+// a Kea log message, e.g., it is a continuation of a multi-line message.
+func parseKeaLogLine(line string) (*keaLogMessage, bool) {
+	match := keaLogLinePattern.FindStringSubmatch(line)
+	if match == nil {
+		return nil, false
+	}
+	return &keaLogMessage{
+		timestamp: match[1],
+		severity:  match[2],
+		logger:    match[3],
+		messageID: match[4],
+		text:      match[5],
+	}, true
+}
+
+// Returns the name of the daemon which logged the message with the given
+// logger, e.g. dhcp4 for kea-dhcp4.alloc-engine. It returns an empty
+// string if the logger doesn't belong to any known daemon.
+func getKeaLogDaemonName(logger string) string {
+	root, _, _ := strings.Cut(logger, ".")
+	switch root {
+	case "kea-dhcp4":
+		return "dhcp4"
+	case "kea-dhcp6":
+		return "dhcp6"
+	case "kea-dhcp-ddns":
+		return "d2"
+	case "kea-ctrl-agent":
+		return "ca"
+	case "kea-netconf":
+		return "netconf"
+	default:
+		return ""
+	}
+}
+
+// A rule selecting the notable log messages reported as events.
+type keaLogEventRule struct {
+	// Pattern matched against the message ID.
+	pattern *regexp.Regexp
+	// Lowest severity of the matching message.
+	minSeverity string
+	// Indicates if the repeated occurrences of the message are suppressed.
+	throttled bool
+}
+
+// Rules selecting the notable log messages. The first matching rule is
+// applied. The messages that may be logged for every DHCP packet are
+// throttled, so they don't flood the server.
+var keaLogEventRules = []keaLogEventRule{
+	{regexp.MustCompile(`^HA_STATE_TRANSITION`), "INFO", false},
+	{regexp.MustCompile(`^HA_SYNC_SUCCESSFUL$`), "INFO", false},
+	{regexp.MustCompile(`^HA_`), "WARN", true},
+	{regexp.MustCompile(`^ALLOC_ENGINE_V[46]_ALLOC_FAIL`), "DEBUG", true},
+	{regexp.MustCompile(`^DHCP[46]_PACKET_DROP_`), "DEBUG", true},
+	{regexp.MustCompile(`.`), "ERROR", true},
+}
+
+// Returns the rule matching the log message or nil if the message is
+// not notable.
+func findKeaLogEventRule(message *keaLogMessage) *keaLogEventRule {
+	for i, rule := range keaLogEventRules {
+		if rule.pattern.MatchString(message.messageID) &&
+			keaLogSeverities[message.severity] >= keaLogSeverities[rule.minSeverity] {
+			return &keaLogEventRules[i]
+		}
+	}
+	return nil
+}
+
+// Follows the Kea log files, parses the logged messages and buffers the
+// notable ones as events until they are fetched by the server. The events
+// are numbered with the sequence numbers. The server acknowledges the
+// received events by sending back the sequence number of the last event
+// it has received. The generation identifies the sequence, so the
+// acknowledgements sent before the agent restart are ignored.
+type keaLogEventCollector struct {
+	mutex      *sync.Mutex
+	files      map[string]*followedFile
+	generation string
+	sequence   int64
+	events     []*agentapi.KeaLogEvent
+	// Times when the throttled messages were last reported by the
+	// log file and message ID.
+	lastReported map[string]time.Time
+	// Numbers of the suppressed occurrences of the throttled messages.
+	suppressed map[string]int64
+	overflow   bool
+	done       chan bool
+	wg         *sync.WaitGroup
+}
+
+// Creates new instance of the collector.
+func newKeaLogEventCollector() *keaLogEventCollector {
+	return &keaLogEventCollector{
+		mutex:        new(sync.Mutex),
+		files:        make(map[string]*followedFile),
+		generation:   fmt.Sprint(time.Now().UnixNano()),
+		lastReported: make(map[string]time.Time),
+		suppressed:   make(map[string]int64),
+		done:         make(chan bool),
+		wg:           new(sync.WaitGroup),
+	}
+}
+
+// Starts following the specified log file. Only the messages logged
+// after the first read are reported. Adding the file that is already
+// followed has no effect.
+func (c *keaLogEventCollector) follow(path string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.files[path]; !ok {
+		c.files[path] = &followedFile{}
+	}
+}
+
+// Starts the goroutine periodically reading the followed log files.
+func (c *keaLogEventCollector) start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(keaLogFollowInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.collect(time.Now())
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stops the goroutine reading the log files.
+func (c *keaLogEventCollector) stop() {
+	close(c.done)
+	c.wg.Wait()
+}
+
+// Reads the new messages from all followed log files.
+func (c *keaLogEventCollector) collect(now time.Time) {
+	c.mutex.Lock()
+	paths := make([]string, 0, len(c.files))
+	for path := range c.files {
+		paths = append(paths, path)
+	}
+	c.mutex.Unlock()
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		c.mutex.Lock()
+		file := c.files[path]
+		c.mutex.Unlock()
+
+		// Only the messages logged after the first read are reported.
+		if file.info == nil {
+			if err := file.seekEnd(path); err != nil {
+				log.WithError(err).Debugf("Failed to read the Kea log file %s", path)
+			}
+			continue
+		}
+		lines, _, err := readFollowedFile(path, file, maxKeaLogReadSize)
+		if err != nil {
+			log.WithError(err).Debugf("Failed to read the Kea log file %s", path)
+			continue
+		}
+		for _, line := range lines {
+			c.handleLine(path, line, now)
+		}
+	}
+}
+
+// Parses the log line and buffers the event if the message is notable.
+func (c *keaLogEventCollector) handleLine(path, line string, now time.Time) {
+	message, ok := parseKeaLogLine(strings.TrimRight(line, "\r"))
+	if !ok {
+		return
+	}
+	rule := findKeaLogEventRule(message)
+	if rule == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := path + " " + message.messageID
+	if rule.throttled {
+		if last, ok := c.lastReported[key]; ok && now.Sub(last) < keaLogEventThrottleWindow {
+			c.suppressed[key]++
+			return
+		}
+		c.lastReported[key] = now
+	}
+
+	c.sequence++
+	c.events = append(c.events, &agentapi.KeaLogEvent{
+		Sequence:   c.sequence,
+		Path:       path,
+		Timestamp:  message.timestamp,
+		Severity:   message.severity,
+		Logger:     message.logger,
+		Daemon:     getKeaLogDaemonName(message.logger),
+		MessageID:  message.messageID,
+		Text:       message.text,
+		Suppressed: c.suppressed[key],
+	})
+	delete(c.suppressed, key)
+
+	if len(c.events) > maxKeaLogEvents {
+		c.events = c.events[len(c.events)-maxKeaLogEvents:]
+		if !c.overflow {
+			log.Warnf("Dropped the oldest Kea log events because they were not fetched by the Stork Server")
+			c.overflow = true
+		}
+	}
+}
+
+// Returns the buffered events. If the generation matches the current
+// generation, the events up to the specified sequence number are
+// acknowledged and dropped from the buffer. It returns the current
+// generation and the remaining events.
+func (c *keaLogEventCollector) getEvents(generation string, lastSequence int64) (string, []*agentapi.KeaLogEvent) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation == c.generation {
+		index := sort.Search(len(c.events), func(i int) bool {
+			return c.events[i].Sequence > lastSequence
+		})
+		c.events = c.events[index:]
+	}
+	c.overflow = false
+
+	events := make([]*agentapi.KeaLogEvent, len(c.events))
+	copy(events, c.events)
+	return c.generation, events
+}
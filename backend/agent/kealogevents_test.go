@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	agentapi "isc.org/stork/api"
+	"isc.org/stork/testutil"
+)
+
+// Test parsing the Kea log lines.
+func TestParseKeaLogLine(t *testing.T) {
+	message, ok := parseKeaLogLine("2022-05-10 12:00:00.123 WARN  [kea-dhcp4.alloc-engine/1234.139] ALLOC_ENGINE_V4_ALLOC_FAIL [hwtype=1 01:02:03:04:05:06], cid=[no info], tid=0x1: failed to allocate an IPv4 address after 0 attempt(s)")
+	require.True(t, ok)
+	require.Equal(t, "2022-05-10 12:00:00.123", message.timestamp)
+	require.Equal(t, "WARN", message.severity)
+	require.Equal(t, "kea-dhcp4.alloc-engine", message.logger)
+	require.Equal(t, "ALLOC_ENGINE_V4_ALLOC_FAIL", message.messageID)
+	require.Equal(t, "[hwtype=1 01:02:03:04:05:06], cid=[no info], tid=0x1: failed to allocate an IPv4 address after 0 attempt(s)", message.text)
+
+	// Old Kea versions don't log the thread ID.
+	message, ok = parseKeaLogLine("2019-01-10 12:00:00.123 INFO  [kea-dhcp6.ha-hooks/1234] HA_STATE_TRANSITION server1: server transitions from WAITING to READY state, partner state is READY")
+	require.True(t, ok)
+	require.Equal(t, "kea-dhcp6.ha-hooks", message.logger)
+	require.Equal(t, "HA_STATE_TRANSITION", message.messageID)
+
+	// Continuation of a multi-line message.
+	_, ok = parseKeaLogLine(`    "Dhcp4": {`)
+	require.False(t, ok)
+
+	_, ok = parseKeaLogLine("")
+	require.False(t, ok)
+}
+
+// Test getting the daemon names from the logger names.
+func TestGetKeaLogDaemonName(t *testing.T) {
+	require.Equal(t, "dhcp4", getKeaLogDaemonName("kea-dhcp4.alloc-engine"))
+	require.Equal(t, "dhcp6", getKeaLogDaemonName("kea-dhcp6"))
+	require.Equal(t, "d2", getKeaLogDaemonName("kea-dhcp-ddns.d2-to-dns"))
+	require.Equal(t, "ca", getKeaLogDaemonName("kea-ctrl-agent.http"))
+	require.Equal(t, "netconf", getKeaLogDaemonName("kea-netconf"))
+	require.Empty(t, getKeaLogDaemonName("foo.bar"))
+}
+
+// Test that the notable messages are selected.
+func TestFindKeaLogEventRule(t *testing.T) {
+	testCases := []struct {
+		severity  string
+		messageID string
+		notable   bool
+		throttled bool
+	}{
+		{"INFO", "HA_STATE_TRANSITION", true, false},
+		{"INFO", "HA_STATE_TRANSITION_PASSIVE_BACKUP", true, false},
+		{"INFO", "HA_SYNC_SUCCESSFUL", true, false},
+		{"INFO", "HA_LOCAL_DHCP_ENABLE", false, false},
+		{"WARN", "HA_COMMUNICATION_INTERRUPTED", true, true},
+		{"WARN", "ALLOC_ENGINE_V4_ALLOC_FAIL_SUBNET", true, true},
+		{"WARN", "ALLOC_ENGINE_V6_ALLOC_FAIL", true, true},
+		{"DEBUG", "DHCP4_PACKET_DROP_0001", true, true},
+		{"DEBUG", "DHCP6_PACKET_DROP_PARSE_FAIL", true, true},
+		{"INFO", "DHCP4_STARTED", false, false},
+		{"ERROR", "DHCPSRV_MEMFILE_LFC_EXECUTE_FAILED", true, true},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.messageID, func(t *testing.T) {
+			rule := findKeaLogEventRule(&keaLogMessage{
+				severity:  testCase.severity,
+				messageID: testCase.messageID,
+			})
+			if !testCase.notable {
+				require.Nil(t, rule)
+				return
+			}
+			require.NotNil(t, rule)
+			require.Equal(t, testCase.throttled, rule.throttled)
+		})
+	}
+}
+
+// Test that the notable messages are buffered as events, the repeated
+// messages are throttled and the acknowledged events are dropped.
+func TestKeaLogEventCollector(t *testing.T) {
+	sb := testutil.NewSandbox()
+	defer sb.Close()
+
+	path, err := sb.Write("kea-dhcp4.log", "")
+	require.NoError(t, err)
+
+	collector := newKeaLogEventCollector()
+	collector.follow(path)
+	collector.follow(path)
+	require.Len(t, collector.files, 1)
+
+	now := time.Now()
+	collector.collect(now)
+
+	allocFail := "2022-05-10 12:00:00.123 WARN  [kea-dhcp4.alloc-engine/1234.139] ALLOC_ENGINE_V4_ALLOC_FAIL_SUBNET [hwtype=1 01:02:03:04:05:06]: failed to allocate an IPv4 lease in the subnet 192.0.2.0/24, subnet-id 1"
+	transition := "2022-05-10 12:00:01.123 INFO  [kea-dhcp4.ha-hooks/1234.139] HA_STATE_TRANSITION server1: server transitions from LOAD-BALANCING to PARTNER-DOWN state, partner state is UNAVAILABLE"
+	started := "2022-05-10 12:00:02.123 INFO  [kea-dhcp4.dhcp4/1234.139] DHCP4_STARTED Kea DHCPv4 server version 2.2.0 started"
+	_, err = sb.Write("kea-dhcp4.log", allocFail+"\n"+transition+"\n"+allocFail+"\n"+started+"\n"+transition+"\n"+allocFail+"\n")
+	require.NoError(t, err)
+	collector.collect(now)
+
+	generation, events := collector.getEvents("", 0)
+	require.Equal(t, collector.generation, generation)
+	require.Len(t, events, 3)
+
+	require.EqualValues(t, 1, events[0].Sequence)
+	require.Equal(t, path, events[0].Path)
+	require.Equal(t, "2022-05-10 12:00:00.123", events[0].Timestamp)
+	require.Equal(t, "WARN", events[0].Severity)
+	require.Equal(t, "kea-dhcp4.alloc-engine", events[0].Logger)
+	require.Equal(t, "dhcp4", events[0].Daemon)
+	require.Equal(t, "ALLOC_ENGINE_V4_ALLOC_FAIL_SUBNET", events[0].MessageID)
+	require.Contains(t, events[0].Text, "192.0.2.0/24")
+	require.Zero(t, events[0].Suppressed)
+
+	// The HA transitions are not throttled.
+	require.Equal(t, "HA_STATE_TRANSITION", events[1].MessageID)
+	require.Equal(t, "HA_STATE_TRANSITION", events[2].MessageID)
+
+	// The repeated allocation failure is reported after the throttle
+	// window with the number of the suppressed occurrences.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(allocFail + "\n")
+	require.NoError(t, err)
+	f.Close()
+	collector.collect(now.Add(keaLogEventThrottleWindow))
+
+	// The acknowledgement with the wrong generation is ignored.
+	_, events = collector.getEvents("foo", 3)
+	require.Len(t, events, 4)
+
+	// Acknowledge the first three events.
+	_, events = collector.getEvents(generation, 3)
+	require.Len(t, events, 1)
+	require.EqualValues(t, 4, events[0].Sequence)
+	require.Equal(t, "ALLOC_ENGINE_V4_ALLOC_FAIL_SUBNET", events[0].MessageID)
+	require.EqualValues(t, 2, events[0].Suppressed)
+
+	_, events = collector.getEvents(generation, 4)
+	require.Empty(t, events)
+}
+
+// Test that the oldest events are dropped when the buffer is full.
+func TestKeaLogEventCollectorOverflow(t *testing.T) {
+	collector := newKeaLogEventCollector()
+	transition := "2022-05-10 12:00:01.123 INFO  [kea-dhcp4.ha-hooks/1234.139] HA_STATE_TRANSITION server1: server transitions from LOAD-BALANCING to PARTNER-DOWN state, partner state is UNAVAILABLE"
+	for i := 0; i < maxKeaLogEvents+10; i++ {
+		collector.handleLine("/tmp/kea.log", transition, time.Now())
+	}
+	_, events := collector.getEvents("", 0)
+	require.Len(t, events, maxKeaLogEvents)
+	require.EqualValues(t, 11, events[0].Sequence)
+}
+
+// Test getting the Kea log events over gRPC.
+func TestGetKeaLogEvents(t *testing.T) {
+	sa, ctx := setupAgentTest()
+	sa.keaLogEvents.handleLine("/tmp/kea.log", "2022-05-10 12:00:01.123 ERROR [kea-dhcp6.dhcp6/1234.139] DHCP6_CONFIG_LOAD_FAIL configuration error using file: /etc/kea/kea-dhcp6.conf", time.Now())
+
+	rsp, err := sa.GetKeaLogEvents(ctx, &agentapi.GetKeaLogEventsReq{})
+	require.NoError(t, err)
+	require.Equal(t, agentapi.Status_OK, rsp.Status.Code)
+	require.NotEmpty(t, rsp.Generation)
+	require.Len(t, rsp.Events, 1)
+	require.Equal(t, "dhcp6", rsp.Events[0].Daemon)
+
+	rsp, err = sa.GetKeaLogEvents(ctx, &agentapi.GetKeaLogEventsReq{
+		Generation:   rsp.Generation,
+		LastSequence: rsp.Events[0].Sequence,
+	})
+	require.NoError(t, err)
+	require.Empty(t, rsp.Events)
+}
@@ -0,0 +1,355 @@
+package agent
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	agentapi "isc.org/stork/api"
+	keaconfig "isc.org/stork/appcfg/kea"
+	keactrl "isc.org/stork/appctrl/kea"
+	keadata "isc.org/stork/appdata/kea"
+)
+
+// Default locations of the memfile lease files used by Kea when the
+// lease file name is not specified in the configuration.
+const (
+	defaultLeaseFile4 = "/var/lib/kea/kea-leases4.csv"
+	defaultLeaseFile6 = "/var/lib/kea/kea-leases6.csv"
+)
+
+// Maximum length of a line in the lease file. The lines may be long
+// when the leases contain user contexts.
+const maxLeaseFileLineLength = 1024 * 1024
+
+// Upper bounds of the buckets the active leases are assigned to by their
+// age, i.e., the time elapsed since the client last contacted the server.
+// The last bucket has no upper bound.
+var leaseAgeBounds = []time.Duration{
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+}
+
+// Lease statistics of a single subnet computed from the lease file.
+type leaseFileSubnetStats struct {
+	// Number of the valid leases in the default state.
+	active uint64
+	// Number of the expired leases, including expired-reclaimed ones.
+	expired uint64
+	// Number of the declined leases.
+	declined uint64
+	// Number of the leases by the lease state.
+	states map[int]uint64
+	// Number of the active leases in the age buckets delimited by the
+	// leaseAgeBounds. The counts are not cumulative.
+	ageBuckets []uint64
+}
+
+// Lease statistics computed from the lease file.
+type leaseFileStats struct {
+	// Lease file location.
+	path string
+	// IP family of the leases in the file.
+	family int
+	// Statistics by the subnet IDs.
+	subnets map[uint32]*leaseFileSubnetStats
+}
+
+// Returns the subnet IDs sorted in the ascending order.
+func (s *leaseFileStats) getSubnetIDs() []uint32 {
+	ids := make([]uint32, 0, len(s.subnets))
+	for id := range s.subnets {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// A lease read from the lease file. Only the data necessary to compute
+// the statistics are stored.
+type leaseFileEntry struct {
+	subnetID      uint32
+	state         int
+	validLifetime uint32
+	expire        int64
+}
+
+// Returns the location of the memfile lease file and the IP family of
+// the leases in this file from the DHCP server's configuration returned
+// in response to the config-get command. It returns false if the server
+// doesn't use the memfile lease database.
+func collectKeaLeaseFile(response *keactrl.Response) (path string, family int, ok bool) {
+	if response.Result != keactrl.ResponseSuccess || response.Arguments == nil {
+		return
+	}
+	cfg := keaconfig.NewConfigFromMap(response.Arguments)
+	if cfg == nil {
+		return
+	}
+	switch {
+	case cfg.IsDHCPv4():
+		path, family = defaultLeaseFile4, 4
+	case cfg.IsDHCPv6():
+		path, family = defaultLeaseFile6, 6
+	default:
+		return
+	}
+	database := cfg.GetAllDatabases().Lease
+	if database == nil || database.Type != "memfile" {
+		return "", 0, false
+	}
+	if database.Name != "" {
+		path = database.Name
+	}
+	return path, family, true
+}
+
+// Sends the config-get command to the DHCP daemons behind the Kea Control
+// Agent and returns the locations of their memfile lease files mapped to
+// the IP families of the leases.
+func detectKeaLeaseFiles(sender keaCommandSender, app App) (map[string]int, error) {
+	var daemons []string
+	for _, daemon := range app.GetConfiguredDaemons() {
+		if daemon == "dhcp4" || daemon == "dhcp6" {
+			daemons = append(daemons, daemon)
+		}
+	}
+	files := make(map[string]int)
+	if len(daemons) == 0 {
+		return files, nil
+	}
+
+	ctrl, err := getAccessPoint(app, AccessPointControl)
+	if err != nil {
+		return nil, err
+	}
+	command := keactrl.NewCommand("config-get", daemons, nil)
+	body, err := sender.sendCommandToKeaCA(ctrl, command.Marshal())
+	if err != nil {
+		return nil, err
+	}
+	responses := keactrl.ResponseList{}
+	err = keactrl.UnmarshalResponseList(command, body, &responses)
+	if err != nil {
+		return nil, err
+	}
+	for i := range responses {
+		if path, family, ok := collectKeaLeaseFile(&responses[i]); ok {
+			files[path] = family
+		}
+	}
+	return files, nil
+}
+
+// Returns the lease files in the order they are loaded by Kea. The lease
+// file cleanup (LFC) moves the current lease file contents to the files
+// with the .1, .2 and .completed suffixes. Kea loads the .completed file
+// if it exists or the .2 and .1 files otherwise, and then the current
+// lease file.
+func getLeaseFileChain(path string) []string {
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	if completed := path + ".completed"; exists(completed) {
+		return []string{completed, path}
+	}
+	var chain []string
+	for _, suffix := range []string{".2", ".1"} {
+		if exists(path + suffix) {
+			chain = append(chain, path+suffix)
+		}
+	}
+	return append(chain, path)
+}
+
+// Parses the lease file row. It returns the lease and the key identifying
+// the lease in the file.
+func parseLeaseFileRow(fields []string, columns map[string]int) (key string, entry leaseFileEntry, err error) {
+	field := func(name string) string {
+		if index, ok := columns[name]; ok && index < len(fields) {
+			return fields[index]
+		}
+		return ""
+	}
+	key = field("address")
+	if key == "" {
+		return "", entry, errors.New("missing lease address")
+	}
+	// The DHCPv6 addresses and prefixes are distinguished by the lease type.
+	if leaseType := field("lease_type"); leaseType != "" {
+		key += "," + leaseType
+	}
+	subnetID, err := strconv.ParseUint(field("subnet_id"), 10, 32)
+	if err != nil {
+		return "", entry, errors.Wrapf(err, "invalid subnet ID in lease %s", key)
+	}
+	validLifetime, err := strconv.ParseUint(field("valid_lifetime"), 10, 32)
+	if err != nil {
+		return "", entry, errors.Wrapf(err, "invalid valid lifetime in lease %s", key)
+	}
+	expire, err := strconv.ParseInt(field("expire"), 10, 64)
+	if err != nil {
+		return "", entry, errors.Wrapf(err, "invalid expiration time in lease %s", key)
+	}
+	// The state column is missing in the files created by old Kea versions.
+	state := keadata.LeaseStateDefault
+	if value := field("state"); value != "" {
+		state, err = strconv.Atoi(value)
+		if err != nil {
+			return "", entry, errors.Wrapf(err, "invalid state in lease %s", key)
+		}
+	}
+	entry = leaseFileEntry{
+		subnetID:      uint32(subnetID),
+		state:         state,
+		validLifetime: uint32(validLifetime),
+		expire:        expire,
+	}
+	return key, entry, nil
+}
+
+// Reads the leases from the lease file into the map. The lease file is
+// append-only, so the later entries for the same lease replace the
+// earlier ones. The deleted leases are stored with zero valid lifetime.
+// The rows that cannot be parsed are skipped. It returns the number of
+// the skipped rows.
+func readLeaseFile(path string, leases map[string]leaseFileEntry) (skipped int, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to open the lease file: %s", path)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLeaseFileLineLength)
+
+	var columns map[string]int
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		// The first line is a header.
+		if columns == nil {
+			columns = make(map[string]int, len(fields))
+			for i, name := range fields {
+				columns[name] = i
+			}
+			for _, name := range []string{"address", "valid_lifetime", "expire", "subnet_id"} {
+				if _, ok := columns[name]; !ok {
+					return 0, errors.Errorf("missing %s column in the lease file: %s", name, path)
+				}
+			}
+			continue
+		}
+		key, entry, err := parseLeaseFileRow(fields, columns)
+		if err != nil {
+			skipped++
+			continue
+		}
+		if entry.validLifetime == 0 {
+			delete(leases, key)
+			continue
+		}
+		leases[key] = entry
+	}
+	if err = scanner.Err(); err != nil {
+		return skipped, errors.Wrapf(err, "failed to read the lease file: %s", path)
+	}
+	return skipped, nil
+}
+
+// Computes the lease statistics of the subnets at the given time.
+func getLeaseFileSubnetStats(leases map[string]leaseFileEntry, now time.Time) map[uint32]*leaseFileSubnetStats {
+	subnets := make(map[uint32]*leaseFileSubnetStats)
+	for _, lease := range leases {
+		stats, ok := subnets[lease.subnetID]
+		if !ok {
+			stats = &leaseFileSubnetStats{
+				states:     make(map[int]uint64),
+				ageBuckets: make([]uint64, len(leaseAgeBounds)+1),
+			}
+			subnets[lease.subnetID] = stats
+		}
+		stats.states[lease.state]++
+
+		expire := time.Unix(lease.expire, 0)
+		switch {
+		case lease.state == keadata.LeaseStateDeclined:
+			stats.declined++
+		case lease.state == keadata.LeaseStateExpiredReclaimed || !expire.After(now):
+			stats.expired++
+		default:
+			stats.active++
+			age := now.Sub(expire.Add(-time.Duration(lease.validLifetime) * time.Second))
+			bucket := sort.Search(len(leaseAgeBounds), func(i int) bool {
+				return age <= leaseAgeBounds[i]
+			})
+			stats.ageBuckets[bucket]++
+		}
+	}
+	return subnets
+}
+
+// Reads the lease file along with the files created by the lease file
+// cleanup and computes the lease statistics at the given time.
+func collectLeaseFileStats(path string, family int, now time.Time) (*leaseFileStats, error) {
+	leases := make(map[string]leaseFileEntry)
+	for _, file := range getLeaseFileChain(path) {
+		skipped, err := readLeaseFile(file, leases)
+		if err != nil {
+			return nil, err
+		}
+		if skipped > 0 {
+			log.Warnf("Skipped %d invalid rows in the lease file %s", skipped, file)
+		}
+	}
+	return &leaseFileStats{
+		path:    path,
+		family:  family,
+		subnets: getLeaseFileSubnetStats(leases, now),
+	}, nil
+}
+
+// Converts the lease file statistics to the gRPC format. The subnets are
+// sorted by the IDs and the lease states by their values.
+func convertLeaseFileStatsToProto(stats *leaseFileStats) []*agentapi.LeaseFileSubnetStats {
+	var subnets []*agentapi.LeaseFileSubnetStats
+	for _, id := range stats.getSubnetIDs() {
+		subnetStats := stats.subnets[id]
+		subnet := &agentapi.LeaseFileSubnetStats{
+			SubnetID: int64(id),
+			Active:   int64(subnetStats.active),
+			Expired:  int64(subnetStats.expired),
+			Declined: int64(subnetStats.declined),
+		}
+		states := make([]int, 0, len(subnetStats.states))
+		for state := range subnetStats.states {
+			states = append(states, state)
+		}
+		sort.Ints(states)
+		for _, state := range states {
+			subnet.States = append(subnet.States, &agentapi.LeaseStateCount{
+				State: int64(state),
+				Count: int64(subnetStats.states[state]),
+			})
+		}
+		for _, count := range subnetStats.ageBuckets {
+			subnet.AgeBuckets = append(subnet.AgeBuckets, int64(count))
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets
+}
@@ -0,0 +1,334 @@
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+
+	agentapi "isc.org/stork/api"
+	keactrl "isc.org/stork/appctrl/kea"
+	storktestutil "isc.org/stork/testutil"
+)
+
+// Header of the DHCPv4 lease file.
+const leaseFile4Header = "address,hwaddr,client_id,valid_lifetime,expire,subnet_id,fqdn_fwd,fqdn_rev,hostname,state,user_context"
+
+// Header of the DHCPv6 lease file.
+const leaseFile6Header = "address,duid,valid_lifetime,expire,subnet_id,pref_lifetime,lease_type,iaid,prefix_len,fqdn_fwd,fqdn_rev,hostname,hwaddr,state,user_context,hwtype,hwaddr_source"
+
+// Returns the DHCPv4 lease file row. The client last contacted the server
+// the specified time before now.
+func makeLeaseFile4Row(address string, subnetID int, state int, validLifetime int64, age time.Duration, now time.Time) string {
+	expire := now.Add(-age).Unix() + validLifetime
+	return fmt.Sprintf("%s,01:02:03:04:05:06,,%d,%d,%d,0,0,,%d,", address, validLifetime, expire, subnetID, state)
+}
+
+// Returns the DHCPv6 lease file row.
+func makeLeaseFile6Row(address string, subnetID int, leaseType int, prefixLen int, validLifetime int64, age time.Duration, now time.Time) string {
+	expire := now.Add(-age).Unix() + validLifetime
+	return fmt.Sprintf("%s,00:01:02,%d,%d,%d,3000,%d,1,%d,0,0,,,0,,1,0", address, validLifetime, expire, subnetID, leaseType, prefixLen)
+}
+
+// Test that the DHCPv4 lease file is read and the statistics are computed.
+func TestCollectLeaseFileStats4(t *testing.T) {
+	sb := storktestutil.NewSandbox()
+	defer sb.Close()
+	now := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	rows := []string{
+		leaseFile4Header,
+		// Active leases.
+		makeLeaseFile4Row("192.0.2.1", 1, 0, 3600, 10*time.Minute, now),
+		makeLeaseFile4Row("192.0.2.2", 1, 0, 86400, 2*time.Hour, now),
+		// The lease is renewed.
+		makeLeaseFile4Row("192.0.2.3", 1, 0, 3600, 2*time.Hour, now),
+		makeLeaseFile4Row("192.0.2.3", 1, 0, 3600, 5*time.Minute, now),
+		// Expired lease.
+		makeLeaseFile4Row("192.0.2.4", 1, 0, 3600, 2*time.Hour, now),
+		// Expired-reclaimed lease.
+		makeLeaseFile4Row("192.0.2.5", 1, 2, 3600, 2*time.Hour, now),
+		// Declined lease.
+		makeLeaseFile4Row("192.0.2.6", 1, 1, 86400, 2*time.Hour, now),
+		// Deleted lease.
+		makeLeaseFile4Row("192.0.2.7", 1, 0, 3600, 5*time.Minute, now),
+		makeLeaseFile4Row("192.0.2.7", 1, 0, 0, 5*time.Minute, now),
+		// Invalid row.
+		"192.0.2.8,01:02:03:04:05:06,,foo,bar,1,0,0,,0,",
+		// Another subnet.
+		makeLeaseFile4Row("192.0.3.1", 2, 0, 864000, 8*24*time.Hour, now),
+	}
+	content := ""
+	for _, row := range rows {
+		content += row + "\n"
+	}
+	path, err := sb.Write("kea-leases4.csv", content)
+	require.NoError(t, err)
+
+	stats, err := collectLeaseFileStats(path, 4, now)
+	require.NoError(t, err)
+	require.Equal(t, path, stats.path)
+	require.Equal(t, 4, stats.family)
+	require.Equal(t, []uint32{1, 2}, stats.getSubnetIDs())
+
+	subnet := stats.subnets[1]
+	require.EqualValues(t, 3, subnet.active)
+	require.EqualValues(t, 2, subnet.expired)
+	require.EqualValues(t, 1, subnet.declined)
+	require.Equal(t, map[int]uint64{0: 4, 1: 1, 2: 1}, subnet.states)
+	require.Equal(t, []uint64{2, 1, 0, 0, 0}, subnet.ageBuckets)
+
+	subnet = stats.subnets[2]
+	require.EqualValues(t, 1, subnet.active)
+	require.Equal(t, []uint64{0, 0, 0, 0, 1}, subnet.ageBuckets)
+}
+
+// Test that the DHCPv6 addresses and prefixes are distinguished and the
+// files created by the lease file cleanup are read.
+func TestCollectLeaseFileStats6(t *testing.T) {
+	sb := storktestutil.NewSandbox()
+	defer sb.Close()
+	now := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	path, err := sb.Write("kea-leases6.csv", leaseFile6Header+"\n"+
+		makeLeaseFile6Row("2001:db8:1::", 1, 2, 56, 3600, time.Minute, now)+"\n")
+	require.NoError(t, err)
+	_, err = sb.Write("kea-leases6.csv.1", leaseFile6Header+"\n"+
+		makeLeaseFile6Row("2001:db8:1::", 1, 0, 128, 3600, time.Minute, now)+"\n"+
+		makeLeaseFile6Row("2001:db8:1::1", 1, 0, 128, 3600, 2*time.Hour, now)+"\n")
+	require.NoError(t, err)
+	_, err = sb.Write("kea-leases6.csv.2", leaseFile6Header+"\n"+
+		makeLeaseFile6Row("2001:db8:1::1", 1, 0, 128, 3600, time.Minute, now)+"\n"+
+		makeLeaseFile6Row("2001:db8:1::2", 1, 0, 128, 3600, time.Minute, now)+"\n")
+	require.NoError(t, err)
+
+	stats, err := collectLeaseFileStats(path, 6, now)
+	require.NoError(t, err)
+	require.Len(t, stats.subnets, 1)
+
+	// The .1 file is read after the .2 file, so the lease for the
+	// 2001:db8:1::1 address is expired.
+	subnet := stats.subnets[1]
+	require.EqualValues(t, 3, subnet.active)
+	require.EqualValues(t, 1, subnet.expired)
+
+	// The .completed file replaces the .1 and .2 files.
+	_, err = sb.Write("kea-leases6.csv.completed", leaseFile6Header+"\n")
+	require.NoError(t, err)
+	stats, err = collectLeaseFileStats(path, 6, now)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, stats.subnets[1].active)
+	require.Zero(t, stats.subnets[1].expired)
+}
+
+// Test that an error is returned when the lease file doesn't exist or
+// lacks the required columns.
+func TestCollectLeaseFileStatsError(t *testing.T) {
+	sb := storktestutil.NewSandbox()
+	defer sb.Close()
+
+	path := filepath.Join(sb.BasePath, "kea-leases4.csv")
+	_, err := collectLeaseFileStats(path, 4, time.Now())
+	require.Error(t, err)
+
+	path, err = sb.Write("kea-leases4.csv", "address,hwaddr\n192.0.2.1,01:02:03:04:05:06\n")
+	require.NoError(t, err)
+	_, err = collectLeaseFileStats(path, 4, time.Now())
+	require.ErrorContains(t, err, "missing valid_lifetime column")
+}
+
+// Test getting the lease file location from the DHCP server's configuration.
+func TestCollectKeaLeaseFile(t *testing.T) {
+	response := &keactrl.Response{
+		Arguments: &map[string]any{
+			"Dhcp4": map[string]any{
+				"lease-database": map[string]any{
+					"type": "memfile",
+					"name": "/tmp/leases4.csv",
+				},
+			},
+		},
+	}
+	path, family, ok := collectKeaLeaseFile(response)
+	require.True(t, ok)
+	require.Equal(t, "/tmp/leases4.csv", path)
+	require.Equal(t, 4, family)
+
+	// Default location.
+	response.Arguments = &map[string]any{
+		"Dhcp6": map[string]any{
+			"lease-database": map[string]any{
+				"type": "memfile",
+			},
+		},
+	}
+	path, family, ok = collectKeaLeaseFile(response)
+	require.True(t, ok)
+	require.Equal(t, defaultLeaseFile6, path)
+	require.Equal(t, 6, family)
+
+	// SQL database.
+	response.Arguments = &map[string]any{
+		"Dhcp6": map[string]any{
+			"lease-database": map[string]any{
+				"type": "postgresql",
+			},
+		},
+	}
+	_, _, ok = collectKeaLeaseFile(response)
+	require.False(t, ok)
+
+	// Not a DHCP server.
+	response.Arguments = &map[string]any{
+		"Control-agent": map[string]any{},
+	}
+	_, _, ok = collectKeaLeaseFile(response)
+	require.False(t, ok)
+
+	// Unsuccessful response.
+	response.Result = keactrl.ResponseError
+	_, _, ok = collectKeaLeaseFile(response)
+	require.False(t, ok)
+}
+
+// Test that the lease file statistics are converted to the gRPC format.
+func TestConvertLeaseFileStatsToProto(t *testing.T) {
+	stats := &leaseFileStats{
+		subnets: map[uint32]*leaseFileSubnetStats{
+			2: {
+				active:     3,
+				states:     map[int]uint64{2: 1, 0: 3},
+				ageBuckets: []uint64{1, 2, 0, 0, 0},
+			},
+			1: {
+				declined:   1,
+				states:     map[int]uint64{1: 1},
+				ageBuckets: []uint64{0, 0, 0, 0, 0},
+			},
+		},
+	}
+	subnets := convertLeaseFileStatsToProto(stats)
+	require.Len(t, subnets, 2)
+	require.EqualValues(t, 1, subnets[0].SubnetID)
+	require.EqualValues(t, 1, subnets[0].Declined)
+	require.EqualValues(t, 2, subnets[1].SubnetID)
+	require.EqualValues(t, 3, subnets[1].Active)
+	require.Len(t, subnets[1].States, 2)
+	require.EqualValues(t, 0, subnets[1].States[0].State)
+	require.EqualValues(t, 3, subnets[1].States[0].Count)
+	require.Equal(t, []int64{1, 2, 0, 0, 0}, subnets[1].AgeBuckets)
+}
+
+// Test that the lease file statistics are added to the Prometheus metrics.
+func TestLeaseFileMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := newLeaseFileMetrics(promauto.With(registry), "dhcp4")
+	stats := &leaseFileStats{
+		subnets: map[uint32]*leaseFileSubnetStats{
+			1: {
+				active:     3,
+				expired:    2,
+				declined:   1,
+				states:     map[int]uint64{0: 4, 1: 1, 2: 1},
+				ageBuckets: []uint64{2, 1, 0, 0, 0},
+			},
+		},
+	}
+
+	metrics.add(stats)
+	metrics.add(stats)
+
+	require.EqualValues(t, 6, testutil.ToFloat64(metrics.Active.WithLabelValues("1")))
+	require.EqualValues(t, 4, testutil.ToFloat64(metrics.Expired.WithLabelValues("1")))
+	require.EqualValues(t, 2, testutil.ToFloat64(metrics.Declined.WithLabelValues("1")))
+	require.EqualValues(t, 8, testutil.ToFloat64(metrics.States.WithLabelValues("1", "0")))
+	require.EqualValues(t, 4, testutil.ToFloat64(metrics.AgeBuckets.WithLabelValues("1", "3600")))
+	require.EqualValues(t, 6, testutil.ToFloat64(metrics.AgeBuckets.WithLabelValues("1", "21600")))
+	require.EqualValues(t, 6, testutil.ToFloat64(metrics.AgeBuckets.WithLabelValues("1", "+Inf")))
+
+	metrics.reset()
+	require.Zero(t, testutil.CollectAndCount(metrics.Active))
+}
+
+// Test getting the lease file statistics over gRPC.
+func TestGetLeaseFileStats(t *testing.T) {
+	sa, ctx := setupAgentTest()
+	sb := storktestutil.NewSandbox()
+	defer sb.Close()
+
+	now := time.Now()
+	path, err := sb.Write("kea-leases4.csv", leaseFile4Header+"\n"+
+		makeLeaseFile4Row("192.0.2.1", 1, 0, 3600, 10*time.Minute, now)+"\n")
+	require.NoError(t, err)
+	missingPath := filepath.Join(sb.BasePath, "kea-leases6.csv")
+
+	defer gock.Off()
+	gock.New("http://localhost:45634").
+		JSON(map[string]any{
+			"command": "config-get",
+			"service": []string{"dhcp4", "dhcp6"},
+		}).
+		Post("/").
+		Reply(200).
+		JSON([]map[string]any{
+			{
+				"result": 0,
+				"arguments": map[string]any{
+					"Dhcp4": map[string]any{
+						"lease-database": map[string]any{"type": "memfile", "name": path},
+					},
+				},
+			},
+			{
+				"result": 0,
+				"arguments": map[string]any{
+					"Dhcp6": map[string]any{
+						"lease-database": map[string]any{"type": "memfile", "name": missingPath},
+					},
+				},
+			},
+		})
+
+	fam, _ := sa.AppMonitor.(*FakeAppMonitor)
+	fam.Apps = append(fam.Apps, &KeaApp{
+		BaseApp: BaseApp{
+			Type:         AppTypeKea,
+			AccessPoints: makeAccessPoint(AccessPointControl, "localhost", "", 45634, false),
+		},
+		ConfiguredDaemons: []string{"ca", "dhcp4", "dhcp6"},
+	})
+
+	rsp, err := sa.GetLeaseFileStats(ctx, &agentapi.GetLeaseFileStatsReq{})
+	require.NoError(t, err)
+	require.Equal(t, agentapi.Status_OK, rsp.Status.Code)
+	require.Equal(t, []int64{3600, 21600, 86400, 604800}, rsp.AgeBounds)
+	require.Len(t, rsp.LeaseFiles, 2)
+
+	leaseFile := rsp.LeaseFiles[0]
+	if leaseFile.Path != path {
+		leaseFile = rsp.LeaseFiles[1]
+	}
+	require.EqualValues(t, 4, leaseFile.Family)
+	require.Empty(t, leaseFile.Error)
+	require.Len(t, leaseFile.Subnets, 1)
+	require.EqualValues(t, 1, leaseFile.Subnets[0].Active)
+
+	leaseFile = rsp.LeaseFiles[0]
+	if leaseFile.Path != missingPath {
+		leaseFile = rsp.LeaseFiles[1]
+	}
+	require.EqualValues(t, 6, leaseFile.Family)
+	require.NotEmpty(t, leaseFile.Error)
+
+	// Kea is not available.
+	rsp, err = sa.GetLeaseFileStats(ctx, &agentapi.GetLeaseFileStatsReq{})
+	require.NoError(t, err)
+	require.Equal(t, agentapi.Status_ERROR, rsp.Status.Code)
+	require.Empty(t, rsp.LeaseFiles)
+}
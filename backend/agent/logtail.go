@@ -2,19 +2,36 @@ package agent
 
 import (
 	"bufio"
+	"context"
 	"io"
 	"os"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+
+	agentapi "isc.org/stork/api"
 )
 
+// Interval between the subsequent reads of the file followed in the
+// follow mode.
+const followInterval = time.Second
+
+// Maximum number of bytes of the followed file read at once. It limits
+// the size of the single response streamed to the server.
+const maxFollowReadSize = 64 * 1024
+
 // Log tailer provides means for viewing log files. It maintains the list of
 // unique files which can be viewed. If the file is not on the list of the allowed
 // files, an error is returned upon an attempt to view it.
 type logTailer struct {
 	allowedPaths map[string]bool
 	mutex        *sync.Mutex
+	// Closed when the agent is shut down to terminate following the files.
+	done     chan bool
+	stopOnce sync.Once
 }
 
 // Creates new instance of the log tailer.
@@ -22,10 +39,18 @@ func newLogTailer() *logTailer {
 	lt := &logTailer{
 		allowedPaths: make(map[string]bool),
 		mutex:        new(sync.Mutex),
+		done:         make(chan bool),
 	}
 	return lt
 }
 
+// Terminates following the files.
+func (lt *logTailer) stop() {
+	lt.stopOnce.Do(func() {
+		close(lt.done)
+	})
+}
+
 // Adds a specified path to the list of files which can be viewed.
 func (lt *logTailer) allow(path string) {
 	lt.mutex.Lock()
@@ -87,3 +112,181 @@ func (lt *logTailer) tail(path string, offset int64) (lines []string, err error)
 	}
 	return lines, err
 }
+
+// Streams the lines of the file specified in the request using the send
+// function. The lines are streamed from the position specified in the
+// request or, if it is not specified, from the offset counted from the end
+// of the file. The optional filter selects the streamed lines. In the follow
+// mode, the function waits for the new lines appended to the file, also
+// after the file rotation, until the context is cancelled or the tailer is
+// stopped. Otherwise, it returns after reaching the end of the file. The
+// first response is sent even if there are no lines to return, so the
+// receiver learns the position in the file. If the file is not allowed or
+// the filter is invalid an error is returned.
+func (lt *logTailer) follow(ctx context.Context, request *agentapi.FollowTextFileReq, send func(*agentapi.FollowTextFileRsp) error) error {
+	if !lt.allowed(request.Path) {
+		return errors.Errorf("access forbidden to the %s", request.Path)
+	}
+
+	var filter *regexp.Regexp
+	if len(request.Filter) > 0 {
+		var err error
+		filter, err = regexp.Compile(request.Filter)
+		if err != nil {
+			return errors.Wrapf(err, "invalid filter %s", request.Filter)
+		}
+	}
+
+	file := &followedFile{
+		offset: request.Position,
+	}
+	if request.Position <= 0 {
+		stat, err := os.Stat(request.Path)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to stat the file opened for following: %s", request.Path)
+		}
+		// Can't go beyond the file size.
+		file.offset = stat.Size() - request.Offset
+		if file.offset < 0 {
+			file.offset = 0
+		}
+	}
+
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+
+	first := true
+	for {
+		lines, rotated, err := readFollowedFile(request.Path, file, maxFollowReadSize)
+		if err != nil {
+			// The file may be missing for a moment when it is rotated.
+			if !request.Follow || !errors.Is(err, os.ErrNotExist) {
+				return errors.WithMessagef(err, "failed to read the followed file: %s", request.Path)
+			}
+		}
+		eof := err != nil || file.offset >= file.info.Size()
+
+		// The last line may not be terminated with the new line character.
+		if eof && !request.Follow && len(file.partial) > 0 {
+			lines = append(lines, file.partial)
+			file.partial = ""
+		}
+
+		if filter != nil {
+			var filtered []string
+			for _, line := range lines {
+				if filter.MatchString(line) {
+					filtered = append(filtered, line)
+				}
+			}
+			lines = filtered
+		}
+
+		if len(lines) > 0 || rotated || first {
+			first = false
+			err = send(&agentapi.FollowTextFileRsp{
+				Status: &agentapi.Status{
+					Code: agentapi.Status_OK,
+				},
+				Lines:    lines,
+				Position: file.position(),
+				Rotated:  rotated,
+			})
+			if err != nil {
+				return errors.WithMessage(err, "failed to send the lines of the followed file")
+			}
+		}
+
+		// Read the remaining data without waiting.
+		if !eof {
+			continue
+		}
+		if !request.Follow {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		case <-lt.done:
+			return nil
+		}
+	}
+}
+
+// State of the file followed like with tail -f.
+type followedFile struct {
+	// File info returned in the previous read. It is used to detect the
+	// file rotation. It is nil until the file is read for the first time.
+	info os.FileInfo
+	// Offset in the file from which the next read starts.
+	offset int64
+	// Incomplete last line read in the previous read.
+	partial string
+}
+
+// Sets the offset to the end of the file, so only the data appended later
+// are read.
+func (file *followedFile) seekEnd(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	file.info = info
+	file.offset = info.Size()
+	file.partial = ""
+	return nil
+}
+
+// Returns the offset in the file following the last complete line read.
+func (file *followedFile) position() int64 {
+	return file.offset - int64(len(file.partial))
+}
+
+// Reads the complete lines appended to the followed file since the previous
+// read, up to the specified number of bytes. The remaining data are read in
+// the next reads. If the file was rotated or truncated, it is read from the
+// beginning and the returned flag is set. The lines appended to the rotated
+// file after the last read are lost. The overly long lines are dropped.
+func readFollowedFile(path string, file *followedFile, maxSize int64) (lines []string, rotated bool, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	if (file.info != nil && !os.SameFile(file.info, info)) || info.Size() < file.offset {
+		file.offset = 0
+		file.partial = ""
+		rotated = true
+	}
+	file.info = info
+	if info.Size() == file.offset {
+		return nil, rotated, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, rotated, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	if _, err = f.Seek(file.offset, io.SeekStart); err != nil {
+		return nil, rotated, errors.WithStack(err)
+	}
+	size := info.Size() - file.offset
+	if size > maxSize {
+		size = maxSize
+	}
+	buffer := make([]byte, size)
+	n, err := io.ReadFull(f, buffer)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, rotated, errors.WithStack(err)
+	}
+	file.offset += int64(n)
+
+	lines = strings.Split(file.partial+string(buffer[:n]), "\n")
+	file.partial = lines[len(lines)-1]
+	if int64(len(file.partial)) > maxSize {
+		file.partial = ""
+	}
+	return lines[:len(lines)-1], rotated, nil
+}
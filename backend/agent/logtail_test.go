@@ -1,12 +1,18 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	agentapi "isc.org/stork/api"
+	"isc.org/stork/testutil"
 )
 
 // Test that the new instance of the log tailer can be created and that
@@ -62,3 +68,278 @@ func TestTailNotExistingFile(t *testing.T) {
 	_, err := lt.tail("non-existing-file", 100)
 	require.Error(t, err)
 }
+
+// Test reading the lines appended to the followed file.
+func TestReadFollowedFile(t *testing.T) {
+	sb := testutil.NewSandbox()
+	defer sb.Close()
+
+	path, err := sb.Write("kea.log", "line1\nline2\n")
+	require.NoError(t, err)
+
+	// Start from the second line.
+	file := &followedFile{offset: 6}
+	lines, rotated, err := readFollowedFile(path, file, 1024)
+	require.NoError(t, err)
+	require.False(t, rotated)
+	require.Equal(t, []string{"line2"}, lines)
+	require.EqualValues(t, 12, file.position())
+
+	// Nothing new.
+	lines, rotated, err = readFollowedFile(path, file, 1024)
+	require.NoError(t, err)
+	require.False(t, rotated)
+	require.Empty(t, lines)
+
+	// The incomplete line is returned when it is completed.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString("line3\nli")
+	require.NoError(t, err)
+	lines, _, err = readFollowedFile(path, file, 1024)
+	require.NoError(t, err)
+	require.Equal(t, []string{"line3"}, lines)
+	require.EqualValues(t, 18, file.position())
+	require.EqualValues(t, 20, file.offset)
+
+	_, err = f.WriteString("ne4\n")
+	require.NoError(t, err)
+	f.Close()
+	lines, _, err = readFollowedFile(path, file, 1024)
+	require.NoError(t, err)
+	require.Equal(t, []string{"line4"}, lines)
+
+	// The size of the data read at once is limited.
+	file = &followedFile{}
+	lines, _, err = readFollowedFile(path, file, 8)
+	require.NoError(t, err)
+	require.Equal(t, []string{"line1"}, lines)
+	lines, _, err = readFollowedFile(path, file, 8)
+	require.NoError(t, err)
+	require.Equal(t, []string{"line2"}, lines)
+
+	// The truncated file is read from the beginning.
+	_, err = sb.Write("kea.log", "line5\n")
+	require.NoError(t, err)
+	lines, rotated, err = readFollowedFile(path, file, 1024)
+	require.NoError(t, err)
+	require.True(t, rotated)
+	require.Equal(t, []string{"line5"}, lines)
+
+	// The rotated file is read from the beginning.
+	err = os.Rename(path, path+".1")
+	require.NoError(t, err)
+	_, err = sb.Write("kea.log", "line6\nline7\nline8\n")
+	require.NoError(t, err)
+	lines, rotated, err = readFollowedFile(path, file, 1024)
+	require.NoError(t, err)
+	require.True(t, rotated)
+	require.Equal(t, []string{"line6", "line7", "line8"}, lines)
+
+	// Reading the missing file fails.
+	_, _, err = readFollowedFile(filepath.Join(sb.BasePath, "missing.log"), &followedFile{}, 1024)
+	require.Error(t, err)
+}
+
+// Test that seeking the end of the followed file skips the existing lines.
+func TestFollowedFileSeekEnd(t *testing.T) {
+	sb := testutil.NewSandbox()
+	defer sb.Close()
+
+	path, err := sb.Write("kea.log", "line1\n")
+	require.NoError(t, err)
+
+	file := &followedFile{}
+	err = file.seekEnd(path)
+	require.NoError(t, err)
+	require.EqualValues(t, 6, file.position())
+
+	lines, rotated, err := readFollowedFile(path, file, 1024)
+	require.NoError(t, err)
+	require.False(t, rotated)
+	require.Empty(t, lines)
+
+	err = file.seekEnd(filepath.Join(sb.BasePath, "missing.log"))
+	require.Error(t, err)
+}
+
+// Test streaming the lines of the file without following it.
+func TestFollow(t *testing.T) {
+	sb := testutil.NewSandbox()
+	defer sb.Close()
+
+	path, err := sb.Write("kea.log", "line1\nfoo2\nline3\nfoo4")
+	require.NoError(t, err)
+
+	lt := newLogTailer()
+	lt.allow(path)
+
+	var responses []*agentapi.FollowTextFileRsp
+	send := func(rsp *agentapi.FollowTextFileRsp) error {
+		responses = append(responses, rsp)
+		return nil
+	}
+
+	// The offset is counted from the end of the file. The last line is
+	// returned although it is not terminated.
+	err = lt.follow(context.Background(), &agentapi.FollowTextFileReq{
+		Path:   path,
+		Offset: 10,
+	}, send)
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	require.Equal(t, agentapi.Status_OK, responses[0].Status.Code)
+	require.Equal(t, []string{"line3", "foo4"}, responses[0].Lines)
+	require.EqualValues(t, 21, responses[0].Position)
+	require.False(t, responses[0].Rotated)
+
+	// The position takes precedence over the offset.
+	responses = nil
+	err = lt.follow(context.Background(), &agentapi.FollowTextFileReq{
+		Path:     path,
+		Offset:   10,
+		Position: 6,
+		Filter:   "^foo",
+	}, send)
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	require.Equal(t, []string{"foo2", "foo4"}, responses[0].Lines)
+
+	// The first response is sent even if no lines match the filter.
+	responses = nil
+	err = lt.follow(context.Background(), &agentapi.FollowTextFileReq{
+		Path:   path,
+		Filter: "bar",
+	}, send)
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	require.Empty(t, responses[0].Lines)
+	require.EqualValues(t, 21, responses[0].Position)
+
+	// The position beyond the end of the file indicates that the file
+	// was truncated since the last read.
+	responses = nil
+	err = lt.follow(context.Background(), &agentapi.FollowTextFileReq{
+		Path:     path,
+		Position: 100,
+	}, send)
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	require.True(t, responses[0].Rotated)
+	require.Len(t, responses[0].Lines, 4)
+}
+
+// Test that following the file fails when the file is not allowed or the
+// filter is invalid.
+func TestFollowError(t *testing.T) {
+	sb := testutil.NewSandbox()
+	defer sb.Close()
+
+	path, err := sb.Write("kea.log", "line1\n")
+	require.NoError(t, err)
+
+	lt := newLogTailer()
+	send := func(rsp *agentapi.FollowTextFileRsp) error {
+		return nil
+	}
+
+	err = lt.follow(context.Background(), &agentapi.FollowTextFileReq{Path: path}, send)
+	require.ErrorContains(t, err, "access forbidden")
+
+	lt.allow(path)
+	err = lt.follow(context.Background(), &agentapi.FollowTextFileReq{Path: path, Filter: "("}, send)
+	require.ErrorContains(t, err, "invalid filter")
+
+	missing := filepath.Join(sb.BasePath, "missing.log")
+	lt.allow(missing)
+	err = lt.follow(context.Background(), &agentapi.FollowTextFileReq{Path: missing}, send)
+	require.Error(t, err)
+}
+
+// Test that the lines appended to the followed file are streamed until
+// the context is cancelled.
+func TestFollowAppendedLines(t *testing.T) {
+	sb := testutil.NewSandbox()
+	defer sb.Close()
+
+	path, err := sb.Write("kea.log", "line1\n")
+	require.NoError(t, err)
+
+	lt := newLogTailer()
+	lt.allow(path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	responses := make(chan *agentapi.FollowTextFileRsp, 10)
+	done := make(chan error)
+	go func() {
+		done <- lt.follow(ctx, &agentapi.FollowTextFileReq{
+			Path:     path,
+			Position: 6,
+			Follow:   true,
+		}, func(rsp *agentapi.FollowTextFileRsp) error {
+			responses <- rsp
+			return nil
+		})
+	}()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString("line2\n")
+	require.NoError(t, err)
+	f.Close()
+
+	// The first response may be sent before the line is appended.
+	var lines []string
+	for len(lines) == 0 {
+		select {
+		case rsp := <-responses:
+			lines = rsp.Lines
+			require.EqualValues(t, 6+6*len(lines), rsp.Position)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no lines received from the followed file")
+		}
+	}
+	require.Equal(t, []string{"line2"}, lines)
+
+	cancel()
+	select {
+	case err = <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "following the file has not been stopped")
+	}
+}
+
+// Test that stopping the tailer terminates following the files.
+func TestFollowStop(t *testing.T) {
+	sb := testutil.NewSandbox()
+	defer sb.Close()
+
+	path, err := sb.Write("kea.log", "line1\n")
+	require.NoError(t, err)
+
+	lt := newLogTailer()
+	lt.allow(path)
+
+	done := make(chan error)
+	go func() {
+		done <- lt.follow(context.Background(), &agentapi.FollowTextFileReq{
+			Path:   path,
+			Follow: true,
+		}, func(rsp *agentapi.FollowTextFileRsp) error {
+			return nil
+		})
+	}()
+
+	lt.stop()
+	require.NotPanics(t, lt.stop)
+
+	select {
+	case err = <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "following the file has not been stopped")
+	}
+}
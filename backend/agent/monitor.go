@@ -262,7 +262,8 @@ func (sm *appMonitor) detectApps(storkAgent *StorkAgent) {
 }
 
 // Gathers the configured log files for detected apps and enables them
-// for viewing from the UI.
+// for viewing from the UI. The Kea log files are also followed for the
+// notable messages.
 func (sm *appMonitor) detectAllowedLogs(storkAgent *StorkAgent) {
 	// Nothing to do if the agent is not set. It may be nil when running some
 	// tests.
@@ -283,6 +284,9 @@ func (sm *appMonitor) detectAllowedLogs(storkAgent *StorkAgent) {
 		} else {
 			for _, p := range paths {
 				storkAgent.logTailer.allow(p)
+				if app.GetBaseApp().Type == AppTypeKea {
+					storkAgent.keaLogEvents.follow(p)
+				}
 			}
 		}
 	}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -337,9 +338,110 @@ type PromKeaExporter struct {
 	Global4StatMap map[string]prometheus.Gauge
 	Global6StatMap map[string]prometheus.Gauge
 
+	// Metrics computed from the memfile lease files. They are nil if
+	// collecting them is disabled.
+	LeaseFile4Metrics *leaseFileMetrics
+	LeaseFile6Metrics *leaseFileMetrics
+
 	// Set of the ignored stats as they are estimated by summing sub-stats
 	// (like ack, nak, etc) or not-supported.
 	ignoredStats map[string]bool
+
+	// Lease file locations detected for the Kea apps by the Kea CA URLs.
+	leaseFiles map[string]*detectedLeaseFiles
+}
+
+// Interval between the subsequent detections of the lease file locations
+// in the Kea servers' configurations.
+const leaseFileDetectionInterval = 5 * time.Minute
+
+// Lease file locations detected for a Kea app.
+type detectedLeaseFiles struct {
+	// Lease file locations mapped to the IP families.
+	files map[string]int
+	// Time of the detection.
+	detectedAt time.Time
+}
+
+// Metrics of a single IP family computed from the memfile lease files.
+type leaseFileMetrics struct {
+	Active     *prometheus.GaugeVec
+	Expired    *prometheus.GaugeVec
+	Declined   *prometheus.GaugeVec
+	States     *prometheus.GaugeVec
+	AgeBuckets *prometheus.GaugeVec
+	collectors []prometheus.Collector
+}
+
+// Creates the lease file metrics for the given DHCP subsystem (dhcp4 or dhcp6).
+func newLeaseFileMetrics(factory promauto.Factory, subsystem string) *leaseFileMetrics {
+	m := &leaseFileMetrics{
+		Active: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: AppTypeKea,
+			Subsystem: subsystem,
+			Name:      "lease_file_active_leases",
+			Help:      "Active leases in the lease file",
+		}, []string{"subnet"}),
+		Expired: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: AppTypeKea,
+			Subsystem: subsystem,
+			Name:      "lease_file_expired_leases",
+			Help:      "Expired leases in the lease file",
+		}, []string{"subnet"}),
+		Declined: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: AppTypeKea,
+			Subsystem: subsystem,
+			Name:      "lease_file_declined_leases",
+			Help:      "Declined leases in the lease file",
+		}, []string{"subnet"}),
+		States: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: AppTypeKea,
+			Subsystem: subsystem,
+			Name:      "lease_file_leases_by_state",
+			Help:      "Leases in the lease file by the lease state",
+		}, []string{"subnet", "state"}),
+		AgeBuckets: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: AppTypeKea,
+			Subsystem: subsystem,
+			Name:      "lease_file_active_leases_by_age",
+			Help:      "Active leases in the lease file not older than 'le' seconds since the last client contact",
+		}, []string{"subnet", "le"}),
+	}
+	m.collectors = []prometheus.Collector{m.Active, m.Expired, m.Declined, m.States, m.AgeBuckets}
+	return m
+}
+
+// Removes all values of the metrics.
+func (m *leaseFileMetrics) reset() {
+	m.Active.Reset()
+	m.Expired.Reset()
+	m.Declined.Reset()
+	m.States.Reset()
+	m.AgeBuckets.Reset()
+}
+
+// Adds the statistics from the lease file to the metrics. The statistics
+// of the subnets having the same ID in different lease files are summed.
+// The age buckets are reported as cumulative counts.
+func (m *leaseFileMetrics) add(stats *leaseFileStats) {
+	for id, subnetStats := range stats.subnets {
+		subnet := fmt.Sprint(id)
+		m.Active.With(prometheus.Labels{"subnet": subnet}).Add(float64(subnetStats.active))
+		m.Expired.With(prometheus.Labels{"subnet": subnet}).Add(float64(subnetStats.expired))
+		m.Declined.With(prometheus.Labels{"subnet": subnet}).Add(float64(subnetStats.declined))
+		for state, count := range subnetStats.states {
+			m.States.With(prometheus.Labels{"subnet": subnet, "state": fmt.Sprint(state)}).Add(float64(count))
+		}
+		var cumulative uint64
+		for i, count := range subnetStats.ageBuckets {
+			cumulative += count
+			le := "+Inf"
+			if i < len(leaseAgeBounds) {
+				le = fmt.Sprint(leaseAgeBounds[i].Seconds())
+			}
+			m.AgeBuckets.With(prometheus.Labels{"subnet": subnet, "le": le}).Add(float64(cumulative))
+		}
+	}
 }
 
 // Create new Prometheus Kea Exporter.
@@ -362,6 +464,7 @@ func NewPromKeaExporter(settings *cli.Context, appMonitor AppMonitor, httpClient
 			"pkt6-received": true,
 			"pkt6-sent":     true,
 		},
+		leaseFiles: make(map[string]*detectedLeaseFiles),
 	}
 
 	factory := promauto.With(pke.Registry)
@@ -615,6 +718,13 @@ func NewPromKeaExporter(settings *cli.Context, appMonitor AppMonitor, httpClient
 		pke.Adr6StatsMap = adr6StatsMap
 	}
 
+	// Collecting the lease file stats is enabled by default. It can be explicitly disabled.
+	enableLeaseFileStatsFlag := "prometheus-kea-exporter-lease-file-stats"
+	if !settings.IsSet(enableLeaseFileStatsFlag) || settings.Bool(enableLeaseFileStatsFlag) {
+		pke.LeaseFile4Metrics = newLeaseFileMetrics(factory, "dhcp4")
+		pke.LeaseFile6Metrics = newLeaseFileMetrics(factory, "dhcp6")
+	}
+
 	// prepare http handler
 	mux := http.NewServeMux()
 	handler := promhttp.HandlerFor(pke.Registry, promhttp.HandlerOpts{})
@@ -696,6 +806,14 @@ func (pke *PromKeaExporter) Shutdown() {
 	for _, stat := range pke.Global6StatMap {
 		pke.Registry.Unregister(stat)
 	}
+	for _, metrics := range []*leaseFileMetrics{pke.LeaseFile4Metrics, pke.LeaseFile6Metrics} {
+		if metrics == nil {
+			continue
+		}
+		for _, collector := range metrics.collectors {
+			pke.Registry.Unregister(collector)
+		}
+	}
 
 	log.Printf("Stopped Prometheus Kea Exporter")
 }
@@ -794,6 +912,14 @@ func (pke *PromKeaExporter) collectStats() error {
 		"dhcp6": true,
 	}
 
+	// The lease file metrics are summed over all apps, so they must be
+	// cleared first.
+	now := time.Now()
+	if pke.LeaseFile4Metrics != nil {
+		pke.LeaseFile4Metrics.reset()
+		pke.LeaseFile6Metrics.reset()
+	}
+
 	// Go through all kea apps discovered by monitor and query them for stats.
 	apps := pke.AppMonitor.GetApps()
 	for _, app := range apps {
@@ -826,6 +952,14 @@ func (pke *PromKeaExporter) collectStats() error {
 			continue
 		}
 
+		// The lease files are read independently of the statistics
+		// returned by Kea.
+		if pke.LeaseFile4Metrics != nil {
+			if err = pke.collectLeaseFileStats(ctrl, app, now); err != nil {
+				lastErr = err
+			}
+		}
+
 		requestDataBytes, err := json.Marshal(requestData)
 		if err != nil {
 			err = errors.Wrap(err, "cannot serialize a request to JSON")
@@ -870,6 +1004,52 @@ func (pke *PromKeaExporter) collectStats() error {
 	return lastErr
 }
 
+// Computes the metrics from the memfile lease files of the Kea app. The
+// lease file locations are detected in the Kea servers' configurations.
+// They are cached and detected again after an interval to avoid loading
+// the Kea control channel on every collection.
+func (pke *PromKeaExporter) collectLeaseFileStats(ctrl *AccessPoint, app App, now time.Time) error {
+	caURL := storkutil.HostWithPortURL(ctrl.Address, ctrl.Port, ctrl.UseSecureProtocol)
+	detected, ok := pke.leaseFiles[caURL]
+	if !ok || now.Sub(detected.detectedAt) >= leaseFileDetectionInterval {
+		files, err := detectKeaLeaseFiles(pke, app)
+		if err != nil {
+			log.WithError(err).Warn("Problem detecting the Kea lease files")
+			// Use the previously detected files if any.
+			if ok {
+				files = detected.files
+			}
+		}
+		detected = &detectedLeaseFiles{
+			files:      files,
+			detectedAt: now,
+		}
+		pke.leaseFiles[caURL] = detected
+	}
+
+	var lastErr error
+	for path, family := range detected.files {
+		stats, err := collectLeaseFileStats(path, family, now)
+		if err != nil {
+			// The lease file may not exist when the leases are not
+			// persisted.
+			if errors.Is(err, os.ErrNotExist) {
+				log.WithError(err).Debug("Kea lease file not found")
+				continue
+			}
+			lastErr = err
+			log.WithError(err).Error("Problem collecting stats from the Kea lease file")
+			continue
+		}
+		if family == 4 {
+			pke.LeaseFile4Metrics.add(stats)
+		} else {
+			pke.LeaseFile6Metrics.add(stats)
+		}
+	}
+	return lastErr
+}
+
 // Send any command to Kea CA and returns body content.
 func (pke *PromKeaExporter) sendCommandToKeaCA(ctrl *AccessPoint, request string) ([]byte, error) {
 	caURL := storkutil.HostWithPortURL(ctrl.Address, ctrl.Port, ctrl.UseSecureProtocol)
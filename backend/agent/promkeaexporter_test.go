@@ -32,6 +32,23 @@ func newFakeMonitorWithDefaults() *FakeAppMonitor {
 	return fam
 }
 
+// Mocks the response to the config-get command sent to detect the lease
+// files. The servers don't use the memfile lease database.
+func mockLeaseFileDetection() {
+	gock.New("http://0.1.2.3:1234/").
+		JSON(map[string]interface{}{
+			"command": "config-get",
+			"service": []string{"dhcp4", "dhcp6"},
+		}).
+		Post("/").
+		Persist().
+		Reply(200).
+		BodyString(`[
+			{ "result": 0, "arguments": { "Dhcp4": { "lease-database": { "type": "mysql" } } } },
+			{ "result": 0, "arguments": { "Dhcp6": { "lease-database": { "type": "mysql" } } } }
+		]`)
+}
+
 // Check creating PromKeaExporter, check if prometheus stats are set up.
 func TestNewPromKeaExporterBasic(t *testing.T) {
 	fam := newFakeMonitorWithDefaults()
@@ -69,6 +86,7 @@ func TestPromKeaExporterStart(t *testing.T) {
                     "pkt4-nak-received": [ [ 19, "2019-07-30 10:04:28.386733" ] ]
             }
 		}]`)
+	mockLeaseFileDetection()
 
 	fam := newFakeMonitorWithDefaults()
 	flags := flag.NewFlagSet("test", 0)
@@ -450,6 +468,7 @@ func TestDisablePerSubnetStatsCollecting(t *testing.T) {
                     "subnet[7].assigned-addresses": [ [ 13, "2019-07-30 10:04:28.386740" ] ],
                     "pkt4-nak-received": [ [ 19, "2019-07-30 10:04:28.386733" ] ]
                 }}]`)
+	mockLeaseFileDetection()
 
 	fam := newFakeMonitorWithDefaults()
 
@@ -1,9 +1,11 @@
 package keaconfig
 
 // Represents a client class in Kea configuration.
-// todo: it currently only contains class name because it is
-// all we need for current use cases. It will have extra fields
-// when we need them.
+// todo: it currently only contains the class name, test expression and
+// option data because it is all we need for current use cases. It will
+// have extra fields when we need them.
 type ClientClass struct {
-	Name string `json:"name"`
+	Name       string             `json:"name"`
+	Test       string             `json:"test,omitempty"`
+	OptionData []SingleOptionData `json:"option-data,omitempty"`
 }
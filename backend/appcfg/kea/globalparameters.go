@@ -0,0 +1,138 @@
+package keaconfig
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Represents the global DHCP configuration parameters that can be edited
+// by Stork. They are applied to the Kea servers by replacing the respective
+// parameters in the entire server's configuration and sending it with the
+// config-set command. The client classes are held in the raw form because
+// the ClientClass structure lacks most of the client class parameters.
+// Holding them as maps guarantees that these parameters are not lost
+// when the configuration is modified.
+type SettableGlobalParameters struct {
+	DDNSParameters
+	PreferredLifetimeParameters
+	TimerParameters
+	ValidLifetimeParameters
+	OptionData    []SingleOptionData `json:"option-data,omitempty"`
+	ClientClasses []map[string]any   `json:"client-classes,omitempty"`
+}
+
+// Returns the names of the configuration parameters (JSON keys) that can be
+// edited using the SettableGlobalParameters structure.
+func GetSettableGlobalParameterNames() []string {
+	return getJSONFieldNames(reflect.TypeOf(SettableGlobalParameters{}))
+}
+
+// Recursively collects the JSON field names from the structure, including
+// the names of the fields belonging to the embedded structures.
+func getJSONFieldNames(t reflect.Type) (names []string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			names = append(names, getJSONFieldNames(field.Type)...)
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// Returns the name of the top-level DHCP configuration key (i.e., Dhcp4 or
+// Dhcp6). It returns an empty string if the configuration does not belong
+// to a DHCP server.
+func (c *Config) getDHCPRootName() string {
+	switch {
+	case c.IsDHCPv4():
+		return "Dhcp4"
+	case c.IsDHCPv6():
+		return "Dhcp6"
+	default:
+		return ""
+	}
+}
+
+// Returns the global DHCP parameters that can be edited by Stork. It returns
+// nil if the configuration does not belong to a DHCP server.
+func (c *Config) GetSettableGlobalParameters() *SettableGlobalParameters {
+	rootName := c.getDHCPRootName()
+	if rootName == "" {
+		return nil
+	}
+	params := &SettableGlobalParameters{
+		DDNSParameters:              c.GetDDNSParameters(),
+		PreferredLifetimeParameters: c.GetPreferredLifetimeParameters(),
+		TimerParameters:             c.GetTimerParameters(),
+		ValidLifetimeParameters:     c.GetValidLifetimeParameters(),
+		OptionData:                  c.GetDHCPOptions(),
+	}
+	if root, ok := c.Raw[rootName].(map[string]any); ok {
+		if classes, ok := root["client-classes"].([]any); ok {
+			for _, class := range classes {
+				if classMap, ok := class.(map[string]any); ok {
+					params.ClientClasses = append(params.ClientClasses, classMap)
+				}
+			}
+		}
+	}
+	return params
+}
+
+// Replaces the global DHCP parameters in the configuration with the specified
+// ones. All parameters that can be edited with the SettableGlobalParameters
+// are first removed from the configuration. Next, the non-empty parameters
+// from the specified structure are inserted. It means that unspecified
+// parameters are removed from the configuration and the Kea server will use
+// their default values. The function modifies the raw configuration and
+// re-parses it to update the typed configuration structures. It returns an
+// error if the configuration does not belong to a DHCP server or when the
+// preferred lifetime parameters are specified for a DHCPv4 server.
+func (c *Config) SetGlobalParameters(params *SettableGlobalParameters) error {
+	rootName := c.getDHCPRootName()
+	if rootName == "" {
+		return errors.New("global parameters can only be set for a DHCP server configuration")
+	}
+	if c.IsDHCPv4() && (params.PreferredLifetime != nil || params.MinPreferredLifetime != nil || params.MaxPreferredLifetime != nil) {
+		return errors.New("preferred lifetime parameters cannot be set for a DHCPv4 server")
+	}
+	root, ok := c.Raw[rootName].(map[string]any)
+	if !ok {
+		return errors.Errorf("%s configuration has invalid format", rootName)
+	}
+	// Convert the parameters to a map so they can be merged with the
+	// raw configuration.
+	marshalled, err := json.Marshal(params)
+	if err != nil {
+		return errors.Wrapf(err, "problem marshalling global parameters")
+	}
+	var values map[string]any
+	if err = json.Unmarshal(marshalled, &values); err != nil {
+		return errors.Wrapf(err, "problem unmarshalling global parameters")
+	}
+	for _, name := range GetSettableGlobalParameterNames() {
+		delete(root, name)
+	}
+	for name, value := range values {
+		root[name] = value
+	}
+	// Parse the modified configuration to update the typed structures.
+	marshalled, err = json.Marshal(c.Raw)
+	if err != nil {
+		return errors.Wrapf(err, "problem marshalling modified %s configuration", rootName)
+	}
+	var updated Config
+	if err = json.Unmarshal(marshalled, &updated); err != nil {
+		return errors.Wrapf(err, "problem parsing modified %s configuration", rootName)
+	}
+	*c = updated
+	return nil
+}
@@ -0,0 +1,178 @@
+package keaconfig_test
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
+)
+
+// Test that the names of the settable global parameters are returned.
+func TestGetSettableGlobalParameterNames(t *testing.T) {
+	names := keaconfig.GetSettableGlobalParameterNames()
+	require.Contains(t, names, "ddns-send-updates")
+	require.Contains(t, names, "ddns-ttl-percent")
+	require.Contains(t, names, "preferred-lifetime")
+	require.Contains(t, names, "renew-timer")
+	require.Contains(t, names, "calculate-tee-times")
+	require.Contains(t, names, "valid-lifetime")
+	require.Contains(t, names, "max-valid-lifetime")
+	require.Contains(t, names, "option-data")
+	require.Contains(t, names, "client-classes")
+	require.NotContains(t, names, "subnet4")
+}
+
+// Test getting the settable global parameters from the DHCPv4 server
+// configuration.
+func TestGetSettableGlobalParameters4(t *testing.T) {
+	cfg, err := keaconfig.NewConfig(`{
+		"Dhcp4": {
+			"valid-lifetime": 3600,
+			"renew-timer": 900,
+			"ddns-send-updates": true,
+			"option-data": [
+				{
+					"code": 6,
+					"data": "192.0.2.1"
+				}
+			],
+			"client-classes": [
+				{
+					"name": "foo",
+					"test": "member('ALL')"
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	params := cfg.GetSettableGlobalParameters()
+	require.NotNil(t, params)
+	require.EqualValues(t, 3600, *params.ValidLifetime)
+	require.EqualValues(t, 900, *params.RenewTimer)
+	require.Nil(t, params.RebindTimer)
+	require.True(t, *params.DDNSSendUpdates)
+	require.Len(t, params.OptionData, 1)
+	require.EqualValues(t, 6, params.OptionData[0].Code)
+	require.Len(t, params.ClientClasses, 1)
+	require.Equal(t, "foo", params.ClientClasses[0]["name"])
+	require.Equal(t, "member('ALL')", params.ClientClasses[0]["test"])
+}
+
+// Test that no settable global parameters are returned for a non-DHCP
+// server configuration.
+func TestGetSettableGlobalParametersNonDHCP(t *testing.T) {
+	cfg, err := keaconfig.NewConfig(`{
+		"Control-agent": {
+			"http-host": "10.20.30.40"
+		}
+	}`)
+	require.NoError(t, err)
+	require.Nil(t, cfg.GetSettableGlobalParameters())
+}
+
+// Test replacing the global parameters in the DHCPv6 server configuration.
+func TestSetGlobalParameters6(t *testing.T) {
+	cfg, err := keaconfig.NewConfig(`{
+		"Dhcp6": {
+			"valid-lifetime": 3600,
+			"preferred-lifetime": 1800,
+			"rebind-timer": 1200,
+			"ddns-send-updates": true,
+			"client-classes": [
+				{
+					"name": "foo"
+				}
+			],
+			"subnet6": [
+				{
+					"id": 1,
+					"subnet": "2001:db8:1::/64"
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	params := &keaconfig.SettableGlobalParameters{
+		PreferredLifetimeParameters: keaconfig.PreferredLifetimeParameters{
+			PreferredLifetime: ptr(int64(2000)),
+		},
+		ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+			ValidLifetime: ptr(int64(4000)),
+		},
+		TimerParameters: keaconfig.TimerParameters{
+			CalculateTeeTimes: ptr(true),
+		},
+		OptionData: []keaconfig.SingleOptionData{
+			{
+				Code: 23,
+				Data: "2001:db8:1::1",
+			},
+		},
+		ClientClasses: []map[string]any{
+			{
+				"name": "bar",
+				"test": "member('ALL')",
+			},
+		},
+	}
+	err = cfg.SetGlobalParameters(params)
+	require.NoError(t, err)
+
+	// Typed configuration should be updated.
+	require.EqualValues(t, 2000, *cfg.GetPreferredLifetimeParameters().PreferredLifetime)
+	require.EqualValues(t, 4000, *cfg.GetValidLifetimeParameters().ValidLifetime)
+	require.True(t, *cfg.GetTimerParameters().CalculateTeeTimes)
+	require.Nil(t, cfg.GetTimerParameters().RebindTimer)
+	require.Nil(t, cfg.GetDDNSParameters().DDNSSendUpdates)
+	require.Len(t, cfg.GetDHCPOptions(), 1)
+	require.Len(t, cfg.GetClientClasses(), 1)
+	require.Equal(t, "bar", cfg.GetClientClasses()[0].Name)
+
+	// Raw configuration should be updated too and the other parameters
+	// should be preserved.
+	root, ok := cfg.Raw["Dhcp6"].(map[string]any)
+	require.True(t, ok)
+	require.NotContains(t, root, "rebind-timer")
+	require.NotContains(t, root, "ddns-send-updates")
+	require.Contains(t, root, "option-data")
+	require.Len(t, cfg.GetSubnets(), 1)
+
+	// Get the parameters back and compare.
+	returned := cfg.GetSettableGlobalParameters()
+	require.NotNil(t, returned)
+	require.Equal(t, "member('ALL')", returned.ClientClasses[0]["test"])
+	require.EqualValues(t, 2000, *returned.PreferredLifetime)
+}
+
+// Test that the preferred lifetime cannot be set for the DHCPv4 server.
+func TestSetGlobalParameters4PreferredLifetime(t *testing.T) {
+	cfg, err := keaconfig.NewConfig(`{
+		"Dhcp4": {
+			"valid-lifetime": 3600
+		}
+	}`)
+	require.NoError(t, err)
+
+	params := &keaconfig.SettableGlobalParameters{
+		PreferredLifetimeParameters: keaconfig.PreferredLifetimeParameters{
+			PreferredLifetime: ptr(int64(2000)),
+		},
+	}
+	require.Error(t, cfg.SetGlobalParameters(params))
+
+	// The configuration should remain unchanged.
+	require.EqualValues(t, 3600, *cfg.GetValidLifetimeParameters().ValidLifetime)
+}
+
+// Test that the global parameters cannot be set for a non-DHCP server.
+func TestSetGlobalParametersNonDHCP(t *testing.T) {
+	cfg, err := keaconfig.NewConfig(`{
+		"DhcpDdns": {
+			"ip-address": "127.0.0.1"
+		}
+	}`)
+	require.NoError(t, err)
+	require.Error(t, cfg.SetGlobalParameters(&keaconfig.SettableGlobalParameters{}))
+}
@@ -1,5 +1,10 @@
 package keaconfig
 
+import (
+	"sort"
+	"strings"
+)
+
 // A structure reflecting an array of high availability configurations
 // for a Kea server. It is a top level HA library configuration.
 type HALibraryParams struct {
@@ -38,10 +43,10 @@ type Peer struct {
 	AutoFailover *bool   `json:"auto-failover"`
 }
 
-// Convenience function returning the first HA configuration. Note that Kea in
-// fact supports only one configuration these days. It may be extended in the
-// future but for now it is convenient to directly access the first HA
-// configuration.
+// Convenience function returning the first HA configuration. Kea 2.4.0 and
+// later support multiple HA relationships per server (e.g., in the hub-and-spoke
+// configuration). This function should only be used when the settings common
+// for all relationships, such as multi-threading, are checked.
 func (params HALibraryParams) GetFirst() *HA {
 	if len(params.HA) > 0 {
 		return &params.HA[0]
@@ -49,6 +54,50 @@ func (params HALibraryParams) GetFirst() *HA {
 	return &HA{}
 }
 
+// Returns the valid HA relationships. Kea 2.4.0 and later support multiple
+// relationships per server (e.g., in the hub-and-spoke configuration).
+func (params HALibraryParams) GetValidRelationships() (relationships []HA) {
+	for _, relationship := range params.HA {
+		if relationship.IsValid() {
+			relationships = append(relationships, relationship)
+		}
+	}
+	return
+}
+
+// Returns the configuration of this server, i.e. the peer configuration
+// with the name matching this-server-name. It returns nil if this server
+// is not found.
+func (c HA) GetThisServer() *Peer {
+	if c.ThisServerName == nil {
+		return nil
+	}
+	for i, p := range c.Peers {
+		if p.Name != nil && *p.Name == *c.ThisServerName {
+			return &c.Peers[i]
+		}
+	}
+	return nil
+}
+
+// Returns the name identifying the HA relationship. It is a comma-separated
+// list of the primary, secondary and standby server names in the alphabetical
+// order. Kea requires that the names of these servers are unique across the
+// relationships, except for this server's name in the hub-and-spoke
+// configuration. The backup servers are excluded, so adding a backup server
+// doesn't change the relationship name.
+func (c HA) GetRelationshipName() string {
+	var names []string
+	for _, p := range c.Peers {
+		if p.Name == nil || (p.Role != nil && *p.Role == "backup") {
+			continue
+		}
+		names = append(names, *p.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
 // Checks if the mandatory Kea HA configuration parameters are set. It doesn't
 // check parameters consistency, though.
 func (c HA) IsValid() bool {
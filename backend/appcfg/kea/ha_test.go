@@ -46,3 +46,51 @@ func TestHAConfigParametersValid(t *testing.T) {
 	cfg.Peers = append(cfg.Peers, p)
 	require.False(t, cfg.IsValid())
 }
+
+// Checks that only the valid HA relationships are returned.
+func TestHALibraryParamsGetValidRelationships(t *testing.T) {
+	params := HALibraryParams{}
+	require.Empty(t, params.GetValidRelationships())
+
+	names := []string{"server1", "server3"}
+	mode := "hot-standby"
+	params.HA = []HA{
+		{ThisServerName: &names[0], Mode: &mode},
+		{ThisServerName: &names[1]},
+		{ThisServerName: &names[1], Mode: &mode},
+	}
+	relationships := params.GetValidRelationships()
+	require.Len(t, relationships, 2)
+	require.Equal(t, "server1", *relationships[0].ThisServerName)
+	require.Equal(t, "server3", *relationships[1].ThisServerName)
+}
+
+// Checks that this server's configuration is found.
+func TestHAConfigGetThisServer(t *testing.T) {
+	cfg := HA{}
+	require.Nil(t, cfg.GetThisServer())
+
+	names := []string{"server1", "server2"}
+	for i := range names {
+		cfg.Peers = append(cfg.Peers, Peer{Name: &names[i]})
+	}
+	require.Nil(t, cfg.GetThisServer())
+
+	cfg.ThisServerName = &names[1]
+	require.NotNil(t, cfg.GetThisServer())
+	require.Equal(t, "server2", *cfg.GetThisServer().Name)
+}
+
+// Checks that the relationship name comprises the sorted names of the
+// active servers.
+func TestHAConfigGetRelationshipName(t *testing.T) {
+	cfg := HA{}
+	require.Empty(t, cfg.GetRelationshipName())
+
+	names := []string{"server3", "server1", "server2"}
+	roles := []string{"standby", "primary", "backup"}
+	for i := range names {
+		cfg.Peers = append(cfg.Peers, Peer{Name: &names[i], Role: &roles[i]})
+	}
+	require.Equal(t, "server1,server3", cfg.GetRelationshipName())
+}
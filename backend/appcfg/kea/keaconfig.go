@@ -335,6 +335,16 @@ func (c *Config) GetHookLibrary(name string) (path string, params map[string]any
 	return
 }
 
+// Returns the valid HA relationships configured in the HA hook library.
+// It returns an empty slice when the hook library is not configured.
+func (c *Config) GetHARelationships() []HA {
+	_, params, ok := c.GetHookLibraries().GetHAHookLibrary()
+	if !ok {
+		return nil
+	}
+	return params.GetValidRelationships()
+}
+
 // Returns configured loggers.
 func (c *Config) GetLoggers() (loggers []Logger) {
 	if accessor := c.getCommonConfigAccessor(); accessor != nil {
@@ -397,14 +407,50 @@ func (c *Config) HideSensitiveData() {
 	hideSensitiveData((*map[string]any)(&c.Raw))
 }
 
+// Returns a copy of the value with the sensitive data hidden. The value is
+// converted to the generic JSON form, i.e., maps, lists and scalar values,
+// so it can be any value serializable to JSON, e.g., a command or a part
+// of the configuration. The sensitive data are hidden like in the
+// HideSensitiveData function.
+func HideSensitiveDataInValue(value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	marshalled, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem marshalling value to JSON")
+	}
+	var copied any
+	if err = json.Unmarshal(marshalled, &copied); err != nil {
+		return nil, errors.Wrap(err, "problem unmarshalling JSON value")
+	}
+	switch typed := copied.(type) {
+	case map[string]any:
+		hideSensitiveData(&typed)
+	case []any:
+		for _, item := range typed {
+			if itemMap, ok := item.(map[string]any); ok {
+				hideSensitiveData(&itemMap)
+			}
+		}
+	}
+	return copied, nil
+}
+
+// Checks if the configuration parameter with the specified name holds
+// sensitive data, i.e., it is a password, secret or token.
+func IsSensitiveDataKey(key string) bool {
+	keyNormalized := strings.ToLower(key)
+	return keyNormalized == "password" || keyNormalized == "secret" || keyNormalized == "token"
+}
+
 // Hides the sensitive data in the configuration map. It traverses the raw
 // configuration and nullifies the values for the following keys: password,
 // secret, token.
 func hideSensitiveData(obj *map[string]any) {
 	for entryKey, entryValue := range *obj {
 		// Check if the value holds sensitive data.
-		entryKeyNormalized := strings.ToLower(entryKey)
-		if entryKeyNormalized == "password" || entryKeyNormalized == "secret" || entryKeyNormalized == "token" {
+		if IsSensitiveDataKey(entryKey) {
 			(*obj)[entryKey] = nil
 			continue
 		}
@@ -348,6 +348,53 @@ func TestGetLoggers(t *testing.T) {
 	require.Equal(t, 99, loggers[1].DebugLevel)
 }
 
+// Test getting the valid HA relationships from the HA hook library
+// configuration.
+func TestGetHARelationships(t *testing.T) {
+	cfg, err := NewConfig(`{
+        "Dhcp4": {
+            "hooks-libraries": [
+                {
+                    "library": "/usr/lib/kea/libdhcp_ha.so",
+                    "parameters": {
+                        "high-availability": [
+                            {
+                                "this-server-name": "server1",
+                                "mode": "hot-standby",
+                                "peers": [
+                                    {
+                                        "name": "server1",
+                                        "url": "http://192.0.2.1:8000",
+                                        "role": "primary"
+                                    },
+                                    {
+                                        "name": "server2",
+                                        "url": "http://192.0.2.2:8000",
+                                        "role": "standby"
+                                    }
+                                ]
+                            },
+                            {
+                                "this-server-name": "server1"
+                            }
+                        ]
+                    }
+                }
+            ]
+        }
+    }`)
+	require.NoError(t, err)
+
+	relationships := cfg.GetHARelationships()
+	require.Len(t, relationships, 1)
+	require.Equal(t, "hot-standby", *relationships[0].Mode)
+
+	// No HA hook library.
+	cfg, err = NewConfig(`{ "Dhcp4": { } }`)
+	require.NoError(t, err)
+	require.Empty(t, cfg.GetHARelationships())
+}
+
 // Verifies that a list of control sockets is parsed correctly for a daemon.
 func TestGetControlSockets(t *testing.T) {
 	configStr := `{
@@ -1659,3 +1706,52 @@ func TestGetDHCPOptions6(t *testing.T) {
 	require.Equal(t, "nis-servers", options[1].Name)
 	require.Equal(t, dhcpmodel.DHCPv6OptionSpace, options[0].Space)
 }
+
+// Test that the sensitive data are hidden in a copy of an arbitrary value.
+func TestHideSensitiveDataInValue(t *testing.T) {
+	type credentials struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+	value := []any{
+		map[string]any{
+			"name":   "foo",
+			"secret": "aaa",
+			"database": credentials{
+				User:     "kea",
+				Password: "xxx",
+			},
+		},
+		"bar",
+	}
+
+	copied, err := HideSensitiveDataInValue(value)
+	require.NoError(t, err)
+	marshalled, err := json.Marshal(copied)
+	require.NoError(t, err)
+
+	require.JSONEq(t, `[
+		{
+			"name": "foo",
+			"secret": null,
+			"database": { "user": "kea", "password": null }
+		},
+		"bar"
+	]`, string(marshalled))
+
+	// The original value is not modified.
+	require.Equal(t, "aaa", value[0].(map[string]any)["secret"])
+
+	copied, err = HideSensitiveDataInValue(nil)
+	require.NoError(t, err)
+	require.Nil(t, copied)
+}
+
+// Test checking if the configuration parameters hold sensitive data.
+func TestIsSensitiveDataKey(t *testing.T) {
+	require.True(t, IsSensitiveDataKey("password"))
+	require.True(t, IsSensitiveDataKey("Secret"))
+	require.True(t, IsSensitiveDataKey("TOKEN"))
+	require.False(t, IsSensitiveDataKey("user"))
+	require.False(t, IsSensitiveDataKey("password-file"))
+}
@@ -248,6 +248,12 @@ func setupApp(reload bool) *cli.App {
 				Usage:   "Enable or disable collecting per-subnet stats from Kea",
 				EnvVars: []string{"STORK_AGENT_PROMETHEUS_KEA_EXPORTER_PER_SUBNET_STATS"},
 			},
+			&cli.BoolFlag{
+				Name:    "prometheus-kea-exporter-lease-file-stats",
+				Value:   true,
+				Usage:   "Enable or disable collecting lease stats from the Kea memfile lease files",
+				EnvVars: []string{"STORK_AGENT_PROMETHEUS_KEA_EXPORTER_LEASE_FILE_STATS"},
+			},
 			// Prometheus Bind 9 exporter settings
 			&cli.StringFlag{
 				Name:    "prometheus-bind9-exporter-address",
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	neturl "net/url"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// Execute hosts export command. It fetches the host reservations matching
+// the specified filters from the server and writes them to the output file
+// or to the standard output.
+func runHostsExport(settings *cli.Context) error {
+	query := neturl.Values{}
+	query.Set("format", settings.String("format"))
+	if settings.IsSet("app-id") {
+		query.Set("appId", strconv.FormatInt(settings.Int64("app-id"), 10))
+	}
+	if settings.IsSet("subnet-id") {
+		query.Set("subnetId", strconv.FormatInt(settings.Int64("subnet-id"), 10))
+	}
+	if settings.IsSet("local-subnet-id") {
+		query.Set("localSubnetId", strconv.FormatInt(settings.Int64("local-subnet-id"), 10))
+	}
+	if settings.IsSet("text") {
+		query.Set("text", settings.String("text"))
+	}
+	if settings.IsSet("global") {
+		query.Set("global", strconv.FormatBool(settings.Bool("global")))
+	}
+
+	client, err := newRestClient(settings.String("server-url"))
+	if err != nil {
+		return err
+	}
+	if err = client.login(settings.String("user"), settings.String("password")); err != nil {
+		return errors.WithMessage(err, "unable to log in to the server")
+	}
+	contents, err := client.get("hosts/export", query)
+	if err != nil {
+		return err
+	}
+
+	var writer io.Writer = os.Stdout
+	path := settings.String("file")
+	if path != "" && path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return errors.Wrapf(err, "problem creating hosts export file %s", path)
+		}
+		defer file.Close()
+		writer = file
+	}
+	if _, err = writer.Write(contents); err != nil {
+		return errors.Wrap(err, "problem writing exported host reservations")
+	}
+	if writer != os.Stdout {
+		log.WithField("file", path).Info("Host reservations exported")
+	}
+	return nil
+}
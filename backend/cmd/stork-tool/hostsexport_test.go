@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"isc.org/stork/server/gen/models"
+)
+
+// Test that the hosts-export command logs in to the server, passes the
+// filters to the hosts export endpoint and writes the output file.
+func TestRunHostsExport(t *testing.T) {
+	var query neturl.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/sessions", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/api/hosts/export", func(w http.ResponseWriter, r *http.Request) {
+		// The session cookie must be sent back.
+		if _, err := r.Cookie("session"); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "text/csv")
+		_, _ = w.Write([]byte("daemon-id,daemon-name\n1,dhcp4\n"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	file := path.Join(t.TempDir(), "hosts.csv")
+	app := setupApp()
+	err := app.Run([]string{
+		"stork-tool", "hosts-export",
+		"--server-url", server.URL,
+		"--password", "secret",
+		"--format", "csv",
+		"--file", file,
+		"--app-id", "2",
+		"--text", "example",
+		"--global=false",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "csv", query.Get("format"))
+	require.Equal(t, "2", query.Get("appId"))
+	require.Equal(t, "example", query.Get("text"))
+	require.Equal(t, "false", query.Get("global"))
+	require.False(t, query.Has("subnetId"))
+	require.False(t, query.Has("localSubnetId"))
+
+	contents, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, "daemon-id,daemon-name\n1,dhcp4\n", string(contents))
+}
+
+// Test that the hosts-export command returns the error message sent by
+// the server.
+func TestRunHostsExportServerError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/sessions", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/api/hosts/export", func(w http.ResponseWriter, r *http.Request) {
+		msg := "Unsupported hosts export format xml"
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(&models.APIError{Message: &msg})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	app := setupApp()
+	err := app.Run([]string{
+		"stork-tool", "hosts-export",
+		"--server-url", server.URL,
+		"--password", "secret",
+		"--format", "xml",
+	})
+	require.ErrorContains(t, err, "Unsupported hosts export format xml")
+}
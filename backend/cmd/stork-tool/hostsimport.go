@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/hostsimport"
+)
+
+// Returns the hosts import format for the file. The format specified
+// explicitly takes precedence over the file extension.
+func getHostsImportFormat(format, path string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch format {
+	case hostsimport.FormatCSV, hostsimport.FormatJSON:
+		return format, nil
+	default:
+		return "", errors.Errorf("unable to determine hosts import format for file %s; use the format flag", path)
+	}
+}
+
+// Execute hosts import command. It sends the file contents to the server
+// which validates the host reservations and imports the valid ones. The
+// errors found in the individual rows are logged.
+func runHostsImport(settings *cli.Context) error {
+	path := settings.String("file")
+	format, err := getHostsImportFormat(settings.String("format"), path)
+	if err != nil {
+		return err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "problem reading hosts import file %s", path)
+	}
+
+	client, err := newRestClient(settings.String("server-url"))
+	if err != nil {
+		return err
+	}
+	if err = client.login(settings.String("user"), settings.String("password")); err != nil {
+		return errors.WithMessage(err, "unable to log in to the server")
+	}
+
+	fileContents := string(contents)
+	request := &models.HostsImportRequest{
+		Format:    &format,
+		Contents:  &fileContents,
+		DaemonIds: settings.Int64Slice("daemon-id"),
+		DryRun:    settings.Bool("dry-run"),
+		BatchSize: settings.Int64("batch-size"),
+	}
+	result := &models.HostsImportResult{}
+	if err = client.post("hosts/import", request, result); err != nil {
+		return err
+	}
+
+	for _, rowError := range result.Errors {
+		log.WithField("row", rowError.Row).Error(rowError.Message)
+	}
+	log.WithFields(log.Fields{
+		"total":    result.Total,
+		"valid":    result.Valid,
+		"imported": result.Imported,
+		"dry-run":  request.DryRun,
+	}).Info("Host reservations import completed")
+
+	if len(result.Errors) > 0 {
+		return errors.Errorf("%d of %d host reservations could not be imported", len(result.Errors), result.Total)
+	}
+	return nil
+}
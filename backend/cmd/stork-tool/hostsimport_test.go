@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"isc.org/stork/server/gen/models"
+)
+
+// Test determining the hosts import format.
+func TestGetHostsImportFormat(t *testing.T) {
+	format, err := getHostsImportFormat("", "/tmp/hosts.CSV")
+	require.NoError(t, err)
+	require.Equal(t, "csv", format)
+
+	format, err = getHostsImportFormat("", "hosts.json")
+	require.NoError(t, err)
+	require.Equal(t, "json", format)
+
+	format, err = getHostsImportFormat("json", "hosts.txt")
+	require.NoError(t, err)
+	require.Equal(t, "json", format)
+
+	_, err = getHostsImportFormat("", "hosts.txt")
+	require.Error(t, err)
+}
+
+// Test that the hosts-import command logs in to the server and sends
+// the file contents to the hosts import endpoint.
+func TestRunHostsImport(t *testing.T) {
+	contents := "identifier-type,identifier,subnet\nhw-address,01:02:03:04:05:06,192.0.2.0/24\n"
+	file := path.Join(t.TempDir(), "hosts.csv")
+	err := os.WriteFile(file, []byte(contents), 0o600)
+	require.NoError(t, err)
+
+	var (
+		credentials models.SessionCredentials
+		request     models.HostsImportRequest
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/sessions", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&credentials)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/api/hosts/import", func(w http.ResponseWriter, r *http.Request) {
+		// The session cookie must be sent back.
+		if _, err := r.Cookie("session"); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		_ = json.NewEncoder(w).Encode(&models.HostsImportResult{
+			Total:    1,
+			Valid:    1,
+			Imported: 1,
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	app := setupApp()
+	err = app.Run([]string{
+		"stork-tool", "hosts-import",
+		"--server-url", server.URL,
+		"--user", "admin",
+		"--password", "secret",
+		"--file", file,
+		"--daemon-id", "1",
+		"--daemon-id", "2",
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, credentials.Identifier)
+	require.Equal(t, "admin", *credentials.Identifier)
+	require.NotNil(t, credentials.Secret)
+	require.Equal(t, "secret", *credentials.Secret)
+
+	require.NotNil(t, request.Format)
+	require.Equal(t, "csv", *request.Format)
+	require.NotNil(t, request.Contents)
+	require.Equal(t, contents, *request.Contents)
+	require.Equal(t, []int64{1, 2}, request.DaemonIds)
+	require.False(t, request.DryRun)
+	require.EqualValues(t, 100, request.BatchSize)
+}
+
+// Test that the hosts-import command returns an error when some of the
+// reservations could not be imported.
+func TestRunHostsImportRowErrors(t *testing.T) {
+	file := path.Join(t.TempDir(), "hosts.json")
+	err := os.WriteFile(file, []byte(`[ { "hw-address": "01:02" } ]`), 0o600)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/sessions", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/api/hosts/import", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&models.HostsImportResult{
+			Total: 1,
+			Errors: []*models.HostsImportRowError{
+				{Row: 1, Message: "subnet not found"},
+			},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	app := setupApp()
+	err = app.Run([]string{
+		"stork-tool", "hosts-import",
+		"--server-url", server.URL,
+		"--password", "secret",
+		"--file", file,
+		"--dry-run",
+	})
+	require.ErrorContains(t, err, "1 of 1 host reservations could not be imported")
+}
+
+// Test that the hosts-import command returns an error when the login fails.
+func TestRunHostsImportLoginFailure(t *testing.T) {
+	file := path.Join(t.TempDir(), "hosts.json")
+	err := os.WriteFile(file, []byte(`[]`), 0o600)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/sessions", func(w http.ResponseWriter, r *http.Request) {
+		msg := "invalid login or password"
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(&models.APIError{Message: &msg})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	app := setupApp()
+	err = app.Run([]string{
+		"stork-tool", "hosts-import",
+		"--server-url", server.URL,
+		"--password", "secret",
+		"--file", file,
+	})
+	require.ErrorContains(t, err, "invalid login or password")
+}
@@ -14,6 +14,8 @@ import (
 	"isc.org/stork/hooksutil"
 	"isc.org/stork/server/certs"
 	dbops "isc.org/stork/server/database"
+	"isc.org/stork/server/hostsexport"
+	"isc.org/stork/server/hostsimport"
 	storkutil "isc.org/stork/util"
 )
 
@@ -368,6 +370,110 @@ func setupApp() *cli.App {
 		},
 	}
 
+	hostsImportFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:    "server-url",
+			Usage:   "The URL of the Stork server",
+			Value:   "http://localhost:8080",
+			Aliases: []string{"s"},
+			EnvVars: []string{"STORK_TOOL_SERVER_URL"},
+		},
+		&cli.StringFlag{
+			Name:    "user",
+			Usage:   "The login or email of the Stork user importing the host reservations",
+			Value:   "admin",
+			Aliases: []string{"u"},
+			EnvVars: []string{"STORK_TOOL_USER"},
+		},
+		&cli.StringFlag{
+			Name:     "password",
+			Usage:    "The password of the Stork user importing the host reservations",
+			Required: true,
+			EnvVars:  []string{"STORK_TOOL_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:     "file",
+			Usage:    "The CSV or JSON file holding the host reservations",
+			Required: true,
+			Aliases:  []string{"i"},
+			EnvVars:  []string{"STORK_TOOL_HOSTS_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "format",
+			Usage:   "The file format; it can be one of 'csv', 'json'; if not provided, it is determined from the file extension",
+			EnvVars: []string{"STORK_TOOL_HOSTS_FORMAT"},
+		},
+		&cli.Int64SliceFlag{
+			Name:    "daemon-id",
+			Usage:   "The ID of the daemon to which the host reservations are imported; it can be specified multiple times; it is required for global reservations",
+			Aliases: []string{"d"},
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Validate the host reservations without importing them",
+		},
+		&cli.Int64Flag{
+			Name:  "batch-size",
+			Usage: "The number of the host reservations committed in a single transaction",
+			Value: hostsimport.DefaultBatchSize,
+		},
+	}
+
+	hostsExportFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:    "server-url",
+			Usage:   "The URL of the Stork server",
+			Value:   "http://localhost:8080",
+			Aliases: []string{"s"},
+			EnvVars: []string{"STORK_TOOL_SERVER_URL"},
+		},
+		&cli.StringFlag{
+			Name:    "user",
+			Usage:   "The login or email of the Stork user exporting the host reservations",
+			Value:   "admin",
+			Aliases: []string{"u"},
+			EnvVars: []string{"STORK_TOOL_USER"},
+		},
+		&cli.StringFlag{
+			Name:     "password",
+			Usage:    "The password of the Stork user exporting the host reservations",
+			Required: true,
+			EnvVars:  []string{"STORK_TOOL_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:    "file",
+			Usage:   "The output file; if not provided, the host reservations are written to the standard output",
+			Aliases: []string{"o"},
+			EnvVars: []string{"STORK_TOOL_HOSTS_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "format",
+			Usage:   "The output format; it can be one of 'kea', 'csv', 'dhcpd'",
+			Value:   hostsexport.FormatKea,
+			EnvVars: []string{"STORK_TOOL_HOSTS_FORMAT"},
+		},
+		&cli.Int64Flag{
+			Name:  "app-id",
+			Usage: "Export only the host reservations of the app with the specified ID",
+		},
+		&cli.Int64Flag{
+			Name:  "subnet-id",
+			Usage: "Export only the host reservations belonging to the subnet with the specified ID",
+		},
+		&cli.Int64Flag{
+			Name:  "local-subnet-id",
+			Usage: "Export only the host reservations belonging to the subnets with the specified ID in the Kea configuration",
+		},
+		&cli.StringFlag{
+			Name:  "text",
+			Usage: "Export only the host reservations containing the specified text",
+		},
+		&cli.BoolFlag{
+			Name:  "global",
+			Usage: "Export only the global host reservations when true or only the in-subnet reservations when false",
+		},
+	}
+
 	cli.HelpFlag = &cli.BoolFlag{
 		Name:    "help",
 		Aliases: []string{"h"},
@@ -522,6 +628,25 @@ func setupApp() *cli.App {
 				Category:    "Certificates Management",
 				Action:      runCertImport,
 			},
+			// HOST RESERVATIONS MANAGEMENT
+			{
+				Name:        "hosts-import",
+				Usage:       "Import host reservations from a CSV or JSON file",
+				UsageText:   "stork-tool hosts-import -i filename --password password [-s server-url] [-u user] [-d daemon-id]... [--dry-run]",
+				Description: "The CSV file must begin with a header naming the columns: identifier-type, identifier, ip-addresses, prefixes, hostname, subnet, options, client-classes. The JSON file holds the reservations in the Kea format; each reservation may include a subnet prefix.",
+				Flags:       hostsImportFlags,
+				Category:    "Host Reservations Management",
+				Action:      runHostsImport,
+			},
+			{
+				Name:        "hosts-export",
+				Usage:       "Export host reservations to a Kea JSON, CSV or ISC DHCP configuration file",
+				UsageText:   "stork-tool hosts-export --password password [-o filename] [--format format] [-s server-url] [-u user] [filters]",
+				Description: "The host reservations are exported from the configurations of the individual daemons. The kea format holds a JSON list of the daemons with the global and in-subnet reservations. The csv format holds one line per reservation per daemon. The dhcpd format holds the ISC DHCP server host declarations grouped by daemons.",
+				Flags:       hostsExportFlags,
+				Category:    "Host Reservations Management",
+				Action:      runHostsExport,
+			},
 			{
 				Name:        "hook-inspect",
 				Usage:       "Prints details about hooks",
@@ -46,6 +46,8 @@ func getExpectedMainFragments() []string {
 		"db-reset",
 		"db-version",
 		"db-set-version",
+		"hosts-import",
+		"hosts-export",
 	}
 }
 
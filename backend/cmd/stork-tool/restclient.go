@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	neturl "net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+)
+
+// Client of the Stork server REST API used by the commands that must
+// apply the changes via the server rather than directly in the database.
+type restClient struct {
+	url    string
+	client *http.Client
+}
+
+// Creates new REST API client for the server with the specified URL.
+func newRestClient(url string) (*restClient, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem creating cookie jar")
+	}
+	return &restClient{
+		url: strings.TrimSuffix(url, "/"),
+		client: &http.Client{
+			Jar: jar,
+		},
+	}, nil
+}
+
+// Sends a POST request with the JSON body to the specified API endpoint
+// and decodes the JSON response. The session cookie returned upon login
+// is preserved across the calls.
+func (c *restClient) post(path string, body, response any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrapf(err, "problem marshalling request to %s", path)
+	}
+	url := fmt.Sprintf("%s/api/%s", c.url, path)
+	rsp, err := c.client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrapf(err, "problem sending request to %s", url)
+	}
+	contents, err := readResponse(url, rsp)
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(contents, response), "problem parsing response from %s", url)
+}
+
+// Sends a GET request with the query parameters to the specified API
+// endpoint and returns the raw response body.
+func (c *restClient) get(path string, query neturl.Values) ([]byte, error) {
+	url := fmt.Sprintf("%s/api/%s", c.url, path)
+	if len(query) > 0 {
+		url = fmt.Sprintf("%s?%s", url, query.Encode())
+	}
+	rsp, err := c.client.Get(url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "problem sending request to %s", url)
+	}
+	return readResponse(url, rsp)
+}
+
+// Reads the response body. If the response status is not OK, it returns
+// an error including the message returned by the server.
+func readResponse(url string, rsp *http.Response) ([]byte, error) {
+	defer rsp.Body.Close()
+	contents, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "problem reading response from %s", url)
+	}
+	if rsp.StatusCode != http.StatusOK {
+		apiError := models.APIError{}
+		if err := json.Unmarshal(contents, &apiError); err == nil && apiError.Message != nil {
+			return nil, errors.Errorf("request to %s failed with status %d: %s", url, rsp.StatusCode, *apiError.Message)
+		}
+		return nil, errors.Errorf("request to %s failed with status %d", url, rsp.StatusCode)
+	}
+	return contents, nil
+}
+
+// Logs in to the server using the internal authentication method.
+func (c *restClient) login(user, password string) error {
+	method := dbmodel.AuthenticationMethodIDInternal
+	return c.post("sessions", &models.SessionCredentials{
+		AuthenticationMethodID: &method,
+		Identifier:             &user,
+		Secret:                 &password,
+	}, nil)
+}
@@ -143,6 +143,9 @@ type ConnectedAgents interface {
 	ForwardToNamedStats(ctx context.Context, agentAddress string, agentPort int64, statsAddress string, statsPort int64, path string, statsOutput interface{}) error
 	ForwardToKeaOverHTTP(ctx context.Context, app ControlledApp, commands []keactrl.SerializableCommand, cmdResponses ...interface{}) (*KeaCmdsResult, error)
 	TailTextFile(ctx context.Context, agentAddress string, agentPort int64, path string, offset int64) ([]string, error)
+	FollowTextFile(ctx context.Context, agentAddress string, agentPort int64, options FollowTextFileOptions, handler func(*agentapi.FollowTextFileRsp) error) error
+	GetLeaseFileStats(ctx context.Context, agentAddress string, agentPort int64) (*agentapi.GetLeaseFileStatsRsp, error)
+	GetKeaLogEvents(ctx context.Context, agentAddress string, agentPort int64, generation string, lastSequence int64) (*agentapi.GetKeaLogEventsRsp, error)
 }
 
 // Agents management map. It tracks Agents currently connected to the Server.
@@ -3,6 +3,7 @@ package agentcomm
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"reflect"
 	"strconv"
@@ -681,3 +682,124 @@ func (agents *connectedAgentsData) TailTextFile(ctx context.Context, agentAddres
 
 	return response.Lines, nil
 }
+
+// Options specifying which lines of the remote text file are streamed.
+type FollowTextFileOptions struct {
+	// Path to the file.
+	Path string
+	// Location relative to the end of the file from which the lines are
+	// streamed. It is ignored if the position is specified.
+	Offset int64
+	// Absolute position in the file from which the lines are streamed,
+	// e.g., the position returned before the stream was interrupted.
+	Position int64
+	// Indicates if the lines appended to the file should be streamed
+	// after reaching the end of the file.
+	Follow bool
+	// Optional regular expression selecting the streamed lines.
+	Filter string
+}
+
+// Stream the lines of the remote text file. The handler is invoked for each
+// chunk of the lines received from the agent. In the follow mode, the
+// function returns when the context is cancelled, the agent terminates the
+// stream or the handler returns an error. Otherwise, it returns after
+// receiving the lines up to the end of the file.
+func (agents *connectedAgentsData) FollowTextFile(ctx context.Context, agentAddress string, agentPort int64, options FollowTextFileOptions, handler func(*agentapi.FollowTextFileRsp) error) error {
+	addrPort := net.JoinHostPort(agentAddress, strconv.FormatInt(agentPort, 10))
+
+	req := &followTextFileCall{
+		ctx: ctx,
+		request: &agentapi.FollowTextFileReq{
+			Path:     options.Path,
+			Offset:   options.Offset,
+			Position: options.Position,
+			Follow:   options.Follow,
+			Filter:   options.Filter,
+		},
+	}
+
+	// Open the stream via queue.
+	agentResponse, err := agents.sendAndRecvViaQueue(addrPort, req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"agent": addrPort,
+			"file":  options.Path,
+		}).Warnf("Failed to follow text file")
+
+		return errors.Wrapf(err, "failed to follow text file: %s", options.Path)
+	}
+
+	stream := agentResponse.(agentapi.Agent_FollowTextFileClient)
+	for {
+		response, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			// The requestor is no longer interested in the lines.
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrapf(err, "failed to receive text file contents: %s", options.Path)
+		}
+		if response.Status.Code != agentapi.Status_OK {
+			return errors.New(response.Status.Message)
+		}
+		if err = handler(response); err != nil {
+			return err
+		}
+	}
+}
+
+// Get the lease statistics computed by the agent from the memfile lease
+// files of the Kea servers running on the agent's machine.
+func (agents *connectedAgentsData) GetLeaseFileStats(ctx context.Context, agentAddress string, agentPort int64) (*agentapi.GetLeaseFileStatsRsp, error) {
+	addrPort := net.JoinHostPort(agentAddress, strconv.FormatInt(agentPort, 10))
+
+	agentResponse, err := agents.sendAndRecvViaQueue(addrPort, &agentapi.GetLeaseFileStatsReq{})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"agent": addrPort,
+		}).Warnf("Failed to fetch lease file statistics")
+
+		return nil, errors.Wrapf(err, "failed to fetch lease file statistics from agent %s", addrPort)
+	}
+
+	response := agentResponse.(*agentapi.GetLeaseFileStatsRsp)
+
+	if response.Status.Code != agentapi.Status_OK {
+		return nil, errors.New(response.Status.Message)
+	}
+
+	return response, nil
+}
+
+// Get the notable messages found by the agent in the Kea log files. The
+// generation and the sequence number of the last event received from the
+// agent acknowledge the events, so the agent doesn't return them again.
+func (agents *connectedAgentsData) GetKeaLogEvents(ctx context.Context, agentAddress string, agentPort int64, generation string, lastSequence int64) (*agentapi.GetKeaLogEventsRsp, error) {
+	addrPort := net.JoinHostPort(agentAddress, strconv.FormatInt(agentPort, 10))
+
+	req := &agentapi.GetKeaLogEventsReq{
+		Generation:   generation,
+		LastSequence: lastSequence,
+	}
+
+	agentResponse, err := agents.sendAndRecvViaQueue(addrPort, req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"agent": addrPort,
+		}).Warnf("Failed to fetch Kea log events")
+
+		return nil, errors.Wrapf(err, "failed to fetch Kea log events from agent %s", addrPort)
+	}
+
+	response := agentResponse.(*agentapi.GetKeaLogEventsRsp)
+
+	if response.Status.Code != agentapi.Status_OK {
+		return nil, errors.New(response.Status.Message)
+	}
+
+	return response, nil
+}
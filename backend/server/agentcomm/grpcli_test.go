@@ -2,6 +2,7 @@ package agentcomm
 
 import (
 	"context"
+	"io"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -87,7 +88,7 @@ func (*gzipMatcher) String() string {
 	return "gzip matcher"
 }
 
-//go:generate mockgen -package=agentcomm -destination=api_mock.go isc.org/stork/api AgentClient
+//go:generate mockgen -package=agentcomm -destination=api_mock.go isc.org/stork/api AgentClient,Agent_FollowTextFileClient
 
 // Check if Ping works.
 func TestPing(t *testing.T) {
@@ -556,6 +557,200 @@ func TestTailTextFile(t *testing.T) {
 	require.Equal(t, "mock agent client", tail[1])
 }
 
+// Test the gRPC call which streams the lines of the specified text file.
+func TestFollowTextFile(t *testing.T) {
+	mockAgentClient, agents, teardown := setupGrpcliTestCase(t)
+	defer teardown()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockStream := NewMockAgent_FollowTextFileClient(ctrl)
+
+	mockAgentClient.EXPECT().
+		FollowTextFile(gomock.Any(), gomock.Any(), newGZIPMatcher()).
+		DoAndReturn(func(ctx context.Context, req *agentapi.FollowTextFileReq, opts ...grpc.CallOption) (agentapi.Agent_FollowTextFileClient, error) {
+			require.Equal(t, "/tmp/log.txt", req.Path)
+			require.EqualValues(t, 100, req.Position)
+			require.True(t, req.Follow)
+			require.Equal(t, "foo", req.Filter)
+			return mockStream, nil
+		})
+
+	gomock.InOrder(
+		mockStream.EXPECT().Recv().Return(&agentapi.FollowTextFileRsp{
+			Status: &agentapi.Status{
+				Code: agentapi.Status_OK,
+			},
+			Lines:    []string{"foo1", "foo2"},
+			Position: 110,
+		}, nil),
+		mockStream.EXPECT().Recv().Return(&agentapi.FollowTextFileRsp{
+			Status: &agentapi.Status{
+				Code: agentapi.Status_OK,
+			},
+			Lines:    []string{"foo3"},
+			Position: 5,
+			Rotated:  true,
+		}, nil),
+		mockStream.EXPECT().Recv().Return(nil, io.EOF),
+	)
+
+	var responses []*agentapi.FollowTextFileRsp
+	err := agents.FollowTextFile(context.Background(), "127.0.0.1", 8080, FollowTextFileOptions{
+		Path:     "/tmp/log.txt",
+		Position: 100,
+		Follow:   true,
+		Filter:   "foo",
+	}, func(rsp *agentapi.FollowTextFileRsp) error {
+		responses = append(responses, rsp)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, responses, 2)
+	require.Equal(t, []string{"foo1", "foo2"}, responses[0].Lines)
+	require.EqualValues(t, 110, responses[0].Position)
+	require.True(t, responses[1].Rotated)
+}
+
+// Test that an error is returned when the agent refuses to stream the file
+// or the handler fails.
+func TestFollowTextFileError(t *testing.T) {
+	mockAgentClient, agents, teardown := setupGrpcliTestCase(t)
+	defer teardown()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockStream := NewMockAgent_FollowTextFileClient(ctrl)
+
+	mockAgentClient.EXPECT().
+		FollowTextFile(gomock.Any(), gomock.Any(), newGZIPMatcher()).
+		Return(mockStream, nil).
+		Times(2)
+
+	gomock.InOrder(
+		mockStream.EXPECT().Recv().Return(&agentapi.FollowTextFileRsp{
+			Status: &agentapi.Status{
+				Code:    agentapi.Status_ERROR,
+				Message: "access forbidden to the /tmp/log.txt",
+			},
+		}, nil),
+		mockStream.EXPECT().Recv().Return(&agentapi.FollowTextFileRsp{
+			Status: &agentapi.Status{
+				Code: agentapi.Status_OK,
+			},
+			Lines: []string{"foo"},
+		}, nil),
+	)
+
+	handler := func(rsp *agentapi.FollowTextFileRsp) error {
+		return pkgerrors.New("handler error")
+	}
+	options := FollowTextFileOptions{
+		Path: "/tmp/log.txt",
+	}
+
+	err := agents.FollowTextFile(context.Background(), "127.0.0.1", 8080, options, handler)
+	require.EqualError(t, err, "access forbidden to the /tmp/log.txt")
+
+	err = agents.FollowTextFile(context.Background(), "127.0.0.1", 8080, options, handler)
+	require.EqualError(t, err, "handler error")
+}
+
+// Test the gRPC call which fetches the lease file statistics.
+func TestGetLeaseFileStats(t *testing.T) {
+	mockAgentClient, agents, teardown := setupGrpcliTestCase(t)
+	defer teardown()
+
+	rsp := agentapi.GetLeaseFileStatsRsp{
+		Status: &agentapi.Status{
+			Code: 0,
+		},
+		AgeBounds: []int64{3600},
+		LeaseFiles: []*agentapi.LeaseFileStats{
+			{
+				Path:   "/var/lib/kea/kea-leases4.csv",
+				Family: 4,
+				Subnets: []*agentapi.LeaseFileSubnetStats{
+					{
+						SubnetID:   1,
+						Active:     2,
+						AgeBuckets: []int64{1, 1},
+					},
+				},
+			},
+		},
+	}
+
+	mockAgentClient.EXPECT().
+		GetLeaseFileStats(gomock.Any(), gomock.Any(), newGZIPMatcher()).
+		Return(&rsp, nil)
+
+	ctx := context.Background()
+	stats, err := agents.GetLeaseFileStats(ctx, "127.0.0.1", 8080)
+	require.NoError(t, err)
+	require.Len(t, stats.LeaseFiles, 1)
+	require.Equal(t, "/var/lib/kea/kea-leases4.csv", stats.LeaseFiles[0].Path)
+	require.Len(t, stats.LeaseFiles[0].Subnets, 1)
+	require.EqualValues(t, 2, stats.LeaseFiles[0].Subnets[0].Active)
+}
+
+// Test that an error is returned when the agent fails to compute the
+// lease file statistics.
+func TestGetLeaseFileStatsError(t *testing.T) {
+	mockAgentClient, agents, teardown := setupGrpcliTestCase(t)
+	defer teardown()
+
+	rsp := agentapi.GetLeaseFileStatsRsp{
+		Status: &agentapi.Status{
+			Code:    agentapi.Status_ERROR,
+			Message: "no lease files",
+		},
+	}
+
+	mockAgentClient.EXPECT().
+		GetLeaseFileStats(gomock.Any(), gomock.Any(), newGZIPMatcher()).
+		Return(&rsp, nil)
+
+	ctx := context.Background()
+	stats, err := agents.GetLeaseFileStats(ctx, "127.0.0.1", 8080)
+	require.ErrorContains(t, err, "no lease files")
+	require.Nil(t, stats)
+}
+
+// Test the gRPC call which fetches the Kea log events.
+func TestGetKeaLogEvents(t *testing.T) {
+	mockAgentClient, agents, teardown := setupGrpcliTestCase(t)
+	defer teardown()
+
+	rsp := agentapi.GetKeaLogEventsRsp{
+		Status: &agentapi.Status{
+			Code: 0,
+		},
+		Generation: "123",
+		Events: []*agentapi.KeaLogEvent{
+			{
+				Sequence:  5,
+				Daemon:    "dhcp4",
+				MessageID: "HA_STATE_TRANSITION",
+			},
+		},
+	}
+
+	mockAgentClient.EXPECT().
+		GetKeaLogEvents(gomock.Any(), &agentapi.GetKeaLogEventsReq{
+			Generation:   "123",
+			LastSequence: 4,
+		}, newGZIPMatcher()).
+		Return(&rsp, nil)
+
+	ctx := context.Background()
+	events, err := agents.GetKeaLogEvents(ctx, "127.0.0.1", 8080, "123", 4)
+	require.NoError(t, err)
+	require.Equal(t, "123", events.Generation)
+	require.Len(t, events.Events, 1)
+	require.Equal(t, "HA_STATE_TRANSITION", events.Events[0].MessageID)
+}
+
 // Check MakeAccessPoint.
 func TestMakeAccessPoint(t *testing.T) {
 	aps := MakeAccessPoint(dbmodel.AccessPointControl, "1.2.3.4", "abcd", 124)
@@ -52,6 +52,14 @@ func (agents *connectedAgentsData) sendAndRecvViaQueue(agentAddr string, in inte
 	return respErr.Response, respErr.Err
 }
 
+// Streaming call following the remote text file. It is passed to the
+// communication loop instead of the bare request because the stream must
+// be bound to the requestor's context rather than to the call timeout.
+type followTextFileCall struct {
+	ctx     context.Context
+	request *agentapi.FollowTextFileReq
+}
+
 // Pass given request directly to an agent.
 func doCall(ctx context.Context, agent *Agent, in interface{}) (interface{}, error) {
 	var response interface{}
@@ -64,6 +72,12 @@ func doCall(ctx context.Context, agent *Agent, in interface{}) (interface{}, err
 	increaseLimitOption := grpc.MaxCallRecvMsgSize(4 * 10 * 1024 * 1024)
 	bigMessageOptions := []grpc.CallOption{compressOption, increaseLimitOption}
 
+	// The stream lasts until the requestor cancels its context, so it
+	// must not be interrupted after the timeout.
+	if call, ok := in.(*followTextFileCall); ok {
+		return agent.Client.FollowTextFile(call.ctx, call.request, compressOption)
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
@@ -80,6 +94,10 @@ func doCall(ctx context.Context, agent *Agent, in interface{}) (interface{}, err
 		response, err = agent.Client.ForwardToKeaOverHTTP(ctx, inData, bigMessageOptions...)
 	case *agentapi.TailTextFileReq:
 		response, err = agent.Client.TailTextFile(ctx, inData, bigMessageOptions...)
+	case *agentapi.GetLeaseFileStatsReq:
+		response, err = agent.Client.GetLeaseFileStats(ctx, inData, bigMessageOptions...)
+	case *agentapi.GetKeaLogEventsReq:
+		response, err = agent.Client.GetKeaLogEvents(ctx, inData, bigMessageOptions...)
 	default:
 		err = errors.New("doCall: unsupported request type")
 	}
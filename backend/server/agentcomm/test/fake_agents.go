@@ -3,6 +3,7 @@ package agentcommtest
 import (
 	"context"
 
+	agentapi "isc.org/stork/api"
 	keactrl "isc.org/stork/appctrl/kea"
 	"isc.org/stork/server/agentcomm"
 	storkutil "isc.org/stork/util"
@@ -15,11 +16,21 @@ type FakeAgents struct {
 	mockKeaFunc      []func(int, []interface{})
 	CallNo           int
 
+	// Events returned by GetKeaLogEvents.
+	KeaLogEvents []*agentapi.KeaLogEvent
+	// Acknowledgements received by GetKeaLogEvents.
+	RecordedKeaLogEventsGeneration   string
+	RecordedKeaLogEventsLastSequence int64
+
+	// Options received by FollowTextFile.
+	RecordedFollowTextFileOptions *agentcomm.FollowTextFileOptions
+
 	RecordedAddress string
 	RecordedPort    int64
 	RecordedKey     string
 	RecordedCommand string
 	mockRndcOutput  string
+	mockRndcFunc    func(string) string
 
 	RecordedStatsURL string
 	mockNamedFunc    func(int, interface{})
@@ -63,6 +74,17 @@ func NewFakeAgents(fnKea func(int, []interface{}), fnNamed func(int, interface{}
 	return fa
 }
 
+// Creates new instance of the FakeAgents structure with the functions returning
+// custom rndc and statistics-channel responses. The rndc function receives the
+// forwarded command and returns its output.
+func NewBind9FakeAgents(fnRndc func(string) string, fnNamed func(int, interface{})) *FakeAgents {
+	fa := &FakeAgents{
+		mockNamedFunc: fnNamed,
+		mockRndcFunc:  fnRndc,
+	}
+	return fa
+}
+
 // Create new instance of the FakeAgents structure with multiple mock functions
 // returning Kea responses. The subsequent mock functions are invoked for each
 // new call.
@@ -184,6 +206,14 @@ func (fa *FakeAgents) ForwardRndcCommand(ctx context.Context, app agentcomm.Cont
 	fa.RecordedAddress, fa.RecordedPort, fa.RecordedKey, _, _ = app.GetControlAccessPoint()
 	fa.RecordedCommand = command
 
+	if fa.mockRndcFunc != nil {
+		output := &agentcomm.RndcOutput{
+			Output: fa.mockRndcFunc(command),
+			Error:  nil,
+		}
+		return output, nil
+	}
+
 	if fa.mockRndcOutput != "" {
 		output := &agentcomm.RndcOutput{
 			Output: fa.mockRndcOutput,
@@ -199,3 +229,39 @@ func (fa *FakeAgents) ForwardRndcCommand(ctx context.Context, app agentcomm.Cont
 func (fa *FakeAgents) TailTextFile(ctx context.Context, agentAddress string, agentPort int64, path string, offset int64) ([]string, error) {
 	return []string{"lorem ipsum"}, nil
 }
+
+// Mimics following text file. It records the options and passes a single
+// line to the handler.
+func (fa *FakeAgents) FollowTextFile(ctx context.Context, agentAddress string, agentPort int64, options agentcomm.FollowTextFileOptions, handler func(*agentapi.FollowTextFileRsp) error) error {
+	fa.RecordedFollowTextFileOptions = &options
+	return handler(&agentapi.FollowTextFileRsp{
+		Status: &agentapi.Status{
+			Code: agentapi.Status_OK,
+		},
+		Lines:    []string{"lorem ipsum"},
+		Position: options.Position + 12,
+	})
+}
+
+// Mimics getting the lease file statistics. It returns no lease files.
+func (fa *FakeAgents) GetLeaseFileStats(ctx context.Context, agentAddress string, agentPort int64) (*agentapi.GetLeaseFileStatsRsp, error) {
+	return &agentapi.GetLeaseFileStatsRsp{
+		Status: &agentapi.Status{
+			Code: agentapi.Status_OK,
+		},
+	}, nil
+}
+
+// Mimics getting the Kea log events. It returns the events specified in
+// the KeaLogEvents field and records the acknowledgement.
+func (fa *FakeAgents) GetKeaLogEvents(ctx context.Context, agentAddress string, agentPort int64, generation string, lastSequence int64) (*agentapi.GetKeaLogEventsRsp, error) {
+	fa.RecordedKeaLogEventsGeneration = generation
+	fa.RecordedKeaLogEventsLastSequence = lastSequence
+	return &agentapi.GetKeaLogEventsRsp{
+		Status: &agentapi.Status{
+			Code: agentapi.Status_OK,
+		},
+		Generation: "1",
+		Events:     fa.KeaLogEvents,
+	}, nil
+}
@@ -16,78 +16,88 @@ import (
 // Provide example date format how named returns dates.
 const namedLongDateFormat = "Mon, 02 Jan 2006 15:04:05 MST"
 
-// The cache statistics of the Bind9 named daemon.
-type CacheStatsData struct {
-	CacheHits   int64 `json:"CacheHits"`
-	CacheMisses int64 `json:"CacheMisses"`
-	QueryHits   int64 `json:"QueryHits"`
-	QueryMisses int64 `json:"QueryMisses"`
-}
-
-// The resolver entry of the view statistics JSON structure.
+// The resolver entry of the view statistics JSON structure. The stats
+// include the numbers of the queries sent by the resolver and the RCODEs
+// received in the responses. The qtypes are the numbers of the queries
+// sent by the resolver per query type. The cachestats include the cache
+// hits and misses.
 type ResolverData struct {
-	CacheStats CacheStatsData `json:"cachestats"`
+	Stats      map[string]int64 `json:"stats"`
+	Qtypes     map[string]int64 `json:"qtypes"`
+	CacheStats map[string]int64 `json:"cachestats"`
 }
 
 // The view statistics data JSON structure.
 type ViewStatsData struct {
+	Zones    []*ZoneData  `json:"zones"`
 	Resolver ResolverData `json:"resolver"`
 }
 
 // JSON Structure of response returned by the named Bind 9 daemon on fetching
 // statistics.
 type NamedStatsGetResponse struct {
-	Views map[string]*ViewStatsData `json:"views,omitempty"`
+	Rcodes map[string]int64          `json:"rcodes,omitempty"`
+	Views  map[string]*ViewStatsData `json:"views,omitempty"`
 }
 
-// Get statistics from named daemon using ForwardToNamedStats function.
-func GetAppStatistics(ctx context.Context, agents agentcomm.ConnectedAgents, dbApp *dbmodel.App) {
-	// prepare URL to named
-	statsChannel, err := dbApp.GetAccessPoint(dbmodel.AccessPointStatistics)
-	if err != nil {
-		log.Warnf("Problem getting named statistics-channel access point: %s", err)
-		return
+// Converts the statistics returned by the named daemon to the format stored
+// in the database. The statistics of all views except the internal _bind
+// view are included. The view's zone count excludes the builtin zones.
+func convertNamedStats(statsOutput *NamedStatsGetResponse) *dbmodel.Bind9NamedStats {
+	namedStats := &dbmodel.Bind9NamedStats{
+		Rcodes: statsOutput.Rcodes,
 	}
 
-	ctx2, cancel := context.WithTimeout(ctx, 2*time.Second)
-	defer cancel()
-
-	// store all collected details in app db record
-	statsOutput := NamedStatsGetResponse{}
-	err = agents.ForwardToNamedStats(ctx2, dbApp.Machine.Address, dbApp.Machine.AgentPort, statsChannel.Address, statsChannel.Port, "json/v1", &statsOutput)
-	if err != nil {
-		log.Warnf("Problem retrieving stats from named: %s", err)
-	}
-
-	namedStats := &dbmodel.Bind9NamedStats{}
-
 	if statsOutput.Views != nil {
 		viewStats := make(map[string]*dbmodel.Bind9StatsView)
 
 		for name, view := range statsOutput.Views {
-			// Only deal with the default view for now.
-			if name != "_default" {
+			if name == bindView || view == nil {
 				continue
 			}
 
-			cacheStats := make(map[string]int64)
-			cacheStats["CacheHits"] = view.Resolver.CacheStats.CacheHits
-			cacheStats["CacheMisses"] = view.Resolver.CacheStats.CacheMisses
-			cacheStats["QueryHits"] = view.Resolver.CacheStats.QueryHits
-			cacheStats["QueryMisses"] = view.Resolver.CacheStats.QueryMisses
+			var zoneCount int64
+			for _, zone := range view.Zones {
+				if zone != nil && zone.ZoneType != builtinZoneType {
+					zoneCount++
+				}
+			}
 
 			viewStats[name] = &dbmodel.Bind9StatsView{
+				ZoneCount: zoneCount,
 				Resolver: &dbmodel.Bind9StatsResolver{
-					CacheStats: cacheStats,
+					Stats:      view.Resolver.Stats,
+					Qtypes:     view.Resolver.Qtypes,
+					CacheStats: view.Resolver.CacheStats,
 				},
 			}
-			break
 		}
 
 		namedStats.Views = viewStats
 	}
+	return namedStats
+}
+
+// Get statistics from named daemon using ForwardToNamedStats function.
+func GetAppStatistics(ctx context.Context, agents agentcomm.ConnectedAgents, dbApp *dbmodel.App) {
+	// prepare URL to named
+	statsChannel, err := dbApp.GetAccessPoint(dbmodel.AccessPointStatistics)
+	if err != nil {
+		log.Warnf("Problem getting named statistics-channel access point: %s", err)
+		return
+	}
+
+	ctx2, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
 
-	dbApp.Daemons[0].Bind9Daemon.Stats.NamedStats = namedStats
+	// store all collected details in app db record
+	statsOutput := NamedStatsGetResponse{}
+	err = agents.ForwardToNamedStats(ctx2, dbApp.Machine.Address, dbApp.Machine.AgentPort, statsChannel.Address, statsChannel.Port, "json/v1", &statsOutput)
+	if err != nil {
+		log.Warnf("Problem retrieving stats from named: %s", err)
+	}
+
+	dbApp.Daemons[0].Bind9Daemon.Stats.NamedStats = convertNamedStats(&statsOutput)
 }
 
 // Get state of named daemon using ForwardRndcCommand function.
@@ -168,6 +178,16 @@ func GetAppState(ctx context.Context, agents agentcomm.ConnectedAgents, dbApp *d
 		log.Warnf("Cannot get BIND 9 number of zones: unable to find number of zones in output")
 	}
 
+	// Preserve the identity of the existing daemon, so the zones and other
+	// data associated with the daemon are not recreated.
+	if len(dbApp.Daemons) > 0 && dbApp.Daemons[0].ID != 0 {
+		bind9Daemon.ID = dbApp.Daemons[0].ID
+		bind9Daemon.CreatedAt = dbApp.Daemons[0].CreatedAt
+		if dbApp.Daemons[0].Bind9Daemon != nil {
+			bind9Daemon.Bind9Daemon.ID = dbApp.Daemons[0].Bind9Daemon.ID
+		}
+	}
+
 	// Save status
 	dbApp.Active = bind9Daemon.Active
 	dbApp.Meta.Version = bind9Daemon.Version
@@ -187,7 +207,10 @@ func CommitAppIntoDB(db *dbops.PgDB, app *dbmodel.App, eventCenter eventcenter.E
 	} else {
 		_, _, err = dbmodel.UpdateApp(db, app)
 	}
+	if err != nil {
+		return err
+	}
 	// todo: perform any additional actions required after storing the
 	// app in the db.
-	return err
+	return nil
 }
@@ -14,26 +14,60 @@ import (
 
 // Named statistics-channel response.
 func mockNamed(callNo int, response interface{}) {
+	if zonesOutput, ok := response.(*NamedZonesGetResponse); ok {
+		mockNamedZones(callNo, zonesOutput)
+		return
+	}
 	statsOutput := response.(*NamedStatsGetResponse)
 	*statsOutput = NamedStatsGetResponse{
+		Rcodes: map[string]int64{
+			"NOERROR":  100,
+			"NXDOMAIN": 5,
+		},
 		Views: map[string]*ViewStatsData{
 			"_default": {
+				Zones: []*ZoneData{
+					{Name: "example.com", ZoneType: "primary"},
+					{Name: "10.IN-ADDR.ARPA", ZoneType: "builtin"},
+				},
+				Resolver: ResolverData{
+					Stats: map[string]int64{
+						"Queryv4":  20,
+						"NXDOMAIN": 3,
+					},
+					Qtypes: map[string]int64{
+						"A": 20,
+					},
+					CacheStats: map[string]int64{
+						"CacheHits":   40,
+						"CacheMisses": 10,
+						"QueryHits":   70,
+						"QueryMisses": 30,
+						"DeleteLRU":   2,
+					},
+				},
+			},
+			"internal": {
+				Zones: []*ZoneData{
+					{Name: "example.com", ZoneType: "primary"},
+					{Name: "example.org", ZoneType: "secondary"},
+				},
 				Resolver: ResolverData{
-					CacheStats: CacheStatsData{
-						CacheHits:   40,
-						CacheMisses: 10,
-						QueryHits:   70,
-						QueryMisses: 30,
+					CacheStats: map[string]int64{
+						"CacheHits":   4,
+						"CacheMisses": 1,
+						"QueryHits":   7,
+						"QueryMisses": 3,
 					},
 				},
 			},
 			"_bind": {
 				Resolver: ResolverData{
-					CacheStats: CacheStatsData{
-						CacheHits:   1,
-						CacheMisses: 5,
-						QueryHits:   4,
-						QueryMisses: 6,
+					CacheStats: map[string]int64{
+						"CacheHits":   1,
+						"CacheMisses": 5,
+						"QueryHits":   4,
+						"QueryMisses": 6,
 					},
 				},
 			},
@@ -84,6 +118,54 @@ func TestGetAppState(t *testing.T) {
 	require.EqualValues(t, 10, daemon.Bind9Daemon.Stats.NamedStats.Views["_default"].Resolver.CacheStats["CacheMisses"])
 	require.EqualValues(t, 70, daemon.Bind9Daemon.Stats.NamedStats.Views["_default"].Resolver.CacheStats["QueryHits"])
 	require.EqualValues(t, 30, daemon.Bind9Daemon.Stats.NamedStats.Views["_default"].Resolver.CacheStats["QueryMisses"])
+	require.EqualValues(t, 2, daemon.Bind9Daemon.Stats.NamedStats.Views["_default"].Resolver.CacheStats["DeleteLRU"])
+	require.EqualValues(t, 20, daemon.Bind9Daemon.Stats.NamedStats.Views["_default"].Resolver.Stats["Queryv4"])
+	require.EqualValues(t, 3, daemon.Bind9Daemon.Stats.NamedStats.Views["_default"].Resolver.Stats["NXDOMAIN"])
+	require.EqualValues(t, 20, daemon.Bind9Daemon.Stats.NamedStats.Views["_default"].Resolver.Qtypes["A"])
+	require.EqualValues(t, 1, daemon.Bind9Daemon.Stats.NamedStats.Views["_default"].ZoneCount)
+	require.EqualValues(t, 5, daemon.Bind9Daemon.Stats.NamedStats.Rcodes["NXDOMAIN"])
+
+	// Test statistics of the other view. The internal _bind view is skipped.
+	require.Len(t, daemon.Bind9Daemon.Stats.NamedStats.Views, 2)
+	require.EqualValues(t, 4, daemon.Bind9Daemon.Stats.NamedStats.Views["internal"].Resolver.CacheStats["CacheHits"])
+	require.EqualValues(t, 2, daemon.Bind9Daemon.Stats.NamedStats.Views["internal"].ZoneCount)
+	require.NotContains(t, daemon.Bind9Daemon.Stats.NamedStats.Views, "_bind")
+}
+
+// Test that the existing daemon's identity is preserved when retrieving
+// the state of the BIND 9 app.
+func TestGetAppStateExistingDaemon(t *testing.T) {
+	fa := agentcommtest.NewFakeAgents(nil, mockNamed)
+	fec := &storktest.FakeEventCenter{}
+
+	var accessPoints []*dbmodel.AccessPoint
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "127.0.0.1", "abcd", 953, false)
+	createdAt := time.Date(2020, 2, 3, 13, 0, 0, 0, time.UTC)
+	dbApp := dbmodel.App{
+		AccessPoints: accessPoints,
+		Machine: &dbmodel.Machine{
+			Address:   "192.0.2.0",
+			AgentPort: 1111,
+		},
+		Daemons: []*dbmodel.Daemon{
+			{
+				ID:        5,
+				CreatedAt: createdAt,
+				Bind9Daemon: &dbmodel.Bind9Daemon{
+					ID: 6,
+				},
+			},
+		},
+	}
+
+	GetAppState(context.Background(), fa, &dbApp, fec)
+
+	require.Len(t, dbApp.Daemons, 1)
+	daemon := dbApp.Daemons[0]
+	require.EqualValues(t, 5, daemon.ID)
+	require.Equal(t, createdAt, daemon.CreatedAt)
+	require.EqualValues(t, 6, daemon.Bind9Daemon.ID)
+	require.Equal(t, "9.9.9", daemon.Version)
 }
 
 // Tests that BIND 9 can be added and then updated in the database.
@@ -82,34 +82,6 @@ func (statsPuller *StatsPuller) getStatsFromApp(dbApp *dbmodel.App) error {
 		return err
 	}
 
-	namedStats := &dbmodel.Bind9NamedStats{}
-
-	if statsOutput.Views != nil {
-		viewStats := make(map[string]*dbmodel.Bind9StatsView)
-
-		for name, view := range statsOutput.Views {
-			// Only deal with the default view for now.
-			if name != "_default" {
-				continue
-			}
-
-			cacheStats := make(map[string]int64)
-			cacheStats["CacheHits"] = view.Resolver.CacheStats.CacheHits
-			cacheStats["CacheMisses"] = view.Resolver.CacheStats.CacheMisses
-			cacheStats["QueryHits"] = view.Resolver.CacheStats.QueryHits
-			cacheStats["QueryMisses"] = view.Resolver.CacheStats.QueryMisses
-
-			viewStats[name] = &dbmodel.Bind9StatsView{
-				Resolver: &dbmodel.Bind9StatsResolver{
-					CacheStats: cacheStats,
-				},
-			}
-			break
-		}
-
-		namedStats.Views = viewStats
-	}
-
-	dbApp.Daemons[0].Bind9Daemon.Stats.NamedStats = namedStats
+	dbApp.Daemons[0].Bind9Daemon.Stats.NamedStats = convertNamedStats(&statsOutput)
 	return dbmodel.UpdateDaemon(statsPuller.DB, dbApp.Daemons[0])
 }
@@ -43,6 +43,10 @@ func TestStatsPullerPullStats(t *testing.T) {
 	bind9Mock := func(callNo int, statsOutput interface{}) {
 		json := `{
 		    "json-stats-version":"1.2",
+		    "rcodes":{
+		        "NOERROR": 30,
+		        "SERVFAIL": 2
+		    },
 		    "views":{
 		        "_default":{
 		            "resolver":{
@@ -54,6 +58,28 @@ func TestStatsPullerPullStats(t *testing.T) {
 		                }
 		            }
 		        },
+		        "guest":{
+		            "zones":[
+		                {
+		                    "name": "example.com",
+		                    "class": "IN",
+		                    "serial": 1,
+		                    "type": "primary"
+		                }
+		            ],
+		            "resolver":{
+		                "stats":{
+		                    "Queryv4": 15,
+		                    "SERVFAIL": 1
+		                },
+		                "cachestats":{
+		                    "CacheHits": 5,
+		                    "CacheMisses": 4,
+		                    "QueryHits": 3,
+		                    "QueryMisses": 2
+		                }
+		            }
+		        },
 		        "_bind":{
 		            "resolver":{
 		                "cachestats":{
@@ -157,6 +183,16 @@ func TestStatsPullerPullStats(t *testing.T) {
 	require.EqualValues(t, 40, daemon.Bind9Daemon.Stats.NamedStats.Views["_default"].Resolver.CacheStats["CacheMisses"])
 	require.EqualValues(t, 10, daemon.Bind9Daemon.Stats.NamedStats.Views["_default"].Resolver.CacheStats["QueryHits"])
 	require.EqualValues(t, 90, daemon.Bind9Daemon.Stats.NamedStats.Views["_default"].Resolver.CacheStats["QueryMisses"])
+	require.EqualValues(t, 2, daemon.Bind9Daemon.Stats.NamedStats.Rcodes["SERVFAIL"])
+
+	// All views except _bind should be stored.
+	require.Len(t, daemon.Bind9Daemon.Stats.NamedStats.Views, 2)
+	require.Contains(t, daemon.Bind9Daemon.Stats.NamedStats.Views, "guest")
+	guest := daemon.Bind9Daemon.Stats.NamedStats.Views["guest"]
+	require.EqualValues(t, 1, guest.ZoneCount)
+	require.EqualValues(t, 15, guest.Resolver.Stats["Queryv4"])
+	require.EqualValues(t, 1, guest.Resolver.Stats["SERVFAIL"])
+	require.EqualValues(t, 5, guest.Resolver.CacheStats["CacheHits"])
 
 	app2, err := dbmodel.GetAppByID(db, dbApp2.ID)
 	require.NoError(t, err)
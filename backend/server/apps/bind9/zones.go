@@ -0,0 +1,282 @@
+package bind9
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"isc.org/stork/server/agentcomm"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// The zone entry of the view zones JSON structure returned by the
+// statistics channel.
+type ZoneData struct {
+	Name     string    `json:"name"`
+	Class    string    `json:"class"`
+	Serial   int64     `json:"serial"`
+	ZoneType string    `json:"type"`
+	Loaded   time.Time `json:"loaded"`
+	Expires  time.Time `json:"expires"`
+	Refresh  time.Time `json:"refresh"`
+}
+
+// The view zones data JSON structure.
+type ViewZonesData struct {
+	Zones []*ZoneData `json:"zones"`
+}
+
+// JSON structure of the response returned by the named BIND 9 daemon on
+// fetching the zones from the statistics channel.
+type NamedZonesGetResponse struct {
+	Views map[string]*ViewZonesData `json:"views,omitempty"`
+}
+
+// Maximum total time spent on sending the rndc commands fetching the
+// details of the zones from a single daemon. The details of the zones
+// not fetched within this time are left unknown and are fetched in the
+// next pull.
+const maxZoneDetailsPullTime = 30 * time.Second
+
+// Name of the internal view holding the CHAOS class zones.
+const bindView = "_bind"
+
+// Type of the automatically created zones, e.g., empty reverse zones.
+const builtinZoneType = "builtin"
+
+// Checks if the zone of the given type is transferred from the primaries.
+func isSecondaryZoneType(zoneType string) bool {
+	switch zoneType {
+	case "secondary", "slave", "mirror", "stub":
+		return true
+	default:
+		return false
+	}
+}
+
+// Parses the time returned by rndc. It returns zero time if the time
+// cannot be parsed.
+func parseRndcTime(value string) time.Time {
+	parsed, err := time.Parse(namedLongDateFormat, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed.UTC()
+}
+
+// Parses the output of the rndc zonestatus command and sets the DNSSEC
+// signing state, key maintenance and timers of the zone. The timers
+// already set from the statistics channel are preserved.
+func parseZoneStatus(output string, zone *dbmodel.Bind9Zone) {
+	values := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	if secure, ok := values["secure"]; ok {
+		switch {
+		case secure != "yes":
+			zone.DNSSECState = dbmodel.Bind9ZoneDNSSECUnsigned
+		case values["inline signing"] == "yes":
+			zone.DNSSECState = dbmodel.Bind9ZoneDNSSECInlineSigned
+		default:
+			zone.DNSSECState = dbmodel.Bind9ZoneDNSSECSigned
+		}
+	}
+	zone.KeyMaintenance = values["key maintenance"]
+	if value, ok := values["next key event"]; ok {
+		zone.NextKeyEventAt = parseRndcTime(value)
+	}
+	if value, ok := values["last loaded"]; ok && zone.LoadedAt.IsZero() {
+		zone.LoadedAt = parseRndcTime(value)
+	}
+	if value, ok := values["next refresh"]; ok && zone.RefreshAt.IsZero() {
+		zone.RefreshAt = parseRndcTime(value)
+	}
+	if value, ok := values["expires"]; ok && zone.ExpiresAt.IsZero() {
+		zone.ExpiresAt = parseRndcTime(value)
+	}
+}
+
+// Pattern matching the primaries (or the legacy masters) clause in the
+// zone configuration returned by the rndc showzone command.
+var primariesPattern = regexp.MustCompile(`(?:primaries|masters)[^{;]*\{([^}]*)\}`)
+
+// Parses the output of the rndc showzone command and returns the primary
+// servers of the zone. Each returned entry is an address or a name of the
+// primaries list, without the port and key specification.
+func parseZonePrimaries(output string) []string {
+	match := primariesPattern.FindStringSubmatch(output)
+	if match == nil {
+		return nil
+	}
+	var primaries []string
+	for _, entry := range strings.Split(match[1], ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		primaries = append(primaries, strings.Trim(fields[0], `"`))
+	}
+	return primaries
+}
+
+// Sends the rndc command for the zone and returns its output.
+func sendZoneRndcCommand(ctx context.Context, agents agentcomm.ConnectedAgents, dbApp *dbmodel.App, command string, zone *dbmodel.Bind9Zone) (string, error) {
+	ctx2, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	out, err := agents.ForwardRndcCommand(ctx2, dbApp, fmt.Sprintf("%s %s %s %s", command, zone.Name, zone.Class, zone.View))
+	if err != nil {
+		return "", err
+	}
+	if out.Error != nil {
+		return "", out.Error
+	}
+	return out.Output, nil
+}
+
+// Checks if the details of the zone fetched using rndc can be copied
+// from the known zone. The details are copied when the zone serial and
+// type didn't change and the details were successfully fetched before.
+func canReuseZoneDetails(zone *dbmodel.Bind9Zone, known *dbmodel.Bind9Zone) bool {
+	if known == nil || known.Serial != zone.Serial || known.ZoneType != zone.ZoneType || known.DNSSECState == "" {
+		return false
+	}
+	return !isSecondaryZoneType(zone.ZoneType) || len(known.Primaries) > 0
+}
+
+// Copies the details fetched using rndc from the known zone. The timers
+// already set from the statistics channel are preserved.
+func reuseZoneDetails(zone *dbmodel.Bind9Zone, known *dbmodel.Bind9Zone) {
+	zone.DNSSECState = known.DNSSECState
+	zone.KeyMaintenance = known.KeyMaintenance
+	zone.NextKeyEventAt = known.NextKeyEventAt
+	zone.Primaries = known.Primaries
+	if zone.LoadedAt.IsZero() {
+		zone.LoadedAt = known.LoadedAt
+	}
+	if zone.RefreshAt.IsZero() {
+		zone.RefreshAt = known.RefreshAt
+	}
+	if zone.ExpiresAt.IsZero() {
+		zone.ExpiresAt = known.ExpiresAt
+	}
+}
+
+// Fetches the details of the zone using rndc. The DNSSEC signing state is
+// fetched using rndc zonestatus and the primaries of the secondary zone
+// using rndc showzone. The failures are logged and the respective zone
+// details are left empty.
+func fetchZoneDetails(ctx context.Context, agents agentcomm.ConnectedAgents, dbApp *dbmodel.App, zone *dbmodel.Bind9Zone) {
+	output, err := sendZoneRndcCommand(ctx, agents, dbApp, "zonestatus", zone)
+	if err != nil {
+		log.Warnf("Problem getting status of zone %s in view %s: %s", zone.Name, zone.View, err)
+	} else {
+		parseZoneStatus(output, zone)
+	}
+
+	if isSecondaryZoneType(zone.ZoneType) {
+		output, err = sendZoneRndcCommand(ctx, agents, dbApp, "showzone", zone)
+		if err != nil {
+			log.Warnf("Problem getting configuration of zone %s in view %s: %s", zone.Name, zone.View, err)
+		} else {
+			zone.Primaries = parseZonePrimaries(output)
+		}
+	}
+}
+
+// Fetches the zones from the named daemon. The zones of all views are
+// enumerated using the statistics channel. The automatically created zones
+// and the zones of the internal _bind view are skipped. The details of the
+// zones, i.e., the DNSSEC signing state and the primaries of the secondary
+// zones, are fetched using rndc only when they are not known yet or when
+// the zone serial changed. Otherwise, they are copied from the known zones,
+// typically the zones stored in the database. The total time spent on
+// fetching the details is bounded; the details of the remaining zones are
+// left empty and are fetched in the next pull.
+func GetAppZones(ctx context.Context, agents agentcomm.ConnectedAgents, dbApp *dbmodel.App, knownZones []dbmodel.Bind9Zone) ([]*dbmodel.Bind9Zone, error) {
+	statsChannel, err := dbApp.GetAccessPoint(dbmodel.AccessPointStatistics)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx2, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	zonesOutput := NamedZonesGetResponse{}
+	err = agents.ForwardToNamedStats(ctx2, dbApp.Machine.Address, dbApp.Machine.AgentPort, statsChannel.Address, statsChannel.Port, "json/v1/zones", &zonesOutput)
+	if err != nil {
+		return nil, pkgerrors.WithMessage(err, "problem retrieving zones from named")
+	}
+
+	type zoneKey struct {
+		view  string
+		name  string
+		class string
+	}
+	known := make(map[zoneKey]*dbmodel.Bind9Zone, len(knownZones))
+	for i := range knownZones {
+		known[zoneKey{knownZones[i].View, knownZones[i].Name, knownZones[i].Class}] = &knownZones[i]
+	}
+
+	zones := []*dbmodel.Bind9Zone{}
+	for viewName, view := range zonesOutput.Views {
+		if viewName == bindView || view == nil {
+			continue
+		}
+		for _, zoneData := range view.Zones {
+			if zoneData == nil || zoneData.ZoneType == builtinZoneType {
+				continue
+			}
+			zone := &dbmodel.Bind9Zone{
+				View:      viewName,
+				Name:      zoneData.Name,
+				Class:     zoneData.Class,
+				ZoneType:  zoneData.ZoneType,
+				Serial:    zoneData.Serial,
+				LoadedAt:  zoneData.Loaded.UTC(),
+				RefreshAt: zoneData.Refresh.UTC(),
+				ExpiresAt: zoneData.Expires.UTC(),
+			}
+			if zone.Class == "" {
+				zone.Class = "IN"
+			}
+			zones = append(zones, zone)
+		}
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		if zones[i].View != zones[j].View {
+			return zones[i].View < zones[j].View
+		}
+		return zones[i].Name < zones[j].Name
+	})
+
+	ctx3, cancel3 := context.WithTimeout(ctx, maxZoneDetailsPullTime)
+	defer cancel3()
+	postponed := 0
+	for _, zone := range zones {
+		knownZone := known[zoneKey{zone.View, zone.Name, zone.Class}]
+		switch {
+		case canReuseZoneDetails(zone, knownZone):
+			reuseZoneDetails(zone, knownZone)
+		case ctx3.Err() != nil:
+			postponed++
+		default:
+			fetchZoneDetails(ctx3, agents, dbApp, zone)
+		}
+	}
+	if postponed > 0 {
+		log.Warnf("Fetching details of %d zones of app %d postponed because it took too long", postponed, dbApp.ID)
+	}
+	return zones, nil
+}
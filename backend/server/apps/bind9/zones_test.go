@@ -0,0 +1,372 @@
+package bind9
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Named statistics-channel zones response. It contains a primary zone in
+// the default view, a secondary zone in the internal view and the builtin
+// zones which should be skipped.
+func mockNamedZones(callNo int, zonesOutput *NamedZonesGetResponse) {
+	loaded := time.Date(2023, 3, 14, 9, 35, 10, 0, time.UTC)
+	*zonesOutput = NamedZonesGetResponse{
+		Views: map[string]*ViewZonesData{
+			"_default": {
+				Zones: []*ZoneData{
+					{
+						Name:     "example.com",
+						Class:    "IN",
+						Serial:   2023031401,
+						ZoneType: "primary",
+						Loaded:   loaded,
+					},
+					{
+						Name:     "10.IN-ADDR.ARPA",
+						Class:    "IN",
+						Serial:   0,
+						ZoneType: "builtin",
+					},
+				},
+			},
+			"internal": {
+				Zones: []*ZoneData{
+					{
+						Name:     "example.org",
+						Class:    "IN",
+						Serial:   7,
+						ZoneType: "secondary",
+						Loaded:   loaded,
+						Refresh:  loaded.Add(time.Hour),
+						Expires:  loaded.Add(7 * 24 * time.Hour),
+					},
+					{
+						Name:     "example.com",
+						Class:    "IN",
+						Serial:   2023031402,
+						ZoneType: "primary",
+						Loaded:   loaded,
+					},
+				},
+			},
+			"_bind": {
+				Zones: []*ZoneData{
+					{
+						Name:     "version.bind",
+						Class:    "CH",
+						ZoneType: "builtin",
+					},
+				},
+			},
+		},
+	}
+}
+
+// Returns the rndc zonestatus and showzone outputs.
+func mockRndcZones(command string) string {
+	switch {
+	case strings.HasPrefix(command, "zonestatus example.com IN _default"):
+		return `name: example.com
+type: primary
+files: example.com.db
+serial: 2023031401
+nodes: 10
+last loaded: Tue, 14 Mar 2023 09:35:10 GMT
+secure: yes
+inline signing: yes
+key maintenance: automatic
+next key event: Tue, 14 Mar 2023 10:35:10 GMT
+dynamic: no
+reconfigurable via modzone: no`
+	case strings.HasPrefix(command, "zonestatus"):
+		return `name: example.org
+type: secondary
+secure: no
+dynamic: no`
+	case strings.HasPrefix(command, "showzone example.org"):
+		return `zone "example.org" { type secondary; file "example.org.db"; primaries { 192.0.2.1; 192.0.2.2 port 5353 key "xfer"; }; };`
+	default:
+		return ""
+	}
+}
+
+// Creates the app with the statistics channel used in the tests.
+func createTestZonesApp() *dbmodel.App {
+	var accessPoints []*dbmodel.AccessPoint
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "127.0.0.1", "abcd", 953, false)
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointStatistics, "127.0.0.1", "", 8000, false)
+	return &dbmodel.App{
+		AccessPoints: accessPoints,
+		Machine: &dbmodel.Machine{
+			Address:   "192.0.2.0",
+			AgentPort: 1111,
+		},
+	}
+}
+
+// Test parsing the rndc zonestatus output.
+func TestParseZoneStatus(t *testing.T) {
+	t.Run("inline signed", func(t *testing.T) {
+		zone := &dbmodel.Bind9Zone{}
+		parseZoneStatus(mockRndcZones("zonestatus example.com IN _default"), zone)
+		require.Equal(t, dbmodel.Bind9ZoneDNSSECInlineSigned, zone.DNSSECState)
+		require.Equal(t, "automatic", zone.KeyMaintenance)
+		require.Equal(t, time.Date(2023, 3, 14, 10, 35, 10, 0, time.UTC), zone.NextKeyEventAt)
+		require.Equal(t, time.Date(2023, 3, 14, 9, 35, 10, 0, time.UTC), zone.LoadedAt)
+	})
+
+	t.Run("signed", func(t *testing.T) {
+		zone := &dbmodel.Bind9Zone{}
+		parseZoneStatus("secure: yes\ninline signing: no\n", zone)
+		require.Equal(t, dbmodel.Bind9ZoneDNSSECSigned, zone.DNSSECState)
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		zone := &dbmodel.Bind9Zone{}
+		parseZoneStatus("secure: no\n", zone)
+		require.Equal(t, dbmodel.Bind9ZoneDNSSECUnsigned, zone.DNSSECState)
+		require.Empty(t, zone.KeyMaintenance)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		zone := &dbmodel.Bind9Zone{}
+		parseZoneStatus("name: example.com\n", zone)
+		require.Empty(t, zone.DNSSECState)
+	})
+
+	t.Run("preserve timers", func(t *testing.T) {
+		loaded := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		zone := &dbmodel.Bind9Zone{
+			LoadedAt: loaded,
+		}
+		parseZoneStatus("last loaded: Tue, 14 Mar 2023 09:35:10 GMT\nnext refresh: Tue, 14 Mar 2023 10:35:10 GMT\n", zone)
+		require.Equal(t, loaded, zone.LoadedAt)
+		require.Equal(t, time.Date(2023, 3, 14, 10, 35, 10, 0, time.UTC), zone.RefreshAt)
+	})
+}
+
+// Test parsing the primaries from the rndc showzone output.
+func TestParseZonePrimaries(t *testing.T) {
+	require.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, parseZonePrimaries(mockRndcZones("showzone example.org IN internal")))
+	require.Equal(t, []string{"2001:db8::1"}, parseZonePrimaries(`zone "example.net" { type slave; masters port 53 { 2001:db8::1; }; };`))
+	require.Equal(t, []string{"upstream"}, parseZonePrimaries(`zone "example.net" { type secondary; primaries { "upstream"; }; };`))
+	require.Nil(t, parseZonePrimaries(`zone "example.com" { type primary; file "example.com.db"; };`))
+}
+
+// Test fetching the zones from the BIND 9 app.
+func TestGetAppZones(t *testing.T) {
+	fa := agentcommtest.NewBind9FakeAgents(mockRndcZones, mockNamed)
+	zones, err := GetAppZones(context.Background(), fa, createTestZonesApp(), nil)
+	require.NoError(t, err)
+	require.Equal(t, "http://127.0.0.1:8000/json/v1/zones", fa.RecordedStatsURL)
+
+	// The builtin zones are skipped and the zones are sorted by view and name.
+	require.Len(t, zones, 3)
+
+	require.Equal(t, "_default", zones[0].View)
+	require.Equal(t, "example.com", zones[0].Name)
+	require.Equal(t, "IN", zones[0].Class)
+	require.Equal(t, "primary", zones[0].ZoneType)
+	require.EqualValues(t, 2023031401, zones[0].Serial)
+	require.Equal(t, dbmodel.Bind9ZoneDNSSECInlineSigned, zones[0].DNSSECState)
+	require.Equal(t, "automatic", zones[0].KeyMaintenance)
+	require.Empty(t, zones[0].Primaries)
+
+	require.Equal(t, "internal", zones[1].View)
+	require.Equal(t, "example.com", zones[1].Name)
+	require.EqualValues(t, 2023031402, zones[1].Serial)
+	require.Equal(t, dbmodel.Bind9ZoneDNSSECUnsigned, zones[1].DNSSECState)
+
+	require.Equal(t, "internal", zones[2].View)
+	require.Equal(t, "example.org", zones[2].Name)
+	require.Equal(t, "secondary", zones[2].ZoneType)
+	require.Equal(t, time.Date(2023, 3, 14, 10, 35, 10, 0, time.UTC), zones[2].RefreshAt)
+	require.Equal(t, time.Date(2023, 3, 21, 9, 35, 10, 0, time.UTC), zones[2].ExpiresAt)
+	require.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, zones[2].Primaries)
+}
+
+// Test that the zones are not fetched when the statistics channel is
+// not configured.
+func TestGetAppZonesNoStatisticsChannel(t *testing.T) {
+	fa := agentcommtest.NewBind9FakeAgents(mockRndcZones, mockNamed)
+	app := createTestZonesApp()
+	app.AccessPoints = app.AccessPoints[:1]
+	zones, err := GetAppZones(context.Background(), fa, app, nil)
+	require.Error(t, err)
+	require.Nil(t, zones)
+}
+
+// Test that the details of the zones are copied from the known zones
+// when the zone serials didn't change.
+func TestGetAppZonesReuseDetails(t *testing.T) {
+	var commands []string
+	fa := agentcommtest.NewBind9FakeAgents(func(command string) string {
+		commands = append(commands, command)
+		return mockRndcZones(command)
+	}, mockNamed)
+
+	knownZones := []dbmodel.Bind9Zone{
+		// Unchanged zone.
+		{
+			View:           "_default",
+			Name:           "example.com",
+			Class:          "IN",
+			ZoneType:       "primary",
+			Serial:         2023031401,
+			DNSSECState:    dbmodel.Bind9ZoneDNSSECSigned,
+			KeyMaintenance: "manual",
+		},
+		// The serial changed.
+		{
+			View:        "internal",
+			Name:        "example.com",
+			Class:       "IN",
+			ZoneType:    "primary",
+			Serial:      2023031401,
+			DNSSECState: dbmodel.Bind9ZoneDNSSECSigned,
+		},
+		// The primaries are unknown.
+		{
+			View:        "internal",
+			Name:        "example.org",
+			Class:       "IN",
+			ZoneType:    "secondary",
+			Serial:      7,
+			DNSSECState: dbmodel.Bind9ZoneDNSSECUnsigned,
+		},
+	}
+	zones, err := GetAppZones(context.Background(), fa, createTestZonesApp(), knownZones)
+	require.NoError(t, err)
+	require.Len(t, zones, 3)
+
+	require.Equal(t, []string{
+		"zonestatus example.com IN internal",
+		"zonestatus example.org IN internal",
+		"showzone example.org IN internal",
+	}, commands)
+
+	require.Equal(t, dbmodel.Bind9ZoneDNSSECSigned, zones[0].DNSSECState)
+	require.Equal(t, "manual", zones[0].KeyMaintenance)
+	require.Equal(t, dbmodel.Bind9ZoneDNSSECUnsigned, zones[1].DNSSECState)
+	require.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, zones[2].Primaries)
+}
+
+// Test that the details of the zones are not fetched when the time
+// allowed for fetching them elapsed.
+func TestGetAppZonesPostponeDetails(t *testing.T) {
+	var commands []string
+	fa := agentcommtest.NewBind9FakeAgents(func(command string) string {
+		commands = append(commands, command)
+		return mockRndcZones(command)
+	}, mockNamed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	zones, err := GetAppZones(ctx, fa, createTestZonesApp(), nil)
+	require.NoError(t, err)
+	require.Len(t, zones, 3)
+	require.Empty(t, commands)
+	for _, zone := range zones {
+		require.Empty(t, zone.DNSSECState)
+		require.Empty(t, zone.Primaries)
+	}
+}
+
+// Check creating and shutting down ZonesPuller.
+func TestZonesPullerBasic(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	err := dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	fa := agentcommtest.NewBind9FakeAgents(mockRndcZones, mockNamed)
+
+	zp, err := NewZonesPuller(db, fa)
+	require.NoError(t, err)
+	require.NotNil(t, zp)
+	zp.Shutdown()
+}
+
+// Test that the zones pulled from the apps are stored in the database,
+// the stale zones are removed and the details of the unchanged zones are
+// not fetched again.
+func TestZonesPullerPullZones(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	err := dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	fec := &storktest.FakeEventCenter{}
+
+	machine := &dbmodel.Machine{
+		Address:   "localhost",
+		AgentPort: 8080,
+	}
+	err = dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	app := createTestZonesApp()
+	app.MachineID = machine.ID
+	app.Machine = machine
+	app.Type = dbmodel.AppTypeBind9
+	app.Daemons = []*dbmodel.Daemon{
+		dbmodel.NewBind9Daemon(true),
+	}
+	err = CommitAppIntoDB(db, app, fec)
+	require.NoError(t, err)
+
+	var commands []string
+	fa := agentcommtest.NewBind9FakeAgents(func(command string) string {
+		commands = append(commands, command)
+		return mockRndcZones(command)
+	}, mockNamed)
+
+	zp, err := NewZonesPuller(db, fa)
+	require.NoError(t, err)
+	defer zp.Shutdown()
+
+	err = zp.pullZones()
+	require.NoError(t, err)
+	require.Len(t, commands, 4)
+
+	zones, err := dbmodel.GetBind9ZonesByDaemonID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+	require.Len(t, zones, 3)
+	require.Equal(t, dbmodel.Bind9ZoneDNSSECInlineSigned, zones[0].DNSSECState)
+	require.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, zones[2].Primaries)
+
+	// The zones didn't change, so their details are not fetched again.
+	commands = nil
+	err = zp.pullZones()
+	require.NoError(t, err)
+	require.Empty(t, commands)
+
+	zones, err = dbmodel.GetBind9ZonesByDaemonID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+	require.Len(t, zones, 3)
+	require.Equal(t, dbmodel.Bind9ZoneDNSSECInlineSigned, zones[0].DNSSECState)
+	require.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, zones[2].Primaries)
+
+	// Zones of an inactive daemon are not pulled.
+	app.Daemons[0].Active = false
+	err = dbmodel.UpdateDaemon(db, app.Daemons[0])
+	require.NoError(t, err)
+	err = dbmodel.CommitBind9Zones(db, app.Daemons[0].ID, []*dbmodel.Bind9Zone{})
+	require.NoError(t, err)
+	err = zp.pullZones()
+	require.NoError(t, err)
+
+	zones, err = dbmodel.GetBind9ZonesByDaemonID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+	require.Empty(t, zones)
+}
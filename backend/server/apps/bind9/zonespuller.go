@@ -0,0 +1,84 @@
+package bind9
+
+import (
+	"context"
+
+	"github.com/go-pg/pg/v10"
+	log "github.com/sirupsen/logrus"
+	"isc.org/stork/server/agentcomm"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// The puller responsible for fetching the zones from the BIND 9 daemons.
+// The zones are pulled separately from the app state because fetching
+// their details may take long for the daemons serving many zones.
+type ZonesPuller struct {
+	*agentcomm.PeriodicPuller
+}
+
+// Create a ZonesPuller object that in background pulls the BIND 9 zones.
+// Beneath it spawns a goroutine that pulls the zones periodically from the
+// BIND 9 statistics-channel and using rndc.
+func NewZonesPuller(db *pg.DB, agents agentcomm.ConnectedAgents) (*ZonesPuller, error) {
+	zonesPuller := &ZonesPuller{}
+	periodicPuller, err := agentcomm.NewPeriodicPuller(db, agents, "BIND 9 zones puller", "bind9_zones_puller_interval",
+		zonesPuller.pullZones)
+	if err != nil {
+		return nil, err
+	}
+	zonesPuller.PeriodicPuller = periodicPuller
+	return zonesPuller, nil
+}
+
+// Shutdown ZonesPuller. It stops goroutine that pulls the zones.
+func (zonesPuller *ZonesPuller) Shutdown() {
+	zonesPuller.PeriodicPuller.Shutdown()
+}
+
+// Pull the zones periodically for all BIND 9 apps which Stork is
+// monitoring. The function returns last encountered error.
+func (zonesPuller *ZonesPuller) pullZones() error {
+	// get list of all bind9 apps from database
+	dbApps, err := dbmodel.GetAppsByType(zonesPuller.DB, dbmodel.AppTypeBind9)
+	if err != nil {
+		return err
+	}
+
+	// get zones from each bind9 app
+	var lastErr error
+	appsOkCnt := 0
+	for _, dbApp := range dbApps {
+		dbApp2 := dbApp
+		err := zonesPuller.getZonesFromApp(&dbApp2)
+		if err != nil {
+			lastErr = err
+			log.Errorf("Error occurred while getting zones from app %d: %+v", dbApp.ID, err)
+		} else {
+			appsOkCnt++
+		}
+	}
+	log.Printf("Completed pulling zones from BIND 9 apps: %d/%d succeeded", appsOkCnt, len(dbApps))
+	return lastErr
+}
+
+// Get the zones from given bind9 app and store them in the database. The
+// zones stored in the database are used to avoid fetching the details of
+// the zones which didn't change.
+func (zonesPuller *ZonesPuller) getZonesFromApp(dbApp *dbmodel.App) error {
+	// if app or daemon not active then do nothing
+	if len(dbApp.Daemons) == 0 || !dbApp.Daemons[0].Active {
+		return nil
+	}
+	daemon := dbApp.Daemons[0]
+
+	knownZones, err := dbmodel.GetBind9ZonesByDaemonID(zonesPuller.DB, daemon.ID)
+	if err != nil {
+		return err
+	}
+
+	zones, err := GetAppZones(context.Background(), zonesPuller.Agents, dbApp, knownZones)
+	if err != nil {
+		return err
+	}
+	return dbmodel.CommitBind9Zones(zonesPuller.DB, daemon.ID, zones)
+}
@@ -0,0 +1,413 @@
+package kea
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	keaconfig "isc.org/stork/appcfg/kea"
+	keactrl "isc.org/stork/appctrl/kea"
+	"isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+	storkutil "isc.org/stork/util"
+)
+
+// Types of the configured objects recorded in the config change log.
+const (
+	ConfigChangeObjectHost             = "host"
+	ConfigChangeObjectSubnet           = "subnet"
+	ConfigChangeObjectSharedNetwork    = "shared-network"
+	ConfigChangeObjectGlobalParameters = "global-parameters"
+	ConfigChangeObjectConfigReport     = "config-report"
+)
+
+// Records the config updates belonging to the transaction in the config
+// change log. Each update is recorded as a separate entry holding the
+// user who made the change, the affected daemons, the commands sent to
+// the daemons and the configured object's state before and after the
+// change. The entries are also recorded when the commit fails. In this
+// case they hold the error message. A failure to record the entries is
+// logged but it does not affect the commit.
+func (module *ConfigModule) recordConfigChanges(ctx context.Context, commitErr error) {
+	module.addConfigChangeLogEntries(ctx, time.Time{}, commitErr)
+}
+
+// Records the config updates belonging to the transaction in the config
+// change log when they are scheduled. The entries are marked scheduled and
+// hold the deadline of the change. The updates are recorded again when
+// they are committed.
+func (module *ConfigModule) RecordSchedule(ctx context.Context, deadline time.Time) {
+	module.addConfigChangeLogEntries(ctx, deadline, nil)
+}
+
+// Adds the config change log entries for the config updates belonging to
+// the transaction. The deadline is zero when the updates are committed.
+func (module *ConfigModule) addConfigChangeLogEntries(ctx context.Context, deadline time.Time, commitErr error) {
+	if module.manager == nil || module.manager.GetDB() == nil {
+		return
+	}
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return
+	}
+	var (
+		userID       int64
+		userIdentity string
+	)
+	if id, ok := config.GetValueAsInt64(ctx, config.UserContextKey); ok {
+		user, err := dbmodel.GetUserByID(module.manager.GetDB(), int(id))
+		if err != nil {
+			log.WithError(err).Warnf("Problem getting user %d committing the Kea configuration changes", id)
+		}
+		if user != nil {
+			userID = id
+			userIdentity = user.Identity()
+		}
+	}
+	for _, update := range state.Updates {
+		entry := module.newConfigChangeLogEntry(update)
+		entry.UserID = userID
+		entry.UserIdentity = userIdentity
+		entry.Scheduled = state.Scheduled || !deadline.IsZero()
+		entry.DeadlineAt = deadline
+		if commitErr != nil {
+			entry.Error = commitErr.Error()
+		}
+		if err := dbmodel.AddConfigChangeLogEntry(module.manager.GetDB(), entry); err != nil {
+			log.WithError(err).Warnf("Problem recording Kea configuration change %s in the config change log", update.Operation)
+		}
+	}
+}
+
+// Creates the config change log entry for the config update. It sets
+// the configured object, the summary of the commands and the object's
+// state before and after the update. The sensitive data, e.g., passwords,
+// are hidden in the recorded commands and states.
+func (module *ConfigModule) newConfigChangeLogEntry(update *config.Update[ConfigRecipe]) *dbmodel.ConfigChangeLogEntry {
+	entry := &dbmodel.ConfigChangeLogEntry{
+		Target:    update.Target,
+		Operation: update.Operation,
+		DaemonIDs: update.DaemonIDs,
+	}
+	if commands, err := getCommandsSummary(update.Recipe.Commands); err == nil {
+		entry.Recipe = commands
+	} else {
+		log.WithError(err).Warnf("Problem summarizing the commands for the config change log")
+	}
+
+	var (
+		before map[string]any
+		after  map[string]any
+		err    error
+	)
+	recipe := update.Recipe
+	switch update.Operation {
+	case "host_add", "host_update", "host_delete":
+		entry.ObjectType = ConfigChangeObjectHost
+		if recipe.HostAfterUpdate != nil {
+			entry.ObjectID = recipe.HostAfterUpdate.ID
+		}
+		if recipe.HostBeforeUpdate != nil {
+			entry.ObjectID = getFirstNonZeroID(entry.ObjectID, recipe.HostBeforeUpdate.ID)
+		}
+		if recipe.HostID != nil {
+			entry.ObjectID = getFirstNonZeroID(entry.ObjectID, *recipe.HostID)
+		}
+		if before, err = module.getHostState(recipe.HostBeforeUpdate); err == nil {
+			after, err = module.getHostState(recipe.HostAfterUpdate)
+		}
+	case "hosts_import":
+		// The batch comprises many hosts, so the entry does not refer
+		// to any particular host.
+		entry.ObjectType = ConfigChangeObjectHost
+		after, err = module.getImportedHostsState(recipe.ImportedHosts)
+	case "subnet_add", "subnet_update", "subnet_delete":
+		entry.ObjectType = ConfigChangeObjectSubnet
+		if recipe.SubnetAfterUpdate != nil {
+			entry.ObjectID = recipe.SubnetAfterUpdate.ID
+		}
+		if recipe.SubnetBeforeUpdate != nil {
+			entry.ObjectID = getFirstNonZeroID(entry.ObjectID, recipe.SubnetBeforeUpdate.ID)
+		}
+		if recipe.SubnetID != nil {
+			entry.ObjectID = getFirstNonZeroID(entry.ObjectID, *recipe.SubnetID)
+		}
+		if before, err = module.getSubnetState(recipe.SubnetBeforeUpdate); err == nil {
+			after, err = module.getSubnetState(recipe.SubnetAfterUpdate)
+		}
+	case "shared_network_add", "shared_network_update", "shared_network_delete":
+		entry.ObjectType = ConfigChangeObjectSharedNetwork
+		if recipe.SharedNetworkAfterUpdate != nil {
+			entry.ObjectID = recipe.SharedNetworkAfterUpdate.ID
+		}
+		if recipe.SharedNetworkBeforeUpdate != nil {
+			entry.ObjectID = getFirstNonZeroID(entry.ObjectID, recipe.SharedNetworkBeforeUpdate.ID)
+		}
+		if recipe.SharedNetworkID != nil {
+			entry.ObjectID = getFirstNonZeroID(entry.ObjectID, *recipe.SharedNetworkID)
+		}
+		if before, err = module.getSharedNetworkState(recipe.SharedNetworkBeforeUpdate); err == nil {
+			after, err = module.getSharedNetworkState(recipe.SharedNetworkAfterUpdate)
+		}
+	case "global_parameters_update":
+		entry.ObjectType = ConfigChangeObjectGlobalParameters
+		before, after = getGlobalParametersState(recipe.DaemonsBeforeUpdate, recipe.ConfigsAfterUpdate)
+	case "config_report_fix":
+		entry.ObjectType = ConfigChangeObjectConfigReport
+		if recipe.ConfigReportID != nil {
+			entry.ObjectID = *recipe.ConfigReportID
+		}
+		setConfigReportFixState(entry, &recipe)
+		return entry
+	}
+	if err == nil {
+		if before, err = hideSensitiveStateData(before); err == nil {
+			after, err = hideSensitiveStateData(after)
+		}
+	}
+	if err != nil {
+		log.WithError(err).Warnf("Problem converting the object to the Kea format for the config change log")
+		return entry
+	}
+	if before != nil {
+		entry.StateBefore = before
+	}
+	if after != nil {
+		entry.StateAfter = after
+	}
+	if before != nil || after != nil {
+		if entry.Diff, err = storkutil.CompareJSON(before, after); err != nil {
+			log.WithError(err).Warnf("Problem comparing the object states for the config change log")
+		}
+	}
+	return entry
+}
+
+// Returns the summary of the commands recorded in the config change log.
+// The configurations sent in the config-set and config-test commands are
+// not recorded because the entry holds the differences between the
+// configurations. The sensitive data in the arguments of the other
+// commands are hidden.
+func getCommandsSummary(commands []ConfigCommand) ([]*keactrl.Command, error) {
+	var summary []*keactrl.Command
+	for _, command := range commands {
+		if command.Command == nil {
+			continue
+		}
+		summarized := &keactrl.Command{
+			Command: command.Command.Command,
+			Daemons: command.Command.Daemons,
+		}
+		switch command.Command.Command {
+		case "config-set", "config-test":
+		default:
+			arguments, err := keaconfig.HideSensitiveDataInValue(command.Command.Arguments)
+			if err != nil {
+				return nil, err
+			}
+			summarized.Arguments = arguments
+		}
+		summary = append(summary, summarized)
+	}
+	return summary, nil
+}
+
+// Returns a copy of the object's state by daemon ID with the sensitive
+// data hidden.
+func hideSensitiveStateData(state map[string]any) (map[string]any, error) {
+	if state == nil {
+		return nil, nil
+	}
+	hidden, err := keaconfig.HideSensitiveDataInValue(state)
+	if err != nil {
+		return nil, err
+	}
+	hiddenState, _ := hidden.(map[string]any)
+	return hiddenState, nil
+}
+
+// Returns the first non-zero ID. It is used to select the ID of the
+// configured object from the object after the update (holding the ID
+// assigned to a new object), the object before the update and the ID
+// of a deleted object.
+func getFirstNonZeroID(ids ...int64) int64 {
+	for _, id := range ids {
+		if id != 0 {
+			return id
+		}
+	}
+	return 0
+}
+
+// Returns the host reservations in the Kea format by daemon ID.
+func (module *ConfigModule) getHostState(host *dbmodel.Host) (map[string]any, error) {
+	if host == nil {
+		return nil, nil
+	}
+	state := make(map[string]any)
+	lookup := module.getDHCPOptionDefinitionLookup()
+	for _, lh := range host.LocalHosts {
+		reservation, err := keaconfig.CreateHostCmdsReservation(lh.DaemonID, lookup, host)
+		if err != nil {
+			return nil, err
+		}
+		state[fmt.Sprint(lh.DaemonID)] = reservation
+	}
+	return state, nil
+}
+
+// Returns the lists of the imported host reservations in the Kea format
+// by daemon ID.
+func (module *ConfigModule) getImportedHostsState(hosts []*dbmodel.Host) (map[string]any, error) {
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+	reservations := make(map[string][]any)
+	for _, host := range hosts {
+		hostState, err := module.getHostState(host)
+		if err != nil {
+			return nil, err
+		}
+		for daemonID, reservation := range hostState {
+			reservations[daemonID] = append(reservations[daemonID], reservation)
+		}
+	}
+	state := make(map[string]any)
+	for daemonID, list := range reservations {
+		state[daemonID] = list
+	}
+	return state, nil
+}
+
+// Returns the subnets in the Kea format by daemon ID.
+func (module *ConfigModule) getSubnetState(subnet *dbmodel.Subnet) (map[string]any, error) {
+	if subnet == nil {
+		return nil, nil
+	}
+	state := make(map[string]any)
+	lookup := module.getDHCPOptionDefinitionLookup()
+	for _, ls := range subnet.LocalSubnets {
+		var (
+			keaSubnet any
+			err       error
+		)
+		if subnet.GetFamily() == 4 {
+			keaSubnet, err = keaconfig.CreateSubnet4(ls.DaemonID, lookup, subnet)
+		} else {
+			keaSubnet, err = keaconfig.CreateSubnet6(ls.DaemonID, lookup, subnet)
+		}
+		if err != nil {
+			return nil, err
+		}
+		state[fmt.Sprint(ls.DaemonID)] = keaSubnet
+	}
+	return state, nil
+}
+
+// Returns the shared networks in the Kea format by daemon ID.
+func (module *ConfigModule) getSharedNetworkState(sharedNetwork *dbmodel.SharedNetwork) (map[string]any, error) {
+	if sharedNetwork == nil {
+		return nil, nil
+	}
+	state := make(map[string]any)
+	lookup := module.getDHCPOptionDefinitionLookup()
+	for _, lsn := range sharedNetwork.LocalSharedNetworks {
+		var (
+			keaSharedNetwork any
+			err              error
+		)
+		if sharedNetwork.Family == 4 {
+			keaSharedNetwork, err = keaconfig.CreateSharedNetwork4(lsn.DaemonID, lookup, sharedNetwork)
+		} else {
+			keaSharedNetwork, err = keaconfig.CreateSharedNetwork6(lsn.DaemonID, lookup, sharedNetwork)
+		}
+		if err != nil {
+			return nil, err
+		}
+		state[fmt.Sprint(lsn.DaemonID)] = keaSharedNetwork
+	}
+	return state, nil
+}
+
+// Returns the global parameters before and after the update by daemon ID.
+func getGlobalParametersState(daemons []*dbmodel.Daemon, configs map[int64]*dbmodel.KeaConfig) (before, after map[string]any) {
+	for _, daemon := range daemons {
+		if daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+			continue
+		}
+		if before == nil {
+			before = make(map[string]any)
+		}
+		before[fmt.Sprint(daemon.ID)] = daemon.KeaDaemon.Config.GetSettableGlobalParameters()
+	}
+	for daemonID, cfg := range configs {
+		if cfg == nil || cfg.Config == nil {
+			continue
+		}
+		if after == nil {
+			after = make(map[string]any)
+		}
+		after[fmt.Sprint(daemonID)] = cfg.GetSettableGlobalParameters()
+	}
+	return
+}
+
+// Sets the state of the config change log entry for the applied config
+// report fix. The fix may change any part of the configuration, so the
+// entry holds the applied patch and the differences between the
+// configurations rather than the entire configurations. The sensitive
+// data are hidden in the configurations before comparing them.
+func setConfigReportFixState(entry *dbmodel.ConfigChangeLogEntry, recipe *ConfigRecipe) {
+	before, after := getDaemonConfigsState(recipe.DaemonsBeforeUpdate, recipe.ConfigsAfterUpdate)
+	before, err := hideSensitiveStateData(before)
+	if err == nil {
+		after, err = hideSensitiveStateData(after)
+	}
+	if err == nil {
+		entry.Diff, err = storkutil.CompareJSON(before, after)
+	}
+	if err != nil {
+		log.WithError(err).Warnf("Problem comparing the configurations for the config change log")
+	}
+	if recipe.ConfigReportFix == nil {
+		return
+	}
+	patch, err := keaconfig.HideSensitiveDataInValue(recipe.ConfigReportFix.Patch)
+	if err != nil {
+		log.WithError(err).Warnf("Problem recording the config report fix in the config change log")
+		return
+	}
+	state := make(map[string]any)
+	for daemonID := range recipe.ConfigsAfterUpdate {
+		state[fmt.Sprint(daemonID)] = map[string]any{
+			"description": recipe.ConfigReportFix.Description,
+			"patch":       patch,
+		}
+	}
+	entry.StateAfter = state
+}
+
+// Returns the entire daemon configurations before and after the update by
+// daemon ID.
+func getDaemonConfigsState(daemons []*dbmodel.Daemon, configs map[int64]*dbmodel.KeaConfig) (before, after map[string]any) {
+	for _, daemon := range daemons {
+		if daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+			continue
+		}
+		if before == nil {
+			before = make(map[string]any)
+		}
+		before[fmt.Sprint(daemon.ID)] = daemon.KeaDaemon.Config
+	}
+	for daemonID, cfg := range configs {
+		if cfg == nil || cfg.Config == nil {
+			continue
+		}
+		if after == nil {
+			after = make(map[string]any)
+		}
+		after[fmt.Sprint(daemonID)] = cfg
+	}
+	return
+}
@@ -0,0 +1,398 @@
+package kea
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
+	keactrl "isc.org/stork/appctrl/kea"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	appstest "isc.org/stork/server/apps/test"
+	"isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktestdbmodel "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
+)
+
+// Test creating the config change log entry for the host update.
+func TestNewConfigChangeLogEntryHostUpdate(t *testing.T) {
+	module := NewConfigModule(nil)
+
+	hostBefore := createTestHostForTwoPhaseCommit()
+	hostAfter := createTestHostForTwoPhaseCommit()
+	hostAfter.Hostname = "modified.example.org"
+
+	update := config.NewUpdate[ConfigRecipe]("kea", "host_update", 1, 2)
+	update.Recipe = ConfigRecipe{
+		Commands: []ConfigCommand{
+			{
+				Command: keactrl.NewCommand("reservation-del", []string{"dhcp4"}, nil),
+			},
+		},
+		HostConfigRecipeParams: HostConfigRecipeParams{
+			HostBeforeUpdate: hostBefore,
+			HostAfterUpdate:  hostAfter,
+		},
+	}
+
+	entry := module.newConfigChangeLogEntry(update)
+	require.NotNil(t, entry)
+	require.EqualValues(t, "kea", entry.Target)
+	require.Equal(t, "host_update", entry.Operation)
+	require.Equal(t, []int64{1, 2}, entry.DaemonIDs)
+	require.Equal(t, ConfigChangeObjectHost, entry.ObjectType)
+	require.EqualValues(t, 1, entry.ObjectID)
+
+	commands, ok := entry.Recipe.([]*keactrl.Command)
+	require.True(t, ok)
+	require.Len(t, commands, 1)
+	require.Equal(t, "reservation-del", commands[0].GetCommand())
+
+	require.IsType(t, map[string]any{}, entry.StateBefore)
+	require.Len(t, entry.StateBefore, 2)
+	require.Contains(t, entry.StateBefore, "1")
+	require.Contains(t, entry.StateAfter, "2")
+
+	require.Len(t, entry.Diff, 2)
+	require.Equal(t, "/1/hostname", entry.Diff[0].Path)
+	require.Equal(t, "cool.example.org", entry.Diff[0].Before)
+	require.Equal(t, "modified.example.org", entry.Diff[0].After)
+	require.Equal(t, "/2/hostname", entry.Diff[1].Path)
+}
+
+// Test creating the config change log entry for the imported hosts.
+func TestNewConfigChangeLogEntryHostsImport(t *testing.T) {
+	module := NewConfigModule(nil)
+
+	host1 := createTestHostForTwoPhaseCommit()
+	host2 := createTestHostForTwoPhaseCommit()
+	host2.ID = 2
+	host2.Hostname = "other.example.org"
+
+	update := config.NewUpdate[ConfigRecipe]("kea", "hosts_import", 1, 2)
+	update.Recipe = ConfigRecipe{
+		HostConfigRecipeParams: HostConfigRecipeParams{
+			ImportedHosts: []*dbmodel.Host{host1, host2},
+		},
+	}
+	entry := module.newConfigChangeLogEntry(update)
+	require.NotNil(t, entry)
+	require.Equal(t, ConfigChangeObjectHost, entry.ObjectType)
+	require.Zero(t, entry.ObjectID)
+	require.Nil(t, entry.StateBefore)
+
+	// The state holds the lists of reservations by daemon.
+	require.IsType(t, map[string]any{}, entry.StateAfter)
+	state := entry.StateAfter.(map[string]any)
+	require.Len(t, state, 2)
+	require.Len(t, state["1"], 2)
+	require.Len(t, state["2"], 2)
+	require.Len(t, entry.Diff, 1)
+	require.Equal(t, "/", entry.Diff[0].Path)
+	require.Nil(t, entry.Diff[0].Before)
+}
+
+// Test creating the config change log entry for the deleted host.
+func TestNewConfigChangeLogEntryHostDelete(t *testing.T) {
+	module := NewConfigModule(nil)
+
+	update := config.NewUpdate[ConfigRecipe]("kea", "host_delete", 1)
+	update.Recipe = ConfigRecipe{
+		HostConfigRecipeParams: HostConfigRecipeParams{
+			HostID: storkutil.Ptr(int64(5)),
+		},
+	}
+	entry := module.newConfigChangeLogEntry(update)
+	require.NotNil(t, entry)
+	require.Equal(t, ConfigChangeObjectHost, entry.ObjectType)
+	require.EqualValues(t, 5, entry.ObjectID)
+	require.Nil(t, entry.StateBefore)
+	require.Nil(t, entry.StateAfter)
+	require.Empty(t, entry.Diff)
+}
+
+// Test creating the config change log entry for the global parameters
+// update.
+func TestNewConfigChangeLogEntryGlobalParameters(t *testing.T) {
+	module := NewConfigModule(nil)
+
+	daemon := createTestDaemonForGlobalParametersUpdate(t, 1)
+	ctx := createTestGlobalParametersUpdateContext(t, daemon)
+	ctx, err := module.ApplyGlobalParametersUpdate(ctx, map[int64]*keaconfig.SettableGlobalParameters{
+		1: {
+			ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+				ValidLifetime: storkutil.Ptr(int64(1800)),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, state.Updates, 1)
+
+	entry := module.newConfigChangeLogEntry(state.Updates[0])
+	require.NotNil(t, entry)
+	require.Equal(t, ConfigChangeObjectGlobalParameters, entry.ObjectType)
+	require.Zero(t, entry.ObjectID)
+
+	// The renew timer is removed because it was not specified in the
+	// updated parameters.
+	require.Len(t, entry.Diff, 2)
+	require.Equal(t, "/1/renew-timer", entry.Diff[0].Path)
+	require.EqualValues(t, 900, entry.Diff[0].Before)
+	require.Nil(t, entry.Diff[0].After)
+	require.Equal(t, "/1/valid-lifetime", entry.Diff[1].Path)
+	require.EqualValues(t, 3600, entry.Diff[1].Before)
+	require.EqualValues(t, 1800, entry.Diff[1].After)
+}
+
+// Test creating the config change log entry for the config report fix.
+func TestNewConfigChangeLogEntryConfigReportFix(t *testing.T) {
+	module := NewConfigModule(nil)
+
+	daemon := createTestDaemonForGlobalParametersUpdate(t, 1)
+	ctx := createTestConfigReportFixContext(t, daemon, &dbmodel.ConfigReportFix{
+		Description: "Replace the non-canonical prefix.",
+		Patch: []storkutil.JSONPatchOperation{
+			{Op: "replace", Path: "/Dhcp4/subnet4/0/subnet", Value: "192.0.3.0/24"},
+		},
+	})
+	ctx, err := module.ApplyConfigReportFix(ctx)
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+
+	entry := module.newConfigChangeLogEntry(state.Updates[0])
+	require.NotNil(t, entry)
+	require.Equal(t, ConfigChangeObjectConfigReport, entry.ObjectType)
+	require.EqualValues(t, 1, entry.ObjectID)
+
+	// The subnet list is compared as a whole.
+	require.Len(t, entry.Diff, 1)
+	require.Equal(t, "/1/Dhcp4/subnet4", entry.Diff[0].Path)
+
+	// The entry holds the applied patch rather than the configurations.
+	require.Nil(t, entry.StateBefore)
+	marshalled, err := json.Marshal(entry.StateAfter)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"1": {
+			"description": "Replace the non-canonical prefix.",
+			"patch": [
+				{ "op": "replace", "path": "/Dhcp4/subnet4/0/subnet", "value": "192.0.3.0/24" }
+			]
+		}
+	}`, string(marshalled))
+}
+
+// Test that the sensitive data are hidden in the config change log entry
+// for the config report fix.
+func TestNewConfigChangeLogEntryConfigReportFixSensitiveData(t *testing.T) {
+	module := NewConfigModule(nil)
+
+	daemon := createTestDaemonForGlobalParametersUpdate(t, 1)
+	ctx := createTestConfigReportFixContext(t, daemon, &dbmodel.ConfigReportFix{
+		Description: "Add the hosts database.",
+		Patch: []storkutil.JSONPatchOperation{
+			{
+				Op:   "add",
+				Path: "/Dhcp4/hosts-database",
+				Value: map[string]any{
+					"type":     "mysql",
+					"password": "xyz",
+				},
+			},
+		},
+	})
+	ctx, err := module.ApplyConfigReportFix(ctx)
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+
+	entry := module.newConfigChangeLogEntry(state.Updates[0])
+	require.NotNil(t, entry)
+
+	require.Len(t, entry.Diff, 1)
+	require.Equal(t, "/1/Dhcp4/hosts-database", entry.Diff[0].Path)
+	require.Nil(t, entry.Diff[0].Before)
+	require.Equal(t, map[string]any{"type": "mysql", "password": nil}, entry.Diff[0].After)
+
+	marshalled, err := json.Marshal(entry)
+	require.NoError(t, err)
+	require.NotContains(t, string(marshalled), "xyz")
+}
+
+// Test that the configurations sent in the config-set and config-test
+// commands are not recorded in the config change log and the sensitive
+// data are hidden in the other commands.
+func TestNewConfigChangeLogEntryCommandsSummary(t *testing.T) {
+	module := NewConfigModule(nil)
+
+	daemonConfig := map[string]any{
+		"Dhcp4": map[string]any{
+			"lease-database": map[string]any{
+				"type":     "mysql",
+				"password": "secret-password",
+			},
+		},
+	}
+	update := config.NewUpdate[ConfigRecipe]("kea", "global_parameters_update", 1)
+	update.Recipe = ConfigRecipe{
+		Commands: []ConfigCommand{
+			{Command: keactrl.NewCommand("config-test", []string{"dhcp4"}, daemonConfig)},
+			{Command: keactrl.NewCommand("config-set", []string{"dhcp4"}, daemonConfig)},
+			{Command: keactrl.NewCommand("remote-server4-set", []string{"dhcp4"}, map[string]any{
+				"servers": []any{
+					map[string]any{"server-tag": "foo", "secret": "xyz"},
+				},
+			})},
+		},
+	}
+
+	entry := module.newConfigChangeLogEntry(update)
+	require.NotNil(t, entry)
+
+	marshalled, err := json.Marshal(entry.Recipe)
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{ "command": "config-test", "service": [ "dhcp4" ] },
+		{ "command": "config-set", "service": [ "dhcp4" ] },
+		{
+			"command": "remote-server4-set",
+			"service": [ "dhcp4" ],
+			"arguments": { "servers": [ { "server-tag": "foo", "secret": null } ] }
+		}
+	]`, string(marshalled))
+
+	// The original commands are not modified.
+	require.Equal(t, daemonConfig, update.Recipe.Commands[1].Command.Arguments)
+}
+
+// Test that the sensitive data are hidden in the object's state recorded
+// in the config change log.
+func TestHideSensitiveStateData(t *testing.T) {
+	state, err := hideSensitiveStateData(map[string]any{
+		"1": map[string]any{
+			"hostname": "foo",
+			"password": "xyz",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"1": map[string]any{
+			"hostname": "foo",
+			"password": nil,
+		},
+	}, state)
+
+	state, err = hideSensitiveStateData(nil)
+	require.NoError(t, err)
+	require.Nil(t, state)
+}
+
+// Test that the committed host deletion is recorded in the config change
+// log.
+func TestCommitRecordsConfigChange(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	user := &dbmodel.SystemUser{
+		Login:    "test",
+		Lastname: "test",
+		Name:     "test",
+	}
+	_, err := dbmodel.CreateUserWithPassword(db, user, "test")
+	require.NoError(t, err)
+
+	hosts, apps := storktestdbmodel.AddTestHosts(t, db)
+	err = dbmodel.AddDaemonToHost(db, &hosts[0], apps[0].Daemons[0].ID, dbmodel.HostDataSourceAPI)
+	require.NoError(t, err)
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	daemonIDs := []int64{apps[0].Daemons[0].ID}
+	ctx := context.WithValue(context.Background(), config.DaemonsContextKey, daemonIDs)
+	ctx = context.WithValue(ctx, config.UserContextKey, int64(user.ID))
+
+	host, err := dbmodel.GetHost(db, hosts[0].ID)
+	require.NoError(t, err)
+	ctx, err = module.ApplyHostDelete(ctx, host)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	entries, total, err := dbmodel.GetConfigChangeLogEntriesByPage(db, 0, 10, nil, "", dbmodel.SortDirAny)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Len(t, entries, 1)
+
+	entry := entries[0]
+	require.EqualValues(t, user.ID, entry.UserID)
+	require.Equal(t, user.Identity(), entry.UserIdentity)
+	require.Equal(t, "host_delete", entry.Operation)
+	require.Equal(t, daemonIDs, entry.DaemonIDs)
+	require.Equal(t, ConfigChangeObjectHost, entry.ObjectType)
+	require.Equal(t, host.ID, entry.ObjectID)
+	require.NotNil(t, entry.Recipe)
+	require.NotNil(t, entry.StateBefore)
+	require.Nil(t, entry.StateAfter)
+	require.NotEmpty(t, entry.Diff)
+	require.Empty(t, entry.Error)
+	require.False(t, entry.Scheduled)
+}
+
+// Test that the scheduled host deletion is recorded in the config change
+// log with the deadline.
+func TestRecordScheduleRecordsConfigChange(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	hosts, apps := storktestdbmodel.AddTestHosts(t, db)
+	err := dbmodel.AddDaemonToHost(db, &hosts[0], apps[0].Daemons[0].ID, dbmodel.HostDataSourceAPI)
+	require.NoError(t, err)
+
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agentcommtest.NewKeaFakeAgents(),
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	daemonIDs := []int64{apps[0].Daemons[0].ID}
+	ctx := context.WithValue(context.Background(), config.DaemonsContextKey, daemonIDs)
+
+	host, err := dbmodel.GetHost(db, hosts[0].ID)
+	require.NoError(t, err)
+	ctx, err = module.ApplyHostDelete(ctx, host)
+	require.NoError(t, err)
+
+	deadline := storkutil.UTCNow().Add(time.Hour)
+	module.RecordSchedule(ctx, deadline)
+
+	entries, total, err := dbmodel.GetConfigChangeLogEntriesByPage(db, 0, 10, nil, "", dbmodel.SortDirAny)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Len(t, entries, 1)
+
+	entry := entries[0]
+	require.Equal(t, "host_delete", entry.Operation)
+	require.Equal(t, host.ID, entry.ObjectID)
+	require.True(t, entry.Scheduled)
+	require.WithinDuration(t, deadline, entry.DeadlineAt, time.Second)
+	require.NotNil(t, entry.StateBefore)
+	require.Empty(t, entry.Error)
+}
@@ -3,7 +3,9 @@ package kea
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
+	"github.com/go-pg/pg/v10"
 	pkgerrors "github.com/pkg/errors"
 	keaconfig "isc.org/stork/appcfg/kea"
 	keactrl "isc.org/stork/appctrl/kea"
@@ -19,14 +21,30 @@ var _ config.TransactionStateAccessor = (*config.TransactionState[ConfigRecipe])
 type ConfigCommand struct {
 	Command *keactrl.Command
 	App     *dbmodel.App
+	// Commands reverting the changes applied by the command. They are
+	// sent to the same app, in order, when the two-phase commit fails
+	// after this command has been applied.
+	CompensatingCommands []*keactrl.Command `json:",omitempty"`
+	// Indicates that the command only validates the changes (e.g.,
+	// config-test). Such commands are sent in the validation phase of
+	// the two-phase commit.
+	Validation bool `json:",omitempty"`
+}
+
+// Appends the commands reverting the changes applied by this command.
+// The compensating commands must be sent to the same app as this command.
+func (command *ConfigCommand) addCompensatingCommands(commands ...*ConfigCommand) {
+	for _, c := range commands {
+		command.CompensatingCommands = append(command.CompensatingCommands, c.Command)
+	}
 }
 
 // A structure embedded in the ConfigRecipe grouping parameters used
 // in transactions adding, updating and deleting host reservations.
 type HostConfigRecipeParams struct {
-	// An instance of the host (reservation) before an update. It is
-	// typically fetched at the beginning of the host update (e.g., when a
-	// user clicks the host edit button).
+	// An instance of the host (reservation) before an update or deletion.
+	// It is typically fetched at the beginning of the host update (e.g.,
+	// when a user clicks the host edit button).
 	HostBeforeUpdate *dbmodel.Host
 	// An instance of the host (reservation) after it has been added or
 	// updated. This instance is held in the context until it is committed
@@ -35,6 +53,64 @@ type HostConfigRecipeParams struct {
 	HostAfterUpdate *dbmodel.Host
 	// Edited or deleted host ID.
 	HostID *int64
+	// Instances of the hosts (reservations) added in a batch when the
+	// hosts are imported. They are held in the context until they are
+	// committed.
+	ImportedHosts []*dbmodel.Host `json:",omitempty"`
+}
+
+// A structure embedded in the ConfigRecipe grouping parameters used
+// in transactions adding, updating and deleting subnets.
+type SubnetConfigRecipeParams struct {
+	// An instance of the subnet before an update or deletion. It is
+	// fetched at the beginning of the subnet update.
+	SubnetBeforeUpdate *dbmodel.Subnet
+	// An instance of the subnet after it has been added or updated. This
+	// instance is held in the context until it is committed or scheduled
+	// for committing later.
+	SubnetAfterUpdate *dbmodel.Subnet
+	// Deleted subnet ID.
+	SubnetID *int64
+}
+
+// A structure embedded in the ConfigRecipe grouping parameters used
+// in transactions adding, updating and deleting shared networks.
+type SharedNetworkConfigRecipeParams struct {
+	// An instance of the shared network before an update or deletion.
+	// It is fetched at the beginning of the shared network update.
+	SharedNetworkBeforeUpdate *dbmodel.SharedNetwork
+	// An instance of the shared network after it has been added or updated.
+	// This instance is held in the context until it is committed or
+	// scheduled for committing later.
+	SharedNetworkAfterUpdate *dbmodel.SharedNetwork
+	// Deleted shared network ID.
+	SharedNetworkID *int64
+}
+
+// A structure embedded in the ConfigRecipe grouping parameters used
+// in transactions updating global DHCP parameters. They are also used in
+// other transactions replacing the entire daemon configurations, e.g.,
+// applying the config report fixes.
+type GlobalParametersConfigRecipeParams struct {
+	// Daemons whose global parameters are updated. They are fetched at
+	// the beginning of the update and hold the configurations before
+	// the update.
+	DaemonsBeforeUpdate []*dbmodel.Daemon
+	// Updated configurations of the daemons by daemon ID. They are held
+	// in the context until they are committed or scheduled for committing
+	// later.
+	ConfigsAfterUpdate map[int64]*dbmodel.KeaConfig
+}
+
+// A structure embedded in the ConfigRecipe grouping parameters used
+// in transactions applying the fixes proposed in the config reports.
+// The daemon configurations before and after applying the fix are held
+// in the GlobalParametersConfigRecipeParams.
+type ConfigReportFixConfigRecipeParams struct {
+	// ID of the config report proposing the fix.
+	ConfigReportID *int64
+	// Applied fix.
+	ConfigReportFix *dbmodel.ConfigReportFix
 }
 
 // Represents a Kea config change recipe. A recipe is associated with
@@ -42,9 +118,8 @@ type HostConfigRecipeParams struct {
 // Kea servers. Other data stored in the recipe structure are used in the
 // Kea config module to pass the information between various configuration
 // stages (begin, apply, commit/schedule). This structure is meant to be
-// generic for different configuration use cases in Kea. Should we need to
-// support a subnet configuration we will extend it with a new embedded
-// structure holding parameters appropriate for this new use case.
+// generic for different configuration use cases in Kea. Each use case
+// has its own embedded structure holding appropriate parameters.
 type ConfigRecipe struct {
 	// A list of commands and the corresponding targets to be sent to
 	// apply a configuration update.
@@ -52,6 +127,18 @@ type ConfigRecipe struct {
 	// Embedded structure holding the parameters appropriate for the
 	// host management.
 	HostConfigRecipeParams
+	// Embedded structure holding the parameters appropriate for the
+	// subnet management.
+	SubnetConfigRecipeParams
+	// Embedded structure holding the parameters appropriate for the
+	// shared network management.
+	SharedNetworkConfigRecipeParams
+	// Embedded structure holding the parameters appropriate for the
+	// global parameters management.
+	GlobalParametersConfigRecipeParams
+	// Embedded structure holding the parameters appropriate for applying
+	// the config report fixes.
+	ConfigReportFixConfigRecipeParams
 }
 
 // A configuration manager module responsible for the Kea configuration.
@@ -83,13 +170,42 @@ func NewConfigModule(manager config.ModuleManager) *ConfigModule {
 	}
 }
 
-// Commits the Kea configuration changes.
+// Returns the interface to the DHCP option definition lookup provided by
+// the manager owning the module. It returns nil if the module has no
+// manager.
+func (module *ConfigModule) getDHCPOptionDefinitionLookup() keaconfig.DHCPOptionDefinitionLookup {
+	if module.manager == nil {
+		return nil
+	}
+	return module.manager.GetDHCPOptionDefinitionLookup()
+}
+
+// Commits the Kea configuration changes. If the two-phase commit is enabled
+// in the settings, the changes are first validated without applying them.
+// The changes are applied only if the validation passes. If any of the
+// daemons rejects the changes, the changes already applied in the other
+// daemons are rolled back. The outcome of the two-phase commit is recorded
+// as a single event. Regardless of the commit mode, the committed changes
+// are recorded in the config change log.
 func (module *ConfigModule) Commit(ctx context.Context) (context.Context, error) {
 	var err error
 	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
 	if !ok {
 		return ctx, pkgerrors.Errorf("context lacks state")
 	}
+	defer func() {
+		module.recordConfigChanges(ctx, err)
+	}()
+	if module.isTwoPhaseCommitEnabled() {
+		journal := &commitJournal{}
+		ctx = context.WithValue(ctx, commitJournalContextKey, journal)
+		defer func() {
+			module.recordCommitEvent(ctx, journal, err)
+		}()
+		if err = module.validateChanges(ctx, journal); err != nil {
+			return ctx, err
+		}
+	}
 	for _, pu := range state.Updates {
 		switch pu.Operation {
 		case "host_add":
@@ -98,6 +214,24 @@ func (module *ConfigModule) Commit(ctx context.Context) (context.Context, error)
 			ctx, err = module.commitHostUpdate(ctx)
 		case "host_delete":
 			ctx, err = module.commitHostDelete(ctx)
+		case "hosts_import":
+			ctx, err = module.commitHostsImport(ctx)
+		case "subnet_add":
+			ctx, err = module.commitSubnetAdd(ctx)
+		case "subnet_update":
+			ctx, err = module.commitSubnetUpdate(ctx)
+		case "subnet_delete":
+			ctx, err = module.commitSubnetDelete(ctx)
+		case "shared_network_add":
+			ctx, err = module.commitSharedNetworkAdd(ctx)
+		case "shared_network_update":
+			ctx, err = module.commitSharedNetworkUpdate(ctx)
+		case "shared_network_delete":
+			ctx, err = module.commitSharedNetworkDelete(ctx)
+		case "global_parameters_update":
+			ctx, err = module.commitGlobalParametersUpdate(ctx)
+		case "config_report_fix":
+			ctx, err = module.commitConfigReportFix(ctx)
 		default:
 			err = pkgerrors.Errorf("unknown operation %s when called Commit()", pu.Operation)
 		}
@@ -119,22 +253,41 @@ func (module *ConfigModule) BeginHostAdd(ctx context.Context) (context.Context,
 // Applies new host reservation. It prepares necessary commands to be sent
 // to Kea upon commit.
 func (module *ConfigModule) ApplyHostAdd(ctx context.Context, host *dbmodel.Host) (context.Context, error) {
+	commands, err := module.createHostAddCommands(host)
+	if err != nil {
+		return ctx, err
+	}
+	recipe := &ConfigRecipe{
+		HostConfigRecipeParams: HostConfigRecipeParams{
+			HostAfterUpdate: host,
+		},
+		Commands: commands,
+	}
+	if ctx, err = config.SetRecipeForUpdate(ctx, 0, recipe); err != nil {
+		return ctx, err
+	}
+	return ctx, nil
+}
+
+// Creates the reservation-add commands adding the host reservation to
+// the daemons associated with the host.
+func (module *ConfigModule) createHostAddCommands(host *dbmodel.Host) ([]ConfigCommand, error) {
 	if len(host.LocalHosts) == 0 {
-		return ctx, pkgerrors.Errorf("applied host %d is not associated with any daemon", host.ID)
+		return nil, pkgerrors.Errorf("applied host %d is not associated with any daemon", host.ID)
 	}
 	var commands []ConfigCommand
 	for _, lh := range host.LocalHosts {
 		if lh.Daemon == nil {
-			return ctx, pkgerrors.Errorf("applied host %d is associated with nil daemon", host.ID)
+			return nil, pkgerrors.Errorf("applied host %d is associated with nil daemon", host.ID)
 		}
 		if lh.Daemon.App == nil {
-			return ctx, pkgerrors.Errorf("applied host %d is associated with nil app", host.ID)
+			return nil, pkgerrors.Errorf("applied host %d is associated with nil app", host.ID)
 		}
 		// Convert the host information to Kea reservation.
-		lookup := module.manager.GetDHCPOptionDefinitionLookup()
+		lookup := module.getDHCPOptionDefinitionLookup()
 		reservation, err := keaconfig.CreateHostCmdsReservation(lh.DaemonID, lookup, host)
 		if err != nil {
-			return ctx, err
+			return nil, err
 		}
 		// Create command arguments.
 		arguments := make(map[string]interface{})
@@ -144,12 +297,67 @@ func (module *ConfigModule) ApplyHostAdd(ctx context.Context, host *dbmodel.Host
 			Command: keactrl.NewCommand("reservation-add", []string{lh.Daemon.Name}, arguments),
 			App:     lh.Daemon.App,
 		}
+		deleteCommand, err := createHostDeleteCommand(lh, host)
+		if err != nil {
+			return nil, err
+		}
+		appCommand.addCompensatingCommands(deleteCommand)
 		commands = append(commands, appCommand)
 	}
+	return commands, nil
+}
+
+// Create the host reservation in the Kea servers.
+func (module *ConfigModule) commitHostAdd(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, pkgerrors.New("context lacks state")
+	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	for _, update := range state.Updates {
+		if update.Recipe.HostAfterUpdate == nil {
+			return ctx, pkgerrors.New("server logic error: the update.Recipe.HostAfterUpdate cannot be nil when committing host creation")
+		}
+		err = dbmodel.AddHostWithLocalHosts(module.manager.GetDB(), update.Recipe.HostAfterUpdate)
+		if err != nil {
+			return ctx, pkgerrors.WithMessagef(err, "host has been successfully added to Kea but adding to the Stork database failed")
+		}
+	}
+	return ctx, nil
+}
+
+// Begins importing a batch of host reservations. It initializes transaction
+// state.
+func (module *ConfigModule) BeginHostsImport(ctx context.Context) (context.Context, error) {
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "hosts_import")
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Applies a batch of new host reservations. It prepares the reservation-add
+// commands for all hosts to be sent to Kea upon commit. The hosts are added
+// to the database when all commands succeed.
+func (module *ConfigModule) ApplyHostsImport(ctx context.Context, hosts []*dbmodel.Host) (context.Context, error) {
+	if len(hosts) == 0 {
+		return ctx, pkgerrors.New("no hosts to import")
+	}
+	var commands []ConfigCommand
+	for _, host := range hosts {
+		hostCommands, err := module.createHostAddCommands(host)
+		if err != nil {
+			return ctx, err
+		}
+		commands = append(commands, hostCommands...)
+	}
 	var err error
 	recipe := &ConfigRecipe{
 		HostConfigRecipeParams: HostConfigRecipeParams{
-			HostAfterUpdate: host,
+			ImportedHosts: hosts,
 		},
 		Commands: commands,
 	}
@@ -159,25 +367,36 @@ func (module *ConfigModule) ApplyHostAdd(ctx context.Context, host *dbmodel.Host
 	return ctx, nil
 }
 
-// Create the host reservation in the Kea servers.
-func (module *ConfigModule) commitHostAdd(ctx context.Context) (context.Context, error) {
+// Creates the batch of host reservations in the Kea servers. The batch is
+// committed as a whole. When adding any of the reservations fails, the
+// reservations already added are deleted from the Kea servers, even if the
+// two-phase commit is disabled, so the failure applies to all hosts in the
+// batch. The hosts are added to the database in a single transaction.
+func (module *ConfigModule) commitHostsImport(ctx context.Context) (context.Context, error) {
 	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
 	if !ok {
 		return ctx, pkgerrors.New("context lacks state")
 	}
+	if getCommitJournal(ctx) == nil {
+		ctx = context.WithValue(ctx, commitJournalContextKey, &commitJournal{})
+	}
 	var err error
-	ctx, err = module.commitHostChanges(ctx)
+	ctx, err = module.commitChanges(ctx)
 	if err != nil {
 		return ctx, err
 	}
-	for _, update := range state.Updates {
-		if update.Recipe.HostAfterUpdate == nil {
-			return ctx, pkgerrors.New("server logic error: the update.Recipe.HostAfterUpdate cannot be nil when committing host creation")
-		}
-		err = dbmodel.AddHostWithLocalHosts(module.manager.GetDB(), update.Recipe.HostAfterUpdate)
-		if err != nil {
-			return ctx, pkgerrors.WithMessagef(err, "host has been successfully added to Kea but adding to the Stork database failed")
+	err = module.manager.GetDB().RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+		for _, update := range state.Updates {
+			for _, host := range update.Recipe.ImportedHosts {
+				if err := dbmodel.AddHostWithLocalHosts(tx, host); err != nil {
+					return err
+				}
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return ctx, pkgerrors.WithMessagef(err, "hosts have been successfully added to Kea but adding to the Stork database failed")
 	}
 	return ctx, nil
 }
@@ -258,6 +477,11 @@ func (module *ConfigModule) ApplyHostUpdate(ctx context.Context, host *dbmodel.H
 		appCommand := ConfigCommand{}
 		appCommand.Command = keactrl.NewCommand("reservation-del", []string{lh.Daemon.Name}, deleteArguments)
 		appCommand.App = lh.Daemon.App
+		restoreCommand, err := module.createHostAddCommand(lh, existingHost)
+		if err != nil {
+			return ctx, err
+		}
+		appCommand.addCompensatingCommands(restoreCommand)
 		commands = append(commands, appCommand)
 	}
 	// Re-create the host reservations.
@@ -269,7 +493,7 @@ func (module *ConfigModule) ApplyHostUpdate(ctx context.Context, host *dbmodel.H
 			return ctx, pkgerrors.Errorf("applied host %d is associated with nil app", host.ID)
 		}
 		// Convert the updated host information to Kea reservation.
-		lookup := module.manager.GetDHCPOptionDefinitionLookup()
+		lookup := module.getDHCPOptionDefinitionLookup()
 		reservation, err := keaconfig.CreateHostCmdsReservation(lh.DaemonID, lookup, host)
 		if err != nil {
 			return ctx, err
@@ -280,6 +504,11 @@ func (module *ConfigModule) ApplyHostUpdate(ctx context.Context, host *dbmodel.H
 		appCommand := ConfigCommand{}
 		appCommand.Command = keactrl.NewCommand("reservation-add", []string{lh.Daemon.Name}, addArguments)
 		appCommand.App = lh.Daemon.App
+		deleteCommand, err := createHostDeleteCommand(lh, host)
+		if err != nil {
+			return ctx, err
+		}
+		appCommand.addCompensatingCommands(deleteCommand)
 		commands = append(commands, appCommand)
 	}
 	recipe.HostAfterUpdate = host
@@ -294,7 +523,7 @@ func (module *ConfigModule) commitHostUpdate(ctx context.Context) (context.Conte
 		return ctx, pkgerrors.New("context lacks state")
 	}
 	var err error
-	ctx, err = module.commitHostChanges(ctx)
+	ctx, err = module.commitChanges(ctx)
 	if err != nil {
 		return ctx, err
 	}
@@ -341,6 +570,11 @@ func (module *ConfigModule) ApplyHostDelete(ctx context.Context, host *dbmodel.H
 		appCommand := ConfigCommand{}
 		appCommand.Command = keactrl.NewCommand("reservation-del", []string{lh.Daemon.Name}, arguments)
 		appCommand.App = lh.Daemon.App
+		restoreCommand, err := module.createHostAddCommand(lh, host)
+		if err != nil {
+			return ctx, err
+		}
+		appCommand.addCompensatingCommands(restoreCommand)
 		commands = append(commands, appCommand)
 	}
 	daemonIDs, _ := ctx.Value(config.DaemonsContextKey).([]int64)
@@ -349,7 +583,8 @@ func (module *ConfigModule) ApplyHostDelete(ctx context.Context, host *dbmodel.H
 	recipe := ConfigRecipe{
 		Commands: commands,
 		HostConfigRecipeParams: HostConfigRecipeParams{
-			HostID: &host.ID,
+			HostBeforeUpdate: host,
+			HostID:           &host.ID,
 		},
 	}
 	if err := state.SetRecipeForUpdate(0, &recipe); err != nil {
@@ -366,7 +601,7 @@ func (module *ConfigModule) commitHostDelete(ctx context.Context) (context.Conte
 		return ctx, pkgerrors.New("context lacks state")
 	}
 	var err error
-	ctx, err = module.commitHostChanges(ctx)
+	ctx, err = module.commitChanges(ctx)
 	if err != nil {
 		return ctx, err
 	}
@@ -382,40 +617,1192 @@ func (module *ConfigModule) commitHostDelete(ctx context.Context) (context.Conte
 	return ctx, nil
 }
 
-// Generic function used to commit host changes (i.e., delete,  add or update host reservation)
-// using the data stored in the context.
-func (module *ConfigModule) commitHostChanges(ctx context.Context) (context.Context, error) {
+// Begins adding a new subnet. It initializes transaction state.
+func (module *ConfigModule) BeginSubnetAdd(ctx context.Context) (context.Context, error) {
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "subnet_add")
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Applies new subnet. It prepares necessary commands to be sent to Kea
+// upon commit. The subnet is added to each daemon using the subnet4-add
+// or subnet6-add command. If the subnet belongs to a shared network, it
+// is also added to this shared network. Finally, the configuration is
+// written to the disk by each daemon.
+func (module *ConfigModule) ApplySubnetAdd(ctx context.Context, subnet *dbmodel.Subnet) (context.Context, error) {
+	if len(subnet.LocalSubnets) == 0 {
+		return ctx, pkgerrors.Errorf("applied subnet %s is not associated with any daemon", subnet.Prefix)
+	}
+	var commands []ConfigCommand
+	for _, ls := range subnet.LocalSubnets {
+		if err := validateLocalSubnet("applied", subnet, ls); err != nil {
+			return ctx, err
+		}
+		addCommand, err := module.createSubnetCommand(ls, subnet, "add")
+		if err != nil {
+			return ctx, err
+		}
+		addCommand.addCompensatingCommands(createSubnetDeleteCommand(ls, subnet))
+		commands = append(commands, *addCommand)
+		if subnet.SharedNetworkID != 0 {
+			networkCommand, err := createSharedNetworkSubnetCommand(ls, subnet, "add")
+			if err != nil {
+				return ctx, err
+			}
+			networkCommand.addCompensatingCommands(createNetworkSubnetCommand(ls, subnet.GetFamily(), subnet.SharedNetwork.Name, "del"))
+			commands = append(commands, *networkCommand)
+		}
+	}
+	commands = append(commands, createConfigWriteCommands(subnet.LocalSubnets)...)
+	var err error
+	recipe := &ConfigRecipe{
+		SubnetConfigRecipeParams: SubnetConfigRecipeParams{
+			SubnetAfterUpdate: subnet,
+		},
+		Commands: commands,
+	}
+	if ctx, err = config.SetRecipeForUpdate(ctx, 0, recipe); err != nil {
+		return ctx, err
+	}
+	return ctx, nil
+}
+
+// Create the subnet in the Kea servers and in the database.
+func (module *ConfigModule) commitSubnetAdd(ctx context.Context) (context.Context, error) {
 	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
 	if !ok {
 		return ctx, pkgerrors.New("context lacks state")
 	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
 	for _, update := range state.Updates {
-		// Retrieve associations between the commands and apps.
-		// Iterate over the associations.
-		for _, acs := range update.Recipe.Commands {
-			// Send the command to Kea.
-			var response keactrl.ResponseList
-			result, err := module.manager.GetConnectedAgents().ForwardToKeaOverHTTP(context.Background(), acs.App, []keactrl.SerializableCommand{acs.Command}, &response)
-			// There was no error in communication between the server and the agent but
-			// the agent could have issues with the Kea response.
-			if err == nil {
-				// Let's check if the agent found errors in communication with Kea.
-				// If not, the individual Kea instances could return error codes as
-				// a result of processing the commands.
-				if err = result.GetFirstError(); err == nil {
-					for _, r := range response {
-						// Let's check if the individual Kea servers returned error
-						// codes for the processed commands.
-						if err = keactrl.GetResponseError(r); err != nil {
-							break
-						}
-					}
+		if update.Recipe.SubnetAfterUpdate == nil {
+			return ctx, pkgerrors.New("server logic error: the update.Recipe.SubnetAfterUpdate cannot be nil when committing subnet creation")
+		}
+		err = dbmodel.AddSubnetWithLocalSubnets(module.manager.GetDB(), update.Recipe.SubnetAfterUpdate)
+		if err != nil {
+			return ctx, pkgerrors.WithMessagef(err, "subnet has been successfully added to Kea but adding to the Stork database failed")
+		}
+	}
+	return ctx, nil
+}
+
+// Begins a subnet update. It fetches the specified subnet from the database
+// and stores it in the context state. Then, it locks the daemons associated
+// with the subnet for updates.
+func (module *ConfigModule) BeginSubnetUpdate(ctx context.Context, subnetID int64) (context.Context, error) {
+	// Try to get the subnet to be updated from the database.
+	subnet, err := dbmodel.GetSubnet(module.manager.GetDB(), subnetID)
+	if err != nil {
+		// Internal database error.
+		return ctx, err
+	}
+	// Subnet does not exist.
+	if subnet == nil {
+		return ctx, pkgerrors.WithStack(config.NewSubnetNotFoundError(subnetID))
+	}
+	// Get the list of daemons for whose configurations must be locked for
+	// updates.
+	var daemonIDs []int64
+	for _, ls := range subnet.LocalSubnets {
+		daemonIDs = append(daemonIDs, ls.DaemonID)
+	}
+	// Try to lock configurations.
+	ctx, err = module.manager.Lock(ctx, daemonIDs...)
+	if err != nil {
+		return ctx, pkgerrors.WithStack(config.NewLockError())
+	}
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "subnet_update", daemonIDs...)
+	recipe := &ConfigRecipe{
+		SubnetConfigRecipeParams: SubnetConfigRecipeParams{
+			SubnetBeforeUpdate: subnet,
+		},
+	}
+	if err := state.SetRecipeForUpdate(0, recipe); err != nil {
+		return ctx, err
+	}
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Applies updated subnet. It prepares necessary commands to be sent to Kea
+// upon commit. The subnet is updated with subnet4-update or subnet6-update
+// in the daemons which already had this subnet. It is added to the daemons
+// which did not have it, and deleted from the daemons no longer associated
+// with it. The subnet is moved between the shared networks if the shared
+// network has changed. Finally, the configuration is written to the disk
+// by each daemon.
+func (module *ConfigModule) ApplySubnetUpdate(ctx context.Context, subnet *dbmodel.Subnet) (context.Context, error) {
+	if len(subnet.LocalSubnets) == 0 {
+		return ctx, pkgerrors.Errorf("applied subnet %s is not associated with any daemon", subnet.Prefix)
+	}
+	// Retrieve existing subnet from the context. We will need it for sending
+	// the subnet4-del commands to the daemons no longer having this subnet.
+	recipe, err := config.GetRecipeForUpdate[ConfigRecipe](ctx, 0)
+	if err != nil {
+		return ctx, err
+	}
+	existingSubnet := recipe.SubnetBeforeUpdate
+	if existingSubnet == nil {
+		return ctx, pkgerrors.New("internal server error: subnet instance cannot be nil when committing subnet update")
+	}
+	if subnet.ID != existingSubnet.ID {
+		return ctx, pkgerrors.Errorf("applied subnet ID %d does not match the updated subnet ID %d", subnet.ID, existingSubnet.ID)
+	}
+
+	var commands []ConfigCommand
+	// Delete the subnet from the daemons no longer associated with it.
+	var deletedLocalSubnets []*dbmodel.LocalSubnet
+	for _, existingLocalSubnet := range existingSubnet.LocalSubnets {
+		if getLocalSubnetByDaemonID(subnet, existingLocalSubnet.DaemonID) != nil {
+			continue
+		}
+		if err := validateLocalSubnet("updated", existingSubnet, existingLocalSubnet); err != nil {
+			return ctx, err
+		}
+		deleteCommand := createSubnetDeleteCommand(existingLocalSubnet, existingSubnet)
+		restoreCommands, err := module.createSubnetRestoreCommands(existingLocalSubnet, existingSubnet)
+		if err != nil {
+			return ctx, err
+		}
+		deleteCommand.addCompensatingCommands(restoreCommands...)
+		commands = append(commands, *deleteCommand)
+		deletedLocalSubnets = append(deletedLocalSubnets, existingLocalSubnet)
+	}
+	// Update the subnet in the daemons already having it or add it to
+	// the other daemons.
+	for _, ls := range subnet.LocalSubnets {
+		if err := validateLocalSubnet("applied", subnet, ls); err != nil {
+			return ctx, err
+		}
+		existingLocalSubnet := getLocalSubnetByDaemonID(existingSubnet, ls.DaemonID)
+		if existingLocalSubnet == nil {
+			addCommand, err := module.createSubnetCommand(ls, subnet, "add")
+			if err != nil {
+				return ctx, err
+			}
+			addCommand.addCompensatingCommands(createSubnetDeleteCommand(ls, subnet))
+			commands = append(commands, *addCommand)
+			if subnet.SharedNetworkID != 0 {
+				networkCommand, err := createSharedNetworkSubnetCommand(ls, subnet, "add")
+				if err != nil {
+					return ctx, err
 				}
+				networkCommand.addCompensatingCommands(createNetworkSubnetCommand(ls, subnet.GetFamily(), subnet.SharedNetwork.Name, "del"))
+				commands = append(commands, *networkCommand)
+			}
+			continue
+		}
+		updateCommand, err := module.createSubnetCommand(ls, subnet, "update")
+		if err != nil {
+			return ctx, err
+		}
+		restoreCommand, err := module.createSubnetCommand(existingLocalSubnet, existingSubnet, "update")
+		if err != nil {
+			return ctx, err
+		}
+		updateCommand.addCompensatingCommands(restoreCommand)
+		commands = append(commands, *updateCommand)
+		if existingSubnet.SharedNetworkID == subnet.SharedNetworkID {
+			continue
+		}
+		// The subnet has been moved to another shared network or removed
+		// from the shared network.
+		if existingSubnet.SharedNetworkID != 0 {
+			networkCommand, err := createSharedNetworkSubnetCommand(existingLocalSubnet, existingSubnet, "del")
+			if err != nil {
+				return ctx, err
 			}
+			networkCommand.addCompensatingCommands(createNetworkSubnetCommand(existingLocalSubnet, existingSubnet.GetFamily(), existingSubnet.SharedNetwork.Name, "add"))
+			commands = append(commands, *networkCommand)
+		}
+		if subnet.SharedNetworkID != 0 {
+			networkCommand, err := createSharedNetworkSubnetCommand(ls, subnet, "add")
 			if err != nil {
-				err = pkgerrors.WithMessagef(err, "%s command to %s failed", acs.Command.GetCommand(), acs.App.GetName())
 				return ctx, err
 			}
+			networkCommand.addCompensatingCommands(createNetworkSubnetCommand(ls, subnet.GetFamily(), subnet.SharedNetwork.Name, "del"))
+			commands = append(commands, *networkCommand)
+		}
+	}
+	commands = append(commands, createConfigWriteCommands(deletedLocalSubnets)...)
+	commands = append(commands, createConfigWriteCommands(subnet.LocalSubnets)...)
+	recipe.SubnetAfterUpdate = subnet
+	recipe.Commands = commands
+	return config.SetRecipeForUpdate(ctx, 0, recipe)
+}
+
+// Update the subnet in the Kea servers and in the database.
+func (module *ConfigModule) commitSubnetUpdate(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, pkgerrors.New("context lacks state")
+	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	for _, update := range state.Updates {
+		if update.Recipe.SubnetAfterUpdate == nil {
+			return ctx, pkgerrors.New("server logic error: the update.Recipe.SubnetAfterUpdate cannot be nil when committing the subnet update")
+		}
+		err = dbmodel.UpdateSubnetWithLocalSubnets(module.manager.GetDB(), update.Recipe.SubnetAfterUpdate)
+		if err != nil {
+			return ctx, pkgerrors.WithMessagef(err, "subnet has been successfully updated in Kea but updating it in the Stork database failed")
+		}
+	}
+	return ctx, nil
+}
+
+// Begins deleting a subnet. Currently it is no-op but may evolve in the
+// future.
+func (module *ConfigModule) BeginSubnetDelete(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+// Creates requests to delete a subnet. It prepares necessary commands to be
+// sent to Kea upon commit.
+func (module *ConfigModule) ApplySubnetDelete(ctx context.Context, subnet *dbmodel.Subnet) (context.Context, error) {
+	if len(subnet.LocalSubnets) == 0 {
+		return ctx, pkgerrors.Errorf("deleted subnet %d is not associated with any daemon", subnet.ID)
+	}
+	var commands []ConfigCommand
+	for _, ls := range subnet.LocalSubnets {
+		if err := validateLocalSubnet("deleted", subnet, ls); err != nil {
+			return ctx, err
+		}
+		deleteCommand := createSubnetDeleteCommand(ls, subnet)
+		restoreCommands, err := module.createSubnetRestoreCommands(ls, subnet)
+		if err != nil {
+			return ctx, err
+		}
+		deleteCommand.addCompensatingCommands(restoreCommands...)
+		commands = append(commands, *deleteCommand)
+	}
+	commands = append(commands, createConfigWriteCommands(subnet.LocalSubnets)...)
+	daemonIDs, _ := ctx.Value(config.DaemonsContextKey).([]int64)
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "subnet_delete", daemonIDs...)
+	recipe := ConfigRecipe{
+		Commands: commands,
+		SubnetConfigRecipeParams: SubnetConfigRecipeParams{
+			SubnetBeforeUpdate: subnet,
+			SubnetID:           &subnet.ID,
+		},
+	}
+	if err := state.SetRecipeForUpdate(0, &recipe); err != nil {
+		return ctx, err
+	}
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Delete the subnet from the Kea servers and from the database.
+func (module *ConfigModule) commitSubnetDelete(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, pkgerrors.New("context lacks state")
+	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	for _, update := range state.Updates {
+		if update.Recipe.SubnetID == nil {
+			return ctx, pkgerrors.New("server logic error: the subnet ID cannot be nil when committing subnet deletion")
+		}
+		err = dbmodel.DeleteSubnet(module.manager.GetDB(), *update.Recipe.SubnetID)
+		if err != nil {
+			return ctx, pkgerrors.WithMessagef(err, "subnet has been successfully deleted in Kea but deleting in the Stork database failed")
+		}
+	}
+	return ctx, nil
+}
+
+// Begins adding a new shared network. It initializes transaction state.
+func (module *ConfigModule) BeginSharedNetworkAdd(ctx context.Context) (context.Context, error) {
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "shared_network_add")
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Applies new shared network. It prepares necessary commands to be sent to
+// Kea upon commit. The shared network is added to each daemon using the
+// network4-add or network6-add command. The subnets listed in the shared
+// network are moved to this shared network with the network4-subnet-add or
+// network6-subnet-add commands. Finally, the configuration is written to
+// the disk by each daemon.
+func (module *ConfigModule) ApplySharedNetworkAdd(ctx context.Context, sharedNetwork *dbmodel.SharedNetwork) (context.Context, error) {
+	if len(sharedNetwork.LocalSharedNetworks) == 0 {
+		return ctx, pkgerrors.Errorf("applied shared network %s is not associated with any daemon", sharedNetwork.Name)
+	}
+	var commands []ConfigCommand
+	for _, lsn := range sharedNetwork.LocalSharedNetworks {
+		if err := validateLocalSharedNetwork("applied", sharedNetwork, lsn); err != nil {
+			return ctx, err
+		}
+		addCommand, err := module.createSharedNetworkAddCommand(lsn, sharedNetwork)
+		if err != nil {
+			return ctx, err
+		}
+		addCommand.addCompensatingCommands(createSharedNetworkDeleteCommand(lsn, sharedNetwork))
+		commands = append(commands, *addCommand)
+		subnetCommands, err := createSharedNetworkSubnetsCommands(lsn, sharedNetwork)
+		if err != nil {
+			return ctx, err
+		}
+		commands = append(commands, subnetCommands...)
+	}
+	for _, lsn := range sharedNetwork.LocalSharedNetworks {
+		commands = append(commands, createConfigWriteCommand(lsn.Daemon))
+	}
+	recipe := &ConfigRecipe{
+		SharedNetworkConfigRecipeParams: SharedNetworkConfigRecipeParams{
+			SharedNetworkAfterUpdate: sharedNetwork,
+		},
+		Commands: commands,
+	}
+	return config.SetRecipeForUpdate(ctx, 0, recipe)
+}
+
+// Create the shared network in the Kea servers and in the database.
+func (module *ConfigModule) commitSharedNetworkAdd(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, pkgerrors.New("context lacks state")
+	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	for _, update := range state.Updates {
+		if update.Recipe.SharedNetworkAfterUpdate == nil {
+			return ctx, pkgerrors.New("server logic error: the update.Recipe.SharedNetworkAfterUpdate cannot be nil when committing shared network creation")
+		}
+		err = dbmodel.AddSharedNetworkWithLocalSharedNetworks(module.manager.GetDB(), update.Recipe.SharedNetworkAfterUpdate)
+		if err != nil {
+			return ctx, pkgerrors.WithMessagef(err, "shared network has been successfully added to Kea but adding to the Stork database failed")
+		}
+	}
+	return ctx, nil
+}
+
+// Begins a shared network update. It fetches the specified shared network
+// from the database and stores it in the context state. Then, it locks the
+// daemons associated with the shared network for updates.
+func (module *ConfigModule) BeginSharedNetworkUpdate(ctx context.Context, sharedNetworkID int64) (context.Context, error) {
+	// Try to get the shared network to be updated from the database.
+	sharedNetwork, err := dbmodel.GetSharedNetwork(module.manager.GetDB(), sharedNetworkID)
+	if err != nil {
+		// Internal database error.
+		return ctx, err
+	}
+	// Shared network does not exist.
+	if sharedNetwork == nil {
+		return ctx, pkgerrors.WithStack(config.NewSharedNetworkNotFoundError(sharedNetworkID))
+	}
+	// Get the list of daemons for whose configurations must be locked for
+	// updates.
+	var daemonIDs []int64
+	for _, lsn := range sharedNetwork.LocalSharedNetworks {
+		daemonIDs = append(daemonIDs, lsn.DaemonID)
+	}
+	// Try to lock configurations.
+	ctx, err = module.manager.Lock(ctx, daemonIDs...)
+	if err != nil {
+		return ctx, pkgerrors.WithStack(config.NewLockError())
+	}
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "shared_network_update", daemonIDs...)
+	recipe := &ConfigRecipe{
+		SharedNetworkConfigRecipeParams: SharedNetworkConfigRecipeParams{
+			SharedNetworkBeforeUpdate: sharedNetwork,
+		},
+	}
+	if err := state.SetRecipeForUpdate(0, recipe); err != nil {
+		return ctx, err
+	}
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Applies updated shared network. It prepares necessary commands to be sent
+// to Kea upon commit. The subnet_cmds hook library provides no command to
+// update a shared network. Therefore, the shared network is deleted from
+// the daemons with the network4-del or network6-del command, keeping its
+// subnets as top-level subnets, and it is re-created with the new parameters
+// using the network4-add or network6-add command. Next, the subnets listed
+// in the updated shared network are added to it. This allows for moving
+// the subnets between the shared networks. The shared network is also
+// deleted from the daemons no longer associated with it. Finally, the
+// configuration is written to the disk by each daemon.
+func (module *ConfigModule) ApplySharedNetworkUpdate(ctx context.Context, sharedNetwork *dbmodel.SharedNetwork) (context.Context, error) {
+	if len(sharedNetwork.LocalSharedNetworks) == 0 {
+		return ctx, pkgerrors.Errorf("applied shared network %s is not associated with any daemon", sharedNetwork.Name)
+	}
+	// Retrieve existing shared network from the context. We will need it
+	// for sending the network4-del commands.
+	recipe, err := config.GetRecipeForUpdate[ConfigRecipe](ctx, 0)
+	if err != nil {
+		return ctx, err
+	}
+	existingSharedNetwork := recipe.SharedNetworkBeforeUpdate
+	if existingSharedNetwork == nil {
+		return ctx, pkgerrors.New("internal server error: shared network instance cannot be nil when committing shared network update")
+	}
+
+	var commands []ConfigCommand
+	// Delete the shared network from the daemons no longer associated with it.
+	var deletedLocalSharedNetworks []*dbmodel.LocalSharedNetwork
+	for _, existingLocalSharedNetwork := range existingSharedNetwork.LocalSharedNetworks {
+		if sharedNetwork.GetLocalSharedNetwork(existingLocalSharedNetwork.DaemonID) != nil {
+			continue
+		}
+		if err := validateLocalSharedNetwork("updated", existingSharedNetwork, existingLocalSharedNetwork); err != nil {
+			return ctx, err
+		}
+		deleteCommand := createSharedNetworkDeleteCommand(existingLocalSharedNetwork, existingSharedNetwork)
+		restoreCommands, err := module.createSharedNetworkRestoreCommands(existingLocalSharedNetwork, existingSharedNetwork)
+		if err != nil {
+			return ctx, err
+		}
+		deleteCommand.addCompensatingCommands(restoreCommands...)
+		commands = append(commands, *deleteCommand)
+		deletedLocalSharedNetworks = append(deletedLocalSharedNetworks, existingLocalSharedNetwork)
+	}
+	// Re-create the shared network in the daemons already having it or add
+	// it to the other daemons.
+	for _, lsn := range sharedNetwork.LocalSharedNetworks {
+		if err := validateLocalSharedNetwork("applied", sharedNetwork, lsn); err != nil {
+			return ctx, err
+		}
+		if existingLocalSharedNetwork := existingSharedNetwork.GetLocalSharedNetwork(lsn.DaemonID); existingLocalSharedNetwork != nil {
+			if err := validateLocalSharedNetwork("updated", existingSharedNetwork, existingLocalSharedNetwork); err != nil {
+				return ctx, err
+			}
+			deleteCommand := createSharedNetworkDeleteCommand(existingLocalSharedNetwork, existingSharedNetwork)
+			restoreCommands, err := module.createSharedNetworkRestoreCommands(existingLocalSharedNetwork, existingSharedNetwork)
+			if err != nil {
+				return ctx, err
+			}
+			deleteCommand.addCompensatingCommands(restoreCommands...)
+			commands = append(commands, *deleteCommand)
+		}
+		addCommand, err := module.createSharedNetworkAddCommand(lsn, sharedNetwork)
+		if err != nil {
+			return ctx, err
+		}
+		addCommand.addCompensatingCommands(createSharedNetworkDeleteCommand(lsn, sharedNetwork))
+		commands = append(commands, *addCommand)
+		subnetCommands, err := createSharedNetworkSubnetsCommands(lsn, sharedNetwork)
+		if err != nil {
+			return ctx, err
+		}
+		commands = append(commands, subnetCommands...)
+	}
+	for _, lsn := range deletedLocalSharedNetworks {
+		commands = append(commands, createConfigWriteCommand(lsn.Daemon))
+	}
+	for _, lsn := range sharedNetwork.LocalSharedNetworks {
+		commands = append(commands, createConfigWriteCommand(lsn.Daemon))
+	}
+	recipe.SharedNetworkAfterUpdate = sharedNetwork
+	recipe.Commands = commands
+	return config.SetRecipeForUpdate(ctx, 0, recipe)
+}
+
+// Update the shared network in the Kea servers and in the database.
+func (module *ConfigModule) commitSharedNetworkUpdate(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, pkgerrors.New("context lacks state")
+	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	for _, update := range state.Updates {
+		if update.Recipe.SharedNetworkAfterUpdate == nil {
+			return ctx, pkgerrors.New("server logic error: the update.Recipe.SharedNetworkAfterUpdate cannot be nil when committing the shared network update")
+		}
+		err = dbmodel.UpdateSharedNetworkWithLocalSharedNetworks(module.manager.GetDB(), update.Recipe.SharedNetworkAfterUpdate)
+		if err != nil {
+			return ctx, pkgerrors.WithMessagef(err, "shared network has been successfully updated in Kea but updating it in the Stork database failed")
+		}
+	}
+	return ctx, nil
+}
+
+// Begins deleting a shared network. Currently it is no-op but may evolve
+// in the future.
+func (module *ConfigModule) BeginSharedNetworkDelete(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+// Creates requests to delete a shared network. It locks the daemons
+// associated with the shared network and prepares necessary commands to be
+// sent to Kea upon commit. The subnets belonging to the shared network are
+// not deleted. They become top-level subnets.
+func (module *ConfigModule) ApplySharedNetworkDelete(ctx context.Context, sharedNetwork *dbmodel.SharedNetwork) (context.Context, error) {
+	if len(sharedNetwork.LocalSharedNetworks) == 0 {
+		return ctx, pkgerrors.Errorf("deleted shared network %d is not associated with any daemon", sharedNetwork.ID)
+	}
+	var (
+		commands  []ConfigCommand
+		daemonIDs []int64
+	)
+	for _, lsn := range sharedNetwork.LocalSharedNetworks {
+		if err := validateLocalSharedNetwork("deleted", sharedNetwork, lsn); err != nil {
+			return ctx, err
+		}
+		deleteCommand := createSharedNetworkDeleteCommand(lsn, sharedNetwork)
+		restoreCommands, err := module.createSharedNetworkRestoreCommands(lsn, sharedNetwork)
+		if err != nil {
+			return ctx, err
+		}
+		deleteCommand.addCompensatingCommands(restoreCommands...)
+		commands = append(commands, *deleteCommand)
+		daemonIDs = append(daemonIDs, lsn.DaemonID)
+	}
+	for _, lsn := range sharedNetwork.LocalSharedNetworks {
+		commands = append(commands, createConfigWriteCommand(lsn.Daemon))
+	}
+	// Try to lock configurations.
+	ctx, err := module.manager.Lock(ctx, daemonIDs...)
+	if err != nil {
+		return ctx, pkgerrors.WithStack(config.NewLockError())
+	}
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "shared_network_delete", daemonIDs...)
+	recipe := ConfigRecipe{
+		Commands: commands,
+		SharedNetworkConfigRecipeParams: SharedNetworkConfigRecipeParams{
+			SharedNetworkBeforeUpdate: sharedNetwork,
+			SharedNetworkID:           &sharedNetwork.ID,
+		},
+	}
+	if err := state.SetRecipeForUpdate(0, &recipe); err != nil {
+		return ctx, err
+	}
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Delete the shared network from the Kea servers and from the database.
+func (module *ConfigModule) commitSharedNetworkDelete(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, pkgerrors.New("context lacks state")
+	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	for _, update := range state.Updates {
+		if update.Recipe.SharedNetworkID == nil {
+			return ctx, pkgerrors.New("server logic error: the shared network ID cannot be nil when committing shared network deletion")
+		}
+		err = dbmodel.DeleteSharedNetwork(module.manager.GetDB(), *update.Recipe.SharedNetworkID)
+		if err != nil {
+			return ctx, pkgerrors.WithMessagef(err, "shared network has been successfully deleted in Kea but deleting in the Stork database failed")
+		}
+	}
+	return ctx, nil
+}
+
+// Begins an update of the global DHCP parameters. It fetches the specified
+// daemons from the database and stores them in the context state. Then, it
+// locks the daemons for updates. It returns an error when any of the daemons
+// does not exist or is not a Kea DHCP daemon with a known configuration.
+func (module *ConfigModule) BeginGlobalParametersUpdate(ctx context.Context, daemonIDs []int64) (context.Context, error) {
+	if len(daemonIDs) == 0 {
+		return ctx, pkgerrors.New("no daemons specified for the global parameters update")
+	}
+	var daemons []*dbmodel.Daemon
+	for _, daemonID := range daemonIDs {
+		daemon, err := dbmodel.GetDaemonByID(module.manager.GetDB(), daemonID)
+		if err != nil {
+			// Internal database error.
+			return ctx, err
+		}
+		// Daemon does not exist.
+		if daemon == nil {
+			return ctx, pkgerrors.WithStack(config.NewDaemonNotFoundError(daemonID))
+		}
+		if daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil ||
+			(!daemon.KeaDaemon.Config.IsDHCPv4() && !daemon.KeaDaemon.Config.IsDHCPv6()) {
+			return ctx, pkgerrors.Errorf("daemon %d is not a Kea DHCP server with a known configuration", daemonID)
+		}
+		daemons = append(daemons, daemon)
+	}
+	// Try to lock configurations.
+	ctx, err := module.manager.Lock(ctx, daemonIDs...)
+	if err != nil {
+		return ctx, pkgerrors.WithStack(config.NewLockError())
+	}
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "global_parameters_update", daemonIDs...)
+	recipe := &ConfigRecipe{
+		GlobalParametersConfigRecipeParams: GlobalParametersConfigRecipeParams{
+			DaemonsBeforeUpdate: daemons,
+		},
+	}
+	if err := state.SetRecipeForUpdate(0, recipe); err != nil {
+		return ctx, err
+	}
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Applies the updated global DHCP parameters. The parameters are specified
+// by daemon ID. The daemons for which no parameters have been specified
+// are not updated. This function copies the configurations of the daemons
+// stored in the database and replaces the global parameters in these
+// configurations. The resulting configurations are first sent to the
+// daemons with the config-test command to validate them. If all daemons
+// accept the configurations, they are applied with the config-set command
+// and written to disk with the config-write command.
+func (module *ConfigModule) ApplyGlobalParametersUpdate(ctx context.Context, params map[int64]*keaconfig.SettableGlobalParameters) (context.Context, error) {
+	recipe, err := config.GetRecipeForUpdate[ConfigRecipe](ctx, 0)
+	if err != nil {
+		return ctx, err
+	}
+	if len(recipe.DaemonsBeforeUpdate) == 0 {
+		return ctx, pkgerrors.New("internal server error: daemons cannot be empty when applying global parameters update")
+	}
+	var (
+		testCommands  []ConfigCommand
+		setCommands   []ConfigCommand
+		writeCommands []ConfigCommand
+	)
+	configs := make(map[int64]*dbmodel.KeaConfig)
+	for daemonID := range params {
+		found := false
+		for _, daemon := range recipe.DaemonsBeforeUpdate {
+			if daemon.ID == daemonID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ctx, pkgerrors.Errorf("applied global parameters for daemon %d which is not updated in this transaction", daemonID)
+		}
+	}
+	for _, daemon := range recipe.DaemonsBeforeUpdate {
+		daemonParams, ok := params[daemon.ID]
+		if !ok || daemonParams == nil {
+			continue
+		}
+		if daemon.App == nil {
+			return ctx, pkgerrors.Errorf("daemon %d is associated with nil app", daemon.ID)
+		}
+		// Copy the existing configuration to avoid modifying the one
+		// held in the recipe.
+		marshalled, err := json.Marshal(daemon.KeaDaemon.Config)
+		if err != nil {
+			return ctx, pkgerrors.Wrapf(err, "problem marshalling configuration of daemon %d", daemon.ID)
+		}
+		updatedConfig, err := dbmodel.NewKeaConfigFromJSON(string(marshalled))
+		if err != nil {
+			return ctx, pkgerrors.WithMessagef(err, "problem copying configuration of daemon %d", daemon.ID)
+		}
+		if err = updatedConfig.SetGlobalParameters(daemonParams); err != nil {
+			return ctx, pkgerrors.WithMessagef(err, "problem updating global parameters of daemon %d", daemon.ID)
+		}
+		configs[daemon.ID] = updatedConfig
+		testCommand := createConfigCommand("config-test", daemon, updatedConfig)
+		testCommand.Validation = true
+		testCommands = append(testCommands, testCommand)
+		setCommand := createConfigCommand("config-set", daemon, updatedConfig)
+		restoreCommand := createConfigCommand("config-set", daemon, daemon.KeaDaemon.Config)
+		setCommand.addCompensatingCommands(&restoreCommand)
+		setCommands = append(setCommands, setCommand)
+		writeCommands = append(writeCommands, createConfigWriteCommand(daemon))
+	}
+	if len(configs) == 0 {
+		return ctx, pkgerrors.New("no global parameters specified for the updated daemons")
+	}
+	recipe.ConfigsAfterUpdate = configs
+	recipe.Commands = append(testCommands, setCommands...)
+	recipe.Commands = append(recipe.Commands, writeCommands...)
+	return config.SetRecipeForUpdate(ctx, 0, recipe)
+}
+
+// Updates the global parameters in the Kea servers and in the database.
+// The configurations of the daemons are replaced in the database and the
+// configuration hashes are reset, so the configurations are refreshed from
+// the servers by the puller.
+func (module *ConfigModule) commitGlobalParametersUpdate(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, pkgerrors.New("context lacks state")
+	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	for _, update := range state.Updates {
+		if len(update.Recipe.ConfigsAfterUpdate) == 0 {
+			return ctx, pkgerrors.New("server logic error: the update.Recipe.ConfigsAfterUpdate cannot be empty when committing the global parameters update")
+		}
+		if err = module.updateDaemonConfigs(update.Recipe.ConfigsAfterUpdate); err != nil {
+			return ctx, pkgerrors.WithMessage(err, "global parameters have been successfully updated in Kea but updating the Stork database failed")
+		}
+	}
+	return ctx, nil
+}
+
+// Replaces the configurations of the daemons in the database with the
+// configurations committed in Kea. The configuration hashes are reset, so
+// the configurations are refreshed from the servers by the puller.
+func (module *ConfigModule) updateDaemonConfigs(configs map[int64]*dbmodel.KeaConfig) error {
+	for daemonID, updatedConfig := range configs {
+		// Fetch the daemon to make sure that the other daemon
+		// information in the database is not overwritten.
+		daemon, err := dbmodel.GetDaemonByID(module.manager.GetDB(), daemonID)
+		if err == nil && daemon == nil {
+			err = pkgerrors.WithStack(config.NewDaemonNotFoundError(daemonID))
+		}
+		if err == nil {
+			if err = daemon.SetConfig(updatedConfig); err == nil {
+				err = dbmodel.UpdateDaemon(module.manager.GetDB(), daemon)
+			}
+		}
+		if err != nil {
+			return pkgerrors.WithMessagef(err, "problem updating the configuration of daemon %d", daemonID)
+		}
+	}
+	return nil
+}
+
+// Begins applying the fix proposed in the config report. It fetches the
+// report and the daemon for which the report was generated from the
+// database and stores them in the context state. Then, it locks the daemon
+// for updates. It returns an error when the report does not exist or
+// proposes no fix, or when the daemon configuration has changed since the
+// report was generated.
+func (module *ConfigModule) BeginConfigReportFix(ctx context.Context, reportID int64) (context.Context, error) {
+	report, err := dbmodel.GetConfigReportByID(module.manager.GetDB(), reportID)
+	if err != nil {
+		// Internal database error.
+		return ctx, err
+	}
+	if report == nil || report.Fix == nil {
+		return ctx, pkgerrors.WithStack(config.NewConfigReportFixNotFoundError(reportID))
+	}
+	daemon, err := dbmodel.GetDaemonByID(module.manager.GetDB(), report.DaemonID)
+	if err != nil {
+		// Internal database error.
+		return ctx, err
+	}
+	if daemon == nil {
+		return ctx, pkgerrors.WithStack(config.NewDaemonNotFoundError(report.DaemonID))
+	}
+	if daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil ||
+		(!daemon.KeaDaemon.Config.IsDHCPv4() && !daemon.KeaDaemon.Config.IsDHCPv6()) {
+		return ctx, pkgerrors.Errorf("daemon %d is not a Kea DHCP server with a known configuration", daemon.ID)
+	}
+	// The fix patches the configuration it was generated for.
+	if report.Fix.ConfigHash == "" || report.Fix.ConfigHash != daemon.KeaDaemon.ConfigHash {
+		return ctx, pkgerrors.WithStack(config.NewConfigReportFixOutdatedError(reportID))
+	}
+	// Try to lock configurations.
+	ctx, err = module.manager.Lock(ctx, daemon.ID)
+	if err != nil {
+		return ctx, pkgerrors.WithStack(config.NewLockError())
+	}
+	// Create transaction state.
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "config_report_fix", daemon.ID)
+	recipe := &ConfigRecipe{
+		GlobalParametersConfigRecipeParams: GlobalParametersConfigRecipeParams{
+			DaemonsBeforeUpdate: []*dbmodel.Daemon{daemon},
+		},
+		ConfigReportFixConfigRecipeParams: ConfigReportFixConfigRecipeParams{
+			ConfigReportID:  &reportID,
+			ConfigReportFix: report.Fix,
+		},
+	}
+	if err := state.SetRecipeForUpdate(0, recipe); err != nil {
+		return ctx, err
+	}
+	ctx = context.WithValue(ctx, config.StateContextKey, *state)
+	return ctx, nil
+}
+
+// Applies the fix proposed in the config report to a copy of the daemon
+// configuration. The resulting configuration is first sent to the daemon
+// with the config-test command to validate it. If the daemon accepts the
+// configuration, it is applied with the config-set command and written
+// to disk with the config-write command.
+func (module *ConfigModule) ApplyConfigReportFix(ctx context.Context) (context.Context, error) {
+	recipe, err := config.GetRecipeForUpdate[ConfigRecipe](ctx, 0)
+	if err != nil {
+		return ctx, err
+	}
+	if len(recipe.DaemonsBeforeUpdate) != 1 || recipe.ConfigReportFix == nil {
+		return ctx, pkgerrors.New("internal server error: daemon and fix must be specified when applying config report fix")
+	}
+	daemon := recipe.DaemonsBeforeUpdate[0]
+	if daemon.App == nil {
+		return ctx, pkgerrors.Errorf("daemon %d is associated with nil app", daemon.ID)
+	}
+	updatedConfig, err := recipe.ConfigReportFix.Apply(daemon.KeaDaemon.Config)
+	if err != nil {
+		return ctx, pkgerrors.WithMessagef(err, "problem applying fix to the configuration of daemon %d", daemon.ID)
+	}
+	testCommand := createConfigCommand("config-test", daemon, updatedConfig)
+	testCommand.Validation = true
+	setCommand := createConfigCommand("config-set", daemon, updatedConfig)
+	restoreCommand := createConfigCommand("config-set", daemon, daemon.KeaDaemon.Config)
+	setCommand.addCompensatingCommands(&restoreCommand)
+
+	recipe.ConfigsAfterUpdate = map[int64]*dbmodel.KeaConfig{
+		daemon.ID: updatedConfig,
+	}
+	recipe.Commands = []ConfigCommand{testCommand, setCommand, createConfigWriteCommand(daemon)}
+	return config.SetRecipeForUpdate(ctx, 0, recipe)
+}
+
+// Applies the config report fix in the Kea server and updates the daemon
+// configuration in the database.
+func (module *ConfigModule) commitConfigReportFix(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, pkgerrors.New("context lacks state")
+	}
+	var err error
+	ctx, err = module.commitChanges(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	for _, update := range state.Updates {
+		if len(update.Recipe.ConfigsAfterUpdate) == 0 {
+			return ctx, pkgerrors.New("server logic error: the update.Recipe.ConfigsAfterUpdate cannot be empty when committing the config report fix")
+		}
+		if err = module.updateDaemonConfigs(update.Recipe.ConfigsAfterUpdate); err != nil {
+			return ctx, pkgerrors.WithMessage(err, "config report fix has been successfully applied in Kea but updating the Stork database failed")
+		}
+	}
+	return ctx, nil
+}
+
+// Creates a reservation-add command adding the host reservation to the
+// daemon associated with the local host.
+func (module *ConfigModule) createHostAddCommand(lh dbmodel.LocalHost, host *dbmodel.Host) (*ConfigCommand, error) {
+	lookup := module.getDHCPOptionDefinitionLookup()
+	reservation, err := keaconfig.CreateHostCmdsReservation(lh.DaemonID, lookup, host)
+	if err != nil {
+		return nil, err
+	}
+	arguments := map[string]any{
+		"reservation": reservation,
+	}
+	return &ConfigCommand{
+		Command: keactrl.NewCommand("reservation-add", []string{lh.Daemon.Name}, arguments),
+		App:     lh.Daemon.App,
+	}, nil
+}
+
+// Creates a reservation-del command deleting the host reservation from the
+// daemon associated with the local host.
+func createHostDeleteCommand(lh dbmodel.LocalHost, host *dbmodel.Host) (*ConfigCommand, error) {
+	arguments, err := keaconfig.CreateHostCmdsDeletedReservation(lh.DaemonID, host)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigCommand{
+		Command: keactrl.NewCommand("reservation-del", []string{lh.Daemon.Name}, arguments),
+		App:     lh.Daemon.App,
+	}, nil
+}
+
+// Checks that the local subnet can be used to generate the Kea commands.
+// The action is a verb describing the operation for which the local subnet
+// is validated (e.g., applied, deleted) and it is used in the error message.
+func validateLocalSubnet(action string, subnet *dbmodel.Subnet, ls *dbmodel.LocalSubnet) error {
+	if ls.Daemon == nil {
+		return pkgerrors.Errorf("%s subnet %s is associated with nil daemon", action, subnet.Prefix)
+	}
+	if ls.Daemon.App == nil {
+		return pkgerrors.Errorf("%s subnet %s is associated with nil app", action, subnet.Prefix)
+	}
+	if ls.LocalSubnetID == 0 {
+		return pkgerrors.Errorf("%s subnet %s lacks subnet ID for daemon %d", action, subnet.Prefix, ls.DaemonID)
+	}
+	return nil
+}
+
+// Returns the local subnet instance associated with the specified daemon
+// or nil if it does not exist.
+func getLocalSubnetByDaemonID(subnet *dbmodel.Subnet, daemonID int64) *dbmodel.LocalSubnet {
+	for _, ls := range subnet.LocalSubnets {
+		if ls.DaemonID == daemonID {
+			return ls
+		}
+	}
+	return nil
+}
+
+// Creates a command adding or updating the subnet in a daemon. The operation
+// is one of "add" or "update". It is used to construct the command name,
+// e.g., subnet4-add, subnet6-update.
+func (module *ConfigModule) createSubnetCommand(ls *dbmodel.LocalSubnet, subnet *dbmodel.Subnet, operation string) (*ConfigCommand, error) {
+	var (
+		keaSubnet any
+		err       error
+	)
+	// Convert the subnet information to the Kea subnet.
+	lookup := module.getDHCPOptionDefinitionLookup()
+	family := subnet.GetFamily()
+	switch family {
+	case 4:
+		keaSubnet, err = keaconfig.CreateSubnet4(ls.DaemonID, lookup, subnet)
+	default:
+		keaSubnet, err = keaconfig.CreateSubnet6(ls.DaemonID, lookup, subnet)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Create command arguments.
+	arguments := map[string]any{
+		fmt.Sprintf("subnet%d", family): []any{keaSubnet},
+	}
+	return &ConfigCommand{
+		Command: keactrl.NewCommand(fmt.Sprintf("subnet%d-%s", family, operation), []string{ls.Daemon.Name}, arguments),
+		App:     ls.Daemon.App,
+	}, nil
+}
+
+// Creates a command deleting the subnet from a daemon.
+func createSubnetDeleteCommand(ls *dbmodel.LocalSubnet, subnet *dbmodel.Subnet) *ConfigCommand {
+	arguments := map[string]any{
+		"id": ls.LocalSubnetID,
+	}
+	return &ConfigCommand{
+		Command: keactrl.NewCommand(fmt.Sprintf("subnet%d-del", subnet.GetFamily()), []string{ls.Daemon.Name}, arguments),
+		App:     ls.Daemon.App,
+	}
+}
+
+// Creates a command adding the subnet to a shared network or deleting it
+// from the shared network. The operation is one of "add" or "del". The
+// subnet must be associated with the shared network instance.
+func createSharedNetworkSubnetCommand(ls *dbmodel.LocalSubnet, subnet *dbmodel.Subnet, operation string) (*ConfigCommand, error) {
+	if subnet.SharedNetwork == nil {
+		return nil, pkgerrors.Errorf("subnet %s lacks the shared network %d information", subnet.Prefix, subnet.SharedNetworkID)
+	}
+	return createNetworkSubnetCommand(ls, subnet.GetFamily(), subnet.SharedNetwork.Name, operation), nil
+}
+
+// Creates a network4-subnet-add, network6-subnet-add, network4-subnet-del
+// or network6-subnet-del command for the local subnet and the shared
+// network having the specified name.
+func createNetworkSubnetCommand(ls *dbmodel.LocalSubnet, family int, sharedNetworkName, operation string) *ConfigCommand {
+	arguments := map[string]any{
+		"name": sharedNetworkName,
+		"id":   ls.LocalSubnetID,
+	}
+	return &ConfigCommand{
+		Command: keactrl.NewCommand(fmt.Sprintf("network%d-subnet-%s", family, operation), []string{ls.Daemon.Name}, arguments),
+		App:     ls.Daemon.App,
+	}
+}
+
+// Creates the commands re-creating the deleted subnet in a daemon. The
+// subnet is also added back to its shared network if it belonged to one.
+func (module *ConfigModule) createSubnetRestoreCommands(ls *dbmodel.LocalSubnet, subnet *dbmodel.Subnet) ([]*ConfigCommand, error) {
+	addCommand, err := module.createSubnetCommand(ls, subnet, "add")
+	if err != nil {
+		return nil, err
+	}
+	commands := []*ConfigCommand{addCommand}
+	if subnet.SharedNetwork != nil {
+		commands = append(commands, createNetworkSubnetCommand(ls, subnet.GetFamily(), subnet.SharedNetwork.Name, "add"))
+	}
+	return commands, nil
+}
+
+// Checks that the local shared network can be used to generate the Kea
+// commands. The action is a verb describing the operation for which the
+// local shared network is validated (e.g., applied, deleted) and it is
+// used in the error message.
+func validateLocalSharedNetwork(action string, sharedNetwork *dbmodel.SharedNetwork, lsn *dbmodel.LocalSharedNetwork) error {
+	if lsn.Daemon == nil {
+		return pkgerrors.Errorf("%s shared network %s is associated with nil daemon", action, sharedNetwork.Name)
+	}
+	if lsn.Daemon.App == nil {
+		return pkgerrors.Errorf("%s shared network %s is associated with nil app", action, sharedNetwork.Name)
+	}
+	return nil
+}
+
+// Creates a command adding the shared network to a daemon. The command
+// does not include the subnets. They are added to the shared network
+// with separate commands.
+func (module *ConfigModule) createSharedNetworkAddCommand(lsn *dbmodel.LocalSharedNetwork, sharedNetwork *dbmodel.SharedNetwork) (*ConfigCommand, error) {
+	var (
+		keaSharedNetwork any
+		err              error
+	)
+	// Convert the shared network information to the Kea shared network.
+	lookup := module.getDHCPOptionDefinitionLookup()
+	switch sharedNetwork.Family {
+	case 4:
+		keaSharedNetwork, err = keaconfig.CreateSharedNetwork4(lsn.DaemonID, lookup, sharedNetwork)
+	default:
+		keaSharedNetwork, err = keaconfig.CreateSharedNetwork6(lsn.DaemonID, lookup, sharedNetwork)
+	}
+	if err != nil {
+		return nil, err
+	}
+	arguments := map[string]any{
+		"shared-networks": []any{keaSharedNetwork},
+	}
+	return &ConfigCommand{
+		Command: keactrl.NewCommand(fmt.Sprintf("network%d-add", sharedNetwork.Family), []string{lsn.Daemon.Name}, arguments),
+		App:     lsn.Daemon.App,
+	}, nil
+}
+
+// Creates a command deleting the shared network from a daemon. The
+// subnets belonging to the shared network are preserved as top-level
+// subnets.
+func createSharedNetworkDeleteCommand(lsn *dbmodel.LocalSharedNetwork, sharedNetwork *dbmodel.SharedNetwork) *ConfigCommand {
+	arguments := map[string]any{
+		"name":           sharedNetwork.Name,
+		"subnets-action": "keep",
+	}
+	return &ConfigCommand{
+		Command: keactrl.NewCommand(fmt.Sprintf("network%d-del", sharedNetwork.Family), []string{lsn.Daemon.Name}, arguments),
+		App:     lsn.Daemon.App,
+	}
+}
+
+// Creates the commands re-creating the deleted shared network in a daemon.
+// The shared network is deleted with the subnets-action set to keep, so
+// the subnets belonging to it must be moved back to the re-created
+// shared network.
+func (module *ConfigModule) createSharedNetworkRestoreCommands(lsn *dbmodel.LocalSharedNetwork, sharedNetwork *dbmodel.SharedNetwork) ([]*ConfigCommand, error) {
+	addCommand, err := module.createSharedNetworkAddCommand(lsn, sharedNetwork)
+	if err != nil {
+		return nil, err
+	}
+	commands := []*ConfigCommand{addCommand}
+	for i := range sharedNetwork.Subnets {
+		ls := getLocalSubnetByDaemonID(&sharedNetwork.Subnets[i], lsn.DaemonID)
+		if ls == nil || ls.LocalSubnetID == 0 {
+			continue
+		}
+		arguments := map[string]any{
+			"name": sharedNetwork.Name,
+			"id":   ls.LocalSubnetID,
+		}
+		commands = append(commands, &ConfigCommand{
+			Command: keactrl.NewCommand(fmt.Sprintf("network%d-subnet-add", sharedNetwork.Family), []string{lsn.Daemon.Name}, arguments),
+			App:     lsn.Daemon.App,
+		})
+	}
+	return commands, nil
+}
+
+// Creates the commands adding the subnets listed in the shared network
+// to this shared network in a daemon. If a subnet belongs to another
+// shared network, it is first deleted from that shared network. The
+// subnets not configured in the daemon are skipped.
+func createSharedNetworkSubnetsCommands(lsn *dbmodel.LocalSharedNetwork, sharedNetwork *dbmodel.SharedNetwork) (commands []ConfigCommand, err error) {
+	for i := range sharedNetwork.Subnets {
+		subnet := &sharedNetwork.Subnets[i]
+		ls := getLocalSubnetByDaemonID(subnet, lsn.DaemonID)
+		if ls == nil {
+			continue
+		}
+		if err = validateLocalSubnet("applied", subnet, ls); err != nil {
+			return nil, err
+		}
+		if subnet.SharedNetworkID != 0 && subnet.SharedNetworkID != sharedNetwork.ID {
+			// The subnet is moved from another shared network.
+			var networkCommand *ConfigCommand
+			if networkCommand, err = createSharedNetworkSubnetCommand(ls, subnet, "del"); err != nil {
+				return nil, err
+			}
+			commands = append(commands, *networkCommand)
+		}
+		commands = append(commands, *createNetworkSubnetCommand(ls, sharedNetwork.Family, sharedNetwork.Name, "add"))
+	}
+	return commands, nil
+}
+
+// Creates config-write commands for the daemons associated with the specified
+// local subnets. The subnet_cmds hook library modifies the configuration of
+// a running server only. The config-write command makes the changes persistent.
+func createConfigWriteCommands(localSubnets []*dbmodel.LocalSubnet) (commands []ConfigCommand) {
+	for _, ls := range localSubnets {
+		commands = append(commands, createConfigWriteCommand(ls.Daemon))
+	}
+	return
+}
+
+// Creates a config-write command for the specified daemon.
+func createConfigWriteCommand(daemon *dbmodel.Daemon) ConfigCommand {
+	return ConfigCommand{
+		Command: keactrl.NewCommand("config-write", []string{daemon.Name}, nil),
+		App:     daemon.App,
+	}
+}
+
+// Creates a command carrying the entire daemon configuration, e.g.,
+// config-test or config-set.
+func createConfigCommand(command string, daemon *dbmodel.Daemon, cfg *dbmodel.KeaConfig) ConfigCommand {
+	return ConfigCommand{
+		Command: keactrl.NewCommand(command, []string{daemon.Name}, cfg),
+		App:     daemon.App,
+	}
+}
+
+// Generic function used to commit configuration changes (e.g., delete, add or
+// update host reservation, subnet or shared network) using the data stored in the context.
+// In the two-phase commit, the commands applied in the daemons are recorded
+// in the commit journal and they are rolled back when any of the subsequent
+// commands fails.
+func (module *ConfigModule) commitChanges(ctx context.Context) (context.Context, error) {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return ctx, pkgerrors.New("context lacks state")
+	}
+	journal := getCommitJournal(ctx)
+	for _, update := range state.Updates {
+		// Retrieve associations between the commands and apps.
+		// Iterate over the associations.
+		for _, acs := range update.Recipe.Commands {
+			// The validating commands have already been sent in the
+			// validation phase of the two-phase commit.
+			if journal != nil && acs.Validation {
+				continue
+			}
+			// Send the command to Kea.
+			if err := module.sendCommand(acs.App, acs.Command); err != nil {
+				if journal != nil {
+					journal.logf("%s", err)
+					err = module.rollbackChanges(journal, err)
+				}
+				return ctx, err
+			}
+			if journal != nil {
+				journal.applied = append(journal.applied, acs)
+				journal.logf("applied %s in %s", acs.Command.GetCommand(), acs.App.GetName())
+			}
 		}
 	}
 	return ctx, nil
@@ -3,6 +3,7 @@ package kea
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 	"isc.org/stork/server/config"
 	dbmodel "isc.org/stork/server/database/model"
 	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/eventcenter"
 	storktestdbmodel "isc.org/stork/server/test/dbmodel"
 	storkutil "isc.org/stork/util"
 )
@@ -24,9 +26,10 @@ import (
 // Test config manager. Besides returning database and agents instance
 // it also provides additional functions useful in testing.
 type testManager struct {
-	db     *pg.DB
-	agents agentcomm.ConnectedAgents
-	lookup keaconfig.DHCPOptionDefinitionLookup
+	db          *pg.DB
+	agents      agentcomm.ConnectedAgents
+	lookup      keaconfig.DHCPOptionDefinitionLookup
+	eventCenter eventcenter.EventCenter
 
 	locks map[int64]bool
 }
@@ -34,9 +37,10 @@ type testManager struct {
 // Creates new test config manager instance.
 func newTestManager(server config.ManagerAccessors) *testManager {
 	return &testManager{
-		db:     server.GetDB(),
-		agents: server.GetConnectedAgents(),
-		locks:  make(map[int64]bool),
+		db:          server.GetDB(),
+		agents:      server.GetConnectedAgents(),
+		eventCenter: server.GetEventCenter(),
+		locks:       make(map[int64]bool),
 	}
 }
 
@@ -56,6 +60,11 @@ func (tm *testManager) GetDHCPOptionDefinitionLookup() keaconfig.DHCPOptionDefin
 	return tm.lookup
 }
 
+// Returns an interface to the test event center.
+func (tm *testManager) GetEventCenter() eventcenter.EventCenter {
+	return tm.eventCenter
+}
+
 // Applies locks on specified daemons.
 func (tm *testManager) Lock(ctx context.Context, daemonIDs ...int64) (context.Context, error) {
 	for _, id := range daemonIDs {
@@ -459,6 +468,170 @@ func TestCommitHostAddResponseWithErrorStatus(t *testing.T) {
 	require.Len(t, agents.RecordedCommands, 1)
 }
 
+// Test that the transaction for importing the hosts is created.
+func TestBeginHostsImport(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	ctx, err := module.BeginHostsImport(context.Background())
+	require.NoError(t, err)
+
+	// There should be no locks on any daemons.
+	require.Empty(t, manager.locks)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, state.Updates, 1)
+	require.Equal(t, datamodel.AppTypeKea, state.Updates[0].Target)
+	require.Equal(t, "hosts_import", state.Updates[0].Operation)
+}
+
+// Test applying a batch of imported hosts.
+func TestApplyHostsImport(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "hosts_import")
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	// Empty batch is not allowed.
+	_, err := module.ApplyHostsImport(ctx, nil)
+	require.Error(t, err)
+
+	host1 := createTestHostForTwoPhaseCommit()
+	host2 := createTestHostForTwoPhaseCommit()
+	host2.Hostname = "other.example.org"
+	host2.HostIdentifiers[0].Value = []byte{6, 5, 4, 3, 2, 1}
+
+	ctx, err = module.ApplyHostsImport(ctx, []*dbmodel.Host{host1, host2})
+	require.NoError(t, err)
+
+	returnedState, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, returnedState.Updates, 1)
+	recipe := returnedState.Updates[0].Recipe
+	require.Len(t, recipe.ImportedHosts, 2)
+
+	// Each host is added to two daemons.
+	require.Len(t, recipe.Commands, 4)
+	for i, command := range recipe.Commands {
+		require.Equal(t, "reservation-add", command.Command.GetCommand())
+		require.Len(t, command.CompensatingCommands, 1)
+		require.Equal(t, "reservation-del", command.CompensatingCommands[0].GetCommand())
+		require.Equal(t, host1.LocalHosts[i%2].Daemon.App, command.App)
+	}
+
+	// A host lacking daemons is rejected.
+	_, err = module.ApplyHostsImport(ctx, []*dbmodel.Host{{ID: 5}})
+	require.Error(t, err)
+}
+
+// Test committing a batch of imported hosts.
+func TestCommitHostsImport(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	ctx, err := module.BeginHostsImport(context.Background())
+	require.NoError(t, err)
+
+	var hosts []*dbmodel.Host
+	for i := 0; i < 2; i++ {
+		hosts = append(hosts, &dbmodel.Host{
+			Hostname: fmt.Sprintf("host%d.example.org", i),
+			HostIdentifiers: []dbmodel.HostIdentifier{
+				{
+					Type:  "hw-address",
+					Value: []byte{1, 2, 3, 4, 5, byte(i)},
+				},
+			},
+			LocalHosts: []dbmodel.LocalHost{
+				{
+					DaemonID: apps[0].Daemons[0].ID,
+					Daemon: &dbmodel.Daemon{
+						Name: "dhcp4",
+						App: &dbmodel.App{
+							AccessPoints: []*dbmodel.AccessPoint{
+								{
+									Type:    dbmodel.AccessPointControl,
+									Address: "192.0.2.1",
+									Port:    1234,
+								},
+							},
+						},
+					},
+					DataSource: dbmodel.HostDataSourceAPI,
+				},
+			},
+		})
+	}
+	ctx, err = module.ApplyHostsImport(ctx, hosts)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	// The commands should be sent to one server.
+	require.Len(t, agents.RecordedCommands, 2)
+	for _, command := range agents.RecordedCommands {
+		require.Equal(t, "reservation-add", command.GetCommand())
+	}
+
+	// The hosts should be added to the database.
+	for _, host := range hosts {
+		require.NotZero(t, host.ID)
+		newHost, err := dbmodel.GetHost(db, host.ID)
+		require.NoError(t, err)
+		require.NotNil(t, newHost)
+		require.Equal(t, host.Hostname, newHost.Hostname)
+		require.Len(t, newHost.LocalHosts, 1)
+	}
+}
+
+// Test that the host reservations already added to Kea are deleted when
+// adding another host reservation from the imported batch fails, even if
+// the two-phase commit is disabled.
+func TestCommitHostsImportRollback(t *testing.T) {
+	// The first daemon accepts the host reservation and the second one
+	// rejects it. The subsequent rollback succeeds.
+	agents := agentcommtest.NewKeaFakeAgents(mockKeaResult(keactrl.ResponseSuccess), mockKeaResult(keactrl.ResponseError), mockKeaResult(keactrl.ResponseSuccess))
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	ctx, err := module.BeginHostsImport(context.Background())
+	require.NoError(t, err)
+	ctx, err = module.ApplyHostsImport(ctx, []*dbmodel.Host{createTestHostForTwoPhaseCommit()})
+	require.NoError(t, err)
+
+	_, err = module.commitHostsImport(ctx)
+	require.ErrorContains(t, err, "applied changes have been rolled back")
+
+	require.Len(t, agents.RecordedCommands, 3)
+	require.Equal(t, "reservation-add", agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, "reservation-add", agents.RecordedCommands[1].GetCommand())
+	require.Equal(t, "reservation-del", agents.RecordedCommands[2].GetCommand())
+	require.Equal(t, "http://192.0.2.1:1234/", agents.RecordedURLs[2])
+}
+
 // Test scheduling config changes in the database, retrieving and committing it.
 func TestCommitScheduledHostAdd(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
@@ -1412,3 +1585,1784 @@ func TestCommitScheduledHostDelete(t *testing.T) {
 	require.NoError(t, err)
 	require.Nil(t, returnedHost)
 }
+
+// Returns a test subnet associated with two daemons and belonging to
+// a shared network.
+func createTestSubnetWithTwoDaemons() *dbmodel.Subnet {
+	return &dbmodel.Subnet{
+		ID:              1,
+		Prefix:          "192.0.2.0/24",
+		SharedNetworkID: 1,
+		SharedNetwork: &dbmodel.SharedNetwork{
+			ID:   1,
+			Name: "foo",
+		},
+		LocalSubnets: []*dbmodel.LocalSubnet{
+			{
+				DaemonID:      1,
+				LocalSubnetID: 123,
+				Daemon: &dbmodel.Daemon{
+					Name: "dhcp4",
+					App: &dbmodel.App{
+						AccessPoints: []*dbmodel.AccessPoint{
+							{
+								Type:    dbmodel.AccessPointControl,
+								Address: "192.0.2.1",
+								Port:    1234,
+							},
+						},
+					},
+				},
+				AddressPools: []dbmodel.AddressPool{
+					{
+						LowerBound: "192.0.2.10",
+						UpperBound: "192.0.2.20",
+					},
+				},
+			},
+			{
+				DaemonID:      2,
+				LocalSubnetID: 123,
+				Daemon: &dbmodel.Daemon{
+					Name: "dhcp4",
+					App: &dbmodel.App{
+						AccessPoints: []*dbmodel.AccessPoint{
+							{
+								Type:    dbmodel.AccessPointControl,
+								Address: "192.0.2.2",
+								Port:    2345,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Test first stage of adding a new subnet.
+func TestBeginSubnetAdd(t *testing.T) {
+	module := NewConfigModule(nil)
+	require.NotNil(t, module)
+
+	ctx, err := module.BeginSubnetAdd(context.Background())
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, state.Updates, 1)
+	require.Equal(t, datamodel.AppTypeKea, state.Updates[0].Target)
+	require.Equal(t, "subnet_add", state.Updates[0].Operation)
+}
+
+// Test second stage of adding a new subnet.
+func TestApplySubnetAdd(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "subnet_add")
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	subnet := createTestSubnetWithTwoDaemons()
+	ctx, err := module.ApplySubnetAdd(ctx, subnet)
+	require.NoError(t, err)
+
+	returnedState, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, returnedState.Updates, 1)
+	update := returnedState.Updates[0]
+	require.Equal(t, "subnet_add", update.Operation)
+	require.Equal(t, subnet, update.Recipe.SubnetAfterUpdate)
+
+	// Each daemon should receive subnet4-add, network4-subnet-add and
+	// config-write.
+	commands := update.Recipe.Commands
+	require.Len(t, commands, 6)
+
+	require.JSONEq(t,
+		`{
+             "command": "subnet4-add",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "subnet4": [
+                     {
+                         "id": 123,
+                         "subnet": "192.0.2.0/24",
+                         "pools": [
+                             {
+                                 "pool": "192.0.2.10-192.0.2.20"
+                             }
+                         ]
+                     }
+                 ]
+             }
+         }`,
+		commands[0].Command.Marshal())
+	require.Equal(t, subnet.LocalSubnets[0].Daemon.App, commands[0].App)
+
+	require.JSONEq(t,
+		`{
+             "command": "network4-subnet-add",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "name": "foo",
+                 "id": 123
+             }
+         }`,
+		commands[1].Command.Marshal())
+	require.Equal(t, subnet.LocalSubnets[0].Daemon.App, commands[1].App)
+
+	require.JSONEq(t,
+		`{
+             "command": "subnet4-add",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "subnet4": [
+                     {
+                         "id": 123,
+                         "subnet": "192.0.2.0/24"
+                     }
+                 ]
+             }
+         }`,
+		commands[2].Command.Marshal())
+	require.Equal(t, subnet.LocalSubnets[1].Daemon.App, commands[2].App)
+
+	require.Equal(t, "network4-subnet-add", commands[3].Command.GetCommand())
+	require.Equal(t, subnet.LocalSubnets[1].Daemon.App, commands[3].App)
+
+	for i, command := range commands[4:] {
+		require.JSONEq(t,
+			`{
+                 "command": "config-write",
+                 "service": [ "dhcp4" ]
+             }`,
+			command.Command.Marshal())
+		require.Equal(t, subnet.LocalSubnets[i].Daemon.App, command.App)
+	}
+}
+
+// Test that applying a subnet lacking the local subnet ID fails.
+func TestApplySubnetAddNoLocalSubnetID(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "subnet_add")
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	subnet := createTestSubnetWithTwoDaemons()
+	subnet.LocalSubnets[1].LocalSubnetID = 0
+	_, err := module.ApplySubnetAdd(ctx, subnet)
+	require.ErrorContains(t, err, "applied subnet 192.0.2.0/24 lacks subnet ID for daemon 2")
+}
+
+// Test committing added subnet, i.e. actually sending control commands to Kea.
+func TestCommitSubnetAdd(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "subnet_add")
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	subnet := &dbmodel.Subnet{
+		Prefix: "192.0.3.0/24",
+	}
+	for i := range apps {
+		subnet.LocalSubnets = append(subnet.LocalSubnets, &dbmodel.LocalSubnet{
+			DaemonID:      apps[i].Daemons[0].ID,
+			LocalSubnetID: 333,
+			Daemon:        apps[i].Daemons[0],
+			AddressPools: []dbmodel.AddressPool{
+				{
+					LowerBound: "192.0.3.10",
+					UpperBound: "192.0.3.20",
+				},
+			},
+		})
+		subnet.LocalSubnets[i].Daemon.App = &apps[i]
+	}
+	ctx, err := module.ApplySubnetAdd(ctx, subnet)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	// Two subnet4-add commands and two config-write commands.
+	require.Len(t, agents.RecordedURLs, 4)
+	require.Equal(t, "https://localhost:1234/", agents.RecordedURLs[0])
+	require.Equal(t, "https://localhost:1235/", agents.RecordedURLs[1])
+	require.Equal(t, "https://localhost:1234/", agents.RecordedURLs[2])
+	require.Equal(t, "https://localhost:1235/", agents.RecordedURLs[3])
+
+	require.Len(t, agents.RecordedCommands, 4)
+	require.Equal(t, "subnet4-add", agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, "subnet4-add", agents.RecordedCommands[1].GetCommand())
+	require.Equal(t, "config-write", agents.RecordedCommands[2].GetCommand())
+	require.Equal(t, "config-write", agents.RecordedCommands[3].GetCommand())
+
+	// Make sure that the subnet has been added to the database too.
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.3.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+	require.Len(t, subnets[0].LocalSubnets, 2)
+}
+
+// Test first stage of a subnet update.
+func TestBeginSubnetUpdate(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.2.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+
+	ctx, err := module.BeginSubnetUpdate(context.Background(), subnets[0].ID)
+	require.NoError(t, err)
+
+	// Make sure that the locks have been applied on the daemons owning
+	// the subnet.
+	require.Contains(t, manager.locks, apps[0].Daemons[0].ID)
+	require.Contains(t, manager.locks, apps[1].Daemons[0].ID)
+
+	// Make sure that the subnet information has been stored in the context.
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, state.Updates, 1)
+	require.Equal(t, datamodel.AppTypeKea, state.Updates[0].Target)
+	require.Equal(t, "subnet_update", state.Updates[0].Operation)
+	require.NotNil(t, state.Updates[0].Recipe.SubnetBeforeUpdate)
+}
+
+// Test that beginning an update of a non-existing subnet fails.
+func TestBeginSubnetUpdateNonExisting(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB: db,
+	})
+
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	_, err := module.BeginSubnetUpdate(context.Background(), 1024)
+	var subnetNotFound *config.SubnetNotFoundError
+	require.ErrorAs(t, err, &subnetNotFound)
+}
+
+// Test second stage of a subnet update. The subnet is removed from one
+// daemon, updated in another daemon and added to a new daemon. It is
+// also moved to another shared network.
+func TestApplySubnetUpdate(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	existingSubnet := createTestSubnetWithTwoDaemons()
+
+	daemonIDs := []int64{1, 2}
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "subnet_update", daemonIDs...)
+	recipe := ConfigRecipe{
+		SubnetConfigRecipeParams: SubnetConfigRecipeParams{
+			SubnetBeforeUpdate: existingSubnet,
+		},
+	}
+	err := state.SetRecipeForUpdate(0, &recipe)
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	subnet := createTestSubnetWithTwoDaemons()
+	subnet.SharedNetworkID = 2
+	subnet.SharedNetwork = &dbmodel.SharedNetwork{
+		ID:   2,
+		Name: "bar",
+	}
+	subnet.LocalSubnets[1] = &dbmodel.LocalSubnet{
+		DaemonID:      3,
+		LocalSubnetID: 123,
+		Daemon: &dbmodel.Daemon{
+			Name: "dhcp4",
+			App: &dbmodel.App{
+				AccessPoints: []*dbmodel.AccessPoint{
+					{
+						Type:    dbmodel.AccessPointControl,
+						Address: "192.0.2.3",
+						Port:    3456,
+					},
+				},
+			},
+		},
+	}
+
+	ctx, err = module.ApplySubnetUpdate(ctx, subnet)
+	require.NoError(t, err)
+
+	returnedState, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, returnedState.Updates, 1)
+	update := returnedState.Updates[0]
+	require.Equal(t, "subnet_update", update.Operation)
+	require.Equal(t, subnet, update.Recipe.SubnetAfterUpdate)
+
+	commands := update.Recipe.Commands
+	require.Len(t, commands, 9)
+
+	// The subnet is deleted from the second daemon.
+	require.JSONEq(t,
+		`{
+             "command": "subnet4-del",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "id": 123
+             }
+         }`,
+		commands[0].Command.Marshal())
+	require.Equal(t, existingSubnet.LocalSubnets[1].Daemon.App, commands[0].App)
+
+	// The subnet is updated in the first daemon and moved to another
+	// shared network.
+	require.Equal(t, "subnet4-update", commands[1].Command.GetCommand())
+	require.Equal(t, subnet.LocalSubnets[0].Daemon.App, commands[1].App)
+	require.JSONEq(t,
+		`{
+             "command": "network4-subnet-del",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "name": "foo",
+                 "id": 123
+             }
+         }`,
+		commands[2].Command.Marshal())
+	require.JSONEq(t,
+		`{
+             "command": "network4-subnet-add",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "name": "bar",
+                 "id": 123
+             }
+         }`,
+		commands[3].Command.Marshal())
+
+	// The subnet is added to the third daemon.
+	require.Equal(t, "subnet4-add", commands[4].Command.GetCommand())
+	require.Equal(t, subnet.LocalSubnets[1].Daemon.App, commands[4].App)
+	require.Equal(t, "network4-subnet-add", commands[5].Command.GetCommand())
+	require.Equal(t, subnet.LocalSubnets[1].Daemon.App, commands[5].App)
+
+	// All daemons should write their configurations.
+	require.Equal(t, "config-write", commands[6].Command.GetCommand())
+	require.Equal(t, existingSubnet.LocalSubnets[1].Daemon.App, commands[6].App)
+	require.Equal(t, "config-write", commands[7].Command.GetCommand())
+	require.Equal(t, subnet.LocalSubnets[0].Daemon.App, commands[7].App)
+	require.Equal(t, "config-write", commands[8].Command.GetCommand())
+	require.Equal(t, subnet.LocalSubnets[1].Daemon.App, commands[8].App)
+}
+
+// Test that the subnet update is rejected when the applied subnet has
+// a different ID than the subnet for which the update has begun.
+func TestApplySubnetUpdateIDMismatch(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	daemonIDs := []int64{1, 2}
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "subnet_update", daemonIDs...)
+	recipe := ConfigRecipe{
+		SubnetConfigRecipeParams: SubnetConfigRecipeParams{
+			SubnetBeforeUpdate: createTestSubnetWithTwoDaemons(),
+		},
+	}
+	err := state.SetRecipeForUpdate(0, &recipe)
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	subnet := createTestSubnetWithTwoDaemons()
+	subnet.ID = 2
+
+	_, err = module.ApplySubnetUpdate(ctx, subnet)
+	require.ErrorContains(t, err, "applied subnet ID 2 does not match the updated subnet ID 1")
+}
+
+// Test committing updated subnet, i.e. actually sending control commands to Kea.
+func TestCommitSubnetUpdate(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	storktestdbmodel.AddTestHosts(t, db)
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.2.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+
+	ctx, err := module.BeginSubnetUpdate(context.Background(), subnets[0].ID)
+	require.NoError(t, err)
+
+	subnet, err := dbmodel.GetSubnet(db, subnets[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, subnet)
+	subnet.ClientClass = "foo"
+	for _, ls := range subnet.LocalSubnets {
+		ls.AddressPools = []dbmodel.AddressPool{
+			{
+				LowerBound: "192.0.2.100",
+				UpperBound: "192.0.2.200",
+			},
+		}
+	}
+
+	ctx, err = module.ApplySubnetUpdate(ctx, subnet)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	// Two subnet4-update commands and two config-write commands.
+	require.Len(t, agents.RecordedCommands, 4)
+	require.Equal(t, "subnet4-update", agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, "subnet4-update", agents.RecordedCommands[1].GetCommand())
+	require.Equal(t, "config-write", agents.RecordedCommands[2].GetCommand())
+	require.Equal(t, "config-write", agents.RecordedCommands[3].GetCommand())
+
+	// Make sure that the subnet has been updated in the database.
+	updatedSubnet, err := dbmodel.GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.NotNil(t, updatedSubnet)
+	require.Equal(t, "foo", updatedSubnet.ClientClass)
+	require.Len(t, updatedSubnet.LocalSubnets, 2)
+	for _, ls := range updatedSubnet.LocalSubnets {
+		require.Len(t, ls.AddressPools, 1)
+		require.Equal(t, "192.0.2.100", ls.AddressPools[0].LowerBound)
+	}
+}
+
+// Test scheduling a subnet update, retrieving the scheduled operation
+// from the database and performing it.
+func TestCommitScheduledSubnetUpdate(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	storktestdbmodel.AddTestHosts(t, db)
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	// It is required to associate the config change with a user.
+	user := &dbmodel.SystemUser{
+		Login:    "test",
+		Lastname: "test",
+		Name:     "test",
+	}
+	_, err := dbmodel.CreateUser(db, user)
+	require.NoError(t, err)
+	require.NotZero(t, user.ID)
+
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.2.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+
+	ctx := context.WithValue(context.Background(), config.UserContextKey, int64(user.ID))
+	ctx, err = module.BeginSubnetUpdate(ctx, subnets[0].ID)
+	require.NoError(t, err)
+
+	subnet, err := dbmodel.GetSubnet(db, subnets[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, subnet)
+	subnet.ClientClass = "foo"
+
+	ctx, err = module.ApplySubnetUpdate(ctx, subnet)
+	require.NoError(t, err)
+
+	// Simulate scheduling the config change and retrieving it from the database.
+	// The context will hold re-created transaction state.
+	ctx = manager.scheduleAndGetChange(ctx, t)
+	require.NotNil(t, ctx)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, agents.RecordedCommands, 4)
+
+	updatedSubnet, err := dbmodel.GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.NotNil(t, updatedSubnet)
+	require.Equal(t, "foo", updatedSubnet.ClientClass)
+}
+
+// Test first stage of deleting a subnet.
+func TestBeginSubnetDelete(t *testing.T) {
+	module := NewConfigModule(nil)
+	require.NotNil(t, module)
+
+	ctx1 := context.Background()
+	ctx2, err := module.BeginSubnetDelete(ctx1)
+	require.NoError(t, err)
+	require.Equal(t, ctx1, ctx2)
+}
+
+// Test second stage of deleting a subnet.
+func TestApplySubnetDelete(t *testing.T) {
+	module := NewConfigModule(nil)
+	require.NotNil(t, module)
+
+	daemonIDs := []int64{1, 2}
+	ctx := context.WithValue(context.Background(), config.DaemonsContextKey, daemonIDs)
+
+	subnet := createTestSubnetWithTwoDaemons()
+	ctx, err := module.ApplySubnetDelete(ctx, subnet)
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.False(t, state.Scheduled)
+	require.Len(t, state.Updates, 1)
+	update := state.Updates[0]
+	require.Equal(t, datamodel.AppTypeKea, update.Target)
+	require.Equal(t, "subnet_delete", update.Operation)
+	require.ElementsMatch(t, daemonIDs, update.DaemonIDs)
+	require.NotNil(t, update.Recipe.SubnetID)
+	require.EqualValues(t, 1, *update.Recipe.SubnetID)
+
+	commands := update.Recipe.Commands
+	require.Len(t, commands, 4)
+	for i := 0; i < 2; i++ {
+		require.JSONEq(t,
+			`{
+                 "command": "subnet4-del",
+                 "service": [ "dhcp4" ],
+                 "arguments": {
+                     "id": 123
+                 }
+             }`,
+			commands[i].Command.Marshal())
+		require.Equal(t, subnet.LocalSubnets[i].Daemon.App, commands[i].App)
+		require.Equal(t, "config-write", commands[i+2].Command.GetCommand())
+		require.Equal(t, subnet.LocalSubnets[i].Daemon.App, commands[i+2].App)
+	}
+}
+
+// Test committing deleted subnet, i.e. actually sending control commands to Kea.
+func TestCommitSubnetDelete(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	storktestdbmodel.AddTestHosts(t, db)
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.2.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+
+	subnet, err := dbmodel.GetSubnet(db, subnets[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, subnet)
+
+	daemonIDs := []int64{1}
+	ctx := context.WithValue(context.Background(), config.DaemonsContextKey, daemonIDs)
+	ctx, err = module.ApplySubnetDelete(ctx, subnet)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, agents.RecordedURLs, 4)
+	require.Equal(t, "https://localhost:1234/", agents.RecordedURLs[0])
+	require.Equal(t, "https://localhost:1235/", agents.RecordedURLs[1])
+
+	require.Len(t, agents.RecordedCommands, 4)
+	for _, command := range agents.RecordedCommands[:2] {
+		require.JSONEq(t,
+			`{
+                 "command": "subnet4-del",
+                 "service": [ "dhcp4" ],
+                 "arguments": {
+                     "id": 111
+                 }
+             }`,
+			command.Marshal())
+	}
+
+	returnedSubnet, err := dbmodel.GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.Nil(t, returnedSubnet)
+}
+
+// Returns a test shared network associated with two daemons. It includes
+// a subnet configured in the first daemon which currently belongs to
+// another shared network.
+func createTestSharedNetworkWithTwoDaemons() *dbmodel.SharedNetwork {
+	return &dbmodel.SharedNetwork{
+		ID:     1,
+		Name:   "foo",
+		Family: 4,
+		LocalSharedNetworks: []*dbmodel.LocalSharedNetwork{
+			{
+				DaemonID: 1,
+				Daemon: &dbmodel.Daemon{
+					Name: "dhcp4",
+					App: &dbmodel.App{
+						AccessPoints: []*dbmodel.AccessPoint{
+							{
+								Type:    dbmodel.AccessPointControl,
+								Address: "192.0.2.1",
+								Port:    1234,
+							},
+						},
+					},
+				},
+				KeaParameters: &keaconfig.SharedNetworkParameters{
+					Interface: storkutil.Ptr("eth0"),
+				},
+			},
+			{
+				DaemonID: 2,
+				Daemon: &dbmodel.Daemon{
+					Name: "dhcp4",
+					App: &dbmodel.App{
+						AccessPoints: []*dbmodel.AccessPoint{
+							{
+								Type:    dbmodel.AccessPointControl,
+								Address: "192.0.2.2",
+								Port:    2345,
+							},
+						},
+					},
+				},
+			},
+		},
+		Subnets: []dbmodel.Subnet{
+			{
+				ID:              2,
+				Prefix:          "192.0.2.0/24",
+				SharedNetworkID: 2,
+				SharedNetwork: &dbmodel.SharedNetwork{
+					ID:   2,
+					Name: "bar",
+				},
+				LocalSubnets: []*dbmodel.LocalSubnet{
+					{
+						DaemonID:      1,
+						LocalSubnetID: 123,
+						Daemon: &dbmodel.Daemon{
+							Name: "dhcp4",
+							App: &dbmodel.App{
+								AccessPoints: []*dbmodel.AccessPoint{
+									{
+										Type:    dbmodel.AccessPointControl,
+										Address: "192.0.2.1",
+										Port:    1234,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Adds a shared network to the database and associates it with the
+// first DHCPv4 daemons of the specified apps.
+func addTestSharedNetwork(t *testing.T, db *pg.DB, apps []dbmodel.App) *dbmodel.SharedNetwork {
+	sharedNetwork := &dbmodel.SharedNetwork{
+		Name:   "foo",
+		Family: 4,
+	}
+	for _, app := range apps {
+		sharedNetwork.LocalSharedNetworks = append(sharedNetwork.LocalSharedNetworks, &dbmodel.LocalSharedNetwork{
+			DaemonID: app.Daemons[0].ID,
+		})
+	}
+	err := dbmodel.AddSharedNetworkWithLocalSharedNetworks(db, sharedNetwork)
+	require.NoError(t, err)
+	require.NotZero(t, sharedNetwork.ID)
+	return sharedNetwork
+}
+
+// Test first stage of adding a new shared network.
+func TestBeginSharedNetworkAdd(t *testing.T) {
+	module := NewConfigModule(nil)
+	require.NotNil(t, module)
+
+	ctx, err := module.BeginSharedNetworkAdd(context.Background())
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, state.Updates, 1)
+	require.Equal(t, datamodel.AppTypeKea, state.Updates[0].Target)
+	require.Equal(t, "shared_network_add", state.Updates[0].Operation)
+}
+
+// Test second stage of adding a new shared network.
+func TestApplySharedNetworkAdd(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "shared_network_add")
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	sharedNetwork := createTestSharedNetworkWithTwoDaemons()
+	sharedNetwork.ID = 0
+	ctx, err := module.ApplySharedNetworkAdd(ctx, sharedNetwork)
+	require.NoError(t, err)
+
+	returnedState, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, returnedState.Updates, 1)
+	update := returnedState.Updates[0]
+	require.Equal(t, "shared_network_add", update.Operation)
+	require.Equal(t, sharedNetwork, update.Recipe.SharedNetworkAfterUpdate)
+
+	// The first daemon should receive network4-add, network4-subnet-del,
+	// network4-subnet-add. The second daemon should only receive the
+	// network4-add because the subnet is not configured there. Both
+	// daemons should receive config-write.
+	commands := update.Recipe.Commands
+	require.Len(t, commands, 6)
+
+	require.JSONEq(t,
+		`{
+             "command": "network4-add",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "shared-networks": [
+                     {
+                         "name": "foo",
+                         "interface": "eth0"
+                     }
+                 ]
+             }
+         }`,
+		commands[0].Command.Marshal())
+	require.Equal(t, sharedNetwork.LocalSharedNetworks[0].Daemon.App, commands[0].App)
+
+	require.JSONEq(t,
+		`{
+             "command": "network4-subnet-del",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "name": "bar",
+                 "id": 123
+             }
+         }`,
+		commands[1].Command.Marshal())
+	require.Equal(t, sharedNetwork.LocalSharedNetworks[0].Daemon.App, commands[1].App)
+
+	require.JSONEq(t,
+		`{
+             "command": "network4-subnet-add",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "name": "foo",
+                 "id": 123
+             }
+         }`,
+		commands[2].Command.Marshal())
+	require.Equal(t, sharedNetwork.LocalSharedNetworks[0].Daemon.App, commands[2].App)
+
+	require.JSONEq(t,
+		`{
+             "command": "network4-add",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "shared-networks": [
+                     {
+                         "name": "foo"
+                     }
+                 ]
+             }
+         }`,
+		commands[3].Command.Marshal())
+	require.Equal(t, sharedNetwork.LocalSharedNetworks[1].Daemon.App, commands[3].App)
+
+	for i := 0; i < 2; i++ {
+		require.Equal(t, "config-write", commands[i+4].Command.GetCommand())
+		require.Equal(t, sharedNetwork.LocalSharedNetworks[i].Daemon.App, commands[i+4].App)
+	}
+}
+
+// Test that applying a shared network fails when it is not associated
+// with any daemon.
+func TestApplySharedNetworkAddNoDaemons(t *testing.T) {
+	module := NewConfigModule(nil)
+	require.NotNil(t, module)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "shared_network_add")
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	sharedNetwork := createTestSharedNetworkWithTwoDaemons()
+	sharedNetwork.LocalSharedNetworks = nil
+	_, err := module.ApplySharedNetworkAdd(ctx, sharedNetwork)
+	require.ErrorContains(t, err, "applied shared network foo is not associated with any daemon")
+}
+
+// Test committing added shared network, i.e. actually sending control commands
+// to Kea and adding the shared network to the database.
+func TestCommitSharedNetworkAdd(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.2.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+	subnet, err := dbmodel.GetSubnet(db, subnets[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, subnet)
+
+	sharedNetwork := &dbmodel.SharedNetwork{
+		Name:   "foo",
+		Family: 4,
+		LocalSharedNetworks: []*dbmodel.LocalSharedNetwork{
+			{
+				DaemonID: apps[0].Daemons[0].ID,
+			},
+			{
+				DaemonID: apps[1].Daemons[0].ID,
+			},
+		},
+		Subnets: []dbmodel.Subnet{*subnet},
+	}
+	err = sharedNetwork.PopulateDaemons(db)
+	require.NoError(t, err)
+
+	ctx, err := module.BeginSharedNetworkAdd(context.Background())
+	require.NoError(t, err)
+
+	ctx, err = module.ApplySharedNetworkAdd(ctx, sharedNetwork)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	// Each daemon should receive network4-add, network4-subnet-add and
+	// config-write.
+	require.Len(t, agents.RecordedCommands, 6)
+	require.Equal(t, "network4-add", agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, "network4-subnet-add", agents.RecordedCommands[1].GetCommand())
+	require.Equal(t, "network4-add", agents.RecordedCommands[2].GetCommand())
+	require.Equal(t, "network4-subnet-add", agents.RecordedCommands[3].GetCommand())
+	require.Equal(t, "config-write", agents.RecordedCommands[4].GetCommand())
+	require.Equal(t, "config-write", agents.RecordedCommands[5].GetCommand())
+
+	// Make sure that the shared network has been added to the database
+	// and the subnet has been associated with it.
+	require.NotZero(t, sharedNetwork.ID)
+	returnedSharedNetwork, err := dbmodel.GetSharedNetwork(db, sharedNetwork.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returnedSharedNetwork)
+	require.Equal(t, "foo", returnedSharedNetwork.Name)
+	require.Len(t, returnedSharedNetwork.LocalSharedNetworks, 2)
+	require.Len(t, returnedSharedNetwork.Subnets, 1)
+	require.Equal(t, subnet.ID, returnedSharedNetwork.Subnets[0].ID)
+}
+
+// Test first stage of updating a shared network.
+func TestBeginSharedNetworkUpdate(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+	sharedNetwork := addTestSharedNetwork(t, db, apps)
+
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB: db,
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	ctx, err := module.BeginSharedNetworkUpdate(context.Background(), sharedNetwork.ID)
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, state.Updates, 1)
+	update := state.Updates[0]
+	require.Equal(t, "shared_network_update", update.Operation)
+	require.Len(t, update.DaemonIDs, 2)
+	require.NotNil(t, update.Recipe.SharedNetworkBeforeUpdate)
+	require.Equal(t, "foo", update.Recipe.SharedNetworkBeforeUpdate.Name)
+
+	// Make sure the daemons have been locked.
+	require.Len(t, manager.locks, 2)
+	require.Contains(t, manager.locks, apps[0].Daemons[0].ID)
+	require.Contains(t, manager.locks, apps[1].Daemons[0].ID)
+}
+
+// Test that an error is returned when beginning the update of a
+// non-existing shared network.
+func TestBeginSharedNetworkUpdateNonExisting(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB: db,
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	_, err := module.BeginSharedNetworkUpdate(context.Background(), 1024)
+	var notFoundErr *config.SharedNetworkNotFoundError
+	require.ErrorAs(t, err, &notFoundErr)
+}
+
+// Test second stage of updating a shared network.
+func TestApplySharedNetworkUpdate(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	// The existing shared network is configured in the first daemon and
+	// in the third daemon which is removed during the update.
+	existingSharedNetwork := createTestSharedNetworkWithTwoDaemons()
+	existingSharedNetwork.Subnets = nil
+	existingSharedNetwork.LocalSharedNetworks[1] = &dbmodel.LocalSharedNetwork{
+		DaemonID: 3,
+		Daemon: &dbmodel.Daemon{
+			Name: "dhcp4",
+			App: &dbmodel.App{
+				AccessPoints: []*dbmodel.AccessPoint{
+					{
+						Type:    dbmodel.AccessPointControl,
+						Address: "192.0.2.3",
+						Port:    3456,
+					},
+				},
+			},
+		},
+	}
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "shared_network_update", 1, 3)
+	err := state.SetRecipeForUpdate(0, &ConfigRecipe{
+		SharedNetworkConfigRecipeParams: SharedNetworkConfigRecipeParams{
+			SharedNetworkBeforeUpdate: existingSharedNetwork,
+		},
+	})
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	// Rename the shared network and move a subnet to it.
+	sharedNetwork := createTestSharedNetworkWithTwoDaemons()
+	sharedNetwork.Name = "baz"
+	ctx, err = module.ApplySharedNetworkUpdate(ctx, sharedNetwork)
+	require.NoError(t, err)
+
+	returnedState, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, returnedState.Updates, 1)
+	update := returnedState.Updates[0]
+	require.Equal(t, sharedNetwork, update.Recipe.SharedNetworkAfterUpdate)
+
+	commands := update.Recipe.Commands
+	require.Len(t, commands, 9)
+
+	// The shared network is deleted from the third daemon.
+	require.JSONEq(t,
+		`{
+             "command": "network4-del",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "name": "foo",
+                 "subnets-action": "keep"
+             }
+         }`,
+		commands[0].Command.Marshal())
+	require.Equal(t, existingSharedNetwork.LocalSharedNetworks[1].Daemon.App, commands[0].App)
+
+	// The shared network is re-created in the first daemon.
+	require.JSONEq(t,
+		`{
+             "command": "network4-del",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "name": "foo",
+                 "subnets-action": "keep"
+             }
+         }`,
+		commands[1].Command.Marshal())
+	require.Equal(t, sharedNetwork.LocalSharedNetworks[0].Daemon.App, commands[1].App)
+
+	require.JSONEq(t,
+		`{
+             "command": "network4-add",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "shared-networks": [
+                     {
+                         "name": "baz",
+                         "interface": "eth0"
+                     }
+                 ]
+             }
+         }`,
+		commands[2].Command.Marshal())
+	require.Equal(t, sharedNetwork.LocalSharedNetworks[0].Daemon.App, commands[2].App)
+
+	require.JSONEq(t,
+		`{
+             "command": "network4-subnet-del",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "name": "bar",
+                 "id": 123
+             }
+         }`,
+		commands[3].Command.Marshal())
+
+	require.JSONEq(t,
+		`{
+             "command": "network4-subnet-add",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "name": "baz",
+                 "id": 123
+             }
+         }`,
+		commands[4].Command.Marshal())
+
+	// The shared network is added to the second daemon.
+	require.JSONEq(t,
+		`{
+             "command": "network4-add",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "shared-networks": [
+                     {
+                         "name": "baz"
+                     }
+                 ]
+             }
+         }`,
+		commands[5].Command.Marshal())
+	require.Equal(t, sharedNetwork.LocalSharedNetworks[1].Daemon.App, commands[5].App)
+
+	// The configuration is written in all affected daemons.
+	require.Equal(t, "config-write", commands[6].Command.GetCommand())
+	require.Equal(t, existingSharedNetwork.LocalSharedNetworks[1].Daemon.App, commands[6].App)
+	require.Equal(t, "config-write", commands[7].Command.GetCommand())
+	require.Equal(t, sharedNetwork.LocalSharedNetworks[0].Daemon.App, commands[7].App)
+	require.Equal(t, "config-write", commands[8].Command.GetCommand())
+	require.Equal(t, sharedNetwork.LocalSharedNetworks[1].Daemon.App, commands[8].App)
+}
+
+// Test committing updated shared network, i.e. actually sending control
+// commands to Kea and updating the shared network in the database.
+func TestCommitSharedNetworkUpdate(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+	existingSharedNetwork := addTestSharedNetwork(t, db, apps)
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	ctx, err := module.BeginSharedNetworkUpdate(context.Background(), existingSharedNetwork.ID)
+	require.NoError(t, err)
+
+	// Move the subnet to the shared network.
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.2.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+	subnet, err := dbmodel.GetSubnet(db, subnets[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, subnet)
+
+	sharedNetwork, err := dbmodel.GetSharedNetwork(db, existingSharedNetwork.ID)
+	require.NoError(t, err)
+	require.NotNil(t, sharedNetwork)
+	sharedNetwork.Name = "bar"
+	sharedNetwork.Subnets = []dbmodel.Subnet{*subnet}
+
+	ctx, err = module.ApplySharedNetworkUpdate(ctx, sharedNetwork)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	// Each daemon should receive network4-del, network4-add,
+	// network4-subnet-add and config-write.
+	require.Len(t, agents.RecordedCommands, 8)
+	for i := 0; i < 2; i++ {
+		require.Equal(t, "network4-del", agents.RecordedCommands[3*i].GetCommand())
+		require.Equal(t, "network4-add", agents.RecordedCommands[3*i+1].GetCommand())
+		require.Equal(t, "network4-subnet-add", agents.RecordedCommands[3*i+2].GetCommand())
+		require.Equal(t, "config-write", agents.RecordedCommands[6+i].GetCommand())
+	}
+
+	// Make sure that the shared network has been updated in the database.
+	updatedSharedNetwork, err := dbmodel.GetSharedNetwork(db, sharedNetwork.ID)
+	require.NoError(t, err)
+	require.NotNil(t, updatedSharedNetwork)
+	require.Equal(t, "bar", updatedSharedNetwork.Name)
+	require.Len(t, updatedSharedNetwork.LocalSharedNetworks, 2)
+	require.Len(t, updatedSharedNetwork.Subnets, 1)
+	require.Equal(t, subnet.ID, updatedSharedNetwork.Subnets[0].ID)
+}
+
+// Test first stage of deleting a shared network.
+func TestBeginSharedNetworkDelete(t *testing.T) {
+	module := NewConfigModule(nil)
+	require.NotNil(t, module)
+
+	ctx1 := context.Background()
+	ctx2, err := module.BeginSharedNetworkDelete(ctx1)
+	require.NoError(t, err)
+	require.Equal(t, ctx1, ctx2)
+}
+
+// Test second stage of deleting a shared network.
+func TestApplySharedNetworkDelete(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	sharedNetwork := createTestSharedNetworkWithTwoDaemons()
+	ctx, err := module.ApplySharedNetworkDelete(context.Background(), sharedNetwork)
+	require.NoError(t, err)
+
+	// Make sure the daemons have been locked.
+	require.Len(t, manager.locks, 2)
+	require.Contains(t, manager.locks, int64(1))
+	require.Contains(t, manager.locks, int64(2))
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, state.Updates, 1)
+	update := state.Updates[0]
+	require.Equal(t, datamodel.AppTypeKea, update.Target)
+	require.Equal(t, "shared_network_delete", update.Operation)
+	require.ElementsMatch(t, []int64{1, 2}, update.DaemonIDs)
+	require.NotNil(t, update.Recipe.SharedNetworkID)
+	require.EqualValues(t, 1, *update.Recipe.SharedNetworkID)
+
+	commands := update.Recipe.Commands
+	require.Len(t, commands, 4)
+	for i := 0; i < 2; i++ {
+		require.JSONEq(t,
+			`{
+                 "command": "network4-del",
+                 "service": [ "dhcp4" ],
+                 "arguments": {
+                     "name": "foo",
+                     "subnets-action": "keep"
+                 }
+             }`,
+			commands[i].Command.Marshal())
+		require.Equal(t, sharedNetwork.LocalSharedNetworks[i].Daemon.App, commands[i].App)
+		require.Equal(t, "config-write", commands[i+2].Command.GetCommand())
+		require.Equal(t, sharedNetwork.LocalSharedNetworks[i].Daemon.App, commands[i+2].App)
+	}
+}
+
+// Test committing deleted shared network, i.e. actually sending control
+// commands to Kea and deleting the shared network from the database.
+func TestCommitSharedNetworkDelete(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+	existingSharedNetwork := addTestSharedNetwork(t, db, apps)
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	sharedNetwork, err := dbmodel.GetSharedNetwork(db, existingSharedNetwork.ID)
+	require.NoError(t, err)
+	require.NotNil(t, sharedNetwork)
+
+	ctx, err := module.ApplySharedNetworkDelete(context.Background(), sharedNetwork)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, agents.RecordedCommands, 4)
+	require.Equal(t, "network4-del", agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, "network4-del", agents.RecordedCommands[1].GetCommand())
+	require.Equal(t, "config-write", agents.RecordedCommands[2].GetCommand())
+	require.Equal(t, "config-write", agents.RecordedCommands[3].GetCommand())
+
+	returnedSharedNetwork, err := dbmodel.GetSharedNetwork(db, sharedNetwork.ID)
+	require.NoError(t, err)
+	require.Nil(t, returnedSharedNetwork)
+}
+
+// Creates a test DHCPv4 daemon with a configuration for testing the
+// global parameters update.
+func createTestDaemonForGlobalParametersUpdate(t *testing.T, id int64) *dbmodel.Daemon {
+	daemon := dbmodel.NewKeaDaemon(dbmodel.DaemonNameDHCPv4, true)
+	daemon.ID = id
+	daemon.App = &dbmodel.App{
+		ID:   id,
+		Type: dbmodel.AppTypeKea,
+	}
+	err := daemon.SetConfigFromJSON(`{
+        "Dhcp4": {
+            "valid-lifetime": 3600,
+            "renew-timer": 900,
+            "subnet4": [
+                {
+                    "id": 1,
+                    "subnet": "192.0.2.0/24"
+                }
+            ]
+        }
+    }`)
+	require.NoError(t, err)
+	return daemon
+}
+
+// Creates a context with the transaction state for the global parameters
+// update of the specified daemons.
+func createTestGlobalParametersUpdateContext(t *testing.T, daemons ...*dbmodel.Daemon) context.Context {
+	var daemonIDs []int64
+	for _, daemon := range daemons {
+		daemonIDs = append(daemonIDs, daemon.ID)
+	}
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "global_parameters_update", daemonIDs...)
+	recipe := &ConfigRecipe{
+		GlobalParametersConfigRecipeParams: GlobalParametersConfigRecipeParams{
+			DaemonsBeforeUpdate: daemons,
+		},
+	}
+	err := state.SetRecipeForUpdate(0, recipe)
+	require.NoError(t, err)
+	return context.WithValue(context.Background(), config.StateContextKey, *state)
+}
+
+// Test first stage of updating global parameters.
+func TestBeginGlobalParametersUpdate(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB: db,
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	daemonIDs := []int64{apps[0].Daemons[0].ID, apps[0].Daemons[1].ID}
+	ctx, err := module.BeginGlobalParametersUpdate(context.Background(), daemonIDs)
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, state.Updates, 1)
+	update := state.Updates[0]
+	require.Equal(t, "global_parameters_update", update.Operation)
+	require.ElementsMatch(t, daemonIDs, update.DaemonIDs)
+	require.Len(t, update.Recipe.DaemonsBeforeUpdate, 2)
+	require.NotNil(t, update.Recipe.DaemonsBeforeUpdate[0].KeaDaemon.Config)
+	require.NotNil(t, update.Recipe.DaemonsBeforeUpdate[0].App)
+
+	// Make sure the daemons have been locked.
+	require.Len(t, manager.locks, 2)
+	require.Contains(t, manager.locks, apps[0].Daemons[0].ID)
+	require.Contains(t, manager.locks, apps[0].Daemons[1].ID)
+}
+
+// Test that an error is returned when beginning the global parameters
+// update for a non-existing daemon.
+func TestBeginGlobalParametersUpdateNonExisting(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB: db,
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	_, err := module.BeginGlobalParametersUpdate(context.Background(), []int64{1024})
+	var notFoundErr *config.DaemonNotFoundError
+	require.ErrorAs(t, err, &notFoundErr)
+	require.Empty(t, manager.locks)
+}
+
+// Test second stage of updating global parameters.
+func TestApplyGlobalParametersUpdate(t *testing.T) {
+	module := NewConfigModule(nil)
+	require.NotNil(t, module)
+
+	daemons := []*dbmodel.Daemon{
+		createTestDaemonForGlobalParametersUpdate(t, 1),
+		createTestDaemonForGlobalParametersUpdate(t, 2),
+	}
+	ctx := createTestGlobalParametersUpdateContext(t, daemons...)
+
+	params := map[int64]*keaconfig.SettableGlobalParameters{}
+	for _, daemon := range daemons {
+		params[daemon.ID] = &keaconfig.SettableGlobalParameters{
+			ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+				ValidLifetime: storkutil.Ptr(int64(7200)),
+			},
+			ClientClasses: []map[string]any{
+				{
+					"name": "foo",
+				},
+			},
+		}
+	}
+	ctx, err := module.ApplyGlobalParametersUpdate(ctx, params)
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, state.Updates, 1)
+	recipe := state.Updates[0].Recipe
+	require.Len(t, recipe.ConfigsAfterUpdate, 2)
+
+	// The original configurations should remain unchanged.
+	for _, daemon := range daemons {
+		require.EqualValues(t, 3600, *daemon.KeaDaemon.Config.GetValidLifetimeParameters().ValidLifetime)
+		require.Contains(t, recipe.ConfigsAfterUpdate, daemon.ID)
+		updatedConfig := recipe.ConfigsAfterUpdate[daemon.ID]
+		require.EqualValues(t, 7200, *updatedConfig.GetValidLifetimeParameters().ValidLifetime)
+		require.Nil(t, updatedConfig.GetTimerParameters().RenewTimer)
+		require.Len(t, updatedConfig.GetSubnets(), 1)
+		require.Len(t, updatedConfig.GetClientClasses(), 1)
+	}
+
+	// The configurations should be first tested on all daemons, then
+	// set and finally written to disk.
+	commands := recipe.Commands
+	require.Len(t, commands, 6)
+	for i, name := range []string{"config-test", "config-set", "config-write"} {
+		for j, daemon := range daemons {
+			command := commands[2*i+j]
+			require.Equal(t, name, command.Command.GetCommand())
+			require.Equal(t, daemon.App, command.App)
+		}
+	}
+	require.JSONEq(t,
+		`{
+             "command": "config-set",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "Dhcp4": {
+                     "valid-lifetime": 7200,
+                     "client-classes": [
+                         {
+                             "name": "foo"
+                         }
+                     ],
+                     "subnet4": [
+                         {
+                             "id": 1,
+                             "subnet": "192.0.2.0/24"
+                         }
+                     ]
+                 }
+             }
+         }`,
+		commands[2].Command.Marshal())
+}
+
+// Test that the daemons for which no parameters have been specified are
+// not updated.
+func TestApplyGlobalParametersUpdatePartial(t *testing.T) {
+	module := NewConfigModule(nil)
+	require.NotNil(t, module)
+
+	daemons := []*dbmodel.Daemon{
+		createTestDaemonForGlobalParametersUpdate(t, 1),
+		createTestDaemonForGlobalParametersUpdate(t, 2),
+	}
+	ctx := createTestGlobalParametersUpdateContext(t, daemons...)
+
+	params := map[int64]*keaconfig.SettableGlobalParameters{
+		2: {},
+	}
+	ctx, err := module.ApplyGlobalParametersUpdate(ctx, params)
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	recipe := state.Updates[0].Recipe
+	require.Len(t, recipe.ConfigsAfterUpdate, 1)
+	require.Contains(t, recipe.ConfigsAfterUpdate, int64(2))
+	require.Len(t, recipe.Commands, 3)
+	for _, command := range recipe.Commands {
+		require.Equal(t, daemons[1].App, command.App)
+	}
+}
+
+// Test that applying the global parameters fails for a daemon not
+// belonging to the transaction or when the parameters are invalid.
+func TestApplyGlobalParametersUpdateErrors(t *testing.T) {
+	module := NewConfigModule(nil)
+	require.NotNil(t, module)
+
+	daemon := createTestDaemonForGlobalParametersUpdate(t, 1)
+
+	t.Run("unknown daemon", func(t *testing.T) {
+		ctx := createTestGlobalParametersUpdateContext(t, daemon)
+		_, err := module.ApplyGlobalParametersUpdate(ctx, map[int64]*keaconfig.SettableGlobalParameters{
+			2: {},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("no parameters", func(t *testing.T) {
+		ctx := createTestGlobalParametersUpdateContext(t, daemon)
+		_, err := module.ApplyGlobalParametersUpdate(ctx, map[int64]*keaconfig.SettableGlobalParameters{})
+		require.Error(t, err)
+	})
+
+	t.Run("preferred lifetime for DHCPv4", func(t *testing.T) {
+		ctx := createTestGlobalParametersUpdateContext(t, daemon)
+		_, err := module.ApplyGlobalParametersUpdate(ctx, map[int64]*keaconfig.SettableGlobalParameters{
+			1: {
+				PreferredLifetimeParameters: keaconfig.PreferredLifetimeParameters{
+					PreferredLifetime: storkutil.Ptr(int64(1000)),
+				},
+			},
+		})
+		require.Error(t, err)
+	})
+}
+
+// Test committing updated global parameters, i.e. actually sending control
+// commands to Kea and updating the daemon configurations in the database.
+func TestCommitGlobalParametersUpdate(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:     db,
+		Agents: agents,
+	})
+
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	daemonID := apps[0].Daemons[0].ID
+	ctx, err := module.BeginGlobalParametersUpdate(context.Background(), []int64{daemonID})
+	require.NoError(t, err)
+
+	ctx, err = module.ApplyGlobalParametersUpdate(ctx, map[int64]*keaconfig.SettableGlobalParameters{
+		daemonID: {
+			ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+				ValidLifetime: storkutil.Ptr(int64(1800)),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, agents.RecordedCommands, 3)
+	require.Equal(t, "config-test", agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, "config-set", agents.RecordedCommands[1].GetCommand())
+	require.Equal(t, "config-write", agents.RecordedCommands[2].GetCommand())
+
+	// Make sure that the configuration has been updated in the database
+	// and the hash has been reset.
+	daemon, err := dbmodel.GetDaemonByID(db, daemonID)
+	require.NoError(t, err)
+	require.NotNil(t, daemon)
+	require.Empty(t, daemon.KeaDaemon.ConfigHash)
+	require.EqualValues(t, 1800, *daemon.KeaDaemon.Config.GetValidLifetimeParameters().ValidLifetime)
+	require.Len(t, daemon.KeaDaemon.Config.GetSubnets(), 1)
+	require.Empty(t, daemon.KeaDaemon.Config.GetClientClasses())
+}
+
+// Creates a context with the transaction state for applying the specified
+// config report fix to the daemon configuration.
+func createTestConfigReportFixContext(t *testing.T, daemon *dbmodel.Daemon, fix *dbmodel.ConfigReportFix) context.Context {
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe]("kea", "config_report_fix", daemon.ID)
+	recipe := &ConfigRecipe{
+		GlobalParametersConfigRecipeParams: GlobalParametersConfigRecipeParams{
+			DaemonsBeforeUpdate: []*dbmodel.Daemon{daemon},
+		},
+		ConfigReportFixConfigRecipeParams: ConfigReportFixConfigRecipeParams{
+			ConfigReportID:  storkutil.Ptr(int64(1)),
+			ConfigReportFix: fix,
+		},
+	}
+	err := state.SetRecipeForUpdate(0, recipe)
+	require.NoError(t, err)
+	return context.WithValue(context.Background(), config.StateContextKey, *state)
+}
+
+// Test second stage of applying the config report fix.
+func TestApplyConfigReportFix(t *testing.T) {
+	module := NewConfigModule(nil)
+	require.NotNil(t, module)
+
+	daemon := createTestDaemonForGlobalParametersUpdate(t, 1)
+	ctx := createTestConfigReportFixContext(t, daemon, &dbmodel.ConfigReportFix{
+		Description: "Load the stat_cmds hook library.",
+		Patch: []storkutil.JSONPatchOperation{
+			{
+				Op:    "add",
+				Path:  "/Dhcp4/hooks-libraries",
+				Value: []any{map[string]any{"library": "libdhcp_stat_cmds.so"}},
+			},
+		},
+	})
+
+	ctx, err := module.ApplyConfigReportFix(ctx)
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	recipe := state.Updates[0].Recipe
+	require.Len(t, recipe.ConfigsAfterUpdate, 1)
+	require.Contains(t, recipe.ConfigsAfterUpdate, daemon.ID)
+
+	// The original configuration should remain unchanged.
+	require.Empty(t, daemon.KeaDaemon.Config.GetHookLibraries())
+	require.Len(t, recipe.ConfigsAfterUpdate[daemon.ID].GetHookLibraries(), 1)
+
+	commands := recipe.Commands
+	require.Len(t, commands, 3)
+	require.Equal(t, "config-test", commands[0].Command.GetCommand())
+	require.True(t, commands[0].Validation)
+	require.Equal(t, "config-set", commands[1].Command.GetCommand())
+	require.Len(t, commands[1].CompensatingCommands, 1)
+	require.Equal(t, "config-write", commands[2].Command.GetCommand())
+	require.JSONEq(t,
+		`{
+             "command": "config-set",
+             "service": [ "dhcp4" ],
+             "arguments": {
+                 "Dhcp4": {
+                     "valid-lifetime": 3600,
+                     "renew-timer": 900,
+                     "hooks-libraries": [
+                         {
+                             "library": "libdhcp_stat_cmds.so"
+                         }
+                     ],
+                     "subnet4": [
+                         {
+                             "id": 1,
+                             "subnet": "192.0.2.0/24"
+                         }
+                     ]
+                 }
+             }
+         }`,
+		commands[1].Command.Marshal())
+}
+
+// Test that applying the config report fix fails when the patch doesn't
+// match the daemon configuration.
+func TestApplyConfigReportFixMismatch(t *testing.T) {
+	module := NewConfigModule(nil)
+	require.NotNil(t, module)
+
+	daemon := createTestDaemonForGlobalParametersUpdate(t, 1)
+	ctx := createTestConfigReportFixContext(t, daemon, &dbmodel.ConfigReportFix{
+		Description: "Remove the shared network.",
+		Patch: []storkutil.JSONPatchOperation{
+			{Op: "remove", Path: "/Dhcp4/shared-networks/0"},
+		},
+	})
+
+	_, err := module.ApplyConfigReportFix(ctx)
+	require.ErrorContains(t, err, "problem applying fix to the configuration of daemon 1")
+}
+
+// Adds a config report with a fix for the specified daemon to the database.
+// The fix adds the reservations-out-of-pool parameter to the configuration.
+func addTestConfigReportWithFix(t *testing.T, db *pg.DB, daemon *dbmodel.Daemon, configHash string) *dbmodel.ConfigReport {
+	report := &dbmodel.ConfigReport{
+		CheckerName: "test",
+		Content:     storkutil.Ptr("test report for {daemon}"),
+		DaemonID:    daemon.ID,
+		RefDaemons:  []*dbmodel.Daemon{daemon},
+		Fix: &dbmodel.ConfigReportFix{
+			Description: "Set the reservations-out-of-pool parameter.",
+			ConfigHash:  configHash,
+			Patch: []storkutil.JSONPatchOperation{
+				{Op: "add", Path: "/Dhcp4/reservations-out-of-pool", Value: true},
+			},
+		},
+	}
+	err := dbmodel.AddConfigReport(db, report)
+	require.NoError(t, err)
+	return report
+}
+
+// Test beginning, applying and committing the config report fix.
+func TestCommitConfigReportFix(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+
+	daemon, err := dbmodel.GetDaemonByID(db, apps[0].Daemons[0].ID)
+	require.NoError(t, err)
+	daemon.KeaDaemon.ConfigHash = "1234"
+	err = dbmodel.UpdateDaemon(db, daemon)
+	require.NoError(t, err)
+
+	report := addTestConfigReportWithFix(t, db, daemon, "1234")
+
+	agents := agentcommtest.NewKeaFakeAgents()
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:     db,
+		Agents: agents,
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	ctx, err := module.BeginConfigReportFix(context.Background(), report.ID)
+	require.NoError(t, err)
+
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	require.True(t, ok)
+	require.Len(t, state.Updates, 1)
+	require.Equal(t, "config_report_fix", state.Updates[0].Operation)
+	require.Equal(t, []int64{daemon.ID}, state.Updates[0].DaemonIDs)
+	require.Len(t, manager.locks, 1)
+	require.Contains(t, manager.locks, daemon.ID)
+
+	ctx, err = module.ApplyConfigReportFix(ctx)
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, agents.RecordedCommands, 3)
+	require.Equal(t, "config-test", agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, "config-set", agents.RecordedCommands[1].GetCommand())
+	require.Equal(t, "config-write", agents.RecordedCommands[2].GetCommand())
+
+	// The configuration has been updated in the database and the hash
+	// has been reset.
+	daemon, err = dbmodel.GetDaemonByID(db, daemon.ID)
+	require.NoError(t, err)
+	require.Empty(t, daemon.KeaDaemon.ConfigHash)
+	require.Contains(t, daemon.KeaDaemon.Config.Raw["Dhcp4"], "reservations-out-of-pool")
+
+	// The fix can't be applied again because the configuration has changed.
+	_, err = module.BeginConfigReportFix(context.Background(), report.ID)
+	var outdatedErr *config.ConfigReportFixOutdatedError
+	require.ErrorAs(t, err, &outdatedErr)
+}
+
+// Test that beginning the config report fix fails when the report does not
+// exist or the daemon configuration has changed.
+func TestBeginConfigReportFixErrors(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB: db,
+	})
+	module := NewConfigModule(manager)
+	require.NotNil(t, module)
+
+	_, err := module.BeginConfigReportFix(context.Background(), 1024)
+	var notFoundErr *config.ConfigReportFixNotFoundError
+	require.ErrorAs(t, err, &notFoundErr)
+
+	report := addTestConfigReportWithFix(t, db, apps[0].Daemons[0], "1234")
+	_, err = module.BeginConfigReportFix(context.Background(), report.ID)
+	var outdatedErr *config.ConfigReportFixOutdatedError
+	require.ErrorAs(t, err, &outdatedErr)
+	require.Empty(t, manager.locks)
+}
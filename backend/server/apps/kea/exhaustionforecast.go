@@ -0,0 +1,260 @@
+package kea
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	log "github.com/sirupsen/logrus"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/eventcenter"
+)
+
+const (
+	// Minimum number of the utilization samples required to forecast
+	// the exhaustion.
+	minForecastSamples = 3
+	// Minimum period covered by the utilization samples required to
+	// forecast the exhaustion.
+	minForecastSpan = time.Hour
+	// The exhaustion forecasted later than this number of days is not
+	// reported. It avoids reporting meaningless forecasts when the
+	// utilization grows very slowly.
+	maxForecastDays = 3650
+)
+
+// A utilization sample used to forecast the exhaustion.
+type forecastPoint struct {
+	at              time.Time
+	addrUtilization float64
+	pdUtilization   float64
+}
+
+// Utilization series of a subnet or a shared network.
+type forecastSeries []forecastPoint
+
+// Fits a line to the utilizations using the least squares method and
+// returns the number of days from now until the fitted line reaches 100%
+// utilization. It returns nil when the exhaustion cannot be forecasted,
+// i.e., there are too few samples, the samples cover too short period,
+// the utilization doesn't grow or the exhaustion is too distant.
+func (series forecastSeries) forecast(now time.Time, utilization func(forecastPoint) float64) *float64 {
+	if len(series) < minForecastSamples || series[len(series)-1].at.Sub(series[0].at) < minForecastSpan {
+		return nil
+	}
+	// The x values are the days relative to now, so the intercept is the
+	// utilization fitted for now.
+	var sumX, sumY float64
+	for _, point := range series {
+		sumX += point.at.Sub(now).Hours() / 24
+		sumY += utilization(point)
+	}
+	n := float64(len(series))
+	meanX, meanY := sumX/n, sumY/n
+	var sxx, sxy float64
+	for _, point := range series {
+		dx := point.at.Sub(now).Hours()/24 - meanX
+		sxx += dx * dx
+		sxy += dx * (utilization(point) - meanY)
+	}
+	if sxx == 0 {
+		return nil
+	}
+	slope := sxy / sxx
+	if slope <= 0 {
+		return nil
+	}
+	days := (1 - (meanY - slope*meanX)) / slope
+	switch {
+	case days > maxForecastDays:
+		return nil
+	case days < 0:
+		days = 0
+	}
+	return &days
+}
+
+// Returns the forecasted number of days until the addresses are
+// exhausted.
+func (series forecastSeries) forecastAddresses(now time.Time) *float64 {
+	return series.forecast(now, func(point forecastPoint) float64 {
+		return point.addrUtilization
+	})
+}
+
+// Returns the forecasted number of days until the delegated prefixes are
+// exhausted.
+func (series forecastSeries) forecastPrefixes(now time.Time) *float64 {
+	return series.forecast(now, func(point forecastPoint) float64 {
+		return point.pdUtilization
+	})
+}
+
+// Identifies the subnet, shared network or pool the series belongs to.
+type forecastSeriesKey struct {
+	subnetID        int64
+	sharedNetworkID int64
+	addressPoolID   int64
+	prefixPoolID    int64
+}
+
+// Groups the utilization samples by the subnets, shared networks and pools. The
+// hourly samples are placed in the middle of the hours they summarize.
+// The samples must be ordered by time, and the hourly samples must
+// precede the raw samples.
+func groupForecastSeries(hourly, raw []dbmodel.UtilizationSample) map[forecastSeriesKey]forecastSeries {
+	series := make(map[forecastSeriesKey]forecastSeries)
+	add := func(sample dbmodel.UtilizationSample, at time.Time) {
+		key := forecastSeriesKey{sample.SubnetID, sample.SharedNetworkID, sample.AddressPoolID, sample.PrefixPoolID}
+		series[key] = append(series[key], forecastPoint{
+			at:              at,
+			addrUtilization: sample.AddrUtilization,
+			pdUtilization:   sample.PdUtilization,
+		})
+	}
+	for _, sample := range hourly {
+		add(sample, sample.SampledAt.Add(30*time.Minute))
+	}
+	for _, sample := range raw {
+		add(sample, sample.SampledAt)
+	}
+	return series
+}
+
+// Checks if the forecasted exhaustion dropped below the horizon.
+func isExhaustionWithinHorizon(previous, current *float64, horizon float64) bool {
+	if horizon <= 0 || current == nil || *current >= horizon {
+		return false
+	}
+	return previous == nil || *previous >= horizon
+}
+
+// Compares the forecasts.
+func isSameForecast(previous, current *float64) bool {
+	if previous == nil || current == nil {
+		return previous == nil && current == nil
+	}
+	return *previous == *current
+}
+
+// Forecasts the exhaustion of the addresses and delegated prefixes in the
+// subnet or shared network and raises the warnings when the forecasted
+// exhaustion drops below the horizon. The name is used in the event text
+// to refer to the subnet or the shared network. The objects are passed
+// to the event center. It returns the new forecasts and a boolean flag
+// indicating if they changed.
+func evaluateExhaustionForecast(eventCenter eventcenter.EventCenter, series forecastSeries, now time.Time, horizon float64, name string, objects []any, addrDays, pdDays *float64) (*float64, *float64, bool) {
+	newAddrDays := series.forecastAddresses(now)
+	if isExhaustionWithinHorizon(addrDays, newAddrDays, horizon) {
+		eventCenter.AddWarningEvent(fmt.Sprintf("Addresses in %s are forecasted to be exhausted in %.1f days", name, *newAddrDays), objects...)
+	}
+	newPdDays := series.forecastPrefixes(now)
+	if isExhaustionWithinHorizon(pdDays, newPdDays, horizon) {
+		eventCenter.AddWarningEvent(fmt.Sprintf("Delegated prefixes in %s are forecasted to be exhausted in %.1f days", name, *newPdDays), objects...)
+	}
+	changed := !isSameForecast(addrDays, newAddrDays) || !isSameForecast(pdDays, newPdDays)
+	return newAddrDays, newPdDays, changed
+}
+
+// Returns the objects the pool exhaustion events are related to, i.e.,
+// the subnet and the daemon configuring the pool.
+func getPoolEventObjects(localSubnet *dbmodel.LocalSubnet) (objects []any) {
+	if localSubnet.Subnet != nil {
+		objects = append(objects, localSubnet.Subnet)
+	}
+	if localSubnet.Daemon != nil {
+		objects = append(objects, localSubnet.Daemon)
+	}
+	return objects
+}
+
+// Forecasts the exhaustion of the addresses and delegated prefixes in the
+// subnets, pools and all shared networks using the utilization history from
+// the configured window. The forecasts are stored in the database. It
+// returns the last encountered error.
+func evaluateExhaustionForecasts(db *pg.DB, eventCenter eventcenter.EventCenter, subnets []*dbmodel.Subnet, addressPools []*dbmodel.AddressPool, prefixPools []*dbmodel.PrefixPool, now time.Time) error {
+	window := dbmodel.GetUtilizationForecastWindow(db)
+	horizon := dbmodel.GetUtilizationForecastHorizon(db)
+
+	// The raw samples taken in the current hour are not rolled up yet.
+	hourly, err := dbmodel.GetUtilizationSamplesSince(db, dbmodel.UtilizationResolutionHourly, now.Add(-window))
+	if err != nil {
+		return err
+	}
+	raw, err := dbmodel.GetUtilizationSamplesSince(db, dbmodel.UtilizationResolutionRaw, now.Truncate(time.Hour))
+	if err != nil {
+		return err
+	}
+	series := groupForecastSeries(hourly, raw)
+
+	var lastErr error
+	for _, subnet := range subnets {
+		addrDays, pdDays, changed := evaluateExhaustionForecast(eventCenter, series[forecastSeriesKey{subnetID: subnet.ID}],
+			now, horizon, "subnet {subnet}", []any{subnet}, subnet.AddrExhaustionDays, subnet.PdExhaustionDays)
+		if !changed {
+			continue
+		}
+		subnet.AddrExhaustionDays, subnet.PdExhaustionDays = addrDays, pdDays
+		err = dbmodel.SetSubnetExhaustionForecast(db, subnet.ID, addrDays, pdDays)
+		if err != nil {
+			lastErr = err
+			log.Errorf("Cannot update exhaustion forecast in subnet %d: %s", subnet.ID, err)
+		}
+	}
+
+	// The pools are related to their subnets and daemons in the events.
+	// The pool forecasts are evaluated separately for each daemon because
+	// the daemons may configure different pools in the same subnet.
+	for _, pool := range addressPools {
+		if pool.LocalSubnet == nil {
+			continue
+		}
+		name := fmt.Sprintf("pool %s-%s in subnet {subnet} of {daemon}", pool.LowerBound, pool.UpperBound)
+		days, _, changed := evaluateExhaustionForecast(eventCenter, series[forecastSeriesKey{addressPoolID: pool.ID}],
+			now, horizon, name, getPoolEventObjects(pool.LocalSubnet), pool.ExhaustionDays, nil)
+		if !changed {
+			continue
+		}
+		pool.ExhaustionDays = days
+		err = dbmodel.SetAddressPoolExhaustionForecast(db, pool.ID, days)
+		if err != nil {
+			lastErr = err
+			log.Errorf("Cannot update exhaustion forecast in address pool %d: %s", pool.ID, err)
+		}
+	}
+	for _, pool := range prefixPools {
+		if pool.LocalSubnet == nil {
+			continue
+		}
+		name := fmt.Sprintf("pool %s (delegated length %d) in subnet {subnet} of {daemon}", pool.Prefix, pool.DelegatedLen)
+		_, days, changed := evaluateExhaustionForecast(eventCenter, series[forecastSeriesKey{prefixPoolID: pool.ID}],
+			now, horizon, name, getPoolEventObjects(pool.LocalSubnet), nil, pool.ExhaustionDays)
+		if !changed {
+			continue
+		}
+		pool.ExhaustionDays = days
+		err = dbmodel.SetPrefixPoolExhaustionForecast(db, pool.ID, days)
+		if err != nil {
+			lastErr = err
+			log.Errorf("Cannot update exhaustion forecast in prefix pool %d: %s", pool.ID, err)
+		}
+	}
+
+	networks, err := dbmodel.GetSharedNetworksWithUtilization(db)
+	if err != nil {
+		return err
+	}
+	for _, network := range networks {
+		addrDays, pdDays, changed := evaluateExhaustionForecast(eventCenter, series[forecastSeriesKey{sharedNetworkID: network.ID}],
+			now, horizon, "shared network {sharedNetwork}", []any{network}, network.AddrExhaustionDays, network.PdExhaustionDays)
+		if !changed {
+			continue
+		}
+		err = dbmodel.SetSharedNetworkExhaustionForecast(db, network.ID, addrDays, pdDays)
+		if err != nil {
+			lastErr = err
+			log.Errorf("Cannot update exhaustion forecast in shared network %d: %s", network.ID, err)
+		}
+	}
+	return lastErr
+}
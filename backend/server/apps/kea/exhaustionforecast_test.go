@@ -0,0 +1,263 @@
+package kea
+
+import (
+	"testing"
+	"time"
+
+	require "github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
+)
+
+// Creates the series with the utilization growing linearly by the given
+// fraction a day. The last sample is taken at the specified time.
+func createLinearForecastSeries(now time.Time, count int, interval time.Duration, utilization, growth float64) forecastSeries {
+	series := forecastSeries{}
+	for i := count - 1; i >= 0; i-- {
+		at := now.Add(-time.Duration(i) * interval)
+		u := utilization - growth*now.Sub(at).Hours()/24
+		series = append(series, forecastPoint{
+			at:              at,
+			addrUtilization: u,
+			pdUtilization:   u / 2,
+		})
+	}
+	return series
+}
+
+// Test forecasting the exhaustion for the linearly growing utilization.
+func TestForecastLinearGrowth(t *testing.T) {
+	now := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	// The utilization is 50% and grows by 5% a day.
+	series := createLinearForecastSeries(now, 24, time.Hour, 0.5, 0.05)
+	days := series.forecastAddresses(now)
+	require.NotNil(t, days)
+	require.InDelta(t, 10, *days, 0.001)
+
+	// The prefix utilization is 25% and grows by 2.5% a day.
+	days = series.forecastPrefixes(now)
+	require.NotNil(t, days)
+	require.InDelta(t, 30, *days, 0.001)
+}
+
+// Test that the exhaustion is not forecasted when there are too few
+// samples or they cover too short period.
+func TestForecastInsufficientSamples(t *testing.T) {
+	now := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	require.Nil(t, forecastSeries{}.forecastAddresses(now))
+
+	series := createLinearForecastSeries(now, 2, time.Hour, 0.5, 0.05)
+	require.Nil(t, series.forecastAddresses(now))
+
+	series = createLinearForecastSeries(now, 10, time.Minute, 0.5, 0.05)
+	require.Nil(t, series.forecastAddresses(now))
+}
+
+// Test that the exhaustion is not forecasted when the utilization doesn't
+// grow or grows very slowly.
+func TestForecastNoGrowth(t *testing.T) {
+	now := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	series := createLinearForecastSeries(now, 24, time.Hour, 0.5, 0)
+	require.Nil(t, series.forecastAddresses(now))
+
+	series = createLinearForecastSeries(now, 24, time.Hour, 0.5, -0.01)
+	require.Nil(t, series.forecastAddresses(now))
+
+	series = createLinearForecastSeries(now, 24, time.Hour, 0.5, 0.00001)
+	require.Nil(t, series.forecastAddresses(now))
+}
+
+// Test that the already exhausted addresses are forecasted to be
+// exhausted in zero days.
+func TestForecastExhausted(t *testing.T) {
+	now := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	series := createLinearForecastSeries(now, 24, time.Hour, 1.2, 0.1)
+	days := series.forecastAddresses(now)
+	require.NotNil(t, days)
+	require.Zero(t, *days)
+}
+
+// Test grouping the utilization samples by the subnets and shared
+// networks.
+func TestGroupForecastSeries(t *testing.T) {
+	hour := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	hourly := []dbmodel.UtilizationSample{
+		{SampledAt: hour, SubnetID: 1, AddrUtilization: 0.1},
+		{SampledAt: hour, SharedNetworkID: 1, AddrUtilization: 0.2},
+	}
+	raw := []dbmodel.UtilizationSample{
+		{SampledAt: hour.Add(time.Hour), SubnetID: 1, AddrUtilization: 0.3},
+		{SampledAt: hour.Add(time.Hour), SubnetID: 2, PdUtilization: 0.4},
+		{SampledAt: hour.Add(time.Hour), AddressPoolID: 1, AddrUtilization: 0.5},
+		{SampledAt: hour.Add(time.Hour), PrefixPoolID: 1, PdUtilization: 0.6},
+	}
+	series := groupForecastSeries(hourly, raw)
+	require.Len(t, series, 5)
+
+	subnet := series[forecastSeriesKey{subnetID: 1}]
+	require.Len(t, subnet, 2)
+	require.Equal(t, hour.Add(30*time.Minute), subnet[0].at)
+	require.EqualValues(t, 0.1, subnet[0].addrUtilization)
+	require.Equal(t, hour.Add(time.Hour), subnet[1].at)
+	require.EqualValues(t, 0.3, subnet[1].addrUtilization)
+
+	network := series[forecastSeriesKey{sharedNetworkID: 1}]
+	require.Len(t, network, 1)
+	require.EqualValues(t, 0.2, network[0].addrUtilization)
+
+	subnet = series[forecastSeriesKey{subnetID: 2}]
+	require.Len(t, subnet, 1)
+	require.EqualValues(t, 0.4, subnet[0].pdUtilization)
+
+	pool := series[forecastSeriesKey{addressPoolID: 1}]
+	require.Len(t, pool, 1)
+	require.EqualValues(t, 0.5, pool[0].addrUtilization)
+
+	pool = series[forecastSeriesKey{prefixPoolID: 1}]
+	require.Len(t, pool, 1)
+	require.EqualValues(t, 0.6, pool[0].pdUtilization)
+}
+
+// Test checking if the forecasted exhaustion dropped below the horizon.
+func TestIsExhaustionWithinHorizon(t *testing.T) {
+	days := func(value float64) *float64 {
+		return &value
+	}
+	require.True(t, isExhaustionWithinHorizon(nil, days(10), 14))
+	require.True(t, isExhaustionWithinHorizon(days(20), days(10), 14))
+	require.True(t, isExhaustionWithinHorizon(days(14), days(13.9), 14))
+	require.False(t, isExhaustionWithinHorizon(days(12), days(10), 14))
+	require.False(t, isExhaustionWithinHorizon(nil, days(20), 14))
+	require.False(t, isExhaustionWithinHorizon(days(10), nil, 14))
+	require.False(t, isExhaustionWithinHorizon(nil, days(10), 0))
+}
+
+// Test that the warning is raised once when the forecasted exhaustion
+// drops below the horizon.
+func TestEvaluateExhaustionForecast(t *testing.T) {
+	now := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	fec := &storktest.FakeEventCenter{}
+	subnet := &dbmodel.Subnet{ID: 1, Prefix: "192.0.2.0/24"}
+
+	series := createLinearForecastSeries(now, 24, time.Hour, 0.5, 0.05)
+	addrDays, pdDays, changed := evaluateExhaustionForecast(fec, series, now, 14, "subnet {subnet}", []any{subnet}, nil, nil)
+	require.True(t, changed)
+	require.NotNil(t, addrDays)
+	require.NotNil(t, pdDays)
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvWarning, fec.Events[0].Level)
+	require.Contains(t, fec.Events[0].Text, "Addresses in subnet <subnet")
+	require.Contains(t, fec.Events[0].Text, "forecasted to be exhausted in 10.0 days")
+	require.EqualValues(t, 1, fec.Events[0].Relations.SubnetID)
+
+	// The warning is not raised again.
+	_, _, changed = evaluateExhaustionForecast(fec, series, now, 14, "subnet {subnet}", []any{subnet}, addrDays, pdDays)
+	require.False(t, changed)
+	require.Len(t, fec.Events, 1)
+
+	// The forecast is cleared when there are no samples.
+	addrDays, pdDays, changed = evaluateExhaustionForecast(fec, forecastSeries{}, now, 14, "subnet {subnet}", []any{subnet}, addrDays, pdDays)
+	require.True(t, changed)
+	require.Nil(t, addrDays)
+	require.Nil(t, pdDays)
+	require.Len(t, fec.Events, 1)
+
+	// The shared network warning is related to the shared network.
+	network := &dbmodel.SharedNetwork{ID: 2, Name: "frog"}
+	_, _, changed = evaluateExhaustionForecast(fec, series, now, 14, "shared network {sharedNetwork}", []any{network}, nil, nil)
+	require.True(t, changed)
+	require.Len(t, fec.Events, 2)
+	require.Contains(t, fec.Events[1].Text, "Addresses in shared network <shared-network id=\"2\" name=\"frog\">")
+	require.EqualValues(t, 2, fec.Events[1].Relations.SharedNetworkID)
+
+	// The pool warning is related to the subnet and the daemon.
+	localSubnet := &dbmodel.LocalSubnet{
+		Subnet: subnet,
+		Daemon: &dbmodel.Daemon{
+			ID:    3,
+			Name:  dbmodel.DaemonNameDHCPv4,
+			AppID: 4,
+			App: &dbmodel.App{
+				ID:        4,
+				MachineID: 5,
+				Type:      dbmodel.AppTypeKea,
+			},
+		},
+	}
+	_, _, changed = evaluateExhaustionForecast(fec, series, now, 14, "pool 192.0.2.10-192.0.2.20 in subnet {subnet} of {daemon}",
+		getPoolEventObjects(localSubnet), nil, nil)
+	require.True(t, changed)
+	require.Len(t, fec.Events, 3)
+	require.Contains(t, fec.Events[2].Text, "Addresses in pool 192.0.2.10-192.0.2.20 in subnet <subnet")
+	require.EqualValues(t, 1, fec.Events[2].Relations.SubnetID)
+	require.EqualValues(t, 3, fec.Events[2].Relations.DaemonID)
+	require.EqualValues(t, 4, fec.Events[2].Relations.AppID)
+	require.EqualValues(t, 5, fec.Events[2].Relations.MachineID)
+}
+
+// Test forecasting the exhaustion of the subnets and shared networks
+// using the utilization history stored in the database.
+func TestEvaluateExhaustionForecasts(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	err := dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	network := &dbmodel.SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err = dbmodel.AddSharedNetwork(db, network)
+	require.NoError(t, err)
+
+	subnet := &dbmodel.Subnet{
+		Prefix:          "192.0.2.0/24",
+		SharedNetworkID: network.ID,
+	}
+	err = dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	// Store the hourly samples of the last day. The utilization grows by
+	// 5% a day and reaches 50% now.
+	now := storkutil.UTCNow()
+	samples := []*dbmodel.UtilizationSample{}
+	for i := 1; i <= 24; i++ {
+		hour := now.Truncate(time.Hour).Add(-time.Duration(i) * time.Hour)
+		utilization := 0.5 - 0.05*now.Sub(hour.Add(30*time.Minute)).Hours()/24
+		sample := dbmodel.NewUtilizationSample(hour, utilization, 0, dbmodel.SubnetStats{})
+		sample.Resolution = dbmodel.UtilizationResolutionHourly
+		sample.SubnetID = subnet.ID
+		samples = append(samples, sample)
+	}
+	err = dbmodel.AddUtilizationSamples(db, samples)
+	require.NoError(t, err)
+
+	subnets, err := dbmodel.GetSubnetsWithLocalSubnets(db)
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+
+	fec := &storktest.FakeEventCenter{}
+	err = evaluateExhaustionForecasts(db, fec, subnets, nil, nil, now)
+	require.NoError(t, err)
+
+	returned, err := dbmodel.GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned.AddrExhaustionDays)
+	require.InDelta(t, 10, *returned.AddrExhaustionDays, 0.01)
+	require.Nil(t, returned.PdExhaustionDays)
+
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvWarning, fec.Events[0].Level)
+
+	// There are no samples for the shared network.
+	returnedNetwork, err := dbmodel.GetSharedNetwork(db, network.ID)
+	require.NoError(t, err)
+	require.Nil(t, returnedNetwork.AddrExhaustionDays)
+}
@@ -0,0 +1,339 @@
+package kea
+
+import (
+	"context"
+	"fmt"
+
+	errors "github.com/pkg/errors"
+
+	keaconfig "isc.org/stork/appcfg/kea"
+	keactrl "isc.org/stork/appctrl/kea"
+	"isc.org/stork/server/agentcomm"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Operation performed on the HA service.
+type HAOperation = string
+
+// Supported HA operations. Note that there is no operation pausing the
+// HA state machine. Kea pauses the state machine only in the states
+// configured with the pause parameter of the state-machine setting. The
+// continue operation resumes such a paused state machine.
+const (
+	HAOperationMaintenanceStart  HAOperation = "maintenance-start"
+	HAOperationMaintenanceCancel HAOperation = "maintenance-cancel"
+	HAOperationPartnerDown       HAOperation = "partner-down"
+	HAOperationSync              HAOperation = "sync"
+	HAOperationScopes            HAOperation = "scopes"
+	HAOperationContinue          HAOperation = "continue"
+)
+
+// An error returned when the requested HA operation cannot be performed
+// on the specified service or server, e.g., the server doesn't belong to
+// the service.
+type InvalidHAOperationError struct {
+	reason string
+}
+
+// Create new instance of the InvalidHAOperationError.
+func NewInvalidHAOperationError(format string, args ...any) error {
+	return &InvalidHAOperationError{
+		reason: fmt.Sprintf(format, args...),
+	}
+}
+
+// Returns error string.
+func (e InvalidHAOperationError) Error() string {
+	return e.reason
+}
+
+// Optional parameters of the HA operations.
+type HAOperationParams struct {
+	// New scopes served by the server in the scopes operation.
+	Scopes []string
+	// Maximum duration of the synchronization in seconds in the sync
+	// operation. Zero means that the duration is not limited.
+	MaxPeriod int64
+}
+
+// Describes the HA operation before it is performed. It comprises the
+// command to be sent and the warnings the user should confirm before
+// the command is sent.
+type HAOperationPlan struct {
+	Service   *dbmodel.Service
+	Operation HAOperation
+	// Server which the operation concerns, e.g., the server to be put
+	// into the maintenance.
+	Daemon *dbmodel.Daemon
+	// Server to which the command is sent. It is the partner of the
+	// Daemon in the maintenance operations.
+	Target  *dbmodel.Daemon
+	Command *keactrl.Command
+	// Issues found in the last known HA status which are likely to
+	// cause the operation to fail or to have unexpected effects.
+	Warnings []string
+}
+
+// Returns the last known HA state of the server and whether it was
+// reachable.
+func getHAServerStatus(ha *dbmodel.BaseHAService, daemonID int64) (dbmodel.HAState, bool) {
+	if daemonID == ha.PrimaryID {
+		return ha.PrimaryLastState, ha.PrimaryReachable
+	}
+	return ha.SecondaryLastState, ha.SecondaryReachable
+}
+
+// Returns a label identifying the server in the warnings.
+func getHAServerLabel(daemon *dbmodel.Daemon) string {
+	if daemon.App != nil {
+		return fmt.Sprintf("%s server of %s", daemon.Name, daemon.App.Name)
+	}
+	return fmt.Sprintf("%s server", daemon.Name)
+}
+
+// Returns the configuration of the HA relationship with the given name
+// configured for the server. If the name is empty, e.g., the service was
+// detected by the earlier Stork version, the first relationship is returned.
+// It returns nil if the relationship is not found.
+func getHARelationship(daemon *dbmodel.Daemon, relationshipName string) *keaconfig.HA {
+	relationships := getHARelationships(daemon)
+	for i := range relationships {
+		if len(relationshipName) == 0 || relationships[i].GetRelationshipName() == relationshipName {
+			return &relationships[i]
+		}
+	}
+	return nil
+}
+
+// Returns the names of the peers which can serve the scopes, i.e. the
+// primary and secondary servers, according to the configuration of the
+// HA relationship of the server. The first returned value is the name of
+// this server.
+func getHAPeerNames(daemon *dbmodel.Daemon, relationshipName string) (string, []string) {
+	ha := getHARelationship(daemon, relationshipName)
+	if ha == nil {
+		return "", nil
+	}
+	var thisServerName string
+	if ha.ThisServerName != nil {
+		thisServerName = *ha.ThisServerName
+	}
+	var names []string
+	for _, peer := range ha.Peers {
+		if peer.Name == nil || (peer.Role != nil && *peer.Role == "backup") {
+			continue
+		}
+		names = append(names, *peer.Name)
+	}
+	return thisServerName, names
+}
+
+// Returns the name of the partner of the server in the HA relationship.
+// It returns an empty string if the partner is not found.
+func getHAPartnerName(daemon *dbmodel.Daemon, relationshipName string) string {
+	thisServerName, peerNames := getHAPeerNames(daemon, relationshipName)
+	for _, name := range peerNames {
+		if name != thisServerName {
+			return name
+		}
+	}
+	return ""
+}
+
+// Prepares the HA operation on the specified server belonging to the
+// HA service. The server must be the primary or the secondary (standby)
+// server. It doesn't access the database.
+func planHAOperation(service *dbmodel.Service, daemonID int64, operation HAOperation, params HAOperationParams) (*HAOperationPlan, error) {
+	ha := service.HAService
+	if ha == nil {
+		return nil, NewInvalidHAOperationError("service with ID %d is not an HA service", service.ID)
+	}
+	if daemonID == 0 || (daemonID != ha.PrimaryID && daemonID != ha.SecondaryID) {
+		return nil, NewInvalidHAOperationError("daemon with ID %d is not a primary or secondary server of the HA service with ID %d", daemonID, service.ID)
+	}
+	var daemon, partner *dbmodel.Daemon
+	for _, d := range service.Daemons {
+		switch {
+		case d.ID == daemonID:
+			daemon = d
+		case d.ID == ha.PrimaryID || d.ID == ha.SecondaryID:
+			partner = d
+		}
+	}
+	if daemon == nil {
+		return nil, NewInvalidHAOperationError("daemon with ID %d not found in the HA service with ID %d", daemonID, service.ID)
+	}
+
+	plan := &HAOperationPlan{
+		Service:   service,
+		Operation: operation,
+		Daemon:    daemon,
+		Target:    daemon,
+	}
+	daemons := []string{daemon.Name}
+	daemonState, daemonReachable := getHAServerStatus(ha, daemon.ID)
+	var partnerState dbmodel.HAState
+	var partnerReachable bool
+
+	switch operation {
+	case HAOperationMaintenanceStart, HAOperationMaintenanceCancel, HAOperationPartnerDown:
+		if partner == nil {
+			return nil, NewInvalidHAOperationError("%s operation requires the partner of the %s", operation, getHAServerLabel(daemon))
+		}
+		partnerState, partnerReachable = getHAServerStatus(ha, partner.ID)
+	}
+
+	switch operation {
+	case HAOperationMaintenanceStart:
+		// The partner transitions to the partner-in-maintenance state
+		// and tells the server to transition to the in-maintenance state.
+		plan.Target = partner
+		plan.Command = keactrl.NewCommand("ha-maintenance-start", []string{partner.Name}, nil)
+		if !partnerReachable || partnerState == dbmodel.HAStateUnavailable {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("The %s receiving the command appears to be unreachable.", getHAServerLabel(partner)))
+		}
+		if !daemonReachable || daemonState == dbmodel.HAStateUnavailable {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("The %s appears to be unreachable. Its partner will transition to the partner-down state instead of the partner-in-maintenance state.", getHAServerLabel(daemon)))
+		}
+		if daemonState == dbmodel.HAStateInMaintenance {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("The %s is already in the in-maintenance state.", getHAServerLabel(daemon)))
+		}
+	case HAOperationMaintenanceCancel:
+		// The maintenance is canceled by the partner which is in the
+		// partner-in-maintenance state.
+		plan.Target = partner
+		plan.Command = keactrl.NewCommand("ha-maintenance-cancel", []string{partner.Name}, nil)
+		if partnerState != dbmodel.HAStatePartnerInMaintenance {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("The %s receiving the command is not in the partner-in-maintenance state. The command is likely to be rejected.", getHAServerLabel(partner)))
+		}
+	case HAOperationPartnerDown:
+		// The server receiving the ha-maintenance-start command
+		// transitions to the partner-down state when its partner is
+		// unavailable.
+		plan.Command = keactrl.NewCommand("ha-maintenance-start", daemons, nil)
+		if partnerReachable && partnerState != dbmodel.HAStateUnavailable {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("The partner %s appears to be running. The %s will transition to the partner-in-maintenance state and its partner to the in-maintenance state instead of the partner-down state.", getHAServerLabel(partner), getHAServerLabel(daemon)))
+		}
+		if !daemonReachable || daemonState == dbmodel.HAStateUnavailable {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("The %s receiving the command appears to be unreachable.", getHAServerLabel(daemon)))
+		}
+	case HAOperationSync:
+		partnerName := getHAPartnerName(daemon, ha.Relationship)
+		if partnerName == "" {
+			return nil, NewInvalidHAOperationError("cannot find the partner name in the HA configuration of the %s", getHAServerLabel(daemon))
+		}
+		arguments := map[string]any{
+			"server-name": partnerName,
+		}
+		if params.MaxPeriod > 0 {
+			arguments["max-period"] = params.MaxPeriod
+		}
+		plan.Command = keactrl.NewCommand("ha-sync", daemons, arguments)
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("The %s stops responding to the DHCP queries until the lease database synchronization with %s is completed.", getHAServerLabel(daemon), partnerName))
+	case HAOperationScopes:
+		_, peerNames := getHAPeerNames(daemon, ha.Relationship)
+		for _, scope := range params.Scopes {
+			found := false
+			for _, name := range peerNames {
+				if scope == name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, NewInvalidHAOperationError("scope %s is not a name of the primary or secondary server in the HA configuration of the %s", scope, getHAServerLabel(daemon))
+			}
+		}
+		scopes := params.Scopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		arguments := map[string]any{
+			"scopes": scopes,
+		}
+		// The server with multiple relationships requires the name of a
+		// server identifying the relationship.
+		if len(getHARelationships(daemon)) > 1 {
+			arguments["server-name"] = getHAPartnerName(daemon, ha.Relationship)
+		}
+		plan.Command = keactrl.NewCommand("ha-scopes", daemons, arguments)
+		if len(scopes) == 0 {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("The %s will not respond to any DHCP queries.", getHAServerLabel(daemon)))
+		}
+	case HAOperationContinue:
+		var arguments any
+		if len(getHARelationships(daemon)) > 1 {
+			arguments = map[string]any{
+				"server-name": getHAPartnerName(daemon, ha.Relationship),
+			}
+		}
+		plan.Command = keactrl.NewCommand("ha-continue", daemons, arguments)
+	default:
+		return nil, NewInvalidHAOperationError("unsupported HA operation %s", operation)
+	}
+
+	// The maintenance applies to all relationships of the server receiving
+	// the command.
+	switch operation {
+	case HAOperationMaintenanceStart, HAOperationMaintenanceCancel, HAOperationPartnerDown:
+		if count := len(getHARelationships(plan.Target)); count > 1 {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("The %s receiving the command belongs to %d HA relationships. The command affects all of them.", getHAServerLabel(plan.Target), count))
+		}
+	}
+	return plan, nil
+}
+
+// Prepares the HA operation on the specified server belonging to the HA
+// service. It returns nil plan and nil error if the service doesn't exist.
+// The returned plan should be presented to the user and passed to the
+// ExecuteHAOperation function when the user confirms the operation.
+func PlanHAOperation(db dbops.DBI, serviceID, daemonID int64, operation HAOperation, params HAOperationParams) (*HAOperationPlan, error) {
+	service, err := dbmodel.GetDetailedService(db, serviceID)
+	if err != nil || service == nil {
+		return nil, err
+	}
+	plan, err := planHAOperation(service, daemonID, operation, params)
+	if err != nil {
+		return nil, err
+	}
+	// The app of the target server is needed to send the command.
+	app, err := dbmodel.GetAppByID(db, plan.Target.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if app == nil {
+		return nil, errors.Errorf("app with ID %d not found", plan.Target.AppID)
+	}
+	plan.Target.App = app
+	return plan, nil
+}
+
+// Sends the command of the HA operation to the target server. It returns
+// the text of the Kea response. An error is returned if the command could
+// not be sent or Kea reported an error.
+func ExecuteHAOperation(ctx context.Context, agents agentcomm.ConnectedAgents, plan *HAOperationPlan) (string, error) {
+	commandName := plan.Command.GetCommand()
+	response := make([]keactrl.ResponseHeader, 1)
+	respResult, err := agents.ForwardToKeaOverHTTP(ctx, plan.Target.App, []keactrl.SerializableCommand{plan.Command}, &response)
+	if err != nil {
+		return "", err
+	}
+	if respResult.Error != nil {
+		return "", respResult.Error
+	}
+	if len(respResult.CmdsErrors) > 0 && respResult.CmdsErrors[0] != nil {
+		return "", respResult.CmdsErrors[0]
+	}
+	if len(response) == 0 {
+		return "", errors.Errorf("invalid response to %s command received", commandName)
+	}
+	switch response[0].Result {
+	case keactrl.ResponseSuccess:
+		return response[0].Text, nil
+	case keactrl.ResponseCommandUnsupported:
+		return "", errors.Errorf("%s command unsupported", commandName)
+	default:
+		return "", errors.Errorf("error returned by Kea in response to %s command: %s", commandName, response[0].Text)
+	}
+}
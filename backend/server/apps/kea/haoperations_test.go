@@ -0,0 +1,280 @@
+package kea
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	keactrl "isc.org/stork/appctrl/kea"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Returns the load balancing HA service with the primary server (ID 1)
+// and the secondary server (ID 2) running in different apps. Both
+// servers are in the load-balancing state.
+func getHAOperationsTestService() *dbmodel.Service {
+	daemons := []*dbmodel.Daemon{
+		{
+			ID:    1,
+			AppID: 1,
+			Name:  "dhcp4",
+			App: &dbmodel.App{
+				ID:   1,
+				Name: "kea-1",
+			},
+			KeaDaemon: &dbmodel.KeaDaemon{
+				Config: getHATestConfig("Dhcp4", "server1", "load-balancing", "server1", "server2", "server4"),
+			},
+		},
+		{
+			ID:    2,
+			AppID: 2,
+			Name:  "dhcp4",
+			App: &dbmodel.App{
+				ID:   2,
+				Name: "kea-2",
+			},
+			KeaDaemon: &dbmodel.KeaDaemon{
+				Config: getHATestConfig("Dhcp4", "server2", "load-balancing", "server1", "server2", "server4"),
+			},
+		},
+	}
+	return &dbmodel.Service{
+		BaseService: dbmodel.BaseService{
+			ID:      1,
+			Daemons: daemons,
+		},
+		HAService: &dbmodel.BaseHAService{
+			HAType:             dbmodel.HATypeDhcp4,
+			HAMode:             dbmodel.HAModeLoadBalancing,
+			PrimaryID:          1,
+			SecondaryID:        2,
+			PrimaryLastState:   dbmodel.HAStateLoadBalancing,
+			SecondaryLastState: dbmodel.HAStateLoadBalancing,
+			PrimaryReachable:   true,
+			SecondaryReachable: true,
+		},
+	}
+}
+
+// Test that the maintenance commands are sent to the partner of the
+// server to be taken down.
+func TestPlanHAOperationMaintenance(t *testing.T) {
+	service := getHAOperationsTestService()
+
+	plan, err := planHAOperation(service, 1, HAOperationMaintenanceStart, HAOperationParams{})
+	require.NoError(t, err)
+	require.Equal(t, HAOperationMaintenanceStart, plan.Operation)
+	require.EqualValues(t, 1, plan.Daemon.ID)
+	require.EqualValues(t, 2, plan.Target.ID)
+	require.JSONEq(t, `{"command": "ha-maintenance-start", "service": ["dhcp4"]}`, plan.Command.Marshal())
+	require.Empty(t, plan.Warnings)
+
+	// The partner cannot notify the unreachable server.
+	service.HAService.PrimaryReachable = false
+	plan, err = planHAOperation(service, 1, HAOperationMaintenanceStart, HAOperationParams{})
+	require.NoError(t, err)
+	require.Len(t, plan.Warnings, 1)
+	require.Contains(t, plan.Warnings[0], "partner-down")
+
+	// The partner is not in the partner-in-maintenance state.
+	plan, err = planHAOperation(service, 1, HAOperationMaintenanceCancel, HAOperationParams{})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, plan.Target.ID)
+	require.Equal(t, "ha-maintenance-cancel", plan.Command.GetCommand())
+	require.Len(t, plan.Warnings, 1)
+	require.Contains(t, plan.Warnings[0], "dhcp4 server of kea-2")
+
+	service.HAService.SecondaryLastState = dbmodel.HAStatePartnerInMaintenance
+	plan, err = planHAOperation(service, 1, HAOperationMaintenanceCancel, HAOperationParams{})
+	require.NoError(t, err)
+	require.Empty(t, plan.Warnings)
+}
+
+// Test that the partner-down operation is sent to the surviving server
+// and that the user is warned when the partner is still running.
+func TestPlanHAOperationPartnerDown(t *testing.T) {
+	service := getHAOperationsTestService()
+
+	plan, err := planHAOperation(service, 2, HAOperationPartnerDown, HAOperationParams{})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, plan.Daemon.ID)
+	require.EqualValues(t, 2, plan.Target.ID)
+	require.Equal(t, "ha-maintenance-start", plan.Command.GetCommand())
+	require.Len(t, plan.Warnings, 1)
+	require.Contains(t, plan.Warnings[0], "appears to be running")
+
+	service.HAService.PrimaryLastState = dbmodel.HAStateUnavailable
+	plan, err = planHAOperation(service, 2, HAOperationPartnerDown, HAOperationParams{})
+	require.NoError(t, err)
+	require.Empty(t, plan.Warnings)
+}
+
+// Test that the ha-sync command points to the partner name taken from
+// the HA configuration.
+func TestPlanHAOperationSync(t *testing.T) {
+	service := getHAOperationsTestService()
+
+	plan, err := planHAOperation(service, 1, HAOperationSync, HAOperationParams{})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"command": "ha-sync", "service": ["dhcp4"], "arguments": {"server-name": "server2"}}`, plan.Command.Marshal())
+	require.Len(t, plan.Warnings, 1)
+
+	plan, err = planHAOperation(service, 2, HAOperationSync, HAOperationParams{MaxPeriod: 60})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"command": "ha-sync", "service": ["dhcp4"], "arguments": {"server-name": "server1", "max-period": 60}}`, plan.Command.Marshal())
+
+	// The partner name is unknown.
+	service.Daemons[0].KeaDaemon.Config = nil
+	_, err = planHAOperation(service, 1, HAOperationSync, HAOperationParams{})
+	var invalid *InvalidHAOperationError
+	require.ErrorAs(t, err, &invalid)
+}
+
+// Test that the scopes are validated against the peer names.
+func TestPlanHAOperationScopes(t *testing.T) {
+	service := getHAOperationsTestService()
+
+	plan, err := planHAOperation(service, 1, HAOperationScopes, HAOperationParams{
+		Scopes: []string{"server1", "server2"},
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"command": "ha-scopes", "service": ["dhcp4"], "arguments": {"scopes": ["server1", "server2"]}}`, plan.Command.Marshal())
+	require.Empty(t, plan.Warnings)
+
+	plan, err = planHAOperation(service, 1, HAOperationScopes, HAOperationParams{})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"command": "ha-scopes", "service": ["dhcp4"], "arguments": {"scopes": []}}`, plan.Command.Marshal())
+	require.Len(t, plan.Warnings, 1)
+
+	// The backup server doesn't serve any scope.
+	_, err = planHAOperation(service, 1, HAOperationScopes, HAOperationParams{
+		Scopes: []string{"server4"},
+	})
+	var invalid *InvalidHAOperationError
+	require.ErrorAs(t, err, &invalid)
+}
+
+// Test the ha-continue command and the invalid operations.
+func TestPlanHAOperationInvalid(t *testing.T) {
+	service := getHAOperationsTestService()
+
+	plan, err := planHAOperation(service, 2, HAOperationContinue, HAOperationParams{})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"command": "ha-continue", "service": ["dhcp4"]}`, plan.Command.Marshal())
+
+	var invalid *InvalidHAOperationError
+
+	_, err = planHAOperation(service, 2, "pause", HAOperationParams{})
+	require.ErrorAs(t, err, &invalid)
+
+	_, err = planHAOperation(service, 3, HAOperationContinue, HAOperationParams{})
+	require.ErrorAs(t, err, &invalid)
+
+	// No partner in the passive-backup mode.
+	service.HAService.SecondaryID = 0
+	_, err = planHAOperation(service, 1, HAOperationMaintenanceStart, HAOperationParams{})
+	require.ErrorAs(t, err, &invalid)
+
+	service.HAService = nil
+	_, err = planHAOperation(service, 1, HAOperationContinue, HAOperationParams{})
+	require.ErrorAs(t, err, &invalid)
+}
+
+// Test that the HA operation command is sent to the target server.
+func TestExecuteHAOperation(t *testing.T) {
+	service := getHAOperationsTestService()
+	plan, err := planHAOperation(service, 1, HAOperationMaintenanceStart, HAOperationParams{})
+	require.NoError(t, err)
+	plan.Target.App.AccessPoints = []*dbmodel.AccessPoint{
+		{
+			Type:    dbmodel.AccessPointControl,
+			Address: "192.0.2.2",
+			Port:    8000,
+		},
+	}
+
+	fa := agentcommtest.NewFakeAgents(func(callNo int, cmdResponses []interface{}) {
+		json := []byte(`[{"result": 0, "text": "Server is in partner-in-maintenance state."}]`)
+		_ = keactrl.UnmarshalResponseList(plan.Command, json, cmdResponses[0])
+	}, nil)
+	text, err := ExecuteHAOperation(context.Background(), fa, plan)
+	require.NoError(t, err)
+	require.Equal(t, "Server is in partner-in-maintenance state.", text)
+	require.Equal(t, []string{"http://192.0.2.2:8000/"}, fa.RecordedURLs)
+	require.Equal(t, "ha-maintenance-start", fa.GetLastCommand().Command)
+
+	// Kea rejected the command.
+	fa = agentcommtest.NewFakeAgents(func(callNo int, cmdResponses []interface{}) {
+		json := []byte(`[{"result": 1, "text": "Unable to transition to the partner-in-maintenance state."}]`)
+		_ = keactrl.UnmarshalResponseList(plan.Command, json, cmdResponses[0])
+	}, nil)
+	_, err = ExecuteHAOperation(context.Background(), fa, plan)
+	require.ErrorContains(t, err, "Unable to transition")
+	require.False(t, errors.As(err, new(*InvalidHAOperationError)))
+}
+
+// Test that the HA operation is prepared for the service from the database.
+func TestPlanHAOperationFromDatabase(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	plan, err := PlanHAOperation(db, 1, 1, HAOperationContinue, HAOperationParams{})
+	require.NoError(t, err)
+	require.Nil(t, plan)
+
+	var apps []*dbmodel.App
+	for i, name := range []string{"server1", "server2"} {
+		machine := &dbmodel.Machine{
+			Address:   "machine" + name,
+			AgentPort: 8080,
+		}
+		err = dbmodel.AddMachine(db, machine)
+		require.NoError(t, err)
+		app := &dbmodel.App{
+			MachineID: machine.ID,
+			Type:      dbmodel.AppTypeKea,
+			Name:      "kea-" + name,
+			AccessPoints: []*dbmodel.AccessPoint{
+				{
+					Type:    dbmodel.AccessPointControl,
+					Address: "192.0.2.1",
+					Port:    int64(8000 + i),
+				},
+			},
+			Daemons: []*dbmodel.Daemon{
+				dbmodel.NewKeaDaemon("dhcp4", true),
+			},
+		}
+		err = app.Daemons[0].SetConfig(getHATestConfig("Dhcp4", name, "load-balancing", "server1", "server2"))
+		require.NoError(t, err)
+		_, err = dbmodel.AddApp(db, app)
+		require.NoError(t, err)
+		apps = append(apps, app)
+	}
+	service := &dbmodel.Service{
+		BaseService: dbmodel.BaseService{
+			ServiceType: "ha_dhcp",
+			Daemons:     []*dbmodel.Daemon{apps[0].Daemons[0], apps[1].Daemons[0]},
+		},
+		HAService: &dbmodel.BaseHAService{
+			HAType:      dbmodel.HATypeDhcp4,
+			HAMode:      dbmodel.HAModeLoadBalancing,
+			PrimaryID:   apps[0].Daemons[0].ID,
+			SecondaryID: apps[1].Daemons[0].ID,
+		},
+	}
+	err = dbmodel.AddService(db, service)
+	require.NoError(t, err)
+
+	plan, err = PlanHAOperation(db, service.ID, apps[0].Daemons[0].ID, HAOperationMaintenanceStart, HAOperationParams{})
+	require.NoError(t, err)
+	require.NotNil(t, plan)
+	require.Equal(t, apps[1].Daemons[0].ID, plan.Target.ID)
+	require.NotNil(t, plan.Target.App)
+	require.Len(t, plan.Target.App.AccessPoints, 1)
+	require.EqualValues(t, 8001, plan.Target.App.AccessPoints[0].Port)
+}
@@ -275,7 +275,12 @@ func GetLeases6ByHostname(agents agentcomm.ConnectedAgents, dbApp *dbmodel.App,
 // Convenience function checking if a daemon being a part of the specified app
 // has the libdhcp_lease_cmds hooks library configured.
 func hasLeaseCmdsHook(app *dbmodel.App, daemonName string) bool {
-	daemon := app.GetDaemonByName(daemonName)
+	return daemonHasLeaseCmdsHook(app.GetDaemonByName(daemonName))
+}
+
+// Convenience function checking if a daemon has the libdhcp_lease_cmds
+// hooks library configured.
+func daemonHasLeaseCmdsHook(daemon *dbmodel.Daemon) bool {
 	if daemon != nil && daemon.KeaDaemon != nil && daemon.KeaDaemon.Config != nil {
 		if _, _, ok := daemon.KeaDaemon.Config.GetHookLibrary("libdhcp_lease_cmds"); ok {
 			return true
@@ -600,3 +605,235 @@ func FindLeasesByHostID(db *dbops.PgDB, agents agentcomm.ConnectedAgents, hostID
 
 	return leases, conflicts, erredApps, err
 }
+
+// Returns the names of the Kea daemon and the lease commands' prefix
+// appropriate for the IP address or prefix. It returns an error if the
+// specified value is not a valid IP address or prefix, or if the daemon
+// lacks the lease_cmds hooks library.
+func getLeaseCommandDaemon(dbApp *dbmodel.App, ipAddress string) (daemonName, commandPrefix string, err error) {
+	parsedIP := storkutil.ParseIP(ipAddress)
+	if parsedIP == nil {
+		return "", "", errors.Errorf("invalid lease IP address %s", ipAddress)
+	}
+	daemonName, commandPrefix = dbmodel.DaemonNameDHCPv6, "lease6"
+	if parsedIP.Protocol == storkutil.IPv4 {
+		daemonName, commandPrefix = dbmodel.DaemonNameDHCPv4, "lease4"
+	}
+	if !hasLeaseCmdsHook(dbApp, daemonName) {
+		return "", "", errors.Errorf("%s daemon in app %s does not use the lease_cmds hooks library", daemonName, dbApp.Name)
+	}
+	return daemonName, commandPrefix, nil
+}
+
+// Sends a command modifying leases to the specified Kea daemon. It returns
+// the result returned by Kea if the command was successful or the lease
+// was not found (i.e., empty result). Otherwise, it returns an error.
+func sendLeaseCommand(agents agentcomm.ConnectedAgents, dbApp *dbmodel.App, daemonName, commandName string, arguments map[string]interface{}) (int, error) {
+	command := keactrl.NewCommand(commandName, []string{daemonName}, arguments)
+	response := make([]keactrl.ResponseHeader, 1)
+	ctx := context.Background()
+	respResult, err := agents.ForwardToKeaOverHTTP(ctx, dbApp, []keactrl.SerializableCommand{command}, &response)
+	if err != nil {
+		return keactrl.ResponseError, err
+	}
+	if respResult.Error != nil {
+		return keactrl.ResponseError, respResult.Error
+	}
+	if len(respResult.CmdsErrors) > 0 && respResult.CmdsErrors[0] != nil {
+		return keactrl.ResponseError, respResult.CmdsErrors[0]
+	}
+	if len(response) == 0 {
+		return keactrl.ResponseError, errors.Errorf("invalid response to %s command received", commandName)
+	}
+	switch response[0].Result {
+	case keactrl.ResponseSuccess, keactrl.ResponseEmpty:
+		return response[0].Result, nil
+	case keactrl.ResponseCommandUnsupported:
+		return response[0].Result, errors.Errorf("%s command unsupported", commandName)
+	default:
+		return response[0].Result, errors.Errorf("error returned by Kea in response to %s command: %s", commandName, response[0].Text)
+	}
+}
+
+// Sends lease4-del or lease6-del command to Kea to delete a lease with
+// the specified IP address. The lease type is only used for the DHCPv6
+// leases. It defaults to IA_NA when empty. The first returned value is
+// false if the lease was not found.
+func DeleteLease(agents agentcomm.ConnectedAgents, dbApp *dbmodel.App, leaseType, ipAddress string) (bool, error) {
+	daemonName, commandPrefix, err := getLeaseCommandDaemon(dbApp, ipAddress)
+	if err != nil {
+		return false, err
+	}
+	arguments := map[string]interface{}{
+		"ip-address": ipAddress,
+	}
+	if daemonName == dbmodel.DaemonNameDHCPv6 {
+		if leaseType == "" {
+			leaseType = "IA_NA"
+		}
+		arguments["type"] = leaseType
+	}
+	result, err := sendLeaseCommand(agents, dbApp, daemonName, commandPrefix+"-del", arguments)
+	if err != nil {
+		return false, err
+	}
+	return result == keactrl.ResponseSuccess, nil
+}
+
+// Converts the lease to the arguments of the commands adding and updating
+// the leases. Kea expects the expiration time rather than cltt, so it is
+// computed from the cltt and valid lifetime when cltt is specified.
+func createLeaseCommandArguments(daemonName string, lease *keadata.Lease) map[string]interface{} {
+	arguments := map[string]interface{}{
+		"ip-address": lease.IPAddress,
+	}
+	setIf := func(condition bool, name string, value interface{}) {
+		if condition {
+			arguments[name] = value
+		}
+	}
+	setIf(lease.SubnetID != 0, "subnet-id", lease.SubnetID)
+	setIf(lease.HWAddress != "", "hw-address", lease.HWAddress)
+	setIf(lease.Hostname != "", "hostname", lease.Hostname)
+	setIf(lease.FqdnFwd, "fqdn-fwd", lease.FqdnFwd)
+	setIf(lease.FqdnRev, "fqdn-rev", lease.FqdnRev)
+	setIf(lease.ValidLifetime != 0, "valid-lft", lease.ValidLifetime)
+	setIf(lease.CLTT != 0, "expire", lease.CLTT+uint64(lease.ValidLifetime))
+	setIf(lease.State != keadata.LeaseStateDefault, "state", lease.State)
+	setIf(len(lease.UserContext) > 0, "user-context", lease.UserContext)
+	if daemonName == dbmodel.DaemonNameDHCPv4 {
+		setIf(lease.ClientID != "", "client-id", lease.ClientID)
+		return arguments
+	}
+	leaseType := lease.Type
+	if leaseType == "" {
+		leaseType = "IA_NA"
+	}
+	arguments["type"] = leaseType
+	arguments["duid"] = lease.DUID
+	arguments["iaid"] = lease.IAID
+	setIf(lease.PrefixLength != 0, "prefix-len", lease.PrefixLength)
+	setIf(lease.PreferredLifetime != 0, "preferred-lft", lease.PreferredLifetime)
+	return arguments
+}
+
+// Sends lease4-add or lease6-add command to Kea to add a new lease.
+// Kea returns an error if the lease already exists.
+func AddLease(agents agentcomm.ConnectedAgents, dbApp *dbmodel.App, lease *keadata.Lease) error {
+	daemonName, commandPrefix, err := getLeaseCommandDaemon(dbApp, lease.IPAddress)
+	if err != nil {
+		return err
+	}
+	arguments := createLeaseCommandArguments(daemonName, lease)
+	_, err = sendLeaseCommand(agents, dbApp, daemonName, commandPrefix+"-add", arguments)
+	return err
+}
+
+// Sends lease4-update or lease6-update command to Kea to update an
+// existing lease. If the force parameter is true, the lease is created
+// when it does not exist.
+func UpdateLease(agents agentcomm.ConnectedAgents, dbApp *dbmodel.App, lease *keadata.Lease, force bool) error {
+	daemonName, commandPrefix, err := getLeaseCommandDaemon(dbApp, lease.IPAddress)
+	if err != nil {
+		return err
+	}
+	arguments := createLeaseCommandArguments(daemonName, lease)
+	if force {
+		arguments["force-create"] = true
+	}
+	_, err = sendLeaseCommand(agents, dbApp, daemonName, commandPrefix+"-update", arguments)
+	return err
+}
+
+// Sends lease4-wipe or lease6-wipe command to the specified Kea daemon
+// to delete all leases in the subnet having the specified ID in the Kea
+// configuration. The caller is responsible for checking that the daemon
+// uses the lease_cmds hooks library.
+func WipeLeases(agents agentcomm.ConnectedAgents, dbApp *dbmodel.App, daemonName string, localSubnetID int64) error {
+	commandName := "lease4-wipe"
+	if daemonName == dbmodel.DaemonNameDHCPv6 {
+		commandName = "lease6-wipe"
+	}
+	arguments := map[string]interface{}{
+		"subnet-id": localSubnetID,
+	}
+	_, err := sendLeaseCommand(agents, dbApp, daemonName, commandName, arguments)
+	return err
+}
+
+// Deletes the subnet leases from all Kea daemons serving the subnet and
+// having the lease_cmds hooks library. The daemons to which the command
+// was successfully sent are returned in the first slice. The daemons which
+// returned an error are returned in the second slice. The returned error
+// indicates a problem with the database communication.
+func WipeSubnetLeases(db *dbops.PgDB, agents agentcomm.ConnectedAgents, subnetID int64) (subnet *dbmodel.Subnet, wipedDaemons, erredDaemons []*dbmodel.Daemon, err error) {
+	subnet, err = dbmodel.GetSubnet(db, subnetID)
+	if err != nil {
+		err = errors.WithMessagef(err, "failed to fetch subnet with ID %d while wiping its leases", subnetID)
+		return
+	}
+	if subnet == nil {
+		return
+	}
+	for _, ls := range subnet.LocalSubnets {
+		daemon := ls.Daemon
+		if daemon == nil || daemon.App == nil || !daemonHasLeaseCmdsHook(daemon) {
+			continue
+		}
+		if err := WipeLeases(agents, daemon.App, daemon.Name, ls.LocalSubnetID); err != nil {
+			log.Warn(err)
+			erredDaemons = append(erredDaemons, daemon)
+			continue
+		}
+		wipedDaemons = append(wipedDaemons, daemon)
+	}
+	return subnet, wipedDaemons, erredDaemons, nil
+}
+
+// Finds the declined leases on the Kea servers and deletes them, so the
+// declined addresses can be allocated again. If the app ID is non-zero,
+// only the leases of this app are reclaimed. The reclaimed leases are
+// returned in the first slice. The Kea servers that returned an error
+// response while searching or deleting the leases are returned in the
+// second value. The third returned value indicates a general error,
+// e.g., issues with Stork database communication.
+func ReclaimDeclinedLeases(db *dbops.PgDB, agents agentcomm.ConnectedAgents, appID int64) (reclaimed []dbmodel.Lease, erredApps []*dbmodel.App, err error) {
+	leases, erredApps, err := FindDeclinedLeases(db, agents)
+	if err != nil {
+		return
+	}
+	if appID != 0 {
+		var appErredApps []*dbmodel.App
+		for _, app := range erredApps {
+			if app.ID == appID {
+				appErredApps = append(appErredApps, app)
+			}
+		}
+		erredApps = appErredApps
+	}
+	for i := range leases {
+		lease := leases[i]
+		if appID != 0 && lease.AppID != appID {
+			continue
+		}
+		deleted, err := DeleteLease(agents, lease.App, lease.Type, lease.IPAddress)
+		if err != nil {
+			log.Warn(err)
+			found := false
+			for _, app := range erredApps {
+				if app.ID == lease.AppID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				erredApps = append(erredApps, lease.App)
+			}
+			continue
+		}
+		if deleted {
+			reclaimed = append(reclaimed, lease)
+		}
+	}
+	return reclaimed, erredApps, nil
+}
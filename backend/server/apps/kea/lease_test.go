@@ -1639,3 +1639,296 @@ func TestFindHostLeaseConflictsSkipDetection(t *testing.T) {
 		})
 	}
 }
+
+// Generates a mock response to a command deleting a lease which
+// does not exist.
+func mockLeaseDelEmpty(callNo int, responses []interface{}) {
+	json := []byte(`[
+        {
+            "result": 3,
+            "text": "IPv4 lease not found."
+        }
+    ]`)
+	command := keactrl.NewCommand("lease4-del", []string{"dhcp4"}, nil)
+	_ = keactrl.UnmarshalResponseList(command, json, responses[0])
+}
+
+// Generates an error mock response to a command modifying a lease.
+func mockLeaseCommandError(callNo int, responses []interface{}) {
+	json := []byte(`[
+        {
+            "result": 1,
+            "text": "lease already exists"
+        }
+    ]`)
+	command := keactrl.NewCommand("lease4-add", []string{"dhcp4"}, nil)
+	_ = keactrl.UnmarshalResponseList(command, json, responses[0])
+}
+
+// Creates an app with the control access point and the DHCPv4 and DHCPv6
+// daemons using the lease_cmds hooks library. It is used in the tests
+// of the commands modifying the leases.
+func createTestLeaseApp() *dbmodel.App {
+	accessPoints := []*dbmodel.AccessPoint{}
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, false)
+	app := &dbmodel.App{
+		ID:           1,
+		Name:         "kea",
+		AccessPoints: accessPoints,
+	}
+	for _, name := range []string{dbmodel.DaemonNameDHCPv4, dbmodel.DaemonNameDHCPv6} {
+		rootName := "Dhcp4"
+		if name == dbmodel.DaemonNameDHCPv6 {
+			rootName = "Dhcp6"
+		}
+		app.Daemons = append(app.Daemons, &dbmodel.Daemon{
+			Name: name,
+			KeaDaemon: &dbmodel.KeaDaemon{
+				Config: dbmodel.NewKeaConfig(&map[string]interface{}{
+					rootName: map[string]interface{}{
+						"hooks-libraries": []interface{}{
+							map[string]interface{}{
+								"library": "libdhcp_lease_cmds.so",
+							},
+						},
+					},
+				}),
+			},
+		})
+	}
+	return app
+}
+
+// Test sending lease4-del command to delete a DHCPv4 lease.
+func TestDeleteLease4(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+
+	deleted, err := DeleteLease(agents, createTestLeaseApp(), "", "192.0.2.1")
+	require.NoError(t, err)
+	require.True(t, deleted)
+
+	require.Len(t, agents.RecordedCommands, 1)
+	command := agents.RecordedCommands[0].(*keactrl.Command)
+	require.Equal(t, "lease4-del", command.Command)
+	require.Equal(t, []string{"dhcp4"}, command.Daemons)
+	require.Equal(t, map[string]interface{}{
+		"ip-address": "192.0.2.1",
+	}, command.Arguments)
+}
+
+// Test sending lease6-del command to delete a delegated prefix.
+func TestDeleteLease6(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+
+	deleted, err := DeleteLease(agents, createTestLeaseApp(), "IA_PD", "3000::")
+	require.NoError(t, err)
+	require.True(t, deleted)
+
+	require.Len(t, agents.RecordedCommands, 1)
+	command := agents.RecordedCommands[0].(*keactrl.Command)
+	require.Equal(t, "lease6-del", command.Command)
+	require.Equal(t, []string{"dhcp6"}, command.Daemons)
+	require.Equal(t, map[string]interface{}{
+		"ip-address": "3000::",
+		"type":       "IA_PD",
+	}, command.Arguments)
+}
+
+// Test that deleting a non-existing lease is not an error.
+func TestDeleteLeaseNotFound(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseDelEmpty, nil)
+
+	deleted, err := DeleteLease(agents, createTestLeaseApp(), "", "192.0.2.1")
+	require.NoError(t, err)
+	require.False(t, deleted)
+}
+
+// Test that an invalid IP address is rejected before sending a command.
+func TestDeleteLeaseInvalidAddress(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+
+	_, err := DeleteLease(agents, createTestLeaseApp(), "", "foo")
+	require.ErrorContains(t, err, "invalid lease IP address foo")
+	require.Empty(t, agents.RecordedCommands)
+}
+
+// Test that the lease is not deleted when the daemon lacks the lease_cmds
+// hooks library.
+func TestDeleteLeaseNoLeaseCmds(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+	app := createTestLeaseApp()
+	app.Daemons = app.Daemons[1:]
+
+	_, err := DeleteLease(agents, app, "", "192.0.2.1")
+	require.ErrorContains(t, err, "dhcp4 daemon in app kea does not use the lease_cmds hooks library")
+	require.Empty(t, agents.RecordedCommands)
+}
+
+// Test sending lease4-add command to add a DHCPv4 lease.
+func TestAddLease4(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+
+	err := AddLease(agents, createTestLeaseApp(), &keadata.Lease{
+		ClientID:      "01:02:03",
+		CLTT:          1000,
+		HWAddress:     "01:02:03:04:05:06",
+		Hostname:      "myhost.example.org",
+		IPAddress:     "192.0.2.1",
+		SubnetID:      1,
+		ValidLifetime: 3600,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, agents.RecordedCommands, 1)
+	command := agents.RecordedCommands[0].(*keactrl.Command)
+	require.Equal(t, "lease4-add", command.Command)
+	require.Equal(t, []string{"dhcp4"}, command.Daemons)
+	require.Equal(t, map[string]interface{}{
+		"client-id":  "01:02:03",
+		"expire":     uint64(4600),
+		"hw-address": "01:02:03:04:05:06",
+		"hostname":   "myhost.example.org",
+		"ip-address": "192.0.2.1",
+		"subnet-id":  uint32(1),
+		"valid-lft":  uint32(3600),
+	}, command.Arguments)
+}
+
+// Test that an error returned by Kea in response to a lease4-add
+// command is returned.
+func TestAddLeaseError(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(mockLeaseCommandError, nil)
+
+	err := AddLease(agents, createTestLeaseApp(), &keadata.Lease{
+		IPAddress: "192.0.2.1",
+	})
+	require.ErrorContains(t, err, "lease already exists")
+}
+
+// Test sending lease6-update command to update a DHCPv6 lease.
+func TestUpdateLease6(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+
+	err := UpdateLease(agents, createTestLeaseApp(), &keadata.Lease{
+		DUID:              "01:02:03:04",
+		IAID:              5,
+		IPAddress:         "2001:db8:1::1",
+		PreferredLifetime: 1800,
+		ValidLifetime:     3600,
+	}, true)
+	require.NoError(t, err)
+
+	require.Len(t, agents.RecordedCommands, 1)
+	command := agents.RecordedCommands[0].(*keactrl.Command)
+	require.Equal(t, "lease6-update", command.Command)
+	require.Equal(t, []string{"dhcp6"}, command.Daemons)
+	require.Equal(t, map[string]interface{}{
+		"duid":          "01:02:03:04",
+		"force-create":  true,
+		"iaid":          uint32(5),
+		"ip-address":    "2001:db8:1::1",
+		"preferred-lft": uint32(1800),
+		"type":          "IA_NA",
+		"valid-lft":     uint32(3600),
+	}, command.Arguments)
+}
+
+// Test sending lease4-wipe and lease6-wipe commands.
+func TestWipeLeases(t *testing.T) {
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+
+	err := WipeLeases(agents, createTestLeaseApp(), dbmodel.DaemonNameDHCPv4, 12)
+	require.NoError(t, err)
+	err = WipeLeases(agents, createTestLeaseApp(), dbmodel.DaemonNameDHCPv6, 13)
+	require.NoError(t, err)
+
+	require.Len(t, agents.RecordedCommands, 2)
+	command := agents.RecordedCommands[0].(*keactrl.Command)
+	require.Equal(t, "lease4-wipe", command.Command)
+	require.Equal(t, []string{"dhcp4"}, command.Daemons)
+	require.Equal(t, map[string]interface{}{"subnet-id": int64(12)}, command.Arguments)
+	command = agents.RecordedCommands[1].(*keactrl.Command)
+	require.Equal(t, "lease6-wipe", command.Command)
+	require.Equal(t, []string{"dhcp6"}, command.Daemons)
+	require.Equal(t, map[string]interface{}{"subnet-id": int64(13)}, command.Arguments)
+}
+
+// Test that the declined leases are found and deleted.
+func TestReclaimDeclinedLeases(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	machine := &dbmodel.Machine{
+		Address:   "machine",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	accessPoints := []*dbmodel.AccessPoint{}
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, true)
+	app := &dbmodel.App{
+		MachineID:    machine.ID,
+		Type:         dbmodel.AppTypeKea,
+		AccessPoints: accessPoints,
+		Daemons: []*dbmodel.Daemon{
+			{
+				Name: dbmodel.DaemonNameDHCPv4,
+				KeaDaemon: &dbmodel.KeaDaemon{
+					Config: dbmodel.NewKeaConfig(&map[string]interface{}{
+						"Dhcp4": map[string]interface{}{
+							"hooks-libraries": []interface{}{
+								map[string]interface{}{
+									"library": "libdhcp_lease_cmds.so",
+								},
+							},
+						},
+					}),
+				},
+			},
+			{
+				Name: dbmodel.DaemonNameDHCPv6,
+				KeaDaemon: &dbmodel.KeaDaemon{
+					Config: dbmodel.NewKeaConfig(&map[string]interface{}{
+						"Dhcp6": map[string]interface{}{
+							"hooks-libraries": []interface{}{
+								map[string]interface{}{
+									"library": "libdhcp_lease_cmds.so",
+								},
+							},
+						},
+					}),
+				},
+			},
+		},
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	// The first call returns the declined leases. The calls deleting
+	// the leases return the default success responses.
+	agents := agentcommtest.NewKeaFakeAgents(mockLeasesGetDeclined, func(int, []interface{}) {})
+
+	t.Run("other app", func(t *testing.T) {
+		leases, erredApps, err := ReclaimDeclinedLeases(db, agents, app.ID+1)
+		require.NoError(t, err)
+		require.Empty(t, erredApps)
+		require.Empty(t, leases)
+	})
+
+	agents = agentcommtest.NewKeaFakeAgents(mockLeasesGetDeclined, func(int, []interface{}) {})
+	leases, erredApps, err := ReclaimDeclinedLeases(db, agents, 0)
+	require.NoError(t, err)
+	require.Empty(t, erredApps)
+	require.Len(t, leases, 3)
+
+	// Two search commands and three delete commands.
+	require.Len(t, agents.RecordedCommands, 5)
+	require.Equal(t, "lease4-del", agents.RecordedCommands[2].GetCommand())
+	require.Equal(t, "lease6-del", agents.RecordedCommands[3].GetCommand())
+	require.Equal(t, "lease6-del", agents.RecordedCommands[4].GetCommand())
+	require.Equal(t, map[string]interface{}{
+		"ip-address": "2001:db8:2::1",
+		"type":       "IA_NA",
+	}, agents.RecordedCommands[3].(*keactrl.Command).Arguments)
+}
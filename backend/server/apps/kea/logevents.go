@@ -0,0 +1,187 @@
+package kea
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-pg/pg/v10"
+	log "github.com/sirupsen/logrus"
+	agentapi "isc.org/stork/api"
+	"isc.org/stork/server/agentcomm"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/eventcenter"
+)
+
+// Acknowledgement of the Kea log events received from an agent.
+type keaLogEventsAck struct {
+	generation   string
+	lastSequence int64
+}
+
+// Log events puller periodically fetches the notable messages found
+// by the agents in the Kea log files, e.g., the allocation failures and
+// the HA state transitions, and stores them as events.
+type LogEventsPuller struct {
+	*agentcomm.PeriodicPuller
+	EventCenter eventcenter.EventCenter
+	// Acknowledgements of the events received from the agents by the
+	// machine IDs.
+	acks map[int64]keaLogEventsAck
+}
+
+// Create a LogEventsPuller object that in background pulls the Kea
+// log events from the agents.
+func NewLogEventsPuller(db *pg.DB, agents agentcomm.ConnectedAgents, eventCenter eventcenter.EventCenter) (*LogEventsPuller, error) {
+	puller := &LogEventsPuller{
+		EventCenter: eventCenter,
+		acks:        make(map[int64]keaLogEventsAck),
+	}
+	periodicPuller, err := agentcomm.NewPeriodicPuller(db, agents, "Kea Log Events puller", "kea_log_events_puller_interval",
+		puller.pullEvents)
+	if err != nil {
+		return nil, err
+	}
+	puller.PeriodicPuller = periodicPuller
+	return puller, nil
+}
+
+// Shutdown LogEventsPuller. It stops goroutine that pulls the events.
+func (puller *LogEventsPuller) Shutdown() {
+	puller.PeriodicPuller.Shutdown()
+}
+
+// Pulls the Kea log events from all authorized machines running Kea. The
+// function returns last encountered error.
+func (puller *LogEventsPuller) pullEvents() error {
+	authorized := true
+	machines, err := dbmodel.GetAllMachines(puller.DB, &authorized)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	machinesOkCnt := 0
+	machinesCnt := 0
+	for i := range machines {
+		machine := &machines[i]
+		hasKea := false
+		for _, app := range machine.Apps {
+			if app.Type == dbmodel.AppTypeKea {
+				hasKea = true
+				break
+			}
+		}
+		if !hasKea {
+			continue
+		}
+		machinesCnt++
+		err := puller.pullEventsFromMachine(machine)
+		if err != nil {
+			lastErr = err
+			log.Errorf("Error occurred while getting Kea log events from machine %d: %+v", machine.ID, err)
+		} else {
+			machinesOkCnt++
+		}
+	}
+	log.Printf("Completed pulling Kea log events from machines: %d/%d succeeded", machinesOkCnt, machinesCnt)
+	return lastErr
+}
+
+// Pulls the Kea log events from the machine and passes them to the event
+// center. The events are acknowledged in the next request to the agent.
+func (puller *LogEventsPuller) pullEventsFromMachine(machine *dbmodel.Machine) error {
+	ack := puller.acks[machine.ID]
+
+	ctx := context.Background()
+	rsp, err := puller.Agents.GetKeaLogEvents(ctx, machine.Address, machine.AgentPort, ack.generation, ack.lastSequence)
+	if err != nil {
+		return err
+	}
+
+	// The log targets are needed to find the daemons which logged the
+	// messages.
+	apps, err := dbmodel.GetAppsByMachine(puller.DB, machine.ID)
+	if err != nil {
+		return err
+	}
+
+	if rsp.Generation != ack.generation {
+		ack = keaLogEventsAck{
+			generation: rsp.Generation,
+		}
+	}
+	for _, event := range rsp.Events {
+		// The events received before are returned again if the previous
+		// acknowledgement didn't reach the agent.
+		if event.Sequence <= ack.lastSequence {
+			continue
+		}
+		puller.EventCenter.AddEvent(createKeaLogEvent(machine, apps, event))
+		ack.lastSequence = event.Sequence
+	}
+	puller.acks[machine.ID] = ack
+	return nil
+}
+
+// Returns the Kea daemon which logged the message to the specified log
+// file. The daemon is primarily matched by its log targets. If none of
+// the daemons has the matching log target, e.g., the configuration has
+// not been fetched yet, the daemon is matched by name, provided that
+// there is exactly one such Kea daemon on the machine.
+func findKeaLogEventDaemon(apps []*dbmodel.App, event *agentapi.KeaLogEvent) *dbmodel.Daemon {
+	var candidates []*dbmodel.Daemon
+	for _, app := range apps {
+		if app.Type != dbmodel.AppTypeKea {
+			continue
+		}
+		for _, daemon := range app.Daemons {
+			if event.Daemon != "" && daemon.Name != event.Daemon {
+				continue
+			}
+			daemon.App = app
+			for _, target := range daemon.LogTargets {
+				if target.Output == event.Path {
+					return daemon
+				}
+			}
+			if event.Daemon != "" {
+				candidates = append(candidates, daemon)
+			}
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	return nil
+}
+
+// Creates the event for the Kea log message. The event level is derived
+// from the message severity. The event relates to the daemon which logged
+// the message or to the machine if the daemon is not found. The message
+// text is included in the event details.
+func createKeaLogEvent(machine *dbmodel.Machine, apps []*dbmodel.App, event *agentapi.KeaLogEvent) *dbmodel.Event {
+	level := dbmodel.EvInfo
+	switch event.Severity {
+	case "WARN":
+		level = dbmodel.EvWarning
+	case "ERROR", "FATAL":
+		level = dbmodel.EvError
+	}
+
+	details := fmt.Sprintf("%s %s [%s] %s %s\nLog file: %s", event.Timestamp, event.Severity,
+		event.Logger, event.MessageID, event.Text, event.Path)
+
+	var text string
+	var object any
+	if daemon := findKeaLogEventDaemon(apps, event); daemon != nil {
+		text = fmt.Sprintf("{daemon} logged %s", event.MessageID)
+		object = daemon
+	} else {
+		text = fmt.Sprintf("Kea on {machine} logged %s", event.MessageID)
+		object = machine
+	}
+	if event.Suppressed > 0 {
+		text += fmt.Sprintf(" (%d similar messages suppressed)", event.Suppressed)
+	}
+	return eventcenter.CreateEvent(level, text, object, details)
+}
@@ -0,0 +1,227 @@
+package kea
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	agentapi "isc.org/stork/api"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Returns two Kea apps with the DHCPv4 daemons logging to different files.
+func getLogEventsTestApps() []*dbmodel.App {
+	return []*dbmodel.App{
+		{
+			ID:        1,
+			MachineID: 1,
+			Type:      dbmodel.AppTypeKea,
+			Daemons: []*dbmodel.Daemon{
+				{
+					ID:    1,
+					AppID: 1,
+					Name:  "dhcp4",
+					LogTargets: []*dbmodel.LogTarget{
+						{Output: "/var/log/kea-dhcp4-1.log"},
+					},
+				},
+				{
+					ID:    2,
+					AppID: 1,
+					Name:  "dhcp6",
+					LogTargets: []*dbmodel.LogTarget{
+						{Output: "/var/log/kea-dhcp6.log"},
+					},
+				},
+			},
+		},
+		{
+			ID:        2,
+			MachineID: 1,
+			Type:      dbmodel.AppTypeKea,
+			Daemons: []*dbmodel.Daemon{
+				{
+					ID:    3,
+					AppID: 2,
+					Name:  "dhcp4",
+					LogTargets: []*dbmodel.LogTarget{
+						{Output: "/var/log/kea-dhcp4-2.log"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Test that the daemon which logged the message is found.
+func TestFindKeaLogEventDaemon(t *testing.T) {
+	apps := getLogEventsTestApps()
+
+	// Matched by the log target.
+	daemon := findKeaLogEventDaemon(apps, &agentapi.KeaLogEvent{
+		Daemon: "dhcp4",
+		Path:   "/var/log/kea-dhcp4-2.log",
+	})
+	require.NotNil(t, daemon)
+	require.EqualValues(t, 3, daemon.ID)
+	require.NotNil(t, daemon.App)
+	require.EqualValues(t, 2, daemon.App.ID)
+
+	// Matched by the name.
+	daemon = findKeaLogEventDaemon(apps, &agentapi.KeaLogEvent{
+		Daemon: "dhcp6",
+		Path:   "/var/log/kea.log",
+	})
+	require.NotNil(t, daemon)
+	require.EqualValues(t, 2, daemon.ID)
+
+	// Ambiguous name.
+	daemon = findKeaLogEventDaemon(apps, &agentapi.KeaLogEvent{
+		Daemon: "dhcp4",
+		Path:   "/var/log/kea.log",
+	})
+	require.Nil(t, daemon)
+
+	// Unknown daemon.
+	daemon = findKeaLogEventDaemon(apps, &agentapi.KeaLogEvent{
+		Path: "/var/log/kea-dhcp6.log",
+	})
+	require.NotNil(t, daemon)
+	require.EqualValues(t, 2, daemon.ID)
+
+	daemon = findKeaLogEventDaemon(apps, &agentapi.KeaLogEvent{
+		Path: "/var/log/kea.log",
+	})
+	require.Nil(t, daemon)
+}
+
+// Test creating the events for the Kea log messages.
+func TestCreateKeaLogEvent(t *testing.T) {
+	machine := &dbmodel.Machine{
+		ID:      1,
+		Address: "192.0.2.1",
+	}
+	apps := getLogEventsTestApps()
+
+	event := createKeaLogEvent(machine, apps, &agentapi.KeaLogEvent{
+		Path:       "/var/log/kea-dhcp4-1.log",
+		Timestamp:  "2022-05-10 12:00:00.123",
+		Severity:   "WARN",
+		Logger:     "kea-dhcp4.alloc-engine",
+		Daemon:     "dhcp4",
+		MessageID:  "ALLOC_ENGINE_V4_ALLOC_FAIL_SUBNET",
+		Text:       "failed to allocate an IPv4 lease in the subnet 192.0.2.0/24",
+		Suppressed: 3,
+	})
+	require.Equal(t, dbmodel.EvWarning, event.Level)
+	require.Contains(t, event.Text, "<daemon id=\"1\" name=\"dhcp4\" appId=\"1\" appType=\"kea\">")
+	require.Contains(t, event.Text, "logged ALLOC_ENGINE_V4_ALLOC_FAIL_SUBNET (3 similar messages suppressed)")
+	require.EqualValues(t, 1, event.Relations.DaemonID)
+	require.EqualValues(t, 1, event.Relations.AppID)
+	require.EqualValues(t, 1, event.Relations.MachineID)
+	require.Equal(t, "2022-05-10 12:00:00.123 WARN [kea-dhcp4.alloc-engine] ALLOC_ENGINE_V4_ALLOC_FAIL_SUBNET failed to allocate an IPv4 lease in the subnet 192.0.2.0/24\nLog file: /var/log/kea-dhcp4-1.log", event.Details)
+
+	// The daemon is not found.
+	event = createKeaLogEvent(machine, apps, &agentapi.KeaLogEvent{
+		Path:      "/var/log/kea.log",
+		Severity:  "ERROR",
+		Daemon:    "dhcp4",
+		MessageID: "DHCPSRV_MEMFILE_LFC_EXECUTE_FAILED",
+	})
+	require.Equal(t, dbmodel.EvError, event.Level)
+	require.Contains(t, event.Text, "Kea on <machine")
+	require.NotContains(t, event.Text, "suppressed")
+	require.Zero(t, event.Relations.DaemonID)
+	require.EqualValues(t, 1, event.Relations.MachineID)
+
+	event = createKeaLogEvent(machine, apps, &agentapi.KeaLogEvent{
+		Path:      "/var/log/kea-dhcp6.log",
+		Severity:  "INFO",
+		Daemon:    "dhcp6",
+		MessageID: "HA_STATE_TRANSITION",
+	})
+	require.Equal(t, dbmodel.EvInfo, event.Level)
+	require.EqualValues(t, 2, event.Relations.DaemonID)
+}
+
+// Test that the Kea log events are pulled from the agents and passed to
+// the event center.
+func TestLogEventsPullerPullEvents(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	err := dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	machine := &dbmodel.Machine{
+		Address:    "localhost",
+		AgentPort:  8080,
+		Authorized: true,
+	}
+	err = dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	app := &dbmodel.App{
+		MachineID: machine.ID,
+		Type:      dbmodel.AppTypeKea,
+		AccessPoints: []*dbmodel.AccessPoint{
+			{
+				Type:    dbmodel.AccessPointControl,
+				Address: "localhost",
+				Port:    8000,
+			},
+		},
+		Daemons: []*dbmodel.Daemon{
+			{
+				Active: true,
+				Name:   "dhcp4",
+				KeaDaemon: &dbmodel.KeaDaemon{
+					KeaDHCPDaemon: &dbmodel.KeaDHCPDaemon{},
+				},
+			},
+		},
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	fa.KeaLogEvents = []*agentapi.KeaLogEvent{
+		{
+			Sequence:  1,
+			Path:      "/var/log/kea-dhcp4.log",
+			Severity:  "INFO",
+			Daemon:    "dhcp4",
+			MessageID: "HA_STATE_TRANSITION",
+		},
+		{
+			Sequence:  2,
+			Path:      "/var/log/kea-dhcp4.log",
+			Severity:  "WARN",
+			Daemon:    "dhcp4",
+			MessageID: "ALLOC_ENGINE_V4_ALLOC_FAIL",
+		},
+	}
+	fec := &storktest.FakeEventCenter{}
+
+	puller, err := NewLogEventsPuller(db, fa, fec)
+	require.NoError(t, err)
+	defer puller.Shutdown()
+
+	err = puller.pullEvents()
+	require.NoError(t, err)
+	require.Empty(t, fa.RecordedKeaLogEventsGeneration)
+	require.Zero(t, fa.RecordedKeaLogEventsLastSequence)
+	require.Len(t, fec.Events, 2)
+	require.EqualValues(t, app.Daemons[0].ID, fec.Events[0].Relations.DaemonID)
+	require.Equal(t, dbmodel.EvWarning, fec.Events[1].Level)
+
+	// The events are acknowledged in the next request. The events
+	// returned again are not duplicated.
+	err = puller.pullEvents()
+	require.NoError(t, err)
+	require.Equal(t, "1", fa.RecordedKeaLogEventsGeneration)
+	require.EqualValues(t, 2, fa.RecordedKeaLogEventsLastSequence)
+	require.Len(t, fec.Events, 2)
+}
@@ -6,22 +6,40 @@ import (
 	dbmodel "isc.org/stork/server/database/model"
 )
 
-// Checks if the specified Kea daemon belongs to a given HA service.
-// This is done by matching the HA configuration of the given daemon
-// with the HA configurations of the other daemons already associated
-// with the service. In particular, the HA mode must match and for
-// the peers' configurations the server names, URLs and roles must
-// match.
-func daemonBelongsToHAService(daemon *dbmodel.Daemon, service *dbmodel.Service) bool {
+// Returns the valid HA relationships configured for the given Kea daemon.
+// Kea 2.4.0 and later support multiple relationships per server, e.g.
+// in the hub-and-spoke configuration.
+func getHARelationships(daemon *dbmodel.Daemon) []keaconfig.HA {
+	if daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+		return nil
+	}
+	return daemon.KeaDaemon.Config.GetHARelationships()
+}
+
+// Checks if the specified Kea daemon's HA relationship belongs to a given HA
+// service. This is done by matching the relationship configuration with the
+// HA configurations of the other daemons already associated with the service.
+// In particular, the relationship names and the HA modes must match and for
+// the peers' configurations the server names, URLs and roles must match.
+// If the service has been associated with a relationship, the relationship
+// names must match too.
+func daemonBelongsToHAService(daemon *dbmodel.Daemon, relationship *keaconfig.HA, service *dbmodel.Service) bool {
 	// If there are no daemons associated with the service, there is
 	// nothing we can compare the daemon's configuration with.
 	if len(service.Daemons) == 0 {
 		return false
 	}
 
-	// Get the daemon's HA configuration and check if it is set.
-	_, daemonConfigHA, ok := daemon.KeaDaemon.Config.GetHookLibraries().GetHAHookLibrary()
-	if !ok || !daemonConfigHA.GetFirst().IsValid() {
+	// Check if the relationship configuration is set.
+	if relationship == nil || !relationship.IsValid() {
+		return false
+	}
+	relationshipName := relationship.GetRelationshipName()
+
+	// The services detected by the earlier Stork versions are not associated
+	// with any relationship.
+	if service.HAService != nil && len(service.HAService.Relationship) > 0 &&
+		service.HAService.Relationship != relationshipName {
 		return false
 	}
 
@@ -39,21 +57,28 @@ func daemonBelongsToHAService(daemon *dbmodel.Daemon, service *dbmodel.Service)
 			continue
 		}
 
-		// Get the HA configuration of the daemon belonging to the service.
-		var serviceDaemonConfigHA keaconfig.HALibraryParams
-		_, serviceDaemonConfigHA, ok = sd.KeaDaemon.Config.GetHookLibraries().GetHAHookLibrary()
-		if !ok || !serviceDaemonConfigHA.GetFirst().IsValid() || (*daemonConfigHA.GetFirst().Mode != *serviceDaemonConfigHA.GetFirst().Mode) {
+		// Get the configuration of the same relationship in the daemon belonging
+		// to the service.
+		var serviceRelationship *keaconfig.HA
+		serviceRelationships := getHARelationships(sd)
+		for i := range serviceRelationships {
+			if serviceRelationships[i].GetRelationshipName() == relationshipName {
+				serviceRelationship = &serviceRelationships[i]
+				break
+			}
+		}
+		if serviceRelationship == nil || (*relationship.Mode != *serviceRelationship.Mode) {
 			// There is something wrong with the service or the mode is not matching.
 			// This service is not matching.
 			return false
 		}
 
 		// Now we have to compare the peers' configurations.
-		for _, servicePeer := range serviceDaemonConfigHA.GetFirst().Peers {
+		for _, servicePeer := range serviceRelationship.Peers {
 			// For the given peer in the service let's find the corresponding one
 			// specified in the daemons's configuration.
-			ok = false
-			for _, daemonPeer := range daemonConfigHA.GetFirst().Peers {
+			ok := false
+			for _, daemonPeer := range relationship.Peers {
 				if (*daemonPeer.Name == *servicePeer.Name) &&
 					(*daemonPeer.URL == *servicePeer.URL) &&
 					(*daemonPeer.Role == *servicePeer.Role) {
@@ -74,8 +99,9 @@ func daemonBelongsToHAService(daemon *dbmodel.Daemon, service *dbmodel.Service)
 }
 
 // Parses High Availability configuration of the given Kea daemon and matches that
-// configuration with existing services. If no matching service is found, it
-// is created and returned. This function neither creates nor updates any
+// configuration with existing services. Each HA relationship configured for the
+// daemon is matched with a distinct service. If no matching service is found,
+// it is created and returned. This function neither creates nor updates any
 // services in the database. It is up to the caller of this function to
 // perform such updates based on the returned services by the function.
 // It is possible to check whether the returned service is a new instance
@@ -92,40 +118,33 @@ func DetectHAServices(dbi dbops.DBI, daemon *dbmodel.Daemon) (services []dbmodel
 		return services
 	}
 
-	// Check if the configuration contains any HA configuration.
-	if _, params, ok := daemon.KeaDaemon.Config.GetHookLibraries().GetHAHookLibrary(); ok {
-		// Make sure that the required parameters are set.
-		if !params.GetFirst().IsValid() {
-			return services
-		}
+	relationships := getHARelationships(daemon)
+	if len(relationships) == 0 {
+		return services
+	}
 
-		// HA configuration must contain this-server-name parameter which indicates
-		// which of the peers' configurations belongs to it. Let's iterate over
-		// the configured peers to identify the one.
-		index := -1
-		for i, p := range params.GetFirst().Peers {
-			// this-server-name matches one of the peers. Remember which one.
-			if *p.Name == *params.GetFirst().ThisServerName {
-				index = i
-			}
-		}
+	dbServices, _ := dbmodel.GetDetailedAllServices(dbi)
+	matched := make(map[int64]bool)
 
-		// This server not found.
-		if index < 0 {
-			return services
-		}
+	for i := range relationships {
+		relationship := &relationships[i]
 
-		// This server configuration found.
-		thisServer := params.GetFirst().Peers[index]
+		// HA configuration must contain this-server-name parameter which indicates
+		// which of the peers' configurations belongs to it.
+		thisServer := relationship.GetThisServer()
+		if thisServer == nil {
+			continue
+		}
 
-		// Next, check if there are any existing services matching this daemon.
-		index = -1
-		dbServices, _ := dbmodel.GetDetailedAllServices(dbi)
-		for i, service := range dbServices {
+		// Next, check if there are any existing services matching this relationship.
+		// The service matched by one relationship can't be matched by another.
+		index := -1
+		for j, service := range dbServices {
 			if (service.HAService != nil) &&
 				(service.HAService.HAType == daemon.Name) &&
-				daemonBelongsToHAService(daemon, &dbServices[i]) {
-				index = i
+				!matched[service.ID] &&
+				daemonBelongsToHAService(daemon, relationship, &dbServices[j]) {
+				index = j
 				break
 			}
 		}
@@ -134,6 +153,7 @@ func DetectHAServices(dbi dbops.DBI, daemon *dbmodel.Daemon) (services []dbmodel
 		if index >= 0 {
 			// Service found.
 			service = dbServices[index]
+			matched[service.ID] = true
 		} else {
 			// No service found in the db, so let's create one.
 			service = dbmodel.Service{
@@ -147,8 +167,11 @@ func DetectHAServices(dbi dbops.DBI, daemon *dbmodel.Daemon) (services []dbmodel
 
 		// Set HA mode, if not set yet.
 		if len(service.HAService.HAMode) == 0 {
-			service.HAService.HAMode = *params.GetFirst().Mode
+			service.HAService.HAMode = *relationship.Mode
 		}
+		// Associate the service with the relationship. The services detected
+		// by the earlier Stork versions are associated here too.
+		service.HAService.Relationship = relationship.GetRelationshipName()
 
 		// Depending on the role of this server we will be setting different column
 		// of the HA service column.
@@ -392,6 +392,130 @@ func TestDetectHAServices(t *testing.T) {
 	require.Len(t, services[0].Daemons, 3)
 }
 
+// Returns the DHCPv4 server configuration with the hot-standby relationships
+// between the hub server and the branch servers. The relationships are
+// created for each branch name. The hub is a standby server in each of them
+// and listens on a different URL in each relationship.
+func getHubAndSpokeTestConfig(thisServerName string, branchNames ...string) *dbmodel.KeaConfig {
+	var relationships []any
+	for _, branchName := range branchNames {
+		relationships = append(relationships, map[string]any{
+			"this-server-name": thisServerName,
+			"mode":             "hot-standby",
+			"peers": []any{
+				map[string]any{
+					"name": branchName,
+					"url":  fmt.Sprintf("http://%s.example.org:8000", branchName),
+					"role": "primary",
+				},
+				map[string]any{
+					"name": "hub",
+					"url":  fmt.Sprintf("http://hub.example.org:8000/%s", branchName),
+					"role": "standby",
+				},
+			},
+		})
+	}
+	return dbmodel.NewKeaConfig(&map[string]any{
+		"Dhcp4": map[string]any{
+			"hooks-libraries": []any{
+				map[string]any{
+					"library": "libdhcp_ha.so",
+					"parameters": map[string]any{
+						"high-availability": relationships,
+					},
+				},
+			},
+		},
+	})
+}
+
+// Test that each HA relationship of the hub server is detected as a distinct
+// service and that the branch servers are associated with the services
+// representing their relationships.
+func TestDetectHAServicesHubAndSpoke(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	addApp := func(address string, config *dbmodel.KeaConfig) *dbmodel.App {
+		m := &dbmodel.Machine{
+			Address:   address,
+			AgentPort: 8080,
+		}
+		err := dbmodel.AddMachine(db, m)
+		require.NoError(t, err)
+
+		var accessPoints []*dbmodel.AccessPoint
+		accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, address, "", 8000, false)
+		app := &dbmodel.App{
+			MachineID:    m.ID,
+			Type:         dbmodel.AppTypeKea,
+			AccessPoints: accessPoints,
+			Daemons: []*dbmodel.Daemon{
+				{
+					Name: "dhcp4",
+					KeaDaemon: &dbmodel.KeaDaemon{
+						Config:        config,
+						KeaDHCPDaemon: &dbmodel.KeaDHCPDaemon{},
+					},
+				},
+			},
+		}
+		_, err = dbmodel.AddApp(db, app)
+		require.NoError(t, err)
+		return app
+	}
+
+	// The hub belongs to two relationships.
+	hub := addApp("192.0.2.100", getHubAndSpokeTestConfig("hub", "branch1", "branch2"))
+	services := DetectHAServices(db, hub.Daemons[0])
+	require.Len(t, services, 2)
+	for i, relationship := range []string{"branch1,hub", "branch2,hub"} {
+		require.True(t, services[i].IsNew())
+		require.Equal(t, relationship, services[i].HAService.Relationship)
+		require.Equal(t, "hot-standby", services[i].HAService.HAMode)
+		require.Zero(t, services[i].HAService.PrimaryID)
+		require.Equal(t, hub.Daemons[0].ID, services[i].HAService.SecondaryID)
+	}
+	err := dbmodel.CommitServicesIntoDB(db, services, hub.Daemons[0])
+	require.NoError(t, err)
+
+	// The second branch must be associated with the service representing
+	// its relationship rather than the first service comprising the hub.
+	branch2 := addApp("192.0.2.2", getHubAndSpokeTestConfig("branch2", "branch2"))
+	services = DetectHAServices(db, branch2.Daemons[0])
+	require.Len(t, services, 1)
+	require.False(t, services[0].IsNew())
+	require.Equal(t, "branch2,hub", services[0].HAService.Relationship)
+	require.Equal(t, branch2.Daemons[0].ID, services[0].HAService.PrimaryID)
+	require.Equal(t, hub.Daemons[0].ID, services[0].HAService.SecondaryID)
+	err = dbmodel.CommitServicesIntoDB(db, services, branch2.Daemons[0])
+	require.NoError(t, err)
+
+	branch1 := addApp("192.0.2.1", getHubAndSpokeTestConfig("branch1", "branch1"))
+	services = DetectHAServices(db, branch1.Daemons[0])
+	require.Len(t, services, 1)
+	require.False(t, services[0].IsNew())
+	require.Equal(t, "branch1,hub", services[0].HAService.Relationship)
+	require.Equal(t, branch1.Daemons[0].ID, services[0].HAService.PrimaryID)
+	err = dbmodel.CommitServicesIntoDB(db, services, branch1.Daemons[0])
+	require.NoError(t, err)
+
+	// Detecting the services for the hub again must not create new services.
+	services = DetectHAServices(db, hub.Daemons[0])
+	require.Len(t, services, 2)
+	require.False(t, services[0].IsNew())
+	require.False(t, services[1].IsNew())
+	require.NotEqual(t, services[0].ID, services[1].ID)
+
+	dbServices, err := dbmodel.GetDetailedAllServices(db)
+	require.NoError(t, err)
+	require.Len(t, dbServices, 2)
+	for _, service := range dbServices {
+		require.Len(t, service.Daemons, 2)
+	}
+}
+
 // Test that a daemon doesn't belong to a blank service , i.e. a
 // service that comprises no daemons.
 func TestAppBelongsToHAServiceBlankService(t *testing.T) {
@@ -425,7 +549,9 @@ func TestAppBelongsToHAServiceBlankService(t *testing.T) {
 	// The daemon doesn't belong to the service because the service includes
 	// no meaningful information to make such determination. In that case
 	// it is up to the administrator to explicitly add the daemon to the service.
-	require.False(t, daemonBelongsToHAService(app.Daemons[0], service))
+	relationships := getHARelationships(app.Daemons[0])
+	require.Len(t, relationships, 1)
+	require.False(t, daemonBelongsToHAService(app.Daemons[0], &relationships[0], service))
 }
 
 // Test that a daemon can be dissociated with all services it belongs to.
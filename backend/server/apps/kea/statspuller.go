@@ -2,15 +2,23 @@ package kea
 
 import (
 	"context"
+	"encoding/json"
 	"math/big"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-pg/pg/v10"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	keaconfig "isc.org/stork/appcfg/kea"
 	keactrl "isc.org/stork/appctrl/kea"
 	"isc.org/stork/server/agentcomm"
+	dbops "isc.org/stork/server/database"
 	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/eventcenter"
+	storkutil "isc.org/stork/util"
 )
 
 // Statistics puller is responsible for fetching the data using the Kea
@@ -18,12 +26,17 @@ import (
 type StatsPuller struct {
 	*agentcomm.PeriodicPuller
 	*RpsWorker
+	EventCenter eventcenter.EventCenter
 }
 
 // Create a StatsPuller object that in background pulls Kea stats about leases.
 // Beneath it spawns a goroutine that pulls stats periodically from Kea apps (that are stored in database).
-func NewStatsPuller(db *pg.DB, agents agentcomm.ConnectedAgents) (*StatsPuller, error) {
-	statsPuller := &StatsPuller{}
+// The event center is used to raise the events when the subnet utilizations
+// cross the alert thresholds.
+func NewStatsPuller(db *pg.DB, agents agentcomm.ConnectedAgents, eventCenter eventcenter.EventCenter) (*StatsPuller, error) {
+	statsPuller := &StatsPuller{
+		EventCenter: eventCenter,
+	}
 	periodicPuller, err := agentcomm.NewPeriodicPuller(db, agents, "Kea Stats puller", "kea_stats_puller_interval",
 		statsPuller.pullStats)
 	if err != nil {
@@ -49,6 +62,11 @@ func (statsPuller *StatsPuller) Shutdown() {
 // Pull stats periodically for all Kea apps which Stork is monitoring. The function returns
 // last encountered error.
 func (statsPuller *StatsPuller) pullStats() error {
+	// the pools with the statistics collected before this time are
+	// not sampled because their statistics are outdated; the time is
+	// truncated because the database stores it with lower precision
+	pulledAt := storkutil.UTCNow().Truncate(time.Second)
+
 	// get list of all kea apps from database
 	dbApps, err := dbmodel.GetAppsByType(statsPuller.DB, dbmodel.AppTypeKea)
 	if err != nil {
@@ -122,6 +140,9 @@ func (statsPuller *StatsPuller) pullStats() error {
 	// go through all Subnets and:
 	// 1) estimate utilization per Subnet and per SharedNetwork
 	// 2) estimate global stats
+	// 3) sample the utilization history
+	sampledAt := time.Now().UTC()
+	samples := []*dbmodel.UtilizationSample{}
 	for _, sn := range subnets {
 		su := counter.add(sn)
 		err = sn.UpdateStatistics(
@@ -135,6 +156,11 @@ func (statsPuller *StatsPuller) pullStats() error {
 				su.GetAddressUtilization(), su.GetDelegatedPrefixUtilization(), sn.ID, err)
 			continue
 		}
+
+		sample := dbmodel.NewUtilizationSample(sampledAt, su.GetAddressUtilization(),
+			su.GetDelegatedPrefixUtilization(), su.GetStatistics())
+		sample.SubnetID = sn.ID
+		samples = append(samples, sample)
 	}
 
 	// shared network utilization
@@ -149,6 +175,36 @@ func (statsPuller *StatsPuller) pullStats() error {
 				u.GetAddressUtilization(), u.GetDelegatedPrefixUtilization(), sharedNetworkID, err)
 			continue
 		}
+
+		sample := dbmodel.NewUtilizationSample(sampledAt, u.GetAddressUtilization(),
+			u.GetDelegatedPrefixUtilization(), u.GetStatistics())
+		sample.SharedNetworkID = sharedNetworkID
+		samples = append(samples, sample)
+	}
+
+	// pool utilization
+	addressPools, err := dbmodel.GetAddressPoolsWithStats(statsPuller.DB)
+	if err != nil {
+		return err
+	}
+	prefixPools, err := dbmodel.GetPrefixPoolsWithStats(statsPuller.DB)
+	if err != nil {
+		return err
+	}
+	samples = append(samples, getPoolUtilizationSamples(addressPools, prefixPools, counter.excludedDaemons, pulledAt, sampledAt)...)
+
+	// store the utilization history
+	err = storeUtilizationHistory(statsPuller.DB, samples, sampledAt)
+	if err != nil {
+		lastErr = err
+		log.Errorf("Cannot store utilization history: %s", err)
+	}
+
+	// forecast the exhaustion using the utilization history
+	err = evaluateExhaustionForecasts(statsPuller.DB, statsPuller.EventCenter, subnets, addressPools, prefixPools, sampledAt)
+	if err != nil {
+		lastErr = err
+		log.Errorf("Cannot forecast exhaustion: %s", err)
 	}
 
 	// global stats to collect
@@ -169,9 +225,43 @@ func (statsPuller *StatsPuller) pullStats() error {
 		lastErr = err
 	}
 
+	// raise the alerts for the subnets and shared networks nearing
+	// exhaustion
+	err = evaluateUtilizationAlerts(statsPuller.DB, statsPuller.EventCenter, subnets)
+	if err != nil {
+		lastErr = err
+	}
+
 	return lastErr
 }
 
+// Returns the utilization samples of the pools with the statistics
+// collected at or after the specified time. The pools of the excluded
+// (passive HA) daemons are not sampled because their statistics duplicate
+// the statistics of the active daemons.
+func getPoolUtilizationSamples(addressPools []*dbmodel.AddressPool, prefixPools []*dbmodel.PrefixPool, excludedDaemons map[int64]bool, pulledAt, sampledAt time.Time) (samples []*dbmodel.UtilizationSample) {
+	isSampled := func(localSubnet *dbmodel.LocalSubnet, statsCollectedAt time.Time) bool {
+		return localSubnet != nil && !excludedDaemons[localSubnet.DaemonID] && !statsCollectedAt.Before(pulledAt)
+	}
+	for _, pool := range addressPools {
+		if !isSampled(pool.LocalSubnet, pool.StatsCollectedAt) {
+			continue
+		}
+		sample := dbmodel.NewUtilizationSample(sampledAt, float64(pool.Utilization)/1000, 0, pool.Stats)
+		sample.AddressPoolID = pool.ID
+		samples = append(samples, sample)
+	}
+	for _, pool := range prefixPools {
+		if !isSampled(pool.LocalSubnet, pool.StatsCollectedAt) {
+			continue
+		}
+		sample := dbmodel.NewUtilizationSample(sampledAt, 0, float64(pool.Utilization)/1000, pool.Stats)
+		sample.PrefixPoolID = pool.ID
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
 // Part of response for stat-lease4-get and stat-lease6-get commands.
 type ResultSetInStatLeaseGet struct {
 	Columns []string
@@ -190,6 +280,28 @@ type StatLeaseGetResponse struct {
 	Arguments *StatLeaseGetArgs `json:"arguments,omitempty"`
 }
 
+// Represents unmarshaled response from Kea daemon to statistic-get-all
+// command. Each statistic is mapped to the list of the value and timestamp
+// pairs, the most recent first. The values are kept raw to avoid losing the
+// precision of the big numbers.
+type StatisticGetAllResponse struct {
+	keactrl.ResponseHeader
+	Arguments map[string][][]json.RawMessage `json:"arguments,omitempty"`
+}
+
+// Matches the names of the pool statistics returned by Kea 2.3 and later,
+// e.g., subnet[1].pool[0].assigned-addresses or
+// subnet[1].pd-pool[0].assigned-pds.
+var poolStatisticNamePattern = regexp.MustCompile(`^subnet\[(\d+)\]\.(pool|pd-pool)\[(\d+)\]\.(.+)$`)
+
+// A key identifying the statistics of a pool in the local subnet. The
+// index is the position of the pool in the subnet configuration.
+type poolStatsKey struct {
+	LocalSubnetID int64
+	PrefixPool    bool
+	Index         int
+}
+
 // A key that is used in map that is mapping from (local subnet id, inet family) to LocalSubnet struct.
 type localSubnetKey struct {
 	LocalSubnetID int64
@@ -258,6 +370,155 @@ func (statsPuller *StatsPuller) storeDaemonStats(response interface{}, subnetsMa
 	return lastErr
 }
 
+// Parses the statistic value returned by Kea. The values not fitting
+// into uint64 are returned as big integers.
+func parseStatisticValue(raw json.RawMessage) (any, error) {
+	text := string(raw)
+	if value, err := strconv.ParseUint(text, 10, 64); err == nil {
+		return value, nil
+	}
+	if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return value, nil
+	}
+	if value, ok := new(big.Int).SetString(text, 10); ok {
+		return value, nil
+	}
+	return nil, errors.Errorf("invalid statistic value %s", text)
+}
+
+// Extracts the pool statistics from the statistic-get-all response
+// arguments and groups them by the pools. Only the counters of the
+// addresses and delegated prefixes are returned. The declined addresses
+// in the DHCPv6 pools are returned as declined-nas for consistency with
+// the subnet statistics.
+func getPoolStats(arguments map[string][][]json.RawMessage, family int) map[poolStatsKey]dbmodel.SubnetStats {
+	poolStats := make(map[poolStatsKey]dbmodel.SubnetStats)
+	for name, samples := range arguments {
+		match := poolStatisticNamePattern.FindStringSubmatch(name)
+		if match == nil || len(samples) == 0 || len(samples[0]) == 0 {
+			continue
+		}
+		statName := match[4]
+		switch statName {
+		case "total-addresses", "assigned-addresses", "declined-addresses",
+			"total-nas", "assigned-nas", "declined-nas",
+			"total-pds", "assigned-pds":
+		default:
+			continue
+		}
+		if family == 6 && statName == "declined-addresses" {
+			statName = "declined-nas"
+		}
+		lsnID, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		index, err := strconv.Atoi(match[3])
+		if err != nil {
+			continue
+		}
+		value, err := parseStatisticValue(samples[0][0])
+		if err != nil {
+			log.Warnf("Skipping pool statistic %s: %s", name, err)
+			continue
+		}
+		key := poolStatsKey{lsnID, match[2] == "pd-pool", index}
+		if _, ok := poolStats[key]; !ok {
+			poolStats[key] = dbmodel.SubnetStats{}
+		}
+		poolStats[key][statName] = value
+	}
+	return poolStats
+}
+
+// An interface to the address and delegated prefix pools storing their
+// statistics in the database.
+type poolStatsUpdater interface {
+	UpdateStats(dbi dbops.DBI, stats dbmodel.SubnetStats) error
+}
+
+// Returns the pool of the local subnet identified by the pool statistics
+// key. The key holds the position of the pool in the subnet configuration
+// of the daemon. The pools stored in the database may be ordered
+// differently, e.g., when a pool has been inserted before the existing
+// ones, so the pool is matched by its boundaries in the configuration.
+// It returns nil if the pool is not found.
+func findLocalSubnetPool(configSubnets map[int64]keaconfig.Subnet, sn *dbmodel.LocalSubnet, key poolStatsKey) poolStatsUpdater {
+	configSubnet, ok := configSubnets[key.LocalSubnetID]
+	if !ok || key.Index < 0 {
+		return nil
+	}
+	if key.PrefixPool {
+		configPools := configSubnet.GetPDPools()
+		if key.Index >= len(configPools) {
+			return nil
+		}
+		prefix := configPools[key.Index].GetCanonicalPrefix()
+		for i := range sn.PrefixPools {
+			if sn.PrefixPools[i].Prefix == prefix && sn.PrefixPools[i].DelegatedLen == configPools[key.Index].DelegatedLen {
+				return &sn.PrefixPools[i]
+			}
+		}
+		return nil
+	}
+	configPools := configSubnet.GetPools()
+	if key.Index >= len(configPools) {
+		return nil
+	}
+	lb, ub, err := configPools[key.Index].GetBoundaries()
+	if err != nil {
+		return nil
+	}
+	configPool := dbmodel.NewAddressPool(lb, ub)
+	for i := range sn.AddressPools {
+		if sn.AddressPools[i].HasEqualData(configPool) {
+			return &sn.AddressPools[i]
+		}
+	}
+	return nil
+}
+
+// Process pool stats results from the given statistic-get-all response for
+// given daemon. The statistics refer to the pools by their positions in the
+// subnet configuration, so the pools are matched with the statistics using
+// the daemon's configuration. The statistics of the pools unknown to Stork
+// are ignored.
+func (statsPuller *StatsPuller) storeDaemonPoolStats(response interface{}, subnetsMap map[localSubnetKey]*dbmodel.LocalSubnet, dbApp *dbmodel.App, daemon *dbmodel.Daemon, family int) error {
+	statsResp, ok := response.(*[]StatisticGetAllResponse)
+	if !ok || len(*statsResp) == 0 {
+		return errors.Errorf("response is empty: %+v", response)
+	}
+	sr := (*statsResp)[0]
+	if sr.Result != keactrl.ResponseSuccess {
+		return errors.Errorf("statistic-get-all command failed: %s", sr.Text)
+	}
+	if daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+		return errors.Errorf("missing configuration of the %s daemon in app %d", daemon.Name, dbApp.ID)
+	}
+
+	configSubnets := make(map[int64]keaconfig.Subnet)
+	for _, configSubnet := range daemon.KeaDaemon.Config.GetSubnets() {
+		configSubnets[configSubnet.GetID()] = configSubnet
+	}
+
+	var lastErr error
+	for key, stats := range getPoolStats(sr.Arguments, family) {
+		sn := subnetsMap[localSubnetKey{key.LocalSubnetID, family}]
+		if sn == nil {
+			continue
+		}
+		pool := findLocalSubnetPool(configSubnets, sn, key)
+		if pool == nil {
+			continue
+		}
+		if err := pool.UpdateStats(statsPuller.DB, stats); err != nil {
+			log.Errorf("Problem updating Kea stats for pool %d in local subnet ID %d, app ID %d: %s", key.Index, sn.LocalSubnetID, dbApp.ID, err.Error())
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
 func (statsPuller *StatsPuller) getStatsFromApp(dbApp *dbmodel.App) error {
 	// If no dhcp daemons found then exit.
 	if len(dbApp.GetActiveDHCPDaemonNames()) == 0 {
@@ -274,6 +535,9 @@ func (statsPuller *StatsPuller) getStatsFromApp(dbApp *dbmodel.App) error {
 	cmdDaemons := []*dbmodel.Daemon{}
 	responses := []interface{}{}
 
+	// Daemons to fetch the pool statistics from.
+	poolStatsDaemons := []*dbmodel.Daemon{}
+
 	// Iterate over active daemons, adding commands and response containers
 	// for dhcp4 and dhcp6 daemons.
 	for _, d := range dbApp.Daemons {
@@ -296,6 +560,7 @@ func (statsPuller *StatsPuller) getStatsFromApp(dbApp *dbmodel.App) error {
 				})
 
 				responses = append(responses, &[]StatLeaseGetResponse{})
+				poolStatsDaemons = append(poolStatsDaemons, d)
 
 				// Add daemon, cmd and response for DHCP4 RPS stats if we have an RpsWorker
 				if statsPuller.RpsWorker != nil {
@@ -313,6 +578,7 @@ func (statsPuller *StatsPuller) getStatsFromApp(dbApp *dbmodel.App) error {
 				})
 
 				responses = append(responses, &[]StatLeaseGetResponse{})
+				poolStatsDaemons = append(poolStatsDaemons, d)
 
 				// Add daemon, cmd and response for DHCP6 RPS stats if we have an RpsWorker
 				if statsPuller.RpsWorker != nil {
@@ -323,6 +589,22 @@ func (statsPuller *StatsPuller) getStatsFromApp(dbApp *dbmodel.App) error {
 		}
 	}
 
+	// Add cmds and responses for the pool stats. They are added after
+	// the other commands to preserve the order of the responses.
+	for _, d := range poolStatsDaemons {
+		// The pool statistics can't be matched with the pools without
+		// the daemon's configuration.
+		if d.KeaDaemon.Config == nil {
+			continue
+		}
+		cmdDaemons = append(cmdDaemons, d)
+		cmds = append(cmds, &keactrl.Command{
+			Command: "statistic-get-all",
+			Daemons: []string{d.Name},
+		})
+		responses = append(responses, &[]StatisticGetAllResponse{})
+	}
+
 	// If there are no commands, nothing to do
 	if len(cmds) == 0 {
 		return nil
@@ -382,6 +664,12 @@ func (statsPuller *StatsPuller) processAppResponses(dbApp *dbmodel.App, cmds []*
 					log.Errorf("Error handling statistic-get (v4) response: %+v", err)
 					lastErr = err
 				}
+			case "statistic-get-all":
+				err = statsPuller.storeDaemonPoolStats(responses[idx], subnetsMap, dbApp, cmdDaemons[idx], 4)
+				if err != nil {
+					log.Errorf("Error handling statistic-get-all (v4) response: %+v", err)
+					lastErr = err
+				}
 			}
 
 		case dhcp6:
@@ -398,6 +686,12 @@ func (statsPuller *StatsPuller) processAppResponses(dbApp *dbmodel.App, cmds []*
 					log.Errorf("Error handling statistic-get (v6) response: %+v", err)
 					lastErr = err
 				}
+			case "statistic-get-all":
+				err = statsPuller.storeDaemonPoolStats(responses[idx], subnetsMap, dbApp, cmdDaemons[idx], 6)
+				if err != nil {
+					log.Errorf("Error handling statistic-get-all (v6) response: %+v", err)
+					lastErr = err
+				}
 			}
 		}
 	}
@@ -5,23 +5,43 @@ import (
 	"math"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/go-pg/pg/v10"
 	"github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
 	keactrl "isc.org/stork/appctrl/kea"
 	agentcommtest "isc.org/stork/server/agentcomm/test"
 	dbmodel "isc.org/stork/server/database/model"
 	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
 )
 
 // Prepares the Kea mock. It accepts list of serialized JSON responses in order:
 // 1. DHCPv4
 // 2. DHCPv4 RSP
 // 3. DHCPv6
-// 4. DHCPv6 RSP.
+// 4. DHCPv6 RSP
+// 5. DHCPv4 pools (optional)
+// 6. DHCPv6 pools (optional).
 func createKeaMock(jsonFactory func(callNo int) (jsons []string)) func(callNo int, cmdResponses []interface{}) {
 	return func(callNo int, cmdResponses []interface{}) {
 		jsons := jsonFactory(callNo)
+
+		// The pool statistics follow the other statistics. The empty
+		// statistics are returned when they are not specified.
+		for idx, response := range cmdResponses {
+			if _, ok := response.(*[]StatisticGetAllResponse); !ok {
+				continue
+			}
+			poolJSON := `[{ "result": 0, "text": "Everything is fine", "arguments": {} }]`
+			if idx < len(jsons) {
+				poolJSON = jsons[idx]
+			}
+			command := keactrl.NewCommand("statistic-get-all", nil, nil)
+			keactrl.UnmarshalResponseList(command, []byte(poolJSON), response)
+		}
+
 		// DHCPv4
 		daemons := []string{"dhcp4"}
 		command := keactrl.NewCommand("stat-lease4-get", daemons, nil)
@@ -128,6 +148,32 @@ func createStandardKeaMock(oldStatsFormat bool) func(callNo int, cmdResponses []
 					},
 				},
 			},
+			[]StatisticGetAllResponse{
+				{
+					ResponseHeader: keactrl.ResponseHeader{
+						Result: 0,
+						Text:   "Everything is fine",
+					},
+					Arguments: map[string][][]json.RawMessage{
+						"subnet[20].pool[0].total-addresses": {
+							{json.RawMessage("10"), json.RawMessage(`"2019-07-30 10:13:00.000000"`)},
+						},
+						"subnet[20].pool[0].assigned-addresses": {
+							{json.RawMessage("8"), json.RawMessage(`"2019-07-30 10:13:00.000000"`)},
+						},
+						"subnet[20].pool[0].declined-addresses": {
+							{json.RawMessage("1"), json.RawMessage(`"2019-07-30 10:13:00.000000"`)},
+						},
+						// Unknown pool.
+						"subnet[20].pool[1].assigned-addresses": {
+							{json.RawMessage("5"), json.RawMessage(`"2019-07-30 10:13:00.000000"`)},
+						},
+						"pkt4-received": {
+							{json.RawMessage("100"), json.RawMessage(`"2019-07-30 10:13:00.000000"`)},
+						},
+					},
+				},
+			},
 		}
 
 		var jsons []string
@@ -354,7 +400,7 @@ func TestStatsPullerBasic(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(nil, nil)
 
 	// Act
-	sp, err := NewStatsPuller(db, fa)
+	sp, err := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	defer sp.Shutdown()
 
 	// Assert
@@ -404,7 +450,7 @@ func TestStatsPullerEmptyResponse(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(keaMock, nil)
 
 	// prepare stats puller
-	sp, _ := NewStatsPuller(db, fa)
+	sp, _ := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	defer sp.Shutdown()
 
 	// Act
@@ -447,7 +493,7 @@ func checkStatsPullerPullStats(t *testing.T, statsFormat string) {
 	}
 
 	// prepare stats puller
-	sp, _ := NewStatsPuller(db, fa)
+	sp, _ := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	defer sp.Shutdown()
 
 	// Act
@@ -460,6 +506,30 @@ func checkStatsPullerPullStats(t *testing.T, statsFormat string) {
 	// check collected stats
 	verifyStandardLocalSubnetsStatistics(t, db)
 
+	// The raw utilization samples should have been stored for the subnets.
+	subnets, err := dbmodel.GetAllSubnets(db, 0)
+	require.NoError(t, err)
+	sampleCount := 0
+	for _, subnet := range subnets {
+		samples, err := dbmodel.GetSubnetUtilizationSamples(db, subnet.ID, dbmodel.UtilizationResolutionRaw, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(samples), 1)
+		sampleCount += len(samples)
+	}
+	require.NotZero(t, sampleCount)
+
+	// Check the pool statistics.
+	pools := []dbmodel.AddressPool{}
+	err = db.Model(&pools).Where("stats IS NOT NULL").Select()
+	require.NoError(t, err)
+	require.Len(t, pools, 1)
+	require.Equal(t, "192.0.3.1", pools[0].LowerBound)
+	require.EqualValues(t, 10, pools[0].Stats["total-addresses"])
+	require.EqualValues(t, 8, pools[0].Stats["assigned-addresses"])
+	require.EqualValues(t, 1, pools[0].Stats["declined-addresses"])
+	require.EqualValues(t, 800, pools[0].Utilization)
+	require.False(t, pools[0].StatsCollectedAt.IsZero())
+
 	// We should have two rows in RpsWorker.PreviousRps map one for each daemon
 	require.Equal(t, 2, len(sp.RpsWorker.PreviousRps))
 
@@ -474,7 +544,7 @@ func checkStatsPullerPullStats(t *testing.T, statsFormat string) {
 	require.EqualValues(t, 66, previous.Value)
 
 	// Check out-of-pool addresses/NAs/PDs utilization
-	subnets, _ := dbmodel.GetAllSubnets(db, 0)
+	subnets, _ = dbmodel.GetAllSubnets(db, 0)
 
 	for _, sn := range subnets {
 		switch sn.LocalSubnets[0].LocalSubnetID {
@@ -560,7 +630,7 @@ func TestGetStatsFromAppWithoutStatCmd(t *testing.T) {
 		},
 	}
 
-	sp, _ := NewStatsPuller(db, fa)
+	sp, _ := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 
 	// Act
 	err := sp.getStatsFromApp(app)
@@ -570,6 +640,203 @@ func TestGetStatsFromAppWithoutStatCmd(t *testing.T) {
 	require.Zero(t, fa.CallNo)
 }
 
+// Test extracting the pool statistics from the statistic-get-all response.
+func TestGetPoolStats(t *testing.T) {
+	sample := func(value string) [][]json.RawMessage {
+		return [][]json.RawMessage{
+			{json.RawMessage(value), json.RawMessage(`"2019-07-30 10:13:00.000000"`)},
+			{json.RawMessage("0"), json.RawMessage(`"2019-07-30 10:12:00.000000"`)},
+		}
+	}
+	arguments := map[string][][]json.RawMessage{
+		"subnet[1].pool[0].total-nas":                    sample("18446744073709551616"),
+		"subnet[1].pool[0].assigned-nas":                 sample("5"),
+		"subnet[1].pool[0].declined-addresses":           sample("1"),
+		"subnet[1].pool[0].cumulative-assigned-nas":      sample("7"),
+		"subnet[1].pd-pool[2].total-pds":                 sample("256"),
+		"subnet[1].pd-pool[2].assigned-pds":              sample("-1"),
+		"subnet[1].total-nas":                            sample("100"),
+		"subnet[2].pool[0].assigned-nas":                 {},
+		"subnet[3].pool[0].assigned-nas":                 sample(`"foo"`),
+		"subnet[99999999999999999999].pool[0].total-nas": sample("1"),
+	}
+
+	stats := getPoolStats(arguments, 6)
+	require.Len(t, stats, 2)
+
+	pool := stats[poolStatsKey{LocalSubnetID: 1, Index: 0}]
+	require.Len(t, pool, 3)
+	total, ok := pool["total-nas"].(*big.Int)
+	require.True(t, ok)
+	require.Equal(t, "18446744073709551616", total.String())
+	require.EqualValues(t, uint64(5), pool["assigned-nas"])
+	require.EqualValues(t, uint64(1), pool["declined-nas"])
+
+	pdPool := stats[poolStatsKey{LocalSubnetID: 1, PrefixPool: true, Index: 2}]
+	require.Len(t, pdPool, 2)
+	require.EqualValues(t, uint64(256), pdPool["total-pds"])
+	require.EqualValues(t, int64(-1), pdPool["assigned-pds"])
+}
+
+// Test that the invalid statistic-get-all responses are rejected.
+func TestStoreDaemonPoolStatsInvalidResponse(t *testing.T) {
+	sp := &StatsPuller{}
+	app := &dbmodel.App{ID: 1}
+	daemon := &dbmodel.Daemon{
+		Name: dhcp4,
+		KeaDaemon: &dbmodel.KeaDaemon{
+			Config: dbmodel.NewKeaConfig(&map[string]interface{}{
+				"Dhcp4": map[string]interface{}{},
+			}),
+		},
+	}
+	subnetsMap := map[localSubnetKey]*dbmodel.LocalSubnet{}
+
+	err := sp.storeDaemonPoolStats(&[]StatLeaseGetResponse{}, subnetsMap, app, daemon, 4)
+	require.Error(t, err)
+
+	err = sp.storeDaemonPoolStats(&[]StatisticGetAllResponse{}, subnetsMap, app, daemon, 4)
+	require.Error(t, err)
+
+	err = sp.storeDaemonPoolStats(&[]StatisticGetAllResponse{
+		{
+			ResponseHeader: keactrl.ResponseHeader{
+				Result: keactrl.ResponseCommandUnsupported,
+				Text:   "'statistic-get-all' command not supported.",
+			},
+		},
+	}, subnetsMap, app, daemon, 4)
+	require.ErrorContains(t, err, "not supported")
+
+	// No configuration.
+	err = sp.storeDaemonPoolStats(&[]StatisticGetAllResponse{{}}, subnetsMap, app, &dbmodel.Daemon{Name: dhcp4}, 4)
+	require.ErrorContains(t, err, "missing configuration")
+
+	// No pools.
+	err = sp.storeDaemonPoolStats(&[]StatisticGetAllResponse{{}}, subnetsMap, app, daemon, 4)
+	require.NoError(t, err)
+}
+
+// Test that the pools are matched with the statistics by their boundaries
+// in the configuration rather than by their positions in the database.
+func TestFindLocalSubnetPool(t *testing.T) {
+	config, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp6": {
+			"subnet6": [
+				{
+					"id": 1,
+					"subnet": "2001:db8:1::/48",
+					"pools": [
+						{ "pool": "2001:db8:1::100-2001:db8:1::1ff" },
+						{ "pool": "2001:db8:1::/120" }
+					],
+					"pd-pools": [
+						{
+							"prefix": "3000::",
+							"prefix-len": 56,
+							"delegated-len": 64
+						},
+						{
+							"prefix": "3001::",
+							"prefix-len": 56,
+							"delegated-len": 64
+						}
+					]
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+	configSubnets := make(map[int64]keaconfig.Subnet)
+	for _, configSubnet := range config.GetSubnets() {
+		configSubnets[configSubnet.GetID()] = configSubnet
+	}
+
+	// The second pools have been inserted into the configuration before the
+	// first pools, so they are stored first in the database.
+	sn := &dbmodel.LocalSubnet{
+		LocalSubnetID: 1,
+		AddressPools: []dbmodel.AddressPool{
+			{ID: 1, LowerBound: "2001:db8:1::", UpperBound: "2001:db8:1::ff"},
+			{ID: 2, LowerBound: "2001:db8:1::100", UpperBound: "2001:db8:1::1ff"},
+		},
+		PrefixPools: []dbmodel.PrefixPool{
+			{ID: 3, Prefix: "3001::/56", DelegatedLen: 64},
+			{ID: 4, Prefix: "3000::/56", DelegatedLen: 64},
+		},
+	}
+
+	pool := findLocalSubnetPool(configSubnets, sn, poolStatsKey{LocalSubnetID: 1, Index: 0})
+	require.Equal(t, &sn.AddressPools[1], pool)
+	pool = findLocalSubnetPool(configSubnets, sn, poolStatsKey{LocalSubnetID: 1, Index: 1})
+	require.Equal(t, &sn.AddressPools[0], pool)
+	pool = findLocalSubnetPool(configSubnets, sn, poolStatsKey{LocalSubnetID: 1, PrefixPool: true, Index: 0})
+	require.Equal(t, &sn.PrefixPools[1], pool)
+	pool = findLocalSubnetPool(configSubnets, sn, poolStatsKey{LocalSubnetID: 1, PrefixPool: true, Index: 1})
+	require.Equal(t, &sn.PrefixPools[0], pool)
+
+	// Unknown pools and subnets.
+	require.Nil(t, findLocalSubnetPool(configSubnets, sn, poolStatsKey{LocalSubnetID: 1, Index: 2}))
+	require.Nil(t, findLocalSubnetPool(configSubnets, sn, poolStatsKey{LocalSubnetID: 1, PrefixPool: true, Index: 2}))
+	require.Nil(t, findLocalSubnetPool(configSubnets, sn, poolStatsKey{LocalSubnetID: 2, Index: 0}))
+
+	// The pool is not in the database.
+	sn.AddressPools = sn.AddressPools[:1]
+	require.Nil(t, findLocalSubnetPool(configSubnets, sn, poolStatsKey{LocalSubnetID: 1, Index: 0}))
+}
+
+// Test that the utilization samples are created for the pools with the
+// current statistics, excluding the pools of the passive HA daemons.
+func TestGetPoolUtilizationSamples(t *testing.T) {
+	pulledAt := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	sampledAt := pulledAt.Add(time.Minute)
+	addressPools := []*dbmodel.AddressPool{
+		{
+			ID:               1,
+			LocalSubnet:      &dbmodel.LocalSubnet{DaemonID: 1},
+			Stats:            dbmodel.SubnetStats{"total-addresses": uint64(256)},
+			StatsCollectedAt: pulledAt,
+			Utilization:      250,
+		},
+		// Outdated statistics.
+		{
+			ID:               2,
+			LocalSubnet:      &dbmodel.LocalSubnet{DaemonID: 1},
+			StatsCollectedAt: pulledAt.Add(-time.Second),
+			Utilization:      100,
+		},
+		// Passive HA daemon.
+		{
+			ID:               3,
+			LocalSubnet:      &dbmodel.LocalSubnet{DaemonID: 2},
+			StatsCollectedAt: pulledAt,
+			Utilization:      100,
+		},
+	}
+	prefixPools := []*dbmodel.PrefixPool{
+		{
+			ID:               4,
+			LocalSubnet:      &dbmodel.LocalSubnet{DaemonID: 1},
+			StatsCollectedAt: pulledAt.Add(time.Second),
+			Utilization:      500,
+		},
+	}
+
+	samples := getPoolUtilizationSamples(addressPools, prefixPools, map[int64]bool{2: true}, pulledAt, sampledAt)
+	require.Len(t, samples, 2)
+
+	require.EqualValues(t, 1, samples[0].AddressPoolID)
+	require.Zero(t, samples[0].PrefixPoolID)
+	require.Equal(t, sampledAt, samples[0].SampledAt)
+	require.EqualValues(t, 0.25, samples[0].AddrUtilization)
+	require.Zero(t, samples[0].PdUtilization)
+
+	require.EqualValues(t, 4, samples[1].PrefixPoolID)
+	require.Zero(t, samples[1].AddressPoolID)
+	require.Zero(t, samples[1].AddrUtilization)
+	require.EqualValues(t, 0.5, samples[1].PdUtilization)
+}
+
 // Prepares the Kea configuration file with HA hook and some subnets.
 func getHATestConfigWithSubnets(rootName, thisServerName, mode string, peerNames ...string) *dbmodel.KeaConfig {
 	// Creates standard HA config.
@@ -920,7 +1187,7 @@ func TestPrepareHAEnvironment(t *testing.T) {
 	keaMock := createKeaMock(func(callNo int) (jsons []string) { return []string{} })
 
 	fa := agentcommtest.NewFakeAgents(keaMock, nil)
-	sp, err := NewStatsPuller(db, fa)
+	sp, err := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 
 	// Assert
 	require.NoError(t, err)
@@ -943,7 +1210,7 @@ func TestStatsPullerPullStatsHAPairNotInitializedYet(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(keaMock, nil)
 
 	// prepare stats puller
-	sp, err := NewStatsPuller(db, fa)
+	sp, err := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	require.NoError(t, err)
 	defer sp.Shutdown()
 
@@ -986,7 +1253,7 @@ func TestStatsPullerPullStatsHAPairHealthy(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(keaMock, nil)
 
 	// prepare stats puller
-	sp, err := NewStatsPuller(db, fa)
+	sp, err := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	require.NoError(t, err)
 	defer sp.Shutdown()
 
@@ -1025,7 +1292,7 @@ func TestStatsPullerPullStatsHAPairPrimaryIsDownSecondaryIsReady(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(keaMock, nil)
 
 	// prepare stats puller
-	sp, err := NewStatsPuller(db, fa)
+	sp, err := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	require.NoError(t, err)
 	defer sp.Shutdown()
 
@@ -1064,7 +1331,7 @@ func TestStatsPullerPullStatsHAPairPrimaryIsDownSecondaryIsDown(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(keaMock, nil)
 
 	// prepare stats puller
-	sp, err := NewStatsPuller(db, fa)
+	sp, err := NewStatsPuller(db, fa, &storktest.FakeEventCenter{})
 	require.NoError(t, err)
 	defer sp.Shutdown()
 
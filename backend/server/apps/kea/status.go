@@ -3,6 +3,7 @@ package kea
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -21,6 +22,8 @@ type HALocalStatus struct {
 	Role   string
 	Scopes []string
 	State  string
+	// ServerName is returned by Kea 2.4.0 and later.
+	ServerName string `json:"server-name"`
 }
 
 // Represents the status of the remote server.
@@ -35,6 +38,8 @@ type HARemoteStatus struct {
 	UnackedClients     int64    `json:"unacked-clients"`
 	UnackedClientsLeft int64    `json:"unacked-clients-left"`
 	AnalyzedPackets    int64    `json:"analyzed-packets"`
+	// ServerName is returned by Kea 2.4.0 and later.
+	ServerName string `json:"server-name"`
 }
 
 // Represents the status of the HA enabled Kea servers.
@@ -218,6 +223,37 @@ func updateHAServiceStatus(status *HAServersStatus, daemon *dbmodel.Daemon, serv
 	}
 }
 
+// Returns the status of the HA relationship represented by the service
+// from the status-get response. The Kea versions earlier than 1.7.8 return
+// the status of the only relationship. Kea 2.4.0 and later return the
+// server names, so the status is matched with the service by the names
+// of the servers in the relationship. If the server names are not returned
+// or the service hasn't been associated with any relationship, the status
+// is only returned if there is exactly one relationship. It returns nil
+// if the status is not found.
+func findHAServersStatus(status *daemonStatus, service *dbmodel.BaseHAService) *HAServersStatus {
+	if len(status.HA) == 0 {
+		return status.HAServers
+	}
+	if len(service.Relationship) > 0 {
+		serverNames := make(map[string]bool)
+		for _, name := range strings.Split(service.Relationship, ",") {
+			serverNames[name] = true
+		}
+		for i, relationship := range status.HA {
+			local := relationship.HAServers.Local.ServerName
+			remote := relationship.HAServers.Remote.ServerName
+			if serverNames[local] && (len(remote) == 0 || serverNames[remote]) {
+				return &status.HA[i].HAServers
+			}
+		}
+	}
+	if len(status.HA) == 1 && (len(service.Relationship) == 0 || len(status.HA[0].HAServers.Local.ServerName) == 0) {
+		return &status.HA[0].HAServers
+	}
+	return nil
+}
+
 // Iterates over the slice of HA services and updates them in the database.
 func (puller *HAStatusPuller) commitHAServicesStatus(appID int64, services []dbmodel.Service) {
 	for i := range services {
@@ -294,8 +330,8 @@ func (puller *HAStatusPuller) pullDataForApp(app *dbmodel.App) (bool, bool) {
 				dbServices[j].HAService.SecondaryLastScopes = []string{}
 				dbServices[j].HAService.SecondaryReachable = false
 			}
-			haServices = append(haServices, dbServices[j])
 		}
+		haServices = append(haServices, dbServices[j])
 	}
 
 	ctx := context.Background()
@@ -312,31 +348,23 @@ func (puller *HAStatusPuller) pullDataForApp(app *dbmodel.App) (bool, bool) {
 		if status.HAServers == nil && len(status.HA) == 0 {
 			continue
 		}
-		// Find the matching service for the returned status.
-		index := -1
-
+		// Find the matching services for the returned status. The server
+		// may belong to multiple HA relationships, each represented by
+		// a distinct service.
 		for i := range haServices {
-			if haServices[i].HAService.HAType == status.Daemon {
-				index = i
+			service := haServices[i].HAService
+			if service.HAType != status.Daemon {
+				continue
 			}
-		}
-		if index < 0 {
-			continue
-		}
-		service := haServices[index].HAService
-		for _, daemon := range app.Daemons {
-			// Update the HA service status only if the given server is primary
-			// or secondary.
-			if service.PrimaryID == daemon.ID || service.SecondaryID == daemon.ID {
-				// todo: Currently Kea supports only one HA service per daemon. This
-				// will change but for now it is safe to assume that only one status
-				// is returned. Supporting more requires some mechanisms to
-				// distinguish the HA relationships which should be first designed
-				// on the Kea side.
-				if len(status.HA) > 0 {
-					updateHAServiceStatus(&status.HA[0].HAServers, daemon, service)
-				} else if status.HAServers != nil {
-					updateHAServiceStatus(status.HAServers, daemon, service)
+			serversStatus := findHAServersStatus(&status, service)
+			if serversStatus == nil {
+				continue
+			}
+			for _, daemon := range app.Daemons {
+				// Update the HA service status only if the given server is primary
+				// or secondary.
+				if service.PrimaryID == daemon.ID || service.SecondaryID == daemon.ID {
+					updateHAServiceStatus(serversStatus, daemon, service)
 				}
 			}
 		}
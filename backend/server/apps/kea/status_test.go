@@ -432,6 +432,111 @@ func TestGetDHCPStatus178(t *testing.T) {
 	require.EqualValues(t, 10, remote.AnalyzedPackets)
 }
 
+// Generates a response to the status-get command returned by the hub
+// server belonging to two HA relationships.
+func mockGetStatusHubAndSpoke(callNo int, cmdResponses []interface{}) {
+	command := keactrl.NewCommand("status-get", []string{"dhcp4"}, nil)
+	json := `[{
+        "result": 0,
+        "text": "Everything is fine",
+        "arguments": {
+            "pid": 1234,
+            "uptime": 3024,
+            "reload": 1111,
+            "high-availability": [
+                {
+                    "ha-mode": "hot-standby",
+                    "ha-servers": {
+                        "local": {
+                            "role": "standby",
+                            "scopes": [ ],
+                            "server-name": "hub",
+                            "state": "hot-standby"
+                        },
+                        "remote": {
+                            "age": 5,
+                            "in-touch": true,
+                            "role": "primary",
+                            "last-scopes": [ "branch1" ],
+                            "last-state": "hot-standby",
+                            "server-name": "branch1"
+                        }
+                    }
+                },
+                {
+                    "ha-mode": "hot-standby",
+                    "ha-servers": {
+                        "local": {
+                            "role": "standby",
+                            "scopes": [ "branch2" ],
+                            "server-name": "hub",
+                            "state": "partner-down"
+                        },
+                        "remote": {
+                            "age": 0,
+                            "in-touch": false,
+                            "role": "primary",
+                            "last-scopes": [ ],
+                            "last-state": "unavailable",
+                            "server-name": "branch2"
+                        }
+                    }
+                }
+            ]
+        }
+    }]`
+	_ = keactrl.UnmarshalResponseList(command, []byte(json), cmdResponses[0])
+}
+
+// Test status-get command returning the status of multiple HA relationships
+// and that the statuses are matched with the services by the server names.
+func TestGetDHCPStatusHubAndSpoke(t *testing.T) {
+	fa := agentcommtest.NewFakeAgents(mockGetStatusHubAndSpoke, nil)
+
+	var accessPoints []*dbmodel.AccessPoint
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "", "", 1234, true)
+
+	app := dbmodel.App{
+		AccessPoints: accessPoints,
+		Machine: &dbmodel.Machine{
+			Address:   "192.0.2.0",
+			AgentPort: 1111,
+		},
+	}
+
+	appStatus, err := getDHCPStatus(context.Background(), fa, &app)
+	require.NoError(t, err)
+	require.Len(t, appStatus, 1)
+	status := appStatus[0]
+	require.Len(t, status.HA, 2)
+	require.Equal(t, "hub", status.HA[0].HAServers.Local.ServerName)
+	require.Equal(t, "branch1", status.HA[0].HAServers.Remote.ServerName)
+
+	serversStatus := findHAServersStatus(&status, &dbmodel.BaseHAService{
+		Relationship: "branch2,hub",
+	})
+	require.NotNil(t, serversStatus)
+	require.Equal(t, "partner-down", serversStatus.Local.State)
+	require.Equal(t, "branch2", serversStatus.Remote.ServerName)
+
+	serversStatus = findHAServersStatus(&status, &dbmodel.BaseHAService{
+		Relationship: "branch1,hub",
+	})
+	require.NotNil(t, serversStatus)
+	require.Equal(t, "hot-standby", serversStatus.Local.State)
+
+	// The relationship is not known.
+	require.Nil(t, findHAServersStatus(&status, &dbmodel.BaseHAService{
+		Relationship: "branch3,hub",
+	}))
+	require.Nil(t, findHAServersStatus(&status, &dbmodel.BaseHAService{}))
+
+	// The service detected by the earlier Stork version matches the only
+	// relationship.
+	status.HA = status.HA[:1]
+	require.NotNil(t, findHAServersStatus(&status, &dbmodel.BaseHAService{}))
+}
+
 // Test status-get command when HA status is not returned.
 func TestGetDHCPStatusNoHA(t *testing.T) {
 	fa := agentcommtest.NewFakeAgents(mockGetStatusNoHA, nil)
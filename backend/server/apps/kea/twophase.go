@@ -0,0 +1,375 @@
+package kea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	keactrl "isc.org/stork/appctrl/kea"
+	config "isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Name of the setting enabling the two-phase commit of the Kea
+// configuration changes.
+const twoPhaseCommitSetting = "kea_two_phase_commit"
+
+// Type of the context key holding the commit journal.
+type commitJournalContextKeyType int
+
+// A context key for accessing the journal of the two-phase commit.
+const commitJournalContextKey commitJournalContextKeyType = iota
+
+// Records the progress of the two-phase commit. It holds the commands
+// successfully applied in the daemons, so they can be reverted when one
+// of the subsequent commands fails. It also holds the log of the actions
+// taken during the commit. The log is included in the event summarizing
+// the commit.
+type commitJournal struct {
+	applied []ConfigCommand
+	log     []string
+}
+
+// Appends a formatted entry to the journal log.
+func (journal *commitJournal) logf(format string, args ...any) {
+	journal.log = append(journal.log, fmt.Sprintf(format, args...))
+}
+
+// Returns the commit journal from the context or nil if the changes are
+// not committed in the two-phase mode.
+func getCommitJournal(ctx context.Context) *commitJournal {
+	journal, _ := ctx.Value(commitJournalContextKey).(*commitJournal)
+	return journal
+}
+
+// Describes a read-only command checking whether an object exists in a
+// daemon before sending a command adding, updating or deleting this
+// object. Such commands are sent in the validation phase of the two-phase
+// commit to detect the commands that would fail.
+type dryRunCheck struct {
+	// A command getting the object from the daemon (e.g., subnet4-get).
+	command *keactrl.Command
+	// Object description used in the logs and to avoid checking the
+	// same object twice.
+	object string
+	// Indicates whether the object must exist for the modifying command
+	// to succeed.
+	exists bool
+}
+
+// Checks if the two-phase commit of the Kea configuration changes has been
+// enabled in the settings. It returns false when the setting cannot be read.
+func (module *ConfigModule) isTwoPhaseCommitEnabled() bool {
+	if module.manager == nil || module.manager.GetDB() == nil {
+		return false
+	}
+	enabled, err := dbmodel.GetSettingBool(module.manager.GetDB(), twoPhaseCommitSetting)
+	if err != nil {
+		log.WithError(err).Warn("Problem getting the two-phase commit setting; committing the Kea configuration changes without validation")
+		return false
+	}
+	return enabled
+}
+
+// Sends a single command to the specified app and returns the responses.
+// It returns an error when communication with the agent or Kea fails. It
+// does not check the result codes returned by the daemons.
+func (module *ConfigModule) forwardCommand(app *dbmodel.App, command *keactrl.Command) (keactrl.ResponseList, error) {
+	var response keactrl.ResponseList
+	result, err := module.manager.GetConnectedAgents().ForwardToKeaOverHTTP(context.Background(), app, []keactrl.SerializableCommand{command}, &response)
+	// There was no error in communication between the server and the agent but
+	// the agent could have issues with the Kea response.
+	if err == nil {
+		// Let's check if the agent found errors in communication with Kea.
+		err = result.GetFirstError()
+	}
+	return response, err
+}
+
+// Sends a single command to the specified app. It returns an error when
+// communication fails or any of the daemons returns an error code.
+func (module *ConfigModule) sendCommand(app *dbmodel.App, command *keactrl.Command) error {
+	response, err := module.forwardCommand(app, command)
+	if err == nil {
+		for _, r := range response {
+			// Let's check if the individual Kea servers returned error
+			// codes for the processed commands.
+			if err = keactrl.GetResponseError(r); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return pkgerrors.WithMessagef(err, "%s command to %s failed", command.GetCommand(), app.GetName())
+	}
+	return nil
+}
+
+// Runs the validation phase of the two-phase commit. It sends the commands
+// validating the changes (e.g., config-test) and checks that the objects
+// modified by the other commands exist or do not exist in the daemons,
+// depending on the command. Each object is checked once, before the first
+// command modifying it, because the subsequent commands depend on the
+// preceding ones (e.g., a host reservation is deleted and re-created).
+// The checks are skipped when a daemon does not return a meaningful
+// result for them. No changes are applied in the daemons in this phase.
+func (module *ConfigModule) validateChanges(ctx context.Context, journal *commitJournal) error {
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return pkgerrors.New("context lacks state")
+	}
+	checked := make(map[string]bool)
+	for _, update := range state.Updates {
+		for _, acs := range update.Recipe.Commands {
+			if acs.Validation {
+				if err := module.sendCommand(acs.App, acs.Command); err != nil {
+					journal.logf("validation failed: %s", err)
+					return err
+				}
+				journal.logf("validated %s in %s", acs.Command.GetCommand(), acs.App.GetName())
+				continue
+			}
+			check := createDryRunCheck(acs.Command)
+			if check == nil {
+				continue
+			}
+			key := fmt.Sprintf("%d:%s:%s", acs.App.ID, strings.Join(acs.Command.Daemons, ","), check.object)
+			if checked[key] {
+				continue
+			}
+			checked[key] = true
+			response, err := module.forwardCommand(acs.App, check.command)
+			if err != nil {
+				err = pkgerrors.WithMessagef(err, "%s command to %s failed", check.command.GetCommand(), acs.App.GetName())
+				journal.logf("validation failed: %s", err)
+				return err
+			}
+			if len(response) == 0 {
+				continue
+			}
+			var exists bool
+			switch response[0].Result {
+			case keactrl.ResponseSuccess:
+				exists = true
+			case keactrl.ResponseEmpty:
+				exists = false
+			default:
+				// The daemon could not check the object.
+				continue
+			}
+			if exists != check.exists {
+				status := "already exists"
+				if check.exists {
+					status = "does not exist"
+				}
+				err = pkgerrors.Errorf("%s command to %s would fail because %s %s", acs.Command.GetCommand(), acs.App.GetName(), check.object, status)
+				journal.logf("validation failed: %s", err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Creates a dry-run check for a command adding, updating or deleting a
+// host reservation, subnet or shared network. It returns nil for other
+// commands or when the command arguments lack the information required
+// to identify the object.
+func createDryRunCheck(command *keactrl.Command) *dryRunCheck {
+	// The arguments can be structures or maps depending on whether the
+	// commands were created in this transaction or restored from the
+	// database. Convert them to the maps.
+	var arguments map[string]any
+	marshalled, err := json.Marshal(command.Arguments)
+	if err != nil || json.Unmarshal(marshalled, &arguments) != nil || arguments == nil {
+		return nil
+	}
+	name := command.GetCommand()
+	switch name {
+	case "reservation-add":
+		reservation, ok := arguments["reservation"].(map[string]any)
+		if !ok {
+			return nil
+		}
+		for _, identifierType := range []string{"hw-address", "duid", "circuit-id", "client-id", "flex-id"} {
+			if identifier, ok := reservation[identifierType]; ok {
+				return createReservationCheck(command, reservation["subnet-id"], identifierType, identifier, false)
+			}
+		}
+		return nil
+	case "reservation-del":
+		return createReservationCheck(command, arguments["subnet-id"], arguments["identifier-type"], arguments["identifier"], true)
+	}
+	// The remaining commands have the form of subnet4-add, network6-del etc.
+	object, operation, found := strings.Cut(name, "-")
+	if !found {
+		return nil
+	}
+	switch object {
+	case "subnet4", "subnet6":
+		var id any
+		switch operation {
+		case "add", "update":
+			if subnets, ok := arguments[object].([]any); ok && len(subnets) > 0 {
+				if subnet, ok := subnets[0].(map[string]any); ok {
+					id = subnet["id"]
+				}
+			}
+		case "del":
+			id = arguments["id"]
+		}
+		if id == nil {
+			return nil
+		}
+		return &dryRunCheck{
+			command: keactrl.NewCommand(object+"-get", command.Daemons, map[string]any{"id": id}),
+			object:  fmt.Sprintf("subnet with ID %v", id),
+			exists:  operation != "add",
+		}
+	case "network4", "network6":
+		var sharedNetworkName any
+		switch operation {
+		case "add":
+			if sharedNetworks, ok := arguments["shared-networks"].([]any); ok && len(sharedNetworks) > 0 {
+				if sharedNetwork, ok := sharedNetworks[0].(map[string]any); ok {
+					sharedNetworkName = sharedNetwork["name"]
+				}
+			}
+		case "del":
+			sharedNetworkName = arguments["name"]
+		}
+		if sharedNetworkName == nil {
+			return nil
+		}
+		return &dryRunCheck{
+			command: keactrl.NewCommand(object+"-get", command.Daemons, map[string]any{"name": sharedNetworkName}),
+			object:  fmt.Sprintf("shared network %v", sharedNetworkName),
+			exists:  operation != "add",
+		}
+	}
+	return nil
+}
+
+// Creates a dry-run check getting a host reservation by subnet ID and
+// DHCP identifier with the reservation-get command.
+func createReservationCheck(command *keactrl.Command, subnetID, identifierType, identifier any, exists bool) *dryRunCheck {
+	if subnetID == nil || identifierType == nil || identifier == nil {
+		return nil
+	}
+	arguments := map[string]any{
+		"subnet-id":       subnetID,
+		"identifier-type": identifierType,
+		"identifier":      identifier,
+	}
+	return &dryRunCheck{
+		command: keactrl.NewCommand("reservation-get", command.Daemons, arguments),
+		object:  fmt.Sprintf("host reservation with %v %v in subnet with ID %v", identifierType, identifier, subnetID),
+		exists:  exists,
+	}
+}
+
+// Reverts the changes applied in the daemons before one of the commands
+// failed. The compensating commands are sent in the reverse order of the
+// applied commands. The configurations of the daemons that have already
+// written the changed configurations to disk are written again after
+// reverting the changes. It returns the cause error annotated with the
+// rollback outcome.
+func (module *ConfigModule) rollbackChanges(journal *commitJournal, cause error) error {
+	if len(journal.applied) == 0 {
+		return cause
+	}
+	var (
+		failures []string
+		written  []ConfigCommand
+	)
+	writtenKeys := make(map[string]bool)
+	for i := len(journal.applied) - 1; i >= 0; i-- {
+		acs := journal.applied[i]
+		if acs.Command.GetCommand() == "config-write" {
+			key := fmt.Sprintf("%d:%s", acs.App.ID, strings.Join(acs.Command.Daemons, ","))
+			if !writtenKeys[key] {
+				writtenKeys[key] = true
+				written = append(written, acs)
+			}
+			continue
+		}
+		for _, command := range acs.CompensatingCommands {
+			if err := module.sendCommand(acs.App, command); err != nil {
+				journal.logf("rolling back %s in %s failed: %s", acs.Command.GetCommand(), acs.App.GetName(), err)
+				failures = append(failures, err.Error())
+				continue
+			}
+			journal.logf("rolled back %s in %s with %s", acs.Command.GetCommand(), acs.App.GetName(), command.GetCommand())
+		}
+	}
+	for _, acs := range written {
+		if err := module.sendCommand(acs.App, acs.Command); err != nil {
+			journal.logf("writing the reverted configuration in %s failed: %s", acs.App.GetName(), err)
+			failures = append(failures, err.Error())
+			continue
+		}
+		journal.logf("wrote the reverted configuration in %s", acs.App.GetName())
+	}
+	journal.applied = nil
+	if len(failures) > 0 {
+		return pkgerrors.Errorf("%s; rolling back the applied changes failed: %s", cause, strings.Join(failures, "; "))
+	}
+	return pkgerrors.WithMessage(cause, "applied changes have been rolled back")
+}
+
+// Records a single event summarizing the outcome of the two-phase commit.
+// The event is related to the user who committed the changes and it lists
+// the updated daemons and the actions taken during the commit.
+func (module *ConfigModule) recordCommitEvent(ctx context.Context, journal *commitJournal, commitErr error) {
+	eventCenter := module.manager.GetEventCenter()
+	if eventCenter == nil {
+		return
+	}
+	state, ok := config.GetTransactionState[ConfigRecipe](ctx)
+	if !ok {
+		return
+	}
+	var (
+		operations []string
+		daemonIDs  []string
+		objects    []any
+	)
+	for _, update := range state.Updates {
+		operations = append(operations, update.Operation)
+		for _, daemonID := range update.DaemonIDs {
+			daemonIDs = append(daemonIDs, fmt.Sprint(daemonID))
+		}
+	}
+	text := fmt.Sprintf("Kea configuration changes (%s)", strings.Join(operations, ", "))
+	if commitErr != nil {
+		text = "failed to commit " + text
+	} else {
+		text = "committed " + text
+	}
+	if userID, ok := config.GetValueAsInt64(ctx, config.UserContextKey); ok && module.manager.GetDB() != nil {
+		user, err := dbmodel.GetUserByID(module.manager.GetDB(), int(userID))
+		if err != nil {
+			log.WithError(err).Warnf("Problem getting user %d committing the Kea configuration changes", userID)
+		}
+		if user != nil {
+			text = "{user} " + text
+			objects = append(objects, user)
+		}
+	}
+	details := journal.log
+	if len(daemonIDs) > 0 {
+		details = append([]string{fmt.Sprintf("daemons: %s", strings.Join(daemonIDs, ", "))}, details...)
+	}
+	if commitErr != nil {
+		details = append(details, fmt.Sprintf("error: %s", commitErr))
+	}
+	objects = append(objects, strings.Join(details, "\n"))
+	if commitErr != nil {
+		eventCenter.AddErrorEvent(text, objects...)
+		return
+	}
+	eventCenter.AddInfoEvent(text, objects...)
+}
@@ -0,0 +1,531 @@
+package kea
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
+	keactrl "isc.org/stork/appctrl/kea"
+	"isc.org/stork/datamodel"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	appstest "isc.org/stork/server/apps/test"
+	"isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktestdbmodel "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
+)
+
+// Returns a function generating a Kea response with the specified result
+// code. It is used to mock the responses of the fake agents.
+func mockKeaResult(result int) func(int, []interface{}) {
+	return func(callNo int, cmdResponses []interface{}) {
+		json := []byte(fmt.Sprintf(`[
+            {
+                "result": %d,
+                "text": "result text"
+            }
+        ]`, result))
+		command := keactrl.NewCommand("test", []string{"dhcp4"}, nil)
+		_ = keactrl.UnmarshalResponseList(command, json, cmdResponses[0])
+	}
+}
+
+// Creates a host reservation associated with two test daemons.
+func createTestHostForTwoPhaseCommit() *dbmodel.Host {
+	host := &dbmodel.Host{
+		ID:       1,
+		Hostname: "cool.example.org",
+		HostIdentifiers: []dbmodel.HostIdentifier{
+			{
+				Type:  "hw-address",
+				Value: []byte{1, 2, 3, 4, 5, 6},
+			},
+		},
+	}
+	for i := 1; i <= 2; i++ {
+		host.LocalHosts = append(host.LocalHosts, dbmodel.LocalHost{
+			DaemonID: int64(i),
+			Daemon: &dbmodel.Daemon{
+				ID:   int64(i),
+				Name: "dhcp4",
+				App: &dbmodel.App{
+					ID: int64(i),
+					AccessPoints: []*dbmodel.AccessPoint{
+						{
+							Type:    dbmodel.AccessPointControl,
+							Address: fmt.Sprintf("192.0.2.%d", i),
+							Port:    1234,
+						},
+					},
+					Name: fmt.Sprintf("kea@192.0.2.%d", i),
+				},
+			},
+			DataSource: dbmodel.HostDataSourceAPI,
+		})
+	}
+	return host
+}
+
+// Test that the commands applying the changes carry the compensating
+// commands reverting these changes.
+func TestApplyHostUpdateCompensatingCommands(t *testing.T) {
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	existingHost := createTestHostForTwoPhaseCommit()
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "host_update", 1, 2)
+	err := state.SetRecipeForUpdate(0, &ConfigRecipe{
+		HostConfigRecipeParams: HostConfigRecipeParams{
+			HostBeforeUpdate: existingHost,
+		},
+	})
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	host := createTestHostForTwoPhaseCommit()
+	host.Hostname = "updated.example.org"
+	ctx, err = module.ApplyHostUpdate(ctx, host)
+	require.NoError(t, err)
+
+	recipe, err := config.GetRecipeForUpdate[ConfigRecipe](ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, recipe.Commands, 4)
+
+	// The deleted host reservation is restored with the original data.
+	require.Len(t, recipe.Commands[0].CompensatingCommands, 1)
+	require.Equal(t, "reservation-add", recipe.Commands[0].CompensatingCommands[0].GetCommand())
+	require.Contains(t, recipe.Commands[0].CompensatingCommands[0].Marshal(), "cool.example.org")
+
+	// The added host reservation is deleted.
+	require.Len(t, recipe.Commands[2].CompensatingCommands, 1)
+	require.Equal(t, "reservation-del", recipe.Commands[2].CompensatingCommands[0].GetCommand())
+	require.False(t, recipe.Commands[2].Validation)
+}
+
+// Test that the config-test commands are marked as validating commands
+// and the config-set commands can be reverted to the original configuration.
+func TestApplyGlobalParametersUpdateCompensatingCommands(t *testing.T) {
+	module := NewConfigModule(nil)
+
+	daemon := createTestDaemonForGlobalParametersUpdate(t, 1)
+	ctx := createTestGlobalParametersUpdateContext(t, daemon)
+
+	ctx, err := module.ApplyGlobalParametersUpdate(ctx, map[int64]*keaconfig.SettableGlobalParameters{
+		1: {
+			ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+				ValidLifetime: storkutil.Ptr(int64(1800)),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	recipe, err := config.GetRecipeForUpdate[ConfigRecipe](ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, recipe.Commands, 3)
+
+	require.True(t, recipe.Commands[0].Validation)
+	require.Empty(t, recipe.Commands[0].CompensatingCommands)
+
+	require.False(t, recipe.Commands[1].Validation)
+	require.Len(t, recipe.Commands[1].CompensatingCommands, 1)
+	restore := recipe.Commands[1].CompensatingCommands[0]
+	require.Equal(t, "config-set", restore.GetCommand())
+	require.Contains(t, restore.Marshal(), `"valid-lifetime":3600`)
+
+	require.Empty(t, recipe.Commands[2].CompensatingCommands)
+}
+
+// Test creating the dry-run checks for the commands modifying the Kea
+// configuration.
+func TestCreateDryRunCheck(t *testing.T) {
+	t.Run("reservation-add", func(t *testing.T) {
+		command := keactrl.NewCommand("reservation-add", []string{"dhcp4"}, map[string]any{
+			"reservation": &keaconfig.HostCmdsReservation{
+				Reservation: keaconfig.Reservation{
+					HWAddress: "010203040506",
+				},
+				SubnetID: 12,
+			},
+		})
+		check := createDryRunCheck(command)
+		require.NotNil(t, check)
+		require.False(t, check.exists)
+		require.JSONEq(t, `{
+            "command": "reservation-get",
+            "service": [ "dhcp4" ],
+            "arguments": {
+                "subnet-id": 12,
+                "identifier-type": "hw-address",
+                "identifier": "010203040506"
+            }
+        }`, check.command.Marshal())
+	})
+
+	t.Run("reservation-del", func(t *testing.T) {
+		command := keactrl.NewCommand("reservation-del", []string{"dhcp6"}, &keaconfig.HostCmdsDeletedReservation{
+			IdentifierType: "duid",
+			Identifier:     "0102",
+			SubnetID:       1,
+		})
+		check := createDryRunCheck(command)
+		require.NotNil(t, check)
+		require.True(t, check.exists)
+		require.Equal(t, "reservation-get", check.command.GetCommand())
+		require.Equal(t, []string{"dhcp6"}, check.command.Daemons)
+	})
+
+	t.Run("subnet4-add", func(t *testing.T) {
+		command := keactrl.NewCommand("subnet4-add", []string{"dhcp4"}, map[string]any{
+			"subnet4": []any{
+				map[string]any{
+					"id":     3,
+					"subnet": "192.0.2.0/24",
+				},
+			},
+		})
+		check := createDryRunCheck(command)
+		require.NotNil(t, check)
+		require.False(t, check.exists)
+		require.JSONEq(t, `{
+            "command": "subnet4-get",
+            "service": [ "dhcp4" ],
+            "arguments": {
+                "id": 3
+            }
+        }`, check.command.Marshal())
+	})
+
+	t.Run("subnet6-update", func(t *testing.T) {
+		command := keactrl.NewCommand("subnet6-update", []string{"dhcp6"}, map[string]any{
+			"subnet6": []any{
+				map[string]any{
+					"id":     3,
+					"subnet": "2001:db8:1::/64",
+				},
+			},
+		})
+		check := createDryRunCheck(command)
+		require.NotNil(t, check)
+		require.True(t, check.exists)
+		require.Equal(t, "subnet6-get", check.command.GetCommand())
+	})
+
+	t.Run("subnet4-del", func(t *testing.T) {
+		command := keactrl.NewCommand("subnet4-del", []string{"dhcp4"}, map[string]any{
+			"id": 3,
+		})
+		check := createDryRunCheck(command)
+		require.NotNil(t, check)
+		require.True(t, check.exists)
+		require.Equal(t, "subnet4-get", check.command.GetCommand())
+	})
+
+	t.Run("network4-add", func(t *testing.T) {
+		command := keactrl.NewCommand("network4-add", []string{"dhcp4"}, map[string]any{
+			"shared-networks": []any{
+				map[string]any{
+					"name": "foo",
+				},
+			},
+		})
+		check := createDryRunCheck(command)
+		require.NotNil(t, check)
+		require.False(t, check.exists)
+		require.JSONEq(t, `{
+            "command": "network4-get",
+            "service": [ "dhcp4" ],
+            "arguments": {
+                "name": "foo"
+            }
+        }`, check.command.Marshal())
+	})
+
+	t.Run("network6-del", func(t *testing.T) {
+		command := keactrl.NewCommand("network6-del", []string{"dhcp6"}, map[string]any{
+			"name":           "foo",
+			"subnets-action": "keep",
+		})
+		check := createDryRunCheck(command)
+		require.NotNil(t, check)
+		require.True(t, check.exists)
+		require.Equal(t, "network6-get", check.command.GetCommand())
+	})
+
+	t.Run("no check", func(t *testing.T) {
+		require.Nil(t, createDryRunCheck(keactrl.NewCommand("config-write", []string{"dhcp4"}, nil)))
+		require.Nil(t, createDryRunCheck(keactrl.NewCommand("network4-subnet-add", []string{"dhcp4"}, map[string]any{
+			"name": "foo",
+			"id":   1,
+		})))
+		require.Nil(t, createDryRunCheck(keactrl.NewCommand("subnet4-del", []string{"dhcp4"}, map[string]any{})))
+	})
+}
+
+// Test that the validation fails when the added host reservation already
+// exists in one of the daemons and that no changes are applied.
+func TestValidateChangesReservationExists(t *testing.T) {
+	// The reservation does not exist in the first daemon and exists in
+	// the second daemon.
+	agents := agentcommtest.NewKeaFakeAgents(mockKeaResult(keactrl.ResponseEmpty), mockKeaResult(keactrl.ResponseSuccess))
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "host_add")
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+	ctx, err := module.ApplyHostAdd(ctx, createTestHostForTwoPhaseCommit())
+	require.NoError(t, err)
+
+	journal := &commitJournal{}
+	err = module.validateChanges(ctx, journal)
+	require.ErrorContains(t, err, "reservation-add command to kea@192.0.2.2 would fail because host reservation with hw-address 010203040506 in subnet with ID 0 already exists")
+
+	// Only the read-only commands should have been sent.
+	require.Len(t, agents.RecordedCommands, 2)
+	require.Equal(t, "reservation-get", agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, "reservation-get", agents.RecordedCommands[1].GetCommand())
+	require.Empty(t, journal.applied)
+	require.NotEmpty(t, journal.log)
+}
+
+// Test that the host reservation is checked once per daemon when it is
+// deleted and re-created during the update.
+func TestValidateChangesHostUpdate(t *testing.T) {
+	agents := agentcommtest.NewKeaFakeAgents(mockKeaResult(keactrl.ResponseSuccess))
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "host_update", 1, 2)
+	err := state.SetRecipeForUpdate(0, &ConfigRecipe{
+		HostConfigRecipeParams: HostConfigRecipeParams{
+			HostBeforeUpdate: createTestHostForTwoPhaseCommit(),
+		},
+	})
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+	ctx, err = module.ApplyHostUpdate(ctx, createTestHostForTwoPhaseCommit())
+	require.NoError(t, err)
+
+	err = module.validateChanges(ctx, &commitJournal{})
+	require.NoError(t, err)
+	require.Len(t, agents.RecordedCommands, 2)
+}
+
+// Test that the validation fails when one of the daemons rejects the
+// configuration with the config-test command.
+func TestValidateChangesConfigTestFailure(t *testing.T) {
+	agents := agentcommtest.NewKeaFakeAgents(mockKeaResult(keactrl.ResponseSuccess), mockKeaResult(keactrl.ResponseError))
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		Agents: agents,
+	})
+	module := NewConfigModule(manager)
+
+	daemon1 := createTestDaemonForGlobalParametersUpdate(t, 1)
+	daemon2 := createTestDaemonForGlobalParametersUpdate(t, 2)
+	ctx := createTestGlobalParametersUpdateContext(t, daemon1, daemon2)
+	params := &keaconfig.SettableGlobalParameters{
+		ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+			ValidLifetime: storkutil.Ptr(int64(1800)),
+		},
+	}
+	ctx, err := module.ApplyGlobalParametersUpdate(ctx, map[int64]*keaconfig.SettableGlobalParameters{
+		1: params,
+		2: params,
+	})
+	require.NoError(t, err)
+
+	journal := &commitJournal{}
+	err = module.validateChanges(ctx, journal)
+	require.ErrorContains(t, err, "config-test command")
+
+	require.Len(t, agents.RecordedCommands, 2)
+	require.Equal(t, "config-test", agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, "config-test", agents.RecordedCommands[1].GetCommand())
+}
+
+// Test that the changes applied in the daemons are rolled back when one
+// of the daemons rejects the changes in the two-phase commit.
+func TestCommitChangesRollback(t *testing.T) {
+	// The first daemon accepts the host reservation and the second one
+	// rejects it. The subsequent rollback succeeds.
+	agents := agentcommtest.NewKeaFakeAgents(mockKeaResult(keactrl.ResponseSuccess), mockKeaResult(keactrl.ResponseError), mockKeaResult(keactrl.ResponseSuccess))
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		Agents:    agents,
+		DefLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	})
+	module := NewConfigModule(manager)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "host_add")
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+	ctx, err := module.ApplyHostAdd(ctx, createTestHostForTwoPhaseCommit())
+	require.NoError(t, err)
+
+	journal := &commitJournal{}
+	ctx = context.WithValue(ctx, commitJournalContextKey, journal)
+	_, err = module.commitChanges(ctx)
+	require.ErrorContains(t, err, "applied changes have been rolled back: reservation-add command to kea@192.0.2.2 failed")
+
+	require.Len(t, agents.RecordedCommands, 3)
+	require.Equal(t, "reservation-add", agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, "reservation-add", agents.RecordedCommands[1].GetCommand())
+	require.Equal(t, "reservation-del", agents.RecordedCommands[2].GetCommand())
+	require.Equal(t, "http://192.0.2.1:1234/", agents.RecordedURLs[2])
+	require.Empty(t, journal.applied)
+}
+
+// Test that the configurations written to disk are written again after
+// reverting the changes and that the rollback failures are reported.
+func TestRollbackChanges(t *testing.T) {
+	// The first compensating command fails.
+	agents := agentcommtest.NewKeaFakeAgents(mockKeaResult(keactrl.ResponseError), mockKeaResult(keactrl.ResponseSuccess))
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		Agents: agents,
+	})
+	module := NewConfigModule(manager)
+
+	app := &dbmodel.App{
+		ID:   1,
+		Name: "kea@192.0.2.1",
+		AccessPoints: []*dbmodel.AccessPoint{
+			{
+				Type:    dbmodel.AccessPointControl,
+				Address: "192.0.2.1",
+				Port:    1234,
+			},
+		},
+	}
+	journal := &commitJournal{
+		applied: []ConfigCommand{
+			{
+				Command:              keactrl.NewCommand("subnet4-add", []string{"dhcp4"}, nil),
+				App:                  app,
+				CompensatingCommands: []*keactrl.Command{keactrl.NewCommand("subnet4-del", []string{"dhcp4"}, nil)},
+			},
+			{
+				Command:              keactrl.NewCommand("network4-subnet-add", []string{"dhcp4"}, nil),
+				App:                  app,
+				CompensatingCommands: []*keactrl.Command{keactrl.NewCommand("network4-subnet-del", []string{"dhcp4"}, nil)},
+			},
+			{
+				Command: keactrl.NewCommand("config-write", []string{"dhcp4"}, nil),
+				App:     app,
+			},
+			{
+				Command: keactrl.NewCommand("config-write", []string{"dhcp4"}, nil),
+				App:     app,
+			},
+		},
+	}
+	err := module.rollbackChanges(journal, fmt.Errorf("cause"))
+	require.ErrorContains(t, err, "cause; rolling back the applied changes failed: network4-subnet-del command to kea@192.0.2.1 failed")
+
+	// The compensating commands are sent in the reverse order and the
+	// configuration is written once.
+	require.Len(t, agents.RecordedCommands, 3)
+	require.Equal(t, "network4-subnet-del", agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, "subnet4-del", agents.RecordedCommands[1].GetCommand())
+	require.Equal(t, "config-write", agents.RecordedCommands[2].GetCommand())
+	require.Len(t, journal.log, 3)
+}
+
+// Test that the outcome of the two-phase commit is recorded as a single
+// event.
+func TestRecordCommitEvent(t *testing.T) {
+	eventCenter := &storktestdbmodel.FakeEventCenter{}
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		EventCenter: eventCenter,
+	})
+	module := NewConfigModule(manager)
+
+	state := config.NewTransactionStateWithUpdate[ConfigRecipe](datamodel.AppTypeKea, "host_add", 1, 2)
+	ctx := context.WithValue(context.Background(), config.StateContextKey, *state)
+
+	journal := &commitJournal{}
+	journal.logf("applied reservation-add in kea@192.0.2.1")
+	module.recordCommitEvent(ctx, journal, nil)
+
+	journal.logf("rolled back reservation-add in kea@192.0.2.1")
+	module.recordCommitEvent(ctx, journal, fmt.Errorf("reservation-add command to kea@192.0.2.2 failed"))
+
+	require.Len(t, eventCenter.Events, 2)
+
+	require.Equal(t, dbmodel.EvInfo, eventCenter.Events[0].Level)
+	require.Equal(t, "committed Kea configuration changes (host_add)", eventCenter.Events[0].Text)
+	require.Equal(t, "daemons: 1, 2\napplied reservation-add in kea@192.0.2.1", eventCenter.Events[0].Details)
+
+	require.Equal(t, dbmodel.EvError, eventCenter.Events[1].Level)
+	require.Equal(t, "failed to commit Kea configuration changes (host_add)", eventCenter.Events[1].Text)
+	require.Contains(t, eventCenter.Events[1].Details, "rolled back reservation-add in kea@192.0.2.1")
+	require.Contains(t, eventCenter.Events[1].Details, "error: reservation-add command to kea@192.0.2.2 failed")
+}
+
+// Test committing the global parameters update in the two-phase mode.
+func TestCommitGlobalParametersUpdateTwoPhase(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	err := dbmodel.InitializeSettings(db, 0)
+	require.NoError(t, err)
+	err = dbmodel.SetSettingBool(db, "kea_two_phase_commit", true)
+	require.NoError(t, err)
+
+	user := &dbmodel.SystemUser{
+		Login:    "test",
+		Lastname: "test",
+		Name:     "test",
+	}
+	_, err = dbmodel.CreateUserWithPassword(db, user, "test")
+	require.NoError(t, err)
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+
+	// The config-test succeeds and the config-set fails.
+	agents := agentcommtest.NewKeaFakeAgents(mockKeaResult(keactrl.ResponseSuccess), mockKeaResult(keactrl.ResponseError), mockKeaResult(keactrl.ResponseSuccess))
+	eventCenter := &storktestdbmodel.FakeEventCenter{}
+	manager := newTestManager(&appstest.ManagerAccessorsWrapper{
+		DB:          db,
+		Agents:      agents,
+		EventCenter: eventCenter,
+	})
+	module := NewConfigModule(manager)
+
+	daemonID := apps[0].Daemons[0].ID
+	ctx := context.WithValue(context.Background(), config.UserContextKey, int64(user.ID))
+	ctx, err = module.BeginGlobalParametersUpdate(ctx, []int64{daemonID})
+	require.NoError(t, err)
+
+	ctx, err = module.ApplyGlobalParametersUpdate(ctx, map[int64]*keaconfig.SettableGlobalParameters{
+		daemonID: {
+			ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+				ValidLifetime: storkutil.Ptr(int64(1800)),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = module.Commit(ctx)
+	require.ErrorContains(t, err, "config-set command")
+
+	// No changes have been applied, so there is nothing to roll back. The
+	// config-write must not be sent.
+	require.Len(t, agents.RecordedCommands, 2)
+	require.Equal(t, "config-test", agents.RecordedCommands[0].GetCommand())
+	require.Equal(t, "config-set", agents.RecordedCommands[1].GetCommand())
+
+	// A single event should be recorded.
+	require.Len(t, eventCenter.Events, 1)
+	require.Equal(t, dbmodel.EvError, eventCenter.Events[0].Level)
+	require.Contains(t, eventCenter.Events[0].Text, "failed to commit Kea configuration changes (global_parameters_update)")
+	require.EqualValues(t, user.ID, eventCenter.Events[0].Relations.UserID)
+	require.Contains(t, eventCenter.Events[0].Details, "validated config-test")
+}
@@ -0,0 +1,203 @@
+package kea
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/eventcenter"
+)
+
+// Returns the utilization alert level for the given utilization and
+// thresholds, in percent. The zero threshold disables the respective
+// alert level. The alert level is raised as soon as the utilization
+// reaches the threshold but it is lowered only when the utilization
+// drops below the threshold of the current level by more than the
+// hysteresis. It prevents raising the alerts repeatedly when the
+// utilization oscillates around the threshold.
+func getUtilizationAlertLevel(current dbmodel.UtilizationAlertLevel, utilization, warning, critical, hysteresis float64) dbmodel.UtilizationAlertLevel {
+	level := dbmodel.UtilizationAlertNone
+	switch {
+	case critical > 0 && utilization >= critical:
+		level = dbmodel.UtilizationAlertCritical
+	case warning > 0 && utilization >= warning:
+		level = dbmodel.UtilizationAlertWarning
+	}
+	if level >= current {
+		return level
+	}
+	if current == dbmodel.UtilizationAlertCritical && critical > 0 && utilization >= critical-hysteresis {
+		return dbmodel.UtilizationAlertCritical
+	}
+	if level == dbmodel.UtilizationAlertNone && warning > 0 && utilization >= warning-hysteresis {
+		return dbmodel.UtilizationAlertWarning
+	}
+	return level
+}
+
+// Evaluates the utilization alert rules for the subnets and the shared
+// networks and raises the events when the alert levels change. The rule
+// specified for a subnet takes precedence over the rule specified for
+// its shared network. The global rule is used when neither of them is
+// specified.
+type utilizationAlertEvaluator struct {
+	eventCenter    eventcenter.EventCenter
+	global         dbmodel.UtilizationAlertRule
+	subnets        map[int64]*dbmodel.UtilizationAlertRule
+	sharedNetworks map[int64]*dbmodel.UtilizationAlertRule
+}
+
+// Creates the evaluator for the given rules. All alerts are disabled if
+// the global rule is not specified.
+func newUtilizationAlertEvaluator(eventCenter eventcenter.EventCenter, rules []dbmodel.UtilizationAlertRule) *utilizationAlertEvaluator {
+	evaluator := &utilizationAlertEvaluator{
+		eventCenter:    eventCenter,
+		subnets:        make(map[int64]*dbmodel.UtilizationAlertRule),
+		sharedNetworks: make(map[int64]*dbmodel.UtilizationAlertRule),
+	}
+	for i := range rules {
+		rule := &rules[i]
+		switch {
+		case rule.SubnetID != 0:
+			evaluator.subnets[rule.SubnetID] = rule
+		case rule.SharedNetworkID != 0:
+			evaluator.sharedNetworks[rule.SharedNetworkID] = rule
+		default:
+			evaluator.global = *rule
+		}
+	}
+	return evaluator
+}
+
+// Returns the rule applying to the subnet.
+func (e *utilizationAlertEvaluator) getSubnetRule(subnet *dbmodel.Subnet) *dbmodel.UtilizationAlertRule {
+	if rule, ok := e.subnets[subnet.ID]; ok {
+		return rule
+	}
+	if rule, ok := e.sharedNetworks[subnet.SharedNetworkID]; ok {
+		return rule
+	}
+	return &e.global
+}
+
+// Returns the rule applying to the shared network.
+func (e *utilizationAlertEvaluator) getSharedNetworkRule(network *dbmodel.SharedNetwork) *dbmodel.UtilizationAlertRule {
+	if rule, ok := e.sharedNetworks[network.ID]; ok {
+		return rule
+	}
+	return &e.global
+}
+
+// Raises an event describing the alert level change. The name is used
+// in the event text to refer to the subnet or the shared network. The
+// objects are passed to the event center.
+func (e *utilizationAlertEvaluator) raiseEvent(kind, name string, objects []any, previous, current dbmodel.UtilizationAlertLevel, utilization, threshold float64) {
+	switch {
+	case current > previous && current == dbmodel.UtilizationAlertCritical:
+		e.eventCenter.AddErrorEvent(fmt.Sprintf("%s utilization in %s reached %.1f%%, exceeding the critical threshold of %.1f%%",
+			kind, name, utilization, threshold), objects...)
+	case current > previous:
+		e.eventCenter.AddWarningEvent(fmt.Sprintf("%s utilization in %s reached %.1f%%, exceeding the warning threshold of %.1f%%",
+			kind, name, utilization, threshold), objects...)
+	case current == dbmodel.UtilizationAlertNone:
+		e.eventCenter.AddInfoEvent(fmt.Sprintf("%s utilization in %s dropped to %.1f%%, the alert is cleared",
+			kind, name, utilization), objects...)
+	default:
+		e.eventCenter.AddInfoEvent(fmt.Sprintf("%s utilization in %s dropped to %.1f%%, the alert is lowered to %s",
+			kind, name, utilization, current), objects...)
+	}
+}
+
+// Evaluates the address and delegated prefix utilization alert levels.
+// It raises the events for the changed levels and returns the new levels.
+func (e *utilizationAlertEvaluator) evaluate(rule *dbmodel.UtilizationAlertRule, name string, objects []any, addrUtilization, pdUtilization int16, addrAlert, pdAlert dbmodel.UtilizationAlertLevel) (dbmodel.UtilizationAlertLevel, dbmodel.UtilizationAlertLevel) {
+	// The utilizations are stored in per-mille.
+	addrPercent := float64(addrUtilization) / 10
+	pdPercent := float64(pdUtilization) / 10
+
+	newAddrAlert := getUtilizationAlertLevel(addrAlert, addrPercent, rule.AddrWarning, rule.AddrCritical, rule.Hysteresis)
+	if newAddrAlert != addrAlert {
+		threshold := rule.AddrWarning
+		if newAddrAlert == dbmodel.UtilizationAlertCritical {
+			threshold = rule.AddrCritical
+		}
+		e.raiseEvent("Address", name, objects, addrAlert, newAddrAlert, addrPercent, threshold)
+	}
+	newPdAlert := getUtilizationAlertLevel(pdAlert, pdPercent, rule.PdWarning, rule.PdCritical, rule.Hysteresis)
+	if newPdAlert != pdAlert {
+		threshold := rule.PdWarning
+		if newPdAlert == dbmodel.UtilizationAlertCritical {
+			threshold = rule.PdCritical
+		}
+		e.raiseEvent("Delegated prefix", name, objects, pdAlert, newPdAlert, pdPercent, threshold)
+	}
+	return newAddrAlert, newPdAlert
+}
+
+// Evaluates the utilization alerts of the subnet. It returns true when
+// the alert levels have changed. The new levels are set in the subnet.
+func (e *utilizationAlertEvaluator) evaluateSubnet(subnet *dbmodel.Subnet) bool {
+	addrAlert, pdAlert := e.evaluate(e.getSubnetRule(subnet), "subnet {subnet}", []any{subnet},
+		subnet.AddrUtilization, subnet.PdUtilization, subnet.AddrUtilizationAlert, subnet.PdUtilizationAlert)
+	if addrAlert == subnet.AddrUtilizationAlert && pdAlert == subnet.PdUtilizationAlert {
+		return false
+	}
+	subnet.AddrUtilizationAlert = addrAlert
+	subnet.PdUtilizationAlert = pdAlert
+	return true
+}
+
+// Evaluates the utilization alerts of the shared network. It returns true
+// when the alert levels have changed. The new levels are set in the
+// shared network.
+func (e *utilizationAlertEvaluator) evaluateSharedNetwork(network *dbmodel.SharedNetwork) bool {
+	addrAlert, pdAlert := e.evaluate(e.getSharedNetworkRule(network), "shared network {sharedNetwork}", []any{network},
+		network.AddrUtilization, network.PdUtilization, network.AddrUtilizationAlert, network.PdUtilizationAlert)
+	if addrAlert == network.AddrUtilizationAlert && pdAlert == network.PdUtilizationAlert {
+		return false
+	}
+	network.AddrUtilizationAlert = addrAlert
+	network.PdUtilizationAlert = pdAlert
+	return true
+}
+
+// Evaluates the utilization alert rules for the subnets with freshly
+// updated utilizations and for all shared networks. The changed alert
+// levels are stored in the database. It returns the last encountered
+// error.
+func evaluateUtilizationAlerts(db dbops.DBI, eventCenter eventcenter.EventCenter, subnets []*dbmodel.Subnet) error {
+	rules, err := dbmodel.GetUtilizationAlertRules(db)
+	if err != nil {
+		return err
+	}
+	evaluator := newUtilizationAlertEvaluator(eventCenter, rules)
+
+	var lastErr error
+	for _, subnet := range subnets {
+		if !evaluator.evaluateSubnet(subnet) {
+			continue
+		}
+		err = dbmodel.SetSubnetUtilizationAlerts(db, subnet.ID, subnet.AddrUtilizationAlert, subnet.PdUtilizationAlert)
+		if err != nil {
+			lastErr = err
+			log.Errorf("Cannot update utilization alerts in subnet %d: %s", subnet.ID, err)
+		}
+	}
+
+	networks, err := dbmodel.GetSharedNetworksWithUtilization(db)
+	if err != nil {
+		return err
+	}
+	for _, network := range networks {
+		if !evaluator.evaluateSharedNetwork(network) {
+			continue
+		}
+		err = dbmodel.SetSharedNetworkUtilizationAlerts(db, network.ID, network.AddrUtilizationAlert, network.PdUtilizationAlert)
+		if err != nil {
+			lastErr = err
+			log.Errorf("Cannot update utilization alerts in shared network %d: %s", network.ID, err)
+		}
+	}
+	return lastErr
+}
@@ -0,0 +1,195 @@
+package kea
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Test that the alert level is raised when the utilization reaches the
+// thresholds and lowered when it drops below them by the hysteresis.
+func TestGetUtilizationAlertLevel(t *testing.T) {
+	none := dbmodel.UtilizationAlertNone
+	warning := dbmodel.UtilizationAlertWarning
+	critical := dbmodel.UtilizationAlertCritical
+
+	testCases := []struct {
+		name        string
+		current     dbmodel.UtilizationAlertLevel
+		utilization float64
+		expected    dbmodel.UtilizationAlertLevel
+	}{
+		{"below warning", none, 79.9, none},
+		{"at warning", none, 80, warning},
+		{"at critical", none, 90, critical},
+		{"warning to critical", warning, 95, critical},
+		{"warning within hysteresis", warning, 76, warning},
+		{"warning cleared", warning, 74.9, none},
+		{"critical within hysteresis", critical, 86, critical},
+		{"critical lowered to warning", critical, 84, warning},
+		{"critical lowered below warning within hysteresis", critical, 77, warning},
+		{"critical cleared", critical, 50, none},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			level := getUtilizationAlertLevel(testCase.current, testCase.utilization, 80, 90, 5)
+			require.Equal(t, testCase.expected, level)
+		})
+	}
+}
+
+// Test that the zero thresholds disable the alerts.
+func TestGetUtilizationAlertLevelDisabled(t *testing.T) {
+	require.Equal(t, dbmodel.UtilizationAlertNone,
+		getUtilizationAlertLevel(dbmodel.UtilizationAlertNone, 100, 0, 0, 5))
+	require.Equal(t, dbmodel.UtilizationAlertCritical,
+		getUtilizationAlertLevel(dbmodel.UtilizationAlertNone, 100, 0, 90, 5))
+	require.Equal(t, dbmodel.UtilizationAlertWarning,
+		getUtilizationAlertLevel(dbmodel.UtilizationAlertNone, 100, 80, 0, 5))
+	// Disabling the threshold clears the alert.
+	require.Equal(t, dbmodel.UtilizationAlertNone,
+		getUtilizationAlertLevel(dbmodel.UtilizationAlertCritical, 100, 0, 0, 5))
+}
+
+// Test that the subnet rule takes precedence over the shared network rule
+// and the shared network rule takes precedence over the global rule.
+func TestUtilizationAlertEvaluatorRules(t *testing.T) {
+	rules := []dbmodel.UtilizationAlertRule{
+		{ID: 1, AddrWarning: 80},
+		{ID: 2, SubnetID: 1, AddrWarning: 50},
+		{ID: 3, SharedNetworkID: 1, AddrWarning: 60},
+	}
+	evaluator := newUtilizationAlertEvaluator(&storktest.FakeEventCenter{}, rules)
+
+	require.EqualValues(t, 50, evaluator.getSubnetRule(&dbmodel.Subnet{ID: 1, SharedNetworkID: 1}).AddrWarning)
+	require.EqualValues(t, 60, evaluator.getSubnetRule(&dbmodel.Subnet{ID: 2, SharedNetworkID: 1}).AddrWarning)
+	require.EqualValues(t, 80, evaluator.getSubnetRule(&dbmodel.Subnet{ID: 3}).AddrWarning)
+	require.EqualValues(t, 60, evaluator.getSharedNetworkRule(&dbmodel.SharedNetwork{ID: 1}).AddrWarning)
+	require.EqualValues(t, 80, evaluator.getSharedNetworkRule(&dbmodel.SharedNetwork{ID: 2}).AddrWarning)
+
+	// No global rule disables the alerts.
+	evaluator = newUtilizationAlertEvaluator(&storktest.FakeEventCenter{}, []dbmodel.UtilizationAlertRule{})
+	require.Zero(t, evaluator.getSubnetRule(&dbmodel.Subnet{ID: 1}).AddrWarning)
+}
+
+// Test that the events are raised when the subnet alert levels change.
+func TestUtilizationAlertEvaluatorSubnetEvents(t *testing.T) {
+	fec := &storktest.FakeEventCenter{}
+	rules := []dbmodel.UtilizationAlertRule{
+		{ID: 1, AddrWarning: 80, AddrCritical: 90, PdWarning: 80, PdCritical: 90, Hysteresis: 5},
+	}
+	evaluator := newUtilizationAlertEvaluator(fec, rules)
+
+	subnet := &dbmodel.Subnet{
+		ID:              1,
+		Prefix:          "2001:db8:1::/64",
+		AddrUtilization: 850,
+		PdUtilization:   950,
+	}
+	require.True(t, evaluator.evaluateSubnet(subnet))
+	require.Equal(t, dbmodel.UtilizationAlertWarning, subnet.AddrUtilizationAlert)
+	require.Equal(t, dbmodel.UtilizationAlertCritical, subnet.PdUtilizationAlert)
+	require.Len(t, fec.Events, 2)
+	require.Equal(t, dbmodel.EvWarning, fec.Events[0].Level)
+	require.Contains(t, fec.Events[0].Text, "Address utilization in subnet <subnet")
+	require.Contains(t, fec.Events[0].Text, "warning threshold of 80.0%")
+	require.EqualValues(t, 1, fec.Events[0].Relations.SubnetID)
+	require.Equal(t, dbmodel.EvError, fec.Events[1].Level)
+	require.Contains(t, fec.Events[1].Text, "Delegated prefix utilization")
+	require.Contains(t, fec.Events[1].Text, "critical threshold of 90.0%")
+
+	// No change.
+	require.False(t, evaluator.evaluateSubnet(subnet))
+	require.Len(t, fec.Events, 2)
+
+	// The address alert is cleared and the delegated prefix alert is
+	// lowered.
+	subnet.AddrUtilization = 100
+	subnet.PdUtilization = 820
+	require.True(t, evaluator.evaluateSubnet(subnet))
+	require.Equal(t, dbmodel.UtilizationAlertNone, subnet.AddrUtilizationAlert)
+	require.Equal(t, dbmodel.UtilizationAlertWarning, subnet.PdUtilizationAlert)
+	require.Len(t, fec.Events, 4)
+	require.Equal(t, dbmodel.EvInfo, fec.Events[2].Level)
+	require.Contains(t, fec.Events[2].Text, "the alert is cleared")
+	require.Equal(t, dbmodel.EvInfo, fec.Events[3].Level)
+	require.Contains(t, fec.Events[3].Text, "the alert is lowered to warning")
+}
+
+// Test that the events are raised when the shared network alert levels
+// change.
+func TestUtilizationAlertEvaluatorSharedNetworkEvents(t *testing.T) {
+	fec := &storktest.FakeEventCenter{}
+	rules := []dbmodel.UtilizationAlertRule{
+		{ID: 1, AddrWarning: 80, AddrCritical: 90},
+	}
+	evaluator := newUtilizationAlertEvaluator(fec, rules)
+
+	network := &dbmodel.SharedNetwork{
+		ID:              1,
+		Name:            "frog",
+		AddrUtilization: 910,
+	}
+	require.True(t, evaluator.evaluateSharedNetwork(network))
+	require.Equal(t, dbmodel.UtilizationAlertCritical, network.AddrUtilizationAlert)
+	require.Equal(t, dbmodel.UtilizationAlertNone, network.PdUtilizationAlert)
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvError, fec.Events[0].Level)
+	require.Equal(t, "Address utilization in shared network <shared-network id=\"1\" name=\"frog\"> reached 91.0%, exceeding the critical threshold of 90.0%", fec.Events[0].Text)
+	require.EqualValues(t, 1, fec.Events[0].Relations.SharedNetworkID)
+}
+
+// Test that the alert levels are evaluated and stored in the database.
+func TestEvaluateUtilizationAlerts(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	network := &dbmodel.SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err := dbmodel.AddSharedNetwork(db, network)
+	require.NoError(t, err)
+
+	subnet := &dbmodel.Subnet{
+		Prefix:          "192.0.2.0/24",
+		SharedNetworkID: network.ID,
+	}
+	err = dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	err = dbmodel.UpdateStatisticsInSharedNetwork(db, network.ID, newSharedNetworkStats())
+	require.NoError(t, err)
+
+	// Lower the thresholds for the shared network and its subnets.
+	err = dbmodel.AddUtilizationAlertRule(db, &dbmodel.UtilizationAlertRule{
+		SharedNetworkID: network.ID,
+		AddrWarning:     20,
+	})
+	require.NoError(t, err)
+
+	subnets, err := dbmodel.GetSubnetsWithLocalSubnets(db)
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+	subnets[0].AddrUtilization = 300
+
+	fec := &storktest.FakeEventCenter{}
+	err = evaluateUtilizationAlerts(db, fec, subnets)
+	require.NoError(t, err)
+	require.Len(t, fec.Events, 1)
+
+	returned, err := dbmodel.GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.Equal(t, dbmodel.UtilizationAlertWarning, returned.AddrUtilizationAlert)
+	require.Equal(t, dbmodel.UtilizationAlertNone, returned.PdUtilizationAlert)
+
+	// The shared network utilization is zero.
+	networks, err := dbmodel.GetSharedNetworksWithUtilization(db)
+	require.NoError(t, err)
+	require.Len(t, networks, 1)
+	require.Equal(t, dbmodel.UtilizationAlertNone, networks[0].AddrUtilizationAlert)
+}
@@ -0,0 +1,39 @@
+package kea
+
+import (
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	log "github.com/sirupsen/logrus"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Stores the raw utilization samples, rolls up the samples taken in the
+// past hours and days and deletes the samples older than the retention
+// periods. The samples are rolled up before they are deleted, so the
+// retention of the finer samples doesn't affect the coarser samples.
+func storeUtilizationHistory(db *pg.DB, samples []*dbmodel.UtilizationSample, now time.Time) error {
+	if err := dbmodel.AddUtilizationSamples(db, samples); err != nil {
+		return err
+	}
+	if err := dbmodel.RollUpUtilizationSamples(db, now); err != nil {
+		return err
+	}
+
+	resolutions := []dbmodel.UtilizationResolution{
+		dbmodel.UtilizationResolutionRaw,
+		dbmodel.UtilizationResolutionHourly,
+		dbmodel.UtilizationResolutionDaily,
+	}
+	for _, resolution := range resolutions {
+		retention := dbmodel.GetUtilizationRetention(db, resolution)
+		deleted, err := dbmodel.DeleteUtilizationSamplesBefore(db, resolution, now.Add(-retention))
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			log.Debugf("Deleted %d %s utilization samples older than %s", deleted, resolution, retention)
+		}
+	}
+	return nil
+}
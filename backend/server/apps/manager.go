@@ -17,6 +17,7 @@ import (
 	"isc.org/stork/server/apps/kea"
 	"isc.org/stork/server/config"
 	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/eventcenter"
 )
 
 // Represents a configuration lock for a user.
@@ -44,6 +45,8 @@ type configManagerImpl struct {
 	// Interface to the instance providing functions to search for
 	// option definitions.
 	lookup keaconfig.DHCPOptionDefinitionLookup
+	// Interface to the event center recording the configuration changes.
+	eventCenter eventcenter.EventCenter
 	// Holds contexts for present transactions. The unique context
 	// identifier exchanged between the server and the client is a
 	// key of this map.
@@ -61,6 +64,8 @@ type configManagerImpl struct {
 	// An interface to the configuration manager's module responsible
 	// for managing Kea configuration.
 	kea config.KeaModule
+	// Commits the scheduled configuration changes at their deadlines.
+	scheduler *configChangeScheduler
 }
 
 // Generates a key for a newly acquired lock. It is called internally
@@ -161,19 +166,32 @@ func (manager *configManagerImpl) unlock(ctx context.Context) {
 // instance of the Stork Server holding the state.).
 func NewManager(server config.ManagerAccessors) config.Manager {
 	manager := &configManagerImpl{
-		db:       server.GetDB(),
-		agents:   server.GetConnectedAgents(),
-		lookup:   server.GetDHCPOptionDefinitionLookup(),
-		contexts: make(map[int64]contextPair),
-		locks:    make(map[int64]configLock),
-		mutex:    &sync.RWMutex{},
+		db:          server.GetDB(),
+		agents:      server.GetConnectedAgents(),
+		lookup:      server.GetDHCPOptionDefinitionLookup(),
+		eventCenter: server.GetEventCenter(),
+		contexts:    make(map[int64]contextPair),
+		locks:       make(map[int64]configLock),
+		mutex:       &sync.RWMutex{},
 	}
 	keaConfigModule := kea.NewConfigModule(manager)
 	manager.kea = keaConfigModule
 	manager.keaCommit = keaConfigModule
+	manager.scheduler = newConfigChangeScheduler(manager)
 	return manager
 }
 
+// Starts committing the scheduled configuration changes when their
+// deadlines expire.
+func (manager *configManagerImpl) Start() {
+	manager.scheduler.start()
+}
+
+// Stops committing the scheduled configuration changes.
+func (manager *configManagerImpl) Shutdown() {
+	manager.scheduler.shutdown()
+}
+
 // Returns the database handle instance. It is used by the configuration
 // modules to access the database.
 func (manager *configManagerImpl) GetDB() *pg.DB {
@@ -191,6 +209,12 @@ func (manager *configManagerImpl) GetDHCPOptionDefinitionLookup() keaconfig.DHCP
 	return manager.lookup
 }
 
+// Returns an interface to the event center recording the configuration
+// changes.
+func (manager *configManagerImpl) GetEventCenter() eventcenter.EventCenter {
+	return manager.eventCenter
+}
+
 // Returns Kea configuration module of the configuration manager.
 func (manager *configManagerImpl) GetKeaModule() config.KeaModule {
 	return manager.kea
@@ -411,6 +435,7 @@ func (manager *configManagerImpl) CommitDue() error {
 
 // Schedules sending the changes queued in the context to one or multiple daemons.
 // The deadline parameter specifies the time when the changes should be committed.
+// The returned context holds the ID of the scheduled config change.
 func (manager *configManagerImpl) Schedule(ctx context.Context, deadline time.Time) (context.Context, error) {
 	state, ok := config.GetAnyTransactionState(ctx)
 	if !ok {
@@ -425,7 +450,11 @@ func (manager *configManagerImpl) Schedule(ctx context.Context, deadline time.Ti
 		DeadlineAt: deadline,
 		UserID:     userID,
 	}
+	hasKeaUpdates := false
 	for _, u := range state.GetUpdates() {
+		if u.Target == datamodel.AppTypeKea {
+			hasKeaUpdates = true
+		}
 		update := &dbmodel.ConfigUpdate{
 			Target:    u.Target,
 			Operation: u.Operation,
@@ -441,5 +470,23 @@ func (manager *configManagerImpl) Schedule(ctx context.Context, deadline time.Ti
 	if err := dbmodel.AddScheduledConfigChange(manager.db, scc); err != nil {
 		return ctx, err
 	}
+	// Record the scheduled change in the config change log.
+	if hasKeaUpdates {
+		manager.keaCommit.RecordSchedule(ctx, deadline)
+	}
+	// The new config change may be due earlier than the next one the
+	// scheduler waits for.
+	manager.scheduler.wake()
+	ctx = context.WithValue(ctx, config.ScheduledChangeContextKey, scc.ID)
 	return ctx, nil
 }
+
+// Sets new deadline for the specified scheduled config change. The change
+// must not be executed yet.
+func (manager *configManagerImpl) Reschedule(changeID int64, deadline time.Time) error {
+	if err := dbmodel.RescheduleConfigChange(manager.db, changeID, deadline); err != nil {
+		return err
+	}
+	manager.scheduler.wake()
+	return nil
+}
@@ -30,9 +30,10 @@ func (lackingStateError) Error() string {
 // test that the manager's Commit() function properly routes
 // the calls to the Commit() function in the Kea module.
 type fakeKeaModuleCommit struct {
-	contexts []context.Context
-	ops      []string
-	err      error
+	contexts  []context.Context
+	ops       []string
+	deadlines []time.Time
+	err       error
 }
 
 // Creates new instance of the fake Kea module.
@@ -54,6 +55,12 @@ func (fkm *fakeKeaModuleCommit) Commit(ctx context.Context) (context.Context, er
 	return ctx, fkm.err
 }
 
+// Implementation of the fake RecordSchedule() function. It records
+// the deadlines of the scheduled changes.
+func (fkm *fakeKeaModuleCommit) RecordSchedule(ctx context.Context, deadline time.Time) {
+	fkm.deadlines = append(fkm.deadlines, deadline)
+}
+
 // Test creating new config manager instance.
 func TestNewManager(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
@@ -721,13 +728,27 @@ func TestSchedule(t *testing.T) {
 	ctx = context.WithValue(ctx, config.StateContextKey, state)
 
 	// Schedule the change.
-	_, err = manager.Schedule(ctx, storkutil.UTCNow().Add(time.Second*100))
+	deadline := storkutil.UTCNow().Add(time.Second * 100)
+	ctx, err = manager.Schedule(ctx, deadline)
+	require.NoError(t, err)
+
+	// The scheduled change should be recorded in the config change log.
+	entries, _, err := dbmodel.GetConfigChangeLogEntriesByPage(db, 0, 10, nil, "", dbmodel.SortDirAny)
 	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "host_add", entries[0].Operation)
+	require.True(t, entries[0].Scheduled)
+	require.WithinDuration(t, deadline, entries[0].DeadlineAt, time.Second)
 
 	// Ensure that the change has been added to the database.
 	changes, err := dbmodel.GetScheduledConfigChanges(db)
 	require.NoError(t, err)
 	require.Len(t, changes, 1)
+
+	// The context should hold the ID of the scheduled change.
+	changeID, ok := config.GetValueAsInt64(ctx, config.ScheduledChangeContextKey)
+	require.True(t, ok)
+	require.Equal(t, changes[0].ID, changeID)
 	require.Len(t, changes[0].Updates, 1)
 	require.Equal(t, dbmodel.AppTypeKea, changes[0].Updates[0].Target)
 	require.Equal(t, "host_add", changes[0].Updates[0].Operation)
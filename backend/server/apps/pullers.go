@@ -7,9 +7,11 @@ import (
 
 // Collection of pullers used by the server.
 type Pullers struct {
-	AppsStatePuller  *StatePuller
-	Bind9StatsPuller *bind9.StatsPuller
-	KeaStatsPuller   *kea.StatsPuller
-	KeaHostsPuller   *kea.HostsPuller
-	HAStatusPuller   *kea.HAStatusPuller
+	AppsStatePuller    *StatePuller
+	Bind9StatsPuller   *bind9.StatsPuller
+	Bind9ZonesPuller   *bind9.ZonesPuller
+	KeaStatsPuller     *kea.StatsPuller
+	KeaHostsPuller     *kea.HostsPuller
+	HAStatusPuller     *kea.HAStatusPuller
+	KeaLogEventsPuller *kea.LogEventsPuller
 }
@@ -0,0 +1,127 @@
+package apps
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+const (
+	// Maximum time the scheduler waits before checking for the due
+	// config changes. It protects against missing the changes scheduled
+	// without waking up the scheduler.
+	maxScheduledConfigChangeWait = 10 * time.Minute
+	// Minimum time the scheduler waits before checking for the due
+	// config changes again. It prevents busy looping when committing
+	// the due changes fails.
+	minScheduledConfigChangeWait = time.Second
+)
+
+// Commits the scheduled config changes when their deadlines expire. It
+// runs a goroutine waiting until the deadline of the next scheduled
+// config change. The goroutine must be woken up when a config change is
+// scheduled or rescheduled because the deadline of the next config
+// change may be earlier than the one the goroutine waits for.
+type configChangeScheduler struct {
+	// Commits the due config changes.
+	commitDue func() error
+	// Returns the time to the deadline of the next scheduled config change.
+	getTimeToNext func() (time.Duration, bool, error)
+	// Channel used to wake up the goroutine.
+	wakeup chan struct{}
+	// Channel used to stop the goroutine.
+	done chan struct{}
+	// Wait group used to wait for the goroutine to stop.
+	wg sync.WaitGroup
+	// Guards against starting or stopping the goroutine multiple times.
+	mutex   sync.Mutex
+	running bool
+}
+
+// Creates new scheduler instance. The manager commits the due config
+// changes stored in its database.
+func newConfigChangeScheduler(manager *configManagerImpl) *configChangeScheduler {
+	return &configChangeScheduler{
+		commitDue: manager.CommitDue,
+		getTimeToNext: func() (time.Duration, bool, error) {
+			return dbmodel.GetTimeToNextScheduledConfigChange(manager.GetDB())
+		},
+		wakeup: make(chan struct{}, 1),
+	}
+}
+
+// Starts the goroutine committing the scheduled config changes. It
+// does nothing if the goroutine is already running.
+func (scheduler *configChangeScheduler) start() {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	if scheduler.running {
+		return
+	}
+	scheduler.running = true
+	scheduler.done = make(chan struct{})
+	scheduler.wg.Add(1)
+	go scheduler.run(scheduler.done)
+}
+
+// Stops the goroutine committing the scheduled config changes and waits
+// until it finishes. It does nothing if the goroutine is not running.
+func (scheduler *configChangeScheduler) shutdown() {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	if !scheduler.running {
+		return
+	}
+	close(scheduler.done)
+	scheduler.wg.Wait()
+	scheduler.running = false
+}
+
+// Wakes up the goroutine to commit the due config changes and to check
+// the time to the next scheduled config change. It doesn't block.
+func (scheduler *configChangeScheduler) wake() {
+	select {
+	case scheduler.wakeup <- struct{}{}:
+	default:
+		// The goroutine has been already woken up.
+	}
+}
+
+// Commits the due config changes and returns the time to wait until the
+// next scheduled config change.
+func (scheduler *configChangeScheduler) commitDueAndGetWait() time.Duration {
+	if err := scheduler.commitDue(); err != nil {
+		log.WithError(err).Error("Problem committing scheduled config changes")
+	}
+	wait := maxScheduledConfigChangeWait
+	next, ok, err := scheduler.getTimeToNext()
+	switch {
+	case err != nil:
+		log.WithError(err).Error("Problem getting time to the next scheduled config change")
+	case ok && next < wait:
+		wait = next
+	}
+	if wait < minScheduledConfigChangeWait {
+		wait = minScheduledConfigChangeWait
+	}
+	return wait
+}
+
+// Goroutine committing the due config changes and waiting until the
+// next scheduled config change, wake up or shutdown.
+func (scheduler *configChangeScheduler) run(done <-chan struct{}) {
+	defer scheduler.wg.Done()
+	for {
+		timer := time.NewTimer(scheduler.commitDueAndGetWait())
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-scheduler.wakeup:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
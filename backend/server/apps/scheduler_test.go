@@ -0,0 +1,126 @@
+package apps
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	appstest "isc.org/stork/server/apps/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storkutil "isc.org/stork/util"
+)
+
+// Test that the scheduler commits the due changes on start and when
+// it is woken up, and stops on shutdown.
+func TestConfigChangeSchedulerWakeUp(t *testing.T) {
+	var commits int32
+	scheduler := &configChangeScheduler{
+		commitDue: func() error {
+			atomic.AddInt32(&commits, 1)
+			return nil
+		},
+		getTimeToNext: func() (time.Duration, bool, error) {
+			// No scheduled changes.
+			return 0, false, nil
+		},
+		wakeup: make(chan struct{}, 1),
+	}
+	scheduler.start()
+	// Starting twice should have no effect.
+	scheduler.start()
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&commits) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	scheduler.wake()
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&commits) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	scheduler.shutdown()
+	// Stopping twice should have no effect.
+	scheduler.shutdown()
+
+	// The stopped scheduler doesn't commit the changes.
+	scheduler.wake()
+	time.Sleep(50 * time.Millisecond)
+	require.EqualValues(t, 2, atomic.LoadInt32(&commits))
+}
+
+// Test that the scheduler waits until the deadline of the next scheduled
+// change and then commits it.
+func TestConfigChangeSchedulerWaitsForDeadline(t *testing.T) {
+	var commits int32
+	start := time.Now()
+	scheduler := &configChangeScheduler{
+		commitDue: func() error {
+			atomic.AddInt32(&commits, 1)
+			return nil
+		},
+		getTimeToNext: func() (time.Duration, bool, error) {
+			if atomic.LoadInt32(&commits) > 1 {
+				return 0, false, nil
+			}
+			return minScheduledConfigChangeWait, true, nil
+		},
+		wakeup: make(chan struct{}, 1),
+	}
+	scheduler.start()
+	defer scheduler.shutdown()
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&commits) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.GreaterOrEqual(t, time.Since(start), minScheduledConfigChangeWait)
+}
+
+// Test that the started config manager commits the scheduled config
+// change when its deadline expires.
+func TestManagerCommitsScheduledConfigChange(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	// Scheduled config changes must be associated with a user.
+	user := &dbmodel.SystemUser{
+		Login:    "test",
+		Lastname: "test",
+		Name:     "test",
+	}
+	_, err := dbmodel.CreateUser(db, user)
+	require.NoError(t, err)
+
+	manager := NewManager(&appstest.ManagerAccessorsWrapper{
+		DB: db,
+	})
+	impl := manager.(*configManagerImpl)
+	fkm := newFakeKeaModuleCommit()
+	impl.keaCommit = fkm
+
+	manager.Start()
+	defer manager.Shutdown()
+
+	// Schedule the change in the distant future and then make it due.
+	change := &dbmodel.ScheduledConfigChange{
+		DeadlineAt: storkutil.UTCNow().Add(time.Hour),
+		UserID:     int64(user.ID),
+		Updates: []*dbmodel.ConfigUpdate{
+			dbmodel.NewConfigUpdate(dbmodel.AppTypeKea, "host_add"),
+		},
+	}
+	err = dbmodel.AddScheduledConfigChange(db, change)
+	require.NoError(t, err)
+
+	err = manager.Reschedule(change.ID, storkutil.UTCNow().Add(-time.Second))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		returned, err := dbmodel.GetScheduledConfigChange(db, change.ID)
+		return err == nil && returned != nil && returned.Executed
+	}, 5*time.Second, 50*time.Millisecond)
+
+	manager.Shutdown()
+	require.Equal(t, []string{"kea.host_add"}, fkm.ops)
+}
@@ -4,13 +4,15 @@ import (
 	"github.com/go-pg/pg/v10"
 	keaconfig "isc.org/stork/appcfg/kea"
 	agentcomm "isc.org/stork/server/agentcomm"
+	eventcenter "isc.org/stork/server/eventcenter"
 )
 
 // Implements ManagerAccessors interface for unit tests.
 type ManagerAccessorsWrapper struct {
-	DB        *pg.DB
-	Agents    agentcomm.ConnectedAgents
-	DefLookup keaconfig.DHCPOptionDefinitionLookup
+	DB          *pg.DB
+	Agents      agentcomm.ConnectedAgents
+	DefLookup   keaconfig.DHCPOptionDefinitionLookup
+	EventCenter eventcenter.EventCenter
 }
 
 // Returns an instance of the database handler used by the configuration manager.
@@ -28,3 +30,8 @@ func (w ManagerAccessorsWrapper) GetConnectedAgents() agentcomm.ConnectedAgents
 func (w ManagerAccessorsWrapper) GetDHCPOptionDefinitionLookup() keaconfig.DHCPOptionDefinitionLookup {
 	return w.DefLookup
 }
+
+// Returns an interface to the event center recording the configuration changes.
+func (w ManagerAccessorsWrapper) GetEventCenter() eventcenter.EventCenter {
+	return w.EventCenter
+}
@@ -4,16 +4,84 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"strconv"
 	"strings"
 
 	dbmodel "isc.org/stork/server/database/model"
 )
 
-// Checks if the given user is permitted to access a resource. Currently the
-// access pattern is very simple, the super-admin user can access all
-// resources. The admin-user can access all resources except those related
-// to users management.
+// Kind of an object referenced in the request URL.
+type ObjectKind string
+
+// Kinds of the objects which can be used to limit the permissions' scope.
+const (
+	ObjectKindMachine       ObjectKind = "machine"
+	ObjectKindApp           ObjectKind = "app"
+	ObjectKindDaemon        ObjectKind = "daemon"
+	ObjectKindHost          ObjectKind = "host"
+	ObjectKindSubnet        ObjectKind = "subnet"
+	ObjectKindSharedNetwork ObjectKind = "shared-network"
+	ObjectKindLogTarget     ObjectKind = "log-target"
+)
+
+// Maps the first URL segment following /api/ to the kind of the object
+// identified by the next segment.
+var objectKindsByPathSegment = map[string]ObjectKind{
+	"machines":        ObjectKindMachine,
+	"apps":            ObjectKindApp,
+	"app":             ObjectKindApp,
+	"daemons":         ObjectKindDaemon,
+	"hosts":           ObjectKindHost,
+	"subnets":         ObjectKindSubnet,
+	"shared-networks": ObjectKindSharedNetwork,
+	"logs":            ObjectKindLogTarget,
+}
+
+// Collections which can be listed by the members of the scoped groups.
+// The handlers returning these collections limit them to the objects
+// belonging to the scope of the user's groups. Listing other collections
+// would expose the objects outside of the scope.
+var scopeFilteredCollections = map[string]bool{
+	"machines":        true,
+	"apps":            true,
+	"hosts":           true,
+	"subnets":         true,
+	"shared-networks": true,
+}
+
+// Holds the IDs of the machines, apps and subnets an object is associated
+// with. For example, a host reservation is associated with its subnet and
+// with the machines and apps of the daemons having this reservation.
+type ObjectScope struct {
+	MachineIDs []int64
+	AppIDs     []int64
+	SubnetIDs  []int64
+}
+
+// Interface to a component returning the scope of the objects referenced
+// in the requests. It is used to check if the object belongs to the
+// scope of a group. It returns nil scope when the object does not exist.
+type ScopeResolver interface {
+	ResolveScope(kind ObjectKind, id int64) (*ObjectScope, error)
+}
+
+// Checks if the given user is permitted to access a resource. The
+// super-admin user can access all resources. The admin-user can access
+// all resources except those related to users management. The members
+// of other groups are granted access according to their groups'
+// permissions. This function does not support the permissions limited
+// to selected objects. The access is denied to the members of such
+// groups when the request refers to a specific object.
 func Authorize(user *dbmodel.SystemUser, req *http.Request) (ok bool, err error) {
+	return AuthorizeWithScope(user, req, nil)
+}
+
+// Checks if the given user is permitted to access a resource. It works
+// like Authorize but uses the specified resolver to check whether the
+// object referenced in the request belongs to the scope of the user's
+// groups. The user's groups must contain permissions and scopes, i.e.,
+// they must be fetched from the database.
+func AuthorizeWithScope(user *dbmodel.SystemUser, req *http.Request, resolver ScopeResolver) (ok bool, err error) {
 	// If there is no user (possibly the user has not signed in) or the
 	// request is nil, reject access to the resource.
 	if user == nil || req == nil {
@@ -25,10 +93,7 @@ func Authorize(user *dbmodel.SystemUser, req *http.Request) (ok bool, err error)
 		return true, nil
 	}
 
-	urlPath := path.Clean(req.URL.Path)
-	if !strings.HasSuffix(urlPath, "/") {
-		urlPath += "/"
-	}
+	urlPath := getCleanPath(req)
 
 	if strings.HasPrefix(urlPath, "/api/users/") {
 		// If the user does not belong to the super-admin group and trying to
@@ -38,6 +103,9 @@ func Authorize(user *dbmodel.SystemUser, req *http.Request) (ok bool, err error)
 	} else if strings.HasPrefix(urlPath, "/api/sessions/") && req.Method == "DELETE" {
 		// Log out is available for all users.
 		return true, nil
+	} else if strings.HasPrefix(urlPath, "/api/groups/") && req.Method != "GET" {
+		// Managing the groups is a part of the users management.
+		return false, nil
 	}
 
 	// All other resources can be accessed by the admin user.
@@ -45,7 +113,180 @@ func Authorize(user *dbmodel.SystemUser, req *http.Request) (ok bool, err error)
 		return true, err
 	}
 
-	// User who doesn't belong to any group is not allowed to access
-	// system resources.
+	// Members of other groups need appropriate permissions.
+	permissions := getRequiredPermissions(urlPath, req.Method)
+	if len(permissions) == 0 {
+		return false, nil
+	}
+
+	kind, id, hasObject := getReferencedObject(urlPath)
+	var scope *ObjectScope
+	scopeResolved := false
+
+	for _, group := range user.Groups {
+		if !hasAnyPermission(group, permissions) {
+			continue
+		}
+		if !group.IsScoped() {
+			return true, nil
+		}
+		if !hasObject {
+			// The scoped groups can list the objects filtered by their
+			// scopes but they cannot create new objects or make other
+			// changes that are not associated with a specific object.
+			if req.Method == "GET" && isScopeFilteredCollection(urlPath) {
+				return true, nil
+			}
+			continue
+		}
+		if resolver == nil {
+			continue
+		}
+		if !scopeResolved {
+			scope, err = resolver.ResolveScope(kind, id)
+			if err != nil {
+				return false, err
+			}
+			scopeResolved = true
+		}
+		if scope != nil && group.InScope(scope.MachineIDs, scope.AppIDs, scope.SubnetIDs) {
+			return true, nil
+		}
+	}
+
+	// User who doesn't belong to any group or whose groups lack the
+	// required permissions is not allowed to access system resources.
 	return false, nil
 }
+
+// Returns the filter limiting the objects returned in response to the
+// request to those belonging to the scope of the user's groups. It returns
+// nil if the user can access all objects, i.e., the user is an admin or
+// super-admin, or belongs to a group granting the access to the resource
+// without limiting its scope. Otherwise, it returns the union of the
+// scopes of the groups granting the access. The user's groups must contain
+// permissions and scopes, i.e., they must be fetched from the database.
+func GetScopeFilter(user *dbmodel.SystemUser, req *http.Request) *dbmodel.ScopeFilter {
+	if user == nil || req == nil {
+		return &dbmodel.ScopeFilter{}
+	}
+	if user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID}) ||
+		user.InGroup(&dbmodel.SystemGroup{ID: dbmodel.AdminGroupID}) {
+		return nil
+	}
+	permissions := getRequiredPermissions(getCleanPath(req), req.Method)
+	filter := &dbmodel.ScopeFilter{}
+	for _, group := range user.Groups {
+		if !hasAnyPermission(group, permissions) {
+			continue
+		}
+		if !group.IsScoped() {
+			return nil
+		}
+		filter.MachineIDs = append(filter.MachineIDs, group.ScopeMachineIDs...)
+		filter.AppIDs = append(filter.AppIDs, group.ScopeAppIDs...)
+		filter.SubnetIDs = append(filter.SubnetIDs, group.ScopeSubnetIDs...)
+	}
+	return filter
+}
+
+// Returns the cleaned URL path of the request terminated with a slash.
+func getCleanPath(req *http.Request) string {
+	urlPath := path.Clean(req.URL.Path)
+	if !strings.HasSuffix(urlPath, "/") {
+		urlPath += "/"
+	}
+	return urlPath
+}
+
+// Checks if the URL refers to a collection whose listing is limited to
+// the objects belonging to the user's scope.
+func isScopeFilteredCollection(urlPath string) bool {
+	segments := strings.Split(strings.Trim(urlPath, "/"), "/")
+	return len(segments) == 2 && segments[0] == "api" && scopeFilteredCollections[segments[1]]
+}
+
+// Returns the permissions allowing for accessing the specified resource.
+// Any of the returned permissions grants the access. It returns nil if
+// the resource can only be accessed by the admin and super-admin users.
+func getRequiredPermissions(urlPath, method string) []dbmodel.Permission {
+	segments := strings.Split(strings.Trim(urlPath, "/"), "/")
+	if len(segments) < 2 || segments[0] != "api" {
+		return nil
+	}
+	isGet := method == "GET"
+	switch segments[1] {
+	case "machines":
+		if len(segments) > 3 && segments[3] == "dump" {
+			return []dbmodel.Permission{dbmodel.PermissionDumpMachines}
+		}
+	case "hosts", "config-changes":
+		// Only the host reservation changes can be scheduled.
+		if isGet {
+			return []dbmodel.Permission{dbmodel.PermissionViewAll, dbmodel.PermissionViewHosts, dbmodel.PermissionEditHosts}
+		}
+		return []dbmodel.Permission{dbmodel.PermissionEditHosts}
+	case "subnets", "shared-networks", "utilization-alert-rules":
+		if isGet {
+			return []dbmodel.Permission{dbmodel.PermissionViewAll, dbmodel.PermissionEditSubnets}
+		}
+		return []dbmodel.Permission{dbmodel.PermissionEditSubnets}
+	case "leases":
+		if isGet {
+			return []dbmodel.Permission{dbmodel.PermissionViewAll, dbmodel.PermissionEditLeases}
+		}
+		return []dbmodel.Permission{dbmodel.PermissionEditLeases}
+	case "notification-channels":
+		// The notification channels contain the credentials and the
+		// addresses of the external systems.
+		return nil
+	case "machines-server-token":
+		// The server token allows for registering new agents.
+		return nil
+	case "app":
+		// The only resources under this path are the access point keys
+		// used to authenticate to the Kea Control Agent.
+		return nil
+	case "daemons":
+		if len(segments) > 3 && (segments[3] == "config-review" || segments[3] == "config-checkers") {
+			if isGet {
+				return []dbmodel.Permission{dbmodel.PermissionViewAll, dbmodel.PermissionRunConfigReview}
+			}
+			return []dbmodel.Permission{dbmodel.PermissionRunConfigReview}
+		}
+	}
+	if isGet {
+		return []dbmodel.Permission{dbmodel.PermissionViewAll}
+	}
+	return nil
+}
+
+// Returns the kind and ID of the object referenced in the URL. The ID
+// is expected in the segment following the collection name, e.g.,
+// /api/hosts/1/. The last returned value is false if the URL does not
+// refer to a specific object.
+func getReferencedObject(urlPath string) (ObjectKind, int64, bool) {
+	segments := strings.Split(strings.Trim(urlPath, "/"), "/")
+	if len(segments) < 3 || segments[0] != "api" {
+		return "", 0, false
+	}
+	kind, ok := objectKindsByPathSegment[segments[1]]
+	if !ok {
+		return "", 0, false
+	}
+	id, err := strconv.ParseInt(segments[2], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return kind, id, true
+}
+
+// Checks if the group has any of the specified permissions.
+func hasAnyPermission(group *dbmodel.SystemGroup, permissions []dbmodel.Permission) bool {
+	for _, permission := range permissions {
+		if group.HasPermission(permission) {
+			return true
+		}
+	}
+	return false
+}
@@ -62,7 +62,7 @@ func TestAuthorize(t *testing.T) {
 	require.False(t, authorizeAccept(t, 0, "/machines/1/", "GET"))
 
 	// The same in case of someone belonging to non existing group.
-	require.False(t, authorizeAccept(t, 3, "/machines/1/", "GET"))
+	require.False(t, authorizeAccept(t, 42, "/machines/1/", "GET"))
 
 	// Someone who belongs to no groups would be able to log out.
 	require.True(t, authorizeAccept(t, 0, "/sessions", "DELETE"))
@@ -73,3 +73,292 @@ func TestAuthorize(t *testing.T) {
 	require.False(t, authorizeAccept(t, 0, "/users/4", "GET"))
 	require.False(t, authorizeAccept(t, 0, "/users/4/password", "GET"))
 }
+
+// Verify that the admin cannot manage the groups.
+func TestAuthorizeGroupsManagement(t *testing.T) {
+	require.True(t, authorizeAccept(t, 2, "/groups", "GET"))
+	require.False(t, authorizeAccept(t, 2, "/groups", "POST"))
+	require.False(t, authorizeAccept(t, 2, "/groups/5", "PUT"))
+	require.False(t, authorizeAccept(t, 2, "/groups/5", "DELETE"))
+
+	require.True(t, authorizeAccept(t, 1, "/groups", "POST"))
+	require.True(t, authorizeAccept(t, 1, "/groups/5", "DELETE"))
+}
+
+// Fake scope resolver returning predefined scopes of the objects.
+type fakeScopeResolver struct {
+	scopes map[ObjectKind]map[int64]*ObjectScope
+	calls  int
+}
+
+// Returns the predefined scope of the object.
+func (r *fakeScopeResolver) ResolveScope(kind ObjectKind, id int64) (*ObjectScope, error) {
+	r.calls++
+	return r.scopes[kind][id], nil
+}
+
+// Helper function checking if the user belonging to the specified groups
+// has access to the resource.
+func authorizeGroupsAccept(t *testing.T, groups []*dbmodel.SystemGroup, resolver ScopeResolver, path, method string) bool {
+	user := &dbmodel.SystemUser{
+		ID:     5,
+		Groups: groups,
+	}
+	req, _ := http.NewRequestWithContext(context.Background(), method, "http://example.org/api"+path, nil)
+	ok, err := AuthorizeWithScope(user, req, resolver)
+	require.NoError(t, err)
+	return ok
+}
+
+// Verify that the members of the read-only group can view but not change
+// anything.
+func TestAuthorizeReadOnly(t *testing.T) {
+	groups := []*dbmodel.SystemGroup{
+		{
+			ID:          dbmodel.ReadOnlyGroupID,
+			Permissions: []dbmodel.Permission{dbmodel.PermissionViewAll},
+		},
+	}
+	for _, path := range []string{"/machines", "/machines/1", "/apps/2", "/hosts", "/hosts/3", "/subnets/4", "/shared-networks", "/daemons/5/config", "/daemons/5/config-reports", "/events", "/settings", "/config-changes"} {
+		require.True(t, authorizeGroupsAccept(t, groups, nil, path, "GET"), path)
+	}
+	for _, path := range []string{"/hosts/new/transaction", "/hosts/3/transaction", "/subnets/4/transaction/1/submit", "/machines/1/ping", "/settings", "/config-changes", "/config-changes/1"} {
+		require.False(t, authorizeGroupsAccept(t, groups, nil, path, "POST"), path)
+		require.False(t, authorizeGroupsAccept(t, groups, nil, path, "PUT"), path)
+	}
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/hosts/3", "DELETE"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/daemons/5/config-review", "PUT"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/machines/1/dump", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/users", "GET"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/leases", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/leases", "DELETE"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/leases/declined/reclaim", "POST"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/notification-channels", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/notification-channels/1/test", "POST"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/machines-server-token", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/app/2/access-points/control/key", "GET"))
+}
+
+// Verify that the members of the operator group can edit hosts and
+// subnets and run config review.
+func TestAuthorizeOperator(t *testing.T) {
+	groups := []*dbmodel.SystemGroup{
+		{
+			ID: dbmodel.OperatorGroupID,
+			Permissions: []dbmodel.Permission{
+				dbmodel.PermissionViewAll,
+				dbmodel.PermissionEditHosts,
+				dbmodel.PermissionEditSubnets,
+				dbmodel.PermissionRunConfigReview,
+			},
+		},
+	}
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/machines/1", "GET"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/hosts/new/transaction", "POST"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/hosts/3/transaction/1/submit", "POST"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/hosts/3", "DELETE"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/subnets/4/transaction", "POST"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/shared-networks/new/transaction", "POST"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/utilization-alert-rules/2", "PUT"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/daemons/5/config-review", "PUT"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/daemons/global/config-checkers", "PUT"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/config-changes", "POST"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/config-changes/2", "DELETE"))
+
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/machines/1", "PUT"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/machines/1/dump", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/daemons/5/config/transaction", "POST"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/settings", "PUT"))
+}
+
+// Verify the access granted by the custom groups with selected
+// permissions.
+func TestAuthorizeCustomGroup(t *testing.T) {
+	// The group can only view the hosts and dump machines.
+	groups := []*dbmodel.SystemGroup{
+		{
+			ID:          5,
+			Permissions: []dbmodel.Permission{dbmodel.PermissionViewHosts, dbmodel.PermissionDumpMachines},
+		},
+	}
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/hosts", "GET"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/hosts/1", "GET"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/machines/1/dump", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/hosts/new/transaction", "POST"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/subnets", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/machines/1", "GET"))
+
+	// Permissions of multiple groups are combined.
+	groups = append(groups, &dbmodel.SystemGroup{
+		ID:          6,
+		Permissions: []dbmodel.Permission{dbmodel.PermissionEditSubnets},
+	})
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/subnets", "GET"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/subnets/new/transaction", "POST"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/hosts/new/transaction", "POST"))
+}
+
+// Verify that the leases can be modified by the members of the group
+// having the edit-leases permission.
+func TestAuthorizeEditLeases(t *testing.T) {
+	groups := []*dbmodel.SystemGroup{
+		{
+			ID:          5,
+			Permissions: []dbmodel.Permission{dbmodel.PermissionEditLeases},
+		},
+	}
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/leases", "GET"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/leases", "POST"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/leases", "PUT"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/leases", "DELETE"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/leases/wipe", "POST"))
+	require.True(t, authorizeGroupsAccept(t, groups, nil, "/leases/declined/reclaim", "POST"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/hosts", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/subnets/1", "DELETE"))
+}
+
+// Verify that the permissions limited to selected objects are enforced.
+func TestAuthorizeScopedGroup(t *testing.T) {
+	resolver := &fakeScopeResolver{
+		scopes: map[ObjectKind]map[int64]*ObjectScope{
+			ObjectKindHost: {
+				1: {MachineIDs: []int64{1}, AppIDs: []int64{10}, SubnetIDs: []int64{100}},
+				2: {MachineIDs: []int64{2}, AppIDs: []int64{20}},
+			},
+			ObjectKindSubnet: {
+				100: {MachineIDs: []int64{1}, AppIDs: []int64{10}, SubnetIDs: []int64{100}},
+				200: {MachineIDs: []int64{2}, AppIDs: []int64{20}, SubnetIDs: []int64{200}},
+			},
+			ObjectKindMachine: {
+				1: {MachineIDs: []int64{1}, AppIDs: []int64{10}},
+			},
+		},
+	}
+	groups := []*dbmodel.SystemGroup{
+		{
+			ID:              5,
+			Permissions:     []dbmodel.Permission{dbmodel.PermissionViewAll, dbmodel.PermissionEditHosts},
+			ScopeMachineIDs: []int64{1},
+		},
+	}
+
+	// Objects within the scope.
+	require.True(t, authorizeGroupsAccept(t, groups, resolver, "/hosts/1", "GET"))
+	require.True(t, authorizeGroupsAccept(t, groups, resolver, "/hosts/1/transaction", "POST"))
+	require.True(t, authorizeGroupsAccept(t, groups, resolver, "/subnets/100", "GET"))
+	require.True(t, authorizeGroupsAccept(t, groups, resolver, "/machines/1", "GET"))
+
+	// Objects out of the scope.
+	require.False(t, authorizeGroupsAccept(t, groups, resolver, "/hosts/2", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, resolver, "/hosts/2/transaction", "POST"))
+	require.False(t, authorizeGroupsAccept(t, groups, resolver, "/subnets/200", "GET"))
+
+	// Non-existing objects.
+	require.False(t, authorizeGroupsAccept(t, groups, resolver, "/machines/3", "GET"))
+
+	// Lists filtered by scope are accessible but new objects cannot be created.
+	require.True(t, authorizeGroupsAccept(t, groups, resolver, "/hosts", "GET"))
+	require.True(t, authorizeGroupsAccept(t, groups, resolver, "/machines", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, resolver, "/hosts/new/transaction", "POST"))
+
+	// Lists which are not filtered by scope are not accessible.
+	require.False(t, authorizeGroupsAccept(t, groups, resolver, "/events", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, resolver, "/leases", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, resolver, "/machines/directory", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, resolver, "/hosts/export", "GET"))
+
+	// Without the resolver the scoped objects are not accessible.
+	require.False(t, authorizeGroupsAccept(t, groups, nil, "/hosts/1", "GET"))
+
+	// Unscoped group with the same permissions grants access to all objects.
+	groups = append(groups, &dbmodel.SystemGroup{
+		ID:          6,
+		Permissions: []dbmodel.Permission{dbmodel.PermissionViewAll},
+	})
+	require.True(t, authorizeGroupsAccept(t, groups, resolver, "/hosts/2", "GET"))
+	require.False(t, authorizeGroupsAccept(t, groups, resolver, "/hosts/2/transaction", "POST"))
+}
+
+// Verify that the scope is resolved once for multiple scoped groups.
+func TestAuthorizeScopeResolvedOnce(t *testing.T) {
+	resolver := &fakeScopeResolver{
+		scopes: map[ObjectKind]map[int64]*ObjectScope{
+			ObjectKindApp: {
+				1: {MachineIDs: []int64{1}, AppIDs: []int64{1}},
+			},
+		},
+	}
+	groups := []*dbmodel.SystemGroup{
+		{
+			ID:          5,
+			Permissions: []dbmodel.Permission{dbmodel.PermissionViewAll},
+			ScopeAppIDs: []int64{2},
+		},
+		{
+			ID:          6,
+			Permissions: []dbmodel.Permission{dbmodel.PermissionViewAll},
+			ScopeAppIDs: []int64{1},
+		},
+	}
+	require.True(t, authorizeGroupsAccept(t, groups, resolver, "/apps/1", "GET"))
+	require.Equal(t, 1, resolver.calls)
+}
+
+// Helper function returning the scope filter for the user belonging to
+// the specified groups.
+func getGroupsScopeFilter(groups []*dbmodel.SystemGroup, path, method string) *dbmodel.ScopeFilter {
+	user := &dbmodel.SystemUser{
+		ID:     5,
+		Groups: groups,
+	}
+	req, _ := http.NewRequestWithContext(context.Background(), method, "http://example.org/api"+path, nil)
+	return GetScopeFilter(user, req)
+}
+
+// Verify that the scope filter is the union of the scopes of the groups
+// granting the access to the resource.
+func TestGetScopeFilter(t *testing.T) {
+	groups := []*dbmodel.SystemGroup{
+		{
+			ID:              5,
+			Permissions:     []dbmodel.Permission{dbmodel.PermissionViewAll},
+			ScopeMachineIDs: []int64{1},
+		},
+		{
+			ID:             6,
+			Permissions:    []dbmodel.Permission{dbmodel.PermissionViewHosts},
+			ScopeAppIDs:    []int64{2},
+			ScopeSubnetIDs: []int64{3},
+		},
+	}
+
+	filter := getGroupsScopeFilter(groups, "/hosts", "GET")
+	require.NotNil(t, filter)
+	require.Equal(t, []int64{1}, filter.MachineIDs)
+	require.Equal(t, []int64{2}, filter.AppIDs)
+	require.Equal(t, []int64{3}, filter.SubnetIDs)
+
+	// The second group lacks the permissions to view the subnets.
+	filter = getGroupsScopeFilter(groups, "/subnets", "GET")
+	require.NotNil(t, filter)
+	require.Equal(t, []int64{1}, filter.MachineIDs)
+	require.Empty(t, filter.AppIDs)
+	require.Empty(t, filter.SubnetIDs)
+
+	// Unscoped group grants the access to all objects.
+	groups = append(groups, &dbmodel.SystemGroup{
+		ID:          7,
+		Permissions: []dbmodel.Permission{dbmodel.PermissionViewHosts},
+	})
+	require.Nil(t, getGroupsScopeFilter(groups, "/hosts", "GET"))
+	require.NotNil(t, getGroupsScopeFilter(groups, "/subnets", "GET"))
+
+	// Admins can access all objects.
+	require.Nil(t, getGroupsScopeFilter([]*dbmodel.SystemGroup{{ID: dbmodel.AdminGroupID}}, "/hosts", "GET"))
+	require.Nil(t, getGroupsScopeFilter([]*dbmodel.SystemGroup{{ID: dbmodel.SuperAdminGroupID}}, "/hosts", "GET"))
+
+	// A user without groups has no access to any objects.
+	filter = getGroupsScopeFilter(nil, "/hosts", "GET")
+	require.NotNil(t, filter)
+	require.Empty(t, filter.MachineIDs)
+}
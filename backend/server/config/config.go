@@ -10,6 +10,7 @@ import (
 	"isc.org/stork/datamodel"
 	agentcomm "isc.org/stork/server/agentcomm"
 	dbmodel "isc.org/stork/server/database/model"
+	eventcenter "isc.org/stork/server/eventcenter"
 )
 
 var _ TransactionStateAccessor = (*TransactionState[any])(nil)
@@ -64,12 +65,33 @@ type KeaModule interface {
 	ApplyHostUpdate(context.Context, *dbmodel.Host) (context.Context, error)
 	BeginHostDelete(context.Context) (context.Context, error)
 	ApplyHostDelete(context.Context, *dbmodel.Host) (context.Context, error)
+	BeginHostsImport(context.Context) (context.Context, error)
+	ApplyHostsImport(context.Context, []*dbmodel.Host) (context.Context, error)
+	BeginSubnetAdd(context.Context) (context.Context, error)
+	ApplySubnetAdd(context.Context, *dbmodel.Subnet) (context.Context, error)
+	BeginSubnetUpdate(context.Context, int64) (context.Context, error)
+	ApplySubnetUpdate(context.Context, *dbmodel.Subnet) (context.Context, error)
+	BeginSubnetDelete(context.Context) (context.Context, error)
+	ApplySubnetDelete(context.Context, *dbmodel.Subnet) (context.Context, error)
+	BeginSharedNetworkAdd(context.Context) (context.Context, error)
+	ApplySharedNetworkAdd(context.Context, *dbmodel.SharedNetwork) (context.Context, error)
+	BeginSharedNetworkUpdate(context.Context, int64) (context.Context, error)
+	ApplySharedNetworkUpdate(context.Context, *dbmodel.SharedNetwork) (context.Context, error)
+	BeginSharedNetworkDelete(context.Context) (context.Context, error)
+	ApplySharedNetworkDelete(context.Context, *dbmodel.SharedNetwork) (context.Context, error)
+	BeginGlobalParametersUpdate(context.Context, []int64) (context.Context, error)
+	ApplyGlobalParametersUpdate(context.Context, map[int64]*keaconfig.SettableGlobalParameters) (context.Context, error)
+	BeginConfigReportFix(context.Context, int64) (context.Context, error)
+	ApplyConfigReportFix(context.Context) (context.Context, error)
 }
 
 // Interface of the Kea configuration module used by the manager to
 // commit configuration changes in Kea servers.
 type KeaModuleCommit interface {
 	Commit(context.Context) (context.Context, error)
+	// Records the configuration changes scheduled for the specified
+	// deadline in the config change log.
+	RecordSchedule(context.Context, time.Time)
 }
 
 // Common configuration manager interface.
@@ -91,6 +113,13 @@ type Manager interface {
 	CommitDue() error
 	// Schedules configuration changes to apply them in the future.
 	Schedule(context.Context, time.Time) (context.Context, error)
+	// Sets new deadline for the scheduled configuration changes.
+	Reschedule(int64, time.Time) error
+	// Starts committing the scheduled configuration changes when their
+	// deadlines expire.
+	Start()
+	// Stops committing the scheduled configuration changes.
+	Shutdown()
 }
 
 // Configuration manager interface exposing functions used for getting
@@ -103,6 +132,9 @@ type ManagerAccessors interface {
 	// Returns an interface to the instance providing the DHCP option definition
 	// lookup logic.
 	GetDHCPOptionDefinitionLookup() keaconfig.DHCPOptionDefinitionLookup
+	// Returns an interface to the event center used to record the
+	// configuration changes. It may be nil.
+	GetEventCenter() eventcenter.EventCenter
 }
 
 // Configuration manager interface exposing functions available to the
@@ -23,6 +23,9 @@ const (
 	LockContextKey
 	// A context key for accessing a list of daemon IDs.
 	DaemonsContextKey
+	// A context key for accessing the ID of the config change scheduled
+	// in the database.
+	ScheduledChangeContextKey
 )
 
 // Convenience function retrieving a value from the context. If the context
@@ -19,6 +19,57 @@ func (e HostNotFoundError) Error() string {
 	return fmt.Sprintf("host with ID %d not found", e.hostID)
 }
 
+// An error returned when specified subnet is not found in the database.
+type SubnetNotFoundError struct {
+	subnetID int64
+}
+
+// Create new instance of the SubnetNotFoundError.
+func NewSubnetNotFoundError(subnetID int64) error {
+	return &SubnetNotFoundError{
+		subnetID: subnetID,
+	}
+}
+
+// Returns error string.
+func (e SubnetNotFoundError) Error() string {
+	return fmt.Sprintf("subnet with ID %d not found", e.subnetID)
+}
+
+// An error returned when specified shared network is not found in the database.
+type SharedNetworkNotFoundError struct {
+	sharedNetworkID int64
+}
+
+// Create new instance of the SharedNetworkNotFoundError.
+func NewSharedNetworkNotFoundError(sharedNetworkID int64) error {
+	return &SharedNetworkNotFoundError{
+		sharedNetworkID: sharedNetworkID,
+	}
+}
+
+// Returns error string.
+func (e SharedNetworkNotFoundError) Error() string {
+	return fmt.Sprintf("shared network with ID %d not found", e.sharedNetworkID)
+}
+
+// An error returned when specified daemon is not found in the database.
+type DaemonNotFoundError struct {
+	daemonID int64
+}
+
+// Create new instance of the DaemonNotFoundError.
+func NewDaemonNotFoundError(daemonID int64) error {
+	return &DaemonNotFoundError{
+		daemonID: daemonID,
+	}
+}
+
+// Returns error string.
+func (e DaemonNotFoundError) Error() string {
+	return fmt.Sprintf("daemon with ID %d not found", e.daemonID)
+}
+
 // An error returned when it was not possible to lock daemons' configuration.
 type LockError struct{}
 
@@ -31,3 +82,39 @@ func NewLockError() error {
 func (e LockError) Error() string {
 	return "problem with locking daemons configuration"
 }
+
+// An error returned when specified config report is not found in the
+// database or it proposes no fix.
+type ConfigReportFixNotFoundError struct {
+	reportID int64
+}
+
+// Creates new instance of the ConfigReportFixNotFoundError.
+func NewConfigReportFixNotFoundError(reportID int64) error {
+	return &ConfigReportFixNotFoundError{
+		reportID: reportID,
+	}
+}
+
+// Returns error string.
+func (e ConfigReportFixNotFoundError) Error() string {
+	return fmt.Sprintf("fix for config report with ID %d not found", e.reportID)
+}
+
+// An error returned when the configuration of the daemon has changed since
+// the config report proposing a fix was generated.
+type ConfigReportFixOutdatedError struct {
+	reportID int64
+}
+
+// Creates new instance of the ConfigReportFixOutdatedError.
+func NewConfigReportFixOutdatedError(reportID int64) error {
+	return &ConfigReportFixOutdatedError{
+		reportID: reportID,
+	}
+}
+
+// Returns error string.
+func (e ConfigReportFixOutdatedError) Error() string {
+	return fmt.Sprintf("fix for config report with ID %d is outdated because the daemon configuration has changed", e.reportID)
+}
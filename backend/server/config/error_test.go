@@ -12,9 +12,41 @@ func TestHostNotFoundError(t *testing.T) {
 	require.EqualError(t, err, "host with ID 123 not found")
 }
 
+// Test creation of an error which indicates that subnet was not found.
+func TestSubnetNotFoundError(t *testing.T) {
+	err := NewSubnetNotFoundError(123)
+	require.EqualError(t, err, "subnet with ID 123 not found")
+}
+
+// Test creation of an error which indicates that shared network was not found.
+func TestSharedNetworkNotFoundError(t *testing.T) {
+	err := NewSharedNetworkNotFoundError(123)
+	require.EqualError(t, err, "shared network with ID 123 not found")
+}
+
+// Test creation of an error which indicates that daemon was not found.
+func TestDaemonNotFoundError(t *testing.T) {
+	err := NewDaemonNotFoundError(123)
+	require.EqualError(t, err, "daemon with ID 123 not found")
+}
+
 // Test creation of an error which indicates a problem with locking
 // configuration.
 func TestLockError(t *testing.T) {
 	err := NewLockError()
 	require.EqualError(t, err, "problem with locking daemons configuration")
 }
+
+// Test creation of an error which indicates that config report fix was
+// not found.
+func TestConfigReportFixNotFoundError(t *testing.T) {
+	err := NewConfigReportFixNotFoundError(123)
+	require.EqualError(t, err, "fix for config report with ID 123 not found")
+}
+
+// Test creation of an error which indicates that config report fix is
+// outdated.
+func TestConfigReportFixOutdatedError(t *testing.T) {
+	err := NewConfigReportFixOutdatedError(123)
+	require.EqualError(t, err, "fix for config report with ID 123 is outdated because the daemon configuration has changed")
+}
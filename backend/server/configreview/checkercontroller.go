@@ -24,6 +24,7 @@ type checkerController interface {
 	setStateForDaemon(daemonID int64, checkerName string, state CheckerState)
 	isCheckerEnabledForDaemon(daemonID int64, checkerName string) bool
 	getStateForDaemon(daemonID int64, checkerName string) CheckerState
+	deleteStates(checkerName string)
 }
 
 // Implementation of the checker controller interface.
@@ -100,3 +101,11 @@ func (c checkerControllerImpl) getStateForDaemon(daemonID int64, checkerName str
 
 	return CheckerStateInherit
 }
+
+// Removes the global and daemon-specific states of a given checker.
+func (c checkerControllerImpl) deleteStates(checkerName string) {
+	delete(c.globalStates, checkerName)
+	for _, states := range c.daemonStates {
+		delete(states, checkerName)
+	}
+}
@@ -95,14 +95,17 @@ type taggedReport struct {
 // daemon configuration),
 // - reports: configuration reports produced so far,
 // - callback: user callback to invoke after the review,
-// - trigger: a trigger that started the current review.
+// - trigger: a trigger that started the current review,
+// - subjectService: an HA service the subject daemon belongs to; it is
+// only set for the checkers belonging to the KeaHAService dispatch group.
 type ReviewContext struct {
-	db            *dbops.PgDB
-	subjectDaemon *dbmodel.Daemon
-	refDaemons    []*dbmodel.Daemon
-	reports       []taggedReport
-	callback      CallbackFunc
-	triggers      Triggers
+	db             *dbops.PgDB
+	subjectDaemon  *dbmodel.Daemon
+	refDaemons     []*dbmodel.Daemon
+	reports        []taggedReport
+	callback       CallbackFunc
+	triggers       Triggers
+	subjectService *dbmodel.Service
 }
 
 // Creates new review context instance.
@@ -116,6 +119,20 @@ func newReviewContext(db *dbops.PgDB, daemon *dbmodel.Daemon, triggers Triggers,
 	return ctx
 }
 
+// Appends a daemon to the referenced daemons unless it is the subject
+// daemon or it has been already appended.
+func (c *ReviewContext) addRefDaemon(daemon *dbmodel.Daemon) {
+	if daemon.ID == c.subjectDaemon.ID {
+		return
+	}
+	for _, refDaemon := range c.refDaemons {
+		if refDaemon.ID == daemon.ID {
+			return
+		}
+	}
+	c.refDaemons = append(c.refDaemons, daemon)
+}
+
 // Returns a number of the generated reports.
 func (c *ReviewContext) getReportsCount() int {
 	return len(c.reports)
@@ -155,6 +172,8 @@ func (s DispatchGroupSelector) String() string {
 		return "kea-d2-daemon"
 	case Bind9Daemon:
 		return "bind9-daemon"
+	case KeaHAService:
+		return "kea-ha-service"
 	}
 	log.WithField("selector", fmt.Sprintf("%d", s)).Error("Config review dispatcher was unable to recognize the dispatch group selector and assign any string representation. Please notify the ISC Stork Development Team about this issue.")
 	return "unknown"
@@ -168,6 +187,9 @@ type DispatchGroupSelectors []DispatchGroupSelector
 // used for reviewing DHCPv4 and DHCPv6 servers configurations. The
 // checkers belonging to the KeaDaemon group are used to review
 // the configuration parts shared by all Kea daemons. And so on...
+// The checkers belonging to the KeaHAService group are special. They
+// are run for each HA service the reviewed DHCP daemon belongs to and
+// they compare the configurations of all daemons in the service.
 const (
 	EachDaemon DispatchGroupSelector = iota
 	KeaDaemon
@@ -177,6 +199,7 @@ const (
 	KeaDHCPv6Daemon
 	KeaD2Daemon
 	Bind9Daemon
+	KeaHAService
 )
 
 // Returns group selectors for selecting registered checkers appropriate
@@ -187,9 +210,9 @@ const (
 func getDispatchGroupSelectors(daemonName string) DispatchGroupSelectors {
 	switch daemonName {
 	case "dhcp4":
-		return DispatchGroupSelectors{EachDaemon, KeaDaemon, KeaDHCPDaemon, KeaDHCPv4Daemon}
+		return DispatchGroupSelectors{EachDaemon, KeaDaemon, KeaDHCPDaemon, KeaDHCPv4Daemon, KeaHAService}
 	case "dhcp6":
-		return DispatchGroupSelectors{EachDaemon, KeaDaemon, KeaDHCPDaemon, KeaDHCPv6Daemon}
+		return DispatchGroupSelectors{EachDaemon, KeaDaemon, KeaDHCPDaemon, KeaDHCPv6Daemon, KeaHAService}
 	case "ca":
 		return DispatchGroupSelectors{EachDaemon, KeaDaemon, KeaCADaemon}
 	case "d2":
@@ -221,6 +244,21 @@ func newDispatchGroup() *dispatchGroup {
 	}
 }
 
+// Returns a copy of the dispatch group. The dispatcher modifies the copies
+// of the groups when the checkers are registered or unregistered, so the
+// ongoing reviews can safely iterate over the original groups.
+func (g *dispatchGroup) clone() *dispatchGroup {
+	cloned := &dispatchGroup{
+		checkers:         make([]*checker, len(g.checkers)),
+		triggerRefCounts: make(map[Trigger]int64, len(g.triggerRefCounts)),
+	}
+	copy(cloned.checkers, g.checkers)
+	for trigger, count := range g.triggerRefCounts {
+		cloned.triggerRefCounts[trigger] = count
+	}
+	return cloned
+}
+
 // Appends a checker to the dispatch group. It updates the trigger reference
 // counts.
 func (g *dispatchGroup) appendChecker(checker *checker) {
@@ -276,6 +314,10 @@ type dispatcherImpl struct {
 	// Config review dispatch groups containing checkers segregated
 	// into groups by daemon types.
 	groups map[DispatchGroupSelector]*dispatchGroup
+	// Mutex protecting the dispatch groups. The checkers can be registered
+	// and unregistered while the dispatcher is running (e.g., when the
+	// user-defined rules are modified).
+	groupsMutex *sync.RWMutex
 	// Wait group used to gracefully stop the dispatcher when the server
 	// is shutdown. It waits for the remaining work to complete.
 	shutdownWg *sync.WaitGroup
@@ -427,29 +469,46 @@ func (d *dispatcherImpl) runForDaemon(daemon *dbmodel.Daemon, triggers Triggers,
 	}
 
 	for _, selector := range selectors {
-		if group := d.getGroup(selector); group != nil {
-			for _, checker := range group.checkers {
-				if !d.checkerController.isCheckerEnabledForDaemon(daemon.ID, checker.name) {
-					// Skip disabled checker.
-					continue
-				}
+		group := d.getGroup(selector)
+		if group == nil {
+			continue
+		}
+		var services []*dbmodel.Service
+		if selector == KeaHAService {
+			services = d.getHAServicesForReview(ctx)
+		}
+		for _, checker := range group.checkers {
+			if !d.checkerController.isCheckerEnabledForDaemon(daemon.ID, checker.name) {
+				// Skip disabled checker.
+				continue
+			}
 
-				// Execute checker.
-				report, err := checker.checkFn(ctx)
-				if err != nil {
-					log.Errorf("Malformed report created by the config review checker %s: %+v",
-						checker.name, err)
+			var reports []*Report
+			if selector == KeaHAService {
+				// Execute checker for each HA service. Report the issues found
+				// for any of the services.
+				for _, service := range services {
+					ctx.subjectService = service
+					if report := runChecker(ctx, checker); report != nil {
+						reports = append(reports, report)
+					}
 				}
+				ctx.subjectService = nil
+			} else if report := runChecker(ctx, checker); report != nil {
+				reports = append(reports, report)
+			}
 
-				if report == nil {
-					// Create a success report.
-					report, err = newEmptyReport(ctx)
-					if err != nil {
-						log.Errorf("Malformed empty report created for a successful config review")
-					}
+			if len(reports) == 0 {
+				// Create a success report.
+				report, err := newEmptyReport(ctx)
+				if err != nil {
+					log.Errorf("Malformed empty report created for a successful config review")
 				}
+				reports = append(reports, report)
+			}
 
-				// Accumulate reports.
+			// Accumulate reports.
+			for _, report := range reports {
 				ctx.reports = append(ctx.reports, taggedReport{
 					checkerName: checker.name,
 					report:      report,
@@ -460,6 +519,47 @@ func (d *dispatcherImpl) runForDaemon(daemon *dbmodel.Daemon, triggers Triggers,
 	d.reviewDoneChan <- ctx
 }
 
+// Executes the checker and returns the report about the found issue. It
+// returns nil if no issues were found.
+func runChecker(ctx *ReviewContext, checker *checker) *Report {
+	report, err := checker.checkFn(ctx)
+	if err != nil {
+		log.Errorf("Malformed report created by the config review checker %s: %+v",
+			checker.name, err)
+	}
+	return report
+}
+
+// Fetches the HA services the subject daemon belongs to for the review by the
+// checkers from the KeaHAService dispatch group. The daemon instance
+// under review replaces its copy fetched from the database because its
+// configuration can be newer. The other daemons in the services become
+// the referenced daemons of the review. As a result, their reports are
+// deleted and rebuilt when the daemon's configuration changes.
+func (d *dispatcherImpl) getHAServicesForReview(ctx *ReviewContext) (services []*dbmodel.Service) {
+	daemon := ctx.subjectDaemon
+	dbServices, err := dbmodel.GetDetailedServicesByDaemonID(d.db, daemon.ID)
+	if err != nil {
+		log.Errorf("Problem getting the HA services for the configuration review of daemon %d: %+v",
+			daemon.ID, err)
+		return
+	}
+	for i := range dbServices {
+		if dbServices[i].HAService == nil {
+			continue
+		}
+		for j := range dbServices[i].Daemons {
+			if dbServices[i].Daemons[j].ID == daemon.ID {
+				dbServices[i].Daemons[j] = daemon
+			} else {
+				ctx.addRefDaemon(dbServices[i].Daemons[j])
+			}
+		}
+		services = append(services, &dbServices[i])
+	}
+	return
+}
+
 // Checks if the dispatch group has checkers enabled for a specific daemon.
 func (d *dispatcherImpl) hasEnabledCheckersForTrigger(daemon *dbmodel.Daemon, trigger Trigger, group *dispatchGroup) bool {
 	if !group.hasCheckersForTrigger(trigger) {
@@ -625,6 +725,11 @@ func (d *dispatcherImpl) populateReports(ctx *ReviewContext) (err error) {
 			Content:     r.report.content,
 			DaemonID:    r.report.daemonID,
 			RefDaemons:  assoc,
+			Fix:         r.report.fix,
+		}
+		// The fix patches the configuration it was generated for.
+		if cr.Fix != nil && ctx.subjectDaemon.KeaDaemon != nil {
+			cr.Fix.ConfigHash = ctx.subjectDaemon.KeaDaemon.ConfigHash
 		}
 		err = dbmodel.AddConfigReport(tx, cr)
 		if err != nil {
@@ -671,6 +776,8 @@ func (d *dispatcherImpl) populateReports(ctx *ReviewContext) (err error) {
 // Returns dispatch group indicated by the selector or nil when such group
 // does not exist.
 func (d *dispatcherImpl) getGroup(selector DispatchGroupSelector) *dispatchGroup {
+	d.groupsMutex.RLock()
+	defer d.groupsMutex.RUnlock()
 	if g, ok := d.groups[selector]; ok {
 		return g
 	}
@@ -683,6 +790,7 @@ func NewDispatcher(db *dbops.PgDB) Dispatcher {
 	dispatcher := &dispatcherImpl{
 		db:                db,
 		groups:            make(map[DispatchGroupSelector]*dispatchGroup),
+		groupsMutex:       &sync.RWMutex{},
 		shutdownWg:        &sync.WaitGroup{},
 		reviewWg:          &sync.WaitGroup{},
 		mutex:             &sync.RWMutex{},
@@ -702,11 +810,13 @@ func NewDispatcher(db *dbops.PgDB) Dispatcher {
 // Each checker is assigned a unique name so it will be possible to
 // list available checkers and/or selectively disable them.
 func (d *dispatcherImpl) RegisterChecker(selector DispatchGroupSelector, checkerName string, triggers Triggers, checkFn func(*ReviewContext) (*Report, error)) {
-	group := d.getGroup(selector)
-	if group == nil {
-		group = newDispatchGroup()
-		d.groups[selector] = group
+	d.groupsMutex.Lock()
+	defer d.groupsMutex.Unlock()
+	group := newDispatchGroup()
+	if g, ok := d.groups[selector]; ok {
+		group = g.clone()
 	}
+	d.groups[selector] = group
 
 	group.appendChecker(
 		&checker{
@@ -720,9 +830,12 @@ func (d *dispatcherImpl) RegisterChecker(selector DispatchGroupSelector, checker
 // Unregisters a checker from a dispatch group. It returns a boolean
 // value indicating if the matching checker was found and removed (if true).
 func (d *dispatcherImpl) UnregisterChecker(selector DispatchGroupSelector, checkerName string) bool {
-	if group := d.getGroup(selector); group != nil {
-		for i := range group.checkers {
-			if group.checkers[i].name == checkerName {
+	d.groupsMutex.Lock()
+	defer d.groupsMutex.Unlock()
+	if g, ok := d.groups[selector]; ok {
+		for i := range g.checkers {
+			if g.checkers[i].name == checkerName {
+				group := g.clone()
 				// When we're removing a checker we should decrease the appropriate
 				// reference counters of the triggers it was using. If the reference
 				// counter becomes 0, the dispatcher no longer runs reviews for
@@ -735,6 +848,14 @@ func (d *dispatcherImpl) UnregisterChecker(selector DispatchGroupSelector, check
 				group.checkers = append(group.checkers[:i], group.checkers[i+1:]...)
 				if len(group.checkers) == 0 {
 					delete(d.groups, selector)
+				} else {
+					d.groups[selector] = group
+				}
+				// Forget the states of the checker no longer registered
+				// in any group, so they are not inherited by a checker
+				// registered under the same name in the future.
+				if !d.isCheckerRegistered(checkerName) {
+					d.checkerController.deleteStates(checkerName)
 				}
 				return true
 			}
@@ -743,6 +864,19 @@ func (d *dispatcherImpl) UnregisterChecker(selector DispatchGroupSelector, check
 	return false
 }
 
+// Checks if the checker with the specified name is registered in any
+// dispatch group. The caller must hold the groups mutex.
+func (d *dispatcherImpl) isCheckerRegistered(checkerName string) bool {
+	for _, group := range d.groups {
+		for _, checker := range group.checkers {
+			if checker.name == checkerName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Returns the metadata of all registered configuration checkers for a given
 // daemon. Metadata is a set of values describing the checker, i.e., name of
 // the checker, list of triggers and selectors, and checker state (enabled
@@ -768,6 +902,8 @@ func (d *dispatcherImpl) GetCheckersMetadata(daemon *dbmodel.Daemon) ([]*Checker
 		}
 	}
 
+	d.groupsMutex.RLock()
+	defer d.groupsMutex.RUnlock()
 	for selector, group := range d.groups {
 		if daemon != nil {
 			// Skips the unavailable selector.
@@ -825,6 +961,8 @@ func (d *dispatcherImpl) GetCheckersMetadata(daemon *dbmodel.Daemon) ([]*Checker
 // In this case, bump up the enforceDispatchSeq constant value to enforce
 // generation of a new signature and new config reviews.
 func (d *dispatcherImpl) GetSignature() string {
+	d.groupsMutex.RLock()
+	defer d.groupsMutex.RUnlock()
 	return storkutil.Fnv128(fmt.Sprintf("%d:%+v", d.enforceSeq, d.groups))
 }
 
@@ -918,6 +1056,14 @@ func RegisterDefaultCheckers(dispatcher Dispatcher) {
 	dispatcher.RegisterChecker(KeaDHCPDaemon, "subnet_cmds_and_cb_mutual_exclusion", GetDefaultTriggers(), subnetCmdsAndConfigBackendMutualExclusion)
 	dispatcher.RegisterChecker(KeaCADaemon, "agent_credentials_over_https", ExtendDefaultTriggers(StorkAgentConfigModified), credentialsOverHTTPS)
 	dispatcher.RegisterChecker(KeaCADaemon, "ca_control_sockets", GetDefaultTriggers(), controlSocketsCA)
+	dispatcher.RegisterChecker(KeaHAService, "ha_peers_consistency", GetDefaultTriggers(), haPeersConsistency)
+	dispatcher.RegisterChecker(KeaHAService, "ha_subnets_consistency", GetDefaultTriggers(), haSubnetsConsistency)
+	dispatcher.RegisterChecker(KeaHAService, "ha_pools_consistency", GetDefaultTriggers(), haPoolsConsistency)
+	dispatcher.RegisterChecker(KeaHAService, "ha_reservations_consistency", GetDefaultTriggers(), haReservationsConsistency)
+	dispatcher.RegisterChecker(KeaHAService, "ha_options_consistency", GetDefaultTriggers(), haOptionsConsistency)
+	dispatcher.RegisterChecker(KeaHAService, "ha_client_classes_consistency", GetDefaultTriggers(), haClientClassesConsistency)
+	dispatcher.RegisterChecker(KeaHAService, "ha_lease_lifetimes_consistency", GetDefaultTriggers(), haLeaseLifetimesConsistency)
+	dispatcher.RegisterChecker(KeaHAService, "ha_hook_libraries_consistency", GetDefaultTriggers(), haHookLibrariesConsistency)
 }
 
 // Fetches all checker preferences from the database and loads them into
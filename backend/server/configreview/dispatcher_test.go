@@ -2,6 +2,7 @@ package configreview
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -548,6 +549,103 @@ func TestCascadeReview(t *testing.T) {
 	require.Equal(t, "CA test output", *reports[0].Content)
 }
 
+// Test that the checkers from the KeaHAService group are run for each HA
+// service the daemon belongs to, and that the review of one daemon in
+// the service causes the review of its partner.
+func TestReviewHAService(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	var daemons []*dbmodel.Daemon
+	for i, subnets := range []string{
+		`[{"id": 1, "subnet": "192.0.2.0/24"}]`,
+		`[{"id": 1, "subnet": "192.0.2.0/24"}, {"id": 2, "subnet": "198.51.100.0/24"}]`,
+		`[]`,
+	} {
+		machine := &dbmodel.Machine{
+			Address:   fmt.Sprintf("machine%d", i),
+			AgentPort: 8080,
+		}
+		err := dbmodel.AddMachine(db, machine)
+		require.NoError(t, err)
+
+		config, err := dbmodel.NewKeaConfigFromJSON(fmt.Sprintf(`{"Dhcp4": {"subnet4": %s}}`, subnets))
+		require.NoError(t, err)
+		app := &dbmodel.App{
+			Type:      dbmodel.AppTypeKea,
+			Name:      fmt.Sprintf("kea-%d", i),
+			MachineID: machine.ID,
+			Daemons: []*dbmodel.Daemon{
+				{
+					Name:   dbmodel.DaemonNameDHCPv4,
+					Active: true,
+					KeaDaemon: &dbmodel.KeaDaemon{
+						Config:     config,
+						ConfigHash: fmt.Sprint(i),
+					},
+				},
+			},
+		}
+		appDaemons, err := dbmodel.AddApp(db, app)
+		require.NoError(t, err)
+		daemons = append(daemons, appDaemons[0])
+	}
+
+	// The first two daemons belong to the HA service.
+	service := &dbmodel.Service{
+		BaseService: dbmodel.BaseService{
+			ServiceType: "ha_dhcp",
+			Daemons:     daemons[:2],
+		},
+		HAService: &dbmodel.BaseHAService{
+			HAType:      dbmodel.HATypeDhcp4,
+			HAMode:      dbmodel.HAModeHotStandby,
+			PrimaryID:   daemons[0].ID,
+			SecondaryID: daemons[1].ID,
+		},
+	}
+	err := dbmodel.AddService(db, service)
+	require.NoError(t, err)
+
+	dispatcher := NewDispatcher(db)
+	dispatcher.RegisterChecker(KeaHAService, "ha_subnets_consistency", GetDefaultTriggers(), haSubnetsConsistency)
+	dispatcher.Start()
+	defer dispatcher.Shutdown()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	ok := dispatcher.BeginReview(daemons[0], Triggers{ConfigModified}, func(daemonID int64, err error) {
+		defer wg.Done()
+		require.NoError(t, err)
+	})
+	require.True(t, ok)
+	wg.Wait()
+
+	// Both daemons in the service have the report referencing both of them.
+	for _, daemon := range daemons[:2] {
+		reports, total, err := dbmodel.GetConfigReportsByDaemonID(db, 0, 0, daemon.ID, false)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, total)
+		require.Equal(t, "ha_subnets_consistency", reports[0].CheckerName)
+		require.NotNil(t, reports[0].Content)
+		require.Contains(t, *reports[0].Content, "the subnet 198.51.100.0/24 is missing in the dhcp4 server of kea-0")
+		require.Len(t, reports[0].RefDaemons, 2)
+	}
+
+	// The daemon outside the service gets the empty report.
+	wg.Add(1)
+	ok = dispatcher.BeginReview(daemons[2], Triggers{ConfigModified}, func(daemonID int64, err error) {
+		defer wg.Done()
+	})
+	require.True(t, ok)
+	wg.Wait()
+
+	reports, total, err := dbmodel.GetConfigReportsByDaemonID(db, 0, 0, daemons[2].ID, false)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Nil(t, reports[0].Content)
+}
+
 // Test that the dispatcher accepts different trigger types and schedules
 // the reviews depending on whether appropriate config checkers have been
 // registered.
@@ -708,6 +806,20 @@ func TestRegisterDefaultCheckers(t *testing.T) {
 	require.EqualValues(t, 2, dispatcher.groups[KeaCADaemon].triggerRefCounts[ConfigModified])
 	require.EqualValues(t, 0, dispatcher.groups[KeaCADaemon].triggerRefCounts[DBHostsModified])
 	require.EqualValues(t, 1, dispatcher.groups[KeaCADaemon].triggerRefCounts[StorkAgentConfigModified])
+
+	// KeaHAService group.
+	checkerNames = []string{}
+	for _, p := range dispatcher.groups[KeaHAService].checkers {
+		checkerNames = append(checkerNames, p.name)
+	}
+	require.Contains(t, checkerNames, "ha_peers_consistency")
+	require.Contains(t, checkerNames, "ha_subnets_consistency")
+	require.Contains(t, checkerNames, "ha_pools_consistency")
+	require.Contains(t, checkerNames, "ha_reservations_consistency")
+	require.Contains(t, checkerNames, "ha_options_consistency")
+	require.Contains(t, checkerNames, "ha_client_classes_consistency")
+	require.Contains(t, checkerNames, "ha_lease_lifetimes_consistency")
+	require.Contains(t, checkerNames, "ha_hook_libraries_consistency")
 }
 
 // Verifies that registering new checkers and bumping up the
@@ -785,6 +897,7 @@ func TestDispatchGroupSelectorToString(t *testing.T) {
 	require.EqualValues(t, "kea-dhcp-v6-daemon", KeaDHCPv6Daemon.String())
 	require.EqualValues(t, "kea-d2-daemon", KeaD2Daemon.String())
 	require.EqualValues(t, "bind9-daemon", Bind9Daemon.String())
+	require.EqualValues(t, "kea-ha-service", KeaHAService.String())
 	require.EqualValues(t, "unknown", DispatchGroupSelector(42).String())
 }
 
@@ -1085,3 +1198,32 @@ func TestTriggersIsInternalRun(t *testing.T) {
 		require.False(t, Triggers{}.isInternalRun())
 	})
 }
+
+// Test that the checker states are removed when the checker is no longer
+// registered in any dispatch group.
+func TestUnregisterCheckerRemovesStates(t *testing.T) {
+	dispatcher := NewDispatcher(nil)
+	dispatcher.RegisterChecker(KeaDHCPv4Daemon, "checker", GetDefaultTriggers(), nil)
+	dispatcher.RegisterChecker(KeaDHCPv6Daemon, "checker", GetDefaultTriggers(), nil)
+	daemon := &dbmodel.Daemon{ID: 1, Name: dbmodel.DaemonNameDHCPv4}
+	require.NoError(t, dispatcher.SetCheckerState(nil, "checker", CheckerStateDisabled))
+	require.NoError(t, dispatcher.SetCheckerState(daemon, "checker", CheckerStateEnabled))
+
+	// The checker is still registered for the DHCPv6 daemons, so its
+	// states are preserved.
+	require.True(t, dispatcher.UnregisterChecker(KeaDHCPv4Daemon, "checker"))
+	metadata, err := dispatcher.GetCheckersMetadata(nil)
+	require.NoError(t, err)
+	require.Len(t, metadata, 1)
+	require.False(t, metadata[0].GloballyEnabled)
+
+	// Register the checker again after unregistering it completely. The
+	// states should be reset to the defaults.
+	require.True(t, dispatcher.UnregisterChecker(KeaDHCPv6Daemon, "checker"))
+	dispatcher.RegisterChecker(KeaDHCPv4Daemon, "checker", GetDefaultTriggers(), nil)
+	metadata, err = dispatcher.GetCheckersMetadata(daemon)
+	require.NoError(t, err)
+	require.Len(t, metadata, 1)
+	require.True(t, metadata[0].GloballyEnabled)
+	require.Equal(t, CheckerStateInherit, metadata[0].State)
+}
@@ -0,0 +1,382 @@
+package configreview
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	keaconfig "isc.org/stork/appcfg/kea"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// The maximum number of differences listed in a single report created
+// by the HA consistency checkers.
+const maxHAReportedDifferences = 10
+
+// Configuration items of an HA peer compared by the HA consistency
+// checkers. The keys are the descriptions of the configuration elements
+// (e.g., "the subnet 192.0.2.0/24") and the values hold the normalized
+// contents of these elements.
+type haPeerConfigItems map[string]string
+
+// Specifies which configuration items missing in some of the HA peers are
+// reported by the HA consistency checkers.
+type haMissingItemsPolicy int
+
+const (
+	// The missing items are not reported. It is used when the items
+	// belong to other items (e.g., pools belong to subnets) which are
+	// verified by other checkers.
+	haIgnoreMissingItems haMissingItemsPolicy = iota
+	// The items are reported as missing unless they are present only in
+	// the peers taking part in multiple HA relationships (i.e., hubs in
+	// the hub-and-spoke configuration). The hubs serve the subnets of
+	// all their partners, so they may have more items.
+	haReportMissingItemsExceptHubs
+	// All missing items are reported.
+	haReportMissingItems
+)
+
+// Returns a description of the daemon used in the reports listing the
+// differences between the HA peers' configurations.
+func getHAPeerLabel(daemon *dbmodel.Daemon) string {
+	if daemon.App != nil && len(daemon.App.Name) > 0 {
+		return fmt.Sprintf("%s server of %s", daemon.Name, daemon.App.Name)
+	}
+	return fmt.Sprintf("%s server with ID %d", daemon.Name, daemon.ID)
+}
+
+// Joins the descriptions into a human-readable enumeration, e.g.
+// "a, b and c".
+func joinEnumeration(descriptions []string) string {
+	if len(descriptions) < 2 {
+		return strings.Join(descriptions, "")
+	}
+	return fmt.Sprintf("%s and %s", strings.Join(descriptions[:len(descriptions)-1], ", "), descriptions[len(descriptions)-1])
+}
+
+// Returns the descriptions of the selected daemons joined into a
+// human-readable enumeration.
+func joinHAPeerLabels(daemons []*dbmodel.Daemon, indexes []int) string {
+	var labels []string
+	for _, i := range indexes {
+		labels = append(labels, getHAPeerLabel(daemons[i]))
+	}
+	return joinEnumeration(labels)
+}
+
+// Returns the daemons of the reviewed HA service which configurations
+// can be compared.
+func getHAServiceDaemons(ctx *ReviewContext) (daemons []*dbmodel.Daemon) {
+	if ctx.subjectService == nil {
+		return
+	}
+	for _, daemon := range ctx.subjectService.Daemons {
+		if daemon.KeaDaemon != nil && daemon.KeaDaemon.Config != nil {
+			daemons = append(daemons, daemon)
+		}
+	}
+	return
+}
+
+// Returns the daemon's configuration of the HA relationship represented
+// by the service. The services detected by the earlier Stork versions
+// are not associated with the relationship names. In this case, the first
+// relationship is returned. It returns nil if the relationship is not
+// configured.
+func getHAServiceRelationship(daemon *dbmodel.Daemon, service *dbmodel.Service) *keaconfig.HA {
+	relationships := daemon.KeaDaemon.Config.GetHARelationships()
+	for i := range relationships {
+		if service.HAService == nil || len(service.HAService.Relationship) == 0 ||
+			relationships[i].GetRelationshipName() == service.HAService.Relationship {
+			return &relationships[i]
+		}
+	}
+	return nil
+}
+
+// Compares the configuration items of the HA peers and returns the
+// descriptions of the differences. The differences are reported when
+// the item contents differ between the peers. The items missing in some
+// of the peers are reported according to the policy. The hubs slice
+// indicates which peers take part in multiple HA relationships.
+func compareHAPeerConfigItems(daemons []*dbmodel.Daemon, hubs []bool, items []haPeerConfigItems, policy haMissingItemsPolicy) (differences []string) {
+	keySet := make(map[string]bool)
+	for _, daemonItems := range items {
+		for key := range daemonItems {
+			keySet[key] = true
+		}
+	}
+	var keys []string
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var holders, missing []int
+		onlyHubs := true
+		for i := range daemons {
+			if _, ok := items[i][key]; ok {
+				holders = append(holders, i)
+				if !hubs[i] || policy == haReportMissingItems {
+					onlyHubs = false
+				}
+			} else {
+				missing = append(missing, i)
+			}
+		}
+		if policy != haIgnoreMissingItems && len(missing) > 0 && !onlyHubs {
+			differences = append(differences, fmt.Sprintf("%s is missing in the %s",
+				key, joinHAPeerLabels(daemons, missing)))
+		}
+		for _, i := range holders[1:] {
+			if items[i][key] != items[holders[0]][key] {
+				differences = append(differences, fmt.Sprintf("%s is configured differently in the %s",
+					key, joinHAPeerLabels(daemons, holders)))
+				break
+			}
+		}
+	}
+	return differences
+}
+
+// Common function for the checkers comparing the configurations of the
+// HA peers. The aspect describes the compared configuration part in the
+// report. The getItems function returns the configuration items of the
+// daemon. The policy specifies which missing items are reported. It
+// returns a report referencing all daemons in the service when any
+// differences are found.
+func checkHAServiceConsistency(ctx *ReviewContext, aspect string, policy haMissingItemsPolicy, getItems func(*dbmodel.Daemon) haPeerConfigItems) (*Report, error) {
+	daemons := getHAServiceDaemons(ctx)
+	if len(daemons) < 2 {
+		// There is nothing to compare.
+		return nil, nil
+	}
+
+	hubs := make([]bool, len(daemons))
+	items := make([]haPeerConfigItems, len(daemons))
+	for i, daemon := range daemons {
+		hubs[i] = len(daemon.KeaDaemon.Config.GetHARelationships()) > 1
+		items[i] = getItems(daemon)
+	}
+
+	differences := compareHAPeerConfigItems(daemons, hubs, items, policy)
+	if len(differences) == 0 {
+		return nil, nil
+	}
+	if len(differences) > maxHAReportedDifferences {
+		differences = append(differences[:maxHAReportedDifferences],
+			fmt.Sprintf("and %d more", len(differences)-maxHAReportedDifferences))
+	}
+
+	placeholders := make([]string, len(daemons))
+	for i := range placeholders {
+		placeholders[i] = "{daemon}"
+	}
+	relationship := ""
+	if len(ctx.subjectService.HAService.Relationship) > 0 {
+		relationship = fmt.Sprintf(" in the '%s' relationship", ctx.subjectService.HAService.Relationship)
+	}
+
+	report := NewReport(ctx, fmt.Sprintf("The configurations of the HA peers %s%s "+
+		"have inconsistent %s: %s. The servers in an HA relationship must be "+
+		"configured consistently. Otherwise, the DHCP service may change "+
+		"unexpectedly when one of the servers takes over the traffic of "+
+		"its partner.", joinEnumeration(placeholders), relationship, aspect,
+		strings.Join(differences, "; ")))
+	for _, daemon := range daemons {
+		report = report.referencingDaemon(daemon)
+	}
+	return report.create()
+}
+
+// Returns a description of the subnet used as a configuration item key.
+func getHASubnetKey(subnet keaconfig.Subnet) string {
+	prefix, err := subnet.GetCanonicalPrefix()
+	if err != nil {
+		prefix = subnet.GetPrefix()
+	}
+	return fmt.Sprintf("the subnet %s", prefix)
+}
+
+// Returns the top-level subnets and the subnets belonging to the shared
+// networks.
+func getHAPeerSubnets(config *dbmodel.KeaConfig) (subnets []keaconfig.Subnet) {
+	for _, sharedNetwork := range config.GetSharedNetworks(true) {
+		subnets = append(subnets, sharedNetwork.GetSubnets()...)
+	}
+	return
+}
+
+// Returns a description of the shared network used as a configuration
+// item key.
+func getHASharedNetworkKey(sharedNetwork keaconfig.SharedNetwork) string {
+	return fmt.Sprintf("the shared network %s", sharedNetwork.GetName())
+}
+
+// Converts the values to JSON and returns them sorted and joined. It
+// makes the comparison independent of the values order in the
+// configuration.
+func joinSortedJSON[T any](values []T) string {
+	var items []string
+	for _, value := range values {
+		item, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		items = append(items, string(item))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+// The checker verifying that the HA peers use the same HA mode and that
+// they have the same names, URLs and roles of the peers configured for
+// their relationship.
+func haPeersConsistency(ctx *ReviewContext) (*Report, error) {
+	if ctx.subjectService == nil {
+		return nil, nil
+	}
+	return checkHAServiceConsistency(ctx, "HA peers configurations", haReportMissingItems, func(daemon *dbmodel.Daemon) haPeerConfigItems {
+		items := make(haPeerConfigItems)
+		relationship := getHAServiceRelationship(daemon, ctx.subjectService)
+		if relationship == nil {
+			return items
+		}
+		items["the HA mode"] = *relationship.Mode
+		for _, peer := range relationship.Peers {
+			items[fmt.Sprintf("the peer %s", *peer.Name)] = fmt.Sprintf("%s %s", *peer.URL, *peer.Role)
+		}
+		return items
+	})
+}
+
+// The checker verifying that the HA peers have the same subnets with the
+// same IDs.
+func haSubnetsConsistency(ctx *ReviewContext) (*Report, error) {
+	return checkHAServiceConsistency(ctx, "subnets", haReportMissingItemsExceptHubs, func(daemon *dbmodel.Daemon) haPeerConfigItems {
+		items := make(haPeerConfigItems)
+		for _, subnet := range getHAPeerSubnets(daemon.KeaDaemon.Config) {
+			items[getHASubnetKey(subnet)] = fmt.Sprint(subnet.GetID())
+		}
+		return items
+	})
+}
+
+// The checker verifying that the HA peers have the same address and
+// delegated prefix pools in their common subnets.
+func haPoolsConsistency(ctx *ReviewContext) (*Report, error) {
+	return checkHAServiceConsistency(ctx, "pools", haIgnoreMissingItems, func(daemon *dbmodel.Daemon) haPeerConfigItems {
+		items := make(haPeerConfigItems)
+		for _, subnet := range getHAPeerSubnets(daemon.KeaDaemon.Config) {
+			var pools []string
+			for _, pool := range subnet.GetPools() {
+				pools = append(pools, pool.Pool)
+			}
+			for _, pdPool := range subnet.GetPDPools() {
+				pools = append(pools, fmt.Sprintf("%s/%d/%d", pdPool.Prefix, pdPool.PrefixLen, pdPool.DelegatedLen))
+			}
+			sort.Strings(pools)
+			items[getHASubnetKey(subnet)] = strings.Join(pools, ",")
+		}
+		return items
+	})
+}
+
+// The checker verifying that the HA peers have the same global host
+// reservations and the same host reservations in their common subnets.
+// It doesn't compare the host reservations stored in the databases.
+func haReservationsConsistency(ctx *ReviewContext) (*Report, error) {
+	return checkHAServiceConsistency(ctx, "host reservations", haIgnoreMissingItems, func(daemon *dbmodel.Daemon) haPeerConfigItems {
+		config := daemon.KeaDaemon.Config
+		items := make(haPeerConfigItems)
+		items["the global scope"] = joinSortedJSON(config.GetReservations())
+		for _, subnet := range getHAPeerSubnets(config) {
+			items[getHASubnetKey(subnet)] = joinSortedJSON(subnet.GetReservations())
+		}
+		return items
+	})
+}
+
+// The checker verifying that the HA peers have the same DHCP options
+// specified at the global level, and in their common shared networks,
+// subnets and pools.
+func haOptionsConsistency(ctx *ReviewContext) (*Report, error) {
+	return checkHAServiceConsistency(ctx, "DHCP options", haIgnoreMissingItems, func(daemon *dbmodel.Daemon) haPeerConfigItems {
+		config := daemon.KeaDaemon.Config
+		items := make(haPeerConfigItems)
+		items["the global scope"] = joinSortedJSON(config.GetDHCPOptions())
+		for _, sharedNetwork := range config.GetSharedNetworks(false) {
+			items[getHASharedNetworkKey(sharedNetwork)] = joinSortedJSON(sharedNetwork.GetDHCPOptions())
+		}
+		for _, subnet := range getHAPeerSubnets(config) {
+			items[getHASubnetKey(subnet)] = joinSortedJSON(subnet.GetDHCPOptions())
+			for _, pool := range subnet.GetPools() {
+				items[fmt.Sprintf("the pool %s", pool.Pool)] = joinSortedJSON(pool.OptionData)
+			}
+			for _, pdPool := range subnet.GetPDPools() {
+				items[fmt.Sprintf("the prefix pool %s/%d", pdPool.Prefix, pdPool.PrefixLen)] = joinSortedJSON(pdPool.OptionData)
+			}
+		}
+		return items
+	})
+}
+
+// The checker verifying that the HA peers have the same client classes
+// with the same test expressions and DHCP options.
+func haClientClassesConsistency(ctx *ReviewContext) (*Report, error) {
+	return checkHAServiceConsistency(ctx, "client classes", haReportMissingItemsExceptHubs, func(daemon *dbmodel.Daemon) haPeerConfigItems {
+		items := make(haPeerConfigItems)
+		for _, clientClass := range daemon.KeaDaemon.Config.GetClientClasses() {
+			items[fmt.Sprintf("the client class %s", clientClass.Name)] = fmt.Sprintf("%s:%s",
+				clientClass.Test, joinSortedJSON(clientClass.OptionData))
+		}
+		return items
+	})
+}
+
+// The checker verifying that the HA peers have the same valid and
+// preferred lease lifetimes specified at the global level, and in their
+// common shared networks and subnets.
+func haLeaseLifetimesConsistency(ctx *ReviewContext) (*Report, error) {
+	// Returns the lifetimes in a comparable form.
+	formatLifetimes := func(valid keaconfig.ValidLifetimeParameters, preferred keaconfig.PreferredLifetimeParameters) string {
+		lifetimes, _ := json.Marshal(struct {
+			keaconfig.ValidLifetimeParameters
+			keaconfig.PreferredLifetimeParameters
+		}{valid, preferred})
+		return string(lifetimes)
+	}
+	return checkHAServiceConsistency(ctx, "lease lifetimes", haIgnoreMissingItems, func(daemon *dbmodel.Daemon) haPeerConfigItems {
+		config := daemon.KeaDaemon.Config
+		items := make(haPeerConfigItems)
+		items["the global scope"] = formatLifetimes(config.GetValidLifetimeParameters(), config.GetPreferredLifetimeParameters())
+		for _, sharedNetwork := range config.GetSharedNetworks(false) {
+			parameters := sharedNetwork.GetSharedNetworkParameters()
+			items[getHASharedNetworkKey(sharedNetwork)] = formatLifetimes(parameters.ValidLifetimeParameters, parameters.PreferredLifetimeParameters)
+		}
+		for _, subnet := range getHAPeerSubnets(config) {
+			parameters := subnet.GetSubnetParameters()
+			items[getHASubnetKey(subnet)] = formatLifetimes(parameters.ValidLifetimeParameters, parameters.PreferredLifetimeParameters)
+		}
+		return items
+	})
+}
+
+// The checker verifying that the HA peers load the same hook libraries.
+// The libraries are compared by their file names because the paths
+// may differ between the machines. The library parameters are not
+// compared because some of them (e.g., this-server-name) are different
+// for each peer.
+func haHookLibrariesConsistency(ctx *ReviewContext) (*Report, error) {
+	return checkHAServiceConsistency(ctx, "hook libraries", haReportMissingItemsExceptHubs, func(daemon *dbmodel.Daemon) haPeerConfigItems {
+		items := make(haPeerConfigItems)
+		for _, library := range daemon.KeaDaemon.Config.GetHookLibraries() {
+			items[fmt.Sprintf("the hook library %s", path.Base(library.Library))] = ""
+		}
+		return items
+	})
+}
@@ -0,0 +1,438 @@
+package configreview
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Returns the DHCPv4 configuration of an HA peer in the load-balancing
+// relationship between server1 and server2. The extra parameter holds
+// the additional top-level parameters.
+func getHAPeerTestConfig(thisServerName, extra string) string {
+	return fmt.Sprintf(`{
+        "Dhcp4": {
+            %s
+            "hooks-libraries": [
+                {
+                    "library": "/usr/lib/kea/libdhcp_lease_cmds.so"
+                },
+                {
+                    "library": "/usr/lib/kea/libdhcp_ha.so",
+                    "parameters": {
+                        "high-availability": [{
+                            "this-server-name": "%s",
+                            "mode": "load-balancing",
+                            "peers": [
+                                {
+                                    "name": "server1",
+                                    "url": "http://192.0.2.1:8001",
+                                    "role": "primary"
+                                },
+                                {
+                                    "name": "server2",
+                                    "url": "http://192.0.2.2:8001",
+                                    "role": "secondary"
+                                }
+                            ]
+                        }]
+                    }
+                }
+            ]
+        }
+    }`, extra, thisServerName)
+}
+
+// Creates the review context for the HA service comprising the daemons
+// with the specified configurations. The first daemon is the subject
+// daemon.
+func createHAServiceReviewContext(t *testing.T, configs ...string) *ReviewContext {
+	service := &dbmodel.Service{
+		HAService: &dbmodel.BaseHAService{
+			HAType:       dbmodel.HATypeDhcp4,
+			HAMode:       dbmodel.HAModeLoadBalancing,
+			Relationship: "server1,server2",
+		},
+	}
+	for i, configStr := range configs {
+		config, err := dbmodel.NewKeaConfigFromJSON(configStr)
+		require.NoError(t, err)
+		service.Daemons = append(service.Daemons, &dbmodel.Daemon{
+			ID:   int64(i + 1),
+			Name: dbmodel.DaemonNameDHCPv4,
+			App: &dbmodel.App{
+				Name: fmt.Sprintf("kea-%d", i+1),
+			},
+			KeaDaemon: &dbmodel.KeaDaemon{
+				Config: config,
+			},
+		})
+	}
+	ctx := newReviewContext(nil, service.Daemons[0], Triggers{ManualRun}, nil)
+	ctx.subjectService = service
+	return ctx
+}
+
+// Test that the HA consistency checkers return no reports when the
+// peers' configurations are consistent.
+func TestHAConsistencyCheckersNoIssues(t *testing.T) {
+	extra := `
+        "valid-lifetime": 3600,
+        "option-data": [{"name": "domain-name-servers", "data": "192.0.2.53"}],
+        "client-classes": [{"name": "foo", "test": "substring(option[60].hex,0,3) == 'foo'"}],
+        "subnet4": [{
+            "id": 1,
+            "subnet": "192.0.2.0/24",
+            "pools": [{"pool": "192.0.2.10-192.0.2.100"}],
+            "reservations": [{"hw-address": "01:02:03:04:05:06", "ip-address": "192.0.2.5"}]
+        }],
+    `
+	ctx := createHAServiceReviewContext(t, getHAPeerTestConfig("server1", extra), getHAPeerTestConfig("server2", extra))
+
+	for _, checker := range []func(*ReviewContext) (*Report, error){
+		haPeersConsistency,
+		haSubnetsConsistency,
+		haPoolsConsistency,
+		haReservationsConsistency,
+		haOptionsConsistency,
+		haClientClassesConsistency,
+		haLeaseLifetimesConsistency,
+		haHookLibrariesConsistency,
+	} {
+		report, err := checker(ctx)
+		require.NoError(t, err)
+		require.Nil(t, report)
+	}
+}
+
+// Test that the HA consistency checkers return no reports when the
+// daemon doesn't belong to any HA service or the service lacks the
+// partner.
+func TestHAConsistencyCheckersNoService(t *testing.T) {
+	ctx := createHAServiceReviewContext(t, getHAPeerTestConfig("server1", ""))
+	report, err := haSubnetsConsistency(ctx)
+	require.NoError(t, err)
+	require.Nil(t, report)
+
+	ctx.subjectService = nil
+	report, err = haPeersConsistency(ctx)
+	require.NoError(t, err)
+	require.Nil(t, report)
+}
+
+// Test that the differences in the subnets are reported and that the
+// report references all daemons in the service.
+func TestHASubnetsConsistency(t *testing.T) {
+	ctx := createHAServiceReviewContext(t,
+		getHAPeerTestConfig("server1", `
+            "subnet4": [
+                {"id": 1, "subnet": "192.0.2.0/24"},
+                {"id": 2, "subnet": "198.51.100.0/24"}
+            ],
+        `),
+		getHAPeerTestConfig("server2", `
+            "shared-networks": [{
+                "name": "foo",
+                "subnet4": [{"id": 3, "subnet": "192.0.2.0/24"}]
+            }],
+        `))
+
+	report, err := haSubnetsConsistency(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "The configurations of the HA peers {daemon} and {daemon} in the 'server1,server2' relationship have inconsistent subnets")
+	require.Contains(t, *report.content, "the subnet 192.0.2.0/24 is configured differently in the dhcp4 server of kea-1 and dhcp4 server of kea-2")
+	require.Contains(t, *report.content, "the subnet 198.51.100.0/24 is missing in the dhcp4 server of kea-2")
+	require.EqualValues(t, 1, report.daemonID)
+	require.ElementsMatch(t, []int64{1, 2}, report.refDaemonIDs)
+}
+
+// Test that the differences in the pools are reported only for the
+// subnets present in both peers.
+func TestHAPoolsConsistency(t *testing.T) {
+	ctx := createHAServiceReviewContext(t,
+		getHAPeerTestConfig("server1", `
+            "subnet4": [
+                {"id": 1, "subnet": "192.0.2.0/24", "pools": [{"pool": "192.0.2.10-192.0.2.100"}]},
+                {"id": 2, "subnet": "198.51.100.0/24", "pools": [{"pool": "198.51.100.10-198.51.100.100"}]}
+            ],
+        `),
+		getHAPeerTestConfig("server2", `
+            "subnet4": [
+                {"id": 1, "subnet": "192.0.2.0/24", "pools": [{"pool": "192.0.2.10-192.0.2.200"}]}
+            ],
+        `))
+
+	report, err := haPoolsConsistency(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "inconsistent pools: the subnet 192.0.2.0/24 is configured differently")
+	require.NotContains(t, *report.content, "198.51.100.0/24")
+}
+
+// Test that the differences in the host reservations are reported
+// regardless of the reservations order.
+func TestHAReservationsConsistency(t *testing.T) {
+	ctx := createHAServiceReviewContext(t,
+		getHAPeerTestConfig("server1", `
+            "reservations": [
+                {"hw-address": "01:02:03:04:05:06", "hostname": "foo"},
+                {"hw-address": "01:02:03:04:05:07", "hostname": "bar"}
+            ],
+            "subnet4": [{
+                "id": 1,
+                "subnet": "192.0.2.0/24",
+                "reservations": [{"hw-address": "01:02:03:04:05:06", "ip-address": "192.0.2.5"}]
+            }],
+        `),
+		getHAPeerTestConfig("server2", `
+            "reservations": [
+                {"hw-address": "01:02:03:04:05:07", "hostname": "bar"},
+                {"hw-address": "01:02:03:04:05:06", "hostname": "foo"}
+            ],
+            "subnet4": [{
+                "id": 1,
+                "subnet": "192.0.2.0/24",
+                "reservations": [{"hw-address": "01:02:03:04:05:06", "ip-address": "192.0.2.6"}]
+            }],
+        `))
+
+	report, err := haReservationsConsistency(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "inconsistent host reservations: the subnet 192.0.2.0/24 is configured differently")
+	require.NotContains(t, *report.content, "global scope")
+}
+
+// Test that the differences in the DHCP options are reported.
+func TestHAOptionsConsistency(t *testing.T) {
+	ctx := createHAServiceReviewContext(t,
+		getHAPeerTestConfig("server1", `
+            "option-data": [{"name": "domain-name-servers", "data": "192.0.2.53"}],
+            "subnet4": [{
+                "id": 1,
+                "subnet": "192.0.2.0/24",
+                "pools": [{"pool": "192.0.2.10-192.0.2.100", "option-data": [{"name": "routers", "data": "192.0.2.1"}]}]
+            }],
+        `),
+		getHAPeerTestConfig("server2", `
+            "option-data": [{"name": "domain-name-servers", "data": "192.0.2.54"}],
+            "subnet4": [{
+                "id": 1,
+                "subnet": "192.0.2.0/24",
+                "pools": [{"pool": "192.0.2.10-192.0.2.100"}]
+            }],
+        `))
+
+	report, err := haOptionsConsistency(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "the global scope is configured differently")
+	require.Contains(t, *report.content, "the pool 192.0.2.10-192.0.2.100 is configured differently")
+	require.NotContains(t, *report.content, "the subnet 192.0.2.0/24")
+}
+
+// Test that the differences in the client classes are reported.
+func TestHAClientClassesConsistency(t *testing.T) {
+	ctx := createHAServiceReviewContext(t,
+		getHAPeerTestConfig("server1", `
+            "client-classes": [
+                {"name": "foo", "test": "member('ALL')"},
+                {"name": "bar"}
+            ],
+        `),
+		getHAPeerTestConfig("server2", `
+            "client-classes": [
+                {"name": "foo", "test": "member('KNOWN')"}
+            ],
+        `))
+
+	report, err := haClientClassesConsistency(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "the client class bar is missing in the dhcp4 server of kea-2")
+	require.Contains(t, *report.content, "the client class foo is configured differently")
+}
+
+// Test that the differences in the lease lifetimes are reported.
+func TestHALeaseLifetimesConsistency(t *testing.T) {
+	ctx := createHAServiceReviewContext(t,
+		getHAPeerTestConfig("server1", `
+            "valid-lifetime": 3600,
+            "subnet4": [{"id": 1, "subnet": "192.0.2.0/24", "max-valid-lifetime": 7200}],
+        `),
+		getHAPeerTestConfig("server2", `
+            "valid-lifetime": 3600,
+            "subnet4": [{"id": 1, "subnet": "192.0.2.0/24"}],
+        `))
+
+	report, err := haLeaseLifetimesConsistency(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "inconsistent lease lifetimes: the subnet 192.0.2.0/24 is configured differently")
+	require.NotContains(t, *report.content, "global scope")
+}
+
+// Test that the hook libraries are compared by the file names.
+func TestHAHookLibrariesConsistency(t *testing.T) {
+	server1 := getHAPeerTestConfig("server1", "")
+	server2 := getHAPeerTestConfig("server2", "")
+
+	ctx := createHAServiceReviewContext(t, server1, server2)
+	report, err := haHookLibrariesConsistency(ctx)
+	require.NoError(t, err)
+	require.Nil(t, report)
+
+	// Different library paths and an additional library.
+	config, err := dbmodel.NewKeaConfigFromJSON(`{
+        "Dhcp4": {
+            "hooks-libraries": [
+                {"library": "/opt/kea/lib/libdhcp_lease_cmds.so"},
+                {"library": "/opt/kea/lib/libdhcp_stat_cmds.so"},
+                {"library": "/opt/kea/lib/libdhcp_ha.so"}
+            ]
+        }
+    }`)
+	require.NoError(t, err)
+	ctx.subjectService.Daemons[1].KeaDaemon.Config = config
+
+	report, err = haHookLibrariesConsistency(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "inconsistent hook libraries: the hook library libdhcp_stat_cmds.so is missing in the dhcp4 server of kea-1.")
+}
+
+// Test that the differences in the peers' configurations are reported.
+func TestHAPeersConsistency(t *testing.T) {
+	server2 := getHAPeerTestConfig("server2", "")
+	ctx := createHAServiceReviewContext(t, getHAPeerTestConfig("server1", ""), server2)
+	report, err := haPeersConsistency(ctx)
+	require.NoError(t, err)
+	require.Nil(t, report)
+
+	// Different URL of the server2 and different HA mode.
+	config, err := dbmodel.NewKeaConfigFromJSON(`{
+        "Dhcp4": {
+            "hooks-libraries": [{
+                "library": "/usr/lib/kea/libdhcp_ha.so",
+                "parameters": {
+                    "high-availability": [{
+                        "this-server-name": "server2",
+                        "mode": "hot-standby",
+                        "peers": [
+                            {"name": "server1", "url": "http://192.0.2.1:8001", "role": "primary"},
+                            {"name": "server2", "url": "http://192.0.2.22:8001", "role": "standby"}
+                        ]
+                    }]
+                }
+            }]
+        }
+    }`)
+	require.NoError(t, err)
+	ctx.subjectService.Daemons[1].KeaDaemon.Config = config
+
+	report, err = haPeersConsistency(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "inconsistent HA peers configurations")
+	require.Contains(t, *report.content, "the HA mode is configured differently")
+	require.Contains(t, *report.content, "the peer server2 is configured differently")
+	require.NotContains(t, *report.content, "the peer server1")
+}
+
+// Test that the subnets present only in the hub of the hub-and-spoke
+// configuration are not reported as missing in the branch server.
+func TestHASubnetsConsistencyHubAndSpoke(t *testing.T) {
+	hub := `{
+        "Dhcp4": {
+            "subnet4": [
+                {"id": 1, "subnet": "192.0.2.0/24"},
+                {"id": 2, "subnet": "198.51.100.0/24"}
+            ],
+            "hooks-libraries": [{
+                "library": "/usr/lib/kea/libdhcp_ha.so",
+                "parameters": {
+                    "high-availability": [
+                        {
+                            "this-server-name": "server1",
+                            "mode": "hot-standby",
+                            "peers": [
+                                {"name": "server1", "url": "http://192.0.2.1:8001", "role": "standby"},
+                                {"name": "server2", "url": "http://192.0.2.2:8001", "role": "primary"}
+                            ]
+                        },
+                        {
+                            "this-server-name": "server1",
+                            "mode": "hot-standby",
+                            "peers": [
+                                {"name": "server1", "url": "http://192.0.2.1:8002", "role": "standby"},
+                                {"name": "server3", "url": "http://192.0.2.3:8001", "role": "primary"}
+                            ]
+                        }
+                    ]
+                }
+            }]
+        }
+    }`
+	branch := `{
+        "Dhcp4": {
+            "subnet4": [
+                {"id": 1, "subnet": "192.0.2.0/24"},
+                {"id": 3, "subnet": "203.0.113.0/24"}
+            ],
+            "hooks-libraries": [{
+                "library": "/usr/lib/kea/libdhcp_ha.so",
+                "parameters": {
+                    "high-availability": [{
+                        "this-server-name": "server2",
+                        "mode": "hot-standby",
+                        "peers": [
+                            {"name": "server1", "url": "http://192.0.2.1:8001", "role": "standby"},
+                            {"name": "server2", "url": "http://192.0.2.2:8001", "role": "primary"}
+                        ]
+                    }]
+                }
+            }]
+        }
+    }`
+	ctx := createHAServiceReviewContext(t, hub, branch)
+	report, err := haSubnetsConsistency(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "the subnet 203.0.113.0/24 is missing in the dhcp4 server of kea-1")
+	require.NotContains(t, *report.content, "198.51.100.0/24")
+	require.NotContains(t, *report.content, "192.0.2.0/24")
+
+	// The relationship is selected by name. The branch server doesn't
+	// have this relationship configured.
+	ctx.subjectService.HAService.Relationship = "server1,server3"
+	report, err = haPeersConsistency(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "the HA mode is missing in the dhcp4 server of kea-2")
+
+	ctx.subjectService.HAService.Relationship = "server1,server2"
+	report, err = haPeersConsistency(ctx)
+	require.NoError(t, err)
+	require.Nil(t, report)
+}
+
+// Test that the number of the listed differences is limited.
+func TestHAConsistencyReportedDifferencesLimit(t *testing.T) {
+	var classes string
+	for i := 0; i < maxHAReportedDifferences+3; i++ {
+		classes += fmt.Sprintf(`{"name": "class%02d"},`, i)
+	}
+	ctx := createHAServiceReviewContext(t,
+		getHAPeerTestConfig("server1", fmt.Sprintf(`"client-classes": [%s {"name": "last"}],`, classes)),
+		getHAPeerTestConfig("server2", `"client-classes": [{"name": "last"}],`))
+
+	report, err := haClientClassesConsistency(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "the client class class09 is missing")
+	require.NotContains(t, *report.content, "class10")
+	require.Contains(t, *report.content, "; and 3 more.")
+}
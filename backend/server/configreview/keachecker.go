@@ -17,16 +17,18 @@ import (
 func statCmdsPresence(ctx *ReviewContext) (*Report, error) {
 	config := ctx.subjectDaemon.KeaDaemon.Config
 	if _, _, present := config.GetHookLibrary("libdhcp_stat_cmds"); !present {
-		r, err := NewReport(ctx, "The Kea Statistics Commands library "+
+		r := NewReport(ctx, "The Kea Statistics Commands library "+
 			"(libdhcp_stat_cmds) provides commands for retrieving accurate "+
 			"DHCP lease statistics for Kea DHCP servers. Stork sends these "+
 			"commands to fetch lease statistics displayed in the dashboard, "+
 			"subnet, and shared-network views. Stork found that {daemon} is "+
 			"not using this hook library. Some statistics will not be "+
 			"available until the library is loaded.").
-			referencingDaemon(ctx.subjectDaemon).
-			create()
-		return r, err
+			referencingDaemon(ctx.subjectDaemon)
+		if description, patch := getStatCmdsPresenceFix(config); len(patch) > 0 {
+			r = r.withFix(description, patch...)
+		}
+		return r.create()
 	}
 	return nil, nil
 }
@@ -90,15 +92,17 @@ func sharedNetworkDispensable(ctx *ReviewContext) (*Report, error) {
 			details += storkutil.FormatNoun(singleCount, "shared network", "s")
 			details += " with only a single subnet"
 		}
-		r, err := NewReport(ctx, fmt.Sprintf("Kea {daemon} configuration "+
+		r := NewReport(ctx, fmt.Sprintf("Kea {daemon} configuration "+
 			"includes %s. Shared networks create overhead for a Kea server "+
 			"configuration and DHCP message processing, affecting their "+
 			"performance. It is recommended to remove any shared networks "+
 			"having none or a single subnet and specify these subnets at the "+
 			"global configuration level.", details)).
-			referencingDaemon(ctx.subjectDaemon).
-			create()
-		return r, err
+			referencingDaemon(ctx.subjectDaemon)
+		if description, patch := getSharedNetworkDispensableFix(config); len(patch) > 0 {
+			r = r.withFix(description, patch...)
+		}
+		return r.create()
 	}
 	// There are no empty shared networks nor shared networks with
 	// a single subnet.
@@ -685,14 +689,18 @@ func canonicalPrefixes(ctx *ReviewContext) (*Report, error) {
 
 	hintMessage := strings.Join(issues, "; ")
 
-	return NewReport(ctx, fmt.Sprintf("Kea {daemon} configuration "+
+	report := NewReport(ctx, fmt.Sprintf("Kea {daemon} configuration "+
 		"contains%s %s. Kea accepts non-canonical prefix forms, which may "+
 		"lead to duplicates if two subnets have the same prefix specified in "+
 		"different forms. Use canonical forms to ensure that Kea properly "+
 		"identifies and validates subnet prefixes to avoid duplication or "+
 		"overlap.\n%s", maxExceedMessage,
 		storkutil.FormatNoun(int64(len(issues)), "non-canonical prefix", "es"),
-		hintMessage)).referencingDaemon(ctx.subjectDaemon).create()
+		hintMessage)).referencingDaemon(ctx.subjectDaemon)
+	if description, patch := getCanonicalPrefixesFix(config); len(patch) > 0 {
+		report = report.withFix(description, patch...)
+	}
+	return report.create()
 }
 
 // Returns the prefix with zeros on masked bits. If it was already valid,
@@ -780,7 +788,7 @@ func highAvailabilityDedicatedPorts(ctx *ReviewContext) (*Report, error) {
 	if haConfig.GetFirst().MultiThreading.HTTPDedicatedListener == nil ||
 		!*haConfig.GetFirst().MultiThreading.HTTPDedicatedListener {
 		// The dedicated listener is disabled.
-		return NewReport(ctx, "The Kea {daemon} daemon is not configured to "+
+		report := NewReport(ctx, "The Kea {daemon} daemon is not configured to "+
 			"use dedicated HTTP listeners to handle communication between HA "+
 			"peers. They will communicate Kea Control Agent. It may cause "+
 			"the bottlenecks that nullify any performance gains offered by "+
@@ -788,7 +796,11 @@ func highAvailabilityDedicatedPorts(ctx *ReviewContext) (*Report, error) {
 			"multi-threading configuration of the High-Availability hook. "+
 			"Remember that the dedicated listeners must be configured to use "+
 			"the HTTP port different from the one used by the Kea Control "+
-			"Agent.").referencingDaemon(ctx.subjectDaemon).create()
+			"Agent.").referencingDaemon(ctx.subjectDaemon)
+		if description, patch := getHADedicatedListenerFix(ctx.subjectDaemon, haConfig); len(patch) > 0 {
+			report = report.withFix(description, patch...)
+		}
+		return report.create()
 	}
 
 	// The loop checks if the subject daemon connects directly to the
@@ -0,0 +1,296 @@
+package configreview
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	keaconfig "isc.org/stork/appcfg/kea"
+	dbmodel "isc.org/stork/server/database/model"
+	storkutil "isc.org/stork/util"
+)
+
+// This file contains the functions generating the machine-applicable fixes
+// for the issues found by the Kea checkers. Each function returns the fix
+// description and the JSON patch modifying the raw daemon configuration.
+// They return an empty patch when the fix can't be generated, e.g., the
+// configuration lacks the information required to generate it. In this
+// case, the report is created without the fix.
+
+// Returns the name and the contents of the top-level entry of the raw
+// DHCP server configuration, i.e., Dhcp4 or Dhcp6. It returns nil contents
+// when the configuration is not a DHCP server configuration.
+func getRawDHCPConfigRoot(config *dbmodel.KeaConfig) (string, map[string]any) {
+	if config == nil || config.Config == nil {
+		return "", nil
+	}
+	for _, key := range []string{"Dhcp4", "Dhcp6"} {
+		if root, ok := config.Raw[key].(map[string]any); ok {
+			return key, root
+		}
+	}
+	return "", nil
+}
+
+// Returns the name of the subnet list in the DHCP server configuration
+// with the specified top-level entry name.
+func getRawSubnetKey(rootKey string) string {
+	if rootKey == "Dhcp6" {
+		return "subnet6"
+	}
+	return "subnet4"
+}
+
+// Generates the fix loading the libdhcp_stat_cmds hook library. The library
+// is expected to be installed in the same directory as the other hook
+// libraries. The fix can't be generated when the configuration includes no
+// hook libraries because the library location is unknown.
+func getStatCmdsPresenceFix(config *dbmodel.KeaConfig) (string, []storkutil.JSONPatchOperation) {
+	rootKey, root := getRawDHCPConfigRoot(config)
+	libraries, _ := root["hooks-libraries"].([]any)
+	for _, library := range libraries {
+		libraryMap, _ := library.(map[string]any)
+		libraryPath, _ := libraryMap["library"].(string)
+		if libraryPath == "" {
+			continue
+		}
+		statCmdsPath := path.Join(path.Dir(libraryPath), "libdhcp_stat_cmds"+path.Ext(libraryPath))
+		description := fmt.Sprintf("Load the %s hook library. The library "+
+			"location is derived from the location of the %s library.",
+			statCmdsPath, libraryPath)
+		return description, []storkutil.JSONPatchOperation{{
+			Op:    "add",
+			Path:  fmt.Sprintf("/%s/hooks-libraries/-", rootKey),
+			Value: map[string]any{"library": statCmdsPath},
+		}}
+	}
+	return "", nil
+}
+
+// Generates the fix removing the shared networks with no subnets or a single
+// subnet. The subnets of the removed shared networks are moved to the global
+// subnet list. The moved subnets take over the parameters they inherit from
+// their shared networks, so the server allocates the same leases with the
+// same parameters after applying the fix.
+func getSharedNetworkDispensableFix(config *dbmodel.KeaConfig) (string, []storkutil.JSONPatchOperation) {
+	rootKey, root := getRawDHCPConfigRoot(config)
+	networks, _ := root["shared-networks"].([]any)
+	subnetKey := getRawSubnetKey(rootKey)
+
+	var (
+		patch   []storkutil.JSONPatchOperation
+		removed []string
+		moved   []any
+	)
+	for i := range networks {
+		network, ok := networks[i].(map[string]any)
+		if !ok {
+			continue
+		}
+		subnets, _ := network[subnetKey].([]any)
+		if len(subnets) > 1 {
+			continue
+		}
+		if len(subnets) == 1 {
+			subnet, ok := subnets[0].(map[string]any)
+			if !ok {
+				continue
+			}
+			moved = append(moved, inheritSharedNetworkParameters(subnet, network, subnetKey, strings.ToLower(rootKey)))
+		}
+		name, _ := network["name"].(string)
+		removed = append(removed, name)
+		// Remove the shared networks from the last one, so the indexes
+		// of the remaining networks don't change.
+		patch = append([]storkutil.JSONPatchOperation{{
+			Op:   "remove",
+			Path: fmt.Sprintf("/%s/shared-networks/%d", rootKey, i),
+		}}, patch...)
+	}
+	if len(patch) == 0 {
+		return "", nil
+	}
+
+	if len(moved) > 0 {
+		if _, ok := root[subnetKey].([]any); ok {
+			for _, subnet := range moved {
+				patch = append(patch, storkutil.JSONPatchOperation{
+					Op:    "add",
+					Path:  fmt.Sprintf("/%s/%s/-", rootKey, subnetKey),
+					Value: subnet,
+				})
+			}
+		} else {
+			patch = append(patch, storkutil.JSONPatchOperation{
+				Op:    "add",
+				Path:  fmt.Sprintf("/%s/%s", rootKey, subnetKey),
+				Value: moved,
+			})
+		}
+	}
+
+	description := fmt.Sprintf("Remove the shared networks: %s.", strings.Join(removed, ", "))
+	if len(moved) > 0 {
+		description += fmt.Sprintf(" Move %s of these shared networks to "+
+			"the global configuration. The moved subnets take over the "+
+			"parameters inherited from their shared networks.",
+			storkutil.FormatNoun(int64(len(moved)), "subnet", "s"))
+	}
+	return description, patch
+}
+
+// Returns a copy of the subnet including the parameters it inherits from
+// the shared network. The subnet-level parameters take precedence over the
+// shared network parameters. The DHCP options are merged. The shared network
+// name, its subnet list, user context and comment are not inherited.
+func inheritSharedNetworkParameters(subnet, network map[string]any, subnetKey, defaultOptionSpace string) map[string]any {
+	inherited := make(map[string]any, len(subnet))
+	for key, value := range subnet {
+		inherited[key] = value
+	}
+	for key, value := range network {
+		switch key {
+		case "name", subnetKey, "user-context", "comment":
+			continue
+		case "option-data":
+			subnetOptions, _ := subnet[key].([]any)
+			networkOptions, _ := value.([]any)
+			if merged := mergeOptionData(subnetOptions, networkOptions, defaultOptionSpace); len(merged) > 0 {
+				inherited[key] = merged
+			}
+		default:
+			if _, ok := inherited[key]; !ok {
+				inherited[key] = value
+			}
+		}
+	}
+	return inherited
+}
+
+// Appends the DHCP options of the shared network to the subnet options
+// unless the subnet overrides them. The options are identified by the
+// option space and the option code or name.
+func mergeOptionData(subnetOptions, networkOptions []any, defaultOptionSpace string) []any {
+	getOptionKey := func(option any) string {
+		optionMap, _ := option.(map[string]any)
+		space, _ := optionMap["space"].(string)
+		if space == "" {
+			space = defaultOptionSpace
+		}
+		if code, ok := optionMap["code"]; ok {
+			return fmt.Sprintf("%s.%v", space, code)
+		}
+		return fmt.Sprintf("%s.%v", space, optionMap["name"])
+	}
+	merged := append([]any{}, subnetOptions...)
+	present := make(map[string]bool)
+	for _, option := range subnetOptions {
+		present[getOptionKey(option)] = true
+	}
+	for _, option := range networkOptions {
+		if !present[getOptionKey(option)] {
+			merged = append(merged, option)
+		}
+	}
+	return merged
+}
+
+// Generates the fix replacing the non-canonical subnet prefixes with their
+// canonical forms.
+func getCanonicalPrefixesFix(config *dbmodel.KeaConfig) (string, []storkutil.JSONPatchOperation) {
+	rootKey, root := getRawDHCPConfigRoot(config)
+	subnetKey := getRawSubnetKey(rootKey)
+
+	var patch []storkutil.JSONPatchOperation
+	replacePrefixes := func(subnets []any, subnetsPath string) {
+		for i, subnet := range subnets {
+			subnetMap, _ := subnet.(map[string]any)
+			prefix, _ := subnetMap["subnet"].(string)
+			canonical, ok := getCanonicalPrefix(prefix)
+			if ok || canonical == "" {
+				continue
+			}
+			patch = append(patch, storkutil.JSONPatchOperation{
+				Op:    "replace",
+				Path:  fmt.Sprintf("%s/%d/subnet", subnetsPath, i),
+				Value: canonical,
+			})
+		}
+	}
+	subnets, _ := root[subnetKey].([]any)
+	replacePrefixes(subnets, fmt.Sprintf("/%s/%s", rootKey, subnetKey))
+
+	networks, _ := root["shared-networks"].([]any)
+	for i, network := range networks {
+		networkMap, _ := network.(map[string]any)
+		subnets, _ := networkMap[subnetKey].([]any)
+		replacePrefixes(subnets, fmt.Sprintf("/%s/shared-networks/%d/%s", rootKey, i, subnetKey))
+	}
+	if len(patch) == 0 {
+		return "", nil
+	}
+	description := fmt.Sprintf("Replace %s with the canonical forms.",
+		storkutil.FormatNoun(int64(len(patch)), "non-canonical subnet prefix", "es"))
+	return description, patch
+}
+
+// Generates the fix enabling the dedicated HTTP listener in the first HA
+// relationship of the daemon. The listener uses the port from this server's
+// URL. The fix can't be generated when this port is also used by the Kea
+// Control Agent because the dedicated listener would fail to bind to it.
+// In this case, the user must change the port in the configurations of all
+// HA peers. The fix is also not generated if the Kea Control Agent ports
+// are unknown.
+func getHADedicatedListenerFix(daemon *dbmodel.Daemon, haConfig keaconfig.HALibraryParams) (string, []storkutil.JSONPatchOperation) {
+	if daemon.App == nil || len(daemon.App.AccessPoints) == 0 {
+		return "", nil
+	}
+	thisServer := haConfig.GetFirst().GetThisServer()
+	if thisServer == nil || thisServer.URL == nil {
+		return "", nil
+	}
+	thisServerURL, err := url.Parse(*thisServer.URL)
+	if err != nil {
+		return "", nil
+	}
+	port, err := strconv.ParseInt(thisServerURL.Port(), 10, 64)
+	if err != nil {
+		return "", nil
+	}
+	for _, accessPoint := range daemon.App.AccessPoints {
+		if accessPoint.Type == dbmodel.AccessPointControl && accessPoint.Port == port {
+			return "", nil
+		}
+	}
+
+	rootKey, root := getRawDHCPConfigRoot(daemon.KeaDaemon.Config)
+	libraries, _ := root["hooks-libraries"].([]any)
+	for i, library := range libraries {
+		libraryMap, _ := library.(map[string]any)
+		libraryPath, _ := libraryMap["library"].(string)
+		if !strings.Contains(libraryPath, "libdhcp_ha") {
+			continue
+		}
+		parameters, _ := libraryMap["parameters"].(map[string]any)
+		relationships, _ := parameters["high-availability"].([]any)
+		if len(relationships) == 0 {
+			return "", nil
+		}
+		relationship, _ := relationships[0].(map[string]any)
+		if _, ok := relationship["multi-threading"].(map[string]any); !ok {
+			return "", nil
+		}
+		description := fmt.Sprintf("Enable the dedicated HTTP listener in "+
+			"the High Availability hook configuration. The listener accepts "+
+			"the connections from the HA peers at %s instead of the Kea "+
+			"Control Agent.", *thisServer.URL)
+		return description, []storkutil.JSONPatchOperation{{
+			Op:    "add",
+			Path:  fmt.Sprintf("/%s/hooks-libraries/%d/parameters/high-availability/0/multi-threading/http-dedicated-listener", rootKey, i),
+			Value: true,
+		}}
+	}
+	return "", nil
+}
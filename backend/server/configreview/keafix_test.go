@@ -0,0 +1,319 @@
+package configreview
+
+import (
+	"encoding/json"
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+	storkutil "isc.org/stork/util"
+)
+
+// Applies the patch to the configuration and returns the JSON form of the
+// fixed configuration.
+func applyTestFix(t *testing.T, config *dbmodel.KeaConfig, patch []storkutil.JSONPatchOperation) string {
+	fix := &dbmodel.ConfigReportFix{Patch: patch}
+	fixed, err := fix.Apply(config)
+	require.NoError(t, err)
+	marshalled, err := json.Marshal(fixed)
+	require.NoError(t, err)
+	return string(marshalled)
+}
+
+// Test that the fix loading the stat_cmds hook library is generated using
+// the location of the other hook libraries.
+func TestStatCmdsPresenceFix(t *testing.T) {
+	config, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp6": {
+			"hooks-libraries": [
+				{ "library": "/usr/lib/kea/hooks/libdhcp_lease_cmds.so" }
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	description, patch := getStatCmdsPresenceFix(config)
+	require.Contains(t, description, "/usr/lib/kea/hooks/libdhcp_stat_cmds.so")
+	require.JSONEq(t, `{
+		"Dhcp6": {
+			"hooks-libraries": [
+				{ "library": "/usr/lib/kea/hooks/libdhcp_lease_cmds.so" },
+				{ "library": "/usr/lib/kea/hooks/libdhcp_stat_cmds.so" }
+			]
+		}
+	}`, applyTestFix(t, config, patch))
+}
+
+// Test that the fix loading the stat_cmds hook library is not generated
+// when the hook libraries location is unknown.
+func TestStatCmdsPresenceFixNoHookLibraries(t *testing.T) {
+	config, err := dbmodel.NewKeaConfigFromJSON(`{ "Dhcp4": { } }`)
+	require.NoError(t, err)
+
+	_, patch := getStatCmdsPresenceFix(config)
+	require.Empty(t, patch)
+}
+
+// Test that the checker attaches the fix to the stat_cmds report.
+func TestStatCmdsAbsentWithFix(t *testing.T) {
+	report, err := statCmdsPresence(createReviewContext(t, nil, `{
+		"Dhcp4": {
+			"hooks-libraries": [
+				{ "library": "/usr/lib/kea/hooks/libdhcp_lease_cmds.so" }
+			]
+		}
+	}`))
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.NotNil(t, report.fix)
+	require.Len(t, report.fix.Patch, 1)
+}
+
+// Test that the fix removing the dispensable shared networks moves their
+// subnets to the global configuration with the inherited parameters.
+func TestSharedNetworkDispensableFix(t *testing.T) {
+	config, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp4": {
+			"subnet4": [
+				{ "id": 1, "subnet": "192.0.2.0/24" }
+			],
+			"shared-networks": [
+				{
+					"name": "empty"
+				},
+				{
+					"name": "multiple",
+					"subnet4": [
+						{ "id": 2, "subnet": "192.0.3.0/24" },
+						{ "id": 3, "subnet": "192.0.4.0/24" }
+					]
+				},
+				{
+					"name": "single",
+					"interface": "eth0",
+					"valid-lifetime": 7200,
+					"user-context": { "site": "hq" },
+					"option-data": [
+						{ "name": "routers", "data": "192.0.5.1" },
+						{ "code": 6, "data": "192.0.5.2" }
+					],
+					"subnet4": [
+						{
+							"id": 4,
+							"subnet": "192.0.5.0/24",
+							"valid-lifetime": 3600,
+							"option-data": [
+								{ "code": 6, "space": "dhcp4", "data": "192.0.5.3" }
+							]
+						}
+					]
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	description, patch := getSharedNetworkDispensableFix(config)
+	require.Equal(t, "Remove the shared networks: empty, single. Move 1 subnet "+
+		"of these shared networks to the global configuration. The moved "+
+		"subnets take over the parameters inherited from their shared networks.",
+		description)
+	require.JSONEq(t, `{
+		"Dhcp4": {
+			"subnet4": [
+				{ "id": 1, "subnet": "192.0.2.0/24" },
+				{
+					"id": 4,
+					"subnet": "192.0.5.0/24",
+					"interface": "eth0",
+					"valid-lifetime": 3600,
+					"option-data": [
+						{ "code": 6, "space": "dhcp4", "data": "192.0.5.3" },
+						{ "name": "routers", "data": "192.0.5.1" }
+					]
+				}
+			],
+			"shared-networks": [
+				{
+					"name": "multiple",
+					"subnet4": [
+						{ "id": 2, "subnet": "192.0.3.0/24" },
+						{ "id": 3, "subnet": "192.0.4.0/24" }
+					]
+				}
+			]
+		}
+	}`, applyTestFix(t, config, patch))
+}
+
+// Test that the fix moving the subnets of the dispensable shared networks
+// creates the global subnet list if it doesn't exist.
+func TestSharedNetworkDispensableFixNoGlobalSubnets(t *testing.T) {
+	config, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp6": {
+			"shared-networks": [
+				{
+					"name": "foo",
+					"subnet6": [ { "id": 1, "subnet": "2001:db8:1::/64" } ]
+				},
+				{
+					"name": "bar",
+					"subnet6": [ { "id": 2, "subnet": "2001:db8:2::/64" } ]
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	_, patch := getSharedNetworkDispensableFix(config)
+	require.JSONEq(t, `{
+		"Dhcp6": {
+			"shared-networks": [],
+			"subnet6": [
+				{ "id": 1, "subnet": "2001:db8:1::/64" },
+				{ "id": 2, "subnet": "2001:db8:2::/64" }
+			]
+		}
+	}`, applyTestFix(t, config, patch))
+}
+
+// Test that the checker attaches the fix to the dispensable shared network
+// report.
+func TestSharedNetworkDispensableWithFix(t *testing.T) {
+	report, err := sharedNetworkDispensable(createReviewContext(t, nil, `{
+		"Dhcp4": {
+			"shared-networks": [ { "name": "foo" } ]
+		}
+	}`))
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.NotNil(t, report.fix)
+	require.Len(t, report.fix.Patch, 1)
+}
+
+// Test that the fix replacing the non-canonical prefixes covers the global
+// subnets and the subnets in the shared networks.
+func TestCanonicalPrefixesFix(t *testing.T) {
+	config, err := dbmodel.NewKeaConfigFromJSON(`{
+		"Dhcp4": {
+			"subnet4": [
+				{ "id": 1, "subnet": "192.0.2.1/24" },
+				{ "id": 2, "subnet": "192.0.3.0/24" }
+			],
+			"shared-networks": [
+				{
+					"name": "foo",
+					"subnet4": [
+						{ "id": 3, "subnet": "192.0.4.0/24" },
+						{ "id": 4, "subnet": "192.0.5.128/16" }
+					]
+				}
+			]
+		}
+	}`)
+	require.NoError(t, err)
+
+	description, patch := getCanonicalPrefixesFix(config)
+	require.Equal(t, "Replace 2 non-canonical subnet prefixes with the canonical forms.", description)
+	require.JSONEq(t, `{
+		"Dhcp4": {
+			"subnet4": [
+				{ "id": 1, "subnet": "192.0.2.0/24" },
+				{ "id": 2, "subnet": "192.0.3.0/24" }
+			],
+			"shared-networks": [
+				{
+					"name": "foo",
+					"subnet4": [
+						{ "id": 3, "subnet": "192.0.4.0/24" },
+						{ "id": 4, "subnet": "192.0.0.0/16" }
+					]
+				}
+			]
+		}
+	}`, applyTestFix(t, config, patch))
+}
+
+// Returns the configuration with the HA hook using the multi-threading
+// without the dedicated listener.
+func getTestHADedicatedListenerConfig() string {
+	return `{
+		"Dhcp4": {
+			"multi-threading": { "enable-multi-threading": true },
+			"hooks-libraries": [
+				{ "library": "/usr/lib/kea/libdhcp_lease_cmds.so" },
+				{
+					"library": "/usr/lib/kea/libdhcp_ha.so",
+					"parameters": {
+						"high-availability": [{
+							"this-server-name": "server1",
+							"mode": "hot-standby",
+							"multi-threading": {
+								"enable-multi-threading": true,
+								"http-dedicated-listener": false
+							},
+							"peers": [
+								{ "name": "server1", "url": "http://192.0.2.1:8001", "role": "primary" },
+								{ "name": "server2", "url": "http://192.0.2.2:8001", "role": "standby" }
+							]
+						}]
+					}
+				}
+			]
+		}
+	}`
+}
+
+// Test that the fix enabling the HA dedicated listener is generated when
+// this server's URL doesn't collide with the Kea Control Agent.
+func TestHADedicatedListenerFix(t *testing.T) {
+	ctx := createReviewContext(t, nil, getTestHADedicatedListenerConfig())
+	ctx.subjectDaemon.App = &dbmodel.App{
+		AccessPoints: []*dbmodel.AccessPoint{
+			{Type: dbmodel.AccessPointControl, Port: 8000},
+		},
+	}
+
+	report, err := highAvailabilityDedicatedPorts(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.NotNil(t, report.fix)
+	require.Contains(t, report.fix.Description, "http://192.0.2.1:8001")
+
+	fixed, err := report.fix.Apply(ctx.subjectDaemon.KeaDaemon.Config)
+	require.NoError(t, err)
+	_, params, ok := fixed.GetHookLibraries().GetHAHookLibrary()
+	require.True(t, ok)
+	require.NotNil(t, params.GetFirst().MultiThreading.HTTPDedicatedListener)
+	require.True(t, *params.GetFirst().MultiThreading.HTTPDedicatedListener)
+}
+
+// Test that the fix enabling the HA dedicated listener is not generated
+// when this server's URL uses the Kea Control Agent port or the agent's
+// ports are unknown.
+func TestHADedicatedListenerFixPortCollision(t *testing.T) {
+	config, err := dbmodel.NewKeaConfigFromJSON(getTestHADedicatedListenerConfig())
+	require.NoError(t, err)
+	_, haConfig, ok := config.GetHookLibraries().GetHAHookLibrary()
+	require.True(t, ok)
+
+	daemon := &dbmodel.Daemon{
+		KeaDaemon: &dbmodel.KeaDaemon{Config: config},
+	}
+	_, patch := getHADedicatedListenerFix(daemon, haConfig)
+	require.Empty(t, patch)
+
+	daemon.App = &dbmodel.App{
+		AccessPoints: []*dbmodel.AccessPoint{
+			{Type: dbmodel.AccessPointControl, Port: 8001},
+		},
+	}
+	_, patch = getHADedicatedListenerFix(daemon, haConfig)
+	require.Empty(t, patch)
+
+	// This server is not among the peers.
+	daemon.App.AccessPoints[0].Port = 8000
+	haConfig.HA[0].ThisServerName = storkutil.Ptr("server3")
+	_, patch = getHADedicatedListenerFix(daemon, haConfig)
+	require.Empty(t, patch)
+}
@@ -5,6 +5,7 @@ import (
 
 	pkgerrors "github.com/pkg/errors"
 	dbmodel "isc.org/stork/server/database/model"
+	storkutil "isc.org/stork/util"
 )
 
 // Represents a single config review report. It may contain a description
@@ -13,11 +14,14 @@ import (
 // The refDaemonIDs slice contain IDs of the daemons referenced in the
 // review. Each daemon can be referenced at most once. The presence of
 // the referenced daemons may trigger cascaded/internal reviews. See
-// the dispatcher documentation.
+// the dispatcher documentation. The optional fix is a patch of the subject
+// daemon's configuration resolving the issue. The user can apply it with
+// the config manager.
 type Report struct {
 	content      *string
 	daemonID     int64
 	refDaemonIDs []int64
+	fix          *dbmodel.ConfigReportFix
 }
 
 // Indicates that the report contains a found issue.
@@ -79,6 +83,18 @@ func (r *IntermediateReport) referencingDaemon(daemon *dbmodel.Daemon) *Intermed
 	return r
 }
 
+// Attaches a machine-applicable fix to the report. The fix is a patch of
+// the subject daemon's configuration described in a human-readable form.
+// The patch paths are the JSON pointers into the entire daemon configuration,
+// e.g., /Dhcp4/subnet4/0/subnet.
+func (r *IntermediateReport) withFix(description string, patch ...storkutil.JSONPatchOperation) *IntermediateReport {
+	r.fix = &dbmodel.ConfigReportFix{
+		Description: description,
+		Patch:       patch,
+	}
+	return r
+}
+
 // Validates the report contents and return an instance of the final
 // report or an error. It should never report an error if the checkers
 // generating the reports are implemented properly.
@@ -105,11 +121,17 @@ func (r *IntermediateReport) create() (*Report, error) {
 		}
 		presentDaemons[id] = true
 	}
+	// Ensure that the fix, if specified, is described and modifies the
+	// configuration.
+	if r.fix != nil && (len(r.fix.Description) == 0 || len(r.fix.Patch) == 0) {
+		return nil, pkgerrors.New("config review report fix must have a description and a patch")
+	}
 	// Everything is fine.
 	rc := &Report{
 		content:      r.content,
 		daemonID:     r.daemonID,
 		refDaemonIDs: r.refDaemonIDs,
+		fix:          r.fix,
 	}
 	return rc, nil
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 	dbmodel "isc.org/stork/server/database/model"
+	storkutil "isc.org/stork/util"
 )
 
 // Test creating a valid report.
@@ -29,6 +30,36 @@ func TestCreateReport(t *testing.T) {
 	require.EqualValues(t, 123, report.refDaemonIDs[1])
 }
 
+// Test creating a report with a fix.
+func TestCreateReportWithFix(t *testing.T) {
+	ctx := newReviewContext(nil, &dbmodel.Daemon{
+		ID: 123,
+	}, Triggers{ConfigModified}, nil)
+	report, err := NewReport(ctx, "new report for {daemon}").
+		referencingDaemon(ctx.subjectDaemon).
+		withFix("Set the valid lifetime.", storkutil.JSONPatchOperation{
+			Op:    "add",
+			Path:  "/Dhcp4/valid-lifetime",
+			Value: 3600,
+		}).
+		create()
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.NotNil(t, report.fix)
+	require.Equal(t, "Set the valid lifetime.", report.fix.Description)
+	require.Len(t, report.fix.Patch, 1)
+
+	// The fix must have a description and a patch.
+	_, err = NewReport(ctx, "new report for {daemon}").
+		withFix("", storkutil.JSONPatchOperation{Op: "remove", Path: "/Dhcp4/valid-lifetime"}).
+		create()
+	require.Error(t, err)
+	_, err = NewReport(ctx, "new report for {daemon}").
+		withFix("Set the valid lifetime.").
+		create()
+	require.Error(t, err)
+}
+
 // Test that an attempt to create a report with a blank content is
 // not possible.
 func TestCreateBlankReport(t *testing.T) {
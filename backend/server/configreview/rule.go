@@ -0,0 +1,384 @@
+package configreview
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Maximum number of the configuration items violating the user-defined
+// rule listed in the report.
+const maxRuleReportedViolations = 10
+
+// Scope of the user-defined config review rule. It designates the
+// configuration items the rule expression is evaluated for.
+type RuleScope string
+
+// Supported scopes of the user-defined config review rules.
+const (
+	// The expression is evaluated once for the daemon configuration.
+	RuleScopeGlobal RuleScope = "global"
+	// The expression is evaluated for each shared network.
+	RuleScopeSharedNetwork RuleScope = "shared-network"
+	// The expression is evaluated for each subnet, including the subnets
+	// belonging to the shared networks.
+	RuleScopeSubnet RuleScope = "subnet"
+	// The expression is evaluated for each address pool.
+	RuleScopePool RuleScope = "pool"
+	// The expression is evaluated for each prefix delegation pool.
+	RuleScopePDPool RuleScope = "pd-pool"
+	// The expression is evaluated for each host reservation specified
+	// in the configuration file, both global and in the subnets.
+	RuleScopeReservation RuleScope = "reservation"
+)
+
+// Names of the variables available in the rule expressions by scope.
+var ruleScopeVariables = map[RuleScope][]string{
+	RuleScopeGlobal:        {"config"},
+	RuleScopeSharedNetwork: {"config", "network"},
+	RuleScopeSubnet:        {"config", "network", "subnet"},
+	RuleScopePool:          {"config", "network", "subnet", "pool"},
+	RuleScopePDPool:        {"config", "network", "subnet", "pool"},
+	RuleScopeReservation:   {"config", "network", "subnet", "reservation"},
+}
+
+// Dispatch group selectors the user-defined rules can be registered for.
+var ruleSelectors = map[string]DispatchGroupSelector{
+	KeaDaemon.String():       KeaDaemon,
+	KeaCADaemon.String():     KeaCADaemon,
+	KeaDHCPDaemon.String():   KeaDHCPDaemon,
+	KeaDHCPv4Daemon.String(): KeaDHCPv4Daemon,
+	KeaDHCPv6Daemon.String(): KeaDHCPv6Daemon,
+	KeaD2Daemon.String():     KeaD2Daemon,
+}
+
+// Pattern of the user-defined rule names. They are used as the checker
+// names, so they follow the naming convention of the built-in checkers.
+var ruleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Top-level keys of the Kea configurations holding the daemon-specific
+// configuration.
+var ruleConfigRootKeys = []string{"Dhcp4", "Dhcp6", "Control-agent", "DhcpDdns"}
+
+// An error returned when the user-defined config review rule is invalid,
+// e.g., its expression can't be parsed.
+type InvalidRuleError struct {
+	reason string
+}
+
+// Create new instance of the InvalidRuleError.
+func NewInvalidRuleError(format string, args ...any) error {
+	return &InvalidRuleError{
+		reason: fmt.Sprintf(format, args...),
+	}
+}
+
+// Returns error string.
+func (e InvalidRuleError) Error() string {
+	return e.reason
+}
+
+// User-defined config review rule with the compiled expression.
+type compiledRule struct {
+	name        string
+	description string
+	selector    DispatchGroupSelector
+	scope       RuleScope
+	expression  *ruleExpression
+}
+
+// Validates the user-defined config review rule and compiles its
+// expression. It returns InvalidRuleError if the rule is invalid.
+func compileRule(rule *dbmodel.ConfigReviewRule) (*compiledRule, error) {
+	if !ruleNamePattern.MatchString(rule.Name) {
+		return nil, NewInvalidRuleError("rule name %s must start with a lowercase letter and contain only lowercase letters, digits and underscores", rule.Name)
+	}
+	selector, ok := ruleSelectors[rule.Selector]
+	if !ok {
+		return nil, NewInvalidRuleError("unsupported selector %s of the rule %s", rule.Selector, rule.Name)
+	}
+	scope := RuleScope(rule.Scope)
+	variables, ok := ruleScopeVariables[scope]
+	if !ok {
+		return nil, NewInvalidRuleError("unsupported scope %s of the rule %s", rule.Scope, rule.Name)
+	}
+	if scope != RuleScopeGlobal && selector != KeaDHCPDaemon && selector != KeaDHCPv4Daemon && selector != KeaDHCPv6Daemon {
+		return nil, NewInvalidRuleError("scope %s of the rule %s requires a DHCP daemon selector", rule.Scope, rule.Name)
+	}
+	expression, err := compileRuleExpression(rule.Expression, variables)
+	if err != nil {
+		return nil, NewInvalidRuleError("invalid expression of the rule %s: %s", rule.Name, err)
+	}
+	return &compiledRule{
+		name:        rule.Name,
+		description: rule.Description,
+		selector:    selector,
+		scope:       scope,
+		expression:  expression,
+	}, nil
+}
+
+// Validates the user-defined config review rule. It returns
+// InvalidRuleError if the rule name, selector or scope is not supported,
+// or the expression can't be parsed.
+func ValidateConfigReviewRule(rule *dbmodel.ConfigReviewRule) error {
+	_, err := compileRule(rule)
+	return err
+}
+
+// Registers the user-defined config review rule as a checker in the
+// dispatcher. The checker is launched by the default triggers. It returns
+// InvalidRuleError if the rule is invalid or its name is already used by
+// another checker.
+func RegisterConfigReviewRule(dispatcher Dispatcher, rule *dbmodel.ConfigReviewRule) error {
+	compiled, err := compileRule(rule)
+	if err != nil {
+		return err
+	}
+	metadata, err := dispatcher.GetCheckersMetadata(nil)
+	if err != nil {
+		return err
+	}
+	for _, m := range metadata {
+		if m.Name == rule.Name {
+			return NewInvalidRuleError("checker with the name %s already exists", rule.Name)
+		}
+	}
+	dispatcher.RegisterChecker(compiled.selector, compiled.name, GetDefaultTriggers(), compiled.check)
+	return nil
+}
+
+// Unregisters the checker of the user-defined config review rule from the
+// dispatcher. It returns a boolean value indicating if the checker was
+// found and removed.
+func UnregisterConfigReviewRule(dispatcher Dispatcher, rule *dbmodel.ConfigReviewRule) bool {
+	selector, ok := ruleSelectors[rule.Selector]
+	if !ok {
+		return false
+	}
+	return dispatcher.UnregisterChecker(selector, rule.Name)
+}
+
+// Fetches the user-defined config review rules from the database and
+// registers them in the dispatcher. The invalid rules are logged and
+// skipped. Returns an error if any database connection problem occurs.
+// It should be called before loading the checker preferences, so the
+// preferences of the rules are not discarded.
+func LoadConfigReviewRules(db dbops.DBI, dispatcher Dispatcher) error {
+	rules, err := dbmodel.GetConfigReviewRules(db)
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		if err := RegisterConfigReviewRule(dispatcher, &rules[i]); err != nil {
+			log.WithError(err).WithField("rule", rules[i].Name).
+				Error("Cannot register the user-defined config review rule")
+		}
+	}
+	return nil
+}
+
+// Configuration item the rule expression is evaluated for. The label
+// describes the item in the report.
+type ruleItem struct {
+	label     string
+	variables map[string]any
+}
+
+// Returns the value of the map as a map or nil.
+func getRuleMap(value any) map[string]any {
+	m, _ := value.(map[string]any)
+	return m
+}
+
+// Returns the value of the map as a list of maps. The elements that are
+// not maps are skipped.
+func getRuleMapList(value any) (list []map[string]any) {
+	elements, _ := value.([]any)
+	for _, element := range elements {
+		if m := getRuleMap(element); m != nil {
+			list = append(list, m)
+		}
+	}
+	return
+}
+
+// Returns the subnets of the shared network or the top-level subnets.
+func getRuleSubnets(parent map[string]any) []map[string]any {
+	return append(getRuleMapList(parent["subnet4"]), getRuleMapList(parent["subnet6"])...)
+}
+
+// Returns a description of the subnet.
+func getRuleSubnetLabel(subnet map[string]any) string {
+	if id, ok := subnet["id"]; ok {
+		return fmt.Sprintf("subnet %v (ID %v)", subnet["subnet"], id)
+	}
+	return fmt.Sprintf("subnet %v", subnet["subnet"])
+}
+
+// Returns a description of the host reservation.
+func getRuleReservationLabel(reservation map[string]any, subnet map[string]any) string {
+	identifier := "unknown identifier"
+	for _, name := range []string{"hw-address", "duid", "client-id", "circuit-id", "flex-id"} {
+		if value, ok := reservation[name]; ok {
+			identifier = fmt.Sprintf("%s %v", name, value)
+			break
+		}
+	}
+	if subnet == nil {
+		return fmt.Sprintf("global reservation for %s", identifier)
+	}
+	return fmt.Sprintf("reservation for %s in %s", identifier, getRuleSubnetLabel(subnet))
+}
+
+// Returns the configuration items of the daemon configuration the rule
+// expression is evaluated for in the specified scope.
+func getRuleItems(config map[string]any, scope RuleScope) (items []ruleItem) {
+	newItem := func(label string, network, subnet map[string]any) ruleItem {
+		variables := map[string]any{
+			"config": config,
+		}
+		if scope != RuleScopeGlobal {
+			// Avoid storing nil maps in the interface values, so the
+			// variables compare equal to null.
+			variables["network"] = nil
+			if network != nil {
+				variables["network"] = network
+			}
+		}
+		if scope != RuleScopeGlobal && scope != RuleScopeSharedNetwork {
+			variables["subnet"] = nil
+			if subnet != nil {
+				variables["subnet"] = subnet
+			}
+		}
+		return ruleItem{label: label, variables: variables}
+	}
+
+	if scope == RuleScopeGlobal {
+		return []ruleItem{newItem("the global configuration", nil, nil)}
+	}
+
+	if scope == RuleScopeReservation {
+		for _, reservation := range getRuleMapList(config["reservations"]) {
+			item := newItem(getRuleReservationLabel(reservation, nil), nil, nil)
+			item.variables["reservation"] = reservation
+			items = append(items, item)
+		}
+	}
+
+	// Collect the subnets along with their shared networks.
+	type networkSubnet struct {
+		network map[string]any
+		subnet  map[string]any
+	}
+	var subnets []networkSubnet
+	for _, subnet := range getRuleSubnets(config) {
+		subnets = append(subnets, networkSubnet{nil, subnet})
+	}
+	for _, network := range getRuleMapList(config["shared-networks"]) {
+		if scope == RuleScopeSharedNetwork {
+			items = append(items, newItem(fmt.Sprintf("shared network %v", network["name"]), network, nil))
+			continue
+		}
+		for _, subnet := range getRuleSubnets(network) {
+			subnets = append(subnets, networkSubnet{network, subnet})
+		}
+	}
+
+	for _, s := range subnets {
+		switch scope {
+		case RuleScopeSubnet:
+			items = append(items, newItem(getRuleSubnetLabel(s.subnet), s.network, s.subnet))
+		case RuleScopePool:
+			for _, pool := range getRuleMapList(s.subnet["pools"]) {
+				item := newItem(fmt.Sprintf("pool %v in %s", pool["pool"], getRuleSubnetLabel(s.subnet)), s.network, s.subnet)
+				item.variables["pool"] = pool
+				items = append(items, item)
+			}
+		case RuleScopePDPool:
+			for _, pool := range getRuleMapList(s.subnet["pd-pools"]) {
+				item := newItem(fmt.Sprintf("prefix delegation pool %v/%v in %s", pool["prefix"], pool["prefix-len"], getRuleSubnetLabel(s.subnet)), s.network, s.subnet)
+				item.variables["pool"] = pool
+				items = append(items, item)
+			}
+		case RuleScopeReservation:
+			for _, reservation := range getRuleMapList(s.subnet["reservations"]) {
+				item := newItem(getRuleReservationLabel(reservation, s.subnet), s.network, s.subnet)
+				item.variables["reservation"] = reservation
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// Returns the daemon-specific part of the raw Kea configuration.
+func getRuleConfigRoot(config *dbmodel.KeaConfig) map[string]any {
+	if config == nil || config.Config == nil {
+		return nil
+	}
+	for _, key := range ruleConfigRootKeys {
+		if root := getRuleMap(config.Raw[key]); root != nil {
+			return root
+		}
+	}
+	return nil
+}
+
+// The checker function of the user-defined rule. It evaluates the rule
+// expression for each configuration item in the rule scope and reports
+// the items for which the expression is not satisfied. If the expression
+// can't be evaluated, e.g., it compares the values of different types,
+// the checker generates a report describing the problem, so the user can
+// fix the rule.
+func (rule *compiledRule) check(ctx *ReviewContext) (*Report, error) {
+	if ctx.subjectDaemon.KeaDaemon == nil {
+		return nil, nil
+	}
+	config := getRuleConfigRoot(ctx.subjectDaemon.KeaDaemon.Config)
+	if config == nil {
+		return nil, nil
+	}
+
+	var violations []string
+	for _, item := range getRuleItems(config, rule.scope) {
+		satisfied, err := rule.expression.evaluate(item.variables)
+		if err != nil {
+			r, err := NewReport(ctx, fmt.Sprintf("The user-defined config review rule %s "+
+				"could not be evaluated for %s of {daemon}: %s. Please correct the rule "+
+				"expression.", rule.name, item.label, err)).
+				referencingDaemon(ctx.subjectDaemon).
+				create()
+			return r, err
+		}
+		if !satisfied {
+			violations = append(violations, item.label)
+		}
+	}
+	if len(violations) == 0 {
+		return nil, nil
+	}
+
+	description := ""
+	if len(rule.description) > 0 {
+		description = fmt.Sprintf(" (%s)", strings.TrimSuffix(rule.description, "."))
+	}
+	content := fmt.Sprintf("The configuration of {daemon} violates the user-defined "+
+		"config review rule %s%s.", rule.name, description)
+	if rule.scope != RuleScopeGlobal {
+		if len(violations) > maxRuleReportedViolations {
+			violations = append(violations[:maxRuleReportedViolations],
+				fmt.Sprintf("%d more", len(violations)-maxRuleReportedViolations))
+		}
+		content += fmt.Sprintf(" The rule is not satisfied for the %s.", joinEnumeration(violations))
+	}
+	r, err := NewReport(ctx, content).
+		referencingDaemon(ctx.subjectDaemon).
+		create()
+	return r, err
+}
@@ -0,0 +1,266 @@
+package configreview
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Returns the DHCPv4 configuration used in the user-defined rule tests.
+func getTestRuleConfig() string {
+	return `{
+		"Dhcp4": {
+			"valid-lifetime": 3600,
+			"reservations": [
+				{ "hw-address": "01:02:03:04:05:06", "ip-address": "192.0.2.5" }
+			],
+			"subnet4": [
+				{
+					"id": 1,
+					"subnet": "192.0.2.0/24",
+					"pools": [ { "pool": "192.0.2.10 - 192.0.2.20" } ],
+					"option-data": [ { "name": "routers", "data": "192.0.2.1" } ],
+					"reservations": [
+						{ "client-id": "01:01", "ip-address": "192.0.2.6" }
+					]
+				},
+				{
+					"id": 2,
+					"subnet": "10.0.0.0/8",
+					"pools": [ { "pool": "10.0.0.0/16" } ]
+				}
+			],
+			"shared-networks": [
+				{
+					"name": "frog",
+					"subnet4": [
+						{
+							"id": 3,
+							"subnet": "198.51.100.0/24",
+							"valid-lifetime": 7200
+						}
+					]
+				}
+			]
+		}
+	}`
+}
+
+// Returns a valid user-defined rule used in the tests.
+func getTestRule() *dbmodel.ConfigReviewRule {
+	return &dbmodel.ConfigReviewRule{
+		Name:        "subnet_routers",
+		Description: "every subnet must have option routers",
+		Selector:    "kea-dhcp-daemon",
+		Scope:       "subnet",
+		Expression:  "any(subnet['option-data'], item.name == 'routers')",
+	}
+}
+
+// Test that the user-defined rules are validated.
+func TestValidateConfigReviewRule(t *testing.T) {
+	require.NoError(t, ValidateConfigReviewRule(getTestRule()))
+
+	// Invalid name.
+	rule := getTestRule()
+	rule.Name = "Subnet-routers"
+	require.ErrorContains(t, ValidateConfigReviewRule(rule), "rule name")
+
+	// Unsupported selector.
+	rule = getTestRule()
+	rule.Selector = "bind9-daemon"
+	require.ErrorContains(t, ValidateConfigReviewRule(rule), "unsupported selector")
+
+	// Unsupported scope.
+	rule = getTestRule()
+	rule.Scope = "option"
+	require.ErrorContains(t, ValidateConfigReviewRule(rule), "unsupported scope")
+
+	// Subnet scope requires a DHCP daemon.
+	rule = getTestRule()
+	rule.Selector = "kea-ca-daemon"
+	require.ErrorContains(t, ValidateConfigReviewRule(rule), "requires a DHCP daemon selector")
+
+	// The pool variable is not available in the subnet scope.
+	rule = getTestRule()
+	rule.Expression = "exists(pool)"
+	err := ValidateConfigReviewRule(rule)
+	require.ErrorContains(t, err, "unknown variable pool")
+
+	var invalid *InvalidRuleError
+	require.ErrorAs(t, err, &invalid)
+}
+
+// Test registering and unregistering the user-defined rules.
+func TestRegisterConfigReviewRule(t *testing.T) {
+	dispatcher := NewDispatcher(nil)
+	RegisterDefaultCheckers(dispatcher)
+
+	rule := getTestRule()
+	require.NoError(t, RegisterConfigReviewRule(dispatcher, rule))
+
+	metadata, err := dispatcher.GetCheckersMetadata(nil)
+	require.NoError(t, err)
+	var found *CheckerMetadata
+	for _, m := range metadata {
+		if m.Name == rule.Name {
+			found = m
+		}
+	}
+	require.NotNil(t, found)
+	require.Equal(t, DispatchGroupSelectors{KeaDHCPDaemon}, found.Selectors)
+	require.Equal(t, GetDefaultTriggers(), found.Triggers)
+
+	// The rule name must not collide with another checker.
+	require.Error(t, RegisterConfigReviewRule(dispatcher, rule))
+	rule.Name = "stat_cmds_presence"
+	require.Error(t, RegisterConfigReviewRule(dispatcher, rule))
+
+	// Invalid rule.
+	rule = getTestRule()
+	rule.Name = "other"
+	rule.Expression = "exists("
+	require.Error(t, RegisterConfigReviewRule(dispatcher, rule))
+
+	require.True(t, UnregisterConfigReviewRule(dispatcher, getTestRule()))
+	require.False(t, UnregisterConfigReviewRule(dispatcher, getTestRule()))
+}
+
+// Test that the configuration items are collected for each scope.
+func TestGetRuleItems(t *testing.T) {
+	config, err := dbmodel.NewKeaConfigFromJSON(getTestRuleConfig())
+	require.NoError(t, err)
+	root := getRuleConfigRoot(config)
+	require.NotNil(t, root)
+
+	getLabels := func(scope RuleScope) (labels []string) {
+		for _, item := range getRuleItems(root, scope) {
+			labels = append(labels, item.label)
+		}
+		return
+	}
+
+	require.Equal(t, []string{"the global configuration"}, getLabels(RuleScopeGlobal))
+	require.Equal(t, []string{"shared network frog"}, getLabels(RuleScopeSharedNetwork))
+	require.Equal(t, []string{
+		"subnet 192.0.2.0/24 (ID 1)",
+		"subnet 10.0.0.0/8 (ID 2)",
+		"subnet 198.51.100.0/24 (ID 3)",
+	}, getLabels(RuleScopeSubnet))
+	require.Equal(t, []string{
+		"pool 192.0.2.10 - 192.0.2.20 in subnet 192.0.2.0/24 (ID 1)",
+		"pool 10.0.0.0/16 in subnet 10.0.0.0/8 (ID 2)",
+	}, getLabels(RuleScopePool))
+	require.Empty(t, getLabels(RuleScopePDPool))
+	require.Equal(t, []string{
+		"global reservation for hw-address 01:02:03:04:05:06",
+		"reservation for client-id 01:01 in subnet 192.0.2.0/24 (ID 1)",
+	}, getLabels(RuleScopeReservation))
+
+	// The subnet in the shared network has the network variable set.
+	items := getRuleItems(root, RuleScopeSubnet)
+	require.Nil(t, items[0].variables["network"])
+	require.NotNil(t, items[2].variables["network"])
+}
+
+// Test that the user-defined rule checker reports the configuration items
+// violating the rule.
+func TestConfigReviewRuleChecker(t *testing.T) {
+	ctx := createReviewContext(t, nil, getTestRuleConfig())
+
+	testCases := []struct {
+		scope      string
+		expression string
+		expected   string
+	}{
+		{
+			"subnet",
+			"any(subnet['option-data'], item.name == 'routers')",
+			"The rule is not satisfied for the subnet 10.0.0.0/8 (ID 2) and subnet 198.51.100.0/24 (ID 3).",
+		},
+		{
+			"subnet",
+			"(subnet['valid-lifetime'] == null && config['valid-lifetime'] <= 3600) || subnet['valid-lifetime'] <= 3600",
+			"The rule is not satisfied for the subnet 198.51.100.0/24 (ID 3).",
+		},
+		{
+			"pool",
+			"poolsize(pool.pool) <= 4096",
+			"The rule is not satisfied for the pool 10.0.0.0/16 in subnet 10.0.0.0/8 (ID 2).",
+		},
+		{
+			"subnet",
+			"prefixlen(subnet.subnet) >= 20",
+			"The rule is not satisfied for the subnet 10.0.0.0/8 (ID 2).",
+		},
+		{
+			"global",
+			"config['valid-lifetime'] >= 7200",
+			"violates the user-defined config review rule test_rule (test description).",
+		},
+		{
+			"global",
+			"config['valid-lifetime'] >= 1800",
+			"",
+		},
+		{
+			"reservation",
+			"exists(reservation['ip-address']) && exists(reservation['hw-address'])",
+			"The rule is not satisfied for the reservation for client-id 01:01 in subnet 192.0.2.0/24 (ID 1).",
+		},
+		{
+			"subnet",
+			"subnet.id",
+			"could not be evaluated for subnet 192.0.2.0/24 (ID 1) of {daemon}: expression evaluated to number instead of a boolean",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.expression, func(t *testing.T) {
+			rule, err := compileRule(&dbmodel.ConfigReviewRule{
+				Name:        "test_rule",
+				Description: "test description.",
+				Selector:    "kea-dhcp-v4-daemon",
+				Scope:       tc.scope,
+				Expression:  tc.expression,
+			})
+			require.NoError(t, err)
+			report, err := rule.check(ctx)
+			require.NoError(t, err)
+			if tc.expected == "" {
+				require.Nil(t, report)
+				return
+			}
+			require.NotNil(t, report)
+			require.Contains(t, *report.content, tc.expected)
+			require.Contains(t, *report.content, "{daemon}")
+			require.Equal(t, []int64{1}, report.refDaemonIDs)
+		})
+	}
+}
+
+// Test that the number of the reported violations is limited.
+func TestConfigReviewRuleCheckerManyViolations(t *testing.T) {
+	var subnets []string
+	for i := 1; i <= 15; i++ {
+		subnets = append(subnets, fmt.Sprintf(`{"id": %d, "subnet": "10.%d.0.0/16"}`, i, i))
+	}
+	ctx := createReviewContext(t, nil, fmt.Sprintf(`{"Dhcp4": {"subnet4": [%s]}}`, strings.Join(subnets, ",")))
+
+	rule, err := compileRule(&dbmodel.ConfigReviewRule{
+		Name:       "test_rule",
+		Selector:   "kea-dhcp-daemon",
+		Scope:      "subnet",
+		Expression: "prefixlen(subnet.subnet) >= 20",
+	})
+	require.NoError(t, err)
+	report, err := rule.check(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	require.Contains(t, *report.content, "violates the user-defined config review rule test_rule.")
+	require.Contains(t, *report.content, "subnet 10.10.0.0/16 (ID 10) and 5 more.")
+	require.NotContains(t, *report.content, "ID 11")
+}
@@ -0,0 +1,982 @@
+package configreview
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	storkutil "isc.org/stork/util"
+)
+
+// The expression language of the user-defined config review rules. It is a
+// small, side-effect free language evaluated over the JSON representation
+// of the Kea configuration. The expressions can access the configuration
+// items bound to the variables (e.g., subnet), their members (e.g.,
+// subnet.id or subnet['valid-lifetime']), compare and combine the values
+// with the logical and arithmetic operators, and call a fixed set of the
+// built-in functions. The language has no loops other than the quantifiers
+// iterating over the lists, and the evaluation is limited by the number of
+// the evaluation steps, so the user-defined expressions can't block the
+// config review.
+
+const (
+	// Maximum length of the expression.
+	maxRuleExpressionLength = 2000
+	// Maximum nesting depth of the parsed expression.
+	maxRuleExpressionDepth = 64
+	// Maximum number of the evaluation steps for a single configuration
+	// item.
+	maxRuleEvaluationSteps = 100000
+)
+
+// Name of the variable bound to the list elements by the quantifiers.
+const ruleItemVariable = "item"
+
+// Kinds of the tokens of the expression.
+type ruleTokenKind int
+
+const (
+	ruleTokenEOF ruleTokenKind = iota
+	ruleTokenNumber
+	ruleTokenString
+	ruleTokenIdentifier
+	ruleTokenOperator
+)
+
+// A single token of the expression.
+type ruleToken struct {
+	kind   ruleTokenKind
+	text   string
+	pos    int
+	number float64
+}
+
+// Operators and punctuation recognized by the lexer. The two-character
+// operators must precede their one-character prefixes.
+var ruleOperators = []string{
+	"&&", "||", "==", "!=", "<=", ">=",
+	"<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "[", "]", ",", ".",
+}
+
+// Splits the expression into tokens. The member names following the dot
+// may contain hyphens and start with digits, so the Kea parameter names
+// such as valid-lifetime can be accessed directly.
+func tokenizeRuleExpression(expression string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	afterDot := false
+	for pos := 0; pos < len(expression); {
+		r, size := utf8.DecodeRuneInString(expression[pos:])
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+			continue
+		case afterDot && isRuleMemberRune(r):
+			end := pos
+			for end < len(expression) && isRuleMemberRune(rune(expression[end])) {
+				end++
+			}
+			tokens = append(tokens, ruleToken{kind: ruleTokenIdentifier, text: expression[pos:end], pos: pos})
+			pos = end
+		case r == '_' || unicode.IsLetter(r):
+			end := pos
+			for end < len(expression) {
+				r, size := utf8.DecodeRuneInString(expression[end:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, ruleToken{kind: ruleTokenIdentifier, text: expression[pos:end], pos: pos})
+			pos = end
+		case unicode.IsDigit(r):
+			end := pos
+			for end < len(expression) && (unicode.IsDigit(rune(expression[end])) || expression[end] == '.') {
+				end++
+			}
+			number, err := strconv.ParseFloat(expression[pos:end], 64)
+			if err != nil {
+				return nil, errors.Errorf("invalid number %s at position %d", expression[pos:end], pos)
+			}
+			tokens = append(tokens, ruleToken{kind: ruleTokenNumber, text: expression[pos:end], pos: pos, number: number})
+			pos = end
+		case r == '\'' || r == '"':
+			var value strings.Builder
+			end := pos + 1
+			for ; end < len(expression) && rune(expression[end]) != r; end++ {
+				if expression[end] == '\\' && end+1 < len(expression) {
+					end++
+				}
+				value.WriteByte(expression[end])
+			}
+			if end >= len(expression) {
+				return nil, errors.Errorf("unterminated string at position %d", pos)
+			}
+			tokens = append(tokens, ruleToken{kind: ruleTokenString, text: value.String(), pos: pos})
+			pos = end + 1
+		default:
+			operator := ""
+			for _, op := range ruleOperators {
+				if strings.HasPrefix(expression[pos:], op) {
+					operator = op
+					break
+				}
+			}
+			if operator == "" {
+				return nil, errors.Errorf("unexpected character %q at position %d", r, pos)
+			}
+			tokens = append(tokens, ruleToken{kind: ruleTokenOperator, text: operator, pos: pos})
+			pos += len(operator)
+		}
+		afterDot = tokens[len(tokens)-1].kind == ruleTokenOperator && tokens[len(tokens)-1].text == "."
+	}
+	tokens = append(tokens, ruleToken{kind: ruleTokenEOF, pos: len(expression)})
+	return tokens, nil
+}
+
+// Checks if the character may be a part of the member name following
+// the dot.
+func isRuleMemberRune(r rune) bool {
+	return r == '_' || r == '-' || (r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+}
+
+// Evaluation environment holding the values of the variables and the
+// remaining number of the evaluation steps shared by the nested
+// environments.
+type ruleEnv struct {
+	variables map[string]any
+	steps     *int
+}
+
+// Creates a new evaluation environment with the specified variables.
+func newRuleEnv(variables map[string]any) *ruleEnv {
+	steps := maxRuleEvaluationSteps
+	return &ruleEnv{
+		variables: variables,
+		steps:     &steps,
+	}
+}
+
+// Creates an environment inheriting the variables and the evaluation budget
+// of the parent and binding an additional variable.
+func (env *ruleEnv) bind(name string, value any) *ruleEnv {
+	variables := make(map[string]any, len(env.variables)+1)
+	for k, v := range env.variables {
+		variables[k] = v
+	}
+	variables[name] = value
+	return &ruleEnv{
+		variables: variables,
+		steps:     env.steps,
+	}
+}
+
+// Consumes a single evaluation step. It returns an error when the
+// evaluation budget is exhausted.
+func (env *ruleEnv) step() error {
+	*env.steps--
+	if *env.steps < 0 {
+		return errors.New("evaluation step limit exceeded")
+	}
+	return nil
+}
+
+// Node of the parsed expression.
+type ruleNode interface {
+	eval(env *ruleEnv) (any, error)
+}
+
+// Literal value.
+type ruleLiteral struct {
+	value any
+}
+
+// Variable reference.
+type ruleVariable struct {
+	name string
+}
+
+// Member access using the dot notation or the indexing.
+type ruleIndex struct {
+	object ruleNode
+	index  ruleNode
+}
+
+// List literal.
+type ruleList struct {
+	elements []ruleNode
+}
+
+// Unary operator.
+type ruleUnary struct {
+	operator string
+	operand  ruleNode
+}
+
+// Binary operator.
+type ruleBinary struct {
+	operator string
+	left     ruleNode
+	right    ruleNode
+}
+
+// Built-in function call.
+type ruleCall struct {
+	function *ruleFunction
+	args     []ruleNode
+	// Regular expression compiled during parsing when the pattern is
+	// a literal.
+	regexp *regexp.Regexp
+}
+
+// Built-in function of the expression language.
+type ruleFunction struct {
+	name  string
+	arity int
+	// Indicates that the function is a quantifier binding the list
+	// elements to the item variable in its second argument.
+	quantifier bool
+}
+
+// Built-in functions by name.
+var ruleFunctions = map[string]*ruleFunction{
+	"len":       {name: "len", arity: 1},
+	"exists":    {name: "exists", arity: 1},
+	"contains":  {name: "contains", arity: 2},
+	"matches":   {name: "matches", arity: 2},
+	"prefixlen": {name: "prefixlen", arity: 1},
+	"poolsize":  {name: "poolsize", arity: 1},
+	"any":       {name: "any", arity: 2, quantifier: true},
+	"all":       {name: "all", arity: 2, quantifier: true},
+	"count":     {name: "count", arity: 2, quantifier: true},
+}
+
+// Compiled config review rule expression.
+type ruleExpression struct {
+	root ruleNode
+}
+
+// Recursive descent parser of the expressions.
+type ruleParser struct {
+	tokens    []ruleToken
+	pos       int
+	depth     int
+	variables map[string]bool
+	// Number of the enclosing quantifier bodies. The item variable is
+	// only available inside them.
+	quantifiers int
+}
+
+// Parses the expression. The variables are the names of the variables
+// the expression may refer to.
+func compileRuleExpression(expression string, variables []string) (*ruleExpression, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, errors.New("expression is empty")
+	}
+	if len(expression) > maxRuleExpressionLength {
+		return nil, errors.Errorf("expression is longer than %d characters", maxRuleExpressionLength)
+	}
+	tokens, err := tokenizeRuleExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	parser := &ruleParser{
+		tokens:    tokens,
+		variables: make(map[string]bool),
+	}
+	for _, variable := range variables {
+		parser.variables[variable] = true
+	}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != ruleTokenEOF {
+		return nil, errors.Errorf("unexpected %s at position %d", token.describe(), token.pos)
+	}
+	return &ruleExpression{root: root}, nil
+}
+
+// Evaluates the expression with the specified variables. The expression
+// must evaluate to a boolean value; null is treated as false.
+func (e *ruleExpression) evaluate(variables map[string]any) (bool, error) {
+	value, err := e.root.eval(newRuleEnv(variables))
+	if err != nil {
+		return false, err
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case nil:
+		return false, nil
+	default:
+		return false, errors.Errorf("expression evaluated to %s instead of a boolean", describeRuleValue(value))
+	}
+}
+
+// Returns a human-readable description of the token for the error
+// messages.
+func (t ruleToken) describe() string {
+	switch t.kind {
+	case ruleTokenEOF:
+		return "end of expression"
+	case ruleTokenString:
+		return fmt.Sprintf("string '%s'", t.text)
+	default:
+		return fmt.Sprintf("'%s'", t.text)
+	}
+}
+
+// Returns the current token.
+func (p *ruleParser) peek() ruleToken {
+	return p.tokens[p.pos]
+}
+
+// Returns the current token and advances to the next one.
+func (p *ruleParser) next() ruleToken {
+	token := p.tokens[p.pos]
+	if token.kind != ruleTokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+// Checks if the current token is one of the specified operators. If so,
+// it consumes the token and returns the operator.
+func (p *ruleParser) acceptOperator(operators ...string) (string, bool) {
+	token := p.peek()
+	if token.kind != ruleTokenOperator {
+		return "", false
+	}
+	for _, operator := range operators {
+		if token.text == operator {
+			p.pos++
+			return operator, true
+		}
+	}
+	return "", false
+}
+
+// Consumes the specified operator or returns an error.
+func (p *ruleParser) expectOperator(operator string) error {
+	if _, ok := p.acceptOperator(operator); !ok {
+		token := p.peek()
+		return errors.Errorf("expected '%s' but found %s at position %d", operator, token.describe(), token.pos)
+	}
+	return nil
+}
+
+// Increases the nesting depth and checks it against the limit.
+func (p *ruleParser) enter() error {
+	p.depth++
+	if p.depth > maxRuleExpressionDepth {
+		return errors.Errorf("expression is nested deeper than %d levels", maxRuleExpressionDepth)
+	}
+	return nil
+}
+
+// Decreases the nesting depth.
+func (p *ruleParser) leave() {
+	p.depth--
+}
+
+// Parses the left-associative binary operators at one precedence level.
+func (p *ruleParser) parseBinary(operand func() (ruleNode, error), operators ...string) (ruleNode, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator, ok := p.acceptOperator(operators...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &ruleBinary{operator: operator, left: left, right: right}
+	}
+}
+
+// or := and ('||' and)*
+func (p *ruleParser) parseOr() (ruleNode, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+// and := comparison ('&&' comparison)*
+func (p *ruleParser) parseAnd() (ruleNode, error) {
+	return p.parseBinary(p.parseComparison, "&&")
+}
+
+// comparison := additive (('==' | '!=' | '<' | '<=' | '>' | '>=') additive)?
+func (p *ruleParser) parseComparison() (ruleNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	operator, ok := p.acceptOperator("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return &ruleBinary{operator: operator, left: left, right: right}, nil
+}
+
+// additive := multiplicative (('+' | '-') multiplicative)*
+func (p *ruleParser) parseAdditive() (ruleNode, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+// multiplicative := unary (('*' | '/' | '%') unary)*
+func (p *ruleParser) parseMultiplicative() (ruleNode, error) {
+	return p.parseBinary(p.parseUnary, "*", "/", "%")
+}
+
+// unary := ('!' | '-') unary | postfix
+func (p *ruleParser) parseUnary() (ruleNode, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	if operator, ok := p.acceptOperator("!", "-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &ruleUnary{operator: operator, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+// postfix := primary ('.' member | '[' or ']')*
+func (p *ruleParser) parsePostfix() (ruleNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator, ok := p.acceptOperator(".", "[")
+		if !ok {
+			return node, nil
+		}
+		if operator == "." {
+			token := p.next()
+			if token.kind != ruleTokenIdentifier {
+				return nil, errors.Errorf("expected member name but found %s at position %d", token.describe(), token.pos)
+			}
+			node = &ruleIndex{object: node, index: &ruleLiteral{value: token.text}}
+			continue
+		}
+		index, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectOperator("]"); err != nil {
+			return nil, err
+		}
+		node = &ruleIndex{object: node, index: index}
+	}
+}
+
+// primary := number | string | 'true' | 'false' | 'null' | variable |
+// function '(' arguments ')' | '[' elements ']' | '(' or ')'
+func (p *ruleParser) parsePrimary() (ruleNode, error) {
+	token := p.next()
+	switch token.kind {
+	case ruleTokenNumber:
+		return &ruleLiteral{value: token.number}, nil
+	case ruleTokenString:
+		return &ruleLiteral{value: token.text}, nil
+	case ruleTokenIdentifier:
+		switch token.text {
+		case "true":
+			return &ruleLiteral{value: true}, nil
+		case "false":
+			return &ruleLiteral{value: false}, nil
+		case "null":
+			return &ruleLiteral{value: nil}, nil
+		}
+		if _, ok := p.acceptOperator("("); ok {
+			return p.parseCall(token)
+		}
+		if p.variables[token.text] || (token.text == ruleItemVariable && p.quantifiers > 0) {
+			return &ruleVariable{name: token.text}, nil
+		}
+		return nil, errors.Errorf("unknown variable %s at position %d", token.text, token.pos)
+	case ruleTokenOperator:
+		if token.text == "[" {
+			list := &ruleList{}
+			if _, ok := p.acceptOperator("]"); ok {
+				return list, nil
+			}
+			for {
+				element, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				list.elements = append(list.elements, element)
+				if _, ok := p.acceptOperator(","); !ok {
+					break
+				}
+			}
+			if err := p.expectOperator("]"); err != nil {
+				return nil, err
+			}
+			return list, nil
+		}
+		if token.text == "(" {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOperator(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	}
+	return nil, errors.Errorf("unexpected %s at position %d", token.describe(), token.pos)
+}
+
+// Parses the arguments of the function call following the opening
+// parenthesis.
+func (p *ruleParser) parseCall(name ruleToken) (ruleNode, error) {
+	function, ok := ruleFunctions[name.text]
+	if !ok {
+		return nil, errors.Errorf("unknown function %s at position %d", name.text, name.pos)
+	}
+	call := &ruleCall{function: function}
+	if _, ok := p.acceptOperator(")"); !ok {
+		for {
+			if function.quantifier && len(call.args) == 1 {
+				p.quantifiers++
+			}
+			arg, err := p.parseOr()
+			if function.quantifier && len(call.args) == 1 {
+				p.quantifiers--
+			}
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if _, ok := p.acceptOperator(","); !ok {
+				break
+			}
+		}
+		if err := p.expectOperator(")"); err != nil {
+			return nil, err
+		}
+	}
+	if len(call.args) != function.arity {
+		return nil, errors.Errorf("function %s at position %d expects %d argument(s) but %d given",
+			function.name, name.pos, function.arity, len(call.args))
+	}
+	if function.name == "matches" {
+		if literal, ok := call.args[1].(*ruleLiteral); ok {
+			pattern, ok := literal.value.(string)
+			if !ok {
+				return nil, errors.Errorf("function matches at position %d expects a string pattern", name.pos)
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid regular expression at position %d", name.pos)
+			}
+			call.regexp = re
+		}
+	}
+	return call, nil
+}
+
+// Returns a human-readable type name of the value for the error messages.
+func describeRuleValue(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "map"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// Returns the literal value.
+func (n *ruleLiteral) eval(env *ruleEnv) (any, error) {
+	return n.value, env.step()
+}
+
+// Returns the value of the variable.
+func (n *ruleVariable) eval(env *ruleEnv) (any, error) {
+	return env.variables[n.name], env.step()
+}
+
+// Returns the list of the evaluated elements.
+func (n *ruleList) eval(env *ruleEnv) (any, error) {
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+	list := make([]any, 0, len(n.elements))
+	for _, element := range n.elements {
+		value, err := element.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+// Returns the member of the map or the element of the list. Accessing
+// a missing member, an element out of range or any member of null yields
+// null, so the optional configuration parameters can be tested without
+// checking their presence first.
+func (n *ruleIndex) eval(env *ruleEnv) (any, error) {
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+	object, err := n.object.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch o := object.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		key, ok := index.(string)
+		if !ok {
+			return nil, errors.Errorf("cannot index a map with %s", describeRuleValue(index))
+		}
+		return o[key], nil
+	case []any:
+		i, ok := index.(float64)
+		if !ok || i != math.Trunc(i) {
+			return nil, errors.Errorf("cannot index a list with %s", describeRuleValue(index))
+		}
+		if i < 0 || int(i) >= len(o) {
+			return nil, nil
+		}
+		return o[int(i)], nil
+	default:
+		return nil, errors.Errorf("cannot access a member of %s", describeRuleValue(object))
+	}
+}
+
+// Converts the value to a boolean for the logical operators. Null is
+// treated as false.
+func toRuleBool(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case nil:
+		return false, nil
+	default:
+		return false, errors.Errorf("expected a boolean but found %s", describeRuleValue(value))
+	}
+}
+
+// Evaluates the unary operator.
+func (n *ruleUnary) eval(env *ruleEnv) (any, error) {
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+	operand, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.operator == "!" {
+		b, err := toRuleBool(operand)
+		return !b, err
+	}
+	number, ok := operand.(float64)
+	if !ok {
+		return nil, errors.Errorf("cannot negate %s", describeRuleValue(operand))
+	}
+	return -number, nil
+}
+
+// Evaluates the binary operator. The logical operators short-circuit.
+func (n *ruleBinary) eval(env *ruleEnv) (any, error) {
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.operator == "&&" || n.operator == "||" {
+		l, err := toRuleBool(left)
+		if err != nil {
+			return nil, err
+		}
+		if l == (n.operator == "||") {
+			return l, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return toRuleBool(right)
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.operator {
+	case "==":
+		return reflect.DeepEqual(left, right), nil
+	case "!=":
+		return !reflect.DeepEqual(left, right), nil
+	case "<", "<=", ">", ">=":
+		return compareRuleValues(n.operator, left, right)
+	default:
+		return computeRuleValues(n.operator, left, right)
+	}
+}
+
+// Compares two numbers or two strings. The comparison with null is always
+// false.
+func compareRuleValues(operator string, left, right any) (bool, error) {
+	if left == nil || right == nil {
+		return false, nil
+	}
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false, errors.Errorf("cannot compare number with %s", describeRuleValue(right))
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false, errors.Errorf("cannot compare string with %s", describeRuleValue(right))
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return false, errors.Errorf("cannot compare %s", describeRuleValue(left))
+	}
+	switch operator {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// Evaluates the arithmetic operators. The addition also concatenates the
+// strings.
+func computeRuleValues(operator string, left, right any) (any, error) {
+	if l, ok := left.(string); ok && operator == "+" {
+		if r, ok := right.(string); ok {
+			return l + r, nil
+		}
+	}
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, errors.Errorf("cannot apply '%s' to %s and %s", operator, describeRuleValue(left), describeRuleValue(right))
+	}
+	switch operator {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	}
+	if r == 0 {
+		return nil, errors.New("division by zero")
+	}
+	if operator == "/" {
+		return l / r, nil
+	}
+	return math.Mod(l, r), nil
+}
+
+// Evaluates the built-in function.
+func (n *ruleCall) eval(env *ruleEnv) (any, error) {
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+	first, err := n.args[0].eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.function.quantifier {
+		return n.evalQuantifier(env, first)
+	}
+	switch n.function.name {
+	case "len":
+		switch v := first.(type) {
+		case nil:
+			return float64(0), nil
+		case string:
+			return float64(utf8.RuneCountInString(v)), nil
+		case []any:
+			return float64(len(v)), nil
+		case map[string]any:
+			return float64(len(v)), nil
+		}
+		return nil, errors.Errorf("function len cannot be applied to %s", describeRuleValue(first))
+	case "exists":
+		return first != nil, nil
+	case "prefixlen":
+		s, ok := first.(string)
+		if !ok {
+			return nil, nil
+		}
+		parsed := storkutil.ParseIP(s)
+		if parsed == nil {
+			return nil, nil
+		}
+		return float64(parsed.PrefixLength), nil
+	case "poolsize":
+		return getRulePoolSize(first), nil
+	}
+	second, err := n.args[1].eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.function.name {
+	case "contains":
+		switch v := first.(type) {
+		case nil:
+			return false, nil
+		case string:
+			s, ok := second.(string)
+			if !ok {
+				return nil, errors.Errorf("cannot search for %s in a string", describeRuleValue(second))
+			}
+			return strings.Contains(v, s), nil
+		case []any:
+			for _, element := range v {
+				if reflect.DeepEqual(element, second) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]any:
+			s, ok := second.(string)
+			if !ok {
+				return nil, errors.Errorf("cannot search for %s in a map", describeRuleValue(second))
+			}
+			_, ok = v[s]
+			return ok, nil
+		}
+		return nil, errors.Errorf("function contains cannot be applied to %s", describeRuleValue(first))
+	default:
+		// matches
+		s, ok := first.(string)
+		if !ok {
+			return false, nil
+		}
+		re := n.regexp
+		if re == nil {
+			pattern, ok := second.(string)
+			if !ok {
+				return nil, errors.Errorf("function matches expects a string pattern but found %s", describeRuleValue(second))
+			}
+			if re, err = regexp.Compile(pattern); err != nil {
+				return nil, errors.Wrap(err, "invalid regular expression")
+			}
+		}
+		return re.MatchString(s), nil
+	}
+}
+
+// Evaluates the quantifier binding the list elements to the item variable.
+func (n *ruleCall) evalQuantifier(env *ruleEnv, first any) (any, error) {
+	var list []any
+	switch v := first.(type) {
+	case nil:
+	case []any:
+		list = v
+	default:
+		return nil, errors.Errorf("function %s expects a list but found %s", n.function.name, describeRuleValue(first))
+	}
+	count := 0
+	for _, element := range list {
+		value, err := n.args[1].eval(env.bind(ruleItemVariable, element))
+		if err != nil {
+			return nil, err
+		}
+		matched, err := toRuleBool(value)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case matched && n.function.name == "any":
+			return true, nil
+		case !matched && n.function.name == "all":
+			return false, nil
+		case matched:
+			count++
+		}
+	}
+	switch n.function.name {
+	case "any":
+		return false, nil
+	case "all":
+		return true, nil
+	default:
+		return float64(count), nil
+	}
+}
+
+// Returns the number of addresses in the pool specified as a range or
+// a prefix. It returns null when the pool is invalid.
+func getRulePoolSize(value any) any {
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	s = strings.ReplaceAll(s, " ", "")
+	if _, prefix, err := net.ParseCIDR(s); err == nil {
+		ones, bits := prefix.Mask.Size()
+		return math.Pow(2, float64(bits-ones))
+	}
+	lb, ub, err := storkutil.ParseIPRange(s)
+	if err != nil {
+		return nil
+	}
+	size := big.NewInt(0).Sub(big.NewInt(0).SetBytes(ub.To16()), big.NewInt(0).SetBytes(lb.To16()))
+	size.Add(size, big.NewInt(1))
+	f, _ := new(big.Float).SetInt(size).Float64()
+	return f
+}
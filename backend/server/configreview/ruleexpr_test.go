@@ -0,0 +1,174 @@
+package configreview
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+)
+
+// Returns the variables used in the expression tests.
+func getTestRuleVariables(t *testing.T) map[string]any {
+	var subnet map[string]any
+	err := json.Unmarshal([]byte(`{
+		"id": 1,
+		"subnet": "192.0.2.0/24",
+		"valid-lifetime": 3600,
+		"pools": [
+			{ "pool": "192.0.2.10 - 192.0.2.20" },
+			{ "pool": "192.0.2.128/25" }
+		],
+		"option-data": [
+			{ "name": "routers", "data": "192.0.2.1" },
+			{ "name": "domain-name-servers", "data": "192.0.2.2, 192.0.2.3" }
+		],
+		"user-context": { "site": "hq" }
+	}`), &subnet)
+	require.NoError(t, err)
+	return map[string]any{
+		"subnet":  subnet,
+		"network": nil,
+	}
+}
+
+// Test that the valid expressions are evaluated correctly.
+func TestEvaluateRuleExpression(t *testing.T) {
+	variables := getTestRuleVariables(t)
+
+	testCases := []struct {
+		expression string
+		expected   bool
+	}{
+		{"true", true},
+		{"false || !false", true},
+		{"subnet.id == 1", true},
+		{"subnet['id'] != 1", false},
+		{"subnet.valid-lifetime >= 1800 && subnet.valid-lifetime <= 7200", true},
+		{"subnet['valid-lifetime'] > 3600", false},
+		{"subnet.valid-lifetime / 60 == 60", true},
+		{"subnet.valid-lifetime % 7 == 2", true},
+		{"-subnet.id + 2 * 3 == 5", true},
+		{"(1 + 2) * 3 == 9", true},
+		{"subnet.subnet == '192.0.2.0/24'", true},
+		{"subnet.subnet + \"!\" == \"192.0.2.0/24!\"", true},
+		{"'abc' < 'abd'", true},
+		{"subnet.missing == null", true},
+		{"subnet.missing.nested == null", true},
+		{"network.name == null", true},
+		{"subnet.missing > 1", false},
+		{"!exists(subnet.missing) && exists(subnet.pools)", true},
+		{"len(subnet.pools) == 2 && len(subnet.subnet) == 12 && len(subnet.missing) == 0", true},
+		{"subnet.pools[1].pool == '192.0.2.128/25'", true},
+		{"subnet.pools[2] == null", true},
+		{"contains(subnet.subnet, '192.0.2')", true},
+		{"contains(subnet['user-context'], 'site')", true},
+		{"contains(['a', 'b'], 'b')", true},
+		{"contains([], 'b') || contains([1, 2], 3)", false},
+		{"matches(subnet.subnet, '^192\\\\.0\\\\.2\\\\.')", true},
+		{"matches(subnet.missing, 'x')", false},
+		{"prefixlen(subnet.subnet) == 24", true},
+		{"prefixlen(subnet.subnet) >= 20", true},
+		{"poolsize(subnet.pools[0].pool) == 11", true},
+		{"poolsize(subnet.pools[1].pool) == 128", true},
+		{"poolsize('2001:db8::/64') == 18446744073709551616", true},
+		{"poolsize('invalid') == null", true},
+		{"any(subnet['option-data'], item.name == 'routers')", true},
+		{"any(subnet['option-data'], item.name == 'time-servers')", false},
+		{"all(subnet.pools, poolsize(item.pool) <= 128)", true},
+		{"all(subnet.missing, false)", true},
+		{"count(subnet.pools, contains(item.pool, '-')) == 1", true},
+		{"any(subnet.pools, all(subnet['option-data'], contains(item.data, '192.0.2')))", true},
+		{"subnet.missing", false},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.expression, func(t *testing.T) {
+			expression, err := compileRuleExpression(tc.expression, []string{"network", "subnet"})
+			require.NoError(t, err)
+			result, err := expression.evaluate(variables)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+// Test that the invalid expressions are rejected when they are compiled.
+func TestCompileInvalidRuleExpression(t *testing.T) {
+	testCases := []string{
+		"",
+		"  ",
+		"subnet.id ==",
+		"subnet.id == 1)",
+		"(subnet.id == 1",
+		"subnet.",
+		"subnet[0",
+		"[1, 2",
+		"pool.pool == ''",
+		"item.name == 'routers'",
+		"unknown(subnet)",
+		"len(subnet, subnet)",
+		"any(subnet.pools)",
+		"matches(subnet.subnet, '[')",
+		"matches(subnet.subnet, 1)",
+		"'unterminated",
+		"subnet.id == 1.2.3",
+		"subnet.id # 1",
+		"subnet.id ? 1 : 0",
+		"subnet.id == 1 == 2",
+		strings.Repeat("(", 100) + "true" + strings.Repeat(")", 100),
+		strings.Repeat("a", maxRuleExpressionLength+1),
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc, func(t *testing.T) {
+			_, err := compileRuleExpression(tc, []string{"subnet"})
+			require.Error(t, err)
+		})
+	}
+}
+
+// Test that the expressions that can't be evaluated return an error.
+func TestEvaluateRuleExpressionError(t *testing.T) {
+	variables := getTestRuleVariables(t)
+
+	testCases := []string{
+		"subnet.id",
+		"subnet.id && true",
+		"subnet.id < 'a'",
+		"subnet.pools < 1",
+		"subnet.id / 0 == 1",
+		"subnet.subnet - 1 == 0",
+		"-subnet.subnet == 1",
+		"subnet.id.name == 1",
+		"subnet.pools['a'] == 1",
+		"subnet[1] == 1",
+		"len(subnet.id) == 1",
+		"contains(subnet.id, 1)",
+		"any(subnet, true)",
+		"any(subnet.pools, item.pool)",
+		"matches(subnet.subnet, subnet.missing)",
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc, func(t *testing.T) {
+			expression, err := compileRuleExpression(tc, []string{"subnet"})
+			require.NoError(t, err)
+			_, err = expression.evaluate(variables)
+			require.Error(t, err)
+		})
+	}
+}
+
+// Test that the evaluation is interrupted when the expression exceeds the
+// evaluation step limit.
+func TestEvaluateRuleExpressionStepLimit(t *testing.T) {
+	list := make([]any, 1000)
+	for i := range list {
+		list[i] = float64(i)
+	}
+	expression, err := compileRuleExpression("all(list, all(list, item >= 0))", []string{"list"})
+	require.NoError(t, err)
+	_, err = expression.evaluate(map[string]any{"list": list})
+	require.ErrorContains(t, err, "evaluation step limit exceeded")
+}
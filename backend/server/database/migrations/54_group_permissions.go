@@ -0,0 +1,48 @@
+package dbmigs
+
+import "github.com/go-pg/migrations/v8"
+
+// This migration adds the permissions and the scope to the system groups.
+// It also adds two predefined groups: read-only and operator.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			-- Permissions granted to the group members.
+			ALTER TABLE system_group ADD COLUMN permissions TEXT[] DEFAULT '{}';
+
+			-- Objects to which the permissions are limited. Empty arrays
+			-- mean that the permissions are not limited.
+			ALTER TABLE system_group ADD COLUMN scope_machine_ids BIGINT[] DEFAULT '{}';
+			ALTER TABLE system_group ADD COLUMN scope_app_ids BIGINT[] DEFAULT '{}';
+			ALTER TABLE system_group ADD COLUMN scope_subnet_ids BIGINT[] DEFAULT '{}';
+
+			-- Group names must be unique.
+			ALTER TABLE system_group
+				ADD CONSTRAINT system_group_name_unique_idx UNIQUE (name);
+
+			-- Predefined groups.
+			INSERT INTO system_group (id, name, description, permissions)
+				VALUES (3, 'read-only', 'This group of users can view all system components but cannot change anything.', '{view-all}');
+			INSERT INTO system_group (id, name, description, permissions)
+				VALUES (4, 'operator', 'This group of users can view all system components, edit host reservations and subnets, and run config review.', '{view-all,edit-hosts,edit-subnets,run-config-review}');
+
+			-- Make sure that the new groups get next IDs.
+			SELECT setval('system_group_id_seq', (SELECT MAX(id) FROM system_group));
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DELETE FROM system_group WHERE id IN (3, 4);
+
+			SELECT setval('system_group_id_seq', (SELECT MAX(id) FROM system_group));
+
+			ALTER TABLE system_group DROP CONSTRAINT system_group_name_unique_idx;
+
+			ALTER TABLE system_group DROP COLUMN scope_subnet_ids;
+			ALTER TABLE system_group DROP COLUMN scope_app_ids;
+			ALTER TABLE system_group DROP COLUMN scope_machine_ids;
+			ALTER TABLE system_group DROP COLUMN permissions;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,45 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Creates the table holding the audit log of the configuration changes
+// committed by the config manager.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            CREATE TABLE IF NOT EXISTS config_change_log (
+                id BIGSERIAL NOT NULL PRIMARY KEY,
+                created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT timezone('utc'::text, now()) NOT NULL,
+                user_id BIGINT,
+                user_identity TEXT,
+                target TEXT NOT NULL,
+                operation TEXT NOT NULL,
+                daemon_ids BIGINT[],
+                object_type TEXT,
+                object_id BIGINT,
+                scheduled BOOLEAN DEFAULT FALSE,
+                recipe JSONB,
+                state_before JSONB,
+                state_after JSONB,
+                diff JSONB,
+                error TEXT,
+                CONSTRAINT config_change_log_user FOREIGN KEY (user_id)
+                    REFERENCES system_user(id)
+                        ON UPDATE CASCADE
+                        ON DELETE SET NULL
+            );
+            CREATE INDEX config_change_log_created_at_idx ON config_change_log USING btree (created_at);
+            CREATE INDEX config_change_log_user_id_idx ON config_change_log USING btree (user_id);
+            CREATE INDEX config_change_log_daemon_ids_idx ON config_change_log USING gin (daemon_ids);
+            CREATE INDEX config_change_log_object_idx ON config_change_log USING btree (object_type, object_id);
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            DROP TABLE IF EXISTS config_change_log;
+        `)
+		return err
+	})
+}
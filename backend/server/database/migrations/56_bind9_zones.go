@@ -0,0 +1,42 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Creates the table holding the zones fetched from the BIND 9 servers.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            CREATE TABLE IF NOT EXISTS bind9_zone (
+                id BIGSERIAL NOT NULL PRIMARY KEY,
+                daemon_id BIGINT NOT NULL,
+                view TEXT NOT NULL,
+                name TEXT NOT NULL,
+                class TEXT NOT NULL,
+                zone_type TEXT NOT NULL,
+                serial BIGINT,
+                loaded_at TIMESTAMP WITHOUT TIME ZONE,
+                refresh_at TIMESTAMP WITHOUT TIME ZONE,
+                expires_at TIMESTAMP WITHOUT TIME ZONE,
+                dnssec_state TEXT,
+                key_maintenance TEXT,
+                next_key_event_at TIMESTAMP WITHOUT TIME ZONE,
+                primaries TEXT[],
+                updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT timezone('utc'::text, now()) NOT NULL,
+                CONSTRAINT bind9_zone_daemon_id FOREIGN KEY (daemon_id)
+                    REFERENCES daemon(id)
+                        ON UPDATE CASCADE
+                        ON DELETE CASCADE,
+                CONSTRAINT bind9_zone_daemon_view_name_class_unique UNIQUE (daemon_id, view, name, class)
+            );
+            CREATE INDEX bind9_zone_name_idx ON bind9_zone USING btree (name);
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            DROP TABLE IF EXISTS bind9_zone;
+        `)
+		return err
+	})
+}
@@ -0,0 +1,40 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Creates the table holding the notification channels used to forward
+// the events to external systems.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            CREATE TABLE IF NOT EXISTS notification_channel (
+                id BIGSERIAL NOT NULL PRIMARY KEY,
+                created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT timezone('utc'::text, now()) NOT NULL,
+                name TEXT NOT NULL,
+                channel_type TEXT NOT NULL,
+                enabled BOOLEAN NOT NULL DEFAULT TRUE,
+                level INTEGER NOT NULL DEFAULT 0,
+                machine_id BIGINT NOT NULL DEFAULT 0,
+                app_id BIGINT NOT NULL DEFAULT 0,
+                daemon_id BIGINT NOT NULL DEFAULT 0,
+                subnet_id BIGINT NOT NULL DEFAULT 0,
+                rate_limit BIGINT NOT NULL DEFAULT 0,
+                rate_limit_interval BIGINT NOT NULL DEFAULT 60,
+                settings JSONB,
+                CONSTRAINT notification_channel_name_unique UNIQUE (name),
+                CONSTRAINT notification_channel_type_check CHECK (
+                    channel_type IN ('smtp', 'webhook', 'slack', 'syslog')
+                ),
+                CONSTRAINT notification_channel_rate_limit_interval_check CHECK (rate_limit_interval > 0)
+            );
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            DROP TABLE IF EXISTS notification_channel;
+        `)
+		return err
+	})
+}
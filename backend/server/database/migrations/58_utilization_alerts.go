@@ -0,0 +1,67 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Creates the table holding the utilization alert rules and adds the
+// columns holding the current alert levels to the subnet and shared
+// network tables. The global rule is created with the default thresholds.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            CREATE TABLE IF NOT EXISTS utilization_alert_rule (
+                id BIGSERIAL NOT NULL PRIMARY KEY,
+                subnet_id BIGINT,
+                shared_network_id BIGINT,
+                addr_warning REAL NOT NULL DEFAULT 0,
+                addr_critical REAL NOT NULL DEFAULT 0,
+                pd_warning REAL NOT NULL DEFAULT 0,
+                pd_critical REAL NOT NULL DEFAULT 0,
+                hysteresis REAL NOT NULL DEFAULT 0,
+                CONSTRAINT utilization_alert_rule_subnet_id FOREIGN KEY (subnet_id)
+                    REFERENCES subnet(id)
+                        ON UPDATE CASCADE
+                        ON DELETE CASCADE,
+                CONSTRAINT utilization_alert_rule_shared_network_id FOREIGN KEY (shared_network_id)
+                    REFERENCES shared_network(id)
+                        ON UPDATE CASCADE
+                        ON DELETE CASCADE,
+                CONSTRAINT utilization_alert_rule_subnet_id_unique UNIQUE (subnet_id),
+                CONSTRAINT utilization_alert_rule_shared_network_id_unique UNIQUE (shared_network_id),
+                CONSTRAINT utilization_alert_rule_single_target CHECK (
+                    subnet_id IS NULL OR shared_network_id IS NULL
+                )
+            );
+
+            -- There is only one global rule.
+            CREATE UNIQUE INDEX utilization_alert_rule_global_idx ON utilization_alert_rule ((TRUE))
+                WHERE subnet_id IS NULL AND shared_network_id IS NULL;
+
+            INSERT INTO utilization_alert_rule (addr_warning, addr_critical, pd_warning, pd_critical, hysteresis)
+                VALUES (80, 90, 80, 90, 5);
+
+            ALTER TABLE subnet
+                ADD COLUMN addr_utilization_alert SMALLINT NOT NULL DEFAULT 0,
+                ADD COLUMN pd_utilization_alert SMALLINT NOT NULL DEFAULT 0;
+
+            ALTER TABLE shared_network
+                ADD COLUMN addr_utilization_alert SMALLINT NOT NULL DEFAULT 0,
+                ADD COLUMN pd_utilization_alert SMALLINT NOT NULL DEFAULT 0;
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE shared_network
+                DROP COLUMN IF EXISTS addr_utilization_alert,
+                DROP COLUMN IF EXISTS pd_utilization_alert;
+
+            ALTER TABLE subnet
+                DROP COLUMN IF EXISTS addr_utilization_alert,
+                DROP COLUMN IF EXISTS pd_utilization_alert;
+
+            DROP TABLE IF EXISTS utilization_alert_rule;
+        `)
+		return err
+	})
+}
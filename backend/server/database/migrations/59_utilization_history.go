@@ -0,0 +1,60 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Creates the table holding the historical utilization samples of the
+// subnets and shared networks. The raw samples are rolled up into the
+// hourly and daily samples.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            CREATE TYPE UTILIZATION_RESOLUTION AS ENUM
+                ('raw', 'hourly', 'daily');
+
+            CREATE TABLE IF NOT EXISTS utilization_sample (
+                id BIGSERIAL NOT NULL PRIMARY KEY,
+                resolution UTILIZATION_RESOLUTION NOT NULL,
+                sampled_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+                subnet_id BIGINT,
+                shared_network_id BIGINT,
+                total_addresses DOUBLE PRECISION NOT NULL DEFAULT 0,
+                assigned_addresses DOUBLE PRECISION NOT NULL DEFAULT 0,
+                declined_addresses DOUBLE PRECISION NOT NULL DEFAULT 0,
+                total_pds DOUBLE PRECISION NOT NULL DEFAULT 0,
+                assigned_pds DOUBLE PRECISION NOT NULL DEFAULT 0,
+                addr_utilization REAL NOT NULL DEFAULT 0,
+                pd_utilization REAL NOT NULL DEFAULT 0,
+                max_addr_utilization REAL NOT NULL DEFAULT 0,
+                max_pd_utilization REAL NOT NULL DEFAULT 0,
+                CONSTRAINT utilization_sample_subnet_id FOREIGN KEY (subnet_id)
+                    REFERENCES subnet(id)
+                        ON UPDATE CASCADE
+                        ON DELETE CASCADE,
+                CONSTRAINT utilization_sample_shared_network_id FOREIGN KEY (shared_network_id)
+                    REFERENCES shared_network(id)
+                        ON UPDATE CASCADE
+                        ON DELETE CASCADE,
+                CONSTRAINT utilization_sample_single_target CHECK (
+                    (subnet_id IS NULL) <> (shared_network_id IS NULL)
+                )
+            );
+
+            -- Prevents rolling up the same samples twice and speeds up
+            -- the time range queries.
+            CREATE UNIQUE INDEX utilization_sample_unique_idx ON utilization_sample
+                (resolution, COALESCE(subnet_id, 0), COALESCE(shared_network_id, 0), sampled_at);
+
+            CREATE INDEX utilization_sample_resolution_sampled_at_idx ON utilization_sample
+                (resolution, sampled_at);
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            DROP TABLE IF EXISTS utilization_sample;
+            DROP TYPE IF EXISTS UTILIZATION_RESOLUTION;
+        `)
+		return err
+	})
+}
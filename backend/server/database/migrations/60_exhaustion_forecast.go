@@ -0,0 +1,35 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Adds the columns holding the forecasted number of days remaining until
+// the addresses and delegated prefixes are exhausted to the subnet and
+// shared network tables. The NULL value indicates that the exhaustion
+// is not forecasted.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE subnet
+                ADD COLUMN addr_exhaustion_days REAL,
+                ADD COLUMN pd_exhaustion_days REAL;
+
+            ALTER TABLE shared_network
+                ADD COLUMN addr_exhaustion_days REAL,
+                ADD COLUMN pd_exhaustion_days REAL;
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE shared_network
+                DROP COLUMN IF EXISTS addr_exhaustion_days,
+                DROP COLUMN IF EXISTS pd_exhaustion_days;
+
+            ALTER TABLE subnet
+                DROP COLUMN IF EXISTS addr_exhaustion_days,
+                DROP COLUMN IF EXISTS pd_exhaustion_days;
+        `)
+		return err
+	})
+}
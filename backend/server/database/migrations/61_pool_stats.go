@@ -0,0 +1,37 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Adds the columns holding the statistics pulled from the Kea servers and
+// the utilizations to the address and prefix pool tables.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE address_pool
+                ADD COLUMN stats JSONB,
+                ADD COLUMN stats_collected_at TIMESTAMP WITHOUT TIME ZONE,
+                ADD COLUMN utilization SMALLINT;
+
+            ALTER TABLE prefix_pool
+                ADD COLUMN stats JSONB,
+                ADD COLUMN stats_collected_at TIMESTAMP WITHOUT TIME ZONE,
+                ADD COLUMN utilization SMALLINT;
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE prefix_pool
+                DROP COLUMN IF EXISTS stats,
+                DROP COLUMN IF EXISTS stats_collected_at,
+                DROP COLUMN IF EXISTS utilization;
+
+            ALTER TABLE address_pool
+                DROP COLUMN IF EXISTS stats,
+                DROP COLUMN IF EXISTS stats_collected_at,
+                DROP COLUMN IF EXISTS utilization;
+        `)
+		return err
+	})
+}
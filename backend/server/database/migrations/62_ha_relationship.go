@@ -0,0 +1,24 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Adds the column holding the name of the HA relationship to the HA
+// service table. A Kea server may belong to several HA relationships,
+// e.g., in the hub-and-spoke configuration.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE ha_service
+                ADD COLUMN relationship TEXT;
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE ha_service
+                DROP COLUMN IF EXISTS relationship;
+        `)
+		return err
+	})
+}
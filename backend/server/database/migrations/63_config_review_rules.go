@@ -0,0 +1,32 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Adds the table holding the user-defined config review rules. The rules
+// are expressions evaluated over the daemons' configurations by the config
+// review dispatcher alongside the built-in checkers.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            CREATE TABLE IF NOT EXISTS config_review_rule (
+                id BIGSERIAL NOT NULL,
+                name TEXT NOT NULL,
+                description TEXT,
+                selector TEXT NOT NULL,
+                scope TEXT NOT NULL,
+                expression TEXT NOT NULL,
+                created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+                CONSTRAINT config_review_rule_pkey PRIMARY KEY (id),
+                CONSTRAINT config_review_rule_name_unique UNIQUE (name)
+            );
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            DROP TABLE IF EXISTS config_review_rule;
+        `)
+		return err
+	})
+}
@@ -0,0 +1,23 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Adds the column holding the machine-applicable fix proposed by a config
+// checker for the issue described in the config report.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE config_report
+                ADD COLUMN fix JSONB;
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE config_report
+                DROP COLUMN IF EXISTS fix;
+        `)
+		return err
+	})
+}
@@ -0,0 +1,23 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Adds the column holding the deadline of the scheduled config change
+// to the config change log.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE config_change_log
+                ADD COLUMN deadline_at TIMESTAMP WITHOUT TIME ZONE;
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE config_change_log
+                DROP COLUMN IF EXISTS deadline_at;
+        `)
+		return err
+	})
+}
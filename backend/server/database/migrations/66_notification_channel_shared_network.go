@@ -0,0 +1,23 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Adds the column holding the ID of the shared network used to filter
+// the events sent over the notification channel.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE notification_channel
+                ADD COLUMN shared_network_id BIGINT NOT NULL DEFAULT 0;
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE notification_channel
+                DROP COLUMN IF EXISTS shared_network_id;
+        `)
+		return err
+	})
+}
@@ -0,0 +1,67 @@
+package dbmigs
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+// Extends the utilization history with the samples of the address and
+// prefix pools and adds the columns holding the forecasted number of days
+// remaining until the pools are exhausted. The NULL value indicates that
+// the exhaustion is not forecasted.
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE utilization_sample
+                ADD COLUMN address_pool_id BIGINT,
+                ADD COLUMN prefix_pool_id BIGINT,
+                ADD CONSTRAINT utilization_sample_address_pool_id FOREIGN KEY (address_pool_id)
+                    REFERENCES address_pool(id)
+                        ON UPDATE CASCADE
+                        ON DELETE CASCADE,
+                ADD CONSTRAINT utilization_sample_prefix_pool_id FOREIGN KEY (prefix_pool_id)
+                    REFERENCES prefix_pool(id)
+                        ON UPDATE CASCADE
+                        ON DELETE CASCADE,
+                DROP CONSTRAINT utilization_sample_single_target,
+                ADD CONSTRAINT utilization_sample_single_target CHECK (
+                    num_nonnulls(subnet_id, shared_network_id, address_pool_id, prefix_pool_id) = 1
+                );
+
+            DROP INDEX utilization_sample_unique_idx;
+            CREATE UNIQUE INDEX utilization_sample_unique_idx ON utilization_sample
+                (resolution, COALESCE(subnet_id, 0), COALESCE(shared_network_id, 0),
+                 COALESCE(address_pool_id, 0), COALESCE(prefix_pool_id, 0), sampled_at);
+
+            ALTER TABLE address_pool
+                ADD COLUMN exhaustion_days REAL;
+
+            ALTER TABLE prefix_pool
+                ADD COLUMN exhaustion_days REAL;
+        `)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+            ALTER TABLE prefix_pool
+                DROP COLUMN IF EXISTS exhaustion_days;
+
+            ALTER TABLE address_pool
+                DROP COLUMN IF EXISTS exhaustion_days;
+
+            DELETE FROM utilization_sample
+                WHERE address_pool_id IS NOT NULL OR prefix_pool_id IS NOT NULL;
+
+            DROP INDEX utilization_sample_unique_idx;
+            CREATE UNIQUE INDEX utilization_sample_unique_idx ON utilization_sample
+                (resolution, COALESCE(subnet_id, 0), COALESCE(shared_network_id, 0), sampled_at);
+
+            ALTER TABLE utilization_sample
+                DROP CONSTRAINT utilization_sample_single_target,
+                ADD CONSTRAINT utilization_sample_single_target CHECK (
+                    (subnet_id IS NULL) <> (shared_network_id IS NULL)
+                ),
+                DROP COLUMN IF EXISTS address_pool_id,
+                DROP COLUMN IF EXISTS prefix_pool_id;
+        `)
+		return err
+	})
+}
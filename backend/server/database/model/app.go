@@ -388,11 +388,12 @@ func GetAppsByType(dbi dbops.DBI, appType AppType) ([]App, error) {
 // Fetches a collection of apps from the database. The offset and
 // limit specify the beginning of the page and the maximum size of the
 // page. Limit has to be greater then 0, otherwise error is
-// returned. sortField allows indicating sort column in database and
-// sortDir allows selection the order of sorting. If sortField is
-// empty then id is used for sorting. If SortDirAny is used then ASC
-// order is used.
-func GetAppsByPage(dbi dbops.DBI, offset int64, limit int64, filterText *string, appType AppType, sortField string, sortDir SortDirEnum) ([]App, int64, error) {
+// returned. The scope limits the apps to those belonging to the user's
+// scope. The nil value disables such filtering. sortField allows
+// indicating sort column in database and sortDir allows selection the
+// order of sorting. If sortField is empty then id is used for sorting.
+// If SortDirAny is used then ASC order is used.
+func GetAppsByPage(dbi dbops.DBI, offset int64, limit int64, filterText *string, appType AppType, scope *ScopeFilter, sortField string, sortDir SortDirEnum) ([]App, int64, error) {
 	if limit == 0 {
 		return nil, 0, pkgerrors.New("limit should be greater than 0")
 	}
@@ -419,6 +420,7 @@ func GetAppsByPage(dbi dbops.DBI, offset int64, limit int64, filterText *string,
 			return qq, nil
 		})
 	}
+	q = scope.applyToApps(q)
 
 	// prepare sorting expression, offset and limit
 	ordExpr := prepareOrderExpr("app", sortField, sortDir)
@@ -1112,13 +1112,13 @@ func TestGetAppsByPage(t *testing.T) {
 	require.NotZero(t, sBind.ID)
 
 	// get all apps
-	apps, total, err := GetAppsByPage(db, 0, 10, nil, "", "", SortDirAny)
+	apps, total, err := GetAppsByPage(db, 0, 10, nil, "", nil, "", SortDirAny)
 	require.NoError(t, err)
 	require.Len(t, apps, 2)
 	require.EqualValues(t, 2, total)
 
 	// get kea apps
-	apps, total, err = GetAppsByPage(db, 0, 10, nil, AppTypeKea, "", SortDirAny)
+	apps, total, err = GetAppsByPage(db, 0, 10, nil, AppTypeKea, nil, "", SortDirAny)
 	require.NoError(t, err)
 	require.Len(t, apps, 1)
 	require.EqualValues(t, 1, total)
@@ -1134,7 +1134,7 @@ func TestGetAppsByPage(t *testing.T) {
 	require.Empty(t, pt.Key)
 
 	// get bind apps
-	apps, total, err = GetAppsByPage(db, 0, 10, nil, AppTypeBind9, "", SortDirAny)
+	apps, total, err = GetAppsByPage(db, 0, 10, nil, AppTypeBind9, nil, "", SortDirAny)
 	require.NoError(t, err)
 	require.Len(t, apps, 1)
 	require.EqualValues(t, 1, total)
@@ -1149,7 +1149,7 @@ func TestGetAppsByPage(t *testing.T) {
 	require.Equal(t, "abcd", pt.Key)
 
 	// get apps sorted by id descending
-	apps, total, err = GetAppsByPage(db, 0, 10, nil, "", "", SortDirDesc)
+	apps, total, err = GetAppsByPage(db, 0, 10, nil, "", nil, "", SortDirDesc)
 	require.NoError(t, err)
 	require.Len(t, apps, 2)
 	require.EqualValues(t, 2, total)
@@ -1157,7 +1157,7 @@ func TestGetAppsByPage(t *testing.T) {
 	require.Equal(t, AppTypeKea, apps[1].Type)
 
 	// get apps sorted by id ascending
-	apps, total, err = GetAppsByPage(db, 0, 10, nil, "", "", SortDirAsc)
+	apps, total, err = GetAppsByPage(db, 0, 10, nil, "", nil, "", SortDirAsc)
 	require.NoError(t, err)
 	require.Len(t, apps, 2)
 	require.EqualValues(t, 2, total)
@@ -1165,7 +1165,7 @@ func TestGetAppsByPage(t *testing.T) {
 	require.Equal(t, AppTypeBind9, apps[1].Type)
 
 	// get apps sorted by type descending
-	apps, total, err = GetAppsByPage(db, 0, 10, nil, "", "type", SortDirDesc)
+	apps, total, err = GetAppsByPage(db, 0, 10, nil, "", nil, "type", SortDirDesc)
 	require.NoError(t, err)
 	require.Len(t, apps, 2)
 	require.EqualValues(t, 2, total)
@@ -1173,7 +1173,7 @@ func TestGetAppsByPage(t *testing.T) {
 	require.Equal(t, AppTypeBind9, apps[1].Type)
 
 	// get apps sorted by type ascending
-	apps, total, err = GetAppsByPage(db, 0, 10, nil, "", "type", SortDirAsc)
+	apps, total, err = GetAppsByPage(db, 0, 10, nil, "", nil, "type", SortDirAsc)
 	require.NoError(t, err)
 	require.Len(t, apps, 2)
 	require.EqualValues(t, 2, total)
@@ -1182,7 +1182,7 @@ func TestGetAppsByPage(t *testing.T) {
 
 	// get apps by filter text, case 1
 	text := "1.2.3"
-	apps, total, err = GetAppsByPage(db, 0, 10, &text, "", "", SortDirAny)
+	apps, total, err = GetAppsByPage(db, 0, 10, &text, "", nil, "", SortDirAny)
 	require.NoError(t, err)
 	require.Len(t, apps, 1)
 	require.EqualValues(t, 1, total)
@@ -1190,7 +1190,7 @@ func TestGetAppsByPage(t *testing.T) {
 
 	// get apps by filter text, case 2
 	text = "1.2.4"
-	apps, total, err = GetAppsByPage(db, 0, 10, &text, "", "", SortDirAny)
+	apps, total, err = GetAppsByPage(db, 0, 10, &text, "", nil, "", SortDirAny)
 	require.NoError(t, err)
 	require.Len(t, apps, 1)
 	require.EqualValues(t, 1, total)
@@ -1198,7 +1198,7 @@ func TestGetAppsByPage(t *testing.T) {
 
 	// get apps by filter text, case 3
 	text = "unique"
-	apps, total, err = GetAppsByPage(db, 0, 10, &text, "", "", SortDirAsc)
+	apps, total, err = GetAppsByPage(db, 0, 10, &text, "", nil, "", SortDirAsc)
 	require.NoError(t, err)
 	require.Len(t, apps, 2)
 	require.EqualValues(t, 2, total)
@@ -1207,7 +1207,7 @@ func TestGetAppsByPage(t *testing.T) {
 
 	// get apps by filter text, case 4
 	text = "unique-k"
-	apps, total, err = GetAppsByPage(db, 0, 10, &text, "", "", SortDirAsc)
+	apps, total, err = GetAppsByPage(db, 0, 10, &text, "", nil, "", SortDirAsc)
 	require.NoError(t, err)
 	require.Len(t, apps, 1)
 	require.EqualValues(t, 1, total)
@@ -1215,7 +1215,7 @@ func TestGetAppsByPage(t *testing.T) {
 
 	// get apps by filter text, case 5
 	text = "unique-b"
-	apps, total, err = GetAppsByPage(db, 0, 10, &text, "", "", SortDirAsc)
+	apps, total, err = GetAppsByPage(db, 0, 10, &text, "", nil, "", SortDirAsc)
 	require.NoError(t, err)
 	require.Len(t, apps, 1)
 	require.EqualValues(t, 1, total)
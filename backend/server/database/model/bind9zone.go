@@ -0,0 +1,201 @@
+package dbmodel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+)
+
+// DNSSEC signing states of the BIND 9 zones.
+const (
+	Bind9ZoneDNSSECUnsigned     = "unsigned"
+	Bind9ZoneDNSSECSigned       = "signed"
+	Bind9ZoneDNSSECInlineSigned = "inline-signed"
+)
+
+// A structure reflecting a zone served by a BIND 9 daemon. The zones
+// are fetched from the daemon's statistics channel and supplemented with
+// the information returned by rndc. The same zone may appear in several
+// views of the same daemon.
+type Bind9Zone struct {
+	ID       int64
+	DaemonID int64
+	Daemon   *Daemon `pg:"rel:has-one"`
+
+	View     string
+	Name     string
+	Class    string
+	ZoneType string
+	Serial   int64 `pg:",use_zero"`
+
+	// Time when the zone was last loaded or transferred.
+	LoadedAt time.Time
+	// Time of the next refresh of the secondary zone.
+	RefreshAt time.Time
+	// Time when the secondary zone expires unless it is refreshed.
+	ExpiresAt time.Time
+
+	// DNSSEC signing state. It is empty when the state is unknown.
+	DNSSECState    string `pg:"dnssec_state"`
+	KeyMaintenance string
+	NextKeyEventAt time.Time
+
+	// Primary servers of the secondary zone.
+	Primaries []string `pg:",array"`
+
+	UpdatedAt time.Time
+}
+
+// Criteria for filtering the BIND 9 zones. The nil values disable the
+// respective filtering.
+type Bind9ZonesByPageFilters struct {
+	AppID    *int64
+	DaemonID *int64
+	View     *string
+	ZoneType *string
+	Text     *string
+}
+
+// Replaces the zones of the daemon in a transaction. The zones not present
+// in the list are deleted. The existing zones are updated and the new ones
+// are inserted.
+func commitBind9Zones(tx *pg.Tx, daemonID int64, zones []*Bind9Zone) error {
+	q := tx.Model((*Bind9Zone)(nil)).Where("bind9_zone.daemon_id = ?", daemonID)
+	if len(zones) > 0 {
+		var keys []any
+		for _, zone := range zones {
+			keys = append(keys, []any{zone.View, zone.Name, zone.Class})
+		}
+		q = q.Where("(bind9_zone.view, bind9_zone.name, bind9_zone.class) NOT IN (?)", pg.InMulti(keys...))
+	}
+	if _, err := q.Delete(); err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting stale zones of daemon %d", daemonID)
+	}
+	if len(zones) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	for _, zone := range zones {
+		zone.DaemonID = daemonID
+		zone.UpdatedAt = now
+	}
+	_, err := tx.Model(&zones).
+		OnConflict("(daemon_id, view, name, class) DO UPDATE").
+		Set("zone_type = EXCLUDED.zone_type").
+		Set("serial = EXCLUDED.serial").
+		Set("loaded_at = EXCLUDED.loaded_at").
+		Set("refresh_at = EXCLUDED.refresh_at").
+		Set("expires_at = EXCLUDED.expires_at").
+		Set("dnssec_state = EXCLUDED.dnssec_state").
+		Set("key_maintenance = EXCLUDED.key_maintenance").
+		Set("next_key_event_at = EXCLUDED.next_key_event_at").
+		Set("primaries = EXCLUDED.primaries").
+		Set("updated_at = EXCLUDED.updated_at").
+		Returning("id").
+		Insert()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem upserting zones of daemon %d", daemonID)
+	}
+	return nil
+}
+
+// Replaces the zones of the daemon. It begins a new transaction when dbi
+// has a *pg.DB type or uses an existing transaction when dbi has a *pg.Tx
+// type.
+func CommitBind9Zones(dbi dbops.DBI, daemonID int64, zones []*Bind9Zone) error {
+	if db, ok := dbi.(*pg.DB); ok {
+		return db.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+			return commitBind9Zones(tx, daemonID, zones)
+		})
+	}
+	return commitBind9Zones(dbi.(*pg.Tx), daemonID, zones)
+}
+
+// Fetches the zone by ID with the daemon, app and machine. It returns nil
+// if the zone does not exist.
+func GetBind9ZoneByID(dbi dbops.DBI, id int64) (*Bind9Zone, error) {
+	zone := &Bind9Zone{}
+	err := dbi.Model(zone).
+		Relation("Daemon.App.Machine").
+		Where("bind9_zone.id = ?", id).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting zone with ID %d", id)
+	}
+	return zone, nil
+}
+
+// Fetches all zones of the daemon ordered by view and name.
+func GetBind9ZonesByDaemonID(dbi dbops.DBI, daemonID int64) ([]Bind9Zone, error) {
+	zones := []Bind9Zone{}
+	err := dbi.Model(&zones).
+		Where("bind9_zone.daemon_id = ?", daemonID).
+		OrderExpr("bind9_zone.view ASC").
+		OrderExpr("bind9_zone.name ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrapf(err, "problem getting zones of daemon %d", daemonID)
+	}
+	return zones, nil
+}
+
+// Fetches a page of the zones matching the filters. The offset and limit
+// specify the beginning of the page and the maximum size of the page. The
+// text filter matches the zone name, view, type and primaries. If sortField
+// is empty, the zones are sorted by ID. It returns the zones, the total
+// number of the zones matching the filters and an error.
+func GetBind9ZonesByPage(dbi dbops.DBI, offset, limit int64, filters *Bind9ZonesByPageFilters, sortField string, sortDir SortDirEnum) ([]Bind9Zone, int64, error) {
+	if limit == 0 {
+		return nil, 0, pkgerrors.New("limit should be greater than 0")
+	}
+	zones := []Bind9Zone{}
+	q := dbi.Model(&zones).Relation("Daemon.App.Machine")
+
+	if filters != nil {
+		if filters.AppID != nil {
+			q = q.Where("daemon.app_id = ?", *filters.AppID)
+		}
+		if filters.DaemonID != nil {
+			q = q.Where("bind9_zone.daemon_id = ?", *filters.DaemonID)
+		}
+		if filters.View != nil {
+			q = q.Where("bind9_zone.view = ?", *filters.View)
+		}
+		if filters.ZoneType != nil {
+			q = q.Where("bind9_zone.zone_type = ?", *filters.ZoneType)
+		}
+		if filters.Text != nil {
+			text := "%" + *filters.Text + "%"
+			q = q.WhereGroup(func(qq *orm.Query) (*orm.Query, error) {
+				qq = qq.WhereOr("bind9_zone.name ILIKE ?", text)
+				qq = qq.WhereOr("bind9_zone.view ILIKE ?", text)
+				qq = qq.WhereOr("bind9_zone.zone_type ILIKE ?", text)
+				qq = qq.WhereOr("array_to_string(bind9_zone.primaries, ' ') ILIKE ?", text)
+				return qq, nil
+			})
+		}
+	}
+
+	ordExpr := prepareOrderExpr("bind9_zone", sortField, sortDir)
+	q = q.OrderExpr(ordExpr)
+	q = q.Offset(int(offset))
+	q = q.Limit(int(limit))
+
+	total, err := q.SelectAndCount()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return []Bind9Zone{}, 0, nil
+		}
+		return nil, 0, pkgerrors.Wrapf(err, "problem getting zones")
+	}
+	return zones, int64(total), nil
+}
@@ -0,0 +1,210 @@
+package dbmodel
+
+import (
+	"testing"
+	"time"
+
+	require "github.com/stretchr/testify/require"
+	dbops "isc.org/stork/server/database"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Adds a machine with a BIND 9 app and returns the app.
+func addTestBind9App(t *testing.T, db *dbops.PgDB, address string) *App {
+	m := &Machine{
+		Address:   address,
+		AgentPort: 8080,
+	}
+	err := AddMachine(db, m)
+	require.NoError(t, err)
+
+	app := &App{
+		MachineID: m.ID,
+		Type:      AppTypeBind9,
+		Name:      "bind9@" + address,
+		Daemons: []*Daemon{
+			NewBind9Daemon(true),
+		},
+	}
+	_, err = AddApp(db, app)
+	require.NoError(t, err)
+	return app
+}
+
+// Returns the zones used in the tests.
+func createTestBind9Zones() []*Bind9Zone {
+	loaded := time.Date(2023, 3, 14, 9, 35, 10, 0, time.UTC)
+	return []*Bind9Zone{
+		{
+			View:        "_default",
+			Name:        "example.com",
+			Class:       "IN",
+			ZoneType:    "primary",
+			Serial:      2023031401,
+			LoadedAt:    loaded,
+			DNSSECState: Bind9ZoneDNSSECSigned,
+		},
+		{
+			View:      "internal",
+			Name:      "example.org",
+			Class:     "IN",
+			ZoneType:  "secondary",
+			Serial:    7,
+			LoadedAt:  loaded,
+			RefreshAt: loaded.Add(time.Hour),
+			ExpiresAt: loaded.Add(24 * time.Hour),
+			Primaries: []string{"192.0.2.1", "192.0.2.2"},
+		},
+	}
+}
+
+// Test that the zones of the daemon are inserted, updated and deleted.
+func TestCommitBind9Zones(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestBind9App(t, db, "localhost")
+	daemonID := app.Daemons[0].ID
+
+	zones := createTestBind9Zones()
+	err := CommitBind9Zones(db, daemonID, zones)
+	require.NoError(t, err)
+	require.NotZero(t, zones[0].ID)
+	require.NotZero(t, zones[1].ID)
+
+	returned, err := GetBind9ZonesByDaemonID(db, daemonID)
+	require.NoError(t, err)
+	require.Len(t, returned, 2)
+	require.Equal(t, "example.com", returned[0].Name)
+	require.EqualValues(t, 2023031401, returned[0].Serial)
+	require.Equal(t, Bind9ZoneDNSSECSigned, returned[0].DNSSECState)
+	require.Equal(t, time.Date(2023, 3, 14, 9, 35, 10, 0, time.UTC), returned[0].LoadedAt)
+	require.Equal(t, "example.org", returned[1].Name)
+	require.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, returned[1].Primaries)
+	require.NotZero(t, returned[1].UpdatedAt)
+
+	// Update the first zone and remove the second one. The ID of the
+	// updated zone should be preserved.
+	id := returned[0].ID
+	zones = createTestBind9Zones()[:1]
+	zones[0].Serial = 2023031402
+	err = CommitBind9Zones(db, daemonID, zones)
+	require.NoError(t, err)
+
+	returned, err = GetBind9ZonesByDaemonID(db, daemonID)
+	require.NoError(t, err)
+	require.Len(t, returned, 1)
+	require.Equal(t, id, returned[0].ID)
+	require.EqualValues(t, 2023031402, returned[0].Serial)
+
+	// Remove all zones.
+	err = CommitBind9Zones(db, daemonID, []*Bind9Zone{})
+	require.NoError(t, err)
+
+	returned, err = GetBind9ZonesByDaemonID(db, daemonID)
+	require.NoError(t, err)
+	require.Empty(t, returned)
+}
+
+// Test that the zones are deleted together with the daemon.
+func TestDeleteBind9ZonesWithApp(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestBind9App(t, db, "localhost")
+	err := CommitBind9Zones(db, app.Daemons[0].ID, createTestBind9Zones())
+	require.NoError(t, err)
+
+	err = DeleteApp(db, app)
+	require.NoError(t, err)
+
+	zones, err := GetBind9ZonesByDaemonID(db, app.Daemons[0].ID)
+	require.NoError(t, err)
+	require.Empty(t, zones)
+}
+
+// Test getting the zone by ID.
+func TestGetBind9ZoneByID(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestBind9App(t, db, "localhost")
+	zones := createTestBind9Zones()
+	err := CommitBind9Zones(db, app.Daemons[0].ID, zones)
+	require.NoError(t, err)
+
+	zone, err := GetBind9ZoneByID(db, zones[1].ID)
+	require.NoError(t, err)
+	require.NotNil(t, zone)
+	require.Equal(t, "example.org", zone.Name)
+	require.NotNil(t, zone.Daemon)
+	require.NotNil(t, zone.Daemon.App)
+	require.Equal(t, app.Name, zone.Daemon.App.Name)
+	require.NotNil(t, zone.Daemon.App.Machine)
+	require.Equal(t, "localhost", zone.Daemon.App.Machine.Address)
+
+	zone, err = GetBind9ZoneByID(db, zones[1].ID+100)
+	require.NoError(t, err)
+	require.Nil(t, zone)
+}
+
+// Test getting the zones by page with filtering.
+func TestGetBind9ZonesByPage(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app1 := addTestBind9App(t, db, "host1")
+	err := CommitBind9Zones(db, app1.Daemons[0].ID, createTestBind9Zones())
+	require.NoError(t, err)
+	app2 := addTestBind9App(t, db, "host2")
+	err = CommitBind9Zones(db, app2.Daemons[0].ID, createTestBind9Zones())
+	require.NoError(t, err)
+
+	t.Run("no filters", func(t *testing.T) {
+		zones, total, err := GetBind9ZonesByPage(db, 0, 3, nil, "", SortDirAny)
+		require.NoError(t, err)
+		require.EqualValues(t, 4, total)
+		require.Len(t, zones, 3)
+		require.NotNil(t, zones[0].Daemon)
+		require.NotNil(t, zones[0].Daemon.App)
+		require.NotNil(t, zones[0].Daemon.App.Machine)
+	})
+
+	t.Run("app", func(t *testing.T) {
+		zones, total, err := GetBind9ZonesByPage(db, 0, 10, &Bind9ZonesByPageFilters{AppID: &app2.ID}, "", SortDirAny)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, total)
+		require.Len(t, zones, 2)
+		require.Equal(t, app2.ID, zones[0].Daemon.AppID)
+		require.Equal(t, app2.ID, zones[1].Daemon.AppID)
+	})
+
+	t.Run("view and type", func(t *testing.T) {
+		view := "internal"
+		zoneType := "secondary"
+		zones, total, err := GetBind9ZonesByPage(db, 0, 10, &Bind9ZonesByPageFilters{View: &view, ZoneType: &zoneType}, "", SortDirAny)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, total)
+		require.Len(t, zones, 2)
+		require.Equal(t, "example.org", zones[0].Name)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		text := "192.0.2.2"
+		zones, total, err := GetBind9ZonesByPage(db, 0, 10, &Bind9ZonesByPageFilters{Text: &text}, "", SortDirAny)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, total)
+		require.Equal(t, "example.org", zones[0].Name)
+
+		text = "EXAMPLE.COM"
+		zones, total, err = GetBind9ZonesByPage(db, 0, 10, &Bind9ZonesByPageFilters{Text: &text}, "name", SortDirDesc)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, total)
+		require.Equal(t, "example.com", zones[0].Name)
+	})
+
+	t.Run("zero limit", func(t *testing.T) {
+		_, _, err := GetBind9ZonesByPage(db, 0, 0, nil, "", SortDirAny)
+		require.Error(t, err)
+	})
+}
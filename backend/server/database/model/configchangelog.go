@@ -0,0 +1,139 @@
+package dbmodel
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+	storkutil "isc.org/stork/util"
+)
+
+// Represents an entry of the audit log of the configuration changes
+// committed by the config manager. Each entry pertains to a single config
+// update, e.g., a host reservation update. It holds the user who made the
+// change, the daemons to which the change was applied, the recipe (the
+// commands sent to the daemons) and the configured object's state before
+// and after the change.
+type ConfigChangeLogEntry struct {
+	tableName struct{} `pg:"config_change_log"` //nolint:unused
+
+	ID        int64
+	CreatedAt time.Time
+
+	// User who made the change. It is zero when the user has been deleted.
+	UserID int64
+	User   *SystemUser `pg:"rel:has-one"`
+	// User's login or email at the time of the change. It is preserved
+	// when the user is deleted.
+	UserIdentity string
+
+	// Type of the configured daemon, e.g. "kea".
+	Target AppType
+	// Type of the operation, e.g. "host_add".
+	Operation string
+	// Identifiers of the daemons affected by the change.
+	DaemonIDs []int64 `pg:",array"`
+
+	// Type of the configured object, e.g. "host", "subnet".
+	ObjectType string
+	// ID of the configured object. It is zero when the change does not
+	// pertain to a particular object (e.g., global parameters update).
+	ObjectID int64
+
+	// Indicates if the change was scheduled and committed later.
+	Scheduled bool
+	// Deadline of the scheduled change. It is only set in the entry
+	// recorded when the change is scheduled. The change is recorded
+	// again when it is committed.
+	DeadlineAt time.Time
+
+	// Commands sent to the daemons.
+	Recipe any
+	// Configured object's state before and after the change.
+	StateBefore any
+	StateAfter  any
+	// Differences between the state before and after the change.
+	Diff []storkutil.JSONDifference
+
+	// Error returned when committing the change. It is empty when the
+	// change was committed successfully.
+	Error string
+}
+
+// Criteria for filtering the config change log entries. The nil values
+// disable the respective filtering.
+type ConfigChangeLogFilter struct {
+	UserID     *int64
+	DaemonID   *int64
+	ObjectType *string
+	ObjectID   *int64
+}
+
+// Inserts the config change log entry into the database.
+func AddConfigChangeLogEntry(dbi dbops.DBI, entry *ConfigChangeLogEntry) error {
+	if _, err := dbi.Model(entry).Insert(); err != nil {
+		return pkgerrors.Wrapf(err, "problem adding config change log entry")
+	}
+	return nil
+}
+
+// Fetches the config change log entry by ID. It returns nil if the entry
+// does not exist.
+func GetConfigChangeLogEntryByID(dbi dbops.DBI, id int64) (*ConfigChangeLogEntry, error) {
+	entry := &ConfigChangeLogEntry{}
+	err := dbi.Model(entry).
+		Relation("User").
+		Where("config_change_log_entry.id = ?", id).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting config change log entry with ID %d", id)
+	}
+	return entry, nil
+}
+
+// Fetches a page of the config change log entries matching the filter.
+// The offset and limit specify the beginning of the page and the maximum
+// size of the page. If sortField is empty, the entries are sorted by ID.
+// It returns the entries, the total number of the entries matching the
+// filter and an error.
+func GetConfigChangeLogEntriesByPage(dbi dbops.DBI, offset, limit int64, filter *ConfigChangeLogFilter, sortField string, sortDir SortDirEnum) ([]ConfigChangeLogEntry, int64, error) {
+	if limit == 0 {
+		return nil, 0, pkgerrors.New("limit should be greater than 0")
+	}
+	entries := []ConfigChangeLogEntry{}
+	q := dbi.Model(&entries).Relation("User")
+
+	if filter != nil {
+		if filter.UserID != nil {
+			q = q.Where("config_change_log_entry.user_id = ?", *filter.UserID)
+		}
+		if filter.DaemonID != nil {
+			q = q.Where("? = ANY(config_change_log_entry.daemon_ids)", *filter.DaemonID)
+		}
+		if filter.ObjectType != nil {
+			q = q.Where("config_change_log_entry.object_type = ?", *filter.ObjectType)
+		}
+		if filter.ObjectID != nil {
+			q = q.Where("config_change_log_entry.object_id = ?", *filter.ObjectID)
+		}
+	}
+
+	ordExpr := prepareOrderExpr("config_change_log_entry", sortField, sortDir)
+	q = q.OrderExpr(ordExpr)
+	q = q.Offset(int(offset))
+	q = q.Limit(int(limit))
+
+	total, err := q.SelectAndCount()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return []ConfigChangeLogEntry{}, 0, nil
+		}
+		return nil, 0, pkgerrors.Wrapf(err, "problem getting config change log entries")
+	}
+	return entries, int64(total), nil
+}
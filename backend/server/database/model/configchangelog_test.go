@@ -0,0 +1,161 @@
+package dbmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+	storkutil "isc.org/stork/util"
+)
+
+// Test adding and fetching the config change log entries.
+func TestAddGetConfigChangeLogEntries(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	user := &SystemUser{
+		Login:    "test",
+		Lastname: "test",
+		Name:     "test",
+	}
+	_, err := CreateUserWithPassword(db, user, "test")
+	require.NoError(t, err)
+
+	entries := []*ConfigChangeLogEntry{
+		{
+			UserID:       int64(user.ID),
+			UserIdentity: user.Identity(),
+			Target:       AppTypeKea,
+			Operation:    "host_update",
+			DaemonIDs:    []int64{1, 2},
+			ObjectType:   "host",
+			ObjectID:     10,
+			Recipe:       []any{map[string]any{"command": "reservation-add"}},
+			StateBefore:  map[string]any{"1": map[string]any{"hostname": "foo"}},
+			StateAfter:   map[string]any{"1": map[string]any{"hostname": "bar"}},
+			Diff: []storkutil.JSONDifference{
+				{
+					Path:   "/1/hostname",
+					Before: "foo",
+					After:  "bar",
+				},
+			},
+		},
+		{
+			UserIdentity: "deleted",
+			Target:       AppTypeKea,
+			Operation:    "subnet_delete",
+			DaemonIDs:    []int64{2},
+			ObjectType:   "subnet",
+			ObjectID:     20,
+			Error:        "failed",
+			Scheduled:    true,
+			DeadlineAt:   time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, entry := range entries {
+		err = AddConfigChangeLogEntry(db, entry)
+		require.NoError(t, err)
+		require.NotZero(t, entry.ID)
+	}
+
+	// Get by ID.
+	entry, err := GetConfigChangeLogEntryByID(db, entries[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	require.NotZero(t, entry.CreatedAt)
+	require.EqualValues(t, user.ID, entry.UserID)
+	require.NotNil(t, entry.User)
+	require.Equal(t, "test", entry.User.Login)
+	require.Equal(t, []int64{1, 2}, entry.DaemonIDs)
+	require.Len(t, entry.Diff, 1)
+	require.Equal(t, "/1/hostname", entry.Diff[0].Path)
+	require.Equal(t, "foo", entry.Diff[0].Before)
+	require.NotNil(t, entry.StateBefore)
+	require.NotNil(t, entry.Recipe)
+	require.Zero(t, entry.DeadlineAt)
+
+	entry, err = GetConfigChangeLogEntryByID(db, entries[1].ID)
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	require.Zero(t, entry.UserID)
+	require.Nil(t, entry.User)
+	require.Equal(t, "failed", entry.Error)
+	require.True(t, entry.Scheduled)
+	require.Equal(t, time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), entry.DeadlineAt)
+
+	// Non-existing entry.
+	entry, err = GetConfigChangeLogEntryByID(db, 1024)
+	require.NoError(t, err)
+	require.Nil(t, entry)
+
+	// Get all entries.
+	returned, total, err := GetConfigChangeLogEntriesByPage(db, 0, 10, nil, "", SortDirAny)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Len(t, returned, 2)
+
+	// Filter by user.
+	userID := int64(user.ID)
+	returned, total, err = GetConfigChangeLogEntriesByPage(db, 0, 10, &ConfigChangeLogFilter{UserID: &userID}, "", SortDirAny)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, entries[0].ID, returned[0].ID)
+
+	// Filter by daemon.
+	daemonID := int64(2)
+	returned, total, err = GetConfigChangeLogEntriesByPage(db, 0, 10, &ConfigChangeLogFilter{DaemonID: &daemonID}, "", SortDirDesc)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Equal(t, entries[1].ID, returned[0].ID)
+
+	// Filter by object.
+	objectType := "subnet"
+	objectID := int64(20)
+	returned, total, err = GetConfigChangeLogEntriesByPage(db, 0, 10, &ConfigChangeLogFilter{ObjectType: &objectType, ObjectID: &objectID}, "", SortDirAny)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Equal(t, entries[1].ID, returned[0].ID)
+
+	// Paging.
+	returned, total, err = GetConfigChangeLogEntriesByPage(db, 1, 1, nil, "", SortDirAny)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Len(t, returned, 1)
+	require.Equal(t, entries[1].ID, returned[0].ID)
+
+	// Zero limit is not allowed.
+	_, _, err = GetConfigChangeLogEntriesByPage(db, 0, 0, nil, "", SortDirAny)
+	require.Error(t, err)
+}
+
+// Test that the config change log entry is preserved when the user is
+// deleted.
+func TestConfigChangeLogEntryUserDeleted(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	user := &SystemUser{
+		Login:    "test",
+		Lastname: "test",
+		Name:     "test",
+	}
+	_, err := CreateUserWithPassword(db, user, "test")
+	require.NoError(t, err)
+
+	entry := &ConfigChangeLogEntry{
+		UserID:       int64(user.ID),
+		UserIdentity: user.Identity(),
+		Target:       AppTypeKea,
+		Operation:    "host_add",
+	}
+	require.NoError(t, AddConfigChangeLogEntry(db, entry))
+	require.NoError(t, DeleteUser(db, user))
+
+	returned, err := GetConfigChangeLogEntryByID(db, entry.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Zero(t, returned.UserID)
+	require.Equal(t, "test", returned.UserIdentity)
+}
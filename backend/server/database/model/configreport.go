@@ -2,6 +2,7 @@ package dbmodel
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/go-pg/pg/v10/orm"
 	pkgerrors "github.com/pkg/errors"
 	dbops "isc.org/stork/server/database"
+	storkutil "isc.org/stork/util"
 )
 
 // Registers M:N SQL relations defined in this file.
@@ -28,6 +30,9 @@ type ConfigReport struct {
 
 	DaemonID int64
 
+	// Optional fix for the issue found by the checker.
+	Fix *ConfigReportFix
+
 	RefDaemons []*Daemon `pg:"many2many:daemon_to_config_report,fk:config_report_id,join_fk:daemon_id"`
 }
 
@@ -36,6 +41,37 @@ func (r *ConfigReport) IsIssueFound() bool {
 	return r.Content != nil
 }
 
+// Structure representing a machine-applicable fix for the issue described
+// in a config report. The fix is a JSON patch modifying the configuration
+// of the daemon for which the report was generated. The patch is valid only
+// for the configuration it was generated for, so the fix holds the hash of
+// this configuration.
+type ConfigReportFix struct {
+	// Human-readable description of the changes made by the fix.
+	Description string
+	// Hash of the daemon configuration for which the fix was generated.
+	ConfigHash string
+	// Operations modifying the daemon configuration.
+	Patch []storkutil.JSONPatchOperation
+}
+
+// Applies the fix to a copy of the specified configuration and returns
+// the modified configuration. The original configuration is not modified.
+func (fix *ConfigReportFix) Apply(config *KeaConfig) (*KeaConfig, error) {
+	if config == nil || config.Config == nil {
+		return nil, pkgerrors.New("no configuration to fix")
+	}
+	marshalled, err := json.Marshal(config)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "problem marshalling configuration to fix")
+	}
+	patched, err := storkutil.ApplyJSONPatch(marshalled, fix.Patch)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeaConfigFromJSON(string(patched))
+}
+
 // Structure representing a many-to-many relationship between daemons
 // and config reports.
 type DaemonToConfigReport struct {
@@ -143,6 +179,26 @@ func GetConfigReportsByDaemonID(db *pg.DB, offset, limit int64, daemonID int64,
 	return configReports, int64(total), nil
 }
 
+// Selects the config report by ID. It returns nil if the report doesn't
+// exist.
+func GetConfigReportByID(dbi dbops.DBI, reportID int64) (*ConfigReport, error) {
+	configReport := &ConfigReport{}
+	err := dbi.Model(configReport).
+		Where("config_report.id = ?", reportID).
+		Relation("RefDaemons", func(q *orm.Query) (*orm.Query, error) {
+			return q.Order("daemon_to_config_report.order_index ASC"), nil
+		}).
+		Relation("RefDaemons.App").
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem selecting config report with ID %d", reportID)
+	}
+	return configReport, nil
+}
+
 // Counts the total number of config reports. Accepts the same filters as
 // GetConfigReportsByDaemonID.
 func CountConfigReportsByDaemonID(db *pg.DB, daemonID int64, issuesOnly bool) (int64, error) {
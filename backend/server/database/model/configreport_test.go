@@ -6,6 +6,7 @@ import (
 
 	require "github.com/stretchr/testify/require"
 	dbtest "isc.org/stork/server/database/test"
+	storkutil "isc.org/stork/util"
 )
 
 // Returns a pointer to a given value. It is helpful to create a pointer from
@@ -448,6 +449,97 @@ func TestConfigReportDeleteNonExisting(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// Test that the config report holding a fix is stored in the database and
+// can be fetched by ID.
+func TestGetConfigReportByIDWithFix(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	machine := &Machine{
+		Address:   "localhost",
+		AgentPort: 8080,
+	}
+	err := AddMachine(db, machine)
+	require.NoError(t, err)
+
+	app := &App{
+		Type:      AppTypeKea,
+		MachineID: machine.ID,
+		Daemons: []*Daemon{
+			NewKeaDaemon("dhcp4", true),
+		},
+	}
+	daemons, err := AddApp(db, app)
+	require.NoError(t, err)
+
+	configReport := &ConfigReport{
+		CheckerName: "test",
+		Content:     newPtr("Here is the test report for {daemon}"),
+		DaemonID:    daemons[0].ID,
+		RefDaemons:  daemons,
+		Fix: &ConfigReportFix{
+			Description: "Set the valid lifetime.",
+			ConfigHash:  "1234",
+			Patch: []storkutil.JSONPatchOperation{
+				{Op: "add", Path: "/Dhcp4/valid-lifetime", Value: 3600},
+			},
+		},
+	}
+	err = AddConfigReport(db, configReport)
+	require.NoError(t, err)
+
+	returned, err := GetConfigReportByID(db, configReport.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, "test", returned.CheckerName)
+	require.Len(t, returned.RefDaemons, 1)
+	require.NotNil(t, returned.Fix)
+	require.Equal(t, "Set the valid lifetime.", returned.Fix.Description)
+	require.Equal(t, "1234", returned.Fix.ConfigHash)
+	require.Len(t, returned.Fix.Patch, 1)
+	require.Equal(t, "/Dhcp4/valid-lifetime", returned.Fix.Patch[0].Path)
+
+	// Non-existing report.
+	returned, err = GetConfigReportByID(db, configReport.ID+1)
+	require.NoError(t, err)
+	require.Nil(t, returned)
+}
+
+// Test that the fix is applied to a copy of the configuration.
+func TestApplyConfigReportFix(t *testing.T) {
+	config, err := NewKeaConfigFromJSON(`{
+		"Dhcp4": {
+			"valid-lifetime": 1800,
+			"subnet4": [ { "id": 1, "subnet": "192.0.2.1/24" } ]
+		}
+	}`)
+	require.NoError(t, err)
+
+	fix := &ConfigReportFix{
+		Patch: []storkutil.JSONPatchOperation{
+			{Op: "replace", Path: "/Dhcp4/subnet4/0/subnet", Value: "192.0.2.0/24"},
+			{Op: "remove", Path: "/Dhcp4/valid-lifetime"},
+		},
+	}
+	fixed, err := fix.Apply(config)
+	require.NoError(t, err)
+	require.NotNil(t, fixed)
+	require.Equal(t, "192.0.2.0/24", fixed.GetSubnets()[0].GetPrefix())
+	require.NotContains(t, fixed.Raw["Dhcp4"], "valid-lifetime")
+
+	// The original configuration is not modified.
+	require.Equal(t, "192.0.2.1/24", config.GetSubnets()[0].GetPrefix())
+	require.Contains(t, config.Raw["Dhcp4"], "valid-lifetime")
+
+	// The fix can't be applied when the configuration does not match.
+	fix.Patch[0].Path = "/Dhcp4/subnet4/1/subnet"
+	_, err = fix.Apply(config)
+	require.Error(t, err)
+
+	_, err = fix.Apply(nil)
+	require.Error(t, err)
+}
+
 // This test verifies that it is possible to delete a daemon
 // having configuration reviews.
 func TestDeleteAppWithConfigReview(t *testing.T) {
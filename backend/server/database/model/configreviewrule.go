@@ -0,0 +1,115 @@
+package dbmodel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+)
+
+// Represents a user-defined config review rule. The rule is an expression
+// evaluated over the configurations of the daemons matching the selector.
+// The scope designates the configuration items the expression is evaluated
+// for, e.g., each subnet or each pool. The rule is reported as violated
+// when the expression evaluates to false for any of these items. The rule
+// name is used as the checker name by the config review dispatcher, so it
+// can't be changed after the rule is created.
+type ConfigReviewRule struct {
+	ID        int64
+	CreatedAt time.Time
+
+	Name        string
+	Description string
+	Selector    string
+	Scope       string
+	Expression  string
+}
+
+// Inserts a config review rule into the database.
+func AddConfigReviewRule(dbi dbops.DBI, rule *ConfigReviewRule) error {
+	_, err := dbi.Model(rule).Insert()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem inserting config review rule %s", rule.Name)
+	}
+	return err
+}
+
+// Updates the config review rule in the database. The rule name is not
+// updated.
+func UpdateConfigReviewRule(dbi dbops.DBI, rule *ConfigReviewRule) error {
+	result, err := dbi.Model(rule).ExcludeColumn("created_at", "name").WherePK().Returning("*").Update()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem updating config review rule with id %d", rule.ID)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "config review rule with id %d does not exist", rule.ID)
+	}
+	return nil
+}
+
+// Returns all config review rules ordered by name.
+func GetConfigReviewRules(dbi dbops.DBI) ([]ConfigReviewRule, error) {
+	rules := []ConfigReviewRule{}
+	err := dbi.Model(&rules).OrderExpr("name ASC").Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting config review rules")
+	}
+	return rules, nil
+}
+
+// Returns the config review rule by ID. It returns nil if the rule does
+// not exist.
+func GetConfigReviewRuleByID(dbi dbops.DBI, id int64) (*ConfigReviewRule, error) {
+	rule := &ConfigReviewRule{}
+	err := dbi.Model(rule).Where("id = ?", id).Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting config review rule with id %d", id)
+	}
+	return rule, nil
+}
+
+// Deletes the config review rule and the checker preferences referring to
+// it.
+func deleteConfigReviewRule(dbi dbops.DBI, rule *ConfigReviewRule) error {
+	result, err := dbi.Model(rule).WherePK().Returning("*").Delete()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting config review rule with id %d", rule.ID)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "config review rule with id %d does not exist", rule.ID)
+	}
+	_, err = dbi.Model((*ConfigCheckerPreference)(nil)).
+		Where("checker_name = ?", rule.Name).
+		Delete()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting checker preferences of config review rule %s", rule.Name)
+	}
+	return nil
+}
+
+// Deletes the config review rule from the database along with the checker
+// preferences referring to it. It returns the deleted rule. The transaction
+// is created if needed.
+func DeleteConfigReviewRule(dbi dbops.DBI, id int64) (*ConfigReviewRule, error) {
+	rule := &ConfigReviewRule{
+		ID: id,
+	}
+	var err error
+	if db, ok := dbi.(*pg.DB); ok {
+		err = db.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+			return deleteConfigReviewRule(tx, rule)
+		})
+	} else {
+		err = deleteConfigReviewRule(dbi, rule)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
@@ -0,0 +1,96 @@
+package dbmodel
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test adding, updating, getting and deleting the config review rules.
+func TestConfigReviewRules(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	rule1 := &ConfigReviewRule{
+		Name:        "subnet_routers",
+		Description: "every subnet must have option routers",
+		Selector:    "kea-dhcp-v4-daemon",
+		Scope:       "subnet",
+		Expression:  "any(subnet['option-data'], item.name == 'routers')",
+	}
+	err := AddConfigReviewRule(db, rule1)
+	require.NoError(t, err)
+	require.NotZero(t, rule1.ID)
+
+	rule2 := &ConfigReviewRule{
+		Name:       "lifetime",
+		Selector:   "kea-dhcp-daemon",
+		Scope:      "global",
+		Expression: "config['valid-lifetime'] >= 3600",
+	}
+	err = AddConfigReviewRule(db, rule2)
+	require.NoError(t, err)
+
+	// The rule names must be unique.
+	err = AddConfigReviewRule(db, &ConfigReviewRule{
+		Name:       "lifetime",
+		Selector:   "kea-dhcp-daemon",
+		Scope:      "global",
+		Expression: "true",
+	})
+	require.Error(t, err)
+
+	rules, err := GetConfigReviewRules(db)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Equal(t, "lifetime", rules[0].Name)
+	require.Empty(t, rules[0].Description)
+	require.Equal(t, "subnet_routers", rules[1].Name)
+	require.Equal(t, "every subnet must have option routers", rules[1].Description)
+	require.Equal(t, "kea-dhcp-v4-daemon", rules[1].Selector)
+	require.Equal(t, "subnet", rules[1].Scope)
+	require.NotZero(t, rules[1].CreatedAt)
+
+	// Update the rule. The name is not updated.
+	rule2.Name = "other"
+	rule2.Expression = "config['valid-lifetime'] >= 7200"
+	err = UpdateConfigReviewRule(db, rule2)
+	require.NoError(t, err)
+	require.Equal(t, "lifetime", rule2.Name)
+
+	rule, err := GetConfigReviewRuleByID(db, rule2.ID)
+	require.NoError(t, err)
+	require.NotNil(t, rule)
+	require.Equal(t, "lifetime", rule.Name)
+	require.Equal(t, "config['valid-lifetime'] >= 7200", rule.Expression)
+
+	// Add the preferences for the rule checker.
+	err = CommitCheckerPreferences(db, []*ConfigCheckerPreference{
+		NewGlobalConfigCheckerPreference("lifetime"),
+		NewGlobalConfigCheckerPreference("subnet_routers"),
+	}, nil)
+	require.NoError(t, err)
+
+	// Delete the rule.
+	rule, err = DeleteConfigReviewRule(db, rule2.ID)
+	require.NoError(t, err)
+	require.NotNil(t, rule)
+	require.Equal(t, "lifetime", rule.Name)
+
+	rule, err = GetConfigReviewRuleByID(db, rule2.ID)
+	require.NoError(t, err)
+	require.Nil(t, rule)
+
+	// The preferences of the deleted rule should be deleted too.
+	preferences, err := GetAllCheckerPreferences(db)
+	require.NoError(t, err)
+	require.Len(t, preferences, 1)
+	require.Equal(t, "subnet_routers", preferences[0].CheckerName)
+
+	// The rule no longer exists.
+	_, err = DeleteConfigReviewRule(db, rule2.ID)
+	require.ErrorIs(t, err, ErrNotExists)
+	err = UpdateConfigReviewRule(db, rule2)
+	require.ErrorIs(t, err, ErrNotExists)
+}
@@ -74,8 +74,9 @@ type Bind9StatsResolver struct {
 
 // A structure holding named view statistics.
 type Bind9StatsView struct {
-	Zones    []*Bind9StatsZone
-	Resolver *Bind9StatsResolver
+	Zones     []*Bind9StatsZone
+	ZoneCount int64
+	Resolver  *Bind9StatsResolver
 }
 
 // A structure holding named socket statistics.
@@ -34,11 +34,12 @@ func (t EventLevel) String() string {
 
 // Relations between the event and other entities.
 type Relations struct {
-	MachineID int64 `json:",omitempty"`
-	AppID     int64 `json:",omitempty"`
-	SubnetID  int64 `json:",omitempty"`
-	DaemonID  int64 `json:",omitempty"`
-	UserID    int64 `json:",omitempty"`
+	MachineID       int64 `json:",omitempty"`
+	AppID           int64 `json:",omitempty"`
+	SubnetID        int64 `json:",omitempty"`
+	SharedNetworkID int64 `json:",omitempty"`
+	DaemonID        int64 `json:",omitempty"`
+	UserID          int64 `json:",omitempty"`
 }
 
 // Represents an event held in event table in the database.
@@ -0,0 +1,128 @@
+package dbmodel
+
+import (
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+)
+
+// Names of the settings controlling the exhaustion forecasts. The window
+// is the period of the utilization history used to forecast the
+// exhaustion. The horizon is the number of days; a forecasted exhaustion
+// within the horizon raises a warning. Both are specified in days.
+const (
+	UtilizationForecastWindowSetting  = "utilization_forecast_window"
+	UtilizationForecastHorizonSetting = "utilization_forecast_horizon"
+)
+
+// Default exhaustion forecast window. It is used when the setting is
+// missing or not positive.
+const DefaultUtilizationForecastWindow = 7 * 24 * time.Hour
+
+// Returns the period of the utilization history used to forecast the
+// exhaustion.
+func GetUtilizationForecastWindow(db *pg.DB) time.Duration {
+	value, err := GetSettingInt(db, UtilizationForecastWindowSetting)
+	if err != nil || value <= 0 {
+		return DefaultUtilizationForecastWindow
+	}
+	return time.Duration(value) * 24 * time.Hour
+}
+
+// Returns the exhaustion forecast horizon in days. The zero value
+// disables the exhaustion warnings.
+func GetUtilizationForecastHorizon(db *pg.DB) float64 {
+	value, err := GetSettingInt(db, UtilizationForecastHorizonSetting)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return float64(value)
+}
+
+// Sets the forecasted numbers of days remaining until the addresses and
+// the delegated prefixes are exhausted in the subnet. The nil values
+// indicate that the exhaustion is not forecasted.
+func SetSubnetExhaustionForecast(dbi dbops.DBI, subnetID int64, addrDays, pdDays *float64) error {
+	subnet := &Subnet{
+		ID:                 subnetID,
+		AddrExhaustionDays: addrDays,
+		PdExhaustionDays:   pdDays,
+	}
+	result, err := dbi.Model(subnet).
+		Column("addr_exhaustion_days", "pd_exhaustion_days").
+		WherePK().
+		Update()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem updating exhaustion forecast in the subnet: %d", subnetID)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "subnet with ID %d does not exist", subnetID)
+	}
+	return nil
+}
+
+// Sets the forecasted numbers of days remaining until the addresses and
+// the delegated prefixes are exhausted in the shared network. The nil
+// values indicate that the exhaustion is not forecasted.
+func SetSharedNetworkExhaustionForecast(dbi dbops.DBI, sharedNetworkID int64, addrDays, pdDays *float64) error {
+	network := &SharedNetwork{
+		ID:                 sharedNetworkID,
+		AddrExhaustionDays: addrDays,
+		PdExhaustionDays:   pdDays,
+	}
+	result, err := dbi.Model(network).
+		Column("addr_exhaustion_days", "pd_exhaustion_days").
+		WherePK().
+		Update()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem updating exhaustion forecast in the shared network: %d", sharedNetworkID)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "shared network with ID %d does not exist", sharedNetworkID)
+	}
+	return nil
+}
+
+// Sets the forecasted number of days remaining until the addresses are
+// exhausted in the address pool. The nil value indicates that the
+// exhaustion is not forecasted.
+func SetAddressPoolExhaustionForecast(dbi dbops.DBI, poolID int64, days *float64) error {
+	pool := &AddressPool{
+		ID:             poolID,
+		ExhaustionDays: days,
+	}
+	result, err := dbi.Model(pool).
+		Column("exhaustion_days").
+		WherePK().
+		Update()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem updating exhaustion forecast in the address pool: %d", poolID)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "address pool with ID %d does not exist", poolID)
+	}
+	return nil
+}
+
+// Sets the forecasted number of days remaining until the delegated
+// prefixes are exhausted in the prefix pool. The nil value indicates
+// that the exhaustion is not forecasted.
+func SetPrefixPoolExhaustionForecast(dbi dbops.DBI, poolID int64, days *float64) error {
+	pool := &PrefixPool{
+		ID:             poolID,
+		ExhaustionDays: days,
+	}
+	result, err := dbi.Model(pool).
+		Column("exhaustion_days").
+		WherePK().
+		Update()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem updating exhaustion forecast in the prefix pool: %d", poolID)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "prefix pool with ID %d does not exist", poolID)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package dbmodel
+
+import (
+	"testing"
+	"time"
+
+	require "github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test getting the exhaustion forecast settings.
+func TestGetUtilizationForecastSettings(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	err := InitializeSettings(db, 0)
+	require.NoError(t, err)
+
+	require.Equal(t, 7*24*time.Hour, GetUtilizationForecastWindow(db))
+	require.EqualValues(t, 14, GetUtilizationForecastHorizon(db))
+
+	// The default window is used when the setting is not positive.
+	err = SetSettingInt(db, UtilizationForecastWindowSetting, 0)
+	require.NoError(t, err)
+	require.Equal(t, DefaultUtilizationForecastWindow, GetUtilizationForecastWindow(db))
+
+	err = SetSettingInt(db, UtilizationForecastHorizonSetting, 0)
+	require.NoError(t, err)
+	require.Zero(t, GetUtilizationForecastHorizon(db))
+}
+
+// Test setting the exhaustion forecasts in the subnets and shared networks.
+func TestSetExhaustionForecast(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	network := &SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err := AddSharedNetwork(db, network)
+	require.NoError(t, err)
+
+	subnet := &Subnet{
+		Prefix:          "192.0.2.0/24",
+		SharedNetworkID: network.ID,
+	}
+	err = AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	addrDays := 12.5
+	err = SetSubnetExhaustionForecast(db, subnet.ID, &addrDays, nil)
+	require.NoError(t, err)
+	err = SetSharedNetworkExhaustionForecast(db, network.ID, nil, &addrDays)
+	require.NoError(t, err)
+
+	returnedSubnet, err := GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returnedSubnet.AddrExhaustionDays)
+	require.EqualValues(t, 12.5, *returnedSubnet.AddrExhaustionDays)
+	require.Nil(t, returnedSubnet.PdExhaustionDays)
+
+	returnedNetwork, err := GetSharedNetwork(db, network.ID)
+	require.NoError(t, err)
+	require.Nil(t, returnedNetwork.AddrExhaustionDays)
+	require.NotNil(t, returnedNetwork.PdExhaustionDays)
+
+	// Updating the subnet doesn't reset the forecast.
+	err = updateSubnet(db, returnedSubnet)
+	require.NoError(t, err)
+	returnedSubnet, err = GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returnedSubnet.AddrExhaustionDays)
+
+	// Clear the forecast.
+	err = SetSubnetExhaustionForecast(db, subnet.ID, nil, nil)
+	require.NoError(t, err)
+	returnedSubnet, err = GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.Nil(t, returnedSubnet.AddrExhaustionDays)
+
+	// Non-existing objects.
+	err = SetSubnetExhaustionForecast(db, subnet.ID+100, nil, nil)
+	require.ErrorIs(t, err, ErrNotExists)
+	err = SetSharedNetworkExhaustionForecast(db, network.ID+100, nil, nil)
+	require.ErrorIs(t, err, ErrNotExists)
+}
+
+// Test setting the exhaustion forecasts in the address and prefix pools.
+func TestSetPoolExhaustionForecast(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+
+	subnet := Subnet{
+		Prefix: "2001:db8:1::/64",
+		LocalSubnets: []*LocalSubnet{
+			{
+				DaemonID: apps[0].Daemons[0].ID,
+				AddressPools: []AddressPool{
+					{
+						LowerBound: "2001:db8:1::10",
+						UpperBound: "2001:db8:1::ff",
+					},
+				},
+				PrefixPools: []PrefixPool{
+					{
+						Prefix:       "2001:db8:1:1::/80",
+						DelegatedLen: 96,
+					},
+				},
+			},
+		},
+	}
+	err := AddSubnet(db, &subnet)
+	require.NoError(t, err)
+	err = AddLocalSubnets(db, &subnet)
+	require.NoError(t, err)
+
+	localSubnet := subnet.LocalSubnets[0]
+	days := 3.5
+	err = SetAddressPoolExhaustionForecast(db, localSubnet.AddressPools[0].ID, &days)
+	require.NoError(t, err)
+	err = SetPrefixPoolExhaustionForecast(db, localSubnet.PrefixPools[0].ID, &days)
+	require.NoError(t, err)
+
+	returnedSubnets, err := GetSubnetsByPrefix(db, "2001:db8:1::/64")
+	require.NoError(t, err)
+	require.Len(t, returnedSubnets, 1)
+	returnedLocalSubnet := returnedSubnets[0].LocalSubnets[0]
+	require.NotNil(t, returnedLocalSubnet.AddressPools[0].ExhaustionDays)
+	require.EqualValues(t, 3.5, *returnedLocalSubnet.AddressPools[0].ExhaustionDays)
+	require.NotNil(t, returnedLocalSubnet.PrefixPools[0].ExhaustionDays)
+	require.EqualValues(t, 3.5, *returnedLocalSubnet.PrefixPools[0].ExhaustionDays)
+
+	// Clear the forecast.
+	err = SetAddressPoolExhaustionForecast(db, localSubnet.AddressPools[0].ID, nil)
+	require.NoError(t, err)
+	returnedSubnets, err = GetSubnetsByPrefix(db, "2001:db8:1::/64")
+	require.NoError(t, err)
+	require.Nil(t, returnedSubnets[0].LocalSubnets[0].AddressPools[0].ExhaustionDays)
+
+	// Non-existing objects.
+	err = SetAddressPoolExhaustionForecast(db, localSubnet.AddressPools[0].ID+100, nil)
+	require.ErrorIs(t, err, ErrNotExists)
+	err = SetPrefixPoolExhaustionForecast(db, localSubnet.PrefixPools[0].ID+100, nil)
+	require.ErrorIs(t, err, ErrNotExists)
+}
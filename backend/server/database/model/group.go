@@ -13,17 +13,207 @@ import (
 const (
 	SuperAdminGroupID int = 1
 	AdminGroupID      int = 2
+	ReadOnlyGroupID   int = 3
+	OperatorGroupID   int = 4
 )
 
+// An error returned on an attempt to modify or delete one of the
+// predefined groups.
+var ErrPredefinedGroup = errors.New("predefined group cannot be modified")
+
+// Permission granted to the members of a group. The super-admin and
+// admin groups are not associated with any permissions; their members
+// are granted access to the resources by the group membership. The
+// permissions are used to control the access of the members of other
+// groups.
+type Permission string
+
+// Permissions supported by the server.
+const (
+	// Allows viewing all system components.
+	PermissionViewAll Permission = "view-all"
+	// Allows viewing host reservations.
+	PermissionViewHosts Permission = "view-hosts"
+	// Allows creating, updating and deleting host reservations.
+	PermissionEditHosts Permission = "edit-hosts"
+	// Allows creating, updating and deleting subnets and shared networks.
+	PermissionEditSubnets Permission = "edit-subnets"
+	// Allows running config review and changing the config checkers'
+	// settings.
+	PermissionRunConfigReview Permission = "run-config-review"
+	// Allows dumping the machines' data for troubleshooting.
+	PermissionDumpMachines Permission = "dump-machines"
+	// Allows adding, updating and deleting the leases on the DHCP
+	// servers.
+	PermissionEditLeases Permission = "edit-leases"
+)
+
+// Returns all permissions supported by the server.
+func GetAllPermissions() []Permission {
+	return []Permission{
+		PermissionViewAll,
+		PermissionViewHosts,
+		PermissionEditHosts,
+		PermissionEditSubnets,
+		PermissionRunConfigReview,
+		PermissionDumpMachines,
+		PermissionEditLeases,
+	}
+}
+
+// Checks if the permission is supported by the server.
+func (p Permission) IsValid() bool {
+	for _, permission := range GetAllPermissions() {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
 // Represents a group of users having some specific permissions.
+// The permissions can be limited to the specified machines, apps
+// and subnets. If all scope lists are empty, the permissions apply
+// to all objects.
 type SystemGroup struct {
 	ID          int
 	Name        string
 	Description string
 
+	Permissions     []Permission `pg:",array"`
+	ScopeMachineIDs []int64      `pg:",array"`
+	ScopeAppIDs     []int64      `pg:",array"`
+	ScopeSubnetIDs  []int64      `pg:",array"`
+
 	Users []*SystemUser `pg:"many2many:system_user_to_group,fk:group_id,join_fk:user_id"`
 }
 
+// Checks if the group is one of the predefined groups.
+func (group *SystemGroup) IsPredefined() bool {
+	return group.ID > 0 && group.ID <= OperatorGroupID
+}
+
+// Checks if the group has the specified permission.
+func (group *SystemGroup) HasPermission(permission Permission) bool {
+	for _, p := range group.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if the group permissions are limited to selected objects.
+func (group *SystemGroup) IsScoped() bool {
+	return len(group.ScopeMachineIDs) > 0 || len(group.ScopeAppIDs) > 0 || len(group.ScopeSubnetIDs) > 0
+}
+
+// Checks if any of the specified objects belongs to the group scope.
+// It always returns true when the group is not scoped.
+func (group *SystemGroup) InScope(machineIDs, appIDs, subnetIDs []int64) bool {
+	if !group.IsScoped() {
+		return true
+	}
+	return containsAnyID(group.ScopeMachineIDs, machineIDs) ||
+		containsAnyID(group.ScopeAppIDs, appIDs) ||
+		containsAnyID(group.ScopeSubnetIDs, subnetIDs)
+}
+
+// Checks if any of the IDs belongs to the specified set.
+func containsAnyID(set, ids []int64) bool {
+	for _, id := range ids {
+		for _, s := range set {
+			if id == s {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Checks if all group permissions are supported.
+func validateGroupPermissions(group *SystemGroup) error {
+	for _, permission := range group.Permissions {
+		if !permission.IsValid() {
+			return pkgerrors.Errorf("unsupported permission %s in group %s", permission, group.Name)
+		}
+	}
+	return nil
+}
+
+// Inserts a new group into the database. The group ID is set to the
+// ID assigned by the database.
+func AddGroup(db dbops.DBI, group *SystemGroup) error {
+	if err := validateGroupPermissions(group); err != nil {
+		return err
+	}
+	_, err := db.Model(group).Insert()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem inserting group %s", group.Name)
+	}
+	return err
+}
+
+// Updates a group in the database. The predefined groups cannot be
+// updated.
+func UpdateGroup(db dbops.DBI, group *SystemGroup) error {
+	if group.IsPredefined() {
+		return pkgerrors.Wrapf(ErrPredefinedGroup, "unable to update group with ID %d", group.ID)
+	}
+	if err := validateGroupPermissions(group); err != nil {
+		return err
+	}
+	result, err := db.Model(group).WherePK().Update()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem updating group with ID %d", group.ID)
+	} else if result.RowsAffected() <= 0 {
+		err = pkgerrors.Wrapf(ErrNotExists, "group with ID %d does not exist", group.ID)
+	}
+	return err
+}
+
+// Deletes a group from the database. The predefined groups cannot be
+// deleted. The group members are not deleted.
+func DeleteGroup(db dbops.DBI, id int) error {
+	group := &SystemGroup{ID: id}
+	if group.IsPredefined() {
+		return pkgerrors.Wrapf(ErrPredefinedGroup, "unable to delete group with ID %d", id)
+	}
+	result, err := db.Model(group).WherePK().Delete()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem deleting group with ID %d", id)
+	} else if result.RowsAffected() <= 0 {
+		err = pkgerrors.Wrapf(ErrNotExists, "group with ID %d does not exist", id)
+	}
+	return err
+}
+
+// Fetches a group by ID. It returns nil if the group does not exist.
+func GetGroupByID(db dbops.DBI, id int) (*SystemGroup, error) {
+	group := &SystemGroup{}
+	err := db.Model(group).Where("id = ?", id).Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting group with ID %d", id)
+	}
+	return group, nil
+}
+
+// Fetches the groups with the specified IDs. The groups are ordered by ID.
+func GetGroupsByIDs(db dbops.DBI, ids []int) ([]SystemGroup, error) {
+	groups := []SystemGroup{}
+	if len(ids) == 0 {
+		return groups, nil
+	}
+	err := db.Model(&groups).WhereIn("id IN (?)", ids).OrderExpr("id ASC").Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrapf(err, "problem getting groups by IDs")
+	}
+	return groups, nil
+}
+
 // Fetches a collection of groups from the database. The offset and
 // limit specify the beginning of the page and the maximum size of the
 // page. The filterText can be used to match the name of description
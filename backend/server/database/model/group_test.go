@@ -14,31 +14,41 @@ func TestGetGroups(t *testing.T) {
 
 	groups, total, err := GetGroupsByPage(db, 0, 10, nil, "", SortDirAny)
 	require.NoError(t, err)
-	require.EqualValues(t, 2, total)
-	// There are two predefined groups.
-	require.Len(t, groups, 2)
+	require.EqualValues(t, 4, total)
+	// There are four predefined groups.
+	require.Len(t, groups, 4)
 
 	// Groups are supposed to be ordered by id.
 	require.Equal(t, 1, groups[0].ID)
 	require.Equal(t, "super-admin", groups[0].Name)
 	require.Equal(t, 2, groups[1].ID)
 	require.Equal(t, "admin", groups[1].Name)
+	require.Equal(t, 3, groups[2].ID)
+	require.Equal(t, "read-only", groups[2].Name)
+	require.Equal(t, []Permission{PermissionViewAll}, groups[2].Permissions)
+	require.Equal(t, 4, groups[3].ID)
+	require.Equal(t, "operator", groups[3].Name)
+	require.Contains(t, groups[3].Permissions, PermissionEditHosts)
 
 	// check sorting field and order ascending
 	groups, total, err = GetGroupsByPage(db, 0, 10, nil, "name", SortDirAsc)
 	require.NoError(t, err)
-	require.EqualValues(t, 2, total)
-	require.Len(t, groups, 2)
+	require.EqualValues(t, 4, total)
+	require.Len(t, groups, 4)
 	require.Equal(t, "admin", groups[0].Name)
-	require.Equal(t, "super-admin", groups[1].Name)
+	require.Equal(t, "operator", groups[1].Name)
+	require.Equal(t, "read-only", groups[2].Name)
+	require.Equal(t, "super-admin", groups[3].Name)
 
 	// check sorting field and order descending
 	groups, total, err = GetGroupsByPage(db, 0, 10, nil, "name", SortDirDesc)
 	require.NoError(t, err)
-	require.EqualValues(t, 2, total)
-	require.Len(t, groups, 2)
+	require.EqualValues(t, 4, total)
+	require.Len(t, groups, 4)
 	require.Equal(t, "super-admin", groups[0].Name)
-	require.Equal(t, "admin", groups[1].Name)
+	require.Equal(t, "read-only", groups[1].Name)
+	require.Equal(t, "operator", groups[2].Name)
+	require.Equal(t, "admin", groups[3].Name)
 
 	// check filtering by text
 	text := "super"
@@ -48,3 +58,126 @@ func TestGetGroups(t *testing.T) {
 	require.Len(t, groups, 1)
 	require.Equal(t, "super-admin", groups[0].Name)
 }
+
+// Test checking if the group has a permission.
+func TestGroupHasPermission(t *testing.T) {
+	group := &SystemGroup{
+		Permissions: []Permission{PermissionViewAll, PermissionEditHosts},
+	}
+	require.True(t, group.HasPermission(PermissionViewAll))
+	require.True(t, group.HasPermission(PermissionEditHosts))
+	require.False(t, group.HasPermission(PermissionEditSubnets))
+	require.False(t, (&SystemGroup{}).HasPermission(PermissionViewAll))
+}
+
+// Test checking if the objects belong to the group scope.
+func TestGroupInScope(t *testing.T) {
+	// Not scoped group covers all objects.
+	group := &SystemGroup{}
+	require.False(t, group.IsScoped())
+	require.True(t, group.InScope(nil, nil, nil))
+	require.True(t, group.InScope([]int64{1}, []int64{2}, []int64{3}))
+
+	group = &SystemGroup{
+		ScopeMachineIDs: []int64{1},
+		ScopeAppIDs:     []int64{5, 6},
+		ScopeSubnetIDs:  []int64{10},
+	}
+	require.True(t, group.IsScoped())
+	require.True(t, group.InScope([]int64{1}, nil, nil))
+	require.True(t, group.InScope([]int64{2}, []int64{6}, nil))
+	require.True(t, group.InScope(nil, nil, []int64{11, 10}))
+	require.False(t, group.InScope([]int64{2}, []int64{7}, []int64{11}))
+	require.False(t, group.InScope(nil, nil, nil))
+}
+
+// Test validating the permission names.
+func TestPermissionIsValid(t *testing.T) {
+	for _, permission := range GetAllPermissions() {
+		require.True(t, permission.IsValid())
+	}
+	require.False(t, Permission("edit-everything").IsValid())
+}
+
+// Test that the predefined groups are recognized.
+func TestGroupIsPredefined(t *testing.T) {
+	require.True(t, (&SystemGroup{ID: SuperAdminGroupID}).IsPredefined())
+	require.True(t, (&SystemGroup{ID: OperatorGroupID}).IsPredefined())
+	require.False(t, (&SystemGroup{ID: 5}).IsPredefined())
+	require.False(t, (&SystemGroup{}).IsPredefined())
+}
+
+// Test adding, updating, fetching and deleting the custom groups.
+func TestAddUpdateDeleteGroup(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	group := &SystemGroup{
+		Name:            "noc",
+		Description:     "NOC staff",
+		Permissions:     []Permission{PermissionViewAll, PermissionEditHosts},
+		ScopeMachineIDs: []int64{1, 2},
+	}
+	err := AddGroup(db, group)
+	require.NoError(t, err)
+	require.Greater(t, group.ID, OperatorGroupID)
+
+	returned, err := GetGroupByID(db, group.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, "noc", returned.Name)
+	require.Equal(t, []Permission{PermissionViewAll, PermissionEditHosts}, returned.Permissions)
+	require.Equal(t, []int64{1, 2}, returned.ScopeMachineIDs)
+	require.Empty(t, returned.ScopeAppIDs)
+
+	// Update the group.
+	returned.Permissions = []Permission{PermissionViewHosts}
+	returned.ScopeMachineIDs = nil
+	returned.ScopeSubnetIDs = []int64{3}
+	err = UpdateGroup(db, returned)
+	require.NoError(t, err)
+
+	groups, err := GetGroupsByIDs(db, []int{ReadOnlyGroupID, group.ID})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	require.Equal(t, "read-only", groups[0].Name)
+	require.Equal(t, []Permission{PermissionViewHosts}, groups[1].Permissions)
+	require.Empty(t, groups[1].ScopeMachineIDs)
+	require.Equal(t, []int64{3}, groups[1].ScopeSubnetIDs)
+
+	// Delete the group.
+	err = DeleteGroup(db, group.ID)
+	require.NoError(t, err)
+	returned, err = GetGroupByID(db, group.ID)
+	require.NoError(t, err)
+	require.Nil(t, returned)
+
+	// Deleting again should fail.
+	err = DeleteGroup(db, group.ID)
+	require.ErrorIs(t, err, ErrNotExists)
+}
+
+// Test that the group with an unsupported permission is rejected.
+func TestAddGroupInvalidPermission(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	group := &SystemGroup{
+		Name:        "bogus",
+		Permissions: []Permission{"edit-everything"},
+	}
+	require.Error(t, AddGroup(db, group))
+}
+
+// Test that the predefined groups cannot be modified or deleted.
+func TestUpdateDeletePredefinedGroup(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	group, err := GetGroupByID(db, ReadOnlyGroupID)
+	require.NoError(t, err)
+	require.NotNil(t, group)
+	group.Permissions = append(group.Permissions, PermissionEditHosts)
+	require.ErrorIs(t, UpdateGroup(db, group), ErrPredefinedGroup)
+	require.ErrorIs(t, DeleteGroup(db, AdminGroupID), ErrPredefinedGroup)
+}
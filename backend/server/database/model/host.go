@@ -373,6 +373,42 @@ func GetHostsBySubnetID(dbi dbops.DBI, subnetID int64) ([]Host, error) {
 	return hosts, err
 }
 
+// Fetches the hosts having the specified identifier in the given subnet.
+// If the subnet ID is zero, it fetches the global hosts having the
+// identifier.
+func GetHostsByIdentifier(dbi dbops.DBI, identifierType string, identifier []byte, subnetID int64) ([]Host, error) {
+	hosts := []Host{}
+	q := dbi.Model(&hosts).
+		Relation("HostIdentifiers", func(q *orm.Query) (*orm.Query, error) {
+			return q.Order("host_identifier.id ASC"), nil
+		}).
+		Relation("IPReservations", func(q *orm.Query) (*orm.Query, error) {
+			return q.Order("ip_reservation.id ASC"), nil
+		}).
+		Relation("LocalHosts").
+		Join("JOIN host_identifier AS i ON i.host_id = host.id").
+		Where("i.type = ?", identifierType).
+		Where("i.value = ?", identifier).
+		OrderExpr("host.id ASC")
+
+	// See the comment in GetHostsBySubnetID.
+	if subnetID == 0 {
+		q = q.Where("host.subnet_id IS NULL")
+	} else {
+		q = q.Where("host.subnet_id = ?", subnetID)
+	}
+
+	err := q.Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		err = pkgerrors.Wrapf(err, "problem getting hosts by %s identifier in subnet %d", identifierType, subnetID)
+		return nil, err
+	}
+	return hosts, err
+}
+
 // Fetches a collection of hosts by daemon ID and optionally filters by a
 // data source.
 func GetHostsByDaemonID(dbi dbops.DBI, daemonID int64, dataSource HostDataSource) ([]Host, int64, error) {
@@ -420,13 +456,15 @@ func GetHostsByDaemonID(dbi dbops.DBI, daemonID int64, dataSource HostDataSource
 // (using hexadecimal digits or a textual format) and hostnames. It is allowed to specify
 // colons while searching for hosts by host identifiers. If Global flag is true then only
 // hosts from the global scope are returned (i.e. not assigned to any subnet), if false
-// then only hosts from subnets are returned.
+// then only hosts from subnets are returned. If Scope is specified then only hosts
+// belonging to the scope are returned.
 type HostsByPageFilters struct {
 	AppID         *int64
 	SubnetID      *int64
 	LocalSubnetID *int64
 	FilterText    *string
 	Global        *bool
+	Scope         *ScopeFilter
 }
 
 // Fetches a collection of hosts from the database.
@@ -470,12 +508,15 @@ func GetHostsByPage(dbi dbops.DBI, offset, limit int64, filters HostsByPageFilte
 
 	// filter global or non-global hosts
 	if (filters.Global != nil && *filters.Global) || (filters.SubnetID != nil && *filters.SubnetID == 0) {
-		q = q.WhereOr("host.subnet_id IS NULL")
+		q = q.Where("host.subnet_id IS NULL")
 	}
 	if filters.Global != nil && !*filters.Global {
-		q = q.WhereOr("host.subnet_id IS NOT NULL")
+		q = q.Where("host.subnet_id IS NOT NULL")
 	}
 
+	// filter by the user's scope
+	q = filters.Scope.applyToHosts(q)
+
 	// filter by text
 	if filters.FilterText != nil && len(*filters.FilterText) > 0 {
 		// It is possible that the user is typing a search text with colons
@@ -487,6 +487,42 @@ func TestGetHostsBySubnetID(t *testing.T) {
 	require.Contains(t, returned, hosts[2])
 }
 
+// Test that the hosts can be fetched by identifier and subnet.
+func TestGetHostsByIdentifier(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	hosts := addTestHosts(t, db)
+
+	// The hw-address is used in the subnets 1 and 2.
+	returned, err := GetHostsByIdentifier(db, "hw-address", []byte{1, 2, 3, 4, 5, 6}, 1)
+	require.NoError(t, err)
+	require.Len(t, returned, 1)
+	require.Equal(t, hosts[0].ID, returned[0].ID)
+	require.Len(t, returned[0].HostIdentifiers, 2)
+
+	returned, err = GetHostsByIdentifier(db, "hw-address", []byte{1, 2, 3, 4, 5, 6}, 2)
+	require.NoError(t, err)
+	require.Len(t, returned, 1)
+	require.Equal(t, hosts[2].ID, returned[0].ID)
+
+	// Global host.
+	returned, err = GetHostsByIdentifier(db, "flex-id", []byte{0x51, 0x52, 0x53, 0x54}, 0)
+	require.NoError(t, err)
+	require.Len(t, returned, 1)
+	require.Equal(t, hosts[3].ID, returned[0].ID)
+
+	// The identifier type must match.
+	returned, err = GetHostsByIdentifier(db, "client-id", []byte{1, 2, 3, 4, 5, 6}, 1)
+	require.NoError(t, err)
+	require.Empty(t, returned)
+
+	// The identifier is not used by global hosts.
+	returned, err = GetHostsByIdentifier(db, "hw-address", []byte{1, 2, 3, 4, 5, 6}, 0)
+	require.NoError(t, err)
+	require.Empty(t, returned)
+}
+
 // Test that page of the hosts can be fetched without filtering.
 func TestGetHostsByPageNoFiltering(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
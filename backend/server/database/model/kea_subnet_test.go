@@ -770,7 +770,7 @@ func TestGetSharedNetworksByPageBasic(t *testing.T) {
 	require.NoError(t, err)
 
 	// Get all shared networks.
-	networks, total, err := GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, "", SortDirAny)
+	networks, total, err := GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, nil, "", SortDirAny)
 	require.NoError(t, err)
 	require.EqualValues(t, 3, total)
 	require.Len(t, networks, 3)
@@ -788,7 +788,7 @@ func TestGetSharedNetworksByPageBasic(t *testing.T) {
 	}
 
 	// Get shared networks for Kea app a4.
-	networks, total, err = GetSharedNetworksByPage(db, 0, 10, a4.ID, 0, nil, "", SortDirAny)
+	networks, total, err = GetSharedNetworksByPage(db, 0, 10, a4.ID, 0, nil, nil, "", SortDirAny)
 	require.NoError(t, err)
 	require.EqualValues(t, 2, total)
 	require.Len(t, networks, 2)
@@ -805,7 +805,7 @@ func TestGetSharedNetworksByPageBasic(t *testing.T) {
 	require.ElementsMatch(t, []string{"frog", "mouse"}, []string{networks[0].Name, networks[1].Name})
 
 	// Get shared networks for Kea app a6.
-	networks, total, err = GetSharedNetworksByPage(db, 0, 10, 0, 6, nil, "", SortDirAny)
+	networks, total, err = GetSharedNetworksByPage(db, 0, 10, 0, 6, nil, nil, "", SortDirAny)
 	require.NoError(t, err)
 	require.EqualValues(t, 1, total)
 	require.Len(t, networks, 1)
@@ -818,7 +818,7 @@ func TestGetSharedNetworksByPageBasic(t *testing.T) {
 
 	// Get networks by text "mous".
 	text := "mous"
-	networks, total, err = GetSharedNetworksByPage(db, 0, 10, 0, 0, &text, "", SortDirAny)
+	networks, total, err = GetSharedNetworksByPage(db, 0, 10, 0, 0, &text, nil, "", SortDirAny)
 	require.NoError(t, err)
 	require.EqualValues(t, 1, total)
 	require.Len(t, networks, 1)
@@ -830,7 +830,7 @@ func TestGetSharedNetworksByPageBasic(t *testing.T) {
 	require.Equal(t, "mouse", networks[0].Name)
 
 	// check sorting by id asc
-	networks, total, err = GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, "", SortDirAsc)
+	networks, total, err = GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, nil, "", SortDirAsc)
 	require.NoError(t, err)
 	require.EqualValues(t, 3, total)
 	require.Len(t, networks, 3)
@@ -838,7 +838,7 @@ func TestGetSharedNetworksByPageBasic(t *testing.T) {
 	require.EqualValues(t, 3, networks[2].ID)
 
 	// check sorting by id desc
-	networks, total, err = GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, "", SortDirDesc)
+	networks, total, err = GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, nil, "", SortDirDesc)
 	require.NoError(t, err)
 	require.EqualValues(t, 3, total)
 	require.Len(t, networks, 3)
@@ -846,7 +846,7 @@ func TestGetSharedNetworksByPageBasic(t *testing.T) {
 	require.EqualValues(t, 1, networks[2].ID)
 
 	// check sorting by name asc
-	networks, total, err = GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, "name", SortDirAsc)
+	networks, total, err = GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, nil, "name", SortDirAsc)
 	require.NoError(t, err)
 	require.EqualValues(t, 3, total)
 	require.Len(t, networks, 3)
@@ -855,7 +855,7 @@ func TestGetSharedNetworksByPageBasic(t *testing.T) {
 	require.EqualValues(t, "mouse", networks[2].Name)
 
 	// check sorting by name desc
-	networks, total, err = GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, "name", SortDirDesc)
+	networks, total, err = GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, nil, "name", SortDirDesc)
 	require.NoError(t, err)
 	require.EqualValues(t, 3, total)
 	require.Len(t, networks, 3)
@@ -191,11 +191,14 @@ func RefreshMachineFromDB(db *pg.DB, machine *Machine) error {
 // machines are returned. If it is nil then no filtering by authorized
 // happens (ie. all machines are returned).
 //
+// scope limits the returned machines to those belonging to the user's
+// scope. If it is nil then no filtering by scope happens.
+//
 // sortField allows indicating sort column in database and sortDir
 // allows selection the order of sorting. If sortField is empty then
 // id is used for sorting.  in SortDirAny is used then ASC order is
 // used.
-func GetMachinesByPage(db *pg.DB, offset int64, limit int64, filterText *string, authorized *bool, sortField string, sortDir SortDirEnum) ([]Machine, int64, error) {
+func GetMachinesByPage(db *pg.DB, offset int64, limit int64, filterText *string, authorized *bool, scope *ScopeFilter, sortField string, sortDir SortDirEnum) ([]Machine, int64, error) {
 	if limit == 0 {
 		return nil, 0, pkgerrors.New("limit should be greater than 0")
 	}
@@ -232,6 +235,9 @@ func GetMachinesByPage(db *pg.DB, offset int64, limit int64, filterText *string,
 		q = q.Where("authorized = ?", *authorized)
 	}
 
+	// prepare filtering by the user's scope
+	q = scope.applyToMachines(q)
+
 	// prepare sorting expression, offset and limit
 	ordExpr := prepareOrderExpr("machine", sortField, sortDir)
 	q = q.OrderExpr(ordExpr)
@@ -531,7 +531,7 @@ func TestGetMachinesByPageBasic(t *testing.T) {
 	defer teardown()
 
 	// no machines yet but try to get some
-	ms, total, err := GetMachinesByPage(db, 0, 10, nil, nil, "", SortDirAny)
+	ms, total, err := GetMachinesByPage(db, 0, 10, nil, nil, nil, "", SortDirAny)
 	require.Nil(t, err)
 	require.Zero(t, total)
 	require.Len(t, ms, 0)
@@ -565,26 +565,26 @@ func TestGetMachinesByPageBasic(t *testing.T) {
 	}
 
 	// get 10 machines from 0
-	ms, total, err = GetMachinesByPage(db, 0, 10, nil, nil, "", SortDirAny)
+	ms, total, err = GetMachinesByPage(db, 0, 10, nil, nil, nil, "", SortDirAny)
 	require.Nil(t, err)
 	require.EqualValues(t, 10, total)
 	require.Len(t, ms, 10)
 
 	// get 2 machines out of 10, from 0
-	ms, total, err = GetMachinesByPage(db, 0, 2, nil, nil, "", SortDirAny)
+	ms, total, err = GetMachinesByPage(db, 0, 2, nil, nil, nil, "", SortDirAny)
 	require.Nil(t, err)
 	require.EqualValues(t, 10, total)
 	require.Len(t, ms, 2)
 
 	// get 3 machines out of 10, from 2
-	ms, total, err = GetMachinesByPage(db, 2, 3, nil, nil, "", SortDirAny)
+	ms, total, err = GetMachinesByPage(db, 2, 3, nil, nil, nil, "", SortDirAny)
 	require.Nil(t, err)
 	require.EqualValues(t, 10, total)
 	require.Len(t, ms, 3)
 
 	// get 10 machines out of 10, from 0, but with '2' in contents; should return 1: 20 and 12
 	text := "2"
-	ms, total, err = GetMachinesByPage(db, 0, 10, &text, nil, "", SortDirAny)
+	ms, total, err = GetMachinesByPage(db, 0, 10, &text, nil, nil, "", SortDirAny)
 	require.Nil(t, err)
 	require.EqualValues(t, 2, total)
 	require.Len(t, ms, 2)
@@ -605,7 +605,7 @@ func TestGetMachinesByPageBasic(t *testing.T) {
 	require.Empty(t, ms[1].Apps[0].AccessPoints[0].Key)
 
 	// check sorting by id asc
-	ms, total, err = GetMachinesByPage(db, 0, 100, nil, nil, "", SortDirAsc)
+	ms, total, err = GetMachinesByPage(db, 0, 100, nil, nil, nil, "", SortDirAsc)
 	require.Nil(t, err)
 	require.EqualValues(t, 10, total)
 	require.Len(t, ms, 10)
@@ -613,7 +613,7 @@ func TestGetMachinesByPageBasic(t *testing.T) {
 	require.EqualValues(t, 6, ms[5].ID)
 
 	// check sorting by id desc
-	ms, total, err = GetMachinesByPage(db, 0, 100, nil, nil, "", SortDirDesc)
+	ms, total, err = GetMachinesByPage(db, 0, 100, nil, nil, nil, "", SortDirDesc)
 	require.Nil(t, err)
 	require.EqualValues(t, 10, total)
 	require.Len(t, ms, 10)
@@ -621,7 +621,7 @@ func TestGetMachinesByPageBasic(t *testing.T) {
 	require.EqualValues(t, 5, ms[5].ID)
 
 	// check sorting by address asc
-	ms, total, err = GetMachinesByPage(db, 0, 100, nil, nil, "address", SortDirAsc)
+	ms, total, err = GetMachinesByPage(db, 0, 100, nil, nil, nil, "address", SortDirAsc)
 	require.Nil(t, err)
 	require.EqualValues(t, 10, total)
 	require.Len(t, ms, 10)
@@ -629,7 +629,7 @@ func TestGetMachinesByPageBasic(t *testing.T) {
 	require.EqualValues(t, 5, ms[5].ID)
 
 	// check sorting by address desc
-	ms, total, err = GetMachinesByPage(db, 0, 100, nil, nil, "address", SortDirDesc)
+	ms, total, err = GetMachinesByPage(db, 0, 100, nil, nil, nil, "address", SortDirDesc)
 	require.Nil(t, err)
 	require.EqualValues(t, 10, total)
 	require.Len(t, ms, 10)
@@ -656,14 +656,14 @@ func TestGetMachinesByPageWithFiltering(t *testing.T) {
 
 	// filter machines by json fields: redhat
 	text := "redhat"
-	ms, total, err := GetMachinesByPage(db, 0, 10, &text, nil, "", SortDirAny)
+	ms, total, err := GetMachinesByPage(db, 0, 10, &text, nil, nil, "", SortDirAny)
 	require.Nil(t, err)
 	require.EqualValues(t, 1, total)
 	require.Len(t, ms, 1)
 
 	// filter machines by json fields: my
 	text = "my"
-	ms, total, err = GetMachinesByPage(db, 0, 10, &text, nil, "", SortDirAny)
+	ms, total, err = GetMachinesByPage(db, 0, 10, &text, nil, nil, "", SortDirAny)
 	require.Nil(t, err)
 	require.EqualValues(t, 1, total)
 	require.Len(t, ms, 1)
@@ -691,7 +691,7 @@ func TestGetMachinesByPageFilteredByAuthorized(t *testing.T) {
 
 	// get unauthorized machines
 	authorized := false
-	ms, total, err := GetMachinesByPage(db, 0, 10, nil, &authorized, "", SortDirAny)
+	ms, total, err := GetMachinesByPage(db, 0, 10, nil, &authorized, nil, "", SortDirAny)
 	require.Nil(t, err)
 	require.EqualValues(t, 1, total)
 	require.Len(t, ms, 1)
@@ -700,7 +700,7 @@ func TestGetMachinesByPageFilteredByAuthorized(t *testing.T) {
 
 	// get authorized machines
 	authorized = true
-	ms, total, err = GetMachinesByPage(db, 0, 10, nil, &authorized, "", SortDirAny)
+	ms, total, err = GetMachinesByPage(db, 0, 10, nil, &authorized, nil, "", SortDirAny)
 	require.Nil(t, err)
 	require.EqualValues(t, 1, total)
 	require.Len(t, ms, 1)
@@ -708,7 +708,7 @@ func TestGetMachinesByPageFilteredByAuthorized(t *testing.T) {
 	require.True(t, ms[0].Authorized)
 
 	// get all machines
-	ms, total, err = GetMachinesByPage(db, 0, 10, nil, nil, "", SortDirAny)
+	ms, total, err = GetMachinesByPage(db, 0, 10, nil, nil, nil, "", SortDirAny)
 	require.Nil(t, err)
 	require.EqualValues(t, 2, total)
 	require.Len(t, ms, 2)
@@ -970,7 +970,7 @@ func TestGetAllMachines(t *testing.T) {
 	require.Len(t, machines, 10)
 
 	// paged get should return indicated limit, not all
-	machines, total, err := GetMachinesByPage(db, 0, 10, nil, nil, "", SortDirAny)
+	machines, total, err := GetMachinesByPage(db, 0, 10, nil, nil, nil, "", SortDirAny)
 	require.NoError(t, err)
 	require.Len(t, machines, 10)
 	require.EqualValues(t, 20, total)
@@ -15,6 +15,39 @@ type CalculatedNetworkMetrics struct {
 	PdUtilization int16
 }
 
+// BIND 9 statistics of a daemon used to calculate the metrics.
+type CalculatedBind9Metrics struct {
+	// Name of the app the daemon belongs to.
+	AppName string
+	// Statistics fetched from the daemon.
+	Stats Bind9DaemonStats
+}
+
+// Statistics of an address or prefix pool used to calculate the metrics.
+type CalculatedPoolMetrics struct {
+	// Prefix of the subnet the pool belongs to.
+	Subnet string
+	// Address range or prefix of the pool.
+	Pool string
+	// Name of the app serving the pool.
+	AppName string
+	// Statistics pulled from the Kea server.
+	Stats SubnetStats
+	// Utilization in percentage multiplied by 10.
+	Utilization int16
+}
+
+// Returns the value of the first of the statistics present in the pool
+// statistics. It returns zero if none of them is present.
+func (m CalculatedPoolMetrics) GetStat(names ...string) float64 {
+	for _, name := range names {
+		if value, ok := m.Stats[name]; ok {
+			return statToFloat64(value)
+		}
+	}
+	return 0
+}
+
 // Metric values calculated from the database.
 type CalculatedMetrics struct {
 	AuthorizedMachines   int64
@@ -22,6 +55,9 @@ type CalculatedMetrics struct {
 	UnreachableMachines  int64
 	SubnetMetrics        []CalculatedNetworkMetrics
 	SharedNetworkMetrics []CalculatedNetworkMetrics
+	Bind9Metrics         []CalculatedBind9Metrics
+	AddressPoolMetrics   []CalculatedPoolMetrics
+	PrefixPoolMetrics    []CalculatedPoolMetrics
 }
 
 // Calculates various metrics using several SELECT queries.
@@ -57,5 +93,53 @@ func GetCalculatedMetrics(db *pg.DB) (*CalculatedMetrics, error) {
 		return nil, errors.Wrap(err, "cannot calculate shared network metrics")
 	}
 
+	err = db.Model().
+		Table("bind9_daemon").
+		ColumnExpr("app.name AS \"app_name\"").
+		ColumnExpr("bind9_daemon.stats AS \"stats\"").
+		Join("JOIN daemon ON daemon.id = bind9_daemon.daemon_id").
+		Join("JOIN app ON app.id = daemon.app_id").
+		Select(&metrics.Bind9Metrics)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot calculate BIND 9 metrics")
+	}
+
+	err = db.Model().
+		Table("address_pool").
+		ColumnExpr("subnet.prefix AS \"subnet\"").
+		ColumnExpr("host(address_pool.lower_bound) || '-' || host(address_pool.upper_bound) AS \"pool\"").
+		ColumnExpr("app.name AS \"app_name\"").
+		ColumnExpr("address_pool.stats AS \"stats\"").
+		ColumnExpr("address_pool.utilization AS \"utilization\"").
+		Join("JOIN local_subnet ON local_subnet.id = address_pool.local_subnet_id").
+		Join("JOIN subnet ON subnet.id = local_subnet.subnet_id").
+		Join("JOIN daemon ON daemon.id = local_subnet.daemon_id").
+		Join("JOIN app ON app.id = daemon.app_id").
+		Where("address_pool.stats IS NOT NULL").
+		Select(&metrics.AddressPoolMetrics)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot calculate address pool metrics")
+	}
+
+	err = db.Model().
+		Table("prefix_pool").
+		ColumnExpr("subnet.prefix AS \"subnet\"").
+		ColumnExpr("prefix_pool.prefix AS \"pool\"").
+		ColumnExpr("app.name AS \"app_name\"").
+		ColumnExpr("prefix_pool.stats AS \"stats\"").
+		ColumnExpr("prefix_pool.utilization AS \"utilization\"").
+		Join("JOIN local_subnet ON local_subnet.id = prefix_pool.local_subnet_id").
+		Join("JOIN subnet ON subnet.id = local_subnet.subnet_id").
+		Join("JOIN daemon ON daemon.id = local_subnet.daemon_id").
+		Join("JOIN app ON app.id = daemon.app_id").
+		Where("prefix_pool.stats IS NOT NULL").
+		Select(&metrics.PrefixPoolMetrics)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot calculate prefix pool metrics")
+	}
+
 	return &metrics, nil
 }
@@ -137,3 +137,116 @@ func TestFilledSharedNetworksDatabaseMetrics(t *testing.T) {
 	require.Zero(t, metrics.SharedNetworkMetrics[2].AddrUtilization)
 	require.Zero(t, metrics.SharedNetworkMetrics[2].PdUtilization)
 }
+
+// Metrics of the BIND 9 daemons should be properly returned.
+func TestBind9DatabaseMetrics(t *testing.T) {
+	// Arrange
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+	app := addTestBind9App(t, db, "localhost")
+	daemon := app.Daemons[0]
+	daemon.Bind9Daemon.Stats.NamedStats = &Bind9NamedStats{
+		Rcodes: map[string]int64{"NOERROR": 10},
+		Views: map[string]*Bind9StatsView{
+			"_default": {
+				ZoneCount: 3,
+			},
+			"internal": {
+				ZoneCount: 1,
+				Resolver: &Bind9StatsResolver{
+					CacheStats: map[string]int64{"QueryHits": 5},
+				},
+			},
+		},
+	}
+	err := UpdateDaemon(db, daemon)
+	require.NoError(t, err)
+
+	// Act
+	metrics, err := GetCalculatedMetrics(db)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, metrics.Bind9Metrics, 1)
+	require.Equal(t, "bind9@localhost", metrics.Bind9Metrics[0].AppName)
+	namedStats := metrics.Bind9Metrics[0].Stats.NamedStats
+	require.NotNil(t, namedStats)
+	require.EqualValues(t, 10, namedStats.Rcodes["NOERROR"])
+	require.Len(t, namedStats.Views, 2)
+	require.EqualValues(t, 3, namedStats.Views["_default"].ZoneCount)
+	require.EqualValues(t, 5, namedStats.Views["internal"].Resolver.CacheStats["QueryHits"])
+}
+
+// Metrics per address and prefix pool should be properly calculated.
+func TestPoolDatabaseMetrics(t *testing.T) {
+	// Arrange
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+	subnet := Subnet{
+		Prefix: "2001:db8:1::/64",
+		LocalSubnets: []*LocalSubnet{
+			{
+				DaemonID: apps[0].Daemons[0].ID,
+				AddressPools: []AddressPool{
+					{
+						LowerBound: "2001:db8:1::10",
+						UpperBound: "2001:db8:1::ff",
+					},
+					{
+						LowerBound: "2001:db8:1::1:10",
+						UpperBound: "2001:db8:1::1:ff",
+					},
+				},
+				PrefixPools: []PrefixPool{
+					{
+						Prefix:       "2001:db8:1:1::/80",
+						DelegatedLen: 96,
+					},
+				},
+			},
+		},
+	}
+	err := AddSubnet(db, &subnet)
+	require.NoError(t, err)
+	err = AddLocalSubnets(db, &subnet)
+	require.NoError(t, err)
+
+	localSubnets, err := GetAppLocalSubnets(db, apps[0].ID)
+	require.NoError(t, err)
+	require.Len(t, localSubnets, 1)
+
+	// The second address pool has no statistics.
+	err = localSubnets[0].AddressPools[0].UpdateStats(db, SubnetStats{
+		"total-nas":    uint64(240),
+		"assigned-nas": uint64(60),
+		"declined-nas": uint64(2),
+	})
+	require.NoError(t, err)
+	err = localSubnets[0].PrefixPools[0].UpdateStats(db, SubnetStats{
+		"total-pds":    uint64(65536),
+		"assigned-pds": uint64(32768),
+	})
+	require.NoError(t, err)
+
+	// Act
+	metrics, err := GetCalculatedMetrics(db)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, metrics.AddressPoolMetrics, 1)
+	poolMetrics := metrics.AddressPoolMetrics[0]
+	require.Equal(t, "2001:db8:1::/64", poolMetrics.Subnet)
+	require.Equal(t, "2001:db8:1::10-2001:db8:1::ff", poolMetrics.Pool)
+	require.NotEmpty(t, poolMetrics.AppName)
+	require.EqualValues(t, 250, poolMetrics.Utilization)
+	require.EqualValues(t, 240, poolMetrics.GetStat("total-addresses", "total-nas"))
+	require.EqualValues(t, 2, poolMetrics.GetStat("declined-addresses", "declined-nas"))
+
+	require.Len(t, metrics.PrefixPoolMetrics, 1)
+	poolMetrics = metrics.PrefixPoolMetrics[0]
+	require.Equal(t, "2001:db8:1:1::/80", poolMetrics.Pool)
+	require.EqualValues(t, 500, poolMetrics.Utilization)
+	require.EqualValues(t, 32768, poolMetrics.GetStat("assigned-pds"))
+}
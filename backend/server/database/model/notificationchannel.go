@@ -0,0 +1,154 @@
+package dbmodel
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+)
+
+// Type of the notification channel.
+type NotificationChannelType string
+
+// Supported notification channel types.
+const (
+	NotificationChannelSMTP    NotificationChannelType = "smtp"
+	NotificationChannelWebhook NotificationChannelType = "webhook"
+	NotificationChannelSlack   NotificationChannelType = "slack"
+	NotificationChannelSyslog  NotificationChannelType = "syslog"
+)
+
+// Default interval, in seconds, in which the number of the notifications
+// is limited.
+const DefaultNotificationRateLimitInterval int64 = 60
+
+// Type-specific settings of the notification channel. Only the settings
+// relevant to the channel type are set.
+type NotificationChannelSettings struct {
+	// URL of the generic or Slack-compatible webhook.
+	URL string `json:",omitempty"`
+
+	// SMTP server address, port and credentials.
+	SMTPHost     string `json:",omitempty"`
+	SMTPPort     int64  `json:",omitempty"`
+	SMTPUsername string `json:",omitempty"`
+	SMTPPassword string `json:",omitempty"`
+	// Sender and recipients of the email notifications.
+	SMTPFrom string   `json:",omitempty"`
+	SMTPTo   []string `json:",omitempty"`
+
+	// Syslog transport protocol (udp or tcp), server address and
+	// facility name, e.g. local0.
+	SyslogNetwork  string `json:",omitempty"`
+	SyslogAddress  string `json:",omitempty"`
+	SyslogFacility string `json:",omitempty"`
+}
+
+// Represents a notification channel used to forward the events to an
+// external system, e.g. to send them by email. The events are filtered
+// by level and the relations with the machines, apps, daemons, subnets
+// and shared networks. The zero values of the filtering IDs match all events.
+// The number of the notifications sent over the channel can be limited
+// to the RateLimit notifications per RateLimitInterval seconds. The zero
+// RateLimit disables rate limiting.
+type NotificationChannel struct {
+	ID        int64
+	CreatedAt time.Time
+
+	Name        string
+	ChannelType NotificationChannelType
+	Enabled     bool       `pg:",use_zero"`
+	Level       EventLevel `pg:",use_zero"`
+
+	MachineID       int64 `pg:",use_zero"`
+	AppID           int64 `pg:",use_zero"`
+	DaemonID        int64 `pg:",use_zero"`
+	SubnetID        int64 `pg:",use_zero"`
+	SharedNetworkID int64 `pg:",use_zero"`
+
+	RateLimit         int64 `pg:",use_zero"`
+	RateLimitInterval int64 `pg:",use_zero"`
+
+	Settings NotificationChannelSettings
+}
+
+// Sets the default rate limit interval if it is not specified.
+func (channel *NotificationChannel) setDefaults() {
+	if channel.RateLimitInterval <= 0 {
+		channel.RateLimitInterval = DefaultNotificationRateLimitInterval
+	}
+}
+
+// Inserts a notification channel into the database.
+func AddNotificationChannel(dbi dbops.DBI, channel *NotificationChannel) error {
+	channel.setDefaults()
+	_, err := dbi.Model(channel).Insert()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem inserting notification channel %s", channel.Name)
+	}
+	return err
+}
+
+// Updates the notification channel in the database.
+func UpdateNotificationChannel(dbi dbops.DBI, channel *NotificationChannel) error {
+	channel.setDefaults()
+	result, err := dbi.Model(channel).ExcludeColumn("created_at").WherePK().Update()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem updating notification channel with id %d", channel.ID)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "notification channel with id %d does not exist", channel.ID)
+	}
+	return nil
+}
+
+// Returns all notification channels ordered by ID.
+func GetNotificationChannels(dbi dbops.DBI) ([]NotificationChannel, error) {
+	channels := []NotificationChannel{}
+	err := dbi.Model(&channels).OrderExpr("id ASC").Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting notification channels")
+	}
+	return channels, nil
+}
+
+// Returns the enabled notification channels ordered by ID.
+func GetEnabledNotificationChannels(dbi dbops.DBI) ([]NotificationChannel, error) {
+	channels := []NotificationChannel{}
+	err := dbi.Model(&channels).Where("enabled = ?", true).OrderExpr("id ASC").Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting enabled notification channels")
+	}
+	return channels, nil
+}
+
+// Returns the notification channel by ID. It returns nil if the channel
+// does not exist.
+func GetNotificationChannelByID(dbi dbops.DBI, id int64) (*NotificationChannel, error) {
+	channel := &NotificationChannel{}
+	err := dbi.Model(channel).Where("id = ?", id).Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting notification channel with id %d", id)
+	}
+	return channel, nil
+}
+
+// Deletes the notification channel from the database.
+func DeleteNotificationChannel(dbi dbops.DBI, id int64) error {
+	channel := &NotificationChannel{
+		ID: id,
+	}
+	result, err := dbi.Model(channel).WherePK().Delete()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting notification channel with id %d", id)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "notification channel with id %d does not exist", id)
+	}
+	return nil
+}
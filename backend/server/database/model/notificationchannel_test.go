@@ -0,0 +1,98 @@
+package dbmodel
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test adding, updating, getting and deleting the notification channels.
+func TestNotificationChannels(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	channel1 := &NotificationChannel{
+		Name:        "mail",
+		ChannelType: NotificationChannelSMTP,
+		Enabled:     true,
+		Level:       EvWarning,
+		MachineID:   5,
+		RateLimit:   10,
+		Settings: NotificationChannelSettings{
+			SMTPHost:     "mail.example.org",
+			SMTPPort:     587,
+			SMTPUsername: "stork",
+			SMTPPassword: "secret",
+			SMTPFrom:     "stork@example.org",
+			SMTPTo:       []string{"admin@example.org"},
+		},
+	}
+	err := AddNotificationChannel(db, channel1)
+	require.NoError(t, err)
+	require.NotZero(t, channel1.ID)
+	require.EqualValues(t, DefaultNotificationRateLimitInterval, channel1.RateLimitInterval)
+
+	channel2 := &NotificationChannel{
+		Name:        "hook",
+		ChannelType: NotificationChannelWebhook,
+		Settings: NotificationChannelSettings{
+			URL: "https://example.org/hook",
+		},
+	}
+	err = AddNotificationChannel(db, channel2)
+	require.NoError(t, err)
+
+	// The channel names must be unique.
+	err = AddNotificationChannel(db, &NotificationChannel{
+		Name:        "hook",
+		ChannelType: NotificationChannelSlack,
+	})
+	require.Error(t, err)
+
+	channels, err := GetNotificationChannels(db)
+	require.NoError(t, err)
+	require.Len(t, channels, 2)
+	require.Equal(t, "mail", channels[0].Name)
+	require.Equal(t, NotificationChannelSMTP, channels[0].ChannelType)
+	require.True(t, channels[0].Enabled)
+	require.Equal(t, EvWarning, channels[0].Level)
+	require.EqualValues(t, 5, channels[0].MachineID)
+	require.EqualValues(t, 10, channels[0].RateLimit)
+	require.Equal(t, "secret", channels[0].Settings.SMTPPassword)
+	require.Equal(t, []string{"admin@example.org"}, channels[0].Settings.SMTPTo)
+	require.NotZero(t, channels[0].CreatedAt)
+	require.Equal(t, "hook", channels[1].Name)
+	require.False(t, channels[1].Enabled)
+
+	channels, err = GetEnabledNotificationChannels(db)
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+	require.Equal(t, "mail", channels[0].Name)
+
+	// Update the channel.
+	channel2.Enabled = true
+	channel2.Settings.URL = "https://example.org/other"
+	err = UpdateNotificationChannel(db, channel2)
+	require.NoError(t, err)
+
+	channel, err := GetNotificationChannelByID(db, channel2.ID)
+	require.NoError(t, err)
+	require.NotNil(t, channel)
+	require.True(t, channel.Enabled)
+	require.Equal(t, "https://example.org/other", channel.Settings.URL)
+
+	// Delete the channel.
+	err = DeleteNotificationChannel(db, channel2.ID)
+	require.NoError(t, err)
+
+	channel, err = GetNotificationChannelByID(db, channel2.ID)
+	require.NoError(t, err)
+	require.Nil(t, channel)
+
+	// The channel no longer exists.
+	err = DeleteNotificationChannel(db, channel2.ID)
+	require.ErrorIs(t, err, ErrNotExists)
+	err = UpdateNotificationChannel(db, channel2)
+	require.ErrorIs(t, err, ErrNotExists)
+}
@@ -4,6 +4,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/go-pg/pg/v10"
 	errors "github.com/pkg/errors"
 	keaconfig "isc.org/stork/appcfg/kea"
 	dhcpmodel "isc.org/stork/datamodel/dhcp"
@@ -29,6 +30,15 @@ type AddressPool struct {
 	LocalSubnet       *LocalSubnet `pg:"rel:has-one"`
 
 	KeaParameters *keaconfig.PoolParameters
+
+	// Statistics pulled from the Kea server and the address utilization
+	// in percentage multiplied by 10.
+	Stats            SubnetStats
+	StatsCollectedAt time.Time
+	Utilization      int16
+
+	// Forecasted number of days until the addresses are exhausted.
+	ExhaustionDays *float64
 }
 
 // Returns lower pool boundary.
@@ -74,6 +84,15 @@ type PrefixPool struct {
 	LocalSubnet       *LocalSubnet `pg:"rel:has-one"`
 
 	KeaParameters *keaconfig.PoolParameters
+
+	// Statistics pulled from the Kea server and the delegated prefix
+	// utilization in percentage multiplied by 10.
+	Stats            SubnetStats
+	StatsCollectedAt time.Time
+	Utilization      int16
+
+	// Forecasted number of days until the delegated prefixes are exhausted.
+	ExhaustionDays *float64
 }
 
 // Returns a pointer to a structure holding the delegated prefix data.
@@ -232,3 +251,96 @@ func DeletePrefixPool(db *dbops.PgDB, poolID int64) error {
 	}
 	return err
 }
+
+// Returns the address pools having the statistics pulled from the Kea
+// servers along with their local subnets, subnets and daemons.
+func GetAddressPoolsWithStats(dbi dbops.DBI) ([]*AddressPool, error) {
+	pools := []*AddressPool{}
+	err := dbi.Model(&pools).
+		Column("address_pool.id", "address_pool.lower_bound", "address_pool.upper_bound", "address_pool.local_subnet_id",
+			"address_pool.stats", "address_pool.stats_collected_at", "address_pool.utilization", "address_pool.exhaustion_days").
+		Relation("LocalSubnet.Subnet").
+		Relation("LocalSubnet.Daemon.App").
+		Where("address_pool.stats IS NOT NULL").
+		OrderExpr("address_pool.id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, errors.Wrap(err, "problem getting address pools with statistics")
+	}
+	return pools, nil
+}
+
+// Returns the prefix pools having the statistics pulled from the Kea
+// servers along with their local subnets, subnets and daemons.
+func GetPrefixPoolsWithStats(dbi dbops.DBI) ([]*PrefixPool, error) {
+	pools := []*PrefixPool{}
+	err := dbi.Model(&pools).
+		Column("prefix_pool.id", "prefix_pool.prefix", "prefix_pool.delegated_len", "prefix_pool.local_subnet_id",
+			"prefix_pool.stats", "prefix_pool.stats_collected_at", "prefix_pool.utilization", "prefix_pool.exhaustion_days").
+		Relation("LocalSubnet.Subnet").
+		Relation("LocalSubnet.Daemon.App").
+		Where("prefix_pool.stats IS NOT NULL").
+		OrderExpr("prefix_pool.id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, errors.Wrap(err, "problem getting prefix pools with statistics")
+	}
+	return pools, nil
+}
+
+// Calculates the utilization from the statistics as a fraction of the
+// assigned and total counters. The statistic names for IPv4 and IPv6
+// are both recognized. It returns zero when the total counter is zero.
+func getPoolUtilization(stats SubnetStats, assignedNames, totalNames []string) float64 {
+	var assigned, total float64
+	for _, name := range assignedNames {
+		if value, ok := stats[name]; ok {
+			assigned = statToFloat64(value)
+		}
+	}
+	for _, name := range totalNames {
+		if value, ok := stats[name]; ok {
+			total = statToFloat64(value)
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return assigned / total
+}
+
+// Updates the statistics pulled for the address pool and its utilization.
+func (ap *AddressPool) UpdateStats(dbi dbops.DBI, stats SubnetStats) error {
+	utilization := getPoolUtilization(stats, []string{"assigned-addresses", "assigned-nas"}, []string{"total-addresses", "total-nas"})
+	ap.Stats = stats
+	ap.StatsCollectedAt = storkutil.UTCNow()
+	ap.Utilization = int16(utilization * 1000)
+	result, err := dbi.Model(ap).
+		Column("stats", "stats_collected_at", "utilization").
+		WherePK().
+		Update()
+	if err != nil {
+		err = errors.Wrapf(err, "problem updating stats in address pool %s-%s", ap.LowerBound, ap.UpperBound)
+	} else if result.RowsAffected() <= 0 {
+		err = errors.Wrapf(ErrNotExists, "address pool with ID %d does not exist", ap.ID)
+	}
+	return err
+}
+
+// Updates the statistics pulled for the prefix pool and its utilization.
+func (pp *PrefixPool) UpdateStats(dbi dbops.DBI, stats SubnetStats) error {
+	utilization := getPoolUtilization(stats, []string{"assigned-pds"}, []string{"total-pds"})
+	pp.Stats = stats
+	pp.StatsCollectedAt = storkutil.UTCNow()
+	pp.Utilization = int16(utilization * 1000)
+	result, err := dbi.Model(pp).
+		Column("stats", "stats_collected_at", "utilization").
+		WherePK().
+		Update()
+	if err != nil {
+		err = errors.Wrapf(err, "problem updating stats in prefix pool %s", pp.Prefix)
+	} else if result.RowsAffected() <= 0 {
+		err = errors.Wrapf(ErrNotExists, "prefix pool with ID %d does not exist", pp.ID)
+	}
+	return err
+}
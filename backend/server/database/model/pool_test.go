@@ -481,3 +481,172 @@ func TestAddressPoolHasEqualDataTheSame(t *testing.T) {
 	require.True(t, equalityFirstSecond)
 	require.True(t, equalitySecondFirst)
 }
+
+// Test calculating the pool utilization from the IPv4 and IPv6 statistics.
+func TestGetPoolUtilization(t *testing.T) {
+	assigned := []string{"assigned-addresses", "assigned-nas"}
+	total := []string{"total-addresses", "total-nas"}
+
+	require.EqualValues(t, 0.25, getPoolUtilization(SubnetStats{
+		"assigned-addresses": uint64(25),
+		"total-addresses":    uint64(100),
+	}, assigned, total))
+
+	require.EqualValues(t, 0.5, getPoolUtilization(SubnetStats{
+		"assigned-nas": uint64(50),
+		"total-nas":    uint64(100),
+	}, assigned, total))
+
+	require.Zero(t, getPoolUtilization(SubnetStats{
+		"assigned-addresses": uint64(25),
+	}, assigned, total))
+}
+
+// Test updating the statistics and utilizations of the address and prefix
+// pools.
+func TestUpdatePoolStats(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+
+	subnet := Subnet{
+		Prefix: "2001:db8:1::/64",
+		LocalSubnets: []*LocalSubnet{
+			{
+				DaemonID: apps[0].Daemons[0].ID,
+				AddressPools: []AddressPool{
+					{
+						LowerBound: "2001:db8:1::10",
+						UpperBound: "2001:db8:1::ff",
+					},
+				},
+				PrefixPools: []PrefixPool{
+					{
+						Prefix:       "2001:db8:1:1::/80",
+						DelegatedLen: 96,
+					},
+				},
+			},
+		},
+	}
+	err := AddSubnet(db, &subnet)
+	require.NoError(t, err)
+	err = AddLocalSubnets(db, &subnet)
+	require.NoError(t, err)
+
+	localSubnets, err := GetAppLocalSubnets(db, apps[0].ID)
+	require.NoError(t, err)
+	require.Len(t, localSubnets, 1)
+	require.Len(t, localSubnets[0].AddressPools, 1)
+	require.Len(t, localSubnets[0].PrefixPools, 1)
+
+	err = localSubnets[0].AddressPools[0].UpdateStats(db, SubnetStats{
+		"total-nas":    uint64(240),
+		"assigned-nas": uint64(60),
+	})
+	require.NoError(t, err)
+	err = localSubnets[0].PrefixPools[0].UpdateStats(db, SubnetStats{
+		"total-pds":    uint64(65536),
+		"assigned-pds": uint64(32768),
+	})
+	require.NoError(t, err)
+
+	returnedSubnets, err := GetSubnetsByPrefix(db, "2001:db8:1::/64")
+	require.NoError(t, err)
+	require.Len(t, returnedSubnets, 1)
+	localSubnet := returnedSubnets[0].LocalSubnets[0]
+
+	addressPool := localSubnet.AddressPools[0]
+	require.EqualValues(t, 250, addressPool.Utilization)
+	require.EqualValues(t, 240, addressPool.Stats["total-nas"])
+	require.NotZero(t, addressPool.StatsCollectedAt)
+
+	prefixPool := localSubnet.PrefixPools[0]
+	require.EqualValues(t, 500, prefixPool.Utilization)
+	require.EqualValues(t, 32768, prefixPool.Stats["assigned-pds"])
+	require.NotZero(t, prefixPool.StatsCollectedAt)
+
+	// Non-existing pool.
+	pool := &AddressPool{ID: addressPool.ID + 100}
+	err = pool.UpdateStats(db, SubnetStats{})
+	require.ErrorIs(t, err, ErrNotExists)
+}
+
+// Test getting the pools having the statistics.
+func TestGetPoolsWithStats(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+
+	subnet := Subnet{
+		Prefix: "2001:db8:1::/64",
+		LocalSubnets: []*LocalSubnet{
+			{
+				DaemonID: apps[0].Daemons[0].ID,
+				AddressPools: []AddressPool{
+					{
+						LowerBound: "2001:db8:1::10",
+						UpperBound: "2001:db8:1::ff",
+					},
+					{
+						LowerBound: "2001:db8:1::100",
+						UpperBound: "2001:db8:1::1ff",
+					},
+				},
+				PrefixPools: []PrefixPool{
+					{
+						Prefix:       "2001:db8:1:1::/80",
+						DelegatedLen: 96,
+					},
+				},
+			},
+		},
+	}
+	err := AddSubnet(db, &subnet)
+	require.NoError(t, err)
+	err = AddLocalSubnets(db, &subnet)
+	require.NoError(t, err)
+
+	// No statistics yet.
+	addressPools, err := GetAddressPoolsWithStats(db)
+	require.NoError(t, err)
+	require.Empty(t, addressPools)
+	prefixPools, err := GetPrefixPoolsWithStats(db)
+	require.NoError(t, err)
+	require.Empty(t, prefixPools)
+
+	localSubnet := subnet.LocalSubnets[0]
+	err = localSubnet.AddressPools[1].UpdateStats(db, SubnetStats{
+		"total-nas":    uint64(256),
+		"assigned-nas": uint64(64),
+	})
+	require.NoError(t, err)
+	err = localSubnet.PrefixPools[0].UpdateStats(db, SubnetStats{
+		"total-pds":    uint64(65536),
+		"assigned-pds": uint64(32768),
+	})
+	require.NoError(t, err)
+
+	addressPools, err = GetAddressPoolsWithStats(db)
+	require.NoError(t, err)
+	require.Len(t, addressPools, 1)
+	require.Equal(t, "2001:db8:1::100", addressPools[0].LowerBound)
+	require.EqualValues(t, 250, addressPools[0].Utilization)
+	require.NotNil(t, addressPools[0].LocalSubnet)
+	require.NotNil(t, addressPools[0].LocalSubnet.Subnet)
+	require.Equal(t, "2001:db8:1::/64", addressPools[0].LocalSubnet.Subnet.Prefix)
+	require.NotNil(t, addressPools[0].LocalSubnet.Daemon)
+	require.NotNil(t, addressPools[0].LocalSubnet.Daemon.App)
+
+	prefixPools, err = GetPrefixPoolsWithStats(db)
+	require.NoError(t, err)
+	require.Len(t, prefixPools, 1)
+	require.Equal(t, "2001:db8:1:1::/80", prefixPools[0].Prefix)
+	require.EqualValues(t, 500, prefixPools[0].Utilization)
+	require.NotNil(t, prefixPools[0].LocalSubnet)
+	require.NotNil(t, prefixPools[0].LocalSubnet.Subnet)
+	require.NotNil(t, prefixPools[0].LocalSubnet.Daemon)
+	require.NotNil(t, prefixPools[0].LocalSubnet.Daemon.App)
+}
@@ -100,6 +100,52 @@ func GetScheduledConfigChanges(dbi dbops.DBI) ([]ScheduledConfigChange, error) {
 	return changes, err
 }
 
+// Returns the scheduled config change by ID. It returns nil if the change
+// does not exist.
+func GetScheduledConfigChange(dbi dbops.DBI, changeID int64) (*ScheduledConfigChange, error) {
+	change := &ScheduledConfigChange{}
+	err := dbi.Model(change).
+		Relation("User").
+		Where("scheduled_config_change.id = ?", changeID).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem with getting scheduled config change with id %d", changeID)
+	}
+	return change, nil
+}
+
+// Returns a page of the scheduled config changes ordered by deadline. The
+// offset and limit specify the beginning of the page and the maximum size
+// of the page. The executed parameter limits the returned changes to the
+// executed or pending ones. It is ignored when nil. It returns the changes,
+// the total number of the changes matching the filter and an error.
+func GetScheduledConfigChangesByPage(dbi dbops.DBI, offset, limit int64, executed *bool) ([]ScheduledConfigChange, int64, error) {
+	if limit == 0 {
+		return nil, 0, pkgerrors.New("limit should be greater than 0")
+	}
+	changes := []ScheduledConfigChange{}
+	q := dbi.Model(&changes).Relation("User")
+	if executed != nil {
+		q = q.Where("scheduled_config_change.executed = ?", *executed)
+	}
+	q = q.OrderExpr("scheduled_config_change.deadline_at ASC").
+		OrderExpr("scheduled_config_change.id ASC").
+		Offset(int(offset)).
+		Limit(int(limit))
+
+	total, err := q.SelectAndCount()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return []ScheduledConfigChange{}, 0, nil
+		}
+		return nil, 0, pkgerrors.Wrapf(err, "problem with getting scheduled config changes")
+	}
+	return changes, int64(total), nil
+}
+
 // Returns scheduled and not executed config changes which deadline has expired.
 func GetDueConfigChanges(dbi dbops.DBI) ([]ScheduledConfigChange, error) {
 	var changes []ScheduledConfigChange
@@ -140,6 +186,27 @@ func SetScheduledConfigChangeExecuted(dbi dbops.DBI, changeID int64, errtext str
 	return nil
 }
 
+// Sets new deadline for the specified config change. The change must not
+// be executed yet. Otherwise, an error is returned.
+func RescheduleConfigChange(dbi dbops.DBI, changeID int64, deadline time.Time) error {
+	change := &ScheduledConfigChange{
+		ID:         changeID,
+		DeadlineAt: deadline,
+	}
+	result, err := dbi.Model(change).
+		Column("deadline_at").
+		WherePK().
+		Where("executed = ?", false).
+		Update()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem with rescheduling config change %d", changeID)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "pending config change with id %d does not exist", changeID)
+	}
+	return nil
+}
+
 // Returns time in seconds to next scheduled config change.
 func GetTimeToNextScheduledConfigChange(dbi dbops.DBI) (time.Duration, bool, error) {
 	var tm struct {
@@ -317,6 +317,139 @@ func TestGetTimeToNextConfigChange(t *testing.T) {
 	require.LessOrEqual(t, tn, time.Second*25)
 }
 
+// Test getting the scheduled config changes by page and by ID.
+func TestGetScheduledConfigChangesByPage(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	// Scheduled config changes must be associated with a user.
+	user := &SystemUser{
+		Login:    "test",
+		Lastname: "test",
+		Name:     "test",
+	}
+	_, err := CreateUser(db, user)
+	require.NoError(t, err)
+	require.NotZero(t, user.ID)
+
+	// Add three config changes. The first one is executed.
+	for i := 0; i < 3; i++ {
+		change := &ScheduledConfigChange{
+			CreatedAt:  storkutil.UTCNow(),
+			DeadlineAt: storkutil.UTCNow().Add(time.Duration(3-i) * time.Hour),
+			UserID:     int64(user.ID),
+			Executed:   i == 0,
+			Updates: []*ConfigUpdate{
+				NewConfigUpdate(AppTypeKea, "host_add", int64(i+1)),
+			},
+		}
+		err = AddScheduledConfigChange(db, change)
+		require.NoError(t, err)
+	}
+
+	// Zero limit is not allowed.
+	_, _, err = GetScheduledConfigChangesByPage(db, 0, 0, nil)
+	require.Error(t, err)
+
+	// Get all changes. They should be ordered by deadline.
+	changes, total, err := GetScheduledConfigChangesByPage(db, 0, 10, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, total)
+	require.Len(t, changes, 3)
+	require.EqualValues(t, 3, changes[0].Updates[0].DaemonIDs[0])
+	require.EqualValues(t, 1, changes[2].Updates[0].DaemonIDs[0])
+	require.NotNil(t, changes[0].User)
+	require.Equal(t, "test", changes[0].User.Login)
+
+	// Get a page.
+	changes, total, err = GetScheduledConfigChangesByPage(db, 1, 1, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, total)
+	require.Len(t, changes, 1)
+	require.EqualValues(t, 2, changes[0].Updates[0].DaemonIDs[0])
+
+	// Get pending changes.
+	executed := false
+	changes, total, err = GetScheduledConfigChangesByPage(db, 0, 10, &executed)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Len(t, changes, 2)
+	require.False(t, changes[0].Executed)
+	require.False(t, changes[1].Executed)
+
+	// Get executed changes.
+	executed = true
+	changes, total, err = GetScheduledConfigChangesByPage(db, 0, 10, &executed)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Len(t, changes, 1)
+	require.True(t, changes[0].Executed)
+
+	// Get the change by ID.
+	change, err := GetScheduledConfigChange(db, changes[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, change)
+	require.Equal(t, changes[0].ID, change.ID)
+	require.NotNil(t, change.User)
+	require.Len(t, change.Updates, 1)
+
+	// Get non-existing change.
+	change, err = GetScheduledConfigChange(db, changes[0].ID+100)
+	require.NoError(t, err)
+	require.Nil(t, change)
+}
+
+// Test setting new deadline for the scheduled config change.
+func TestRescheduleConfigChange(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	// Scheduled config changes must be associated with a user.
+	user := &SystemUser{
+		Login:    "test",
+		Lastname: "test",
+		Name:     "test",
+	}
+	_, err := CreateUser(db, user)
+	require.NoError(t, err)
+	require.NotZero(t, user.ID)
+
+	change := &ScheduledConfigChange{
+		CreatedAt:  storkutil.UTCNow(),
+		DeadlineAt: storkutil.UTCNow().Add(time.Hour),
+		UserID:     int64(user.ID),
+		Updates: []*ConfigUpdate{
+			NewConfigUpdate(AppTypeKea, "host_add", 1),
+		},
+	}
+	err = AddScheduledConfigChange(db, change)
+	require.NoError(t, err)
+
+	deadline := storkutil.UTCNow().Add(2 * time.Hour)
+	err = RescheduleConfigChange(db, change.ID, deadline)
+	require.NoError(t, err)
+
+	returned, err := GetScheduledConfigChange(db, change.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.WithinDuration(t, deadline, returned.DeadlineAt, time.Millisecond)
+	require.Len(t, returned.Updates, 1)
+
+	// Non-existing change.
+	err = RescheduleConfigChange(db, change.ID+1, deadline)
+	require.ErrorIs(t, err, ErrNotExists)
+
+	// Executed change cannot be rescheduled.
+	err = SetScheduledConfigChangeExecuted(db, change.ID, "")
+	require.NoError(t, err)
+	err = RescheduleConfigChange(db, change.ID, deadline.Add(time.Hour))
+	require.ErrorIs(t, err, ErrNotExists)
+
+	returned, err = GetScheduledConfigChange(db, change.ID)
+	require.NoError(t, err)
+	require.WithinDuration(t, deadline, returned.DeadlineAt, time.Millisecond)
+}
+
 // Test deleting specified scheduled config change.
 func TestDeleteScheduledConfigChange(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
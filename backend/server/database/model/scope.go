@@ -0,0 +1,145 @@
+package dbmodel
+
+import (
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// Limits the objects fetched from the database to those associated with
+// the specified machines, apps or subnets. It is used to return only the
+// objects belonging to the scope of the user's groups. An object belongs
+// to the scope when any of the machines, apps or subnets it is associated
+// with is in the scope. The empty filter matches no objects. The nil filter
+// does not limit the fetched objects.
+type ScopeFilter struct {
+	MachineIDs []int64
+	AppIDs     []int64
+	SubnetIDs  []int64
+}
+
+// Checks if an object associated with the specified machines, apps and
+// subnets belongs to the scope.
+func (scope *ScopeFilter) Includes(machineIDs, appIDs, subnetIDs []int64) bool {
+	if scope == nil {
+		return true
+	}
+	return containsAnyID(scope.MachineIDs, machineIDs) ||
+		containsAnyID(scope.AppIDs, appIDs) ||
+		containsAnyID(scope.SubnetIDs, subnetIDs)
+}
+
+// Returns the subquery selecting the IDs of the daemons belonging to the
+// apps or machines in the scope and its parameters. It returns an empty
+// query if the scope contains no apps and machines.
+func (scope *ScopeFilter) getDaemonsSubquery() (string, []any) {
+	var (
+		conditions []string
+		params     []any
+	)
+	if len(scope.AppIDs) > 0 {
+		conditions = append(conditions, "scope_app.id IN (?)")
+		params = append(params, pg.In(scope.AppIDs))
+	}
+	if len(scope.MachineIDs) > 0 {
+		conditions = append(conditions, "scope_app.machine_id IN (?)")
+		params = append(params, pg.In(scope.MachineIDs))
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "SELECT scope_daemon.id FROM daemon AS scope_daemon " +
+		"JOIN app AS scope_app ON scope_daemon.app_id = scope_app.id " +
+		"WHERE " + strings.Join(conditions, " OR "), params
+}
+
+// Limits the query to the objects matching any of the conditions. The
+// conditions are built by the specified function. The query returns no
+// objects when the function returns no conditions.
+func (scope *ScopeFilter) apply(q *orm.Query, addConditions func(*orm.Query) *orm.Query) *orm.Query {
+	if scope == nil {
+		return q
+	}
+	return q.WhereGroup(func(qq *orm.Query) (*orm.Query, error) {
+		qq = qq.Where("FALSE")
+		return addConditions(qq), nil
+	})
+}
+
+// Limits the query selecting the machines to those belonging to the scope.
+func (scope *ScopeFilter) applyToMachines(q *orm.Query) *orm.Query {
+	return scope.apply(q, func(qq *orm.Query) *orm.Query {
+		if len(scope.MachineIDs) > 0 {
+			qq = qq.WhereOr("machine.id IN (?)", pg.In(scope.MachineIDs))
+		}
+		if len(scope.AppIDs) > 0 {
+			qq = qq.WhereOr("machine.id IN (SELECT scope_app.machine_id FROM app AS scope_app WHERE scope_app.id IN (?))", pg.In(scope.AppIDs))
+		}
+		return qq
+	})
+}
+
+// Limits the query selecting the apps to those belonging to the scope.
+func (scope *ScopeFilter) applyToApps(q *orm.Query) *orm.Query {
+	return scope.apply(q, func(qq *orm.Query) *orm.Query {
+		if len(scope.AppIDs) > 0 {
+			qq = qq.WhereOr("app.id IN (?)", pg.In(scope.AppIDs))
+		}
+		if len(scope.MachineIDs) > 0 {
+			qq = qq.WhereOr("app.machine_id IN (?)", pg.In(scope.MachineIDs))
+		}
+		return qq
+	})
+}
+
+// Appends the conditions matching the subnets belonging to the scope, i.e.,
+// the subnets in the scope and the subnets configured in the daemons of the
+// apps and machines in the scope. The subnetColumn is the column holding the
+// subnet ID.
+func (scope *ScopeFilter) whereOrSubnetInScope(q *orm.Query, subnetColumn string) *orm.Query {
+	if len(scope.SubnetIDs) > 0 {
+		q = q.WhereOr(subnetColumn+" IN (?)", pg.In(scope.SubnetIDs))
+	}
+	if daemons, params := scope.getDaemonsSubquery(); daemons != "" {
+		q = q.WhereOr(subnetColumn+" IN (SELECT scope_ls.subnet_id FROM local_subnet AS scope_ls WHERE scope_ls.daemon_id IN ("+daemons+"))", params...)
+	}
+	return q
+}
+
+// Limits the query selecting the subnets to those belonging to the scope.
+func (scope *ScopeFilter) applyToSubnets(q *orm.Query) *orm.Query {
+	return scope.apply(q, func(qq *orm.Query) *orm.Query {
+		return scope.whereOrSubnetInScope(qq, "subnet.id")
+	})
+}
+
+// Limits the query selecting the shared networks to those belonging to the
+// scope, i.e., the shared networks including the subnets belonging to the
+// scope and the shared networks configured in the daemons in the scope.
+func (scope *ScopeFilter) applyToSharedNetworks(q *orm.Query) *orm.Query {
+	return scope.apply(q, func(qq *orm.Query) *orm.Query {
+		if len(scope.SubnetIDs) > 0 {
+			qq = qq.WhereOr("shared_network.id IN (SELECT scope_subnet.shared_network_id FROM subnet AS scope_subnet WHERE scope_subnet.id IN (?))", pg.In(scope.SubnetIDs))
+		}
+		if daemons, params := scope.getDaemonsSubquery(); daemons != "" {
+			qq = qq.WhereOr("shared_network.id IN (SELECT scope_subnet.shared_network_id FROM subnet AS scope_subnet "+
+				"JOIN local_subnet AS scope_ls ON scope_ls.subnet_id = scope_subnet.id WHERE scope_ls.daemon_id IN ("+daemons+"))", params...)
+			qq = qq.WhereOr("shared_network.id IN (SELECT scope_lsn.shared_network_id FROM local_shared_network AS scope_lsn WHERE scope_lsn.daemon_id IN ("+daemons+"))", params...)
+		}
+		return qq
+	})
+}
+
+// Limits the query selecting the host reservations to those belonging to
+// the scope, i.e., the host reservations in the subnets belonging to the
+// scope and the host reservations configured in the daemons in the scope.
+func (scope *ScopeFilter) applyToHosts(q *orm.Query) *orm.Query {
+	return scope.apply(q, func(qq *orm.Query) *orm.Query {
+		qq = scope.whereOrSubnetInScope(qq, "host.subnet_id")
+		if daemons, params := scope.getDaemonsSubquery(); daemons != "" {
+			qq = qq.WhereOr("host.id IN (SELECT scope_lh.host_id FROM local_host AS scope_lh WHERE scope_lh.daemon_id IN ("+daemons+"))", params...)
+		}
+		return qq
+	})
+}
@@ -0,0 +1,252 @@
+package dbmodel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbops "isc.org/stork/server/database"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test objects used in the scope filter tests.
+type scopeTestObjects struct {
+	apps          []*App
+	subnets       []*Subnet
+	sharedNetwork *SharedNetwork
+	hosts         []*Host
+}
+
+// Adds two machines with one app each, three subnets, a shared network and
+// three host reservations. The first subnet belongs to the shared network
+// and is configured in the first app. The second subnet is configured in
+// the second app. The third subnet is not associated with any app. The
+// first host reservation belongs to the first subnet, the second one is a
+// global reservation configured in the second app and the third one belongs
+// to the third subnet.
+func addScopeTestObjects(t *testing.T, db *dbops.PgDB) *scopeTestObjects {
+	objects := &scopeTestObjects{
+		apps: addTestSubnetApps(t, db),
+	}
+
+	objects.sharedNetwork = &SharedNetwork{
+		Name:   "foo",
+		Family: 4,
+	}
+	err := AddSharedNetwork(db, objects.sharedNetwork)
+	require.NoError(t, err)
+
+	for i, prefix := range []string{"192.0.2.0/24", "192.0.3.0/24", "192.0.4.0/24"} {
+		subnet := &Subnet{
+			Prefix: prefix,
+		}
+		if i == 0 {
+			subnet.SharedNetworkID = objects.sharedNetwork.ID
+		}
+		err = AddSubnet(db, subnet)
+		require.NoError(t, err)
+		if i < len(objects.apps) {
+			err = AddDaemonToSubnet(db, subnet, objects.apps[i].Daemons[0])
+			require.NoError(t, err)
+		}
+		objects.subnets = append(objects.subnets, subnet)
+	}
+
+	for i, subnetID := range []int64{objects.subnets[0].ID, 0, objects.subnets[2].ID} {
+		host := &Host{
+			SubnetID: subnetID,
+			HostIdentifiers: []HostIdentifier{
+				{
+					Type:  "hw-address",
+					Value: []byte{1, 2, 3, 4, 5, byte(i)},
+				},
+			},
+		}
+		err = AddHost(db, host)
+		require.NoError(t, err)
+		objects.hosts = append(objects.hosts, host)
+	}
+	err = AddDaemonToHost(db, objects.hosts[1], objects.apps[1].Daemons[0].ID, HostDataSourceConfig)
+	require.NoError(t, err)
+
+	return objects
+}
+
+// Returns the IDs of the machines.
+func getMachineIDs(machines []Machine) (ids []int64) {
+	for _, machine := range machines {
+		ids = append(ids, machine.ID)
+	}
+	return
+}
+
+// Returns the IDs of the apps.
+func getAppIDs(apps []App) (ids []int64) {
+	for _, app := range apps {
+		ids = append(ids, app.ID)
+	}
+	return
+}
+
+// Returns the IDs of the subnets.
+func getSubnetIDs(subnets []Subnet) (ids []int64) {
+	for _, subnet := range subnets {
+		ids = append(ids, subnet.ID)
+	}
+	return
+}
+
+// Returns the IDs of the host reservations.
+func getHostIDs(hosts []Host) (ids []int64) {
+	for _, host := range hosts {
+		ids = append(ids, host.ID)
+	}
+	return
+}
+
+// Test that the nil scope filter does not limit the returned objects and
+// the empty scope filter excludes all objects.
+func TestScopeFilterNilAndEmpty(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	addScopeTestObjects(t, db)
+
+	machines, total, err := GetMachinesByPage(db, 0, 10, nil, nil, nil, "", SortDirAny)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Len(t, machines, 2)
+
+	machines, total, err = GetMachinesByPage(db, 0, 10, nil, nil, &ScopeFilter{}, "", SortDirAny)
+	require.NoError(t, err)
+	require.Zero(t, total)
+	require.Empty(t, machines)
+
+	apps, total, err := GetAppsByPage(db, 0, 10, nil, "", &ScopeFilter{}, "", SortDirAny)
+	require.NoError(t, err)
+	require.Zero(t, total)
+	require.Empty(t, apps)
+
+	subnets, total, err := GetSubnetsByPage(db, 0, 10, &SubnetsByPageFilters{Scope: &ScopeFilter{}}, "", SortDirAny)
+	require.NoError(t, err)
+	require.Zero(t, total)
+	require.Empty(t, subnets)
+
+	networks, total, err := GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, &ScopeFilter{}, "", SortDirAny)
+	require.NoError(t, err)
+	require.Zero(t, total)
+	require.Empty(t, networks)
+
+	hosts, total, err := GetHostsByPage(db, 0, 10, HostsByPageFilters{Scope: &ScopeFilter{}}, "", SortDirAny)
+	require.NoError(t, err)
+	require.Zero(t, total)
+	require.Empty(t, hosts)
+}
+
+// Test that the objects are filtered by the machines in the scope.
+func TestScopeFilterMachines(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	objects := addScopeTestObjects(t, db)
+	scope := &ScopeFilter{
+		MachineIDs: []int64{objects.apps[0].MachineID},
+	}
+
+	machines, _, err := GetMachinesByPage(db, 0, 10, nil, nil, scope, "", SortDirAny)
+	require.NoError(t, err)
+	require.Equal(t, []int64{objects.apps[0].MachineID}, getMachineIDs(machines))
+
+	apps, _, err := GetAppsByPage(db, 0, 10, nil, "", scope, "", SortDirAny)
+	require.NoError(t, err)
+	require.Equal(t, []int64{objects.apps[0].ID}, getAppIDs(apps))
+
+	subnets, _, err := GetSubnetsByPage(db, 0, 10, &SubnetsByPageFilters{Scope: scope}, "", SortDirAny)
+	require.NoError(t, err)
+	require.Equal(t, []int64{objects.subnets[0].ID}, getSubnetIDs(subnets))
+
+	networks, _, err := GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, scope, "", SortDirAny)
+	require.NoError(t, err)
+	require.Len(t, networks, 1)
+	require.EqualValues(t, objects.sharedNetwork.ID, networks[0].ID)
+
+	hosts, _, err := GetHostsByPage(db, 0, 10, HostsByPageFilters{Scope: scope}, "", SortDirAny)
+	require.NoError(t, err)
+	require.Equal(t, []int64{objects.hosts[0].ID}, getHostIDs(hosts))
+}
+
+// Test that the objects are filtered by the apps in the scope.
+func TestScopeFilterApps(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	objects := addScopeTestObjects(t, db)
+	scope := &ScopeFilter{
+		AppIDs: []int64{objects.apps[1].ID},
+	}
+
+	machines, _, err := GetMachinesByPage(db, 0, 10, nil, nil, scope, "", SortDirAny)
+	require.NoError(t, err)
+	require.Equal(t, []int64{objects.apps[1].MachineID}, getMachineIDs(machines))
+
+	apps, _, err := GetAppsByPage(db, 0, 10, nil, "", scope, "", SortDirAny)
+	require.NoError(t, err)
+	require.Equal(t, []int64{objects.apps[1].ID}, getAppIDs(apps))
+
+	subnets, _, err := GetSubnetsByPage(db, 0, 10, &SubnetsByPageFilters{Scope: scope}, "", SortDirAny)
+	require.NoError(t, err)
+	require.Equal(t, []int64{objects.subnets[1].ID}, getSubnetIDs(subnets))
+
+	networks, _, err := GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, scope, "", SortDirAny)
+	require.NoError(t, err)
+	require.Empty(t, networks)
+
+	// The global host reservation is configured in the app.
+	hosts, _, err := GetHostsByPage(db, 0, 10, HostsByPageFilters{Scope: scope}, "", SortDirAny)
+	require.NoError(t, err)
+	require.Equal(t, []int64{objects.hosts[1].ID}, getHostIDs(hosts))
+}
+
+// Test that the objects are filtered by the subnets in the scope.
+func TestScopeFilterSubnets(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	objects := addScopeTestObjects(t, db)
+	scope := &ScopeFilter{
+		SubnetIDs: []int64{objects.subnets[0].ID, objects.subnets[2].ID},
+	}
+
+	machines, _, err := GetMachinesByPage(db, 0, 10, nil, nil, scope, "", SortDirAny)
+	require.NoError(t, err)
+	require.Empty(t, machines)
+
+	subnets, _, err := GetSubnetsByPage(db, 0, 10, &SubnetsByPageFilters{Scope: scope}, "", SortDirAny)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int64{objects.subnets[0].ID, objects.subnets[2].ID}, getSubnetIDs(subnets))
+
+	networks, _, err := GetSharedNetworksByPage(db, 0, 10, 0, 0, nil, scope, "", SortDirAny)
+	require.NoError(t, err)
+	require.Len(t, networks, 1)
+	require.EqualValues(t, objects.sharedNetwork.ID, networks[0].ID)
+
+	hosts, _, err := GetHostsByPage(db, 0, 10, HostsByPageFilters{Scope: scope}, "", SortDirAny)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int64{objects.hosts[0].ID, objects.hosts[2].ID}, getHostIDs(hosts))
+}
+
+// Test checking if the objects belong to the scope.
+func TestScopeFilterIncludes(t *testing.T) {
+	var scope *ScopeFilter
+	require.True(t, scope.Includes(nil, nil, nil))
+
+	scope = &ScopeFilter{
+		MachineIDs: []int64{1},
+		AppIDs:     []int64{2},
+		SubnetIDs:  []int64{3},
+	}
+	require.True(t, scope.Includes([]int64{1}, nil, nil))
+	require.True(t, scope.Includes(nil, []int64{5, 2}, nil))
+	require.True(t, scope.Includes(nil, nil, []int64{3}))
+	require.False(t, scope.Includes([]int64{2}, []int64{3}, []int64{1}))
+	require.False(t, (&ScopeFilter{}).Includes([]int64{1}, []int64{2}, []int64{3}))
+}
@@ -467,6 +467,41 @@ func TestGetServicesByAppID(t *testing.T) {
 	require.Equal(t, services[3].Name, appServices[2].Name)
 }
 
+// Test getting services for a daemon.
+func TestGetServicesByDaemonID(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	services := addTestServices(t, db)
+	require.GreaterOrEqual(t, len(services), 4)
+
+	// The first daemon of the service2 also belongs to the service4.
+	daemonServices, err := GetDetailedServicesByDaemonID(db, services[1].Daemons[0].ID)
+	require.NoError(t, err)
+	require.Len(t, daemonServices, 2)
+	require.Equal(t, services[1].Name, daemonServices[0].Name)
+	require.Equal(t, services[3].Name, daemonServices[1].Name)
+	require.NotNil(t, daemonServices[0].HAService)
+	require.True(t, daemonArraysMatch(daemonServices[0].Daemons, services[1].Daemons))
+	require.NotNil(t, daemonServices[0].Daemons[0].App)
+
+	// Make the daemon shared between three services.
+	err = AddDaemonToService(db, services[0].ID, services[1].Daemons[0])
+	require.NoError(t, err)
+
+	daemonServices, err = GetDetailedServicesByDaemonID(db, services[1].Daemons[0].ID)
+	require.NoError(t, err)
+	require.Len(t, daemonServices, 3)
+	require.Equal(t, services[0].Name, daemonServices[0].Name)
+	require.Equal(t, services[1].Name, daemonServices[1].Name)
+	require.Equal(t, services[3].Name, daemonServices[2].Name)
+
+	// The daemon belongs to no services.
+	daemonServices, err = GetDetailedServicesByDaemonID(db, 12345)
+	require.NoError(t, err)
+	require.Empty(t, daemonServices)
+}
+
 // Test that it is possible to get apps by type and get the services
 // returned along with them.
 func TestGetAppWithServices(t *testing.T) {
@@ -58,6 +58,11 @@ func InitializeSettings(db *pg.DB, initialPullerInterval int64) error {
 			ValType: SettingValTypeInt,
 			Value:   longInterval,
 		},
+		{
+			Name:    "bind9_zones_puller_interval", // in seconds
+			ValType: SettingValTypeInt,
+			Value:   longInterval,
+		},
 		{
 			Name:    "kea_stats_puller_interval", // in seconds
 			ValType: SettingValTypeInt,
@@ -73,6 +78,11 @@ func InitializeSettings(db *pg.DB, initialPullerInterval int64) error {
 			ValType: SettingValTypeInt,
 			Value:   mediumInterval,
 		},
+		{
+			Name:    "kea_log_events_puller_interval", // in seconds
+			ValType: SettingValTypeInt,
+			Value:   shortInterval,
+		},
 		{
 			Name:    "apps_state_puller_interval", // in seconds
 			ValType: SettingValTypeInt,
@@ -93,6 +103,36 @@ func InitializeSettings(db *pg.DB, initialPullerInterval int64) error {
 			ValType: SettingValTypeInt,
 			Value:   shortInterval, // in seconds
 		},
+		{
+			Name:    "kea_two_phase_commit",
+			ValType: SettingValTypeBool,
+			Value:   "false",
+		},
+		{
+			Name:    "utilization_raw_retention", // in hours
+			ValType: SettingValTypeInt,
+			Value:   "48",
+		},
+		{
+			Name:    "utilization_hourly_retention", // in days
+			ValType: SettingValTypeInt,
+			Value:   "30",
+		},
+		{
+			Name:    "utilization_daily_retention", // in days
+			ValType: SettingValTypeInt,
+			Value:   "730",
+		},
+		{
+			Name:    "utilization_forecast_window", // in days
+			ValType: SettingValTypeInt,
+			Value:   "7",
+		},
+		{
+			Name:    "utilization_forecast_horizon", // in days
+			ValType: SettingValTypeInt,
+			Value:   "14",
+		},
 	}
 
 	// Check if there are new settings vs existing ones. Add new ones to DB.
@@ -37,6 +37,10 @@ func TestInitializeSettings(t *testing.T) {
 	require.NoError(t, err)
 	require.EqualValues(t, 60, val)
 
+	val, err = GetSettingInt(db, "bind9_zones_puller_interval")
+	require.NoError(t, err)
+	require.EqualValues(t, 60, val)
+
 	val, err = GetSettingInt(db, "kea_stats_puller_interval")
 	require.NoError(t, err)
 	require.EqualValues(t, 60, val)
@@ -86,6 +90,7 @@ func TestInitializeSettingsWithInterval(t *testing.T) {
 	appsStateInterval, err6 := GetSettingInt(db, "apps_state_puller_interval")
 	haStatusInterval, err7 := GetSettingInt(db, "kea_status_puller_interval")
 	metricsInterval, err8 := GetSettingInt(db, "metrics_collector_interval")
+	logEventsInterval, err9 := GetSettingInt(db, "kea_log_events_puller_interval")
 
 	// Assert
 	require.NoError(t, err1)
@@ -96,6 +101,7 @@ func TestInitializeSettingsWithInterval(t *testing.T) {
 	require.NoError(t, err6)
 	require.NoError(t, err7)
 	require.NoError(t, err8)
+	require.NoError(t, err9)
 
 	require.EqualValues(t, 42, bind9Interval)
 	require.EqualValues(t, 42, keaStatsInterval)
@@ -103,6 +109,7 @@ func TestInitializeSettingsWithInterval(t *testing.T) {
 	require.EqualValues(t, 42, appsStateInterval)
 	require.EqualValues(t, 42, haStatusInterval)
 	require.EqualValues(t, 42, metricsInterval)
+	require.EqualValues(t, 42, logEventsInterval)
 }
 
 // Check getting and setting settings.
@@ -33,6 +33,17 @@ type SharedNetwork struct {
 	PdUtilization    int16
 	Stats            SubnetStats
 	StatsCollectedAt time.Time
+
+	// Current utilization alert levels. They are maintained by the
+	// utilization alert evaluation.
+	AddrUtilizationAlert UtilizationAlertLevel `pg:",use_zero"`
+	PdUtilizationAlert   UtilizationAlertLevel `pg:",use_zero"`
+
+	// Forecasted numbers of days remaining until the addresses and the
+	// delegated prefixes are exhausted. They are nil when the exhaustion
+	// is not forecasted, e.g., when the utilization doesn't grow.
+	AddrExhaustionDays *float64
+	PdExhaustionDays   *float64
 }
 
 // This structure holds shared network information retrieved from an app.
@@ -147,7 +158,10 @@ func AddLocalSharedNetworks(dbi dbops.DBI, network *SharedNetwork) error {
 // Updates shared network in the database in a transaction. It neither adds
 // nor modifies associations with the subnets it contains.
 func updateSharedNetwork(tx *pg.Tx, network *SharedNetwork) error {
-	result, err := tx.Model(network).WherePK().ExcludeColumn("created_at").Update()
+	result, err := tx.Model(network).WherePK().
+		ExcludeColumn("created_at", "addr_utilization_alert", "pd_utilization_alert",
+			"addr_exhaustion_days", "pd_exhaustion_days").
+		Update()
 	if err != nil {
 		err = pkgerrors.Wrapf(err, "problem updating the shared network with ID %d", network.ID)
 	} else if result.RowsAffected() <= 0 {
@@ -169,6 +183,109 @@ func UpdateSharedNetwork(dbi dbops.DBI, network *SharedNetwork) error {
 	return updateSharedNetwork(dbi.(*pg.Tx), network)
 }
 
+// Associates the subnets listed in the shared network instance with this
+// shared network and dissociates the subnets that are no longer listed.
+// The subnets must already exist in the database. The dissociated subnets
+// become top-level (global) subnets.
+func setSharedNetworkSubnets(tx *pg.Tx, network *SharedNetwork) error {
+	var subnetIDs []int64
+	for _, subnet := range network.Subnets {
+		subnetIDs = append(subnetIDs, subnet.ID)
+	}
+	q := tx.Model((*Subnet)(nil)).
+		Set("shared_network_id = NULL").
+		Where("shared_network_id = ?", network.ID)
+	if len(subnetIDs) > 0 {
+		q = q.WhereIn("id NOT IN (?)", subnetIDs)
+	}
+	if _, err := q.Update(); err != nil {
+		return pkgerrors.Wrapf(err, "problem dissociating subnets from the shared network %s", network.Name)
+	}
+	if len(subnetIDs) == 0 {
+		return nil
+	}
+	_, err := tx.Model((*Subnet)(nil)).
+		Set("shared_network_id = ?", network.ID).
+		WhereIn("id IN (?)", subnetIDs).
+		Update()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem associating subnets with the shared network %s", network.Name)
+	}
+	for i := range network.Subnets {
+		network.Subnets[i].SharedNetworkID = network.ID
+	}
+	return nil
+}
+
+// Adds a shared network with its local shared networks into the database
+// within a transaction. In contrast to the addSharedNetwork, the subnets
+// held in the shared network instance are not inserted. They must already
+// exist in the database and are merely associated with the new shared network.
+func addSharedNetworkWithLocalSharedNetworks(tx *pg.Tx, network *SharedNetwork) error {
+	_, err := tx.Model(network).Insert()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem adding new shared network %s to the database", network.Name)
+	}
+	if err = AddLocalSharedNetworks(tx, network); err != nil {
+		return err
+	}
+	return setSharedNetworkSubnets(tx, network)
+}
+
+// Attempts to add a shared network, its local shared networks and the
+// associations with the existing subnets within a transaction. If the
+// dbi does not point to a transaction, a new transaction is started.
+func AddSharedNetworkWithLocalSharedNetworks(dbi dbops.DBI, network *SharedNetwork) error {
+	if db, ok := dbi.(*pg.DB); ok {
+		return db.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+			return addSharedNetworkWithLocalSharedNetworks(tx, network)
+		})
+	}
+	return addSharedNetworkWithLocalSharedNetworks(dbi.(*pg.Tx), network)
+}
+
+// Updates a shared network with its local shared networks within a
+// transaction. The associations with the daemons that are no longer
+// present in the shared network instance are removed. The subnets
+// are associated with or dissociated from the shared network according
+// to the subnets list in the shared network instance.
+func updateSharedNetworkWithLocalSharedNetworks(tx *pg.Tx, network *SharedNetwork) error {
+	err := updateSharedNetwork(tx, network)
+	if err != nil {
+		return err
+	}
+	// Delete associations with the daemons no longer serving the shared network.
+	q := tx.Model((*LocalSharedNetwork)(nil)).
+		Where("shared_network_id = ?", network.ID)
+	var daemonIDs []int64
+	for _, lsn := range network.LocalSharedNetworks {
+		daemonIDs = append(daemonIDs, lsn.DaemonID)
+	}
+	if len(daemonIDs) > 0 {
+		q = q.WhereIn("daemon_id NOT IN (?)", daemonIDs)
+	}
+	if _, err = q.Delete(); err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting daemons from shared network %d", network.ID)
+	}
+	// Add or update the remaining associations.
+	if err = AddLocalSharedNetworks(tx, network); err != nil {
+		return err
+	}
+	return setSharedNetworkSubnets(tx, network)
+}
+
+// Attempts to update a shared network, its local shared networks and the
+// associations with the subnets within a transaction. If the dbi does not
+// point to a transaction, a new transaction is started.
+func UpdateSharedNetworkWithLocalSharedNetworks(dbi dbops.DBI, network *SharedNetwork) error {
+	if db, ok := dbi.(*pg.DB); ok {
+		return db.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+			return updateSharedNetworkWithLocalSharedNetworks(tx, network)
+		})
+	}
+	return updateSharedNetworkWithLocalSharedNetworks(dbi.(*pg.Tx), network)
+}
+
 // Dissociates a daemon from the shared networks. The first returned value
 // indicates if any row was removed from the local_shared_network table.
 func DeleteDaemonFromSharedNetworks(dbi dbops.DBI, daemonID int64) (int64, error) {
@@ -242,12 +359,13 @@ func GetSharedNetwork(dbi dbops.DBI, networkID int64) (network *SharedNetwork, e
 // family parameter both IPv4 and IPv6 shared networks are
 // returned. The filterText can be used to match the shared network
 // name or subnet prefix. The nil value disables such
-// filtering. sortField allows indicating sort column in database and
+// filtering. The scope limits the shared networks to those belonging
+// to the user's scope. The nil value disables such filtering. sortField allows indicating sort column in database and
 // sortDir allows selection the order of sorting. If sortField is
 // empty then id is used for sorting.  in SortDirAny is used then ASC
 // order is used. This function returns a collection of shared
 // networks, the total number of shared networks and error.
-func GetSharedNetworksByPage(dbi dbops.DBI, offset, limit, appID, family int64, filterText *string, sortField string, sortDir SortDirEnum) ([]SharedNetwork, int64, error) {
+func GetSharedNetworksByPage(dbi dbops.DBI, offset, limit, appID, family int64, filterText *string, scope *ScopeFilter, sortField string, sortDir SortDirEnum) ([]SharedNetwork, int64, error) {
 	networks := []SharedNetwork{}
 	q := dbi.Model(&networks)
 
@@ -297,6 +415,9 @@ func GetSharedNetworksByPage(dbi dbops.DBI, offset, limit, appID, family int64,
 		q = q.Where("d.app_id = ?", appID)
 	}
 
+	// Filter by the user's scope.
+	q = scope.applyToSharedNetworks(q)
+
 	// Quick filtering by shared network name or subnet prefix.
 	if filterText != nil {
 		q = q.WhereGroup(func(q *orm.Query) (*orm.Query, error) {
@@ -451,6 +572,61 @@ func (sn *SharedNetwork) SetLocalSharedNetwork(localSharedNetwork *LocalSharedNe
 	sn.LocalSharedNetworks = append(sn.LocalSharedNetworks, localSharedNetwork)
 }
 
+// Fetches daemon information for each daemon ID within the local shared
+// networks. The shared network information can be partial when it is
+// created from the request received over the REST API. In particular, the
+// LocalSharedNetworks can merely contain DaemonID values and the Daemon
+// pointers can be nil. If any of the daemons does not exist or an error
+// occurs, the shared network is not updated.
+func (sn *SharedNetwork) PopulateDaemons(dbi dbops.DBI) error {
+	var daemons []*Daemon
+	for _, lsn := range sn.LocalSharedNetworks {
+		// DaemonID is required for this function to run.
+		if lsn.DaemonID == 0 {
+			return pkgerrors.Errorf("problem with populating daemons: shared network %d lacks daemon ID", sn.ID)
+		}
+		daemon, err := GetDaemonByID(dbi, lsn.DaemonID)
+		if err != nil {
+			return pkgerrors.WithMessage(err, "problem with populating daemons")
+		}
+		// Daemon does not exist.
+		if daemon == nil {
+			return pkgerrors.Errorf("problem with populating daemons for shared network %d: daemon %d does not exist", sn.ID, lsn.DaemonID)
+		}
+		daemons = append(daemons, daemon)
+	}
+	// Everything fine. Assign fetched daemons to the shared network.
+	for i := range sn.LocalSharedNetworks {
+		sn.LocalSharedNetworks[i].Daemon = daemons[i]
+	}
+	return nil
+}
+
+// Fetches full subnet information for each subnet ID within the shared
+// network. The shared network created from the request received over the
+// REST API merely contains the IDs of the subnets that should belong to it.
+// This function replaces them with the subnets fetched from the database,
+// including their local subnets, daemons and current shared networks.
+// If any of the subnets does not exist or an error occurs, the shared
+// network is not updated.
+func (sn *SharedNetwork) PopulateSubnets(dbi dbops.DBI) error {
+	var subnets []Subnet
+	for _, s := range sn.Subnets {
+		subnet, err := GetSubnet(dbi, s.ID)
+		if err != nil {
+			return pkgerrors.WithMessage(err, "problem with populating subnets")
+		}
+		// Subnet does not exist.
+		if subnet == nil {
+			return pkgerrors.Errorf("problem with populating subnets for shared network %d: subnet %d does not exist", sn.ID, s.ID)
+		}
+		subnets = append(subnets, *subnet)
+	}
+	// Everything fine. Assign fetched subnets to the shared network.
+	sn.Subnets = subnets
+	return nil
+}
+
 // Combines two hosts into a single host by copying LocalHost data from
 // the other host.
 func (sn *SharedNetwork) Join(other *SharedNetwork) {
@@ -249,7 +249,7 @@ func TestAddSharedNetworkWithSubnetsPools(t *testing.T) {
 	})
 
 	t.Run("GetSharedNetworksByPage", func(t *testing.T) {
-		returnedNetworks, total, err := GetSharedNetworksByPage(db, 0, 10, apps[1].ID, 6, nil, "", SortDirAny)
+		returnedNetworks, total, err := GetSharedNetworksByPage(db, 0, 10, apps[1].ID, 6, nil, nil, "", SortDirAny)
 		require.NoError(t, err)
 		require.EqualValues(t, 1, total)
 		require.Len(t, returnedNetworks, 1)
@@ -432,6 +432,131 @@ func TestUpdateSharedNetwork(t *testing.T) {
 	require.Equal(t, createdAt, returned.CreatedAt)
 }
 
+// Tests that a shared network can be added with its local shared networks
+// and associated with the existing subnets.
+func TestAddSharedNetworkWithLocalSharedNetworks(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+
+	subnet := &Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err := AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	network := &SharedNetwork{
+		Name:   "foo",
+		Family: 4,
+		LocalSharedNetworks: []*LocalSharedNetwork{
+			{
+				DaemonID: apps[0].Daemons[0].ID,
+			},
+			{
+				DaemonID: apps[1].Daemons[0].ID,
+			},
+		},
+		Subnets: []Subnet{
+			{
+				ID: subnet.ID,
+			},
+		},
+	}
+	err = AddSharedNetworkWithLocalSharedNetworks(db, network)
+	require.NoError(t, err)
+	require.NotZero(t, network.ID)
+
+	returned, err := GetSharedNetwork(db, network.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, "foo", returned.Name)
+	require.Len(t, returned.LocalSharedNetworks, 2)
+	require.Len(t, returned.Subnets, 1)
+	require.Equal(t, subnet.ID, returned.Subnets[0].ID)
+	require.Equal(t, "192.0.2.0/24", returned.Subnets[0].Prefix)
+}
+
+// Tests that a shared network can be updated with its local shared networks
+// and the associations with the subnets.
+func TestUpdateSharedNetworkWithLocalSharedNetworks(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+
+	network := &SharedNetwork{
+		Name:   "foo",
+		Family: 4,
+		LocalSharedNetworks: []*LocalSharedNetwork{
+			{
+				DaemonID: apps[0].Daemons[0].ID,
+			},
+			{
+				DaemonID: apps[1].Daemons[0].ID,
+			},
+		},
+		Subnets: []Subnet{
+			{
+				Prefix: "192.0.2.0/24",
+			},
+		},
+	}
+	err := AddSharedNetwork(db, network)
+	require.NoError(t, err)
+	err = AddLocalSharedNetworks(db, network)
+	require.NoError(t, err)
+
+	subnet := &Subnet{
+		Prefix: "192.0.3.0/24",
+	}
+	err = AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	// Rename the shared network, remove one daemon and replace the
+	// subnet with the other one.
+	network.Name = "bar"
+	network.LocalSharedNetworks = network.LocalSharedNetworks[1:]
+	network.LocalSharedNetworks[0].KeaParameters = &keaconfig.SharedNetworkParameters{
+		Interface: storkutil.Ptr("eth0"),
+	}
+	oldSubnetID := network.Subnets[0].ID
+	network.Subnets = []Subnet{
+		{
+			ID: subnet.ID,
+		},
+	}
+	err = UpdateSharedNetworkWithLocalSharedNetworks(db, network)
+	require.NoError(t, err)
+
+	returned, err := GetSharedNetwork(db, network.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, "bar", returned.Name)
+	require.Len(t, returned.LocalSharedNetworks, 1)
+	require.Equal(t, apps[1].Daemons[0].ID, returned.LocalSharedNetworks[0].DaemonID)
+	require.NotNil(t, returned.LocalSharedNetworks[0].KeaParameters)
+	require.Equal(t, "eth0", *returned.LocalSharedNetworks[0].KeaParameters.Interface)
+	require.Len(t, returned.Subnets, 1)
+	require.Equal(t, subnet.ID, returned.Subnets[0].ID)
+
+	// The subnet removed from the shared network should still exist.
+	oldSubnet, err := GetSubnet(db, oldSubnetID)
+	require.NoError(t, err)
+	require.NotNil(t, oldSubnet)
+	require.Zero(t, oldSubnet.SharedNetworkID)
+
+	// Remove all subnets from the shared network.
+	network.Subnets = []Subnet{}
+	err = UpdateSharedNetworkWithLocalSharedNetworks(db, network)
+	require.NoError(t, err)
+
+	returned, err = GetSharedNetwork(db, network.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Empty(t, returned.Subnets)
+}
+
 // Tests that the shared network can be deleted.
 func TestDeleteSharedNetwork(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
@@ -725,3 +850,69 @@ func TestJoinSharedNetworks(t *testing.T) {
 	require.EqualValues(t, 2, sharedNetwork0.LocalSharedNetworks[1].DaemonID)
 	require.EqualValues(t, 3, sharedNetwork0.LocalSharedNetworks[2].DaemonID)
 }
+
+// Test that the daemons are populated for the local shared networks.
+func TestPopulateSharedNetworkDaemons(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+
+	network := &SharedNetwork{
+		Name: "foo",
+		LocalSharedNetworks: []*LocalSharedNetwork{
+			{
+				DaemonID: apps[0].Daemons[0].ID,
+			},
+			{
+				DaemonID: apps[1].Daemons[0].ID,
+			},
+		},
+	}
+	err := network.PopulateDaemons(db)
+	require.NoError(t, err)
+	require.NotNil(t, network.LocalSharedNetworks[0].Daemon)
+	require.NotNil(t, network.LocalSharedNetworks[0].Daemon.App)
+	require.Equal(t, apps[0].Daemons[0].ID, network.LocalSharedNetworks[0].Daemon.ID)
+	require.NotNil(t, network.LocalSharedNetworks[1].Daemon)
+	require.Equal(t, apps[1].Daemons[0].ID, network.LocalSharedNetworks[1].Daemon.ID)
+
+	// Non-existing daemon.
+	network.LocalSharedNetworks[1].DaemonID = 1024
+	network.LocalSharedNetworks[1].Daemon = nil
+	err = network.PopulateDaemons(db)
+	require.Error(t, err)
+	require.Nil(t, network.LocalSharedNetworks[1].Daemon)
+}
+
+// Test that the subnets are populated for the shared network.
+func TestPopulateSharedNetworkSubnets(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	subnet := &Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err := AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	network := &SharedNetwork{
+		Name: "foo",
+		Subnets: []Subnet{
+			{
+				ID: subnet.ID,
+			},
+		},
+	}
+	err = network.PopulateSubnets(db)
+	require.NoError(t, err)
+	require.Len(t, network.Subnets, 1)
+	require.Equal(t, "192.0.2.0/24", network.Subnets[0].Prefix)
+
+	// Non-existing subnet.
+	network.Subnets = append(network.Subnets, Subnet{ID: 1024})
+	err = network.PopulateSubnets(db)
+	require.Error(t, err)
+	require.Len(t, network.Subnets, 2)
+	require.Empty(t, network.Subnets[1].Prefix)
+}
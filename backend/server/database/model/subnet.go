@@ -166,6 +166,17 @@ type Subnet struct {
 	PdUtilization    int16
 	Stats            SubnetStats
 	StatsCollectedAt time.Time
+
+	// Current utilization alert levels. They are maintained by the
+	// utilization alert evaluation.
+	AddrUtilizationAlert UtilizationAlertLevel `pg:",use_zero"`
+	PdUtilizationAlert   UtilizationAlertLevel `pg:",use_zero"`
+
+	// Forecasted numbers of days remaining until the addresses and the
+	// delegated prefixes are exhausted. They are nil when the exhaustion
+	// is not forecasted, e.g., when the utilization doesn't grow.
+	AddrExhaustionDays *float64
+	PdExhaustionDays   *float64
 }
 
 // Returns local subnet id for the specified daemon.
@@ -254,6 +265,57 @@ func (s *Subnet) SetLocalSubnet(localSubnet *LocalSubnet) {
 	s.LocalSubnets = append(s.LocalSubnets, localSubnet)
 }
 
+// Fetches daemon information for each daemon ID within the local subnets.
+// The subnet information can be partial when it is created from the request
+// received over the REST API. In particular, the LocalSubnets can merely
+// contain DaemonID values and the Daemon pointers can be nil. In order
+// to initialize Daemon pointers, this function fetches the daemons from
+// the database and assigns them to the respective LocalSubnet instances.
+// If any of the daemons does not exist or an error occurs, the subnet
+// is not updated.
+func (s *Subnet) PopulateDaemons(dbi dbops.DBI) error {
+	var daemons []*Daemon
+	for _, ls := range s.LocalSubnets {
+		// DaemonID is required for this function to run.
+		if ls.DaemonID == 0 {
+			return pkgerrors.Errorf("problem with populating daemons: subnet %d lacks daemon ID", s.ID)
+		}
+		daemon, err := GetDaemonByID(dbi, ls.DaemonID)
+		if err != nil {
+			return pkgerrors.WithMessage(err, "problem with populating daemons")
+		}
+		// Daemon does not exist.
+		if daemon == nil {
+			return pkgerrors.Errorf("problem with populating daemons for subnet %d: daemon %d does not exist", s.ID, ls.DaemonID)
+		}
+		daemons = append(daemons, daemon)
+	}
+	// Everything fine. Assign fetched daemons to the subnet.
+	for i := range s.LocalSubnets {
+		s.LocalSubnets[i].Daemon = daemons[i]
+	}
+	return nil
+}
+
+// Fetches shared network information for a non-zero shared network ID in
+// the subnet. The subnet information can be partial when it is created from
+// the request received over the REST API. This function is no-op when the
+// shared network ID is 0 or when the SharedNetwork pointer is already
+// non-nil. If the shared network doesn't exist, an error is returned.
+func (s *Subnet) PopulateSharedNetwork(dbi dbops.DBI) error {
+	if s.SharedNetworkID != 0 && s.SharedNetwork == nil {
+		sharedNetwork, err := GetSharedNetwork(dbi, s.SharedNetworkID)
+		if err != nil {
+			return pkgerrors.WithMessagef(err, "problem with populating shared network %d for subnet %d", s.SharedNetworkID, s.ID)
+		}
+		if sharedNetwork == nil {
+			return pkgerrors.Errorf("problem with populating shared network %d for subnet %d because such shared network does not exist", s.SharedNetworkID, s.ID)
+		}
+		s.SharedNetwork = sharedNetwork
+	}
+	return nil
+}
+
 // Combines two hosts into a single host by copying LocalHost data from
 // the other host.
 func (s *Subnet) Join(other *Subnet) {
@@ -358,8 +420,14 @@ func addSubnet(tx *pg.Tx, subnet *Subnet) (err error) {
 
 // Updates a subnet in the database within a transaction.
 func updateSubnet(dbi dbops.DBI, subnet *Subnet) (err error) {
-	// Update the subnet first.
-	_, err = dbi.Model(subnet).WherePK().ExcludeColumn("created_at").Update()
+	// Update the subnet first. The statistics are maintained by the
+	// statistics puller, so they must not be overwritten with the values
+	// from the subnet instance built from the configuration.
+	_, err = dbi.Model(subnet).WherePK().
+		ExcludeColumn("created_at", "addr_utilization_alert", "pd_utilization_alert",
+			"addr_exhaustion_days", "pd_exhaustion_days", "addr_utilization",
+			"pd_utilization", "stats", "stats_collected_at").
+		Update()
 
 	if err != nil {
 		err = pkgerrors.Wrapf(err, "problem updating subnet with prefix %s", subnet.Prefix)
@@ -405,6 +473,78 @@ func AddLocalSubnets(dbi dbops.DBI, subnet *Subnet) error {
 	return nil
 }
 
+// Adds a subnet with its local subnets and pools into the database
+// within a transaction.
+func addSubnetWithLocalSubnets(tx *pg.Tx, subnet *Subnet) error {
+	err := addSubnet(tx, subnet)
+	if err != nil {
+		return err
+	}
+	return AddLocalSubnets(tx, subnet)
+}
+
+// Attempts to add a subnet and its local subnets within a transaction. If
+// the dbi does not point to a transaction, a new transaction is started.
+func AddSubnetWithLocalSubnets(dbi dbops.DBI, subnet *Subnet) error {
+	if db, ok := dbi.(*pg.DB); ok {
+		return db.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+			return addSubnetWithLocalSubnets(tx, subnet)
+		})
+	}
+	return addSubnetWithLocalSubnets(dbi.(*pg.Tx), subnet)
+}
+
+// Updates a subnet with its local subnets and pools within a transaction.
+// The associations with the daemons that are no longer present in the
+// subnet instance are removed.
+func updateSubnetWithLocalSubnets(tx *pg.Tx, subnet *Subnet) error {
+	err := updateSubnet(tx, subnet)
+	if err != nil {
+		return err
+	}
+	// Delete associations with the daemons no longer serving the subnet.
+	q := tx.Model((*LocalSubnet)(nil)).
+		Where("subnet_id = ?", subnet.ID)
+	var daemonIDs []int64
+	for _, ls := range subnet.LocalSubnets {
+		daemonIDs = append(daemonIDs, ls.DaemonID)
+	}
+	if len(daemonIDs) > 0 {
+		q = q.WhereIn("daemon_id NOT IN (?)", daemonIDs)
+	}
+	if _, err = q.Delete(); err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting daemons from subnet %d", subnet.ID)
+	}
+	// Add or update the remaining associations.
+	return AddLocalSubnets(tx, subnet)
+}
+
+// Attempts to update a subnet and its local subnets within a transaction.
+// If the dbi does not point to a transaction, a new transaction is started.
+func UpdateSubnetWithLocalSubnets(dbi dbops.DBI, subnet *Subnet) error {
+	if db, ok := dbi.(*pg.DB); ok {
+		return db.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+			return updateSubnetWithLocalSubnets(tx, subnet)
+		})
+	}
+	return updateSubnetWithLocalSubnets(dbi.(*pg.Tx), subnet)
+}
+
+// Deletes the subnet by ID. The local subnets and pools are removed by
+// cascade. It returns ErrNotExists when the subnet does not exist.
+func DeleteSubnet(dbi dbops.DBI, subnetID int64) error {
+	subnet := &Subnet{
+		ID: subnetID,
+	}
+	result, err := dbi.Model(subnet).WherePK().Delete()
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem deleting the subnet with ID %d", subnetID)
+	} else if result.RowsAffected() <= 0 {
+		err = pkgerrors.Wrapf(ErrNotExists, "subnet with ID %d does not exist", subnetID)
+	}
+	return err
+}
+
 // Fetches the subnet and its pools by id from the database.
 func GetSubnet(dbi dbops.DBI, subnetID int64) (*Subnet, error) {
 	subnet := &Subnet{}
@@ -551,6 +691,7 @@ type SubnetsByPageFilters struct {
 	LocalSubnetID *int64
 	Family        *int64
 	Text          *string
+	Scope         *ScopeFilter
 }
 
 // Shorthand to set the IPv4 family.
@@ -621,6 +762,9 @@ func GetSubnetsByPage(dbi dbops.DBI, offset, limit int64, filters *SubnetsByPage
 		q = q.Where("ls.local_subnet_id = ?", *filters.LocalSubnetID)
 	}
 
+	// Filter by the user's scope.
+	q = filters.Scope.applyToSubnets(q)
+
 	// Quick filtering by subnet prefix, pool ranges or shared network name.
 	if filters.Text != nil {
 		// The combination of the concat and host functions reconstruct the textual
@@ -656,7 +800,8 @@ func GetSubnetsWithLocalSubnets(dbi dbops.DBI) ([]*Subnet, error) {
 	subnets := []*Subnet{}
 	q := dbi.Model(&subnets)
 	// only selected columns are returned for performance reasons
-	q = q.Column("id", "shared_network_id", "prefix")
+	q = q.Column("id", "shared_network_id", "prefix", "addr_utilization_alert", "pd_utilization_alert",
+		"addr_exhaustion_days", "pd_exhaustion_days")
 	q = q.Relation("LocalSubnets")
 	q = q.Order("shared_network_id ASC")
 
@@ -869,7 +1014,8 @@ func CommitNetworksIntoDB(dbi dbops.DBI, networks []SharedNetwork, subnets []Sub
 	return
 }
 
-// Fetch all local subnets for indicated app.
+// Fetch all local subnets for indicated app with their address and prefix
+// pools.
 func GetAppLocalSubnets(dbi dbops.DBI, appID int64) ([]*LocalSubnet, error) {
 	subnets := []*LocalSubnet{}
 	q := dbi.Model(&subnets)
@@ -878,6 +1024,16 @@ func GetAppLocalSubnets(dbi dbops.DBI, appID int64) ([]*LocalSubnet, error) {
 	q = q.Column("local_subnet.id", "local_subnet.daemon_id", "local_subnet.subnet_id", "local_subnet.local_subnet_id")
 	q = q.Relation("Subnet")
 	q = q.Relation("Daemon.App")
+	// Only the pool columns needed to match the pools with the Kea
+	// statistics are selected.
+	q = q.Relation("AddressPools", func(q *orm.Query) (*orm.Query, error) {
+		return q.Column("address_pool.id", "address_pool.local_subnet_id", "address_pool.lower_bound", "address_pool.upper_bound").
+			Order("address_pool.id ASC"), nil
+	})
+	q = q.Relation("PrefixPools", func(q *orm.Query) (*orm.Query, error) {
+		return q.Column("prefix_pool.id", "prefix_pool.local_subnet_id", "prefix_pool.prefix", "prefix_pool.delegated_len").
+			Order("prefix_pool.id ASC"), nil
+	})
 	q = q.Where("d.app_id = ?", appID)
 
 	err := q.Select()
@@ -1292,6 +1292,178 @@ func TestUpdateSubnet(t *testing.T) {
 	require.Equal(t, createdAt, subnet.CreatedAt)
 }
 
+// Test that updating the subnet does not wipe its statistics.
+func TestUpdateSubnetPreservesStatistics(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	subnet := &Subnet{
+		Prefix:      "192.0.2.0/24",
+		ClientClass: "foo",
+	}
+	err := AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	err = subnet.UpdateStatistics(db, newUtilizationStatsMock(0.01, 0.02, SubnetStats{
+		"total-nas":    uint64(100),
+		"assigned-nas": uint64(1),
+	}))
+	require.NoError(t, err)
+
+	// Update the subnet using an instance without the statistics, e.g.,
+	// the one built from the REST API request.
+	err = updateSubnet(db, &Subnet{
+		ID:          subnet.ID,
+		Prefix:      "192.0.2.0/24",
+		ClientClass: "bar",
+	})
+	require.NoError(t, err)
+
+	returned, err := GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, "bar", returned.ClientClass)
+	require.EqualValues(t, 10, returned.AddrUtilization)
+	require.EqualValues(t, 20, returned.PdUtilization)
+	require.EqualValues(t, 1, returned.Stats["assigned-nas"])
+	require.NotZero(t, returned.StatsCollectedAt)
+}
+
+// Test that the subnet can be added along with its local subnets in
+// a single call.
+func TestAddSubnetWithLocalSubnets(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestApps(t, db)
+
+	subnet := &Subnet{
+		Prefix: "192.0.2.0/24",
+		LocalSubnets: []*LocalSubnet{
+			{
+				DaemonID:      apps[0].Daemons[0].ID,
+				LocalSubnetID: 123,
+				AddressPools: []AddressPool{
+					{LowerBound: "192.0.2.10", UpperBound: "192.0.2.20"},
+				},
+			},
+			{
+				DaemonID:      apps[1].Daemons[0].ID,
+				LocalSubnetID: 123,
+				AddressPools: []AddressPool{
+					{LowerBound: "192.0.2.30", UpperBound: "192.0.2.40"},
+				},
+			},
+		},
+	}
+	err := AddSubnetWithLocalSubnets(db, subnet)
+	require.NoError(t, err)
+	require.NotZero(t, subnet.ID)
+
+	returned, err := GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, "192.0.2.0/24", returned.Prefix)
+	require.Len(t, returned.LocalSubnets, 2)
+	for _, ls := range returned.LocalSubnets {
+		require.EqualValues(t, 123, ls.LocalSubnetID)
+		require.Len(t, ls.AddressPools, 1)
+	}
+}
+
+// Test that the subnet and its local subnets can be updated in a single
+// call and that the associations with the daemons no longer serving the
+// subnet are removed.
+func TestUpdateSubnetWithLocalSubnets(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestApps(t, db)
+
+	subnet := &Subnet{
+		Prefix: "192.0.2.0/24",
+		LocalSubnets: []*LocalSubnet{
+			{
+				DaemonID:      apps[0].Daemons[0].ID,
+				LocalSubnetID: 123,
+				AddressPools: []AddressPool{
+					{LowerBound: "192.0.2.10", UpperBound: "192.0.2.20"},
+				},
+			},
+			{
+				DaemonID:      apps[1].Daemons[0].ID,
+				LocalSubnetID: 123,
+			},
+		},
+	}
+	err := AddSubnetWithLocalSubnets(db, subnet)
+	require.NoError(t, err)
+
+	// Remove one daemon, add another one and modify the pools.
+	subnet.ClientClass = "foo"
+	subnet.LocalSubnets = []*LocalSubnet{
+		{
+			DaemonID:      apps[0].Daemons[0].ID,
+			LocalSubnetID: 234,
+			AddressPools: []AddressPool{
+				{LowerBound: "192.0.2.50", UpperBound: "192.0.2.60"},
+			},
+		},
+		{
+			DaemonID:      apps[2].Daemons[0].ID,
+			LocalSubnetID: 234,
+		},
+	}
+	err = UpdateSubnetWithLocalSubnets(db, subnet)
+	require.NoError(t, err)
+
+	returned, err := GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.Equal(t, "foo", returned.ClientClass)
+	require.Len(t, returned.LocalSubnets, 2)
+	sort.Slice(returned.LocalSubnets, func(i, j int) bool {
+		return returned.LocalSubnets[i].DaemonID < returned.LocalSubnets[j].DaemonID
+	})
+	require.Equal(t, apps[0].Daemons[0].ID, returned.LocalSubnets[0].DaemonID)
+	require.EqualValues(t, 234, returned.LocalSubnets[0].LocalSubnetID)
+	require.Len(t, returned.LocalSubnets[0].AddressPools, 1)
+	require.Equal(t, "192.0.2.50", returned.LocalSubnets[0].AddressPools[0].LowerBound)
+	require.Equal(t, apps[2].Daemons[0].ID, returned.LocalSubnets[1].DaemonID)
+	require.EqualValues(t, 234, returned.LocalSubnets[1].LocalSubnetID)
+}
+
+// Test deleting a subnet.
+func TestDeleteSubnet(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestApps(t, db)
+
+	subnet := &Subnet{
+		Prefix: "192.0.2.0/24",
+		LocalSubnets: []*LocalSubnet{
+			{
+				DaemonID:      apps[0].Daemons[0].ID,
+				LocalSubnetID: 123,
+			},
+		},
+	}
+	err := AddSubnetWithLocalSubnets(db, subnet)
+	require.NoError(t, err)
+
+	err = DeleteSubnet(db, subnet.ID)
+	require.NoError(t, err)
+
+	returned, err := GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.Nil(t, returned)
+
+	// Deleting non-existing subnet should return an error.
+	err = DeleteSubnet(db, subnet.ID)
+	require.ErrorIs(t, err, ErrNotExists)
+}
+
 // Test that the new pools are added and existing ones are untouched. The
 // out-of-date entries should be removed.
 func TestAddAndClearSubnetPools(t *testing.T) {
@@ -1698,3 +1870,86 @@ func TestJoinSubnets(t *testing.T) {
 	require.EqualValues(t, 2, subnet0.LocalSubnets[1].DaemonID)
 	require.EqualValues(t, 3, subnet0.LocalSubnets[2].DaemonID)
 }
+
+// Test that daemon information can be populated to the subnet instance
+// when the local subnets merely contain daemon IDs.
+func TestPopulateSubnetDaemons(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+	subnet := &Subnet{
+		LocalSubnets: []*LocalSubnet{
+			{
+				DaemonID: apps[0].Daemons[0].ID,
+			},
+			{
+				DaemonID: apps[1].Daemons[0].ID,
+			},
+		},
+	}
+	err := subnet.PopulateDaemons(db)
+	require.NoError(t, err)
+
+	require.Len(t, subnet.LocalSubnets, 2)
+	require.NotNil(t, subnet.LocalSubnets[0].Daemon)
+	require.EqualValues(t, apps[0].Daemons[0].ID, subnet.LocalSubnets[0].Daemon.ID)
+	require.NotNil(t, subnet.LocalSubnets[0].Daemon.App)
+	require.NotNil(t, subnet.LocalSubnets[1].Daemon)
+	require.EqualValues(t, apps[1].Daemons[0].ID, subnet.LocalSubnets[1].Daemon.ID)
+}
+
+// Test that an attempt to populate daemon information to a subnet fails
+// when one of the daemons does not exist.
+func TestPopulateSubnetDaemonsMissingDaemons(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	apps := addTestSubnetApps(t, db)
+	subnet := &Subnet{
+		LocalSubnets: []*LocalSubnet{
+			{
+				DaemonID: apps[0].Daemons[0].ID,
+			},
+			{
+				DaemonID: apps[0].Daemons[0].ID + apps[1].Daemons[0].ID + 1000,
+			},
+		},
+	}
+	err := subnet.PopulateDaemons(db)
+	require.Error(t, err)
+
+	// The subnet should not be updated because of an error.
+	require.Nil(t, subnet.LocalSubnets[0].Daemon)
+	require.Nil(t, subnet.LocalSubnets[1].Daemon)
+}
+
+// Test that shared network information can be populated to the subnet
+// instance when the shared network ID is available.
+func TestPopulateSubnetSharedNetwork(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	sharedNetwork := &SharedNetwork{
+		Name:   "foo",
+		Family: 4,
+	}
+	err := AddSharedNetwork(db, sharedNetwork)
+	require.NoError(t, err)
+
+	subnet := &Subnet{
+		SharedNetworkID: sharedNetwork.ID,
+	}
+	err = subnet.PopulateSharedNetwork(db)
+	require.NoError(t, err)
+	require.NotNil(t, subnet.SharedNetwork)
+	require.Equal(t, "foo", subnet.SharedNetwork.Name)
+
+	// Non-existing shared network.
+	subnet = &Subnet{
+		SharedNetworkID: sharedNetwork.ID + 1,
+	}
+	err = subnet.PopulateSharedNetwork(db)
+	require.Error(t, err)
+	require.Nil(t, subnet.SharedNetwork)
+}
@@ -0,0 +1,209 @@
+package dbmodel
+
+import (
+	"errors"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+)
+
+// Utilization alert level of a subnet or a shared network.
+type UtilizationAlertLevel int16
+
+// Supported utilization alert levels.
+const (
+	UtilizationAlertNone     UtilizationAlertLevel = 0
+	UtilizationAlertWarning  UtilizationAlertLevel = 1
+	UtilizationAlertCritical UtilizationAlertLevel = 2
+)
+
+// Returns the alert level name.
+func (level UtilizationAlertLevel) String() string {
+	switch level {
+	case UtilizationAlertWarning:
+		return "warning"
+	case UtilizationAlertCritical:
+		return "critical"
+	default:
+		return "none"
+	}
+}
+
+// Represents a rule specifying the address and delegated prefix
+// utilization thresholds, in percent, at which the alerts are raised.
+// The zero threshold disables the alert. The rule without the subnet
+// and shared network IDs is the global rule applied to the subnets and
+// shared networks without their own rules. The alert is cleared or
+// lowered when the utilization drops below the threshold by at least
+// the hysteresis.
+type UtilizationAlertRule struct {
+	ID              int64
+	SubnetID        int64
+	SharedNetworkID int64
+
+	AddrWarning  float64 `pg:",use_zero"`
+	AddrCritical float64 `pg:",use_zero"`
+	PdWarning    float64 `pg:",use_zero"`
+	PdCritical   float64 `pg:",use_zero"`
+	Hysteresis   float64 `pg:",use_zero"`
+}
+
+// Checks if the rule is the global rule.
+func (rule *UtilizationAlertRule) IsGlobal() bool {
+	return rule.SubnetID == 0 && rule.SharedNetworkID == 0
+}
+
+// Checks if the thresholds and the hysteresis are valid.
+func (rule *UtilizationAlertRule) Validate() error {
+	if rule.SubnetID != 0 && rule.SharedNetworkID != 0 {
+		return pkgerrors.New("utilization alert rule must not apply to a subnet and a shared network at the same time")
+	}
+	thresholds := []struct {
+		name  string
+		value float64
+	}{
+		{"address warning", rule.AddrWarning},
+		{"address critical", rule.AddrCritical},
+		{"delegated prefix warning", rule.PdWarning},
+		{"delegated prefix critical", rule.PdCritical},
+	}
+	for _, threshold := range thresholds {
+		if threshold.value < 0 || threshold.value > 100 {
+			return pkgerrors.Errorf("%s threshold %.1f must be between 0 and 100", threshold.name, threshold.value)
+		}
+	}
+	if rule.AddrWarning > 0 && rule.AddrCritical > 0 && rule.AddrWarning > rule.AddrCritical {
+		return pkgerrors.New("address warning threshold must not be greater than the critical threshold")
+	}
+	if rule.PdWarning > 0 && rule.PdCritical > 0 && rule.PdWarning > rule.PdCritical {
+		return pkgerrors.New("delegated prefix warning threshold must not be greater than the critical threshold")
+	}
+	if rule.Hysteresis < 0 || rule.Hysteresis > 100 {
+		return pkgerrors.Errorf("hysteresis %.1f must be between 0 and 100", rule.Hysteresis)
+	}
+	return nil
+}
+
+// Inserts a utilization alert rule into the database.
+func AddUtilizationAlertRule(dbi dbops.DBI, rule *UtilizationAlertRule) error {
+	_, err := dbi.Model(rule).Insert()
+	if err != nil {
+		err = pkgerrors.Wrap(err, "problem inserting utilization alert rule")
+	}
+	return err
+}
+
+// Updates the utilization alert rule in the database.
+func UpdateUtilizationAlertRule(dbi dbops.DBI, rule *UtilizationAlertRule) error {
+	result, err := dbi.Model(rule).WherePK().Update()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem updating utilization alert rule with id %d", rule.ID)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "utilization alert rule with id %d does not exist", rule.ID)
+	}
+	return nil
+}
+
+// Returns all utilization alert rules. The global rule is returned first.
+func GetUtilizationAlertRules(dbi dbops.DBI) ([]UtilizationAlertRule, error) {
+	rules := []UtilizationAlertRule{}
+	err := dbi.Model(&rules).
+		OrderExpr("subnet_id IS NOT NULL OR shared_network_id IS NOT NULL").
+		OrderExpr("id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting utilization alert rules")
+	}
+	return rules, nil
+}
+
+// Returns the utilization alert rule by ID. It returns nil if the rule
+// does not exist.
+func GetUtilizationAlertRuleByID(dbi dbops.DBI, id int64) (*UtilizationAlertRule, error) {
+	rule := &UtilizationAlertRule{}
+	err := dbi.Model(rule).Where("id = ?", id).Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, pkgerrors.Wrapf(err, "problem getting utilization alert rule with id %d", id)
+	}
+	return rule, nil
+}
+
+// Deletes the utilization alert rule from the database. The global rule
+// cannot be deleted.
+func DeleteUtilizationAlertRule(dbi dbops.DBI, id int64) error {
+	rule := &UtilizationAlertRule{
+		ID: id,
+	}
+	result, err := dbi.Model(rule).
+		WherePK().
+		Where("subnet_id IS NOT NULL OR shared_network_id IS NOT NULL").
+		Delete()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem deleting utilization alert rule with id %d", id)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "utilization alert rule with id %d does not exist or is the global rule", id)
+	}
+	return nil
+}
+
+// Sets the utilization alert levels of the subnet.
+func SetSubnetUtilizationAlerts(dbi dbops.DBI, subnetID int64, addrAlert, pdAlert UtilizationAlertLevel) error {
+	subnet := &Subnet{
+		ID:                   subnetID,
+		AddrUtilizationAlert: addrAlert,
+		PdUtilizationAlert:   pdAlert,
+	}
+	result, err := dbi.Model(subnet).
+		Column("addr_utilization_alert", "pd_utilization_alert").
+		WherePK().
+		Update()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem updating utilization alerts in the subnet: %d", subnetID)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "subnet with ID %d does not exist", subnetID)
+	}
+	return nil
+}
+
+// Sets the utilization alert levels of the shared network.
+func SetSharedNetworkUtilizationAlerts(dbi dbops.DBI, sharedNetworkID int64, addrAlert, pdAlert UtilizationAlertLevel) error {
+	network := &SharedNetwork{
+		ID:                   sharedNetworkID,
+		AddrUtilizationAlert: addrAlert,
+		PdUtilizationAlert:   pdAlert,
+	}
+	result, err := dbi.Model(network).
+		Column("addr_utilization_alert", "pd_utilization_alert").
+		WherePK().
+		Update()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "problem updating utilization alerts in the shared network: %d", sharedNetworkID)
+	}
+	if result.RowsAffected() <= 0 {
+		return pkgerrors.Wrapf(ErrNotExists, "shared network with ID %d does not exist", sharedNetworkID)
+	}
+	return nil
+}
+
+// Returns the shared networks with their utilizations, alert levels and
+// exhaustion forecasts. Only the columns required to evaluate the
+// utilization alerts and the forecasts are returned.
+func GetSharedNetworksWithUtilization(dbi dbops.DBI) ([]*SharedNetwork, error) {
+	networks := []*SharedNetwork{}
+	err := dbi.Model(&networks).
+		Column("id", "name", "addr_utilization", "pd_utilization", "addr_utilization_alert", "pd_utilization_alert",
+			"addr_exhaustion_days", "pd_exhaustion_days").
+		OrderExpr("id ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrap(err, "problem getting shared networks utilization")
+	}
+	return networks, nil
+}
@@ -0,0 +1,201 @@
+package dbmodel
+
+import (
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test the utilization alert rule validation.
+func TestUtilizationAlertRuleValidate(t *testing.T) {
+	rule := &UtilizationAlertRule{
+		AddrWarning:  80,
+		AddrCritical: 90,
+		PdWarning:    70,
+		Hysteresis:   5,
+	}
+	require.NoError(t, rule.Validate())
+	require.True(t, rule.IsGlobal())
+
+	rule.SubnetID = 1
+	require.NoError(t, rule.Validate())
+	require.False(t, rule.IsGlobal())
+
+	rule.SharedNetworkID = 1
+	require.Error(t, rule.Validate())
+	rule.SharedNetworkID = 0
+
+	rule.AddrCritical = 101
+	require.Error(t, rule.Validate())
+
+	rule.AddrCritical = 70
+	require.Error(t, rule.Validate())
+
+	rule.AddrCritical = 90
+	rule.PdCritical = 60
+	require.Error(t, rule.Validate())
+
+	rule.PdCritical = 0
+	rule.Hysteresis = -1
+	require.Error(t, rule.Validate())
+}
+
+// Test adding, updating, getting and deleting the utilization alert rules.
+func TestUtilizationAlertRules(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	// The global rule is created by the migration.
+	rules, err := GetUtilizationAlertRules(db)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.True(t, rules[0].IsGlobal())
+	require.EqualValues(t, 80, rules[0].AddrWarning)
+	require.EqualValues(t, 90, rules[0].AddrCritical)
+	require.EqualValues(t, 80, rules[0].PdWarning)
+	require.EqualValues(t, 90, rules[0].PdCritical)
+	require.EqualValues(t, 5, rules[0].Hysteresis)
+	globalID := rules[0].ID
+
+	// There is only one global rule.
+	err = AddUtilizationAlertRule(db, &UtilizationAlertRule{AddrWarning: 50})
+	require.Error(t, err)
+
+	subnet := &Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err = AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	network := &SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err = AddSharedNetwork(db, network)
+	require.NoError(t, err)
+
+	subnetRule := &UtilizationAlertRule{
+		SubnetID:     subnet.ID,
+		AddrWarning:  60,
+		AddrCritical: 0,
+	}
+	err = AddUtilizationAlertRule(db, subnetRule)
+	require.NoError(t, err)
+	require.NotZero(t, subnetRule.ID)
+
+	// Only one rule per subnet is allowed.
+	err = AddUtilizationAlertRule(db, &UtilizationAlertRule{SubnetID: subnet.ID})
+	require.Error(t, err)
+
+	networkRule := &UtilizationAlertRule{
+		SharedNetworkID: network.ID,
+		AddrWarning:     70,
+		AddrCritical:    95,
+	}
+	err = AddUtilizationAlertRule(db, networkRule)
+	require.NoError(t, err)
+
+	rules, err = GetUtilizationAlertRules(db)
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+	require.True(t, rules[0].IsGlobal())
+	require.Equal(t, subnet.ID, rules[1].SubnetID)
+	require.Zero(t, rules[1].SharedNetworkID)
+	require.EqualValues(t, 60, rules[1].AddrWarning)
+	require.Equal(t, network.ID, rules[2].SharedNetworkID)
+	require.Zero(t, rules[2].SubnetID)
+
+	// Update the rule.
+	networkRule.AddrWarning = 75
+	networkRule.Hysteresis = 2
+	err = UpdateUtilizationAlertRule(db, networkRule)
+	require.NoError(t, err)
+
+	returned, err := GetUtilizationAlertRuleByID(db, networkRule.ID)
+	require.NoError(t, err)
+	require.NotNil(t, returned)
+	require.EqualValues(t, 75, returned.AddrWarning)
+	require.EqualValues(t, 2, returned.Hysteresis)
+
+	err = UpdateUtilizationAlertRule(db, &UtilizationAlertRule{ID: networkRule.ID + 100})
+	require.ErrorIs(t, err, ErrNotExists)
+
+	returned, err = GetUtilizationAlertRuleByID(db, networkRule.ID+100)
+	require.NoError(t, err)
+	require.Nil(t, returned)
+
+	// The global rule cannot be deleted.
+	err = DeleteUtilizationAlertRule(db, globalID)
+	require.ErrorIs(t, err, ErrNotExists)
+
+	err = DeleteUtilizationAlertRule(db, networkRule.ID)
+	require.NoError(t, err)
+
+	// Deleting the subnet deletes its rule.
+	err = DeleteSubnet(db, subnet.ID)
+	require.NoError(t, err)
+
+	rules, err = GetUtilizationAlertRules(db)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Equal(t, globalID, rules[0].ID)
+}
+
+// Test setting the utilization alert levels in the subnets and the shared
+// networks.
+func TestSetUtilizationAlerts(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	network := &SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err := AddSharedNetwork(db, network)
+	require.NoError(t, err)
+
+	subnet := &Subnet{
+		Prefix:          "192.0.2.0/24",
+		SharedNetworkID: network.ID,
+	}
+	err = AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	err = SetSubnetUtilizationAlerts(db, subnet.ID, UtilizationAlertCritical, UtilizationAlertWarning)
+	require.NoError(t, err)
+
+	err = SetSharedNetworkUtilizationAlerts(db, network.ID, UtilizationAlertWarning, UtilizationAlertNone)
+	require.NoError(t, err)
+
+	err = SetSubnetUtilizationAlerts(db, subnet.ID+100, UtilizationAlertCritical, UtilizationAlertWarning)
+	require.ErrorIs(t, err, ErrNotExists)
+
+	err = SetSharedNetworkUtilizationAlerts(db, network.ID+100, UtilizationAlertCritical, UtilizationAlertWarning)
+	require.ErrorIs(t, err, ErrNotExists)
+
+	returnedSubnet, err := GetSubnet(db, subnet.ID)
+	require.NoError(t, err)
+	require.Equal(t, UtilizationAlertCritical, returnedSubnet.AddrUtilizationAlert)
+	require.Equal(t, UtilizationAlertWarning, returnedSubnet.PdUtilizationAlert)
+
+	// Updating the subnet must not reset the alert levels.
+	returnedSubnet.ClientClass = "foo"
+	returnedSubnet.AddrUtilizationAlert = UtilizationAlertNone
+	returnedSubnet.PdUtilizationAlert = UtilizationAlertNone
+	err = updateSubnet(db, returnedSubnet)
+	require.NoError(t, err)
+
+	subnets, err := GetSubnetsWithLocalSubnets(db)
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+	require.Equal(t, UtilizationAlertCritical, subnets[0].AddrUtilizationAlert)
+	require.Equal(t, UtilizationAlertWarning, subnets[0].PdUtilizationAlert)
+
+	networks, err := GetSharedNetworksWithUtilization(db)
+	require.NoError(t, err)
+	require.Len(t, networks, 1)
+	require.Equal(t, "frog", networks[0].Name)
+	require.Equal(t, UtilizationAlertWarning, networks[0].AddrUtilizationAlert)
+	require.Equal(t, UtilizationAlertNone, networks[0].PdUtilizationAlert)
+}
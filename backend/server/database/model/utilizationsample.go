@@ -0,0 +1,250 @@
+package dbmodel
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	dbops "isc.org/stork/server/database"
+)
+
+// Time resolution of the utilization samples.
+type UtilizationResolution string
+
+// The raw samples are taken each time the statistics are pulled from the
+// Kea servers. They are rolled up into the hourly samples, and the hourly
+// samples are rolled up into the daily samples.
+const (
+	UtilizationResolutionRaw    UtilizationResolution = "raw"
+	UtilizationResolutionHourly UtilizationResolution = "hourly"
+	UtilizationResolutionDaily  UtilizationResolution = "daily"
+)
+
+// Names of the settings holding the retention periods of the utilization
+// samples. The raw sample retention is specified in hours and the hourly
+// and daily sample retentions are specified in days.
+const (
+	UtilizationRawRetentionSetting    = "utilization_raw_retention"
+	UtilizationHourlyRetentionSetting = "utilization_hourly_retention"
+	UtilizationDailyRetentionSetting  = "utilization_daily_retention"
+)
+
+// Default retention periods of the utilization samples. They are used
+// when the retention settings are missing or not positive.
+const (
+	DefaultUtilizationRawRetention    = 48 * time.Hour
+	DefaultUtilizationHourlyRetention = 30 * 24 * time.Hour
+	DefaultUtilizationDailyRetention  = 730 * 24 * time.Hour
+)
+
+// Represents a historical utilization sample of a subnet, a shared
+// network, an address pool or a prefix pool. The counters are stored as
+// floating point numbers because the IPv6 counters may exceed the range
+// of the 64-bit integers. The utilizations are fractions between 0 and
+// 1. The rolled up samples hold the average counters and utilizations in
+// the sampling period, and the maximum utilizations reached in that
+// period.
+type UtilizationSample struct {
+	ID              int64
+	Resolution      UtilizationResolution
+	SampledAt       time.Time
+	SubnetID        int64
+	SharedNetworkID int64
+	AddressPoolID   int64
+	PrefixPoolID    int64
+
+	TotalAddresses    float64 `pg:",use_zero"`
+	AssignedAddresses float64 `pg:",use_zero"`
+	DeclinedAddresses float64 `pg:",use_zero"`
+	TotalPds          float64 `pg:",use_zero"`
+	AssignedPds       float64 `pg:",use_zero"`
+
+	AddrUtilization    float64 `pg:",use_zero"`
+	PdUtilization      float64 `pg:",use_zero"`
+	MaxAddrUtilization float64 `pg:",use_zero"`
+	MaxPdUtilization   float64 `pg:",use_zero"`
+}
+
+// Converts the statistic value to a floating point number. The values
+// may be stored as the native integers or big integers.
+func statToFloat64(value any) float64 {
+	switch v := value.(type) {
+	case uint64:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	case *big.Int:
+		if v == nil {
+			return 0
+		}
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f
+	default:
+		return 0
+	}
+}
+
+// Creates a raw utilization sample from the subnet, shared network or
+// pool statistics. The IPv4 and IPv6 statistic names are both recognized.
+func NewUtilizationSample(sampledAt time.Time, addrUtilization, pdUtilization float64, stats SubnetStats) *UtilizationSample {
+	sample := &UtilizationSample{
+		Resolution:         UtilizationResolutionRaw,
+		SampledAt:          sampledAt,
+		AddrUtilization:    addrUtilization,
+		PdUtilization:      pdUtilization,
+		MaxAddrUtilization: addrUtilization,
+		MaxPdUtilization:   pdUtilization,
+	}
+	for name, value := range stats {
+		switch name {
+		case "total-addresses", "total-nas":
+			sample.TotalAddresses = statToFloat64(value)
+		case "assigned-addresses", "assigned-nas":
+			sample.AssignedAddresses = statToFloat64(value)
+		case "declined-addresses", "declined-nas":
+			sample.DeclinedAddresses = statToFloat64(value)
+		case "total-pds":
+			sample.TotalPds = statToFloat64(value)
+		case "assigned-pds":
+			sample.AssignedPds = statToFloat64(value)
+		}
+	}
+	return sample
+}
+
+// Returns the retention period of the utilization samples having the
+// specified resolution. The default is returned when the setting is
+// missing or not positive.
+func GetUtilizationRetention(db *pg.DB, resolution UtilizationResolution) time.Duration {
+	setting, unit, defaultRetention := UtilizationRawRetentionSetting, time.Hour, DefaultUtilizationRawRetention
+	switch resolution {
+	case UtilizationResolutionHourly:
+		setting, unit, defaultRetention = UtilizationHourlyRetentionSetting, 24*time.Hour, DefaultUtilizationHourlyRetention
+	case UtilizationResolutionDaily:
+		setting, unit, defaultRetention = UtilizationDailyRetentionSetting, 24*time.Hour, DefaultUtilizationDailyRetention
+	}
+	value, err := GetSettingInt(db, setting)
+	if err != nil || value <= 0 {
+		return defaultRetention
+	}
+	return time.Duration(value) * unit
+}
+
+// Inserts the utilization samples into the database.
+func AddUtilizationSamples(dbi dbops.DBI, samples []*UtilizationSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	_, err := dbi.Model(&samples).Insert()
+	if err != nil {
+		err = pkgerrors.Wrap(err, "problem inserting utilization samples")
+	}
+	return err
+}
+
+// Rolls up the samples having the source resolution into the samples
+// having the target resolution. Only the complete periods, i.e., the
+// periods ended before the specified time, are rolled up. The periods
+// rolled up earlier are skipped. The unit is the PostgreSQL date_trunc
+// function unit corresponding to the target resolution.
+func rollUpUtilizationSamples(dbi dbops.DBI, source, target UtilizationResolution, unit string, now time.Time) error {
+	_, err := dbi.Exec(`
+        INSERT INTO utilization_sample (
+            resolution, sampled_at, subnet_id, shared_network_id, address_pool_id, prefix_pool_id,
+            total_addresses, assigned_addresses, declined_addresses, total_pds, assigned_pds,
+            addr_utilization, pd_utilization, max_addr_utilization, max_pd_utilization
+        )
+        SELECT ?, date_trunc(?, sampled_at) AS period, subnet_id, shared_network_id, address_pool_id, prefix_pool_id,
+            AVG(total_addresses), AVG(assigned_addresses), AVG(declined_addresses), AVG(total_pds), AVG(assigned_pds),
+            AVG(addr_utilization), AVG(pd_utilization), MAX(max_addr_utilization), MAX(max_pd_utilization)
+        FROM utilization_sample
+        WHERE resolution = ? AND sampled_at < date_trunc(?, CAST(? AS TIMESTAMP))
+        GROUP BY period, subnet_id, shared_network_id, address_pool_id, prefix_pool_id
+        ON CONFLICT (resolution, COALESCE(subnet_id, 0), COALESCE(shared_network_id, 0),
+            COALESCE(address_pool_id, 0), COALESCE(prefix_pool_id, 0), sampled_at) DO NOTHING
+    `, target, unit, source, unit, now.UTC())
+	if err != nil {
+		err = pkgerrors.Wrapf(err, "problem rolling up %s utilization samples into %s samples", source, target)
+	}
+	return err
+}
+
+// Rolls up the raw utilization samples into the hourly samples and the
+// hourly samples into the daily samples. The samples are rolled up only
+// for the hours and days ended before the specified time.
+func RollUpUtilizationSamples(dbi dbops.DBI, now time.Time) error {
+	err := rollUpUtilizationSamples(dbi, UtilizationResolutionRaw, UtilizationResolutionHourly, "hour", now)
+	if err != nil {
+		return err
+	}
+	return rollUpUtilizationSamples(dbi, UtilizationResolutionHourly, UtilizationResolutionDaily, "day", now)
+}
+
+// Deletes the utilization samples having the specified resolution and
+// taken before the specified time. It returns the number of deleted
+// samples.
+func DeleteUtilizationSamplesBefore(dbi dbops.DBI, resolution UtilizationResolution, before time.Time) (int64, error) {
+	result, err := dbi.Model((*UtilizationSample)(nil)).
+		Where("resolution = ?", resolution).
+		Where("sampled_at < ?", before).
+		Delete()
+	if err != nil {
+		return 0, pkgerrors.Wrapf(err, "problem deleting %s utilization samples", resolution)
+	}
+	return int64(result.RowsAffected()), nil
+}
+
+// Returns the utilization samples of all subnets, shared networks and
+// pools having the specified resolution and taken at or after the specified
+// time. The samples are ordered by time.
+func GetUtilizationSamplesSince(dbi dbops.DBI, resolution UtilizationResolution, since time.Time) ([]UtilizationSample, error) {
+	samples := []UtilizationSample{}
+	err := dbi.Model(&samples).
+		Where("resolution = ?", resolution).
+		Where("sampled_at >= ?", since).
+		OrderExpr("sampled_at ASC").
+		Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrapf(err, "problem getting %s utilization samples", resolution)
+	}
+	return samples, nil
+}
+
+// Returns the utilization samples of the subnet or the shared network
+// having the specified resolution and taken in the specified time range.
+// The zero times leave the range open. The samples are ordered by time.
+func getUtilizationSamples(dbi dbops.DBI, column string, id int64, resolution UtilizationResolution, start, end time.Time) ([]UtilizationSample, error) {
+	samples := []UtilizationSample{}
+	q := dbi.Model(&samples).
+		Where("? = ?", pg.Ident(column), id).
+		Where("resolution = ?", resolution)
+	if !start.IsZero() {
+		q = q.Where("sampled_at >= ?", start)
+	}
+	if !end.IsZero() {
+		q = q.Where("sampled_at <= ?", end)
+	}
+	err := q.OrderExpr("sampled_at ASC").Select()
+	if err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, pkgerrors.Wrapf(err, "problem getting %s utilization samples", resolution)
+	}
+	return samples, nil
+}
+
+// Returns the utilization samples of the subnet having the specified
+// resolution and taken in the specified time range.
+func GetSubnetUtilizationSamples(dbi dbops.DBI, subnetID int64, resolution UtilizationResolution, start, end time.Time) ([]UtilizationSample, error) {
+	return getUtilizationSamples(dbi, "subnet_id", subnetID, resolution, start, end)
+}
+
+// Returns the utilization samples of the shared network having the
+// specified resolution and taken in the specified time range.
+func GetSharedNetworkUtilizationSamples(dbi dbops.DBI, sharedNetworkID int64, resolution UtilizationResolution, start, end time.Time) ([]UtilizationSample, error) {
+	return getUtilizationSamples(dbi, "shared_network_id", sharedNetworkID, resolution, start, end)
+}
@@ -0,0 +1,151 @@
+package dbmodel
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	require "github.com/stretchr/testify/require"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test creating the utilization samples from the IPv4 statistics.
+func TestNewUtilizationSampleIPv4(t *testing.T) {
+	now := time.Now().UTC()
+	sample := NewUtilizationSample(now, 0.5, 0, SubnetStats{
+		"total-addresses":    uint64(256),
+		"assigned-addresses": uint64(128),
+		"declined-addresses": uint64(3),
+	})
+	require.Equal(t, UtilizationResolutionRaw, sample.Resolution)
+	require.Equal(t, now, sample.SampledAt)
+	require.EqualValues(t, 256, sample.TotalAddresses)
+	require.EqualValues(t, 128, sample.AssignedAddresses)
+	require.EqualValues(t, 3, sample.DeclinedAddresses)
+	require.Zero(t, sample.TotalPds)
+	require.EqualValues(t, 0.5, sample.AddrUtilization)
+	require.EqualValues(t, 0.5, sample.MaxAddrUtilization)
+}
+
+// Test creating the utilization samples from the IPv6 statistics holding
+// the big numbers.
+func TestNewUtilizationSampleIPv6(t *testing.T) {
+	total := big.NewInt(0).Lsh(big.NewInt(1), 64)
+	sample := NewUtilizationSample(time.Now(), 0, 0.25, SubnetStats{
+		"total-nas":    total,
+		"assigned-nas": int64(10),
+		"declined-nas": uint64(1),
+		"total-pds":    uint64(1024),
+		"assigned-pds": uint64(256),
+	})
+	require.EqualValues(t, 18446744073709551616.0, sample.TotalAddresses)
+	require.EqualValues(t, 10, sample.AssignedAddresses)
+	require.EqualValues(t, 1, sample.DeclinedAddresses)
+	require.EqualValues(t, 1024, sample.TotalPds)
+	require.EqualValues(t, 256, sample.AssignedPds)
+	require.EqualValues(t, 0.25, sample.PdUtilization)
+	require.EqualValues(t, 0.25, sample.MaxPdUtilization)
+}
+
+// Test adding, rolling up, getting and deleting the utilization samples.
+func TestUtilizationSamples(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	network := &SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err := AddSharedNetwork(db, network)
+	require.NoError(t, err)
+
+	subnet := &Subnet{
+		Prefix:          "192.0.2.0/24",
+		SharedNetworkID: network.ID,
+	}
+	err = AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	// Take the samples in two hours of the same day.
+	day := time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC)
+	samples := []*UtilizationSample{}
+	for i, minutes := range []int{10, 20, 70, 80} {
+		sampledAt := day.Add(time.Duration(minutes) * time.Minute)
+		utilization := float64(i+1) / 10
+		sample := NewUtilizationSample(sampledAt, utilization, 0, SubnetStats{
+			"total-addresses":    uint64(100),
+			"assigned-addresses": uint64((i + 1) * 10),
+		})
+		sample.SubnetID = subnet.ID
+		samples = append(samples, sample)
+
+		sample = NewUtilizationSample(sampledAt, utilization, 0, SubnetStats{})
+		sample.SharedNetworkID = network.ID
+		samples = append(samples, sample)
+	}
+	err = AddUtilizationSamples(db, samples)
+	require.NoError(t, err)
+
+	returned, err := GetSubnetUtilizationSamples(db, subnet.ID, UtilizationResolutionRaw, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, returned, 4)
+	require.EqualValues(t, 10, returned[0].AssignedAddresses)
+	require.EqualValues(t, 40, returned[3].AssignedAddresses)
+
+	// Time range.
+	returned, err = GetSubnetUtilizationSamples(db, subnet.ID, UtilizationResolutionRaw,
+		day.Add(15*time.Minute), day.Add(75*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, returned, 2)
+
+	// Roll up during the second hour. Only the first hour is complete.
+	err = RollUpUtilizationSamples(db, day.Add(90*time.Minute))
+	require.NoError(t, err)
+
+	returned, err = GetSubnetUtilizationSamples(db, subnet.ID, UtilizationResolutionHourly, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, returned, 1)
+	require.Equal(t, day, returned[0].SampledAt)
+	require.InDelta(t, 15, returned[0].AssignedAddresses, 0.001)
+	require.InDelta(t, 0.15, returned[0].AddrUtilization, 0.001)
+	require.InDelta(t, 0.2, returned[0].MaxAddrUtilization, 0.001)
+
+	returned, err = GetSharedNetworkUtilizationSamples(db, network.ID, UtilizationResolutionHourly, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, returned, 1)
+
+	// Roll up on the next day. The second hour and the day are complete.
+	// The first hour is not rolled up again.
+	err = RollUpUtilizationSamples(db, day.Add(25*time.Hour))
+	require.NoError(t, err)
+
+	returned, err = GetSubnetUtilizationSamples(db, subnet.ID, UtilizationResolutionHourly, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, returned, 2)
+	require.InDelta(t, 0.35, returned[1].AddrUtilization, 0.001)
+	require.InDelta(t, 0.4, returned[1].MaxAddrUtilization, 0.001)
+
+	returned, err = GetSubnetUtilizationSamples(db, subnet.ID, UtilizationResolutionDaily, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, returned, 1)
+	require.Equal(t, day, returned[0].SampledAt)
+	require.InDelta(t, 0.25, returned[0].AddrUtilization, 0.001)
+	require.InDelta(t, 0.4, returned[0].MaxAddrUtilization, 0.001)
+
+	// Delete the raw samples taken in the first hour.
+	deleted, err := DeleteUtilizationSamplesBefore(db, UtilizationResolutionRaw, day.Add(time.Hour))
+	require.NoError(t, err)
+	require.EqualValues(t, 4, deleted)
+
+	returned, err = GetSubnetUtilizationSamples(db, subnet.ID, UtilizationResolutionRaw, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, returned, 2)
+
+	// Deleting the subnet deletes its samples.
+	err = DeleteSubnet(db, subnet.ID)
+	require.NoError(t, err)
+
+	returned, err = GetSubnetUtilizationSamples(db, subnet.ID, UtilizationResolutionHourly, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Empty(t, returned)
+}
@@ -100,6 +100,20 @@ func (s *SessionMgr) LogoutUser(ctx context.Context, user *dbmodel.SystemUser) e
 	return errors.Wrapf(err, "error while destroying a user session")
 }
 
+// Loads the session data for the token sent in the request cookie and
+// returns the context holding the data. Contrary to the SessionMiddleware,
+// it doesn't buffer the response, so it is suitable for the handlers
+// streaming the response, e.g., server-sent events. The session is not
+// saved, so the handler must not modify it.
+func (s *SessionMgr) LoadSession(req *http.Request) (context.Context, error) {
+	var token string
+	if cookie, err := req.Cookie(s.scsSessionMgr.Cookie.Name); err == nil {
+		token = cookie.Value
+	}
+	ctx, err := s.scsSessionMgr.Load(req.Context(), token)
+	return ctx, errors.Wrap(err, "error while loading the session data")
+}
+
 // Implements middleware which reads the session cookie, loads session data for the
 // user and stores the token/ in the Cookie being sent to the user.
 func (s *SessionMgr) SessionMiddleware(handler http.Handler) http.Handler {
@@ -108,6 +108,47 @@ func TestLoad(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// Test that the session data are loaded for the token sent in the cookie.
+func TestLoadSession(t *testing.T) {
+	// Reset database schema.
+	_, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	mgr, err := NewSessionMgr(dbSettings)
+	require.NoError(t, err)
+
+	// Log in the user to create the session.
+	var token string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		err := mgr.LoginHandler(r.Context(), &dbmodel.SystemUser{
+			ID:    1,
+			Login: "johnw",
+		})
+		require.NoError(t, err)
+	}
+	w := httptest.NewRecorder()
+	mgr.SessionMiddleware(http.HandlerFunc(handler)).ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/foo", nil))
+	resp := w.Result()
+	defer resp.Body.Close()
+	_, token = getCookie(resp, "session")
+	require.NotEmpty(t, token)
+
+	// The session is loaded for the token.
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: token})
+	ctx, err := mgr.LoadSession(req)
+	require.NoError(t, err)
+	logged, user := mgr.Logged(ctx)
+	require.True(t, logged)
+	require.Equal(t, "johnw", user.Login)
+
+	// No session without the cookie.
+	ctx, err = mgr.LoadSession(httptest.NewRequest("GET", "http://example.com/foo", nil))
+	require.NoError(t, err)
+	logged, _ = mgr.Logged(ctx)
+	require.False(t, logged)
+}
+
 func TestLogOutUser(t *testing.T) {
 	// Reset database schema.
 	_, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
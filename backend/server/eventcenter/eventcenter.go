@@ -22,15 +22,17 @@ type EventCenter interface {
 	ServeHTTP(w http.ResponseWriter, req *http.Request)
 }
 
-// EventCenter. It has channel for receiving events
-// and a SSE broker for dispatching events to subscribers.
+// EventCenter. It has channel for receiving events, a SSE broker
+// for dispatching events to subscribers and a notification dispatcher
+// sending the events over the configured notification channels.
 type eventCenter struct {
 	db     *dbops.PgDB
 	done   chan bool
 	wg     *sync.WaitGroup
 	events chan *dbmodel.Event
 
-	sseBroker *SSEBroker
+	sseBroker              *SSEBroker
+	notificationDispatcher *notificationDispatcher
 }
 
 // Create new EventCenter object.
@@ -41,7 +43,10 @@ func NewEventCenter(db *pg.DB) EventCenter {
 		wg:        &sync.WaitGroup{},
 		events:    make(chan *dbmodel.Event),
 		sseBroker: NewSSEBroker(db),
+
+		notificationDispatcher: newNotificationDispatcher(db),
 	}
+	ec.notificationDispatcher.start()
 	ec.wg.Add(1)
 	go ec.mainLoop()
 
@@ -90,6 +95,9 @@ func CreateEvent(level dbmodel.EventLevel, text string, objects ...interface{})
 		} else if s, ok := obj.(*dbmodel.Subnet); ok {
 			text = strings.ReplaceAll(text, "{subnet}", subnetTag(s))
 			relations.SubnetID = s.ID
+		} else if n, ok := obj.(*dbmodel.SharedNetwork); ok {
+			text = strings.ReplaceAll(text, "{sharedNetwork}", sharedNetworkTag(n))
+			relations.SharedNetworkID = n.ID
 		} else if u, ok := obj.(*dbmodel.SystemUser); ok {
 			text = strings.ReplaceAll(text, "{user}", userTag(u))
 			relations.UserID = int64(u.ID)
@@ -128,11 +136,13 @@ func (ec *eventCenter) Shutdown() {
 	log.Printf("Stopping EventCenter")
 	ec.done <- true
 	ec.wg.Wait()
+	ec.notificationDispatcher.shutdown()
 	log.Printf("Stopped EventCenter")
 }
 
 // A main loop of EventCenter. It receives events via channel, stores
-// them into database and dispatches them to subscribers using SSE broker.
+// them into database and dispatches them to subscribers using SSE broker
+// and to the notification channels.
 func (ec *eventCenter) mainLoop() {
 	defer ec.wg.Done()
 	for {
@@ -148,6 +158,7 @@ func (ec *eventCenter) mainLoop() {
 				continue
 			}
 			ec.sseBroker.dispatchEvent(event)
+			ec.notificationDispatcher.dispatchEvent(event)
 		}
 	}
 }
@@ -184,6 +195,13 @@ func subnetTag(subnet *dbmodel.Subnet) string {
 	return tag
 }
 
+// Prepare a tag describing a shared network.
+func sharedNetworkTag(network *dbmodel.SharedNetwork) string {
+	tag := fmt.Sprintf("<shared-network id=\"%d\" name=\"%s\">",
+		network.ID, network.Name)
+	return tag
+}
+
 // Prepare a tag describing a user.
 func userTag(user *dbmodel.SystemUser) string {
 	tag := fmt.Sprintf("<user id=\"%d\" login=\"%s\" email=\"%s\">",
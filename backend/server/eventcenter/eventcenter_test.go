@@ -193,6 +193,30 @@ func TestCreateEventSubnet(t *testing.T) {
 	require.Zero(t, ev.CreatedAt)
 }
 
+// Test that the event with a shared network entry is created properly.
+func TestCreateEventSharedNetwork(t *testing.T) {
+	// Arrange
+	network := &dbmodel.SharedNetwork{
+		ID:   456,
+		Name: "frog",
+	}
+
+	// Act
+	ev := CreateEvent(dbmodel.EvWarning, "foo {sharedNetwork} bar", network)
+
+	// Assert
+	require.EqualValues(t, "foo <shared-network id=\"456\" name=\"frog\"> bar", ev.Text)
+	require.EqualValues(t, dbmodel.EvWarning, ev.Level)
+	require.NotNil(t, ev.Relations)
+	require.Zero(t, ev.Relations.MachineID)
+	require.Zero(t, ev.Relations.AppID)
+	require.Zero(t, ev.Relations.DaemonID)
+	require.Zero(t, ev.Relations.SubnetID)
+	require.EqualValues(t, 456, ev.Relations.SharedNetworkID)
+	require.Zero(t, ev.Relations.UserID)
+	require.Empty(t, ev.Details)
+}
+
 // Test that the error with a user entry is created properly.
 func TestCreateEventUser(t *testing.T) {
 	// Arrange
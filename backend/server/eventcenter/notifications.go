@@ -0,0 +1,172 @@
+package eventcenter
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Maximum number of the events waiting for being sent over the
+// notification channels. The events exceeding this number are dropped.
+const notificationQueueSize = 100
+
+// Limits the number of the notifications sent over a channel to the
+// specified number in a fixed time window. The notifications exceeding
+// the limit are suppressed.
+type rateLimiter struct {
+	limit       int64
+	interval    time.Duration
+	windowStart time.Time
+	count       int64
+	suppressed  int64
+}
+
+// Creates a new rate limiter. The zero limit disables rate limiting.
+func newRateLimiter(limit int64, interval time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:    limit,
+		interval: interval,
+	}
+}
+
+// Checks if the notification can be sent at the specified time. The
+// second returned value is the number of notifications suppressed in
+// the previous time window. It is non-zero only for the first
+// notification in the new time window.
+func (l *rateLimiter) allow(now time.Time) (bool, int64) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+	var suppressed int64
+	if l.windowStart.IsZero() || now.Sub(l.windowStart) >= l.interval {
+		suppressed = l.suppressed
+		l.windowStart = now
+		l.count = 0
+		l.suppressed = 0
+	}
+	if l.count >= l.limit {
+		l.suppressed++
+		return false, suppressed
+	}
+	l.count++
+	return true, suppressed
+}
+
+// Sends the events over the enabled notification channels. The events
+// are queued and sent in the background to not block the event center.
+// Each channel filters the events by level and related objects in the
+// same way as the SSE subscribers do.
+type notificationDispatcher struct {
+	db       *dbops.PgDB
+	events   chan *dbmodel.Event
+	wg       *sync.WaitGroup
+	limiters map[int64]*rateLimiter
+	// Creates the notifier for a channel. It can be replaced in the
+	// unit tests.
+	newNotifier func(channel *dbmodel.NotificationChannel) (Notifier, error)
+}
+
+// Creates a new notification dispatcher. It doesn't start sending the
+// notifications.
+func newNotificationDispatcher(db *dbops.PgDB) *notificationDispatcher {
+	return &notificationDispatcher{
+		db:          db,
+		events:      make(chan *dbmodel.Event, notificationQueueSize),
+		wg:          &sync.WaitGroup{},
+		limiters:    make(map[int64]*rateLimiter),
+		newNotifier: NewNotifier,
+	}
+}
+
+// Starts sending the queued events in the background.
+func (d *notificationDispatcher) start() {
+	d.wg.Add(1)
+	go d.run()
+}
+
+// Stops sending the events and waits until the queued events are sent.
+func (d *notificationDispatcher) shutdown() {
+	close(d.events)
+	d.wg.Wait()
+}
+
+// Queues the event for sending. The event is dropped when the queue is
+// full, e.g. when the notification channels are unresponsive.
+func (d *notificationDispatcher) dispatchEvent(event *dbmodel.Event) {
+	select {
+	case d.events <- event:
+	default:
+		log.Warnf("Notification queue is full; dropping notification about event '%s'", event.Text)
+	}
+}
+
+// Sends the queued events over the enabled notification channels. The
+// channels are fetched from the database for each event, so the changes
+// to the channels are applied immediately.
+func (d *notificationDispatcher) run() {
+	defer d.wg.Done()
+	for event := range d.events {
+		channels, err := dbmodel.GetEnabledNotificationChannels(d.db)
+		if err != nil {
+			log.Errorf("Problem getting notification channels from db: %+v", err)
+			continue
+		}
+		d.notify(channels, event, time.Now())
+	}
+}
+
+// Sends the event over the channels accepting it and not exceeding their
+// rate limits.
+func (d *notificationDispatcher) notify(channels []dbmodel.NotificationChannel, event *dbmodel.Event, now time.Time) {
+	if event.Relations == nil {
+		// The filters expect the relations to be set.
+		eventCopy := *event
+		eventCopy.Relations = &dbmodel.Relations{}
+		event = &eventCopy
+	}
+	limiters := make(map[int64]*rateLimiter)
+	for i := range channels {
+		channel := &channels[i]
+
+		// Preserve the limiter state unless the rate limit has changed.
+		interval := time.Duration(channel.RateLimitInterval) * time.Second
+		limiter, ok := d.limiters[channel.ID]
+		if !ok || limiter.limit != channel.RateLimit || limiter.interval != interval {
+			limiter = newRateLimiter(channel.RateLimit, interval)
+		}
+		limiters[channel.ID] = limiter
+
+		subscriber := newSubscriberWithFilters(channel.Level, subscriberFilters{
+			MachineID:       channel.MachineID,
+			AppID:           channel.AppID,
+			DaemonID:        channel.DaemonID,
+			SubnetID:        channel.SubnetID,
+			SharedNetworkID: channel.SharedNetworkID,
+		})
+		if !subscriber.AcceptsEvent(event) {
+			continue
+		}
+
+		allowed, suppressed := limiter.allow(now)
+		if suppressed > 0 {
+			log.Warnf("Suppressed %d notifications over channel %s due to rate limiting", suppressed, channel.Name)
+		}
+		if !allowed {
+			continue
+		}
+
+		notifier, err := d.newNotifier(channel)
+		if err != nil {
+			log.Errorf("Invalid notification channel %s: %+v", channel.Name, err)
+			continue
+		}
+		if err = notifier.Notify(event); err != nil {
+			log.Errorf("Problem sending notification over channel %s: %+v", channel.Name, err)
+		}
+	}
+	// Forget the limiters of the removed and disabled channels.
+	d.limiters = limiters
+}
@@ -0,0 +1,117 @@
+package eventcenter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Notifier recording the events in the unit tests.
+type testNotifier struct {
+	events []*dbmodel.Event
+}
+
+// Records the event.
+func (n *testNotifier) Notify(event *dbmodel.Event) error {
+	n.events = append(n.events, event)
+	return nil
+}
+
+// Test that the rate limiter allows the specified number of notifications
+// in the time window.
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2023, 3, 14, 9, 35, 10, 0, time.UTC)
+	limiter := newRateLimiter(2, time.Minute)
+
+	allowed, suppressed := limiter.allow(now)
+	require.True(t, allowed)
+	require.Zero(t, suppressed)
+	allowed, _ = limiter.allow(now.Add(time.Second))
+	require.True(t, allowed)
+	allowed, _ = limiter.allow(now.Add(2 * time.Second))
+	require.False(t, allowed)
+	allowed, _ = limiter.allow(now.Add(59 * time.Second))
+	require.False(t, allowed)
+
+	// New time window.
+	allowed, suppressed = limiter.allow(now.Add(time.Minute))
+	require.True(t, allowed)
+	require.EqualValues(t, 2, suppressed)
+	allowed, suppressed = limiter.allow(now.Add(time.Minute + time.Second))
+	require.True(t, allowed)
+	require.Zero(t, suppressed)
+}
+
+// Test that the zero limit disables rate limiting.
+func TestRateLimiterUnlimited(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(0, time.Minute)
+	for i := 0; i < 100; i++ {
+		allowed, _ := limiter.allow(now)
+		require.True(t, allowed)
+	}
+}
+
+// Test that the events are filtered and rate limited per channel.
+func TestNotificationDispatcherNotify(t *testing.T) {
+	dispatcher := newNotificationDispatcher(nil)
+	notifiers := map[int64]*testNotifier{}
+	dispatcher.newNotifier = func(channel *dbmodel.NotificationChannel) (Notifier, error) {
+		if _, ok := notifiers[channel.ID]; !ok {
+			notifiers[channel.ID] = &testNotifier{}
+		}
+		return notifiers[channel.ID], nil
+	}
+
+	channels := []dbmodel.NotificationChannel{
+		{
+			ID:                1,
+			Name:              "all",
+			RateLimit:         2,
+			RateLimitInterval: 60,
+		},
+		{
+			ID:    2,
+			Name:  "errors",
+			Level: dbmodel.EvError,
+		},
+		{
+			ID:        3,
+			Name:      "machine",
+			MachineID: 5,
+		},
+	}
+
+	now := time.Date(2023, 3, 14, 9, 35, 10, 0, time.UTC)
+	events := []*dbmodel.Event{
+		{Level: dbmodel.EvInfo, Relations: &dbmodel.Relations{MachineID: 5}},
+		{Level: dbmodel.EvError, Relations: &dbmodel.Relations{MachineID: 6}},
+		{Level: dbmodel.EvWarning},
+	}
+	for _, event := range events {
+		dispatcher.notify(channels, event, now)
+	}
+
+	// The first channel accepts all events but the third one exceeds
+	// the rate limit.
+	require.Len(t, notifiers[1].events, 2)
+	require.Same(t, events[0], notifiers[1].events[0])
+	require.Same(t, events[1], notifiers[1].events[1])
+	// The second channel only accepts errors.
+	require.Len(t, notifiers[2].events, 1)
+	require.Same(t, events[1], notifiers[2].events[0])
+	// The third channel only accepts the events related to machine 5.
+	require.Len(t, notifiers[3].events, 1)
+	require.Same(t, events[0], notifiers[3].events[0])
+
+	// The rate limit is reset in the next time window.
+	dispatcher.notify(channels, events[2], now.Add(time.Minute))
+	require.Len(t, notifiers[1].events, 3)
+
+	// The limiters of the removed channels are forgotten.
+	dispatcher.notify(channels[1:], events[2], now.Add(time.Minute))
+	require.NotContains(t, dispatcher.limiters, int64(1))
+	require.Contains(t, dispatcher.limiters, int64(2))
+}
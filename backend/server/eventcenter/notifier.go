@@ -0,0 +1,76 @@
+package eventcenter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	errors "github.com/pkg/errors"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Timeout for delivering a single notification.
+const notificationTimeout = 10 * time.Second
+
+// Interface to the notification sinks. A sink delivers the events to an
+// external system, e.g. sends them by email.
+type Notifier interface {
+	Notify(event *dbmodel.Event) error
+}
+
+// Creates the notifier for the specified notification channel. It returns
+// an error if the channel settings are invalid.
+func NewNotifier(channel *dbmodel.NotificationChannel) (Notifier, error) {
+	switch channel.ChannelType {
+	case dbmodel.NotificationChannelSMTP:
+		return newSMTPNotifier(&channel.Settings)
+	case dbmodel.NotificationChannelWebhook:
+		return newWebhookNotifier(&channel.Settings)
+	case dbmodel.NotificationChannelSlack:
+		return newSlackNotifier(&channel.Settings)
+	case dbmodel.NotificationChannelSyslog:
+		return newSyslogNotifier(&channel.Settings)
+	default:
+		return nil, errors.Errorf("unsupported notification channel type %s", channel.ChannelType)
+	}
+}
+
+// Matches the tags describing the objects in the event text, e.g.
+// <machine id="1" address="192.0.2.1" hostname="server1">.
+var eventTagRegexp = regexp.MustCompile(`<(daemon|app|machine|subnet|user)((?:\s+\w+="[^"]*")*)>`)
+
+// Matches a single attribute of the event tag.
+var eventTagAttrRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Names of the tag attributes describing the objects in the notifications.
+var eventTagLabels = map[string]string{
+	"daemon":  "name",
+	"app":     "name",
+	"machine": "address",
+	"subnet":  "prefix",
+	"user":    "login",
+}
+
+// Returns the event text with the object tags replaced by the object
+// names, e.g. the machine tag is replaced with the machine address. The
+// tags are meant for the UI and they are hard to read in the notifications.
+func formatEventText(text string) string {
+	return eventTagRegexp.ReplaceAllStringFunc(text, func(tag string) string {
+		match := eventTagRegexp.FindStringSubmatch(tag)
+		attrs := map[string]string{}
+		for _, attr := range eventTagAttrRegexp.FindAllStringSubmatch(match[2], -1) {
+			attrs[attr[1]] = attr[2]
+		}
+		label := attrs[eventTagLabels[match[1]]]
+		if label == "" {
+			label = fmt.Sprintf("%s %s", match[1], attrs["id"])
+		}
+		return label
+	})
+}
+
+// Returns the short event summary used as a title of the notifications.
+func formatEventSummary(event *dbmodel.Event) string {
+	return fmt.Sprintf("[Stork] %s: %s", strings.ToUpper(event.Level.String()), formatEventText(event.Text))
+}
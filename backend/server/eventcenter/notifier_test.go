@@ -0,0 +1,151 @@
+package eventcenter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Test that the object tags are replaced with the object names.
+func TestFormatEventText(t *testing.T) {
+	require.Equal(t, "Machine 192.0.2.1 is unreachable",
+		formatEventText(`Machine <machine id="1" address="192.0.2.1" hostname="server1"> is unreachable`))
+	require.Equal(t, "dhcp4 in kea@server1 is down",
+		formatEventText(`<daemon id="2" name="dhcp4" appId="3" appType="kea"> in <app id="3" name="kea@server1" type="kea" version="2.2.0"> is down`))
+	require.Equal(t, "Subnet 192.0.2.0/24 changed by admin",
+		formatEventText(`Subnet <subnet id="4" prefix="192.0.2.0/24"> changed by <user id="1" login="admin" email="">`))
+	require.Equal(t, "user 5 logged in",
+		formatEventText(`<user id="5" login="" email=""> logged in`))
+	require.Equal(t, "plain <b>text</b>", formatEventText("plain <b>text</b>"))
+}
+
+// Test that the event summary includes the level.
+func TestFormatEventSummary(t *testing.T) {
+	event := &dbmodel.Event{
+		Level: dbmodel.EvError,
+		Text:  `Machine <machine id="1" address="192.0.2.1" hostname="server1"> is unreachable`,
+	}
+	require.Equal(t, "[Stork] ERROR: Machine 192.0.2.1 is unreachable", formatEventSummary(event))
+}
+
+// Test that the notifiers are created for the supported channel types
+// and the invalid settings are rejected.
+func TestNewNotifier(t *testing.T) {
+	testCases := []struct {
+		name     string
+		channel  dbmodel.NotificationChannel
+		expected any
+	}{
+		{
+			name: "smtp",
+			channel: dbmodel.NotificationChannel{
+				ChannelType: dbmodel.NotificationChannelSMTP,
+				Settings: dbmodel.NotificationChannelSettings{
+					SMTPHost: "mail.example.org",
+					SMTPFrom: "stork@example.org",
+					SMTPTo:   []string{"admin@example.org"},
+				},
+			},
+			expected: &smtpNotifier{},
+		},
+		{
+			name: "smtp no recipients",
+			channel: dbmodel.NotificationChannel{
+				ChannelType: dbmodel.NotificationChannelSMTP,
+				Settings: dbmodel.NotificationChannelSettings{
+					SMTPHost: "mail.example.org",
+					SMTPFrom: "stork@example.org",
+				},
+			},
+		},
+		{
+			name: "smtp invalid sender",
+			channel: dbmodel.NotificationChannel{
+				ChannelType: dbmodel.NotificationChannelSMTP,
+				Settings: dbmodel.NotificationChannelSettings{
+					SMTPHost: "mail.example.org",
+					SMTPFrom: "stork",
+					SMTPTo:   []string{"admin@example.org"},
+				},
+			},
+		},
+		{
+			name: "webhook",
+			channel: dbmodel.NotificationChannel{
+				ChannelType: dbmodel.NotificationChannelWebhook,
+				Settings: dbmodel.NotificationChannelSettings{
+					URL: "https://example.org/hook",
+				},
+			},
+			expected: &webhookNotifier{},
+		},
+		{
+			name: "webhook relative URL",
+			channel: dbmodel.NotificationChannel{
+				ChannelType: dbmodel.NotificationChannelWebhook,
+				Settings: dbmodel.NotificationChannelSettings{
+					URL: "/hook",
+				},
+			},
+		},
+		{
+			name: "slack",
+			channel: dbmodel.NotificationChannel{
+				ChannelType: dbmodel.NotificationChannelSlack,
+				Settings: dbmodel.NotificationChannelSettings{
+					URL: "https://hooks.example.org/services/1",
+				},
+			},
+			expected: &webhookNotifier{},
+		},
+		{
+			name: "syslog",
+			channel: dbmodel.NotificationChannel{
+				ChannelType: dbmodel.NotificationChannelSyslog,
+				Settings: dbmodel.NotificationChannelSettings{
+					SyslogAddress: "192.0.2.1:514",
+				},
+			},
+			expected: &syslogNotifier{},
+		},
+		{
+			name: "syslog unknown facility",
+			channel: dbmodel.NotificationChannel{
+				ChannelType: dbmodel.NotificationChannelSyslog,
+				Settings: dbmodel.NotificationChannelSettings{
+					SyslogAddress:  "192.0.2.1:514",
+					SyslogFacility: "local8",
+				},
+			},
+		},
+		{
+			name: "syslog no port",
+			channel: dbmodel.NotificationChannel{
+				ChannelType: dbmodel.NotificationChannelSyslog,
+				Settings: dbmodel.NotificationChannelSettings{
+					SyslogAddress: "192.0.2.1",
+				},
+			},
+		},
+		{
+			name: "unknown type",
+			channel: dbmodel.NotificationChannel{
+				ChannelType: "pager",
+			},
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			notifier, err := NewNotifier(&tc.channel)
+			if tc.expected == nil {
+				require.Error(t, err)
+				require.Nil(t, notifier)
+				return
+			}
+			require.NoError(t, err)
+			require.IsType(t, tc.expected, notifier)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package eventcenter
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	errors "github.com/pkg/errors"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Default SMTP submission port.
+const defaultSMTPPort = 587
+
+// Sends the events by email. The connection is upgraded to TLS when the
+// server supports the STARTTLS extension. The credentials are only sent
+// when they are specified.
+type smtpNotifier struct {
+	host     string
+	port     int64
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// Creates a notifier sending the events by email.
+func newSMTPNotifier(settings *dbmodel.NotificationChannelSettings) (Notifier, error) {
+	if settings.SMTPHost == "" {
+		return nil, errors.New("SMTP server host must be specified")
+	}
+	port := settings.SMTPPort
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	if port < 0 || port > 65535 {
+		return nil, errors.Errorf("invalid SMTP server port %d", port)
+	}
+	if _, err := mail.ParseAddress(settings.SMTPFrom); err != nil {
+		return nil, errors.Wrapf(err, "invalid sender address %s", settings.SMTPFrom)
+	}
+	if len(settings.SMTPTo) == 0 {
+		return nil, errors.New("at least one recipient address must be specified")
+	}
+	for _, to := range settings.SMTPTo {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, errors.Wrapf(err, "invalid recipient address %s", to)
+		}
+	}
+	return &smtpNotifier{
+		host:     settings.SMTPHost,
+		port:     port,
+		username: settings.SMTPUsername,
+		password: settings.SMTPPassword,
+		from:     settings.SMTPFrom,
+		to:       settings.SMTPTo,
+	}, nil
+}
+
+// Returns the email message including the headers.
+func (n *smtpNotifier) formatMessage(event *dbmodel.Event) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", formatEventSummary(event)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	fmt.Fprintf(&msg, "Level: %s\r\n", event.Level)
+	if !event.CreatedAt.IsZero() {
+		fmt.Fprintf(&msg, "Time: %s\r\n", event.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(&msg, "\r\n%s\r\n", formatEventText(event.Text))
+	if event.Details != "" {
+		fmt.Fprintf(&msg, "\r\n%s\r\n", strings.ReplaceAll(event.Details, "\n", "\r\n"))
+	}
+	return msg.Bytes()
+}
+
+// Sends the event by email.
+func (n *smtpNotifier) Notify(event *dbmodel.Event) error {
+	address := net.JoinHostPort(n.host, strconv.FormatInt(n.port, 10))
+	conn, err := net.DialTimeout("tcp", address, notificationTimeout)
+	if err != nil {
+		return errors.Wrapf(err, "problem connecting to SMTP server %s", address)
+	}
+	_ = conn.SetDeadline(time.Now().Add(notificationTimeout))
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrapf(err, "problem starting SMTP session with %s", address)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: n.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return errors.Wrapf(err, "problem starting TLS with SMTP server %s", address)
+		}
+	}
+	if n.username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.Errorf("SMTP server %s does not support authentication", address)
+		}
+		if err = client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return errors.Wrapf(err, "problem authenticating to SMTP server %s", address)
+		}
+	}
+	if err = client.Mail(n.from); err != nil {
+		return errors.Wrapf(err, "SMTP server %s rejected sender %s", address, n.from)
+	}
+	for _, to := range n.to {
+		if err = client.Rcpt(to); err != nil {
+			return errors.Wrapf(err, "SMTP server %s rejected recipient %s", address, to)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return errors.Wrapf(err, "problem sending email to SMTP server %s", address)
+	}
+	if _, err = writer.Write(n.formatMessage(event)); err != nil {
+		return errors.Wrapf(err, "problem sending email to SMTP server %s", address)
+	}
+	if err = writer.Close(); err != nil {
+		return errors.Wrapf(err, "problem sending email to SMTP server %s", address)
+	}
+	return client.Quit()
+}
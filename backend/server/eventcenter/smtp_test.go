@@ -0,0 +1,125 @@
+package eventcenter
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Message received by the fake SMTP server.
+type receivedMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// Starts a minimal SMTP server accepting a single message. It returns
+// the server port and the channel receiving the message.
+func startTestSMTPServer(t *testing.T) (int64, chan *receivedMail) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan *receivedMail, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) {
+			fmt.Fprintf(conn, "%s\r\n", line)
+		}
+		mail := &receivedMail{}
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(command, "EHLO"):
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case strings.HasPrefix(command, "AUTH PLAIN"):
+				reply("235 Authentication successful")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				mail.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				mail.to = append(mail.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 Go ahead")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil || dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				mail.data = data.String()
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				received <- mail
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	portNumber, err := strconv.ParseInt(port, 10, 64)
+	require.NoError(t, err)
+	return portNumber, received
+}
+
+// Test sending the event by email.
+func TestSMTPNotify(t *testing.T) {
+	port, received := startTestSMTPServer(t)
+
+	notifier, err := newSMTPNotifier(&dbmodel.NotificationChannelSettings{
+		SMTPHost:     "127.0.0.1",
+		SMTPPort:     port,
+		SMTPUsername: "stork",
+		SMTPPassword: "secret",
+		SMTPFrom:     "stork@example.org",
+		SMTPTo:       []string{"admin@example.org", "ops@example.org"},
+	})
+	require.NoError(t, err)
+
+	err = notifier.Notify(createTestNotificationEvent())
+	require.NoError(t, err)
+
+	mail := <-received
+	require.Equal(t, "stork@example.org", mail.from)
+	require.Equal(t, []string{"admin@example.org", "ops@example.org"}, mail.to)
+	require.Contains(t, mail.data, "Subject: [Stork] WARNING: Machine 192.0.2.1 is unreachable\r\n")
+	require.Contains(t, mail.data, "To: admin@example.org, ops@example.org\r\n")
+	require.Contains(t, mail.data, "Time: 2023-03-14T09:35:10Z\r\n")
+	require.Contains(t, mail.data, "\r\nconnection refused\r\n")
+}
+
+// Test that the default SMTP port is used when the port is not specified.
+func TestNewSMTPNotifierDefaultPort(t *testing.T) {
+	notifier, err := newSMTPNotifier(&dbmodel.NotificationChannelSettings{
+		SMTPHost: "mail.example.org",
+		SMTPFrom: "stork@example.org",
+		SMTPTo:   []string{"admin@example.org"},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 587, notifier.(*smtpNotifier).port)
+}
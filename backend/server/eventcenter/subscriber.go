@@ -57,6 +57,18 @@ func newSubscriber(serverURL *url.URL) *Subscriber {
 	return subscriber
 }
 
+// Creates a new instance of the subscriber with the filters specified
+// explicitly rather than in the URL. It is used by the notification
+// channels.
+func newSubscriberWithFilters(level dbmodel.EventLevel, filters subscriberFilters) *Subscriber {
+	subscriber := &Subscriber{
+		level:   level,
+		filters: filters,
+	}
+	subscriber.updateUseFilter()
+	return subscriber
+}
+
 // Populates filters from URL. In a simplest case, a caller provides ids of the
 // objects to filter by, e.g. machine=1, indicating that only events associated
 // with machine id of 1 should be returned. However, there are also other
@@ -80,6 +92,9 @@ func (s *Subscriber) applyFiltersFromQuery(db *dbops.PgDB) (err error) {
 	if f.SubnetID, err = getQueryValueAsInt64("subnet", queryValues); err != nil {
 		return err
 	}
+	if f.SharedNetworkID, err = getQueryValueAsInt64("sharedNetwork", queryValues); err != nil {
+		return err
+	}
 	if f.DaemonID, err = getQueryValueAsInt64("daemon", queryValues); err != nil {
 		return err
 	}
@@ -153,19 +168,24 @@ func (s *Subscriber) applyFiltersFromQuery(db *dbops.PgDB) (err error) {
 		}
 	}
 
-	// In order to avoid iterating over all the filters every time we have a new
-	// event we should check if everything we have done above resulted in setting
-	// any of these values. If all of them happen to be zero we leave the useFilter
-	// value as false reducing the number of checks to be performed to only this
-	// value. Otherwise, we need to do the matching for each event.
-	for _, id := range []int64{f.MachineID, f.AppID, f.SubnetID, f.DaemonID, f.UserID, level} {
+	s.updateUseFilter()
+
+	return nil
+}
+
+// In order to avoid iterating over all the filters every time we have a new
+// event we should check if any of the filters has been set. If all of them
+// happen to be zero we leave the useFilter value as false reducing the number
+// of checks to be performed to only this value. Otherwise, we need to do the
+// matching for each event.
+func (s *Subscriber) updateUseFilter() {
+	f := &s.filters
+	for _, id := range []int64{f.MachineID, f.AppID, f.SubnetID, f.SharedNetworkID, f.DaemonID, f.UserID, int64(s.level)} {
 		if id != 0 {
 			s.useFilter = true
 			break
 		}
 	}
-
-	return nil
 }
 
 // Returns a boolean value indicating if the subscriber is eligible to receive
@@ -175,6 +195,7 @@ func (s *Subscriber) AcceptsEvent(event *dbmodel.Event) bool {
 		((s.filters.MachineID == 0 || event.Relations.MachineID == s.filters.MachineID) &&
 			(s.filters.AppID == 0 || event.Relations.AppID == s.filters.AppID) &&
 			(s.filters.SubnetID == 0 || event.Relations.SubnetID == s.filters.SubnetID) &&
+			(s.filters.SharedNetworkID == 0 || event.Relations.SharedNetworkID == s.filters.SharedNetworkID) &&
 			(s.filters.DaemonID == 0 || event.Relations.DaemonID == s.filters.DaemonID) &&
 			(s.filters.UserID == 0 || event.Relations.UserID == s.filters.UserID) &&
 			(s.level == 0 || event.Level >= s.level))
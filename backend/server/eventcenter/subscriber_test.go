@@ -36,7 +36,7 @@ func TestSetFilterValues(t *testing.T) {
 	defer teardown()
 
 	// Use an URL with all parameters set.
-	url, err := url.Parse("http://example.org/sse?machine=1&app=2&subnet=3&daemon=4&user=5&level=1")
+	url, err := url.Parse("http://example.org/sse?machine=1&app=2&subnet=3&daemon=4&user=5&sharedNetwork=6&level=1")
 	require.NoError(t, err)
 
 	subscriber := newSubscriber(url)
@@ -55,6 +55,7 @@ func TestSetFilterValues(t *testing.T) {
 	require.EqualValues(t, 3, subscriber.filters.SubnetID)
 	require.EqualValues(t, 4, subscriber.filters.DaemonID)
 	require.EqualValues(t, 5, subscriber.filters.UserID)
+	require.EqualValues(t, 6, subscriber.filters.SharedNetworkID)
 	require.EqualValues(t, 1, subscriber.level)
 }
 
@@ -63,7 +64,7 @@ func TestAcceptEventsSingleFilter(t *testing.T) {
 	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
 	defer teardown()
 
-	testCases := []string{"machine", "app", "subnet", "daemon", "user"}
+	testCases := []string{"machine", "app", "subnet", "sharedNetwork", "daemon", "user"}
 	for i := range testCases {
 		tc := testCases[i]
 		t.Run(tc, func(t *testing.T) {
@@ -91,6 +92,8 @@ func TestAcceptEventsSingleFilter(t *testing.T) {
 				ev.Relations.AppID = 123
 			case "subnet":
 				ev.Relations.SubnetID = 123
+			case "sharedNetwork":
+				ev.Relations.SharedNetworkID = 123
 			case "daemon":
 				ev.Relations.DaemonID = 123
 			case "user":
@@ -303,3 +306,22 @@ func TestIndirectRelationsWrongParams(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+// Test that the subscriber created with the explicit filters accepts
+// the matching events.
+func TestNewSubscriberWithFilters(t *testing.T) {
+	subscriber := newSubscriberWithFilters(dbmodel.EvInfo, subscriberFilters{})
+	require.False(t, subscriber.useFilter)
+	require.True(t, subscriber.AcceptsEvent(&dbmodel.Event{Relations: &dbmodel.Relations{}}))
+
+	subscriber = newSubscriberWithFilters(dbmodel.EvWarning, subscriberFilters{AppID: 3})
+	require.True(t, subscriber.useFilter)
+	require.True(t, subscriber.AcceptsEvent(&dbmodel.Event{Level: dbmodel.EvError, Relations: &dbmodel.Relations{AppID: 3}}))
+	require.False(t, subscriber.AcceptsEvent(&dbmodel.Event{Level: dbmodel.EvInfo, Relations: &dbmodel.Relations{AppID: 3}}))
+	require.False(t, subscriber.AcceptsEvent(&dbmodel.Event{Level: dbmodel.EvError, Relations: &dbmodel.Relations{AppID: 4}}))
+
+	subscriber = newSubscriberWithFilters(dbmodel.EvInfo, subscriberFilters{SharedNetworkID: 5})
+	require.True(t, subscriber.useFilter)
+	require.True(t, subscriber.AcceptsEvent(&dbmodel.Event{Relations: &dbmodel.Relations{SharedNetworkID: 5}}))
+	require.False(t, subscriber.AcceptsEvent(&dbmodel.Event{Relations: &dbmodel.Relations{SubnetID: 5}}))
+}
@@ -0,0 +1,132 @@
+package eventcenter
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	errors "github.com/pkg/errors"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Application name included in the syslog messages.
+const syslogAppName = "stork-server"
+
+// Syslog facility codes by name (see RFC 5424, section 6.2.1).
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// Sends the events to a syslog server in the RFC 5424 format. The
+// messages are sent over UDP or over TCP using the octet counting
+// framing (see RFC 6587).
+type syslogNotifier struct {
+	network  string
+	address  string
+	facility int
+	hostname string
+}
+
+// Creates a notifier sending the events to a syslog server. The UDP
+// transport and the local0 facility are used by default.
+func newSyslogNotifier(settings *dbmodel.NotificationChannelSettings) (Notifier, error) {
+	network := settings.SyslogNetwork
+	if network == "" {
+		network = "udp"
+	}
+	if network != "udp" && network != "tcp" {
+		return nil, errors.Errorf("unsupported syslog transport %s", network)
+	}
+	if settings.SyslogAddress == "" {
+		return nil, errors.New("syslog server address must be specified")
+	}
+	if _, _, err := net.SplitHostPort(settings.SyslogAddress); err != nil {
+		return nil, errors.Wrapf(err, "invalid syslog server address %s", settings.SyslogAddress)
+	}
+	facilityName := settings.SyslogFacility
+	if facilityName == "" {
+		facilityName = "local0"
+	}
+	facility, ok := syslogFacilities[facilityName]
+	if !ok {
+		return nil, errors.Errorf("unknown syslog facility %s", facilityName)
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &syslogNotifier{
+		network:  network,
+		address:  settings.SyslogAddress,
+		facility: facility,
+		hostname: hostname,
+	}, nil
+}
+
+// Returns the syslog severity corresponding to the event level.
+func getSyslogSeverity(level dbmodel.EventLevel) int {
+	switch level {
+	case dbmodel.EvError:
+		return 3
+	case dbmodel.EvWarning:
+		return 4
+	default:
+		return 6
+	}
+}
+
+// Returns the event formatted as an RFC 5424 syslog message.
+func (n *syslogNotifier) formatMessage(event *dbmodel.Event) string {
+	timestamp := event.CreatedAt
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	msg := formatEventText(event.Text)
+	if event.Details != "" {
+		msg += ": " + strings.ReplaceAll(event.Details, "\n", " ")
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+		n.facility*8+getSyslogSeverity(event.Level),
+		timestamp.UTC().Format(time.RFC3339Nano),
+		n.hostname, syslogAppName, os.Getpid(), msg)
+}
+
+// Sends the event to the syslog server.
+func (n *syslogNotifier) Notify(event *dbmodel.Event) error {
+	conn, err := net.DialTimeout(n.network, n.address, notificationTimeout)
+	if err != nil {
+		return errors.Wrapf(err, "problem connecting to syslog server %s", n.address)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(notificationTimeout))
+
+	msg := n.formatMessage(event)
+	if n.network == "tcp" {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	if _, err = conn.Write([]byte(msg)); err != nil {
+		return errors.Wrapf(err, "problem sending event to syslog server %s", n.address)
+	}
+	return nil
+}
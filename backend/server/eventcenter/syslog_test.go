@@ -0,0 +1,94 @@
+package eventcenter
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Test formatting the event as an RFC 5424 syslog message.
+func TestSyslogFormatMessage(t *testing.T) {
+	notifier, err := newSyslogNotifier(&dbmodel.NotificationChannelSettings{
+		SyslogAddress:  "127.0.0.1:514",
+		SyslogFacility: "daemon",
+	})
+	require.NoError(t, err)
+	n := notifier.(*syslogNotifier)
+	n.hostname = "stork"
+
+	msg := n.formatMessage(createTestNotificationEvent())
+	// The facility is 3 and the warning severity is 4.
+	require.Equal(t, fmt.Sprintf("<28>1 2023-03-14T09:35:10Z stork stork-server %d - - Machine 192.0.2.1 is unreachable: connection refused", os.Getpid()), msg)
+}
+
+// Test that the event levels are mapped to the syslog severities.
+func TestGetSyslogSeverity(t *testing.T) {
+	require.Equal(t, 6, getSyslogSeverity(dbmodel.EvInfo))
+	require.Equal(t, 4, getSyslogSeverity(dbmodel.EvWarning))
+	require.Equal(t, 3, getSyslogSeverity(dbmodel.EvError))
+}
+
+// Test sending the event to the syslog server over UDP.
+func TestSyslogNotifyUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	notifier, err := newSyslogNotifier(&dbmodel.NotificationChannelSettings{
+		SyslogAddress: conn.LocalAddr().String(),
+	})
+	require.NoError(t, err)
+
+	err = notifier.Notify(createTestNotificationEvent())
+	require.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	// The local0 facility is used by default.
+	require.Regexp(t, `^<132>1 2023-03-14T09:35:10Z \S+ stork-server \d+ - - Machine 192.0.2.1 is unreachable: connection refused$`, string(buf[:n]))
+}
+
+// Test sending the event to the syslog server over TCP with the octet
+// counting framing.
+func TestSyslogNotifyTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan string)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		var length int
+		if _, err = fmt.Fscanf(reader, "%d ", &length); err != nil {
+			close(received)
+			return
+		}
+		buf := make([]byte, length)
+		_, _ = reader.Read(buf)
+		received <- string(buf)
+	}()
+
+	notifier, err := newSyslogNotifier(&dbmodel.NotificationChannelSettings{
+		SyslogNetwork: "tcp",
+		SyslogAddress: listener.Addr().String(),
+	})
+	require.NoError(t, err)
+
+	err = notifier.Notify(createTestNotificationEvent())
+	require.NoError(t, err)
+
+	msg := <-received
+	require.Regexp(t, `^<132>1 2023-03-14T09:35:10Z \S+ stork-server \d+ - - Machine 192.0.2.1 is unreachable: connection refused$`, msg)
+}
@@ -0,0 +1,109 @@
+package eventcenter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	errors "github.com/pkg/errors"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Event sent to the generic webhook as a JSON object.
+type webhookPayload struct {
+	ID        int64              `json:"id"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Level     string             `json:"level"`
+	Text      string             `json:"text"`
+	Details   string             `json:"details,omitempty"`
+	Relations *dbmodel.Relations `json:"relations,omitempty"`
+}
+
+// Event sent to the Slack-compatible webhook.
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// Sends the events to the HTTP endpoints as JSON objects. The payload
+// is generated by the format function.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+	format func(event *dbmodel.Event) any
+}
+
+// Checks if the URL of the webhook is valid.
+func validateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrapf(err, "invalid webhook URL %s", rawURL)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.Errorf("webhook URL %s must be an absolute HTTP or HTTPS URL", rawURL)
+	}
+	return nil
+}
+
+// Creates a notifier sending the events to a generic webhook. The events
+// are sent as JSON objects including the event level, text, details and
+// the IDs of the related objects.
+func newWebhookNotifier(settings *dbmodel.NotificationChannelSettings) (Notifier, error) {
+	if err := validateWebhookURL(settings.URL); err != nil {
+		return nil, err
+	}
+	return &webhookNotifier{
+		url:    settings.URL,
+		client: &http.Client{Timeout: notificationTimeout},
+		format: func(event *dbmodel.Event) any {
+			return &webhookPayload{
+				ID:        event.ID,
+				CreatedAt: event.CreatedAt,
+				Level:     event.Level.String(),
+				Text:      formatEventText(event.Text),
+				Details:   event.Details,
+				Relations: event.Relations,
+			}
+		},
+	}, nil
+}
+
+// Creates a notifier sending the events to a Slack-compatible incoming
+// webhook. The event is sent as a message text.
+func newSlackNotifier(settings *dbmodel.NotificationChannelSettings) (Notifier, error) {
+	if err := validateWebhookURL(settings.URL); err != nil {
+		return nil, err
+	}
+	return &webhookNotifier{
+		url:    settings.URL,
+		client: &http.Client{Timeout: notificationTimeout},
+		format: func(event *dbmodel.Event) any {
+			text := formatEventSummary(event)
+			if event.Details != "" {
+				text += "\n```" + event.Details + "```"
+			}
+			return &slackPayload{
+				Text: text,
+			}
+		},
+	}, nil
+}
+
+// Posts the event to the webhook. It returns an error if the webhook
+// responds with a status code other than 2xx.
+func (n *webhookNotifier) Notify(event *dbmodel.Event) error {
+	body, err := json.Marshal(n.format(event))
+	if err != nil {
+		return errors.Wrap(err, "problem serializing event to JSON")
+	}
+	rsp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "problem sending event to webhook %s", n.url)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return errors.Errorf("webhook %s responded with status %s", n.url, rsp.Status)
+	}
+	return nil
+}
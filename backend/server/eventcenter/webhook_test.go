@@ -0,0 +1,94 @@
+package eventcenter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Returns the event used in the notification tests.
+func createTestNotificationEvent() *dbmodel.Event {
+	return &dbmodel.Event{
+		ID:        7,
+		CreatedAt: time.Date(2023, 3, 14, 9, 35, 10, 0, time.UTC),
+		Level:     dbmodel.EvWarning,
+		Text:      `Machine <machine id="1" address="192.0.2.1" hostname="server1"> is unreachable`,
+		Details:   "connection refused",
+		Relations: &dbmodel.Relations{
+			MachineID: 1,
+		},
+	}
+}
+
+// Starts the HTTP server recording the bodies of the received requests.
+func startTestWebhookServer(t *testing.T, status int) (*httptest.Server, *[]map[string]any) {
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(data, &body))
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	return server, &bodies
+}
+
+// Test sending the event to the generic webhook.
+func TestWebhookNotify(t *testing.T) {
+	server, bodies := startTestWebhookServer(t, http.StatusOK)
+	defer server.Close()
+
+	notifier, err := newWebhookNotifier(&dbmodel.NotificationChannelSettings{URL: server.URL})
+	require.NoError(t, err)
+
+	err = notifier.Notify(createTestNotificationEvent())
+	require.NoError(t, err)
+
+	require.Len(t, *bodies, 1)
+	body := (*bodies)[0]
+	require.EqualValues(t, 7, body["id"])
+	require.Equal(t, "2023-03-14T09:35:10Z", body["createdAt"])
+	require.Equal(t, "warning", body["level"])
+	require.Equal(t, "Machine 192.0.2.1 is unreachable", body["text"])
+	require.Equal(t, "connection refused", body["details"])
+	require.Equal(t, map[string]any{"MachineID": float64(1)}, body["relations"])
+}
+
+// Test sending the event to the Slack-compatible webhook.
+func TestSlackNotify(t *testing.T) {
+	server, bodies := startTestWebhookServer(t, http.StatusOK)
+	defer server.Close()
+
+	notifier, err := newSlackNotifier(&dbmodel.NotificationChannelSettings{URL: server.URL})
+	require.NoError(t, err)
+
+	err = notifier.Notify(createTestNotificationEvent())
+	require.NoError(t, err)
+
+	require.Len(t, *bodies, 1)
+	require.Equal(t, map[string]any{
+		"text": "[Stork] WARNING: Machine 192.0.2.1 is unreachable\n```connection refused```",
+	}, (*bodies)[0])
+}
+
+// Test that an error is returned when the webhook responds with an
+// error status.
+func TestWebhookNotifyErrorStatus(t *testing.T) {
+	server, _ := startTestWebhookServer(t, http.StatusForbidden)
+	defer server.Close()
+
+	notifier, err := newWebhookNotifier(&dbmodel.NotificationChannelSettings{URL: server.URL})
+	require.NoError(t, err)
+
+	err = notifier.Notify(createTestNotificationEvent())
+	require.ErrorContains(t, err, "403")
+}
@@ -0,0 +1,98 @@
+package hostsexport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	keaconfig "isc.org/stork/appcfg/kea"
+)
+
+// Separator of the values in the CSV columns holding multiple values.
+// It is the same as in the hosts import.
+const listSeparator = ";"
+
+// Names of the columns in the exported CSV file. The columns shared
+// with the hosts import have the same names.
+var csvHeader = []string{
+	"daemon-id",
+	"daemon-name",
+	"app-name",
+	"identifier-type",
+	"identifier",
+	"ip-addresses",
+	"prefixes",
+	"hostname",
+	"subnet",
+	"subnet-id",
+	"options",
+	"client-classes",
+	"next-server",
+	"server-hostname",
+	"boot-file-name",
+}
+
+// Writes the host reservations in the CSV format. Each line holds the
+// reservation data configured in one daemon, so the host reservation
+// shared by multiple daemons occupies multiple lines.
+func writeCSV(writer io.Writer, daemons []*daemonReservations) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(csvHeader); err != nil {
+		return pkgerrors.Wrap(err, "problem writing CSV header")
+	}
+	for _, d := range daemons {
+		for _, dr := range d.reservations {
+			reservation := dr.reservation
+			identifierTypes, identifiers := getIdentifiers(reservation)
+			var addresses []string
+			if reservation.IPAddress != "" {
+				addresses = append(addresses, reservation.IPAddress)
+			}
+			addresses = append(addresses, reservation.IPAddresses...)
+			var options []string
+			for _, option := range reservation.OptionData {
+				options = append(options, formatCSVOption(d.daemon.Name, option))
+			}
+			var localSubnetID string
+			if dr.subnetPrefix != "" {
+				localSubnetID = strconv.FormatInt(dr.localSubnetID, 10)
+			}
+			err := csvWriter.Write([]string{
+				strconv.FormatInt(d.daemon.ID, 10),
+				d.daemon.Name,
+				getAppName(d.daemon),
+				strings.Join(identifierTypes, listSeparator),
+				strings.Join(identifiers, listSeparator),
+				strings.Join(addresses, listSeparator),
+				strings.Join(reservation.Prefixes, listSeparator),
+				reservation.Hostname,
+				dr.subnetPrefix,
+				localSubnetID,
+				strings.Join(options, listSeparator),
+				strings.Join(reservation.ClientClasses, listSeparator),
+				reservation.NextServer,
+				reservation.ServerHostname,
+				reservation.BootFileName,
+			})
+			if err != nil {
+				return pkgerrors.Wrapf(err, "problem writing host %d in CSV format", dr.host.ID)
+			}
+		}
+	}
+	csvWriter.Flush()
+	return pkgerrors.Wrap(csvWriter.Error(), "problem writing host reservations in CSV format")
+}
+
+// Formats the option as a code and data pair. The code is preceded by
+// the option space if the option does not belong to the top-level space
+// of the daemon.
+func formatCSVOption(daemonName string, option keaconfig.SingleOptionData) string {
+	code := strconv.Itoa(int(option.Code))
+	if option.Space != "" && option.Space != daemonName {
+		code = fmt.Sprintf("%s.%s", option.Space, code)
+	}
+	return fmt.Sprintf("%s=%s", code, option.Data)
+}
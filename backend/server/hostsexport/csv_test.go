@@ -0,0 +1,41 @@
+package hostsexport
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
+)
+
+// Test writing the host reservations in the CSV format.
+func TestWriteCSV(t *testing.T) {
+	var buffer bytes.Buffer
+	err := writeCSV(&buffer, createTestDaemonReservations(t))
+	require.NoError(t, err)
+
+	require.Equal(t,
+		"daemon-id,daemon-name,app-name,identifier-type,identifier,ip-addresses,prefixes,hostname,subnet,subnet-id,options,client-classes,next-server,server-hostname,boot-file-name\n"+
+			"1,dhcp4,kea@192.0.2.1,hw-address,010203040506,192.0.2.10,,first.example.org,192.0.2.0/24,111,3=192.0.2.1;15=example.org,foo;bar,192.0.2.1,boot.example.org,/tmp/boot\n"+
+			"1,dhcp4,kea@192.0.2.1,circuit-id,0102,10.0.0.1,,,,,,,,,\n"+
+			"2,dhcp6,,duid,01020304,2001:db8:1::10,3000:1::/64,second.example.org,2001:db8:1::/64,222,,,,,\n",
+		buffer.String())
+}
+
+// Test formatting the options in the CSV format.
+func TestFormatCSVOption(t *testing.T) {
+	require.Equal(t, "3=192.0.2.1", formatCSVOption("dhcp4", keaconfig.SingleOptionData{
+		Code:  3,
+		Data:  "192.0.2.1",
+		Space: "dhcp4",
+	}))
+	require.Equal(t, "foo.1=bar", formatCSVOption("dhcp4", keaconfig.SingleOptionData{
+		Code:  1,
+		Data:  "bar",
+		Space: "foo",
+	}))
+	require.Equal(t, "23=2001:db8:1::1", formatCSVOption("dhcp6", keaconfig.SingleOptionData{
+		Code: 23,
+		Data: "2001:db8:1::1",
+	}))
+}
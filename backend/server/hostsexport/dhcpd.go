@@ -0,0 +1,143 @@
+package hostsexport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	keaconfig "isc.org/stork/appcfg/kea"
+	dhcpmodel "isc.org/stork/datamodel/dhcp"
+	dbmodel "isc.org/stork/server/database/model"
+	storkutil "isc.org/stork/util"
+)
+
+// Writes the host reservations as the ISC DHCP server host declarations.
+// The declarations are grouped by daemons because each daemon corresponds
+// to a separate ISC DHCP server configuration. The ISC DHCP server lacks
+// the equivalents of some Kea parameters, e.g., the client classes and
+// some identifier types. Such parameters are written as comments.
+func writeDhcpd(writer io.Writer, daemons []*daemonReservations, lookup keaconfig.DHCPOptionDefinitionLookup) error {
+	buffered := bufio.NewWriter(writer)
+	for i, d := range daemons {
+		if i > 0 {
+			fmt.Fprintln(buffered)
+		}
+		fmt.Fprintf(buffered, "# Host reservations of the %s daemon %d", d.daemon.Name, d.daemon.ID)
+		if appName := getAppName(d.daemon); appName != "" {
+			fmt.Fprintf(buffered, " in app %s", appName)
+		}
+		fmt.Fprintln(buffered, ".")
+		for _, dr := range d.reservations {
+			if err := writeDhcpdHost(buffered, d.daemon, dr, lookup); err != nil {
+				return err
+			}
+		}
+	}
+	return pkgerrors.Wrap(buffered.Flush(), "problem writing host reservations in ISC DHCP format")
+}
+
+// Writes a single host declaration.
+func writeDhcpdHost(writer io.Writer, daemon *dbmodel.Daemon, dr *daemonReservation, lookup keaconfig.DHCPOptionDefinitionLookup) error {
+	isDHCPv6 := daemon.Name == dbmodel.DaemonNameDHCPv6
+	reservation := dr.reservation
+	lines := []string{}
+
+	if dr.subnetPrefix != "" {
+		lines = append(lines, fmt.Sprintf("# subnet %s", dr.subnetPrefix))
+	}
+	identifierTypes, identifiers := getIdentifiers(reservation)
+	for i := range identifierTypes {
+		// The ISC DHCP server expects the colon-separated hexadecimal
+		// identifiers.
+		if formatted, ok := storkutil.FormatMACAddress(identifiers[i]); ok {
+			identifiers[i] = formatted
+		}
+		switch {
+		case identifierTypes[i] == "hw-address":
+			lines = append(lines, fmt.Sprintf("hardware ethernet %s;", identifiers[i]))
+		case identifierTypes[i] == "duid" && isDHCPv6:
+			lines = append(lines, fmt.Sprintf("host-identifier option dhcp6.client-id %s;", identifiers[i]))
+		case identifierTypes[i] == "client-id" && !isDHCPv6:
+			lines = append(lines, fmt.Sprintf("option dhcp-client-identifier %s;", identifiers[i]))
+		default:
+			lines = append(lines, fmt.Sprintf("# unsupported identifier %s %s", identifierTypes[i], identifiers[i]))
+		}
+	}
+	if reservation.IPAddress != "" {
+		lines = append(lines, fmt.Sprintf("fixed-address %s;", reservation.IPAddress))
+	}
+	for _, address := range reservation.IPAddresses {
+		lines = append(lines, fmt.Sprintf("fixed-address6 %s;", address))
+	}
+	for _, prefix := range reservation.Prefixes {
+		lines = append(lines, fmt.Sprintf("fixed-prefix6 %s;", prefix))
+	}
+	if reservation.Hostname != "" {
+		if isDHCPv6 {
+			lines = append(lines, fmt.Sprintf("ddns-hostname %s;", strconv.Quote(reservation.Hostname)))
+		} else {
+			lines = append(lines, fmt.Sprintf("option host-name %s;", strconv.Quote(reservation.Hostname)))
+		}
+	}
+	if reservation.NextServer != "" {
+		lines = append(lines, fmt.Sprintf("next-server %s;", reservation.NextServer))
+	}
+	if reservation.ServerHostname != "" {
+		lines = append(lines, fmt.Sprintf("server-name %s;", strconv.Quote(reservation.ServerHostname)))
+	}
+	if reservation.BootFileName != "" {
+		lines = append(lines, fmt.Sprintf("filename %s;", strconv.Quote(reservation.BootFileName)))
+	}
+	for _, option := range dr.host.GetDHCPOptions(daemon.ID) {
+		line, err := formatDhcpdOption(daemon, option, lookup)
+		if err != nil {
+			return pkgerrors.WithMessagef(err, "problem exporting option %d of host %d", option.GetCode(), dr.host.ID)
+		}
+		lines = append(lines, line)
+	}
+	if len(reservation.ClientClasses) > 0 {
+		lines = append(lines, fmt.Sprintf("# client-classes %s", strings.Join(reservation.ClientClasses, ", ")))
+	}
+
+	fmt.Fprintf(writer, "host host-%d {\n", dr.host.ID)
+	for _, line := range lines {
+		fmt.Fprintf(writer, "  %s\n", line)
+	}
+	fmt.Fprintln(writer, "}")
+	return nil
+}
+
+// Formats an option statement. The option name is taken from the Kea
+// option definition. The options without the definitions, with the binary
+// data or belonging to the encapsulated option spaces are written as
+// comments.
+func formatDhcpdOption(daemon *dbmodel.Daemon, option dhcpmodel.DHCPOptionAccessor, lookup keaconfig.DHCPOptionDefinitionLookup) (string, error) {
+	optionData, err := keaconfig.CreateSingleOptionData(daemon.ID, lookup, option)
+	if err != nil {
+		return "", err
+	}
+	definition := lookup.Find(daemon.ID, option)
+	if definition == nil || !optionData.CSVFormat || optionData.Space != daemon.Name {
+		return fmt.Sprintf("# unsupported option %s %d %s", optionData.Space, optionData.Code, optionData.Data), nil
+	}
+	name := definition.GetName()
+	if daemon.Name == dbmodel.DaemonNameDHCPv6 {
+		name = "dhcp6." + name
+	}
+	data := optionData.Data
+	switch definition.GetType() {
+	case keaconfig.StringOption, keaconfig.FqdnOption:
+		if !definition.GetArray() {
+			data = strconv.Quote(data)
+		}
+	case keaconfig.EmptyOption:
+		data = ""
+	}
+	if data == "" {
+		return fmt.Sprintf("option %s;", name), nil
+	}
+	return fmt.Sprintf("option %s %s;", name, data), nil
+}
@@ -0,0 +1,91 @@
+package hostsexport
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Test writing the host reservations in the ISC DHCP format.
+func TestWriteDhcpd(t *testing.T) {
+	var buffer bytes.Buffer
+	err := writeDhcpd(&buffer, createTestDaemonReservations(t), dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+
+	require.Equal(t, `# Host reservations of the dhcp4 daemon 1 in app kea@192.0.2.1.
+host host-1 {
+  # subnet 192.0.2.0/24
+  hardware ethernet 01:02:03:04:05:06;
+  fixed-address 192.0.2.10;
+  option host-name "first.example.org";
+  next-server 192.0.2.1;
+  server-name "boot.example.org";
+  filename "/tmp/boot";
+  option routers 192.0.2.1;
+  option domain-name "example.org";
+  # client-classes foo, bar
+}
+host host-2 {
+  # unsupported identifier circuit-id 01:02
+  fixed-address 10.0.0.1;
+}
+
+# Host reservations of the dhcp6 daemon 2.
+host host-3 {
+  # subnet 2001:db8:1::/64
+  host-identifier option dhcp6.client-id 01:02:03:04;
+  fixed-address6 2001:db8:1::10;
+  fixed-prefix6 3000:1::/64;
+  ddns-hostname "second.example.org";
+}
+`, buffer.String())
+}
+
+// Test formatting the options in the ISC DHCP format.
+func TestFormatDhcpdOption(t *testing.T) {
+	lookup := dbmodel.NewDHCPOptionDefinitionLookup()
+	daemon4 := &dbmodel.Daemon{ID: 1, Name: dbmodel.DaemonNameDHCPv4}
+	daemon6 := &dbmodel.Daemon{ID: 2, Name: dbmodel.DaemonNameDHCPv6}
+
+	t.Run("IP address list", func(t *testing.T) {
+		line, err := formatDhcpdOption(daemon4, dbmodel.DHCPOption{
+			Code:     6,
+			Space:    "dhcp4",
+			Universe: 4,
+			Fields: []dbmodel.DHCPOptionField{
+				{FieldType: "ipv4-address", Values: []any{"192.0.2.1"}},
+				{FieldType: "ipv4-address", Values: []any{"192.0.2.2"}},
+			},
+		}, lookup)
+		require.NoError(t, err)
+		require.Equal(t, "option domain-name-servers 192.0.2.1,192.0.2.2;", line)
+	})
+
+	t.Run("DHCPv6 option", func(t *testing.T) {
+		line, err := formatDhcpdOption(daemon6, dbmodel.DHCPOption{
+			Code:     23,
+			Space:    "dhcp6",
+			Universe: 6,
+			Fields: []dbmodel.DHCPOptionField{
+				{FieldType: "ipv6-address", Values: []any{"2001:db8:1::1"}},
+			},
+		}, lookup)
+		require.NoError(t, err)
+		require.Equal(t, "option dhcp6.dns-servers 2001:db8:1::1;", line)
+	})
+
+	t.Run("unknown option", func(t *testing.T) {
+		line, err := formatDhcpdOption(daemon4, dbmodel.DHCPOption{
+			Code:     250,
+			Space:    "dhcp4",
+			Universe: 4,
+			Fields: []dbmodel.DHCPOptionField{
+				{FieldType: "string", Values: []any{"foo"}},
+			},
+		}, lookup)
+		require.NoError(t, err)
+		require.Equal(t, "# unsupported option dhcp4 250 666F6F", line)
+	})
+}
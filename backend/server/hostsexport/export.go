@@ -0,0 +1,212 @@
+package hostsexport
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	keaconfig "isc.org/stork/appcfg/kea"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Formats of the exported host reservations.
+const (
+	// Kea host reservations grouped by daemons and subnets.
+	FormatKea = "kea"
+	// One line per host reservation and daemon.
+	FormatCSV = "csv"
+	// ISC DHCP server host declarations grouped by daemons.
+	FormatDhcpd = "dhcpd"
+)
+
+// Number of the hosts fetched from the database at once.
+const pageSize = 1000
+
+// Host reservation data specific to a daemon. The Kea reservation is
+// created from the LocalHost data so it includes the boot fields, options
+// and client classes configured in this daemon.
+type daemonReservation struct {
+	host        *dbmodel.Host
+	reservation *keaconfig.Reservation
+	// Subnet ID in the Kea configuration. It is 0 for the global
+	// reservations.
+	localSubnetID int64
+	// Subnet prefix. It is empty for the global reservations.
+	subnetPrefix string
+}
+
+// Host reservations configured in a daemon.
+type daemonReservations struct {
+	daemon       *dbmodel.Daemon
+	reservations []*daemonReservation
+}
+
+// Fetches host reservations from the database and writes them in the
+// selected format.
+type Exporter struct {
+	db     *pg.DB
+	lookup keaconfig.DHCPOptionDefinitionLookup
+	// Subnets fetched from the database by ID.
+	subnets map[int64]*dbmodel.Subnet
+}
+
+// Creates new exporter instance.
+func NewExporter(db *pg.DB, lookup keaconfig.DHCPOptionDefinitionLookup) *Exporter {
+	return &Exporter{
+		db:      db,
+		lookup:  lookup,
+		subnets: make(map[int64]*dbmodel.Subnet),
+	}
+}
+
+// Checks if the export format is supported.
+func IsFormatSupported(format string) bool {
+	switch format {
+	case FormatKea, FormatCSV, FormatDhcpd:
+		return true
+	default:
+		return false
+	}
+}
+
+// Exports the host reservations matching the filters in the specified
+// format. The filters are the same as in the hosts list. When filtering
+// by app, only the data of the daemons belonging to this app are exported.
+func (exporter *Exporter) Export(writer io.Writer, format string, filters dbmodel.HostsByPageFilters) error {
+	if !IsFormatSupported(format) {
+		return pkgerrors.Errorf("unsupported hosts export format %s", format)
+	}
+	daemons, err := exporter.collect(filters)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case FormatKea:
+		return writeKea(writer, daemons)
+	case FormatCSV:
+		return writeCSV(writer, daemons)
+	default:
+		return writeDhcpd(writer, daemons, exporter.lookup)
+	}
+}
+
+// Fetches the host reservations matching the filters and groups them
+// by daemons. The daemons are sorted by ID. The reservations within
+// a daemon are sorted by host ID.
+func (exporter *Exporter) collect(filters dbmodel.HostsByPageFilters) ([]*daemonReservations, error) {
+	var hosts []dbmodel.Host
+	for offset := int64(0); ; offset += pageSize {
+		page, total, err := dbmodel.GetHostsByPage(exporter.db, offset, pageSize, filters, "", dbmodel.SortDirAny)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, page...)
+		if len(page) < pageSize || int64(len(hosts)) >= total {
+			break
+		}
+	}
+	var appID int64
+	if filters.AppID != nil {
+		appID = *filters.AppID
+	}
+	daemons := make(map[int64]*daemonReservations)
+	for i := range hosts {
+		host := &hosts[i]
+		if host.SubnetID != 0 {
+			// The hosts list lacks the local subnets holding the subnet
+			// IDs used in the Kea configuration.
+			subnet, err := exporter.getSubnet(host.SubnetID)
+			if err != nil {
+				return nil, err
+			}
+			host.Subnet = subnet
+		}
+		for j := range host.LocalHosts {
+			lh := &host.LocalHosts[j]
+			if lh.Daemon == nil || (appID != 0 && lh.Daemon.AppID != appID) {
+				continue
+			}
+			reservation, err := keaconfig.CreateReservation(lh.DaemonID, exporter.lookup, host)
+			if err != nil {
+				return nil, pkgerrors.WithMessagef(err, "problem exporting host %d", host.ID)
+			}
+			localSubnetID, err := host.GetSubnetID(lh.DaemonID)
+			if err != nil {
+				return nil, err
+			}
+			dr := &daemonReservation{
+				host:          host,
+				reservation:   reservation,
+				localSubnetID: localSubnetID,
+			}
+			if host.Subnet != nil {
+				dr.subnetPrefix = host.Subnet.Prefix
+			}
+			if _, ok := daemons[lh.DaemonID]; !ok {
+				daemons[lh.DaemonID] = &daemonReservations{
+					daemon: lh.Daemon,
+				}
+			}
+			daemons[lh.DaemonID].reservations = append(daemons[lh.DaemonID].reservations, dr)
+		}
+	}
+	var sorted []*daemonReservations
+	for _, d := range daemons {
+		sort.Slice(d.reservations, func(i, j int) bool {
+			return d.reservations[i].host.ID < d.reservations[j].host.ID
+		})
+		sorted = append(sorted, d)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].daemon.ID < sorted[j].daemon.ID
+	})
+	return sorted, nil
+}
+
+// Returns the subnet with the specified ID. The subnets are cached
+// because many reservations typically belong to the same subnet.
+func (exporter *Exporter) getSubnet(subnetID int64) (*dbmodel.Subnet, error) {
+	if subnet, ok := exporter.subnets[subnetID]; ok {
+		return subnet, nil
+	}
+	subnet, err := dbmodel.GetSubnet(exporter.db, subnetID)
+	if err != nil {
+		return nil, err
+	}
+	if subnet == nil {
+		return nil, pkgerrors.Errorf("subnet %d not found", subnetID)
+	}
+	exporter.subnets[subnetID] = subnet
+	return subnet, nil
+}
+
+// Returns the name of the app the daemon belongs to or an empty string
+// if the app is unknown.
+func getAppName(daemon *dbmodel.Daemon) string {
+	if daemon.App != nil {
+		return daemon.App.Name
+	}
+	return ""
+}
+
+// Returns the types and the values of the identifiers in the reservation.
+func getIdentifiers(reservation *keaconfig.Reservation) (types, values []string) {
+	for _, identifier := range []struct {
+		name  string
+		value string
+	}{
+		{"hw-address", reservation.HWAddress},
+		{"duid", reservation.DUID},
+		{"circuit-id", reservation.CircuitID},
+		{"client-id", reservation.ClientID},
+		{"flex-id", reservation.FlexID},
+	} {
+		if value := strings.TrimSpace(identifier.value); value != "" {
+			types = append(types, identifier.name)
+			values = append(values, value)
+		}
+	}
+	return
+}
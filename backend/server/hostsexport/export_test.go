@@ -0,0 +1,159 @@
+package hostsexport
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktestdbmodel "isc.org/stork/server/test/dbmodel"
+)
+
+// Creates the host reservations of two daemons used in the tests of
+// the export formats. The DHCPv4 daemon has a global and an in-subnet
+// reservation. The DHCPv6 daemon has an in-subnet reservation.
+func createTestDaemonReservations(t *testing.T) []*daemonReservations {
+	lookup := dbmodel.NewDHCPOptionDefinitionLookup()
+	daemon4 := &dbmodel.Daemon{
+		ID:   1,
+		Name: dbmodel.DaemonNameDHCPv4,
+		App: &dbmodel.App{
+			Name: "kea@192.0.2.1",
+		},
+	}
+	daemon6 := &dbmodel.Daemon{
+		ID:   2,
+		Name: dbmodel.DaemonNameDHCPv6,
+	}
+
+	newReservation := func(daemon *dbmodel.Daemon, hostID int64, reservation keaconfig.Reservation, localSubnetID int64, prefix string) *daemonReservation {
+		host, err := dbmodel.NewHostFromKeaConfigReservation(reservation, daemon, dbmodel.HostDataSourceConfig, lookup)
+		require.NoError(t, err)
+		host.ID = hostID
+		kea, err := keaconfig.CreateReservation(daemon.ID, lookup, host)
+		require.NoError(t, err)
+		return &daemonReservation{
+			host:          host,
+			reservation:   kea,
+			localSubnetID: localSubnetID,
+			subnetPrefix:  prefix,
+		}
+	}
+
+	return []*daemonReservations{
+		{
+			daemon: daemon4,
+			reservations: []*daemonReservation{
+				newReservation(daemon4, 1, keaconfig.Reservation{
+					HWAddress:      "01:02:03:04:05:06",
+					IPAddress:      "192.0.2.10",
+					Hostname:       "first.example.org",
+					ClientClasses:  []string{"foo", "bar"},
+					NextServer:     "192.0.2.1",
+					ServerHostname: "boot.example.org",
+					BootFileName:   "/tmp/boot",
+					OptionData: []keaconfig.SingleOptionData{
+						{Code: 3, CSVFormat: true, Data: "192.0.2.1", Space: "dhcp4"},
+						{Code: 15, CSVFormat: true, Data: "example.org", Space: "dhcp4"},
+					},
+				}, 111, "192.0.2.0/24"),
+				newReservation(daemon4, 2, keaconfig.Reservation{
+					CircuitID: "01:02",
+					IPAddress: "10.0.0.1",
+				}, 0, ""),
+			},
+		},
+		{
+			daemon: daemon6,
+			reservations: []*daemonReservation{
+				newReservation(daemon6, 3, keaconfig.Reservation{
+					DUID:        "01:02:03:04",
+					IPAddresses: []string{"2001:db8:1::10"},
+					Prefixes:    []string{"3000:1::/64"},
+					Hostname:    "second.example.org",
+				}, 222, "2001:db8:1::/64"),
+			},
+		},
+	}
+}
+
+// Test checking the supported export formats.
+func TestIsFormatSupported(t *testing.T) {
+	require.True(t, IsFormatSupported(FormatKea))
+	require.True(t, IsFormatSupported(FormatCSV))
+	require.True(t, IsFormatSupported(FormatDhcpd))
+	require.False(t, IsFormatSupported("xml"))
+}
+
+// Test exporting the host reservations from the database.
+func TestExport(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	hosts, apps := storktestdbmodel.AddTestHosts(t, db)
+	exporter := NewExporter(db, dbmodel.NewDHCPOptionDefinitionLookup())
+
+	t.Run("kea", func(t *testing.T) {
+		var buffer bytes.Buffer
+		err := exporter.Export(&buffer, FormatKea, dbmodel.HostsByPageFilters{})
+		require.NoError(t, err)
+
+		var output []keaDaemonReservations
+		err = json.Unmarshal(buffer.Bytes(), &output)
+		require.NoError(t, err)
+
+		// Two apps with two daemons each.
+		require.Len(t, output, 4)
+		daemon := output[0]
+		require.Equal(t, apps[0].Daemons[0].ID, daemon.DaemonID)
+		require.Equal(t, dbmodel.DaemonNameDHCPv4, daemon.DaemonName)
+		require.Equal(t, apps[0].Name, daemon.AppName)
+		require.Len(t, daemon.Reservations, 1)
+		require.Equal(t, "020304050607", daemon.Reservations[0].HWAddress)
+		require.Len(t, daemon.Subnet4, 1)
+		require.EqualValues(t, 111, daemon.Subnet4[0].ID)
+		require.Equal(t, "192.0.2.0/24", daemon.Subnet4[0].Subnet)
+		require.Len(t, daemon.Subnet4[0].Reservations, 1)
+		reservation := daemon.Subnet4[0].Reservations[0]
+		require.Equal(t, "first.example.org", reservation.Hostname)
+		require.Equal(t, "192.2.2.2", reservation.NextServer)
+		require.Equal(t, "stork.example.org", reservation.ServerHostname)
+		require.Equal(t, "/tmp/boot.xyz", reservation.BootFileName)
+		require.Empty(t, daemon.Subnet6)
+	})
+
+	t.Run("filter by subnet", func(t *testing.T) {
+		var buffer bytes.Buffer
+		err := exporter.Export(&buffer, FormatCSV, dbmodel.HostsByPageFilters{
+			SubnetID: &hosts[0].SubnetID,
+		})
+		require.NoError(t, err)
+
+		// Header and the first host in both DHCPv4 servers.
+		lines := bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n"))
+		require.Len(t, lines, 3)
+		require.Contains(t, string(lines[1]), "first.example.org")
+		require.Contains(t, string(lines[2]), "first.example.org")
+	})
+
+	t.Run("filter by app", func(t *testing.T) {
+		var buffer bytes.Buffer
+		err := exporter.Export(&buffer, FormatDhcpd, dbmodel.HostsByPageFilters{
+			AppID: &apps[1].ID,
+		})
+		require.NoError(t, err)
+
+		// Only the daemons of the selected app are exported.
+		require.NotContains(t, buffer.String(), apps[0].Name)
+		require.Contains(t, buffer.String(), apps[1].Name)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		var buffer bytes.Buffer
+		err := exporter.Export(&buffer, "xml", dbmodel.HostsByPageFilters{})
+		require.ErrorContains(t, err, "unsupported hosts export format xml")
+	})
+}
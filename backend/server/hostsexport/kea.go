@@ -0,0 +1,75 @@
+package hostsexport
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	pkgerrors "github.com/pkg/errors"
+	keaconfig "isc.org/stork/appcfg/kea"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Host reservations of a daemon in the Kea format. The global reservations
+// and the subnets holding the in-subnet reservations are laid out like in
+// the Kea configuration, so they can be copied into it.
+type keaDaemonReservations struct {
+	DaemonID     int64                   `json:"daemon-id"`
+	DaemonName   string                  `json:"daemon-name"`
+	AppName      string                  `json:"app-name,omitempty"`
+	Reservations []keaconfig.Reservation `json:"reservations,omitempty"`
+	Subnet4      []*keaSubnet            `json:"subnet4,omitempty"`
+	Subnet6      []*keaSubnet            `json:"subnet6,omitempty"`
+}
+
+// Subnet holding the host reservations in the Kea format.
+type keaSubnet struct {
+	ID           int64                   `json:"id"`
+	Subnet       string                  `json:"subnet"`
+	Reservations []keaconfig.Reservation `json:"reservations"`
+}
+
+// Writes the host reservations as a JSON list of the daemons holding
+// the reservations in the Kea format.
+func writeKea(writer io.Writer, daemons []*daemonReservations) error {
+	output := []*keaDaemonReservations{}
+	for _, d := range daemons {
+		daemonOutput := &keaDaemonReservations{
+			DaemonID:   d.daemon.ID,
+			DaemonName: d.daemon.Name,
+			AppName:    getAppName(d.daemon),
+		}
+		subnets := make(map[int64]*keaSubnet)
+		for _, dr := range d.reservations {
+			if dr.localSubnetID == 0 && dr.subnetPrefix == "" {
+				daemonOutput.Reservations = append(daemonOutput.Reservations, *dr.reservation)
+				continue
+			}
+			subnet, ok := subnets[dr.localSubnetID]
+			if !ok {
+				subnet = &keaSubnet{
+					ID:     dr.localSubnetID,
+					Subnet: dr.subnetPrefix,
+				}
+				subnets[dr.localSubnetID] = subnet
+			}
+			subnet.Reservations = append(subnet.Reservations, *dr.reservation)
+		}
+		var sortedSubnets []*keaSubnet
+		for _, subnet := range subnets {
+			sortedSubnets = append(sortedSubnets, subnet)
+		}
+		sort.Slice(sortedSubnets, func(i, j int) bool {
+			return sortedSubnets[i].ID < sortedSubnets[j].ID
+		})
+		if d.daemon.Name == dbmodel.DaemonNameDHCPv6 {
+			daemonOutput.Subnet6 = sortedSubnets
+		} else {
+			daemonOutput.Subnet4 = sortedSubnets
+		}
+		output = append(output, daemonOutput)
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return pkgerrors.Wrap(encoder.Encode(output), "problem writing host reservations in Kea format")
+}
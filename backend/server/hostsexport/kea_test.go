@@ -0,0 +1,86 @@
+package hostsexport
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test writing the host reservations in the Kea format.
+func TestWriteKea(t *testing.T) {
+	var buffer bytes.Buffer
+	err := writeKea(&buffer, createTestDaemonReservations(t))
+	require.NoError(t, err)
+
+	require.JSONEq(t, `[
+		{
+			"daemon-id": 1,
+			"daemon-name": "dhcp4",
+			"app-name": "kea@192.0.2.1",
+			"reservations": [
+				{
+					"circuit-id": "0102",
+					"ip-address": "10.0.0.1"
+				}
+			],
+			"subnet4": [
+				{
+					"id": 111,
+					"subnet": "192.0.2.0/24",
+					"reservations": [
+						{
+							"hw-address": "010203040506",
+							"ip-address": "192.0.2.10",
+							"hostname": "first.example.org",
+							"client-classes": [ "foo", "bar" ],
+							"next-server": "192.0.2.1",
+							"server-hostname": "boot.example.org",
+							"boot-file-name": "/tmp/boot",
+							"option-data": [
+								{
+									"code": 3,
+									"csv-format": true,
+									"data": "192.0.2.1",
+									"space": "dhcp4"
+								},
+								{
+									"code": 15,
+									"csv-format": true,
+									"data": "example.org",
+									"space": "dhcp4"
+								}
+							]
+						}
+					]
+				}
+			]
+		},
+		{
+			"daemon-id": 2,
+			"daemon-name": "dhcp6",
+			"subnet6": [
+				{
+					"id": 222,
+					"subnet": "2001:db8:1::/64",
+					"reservations": [
+						{
+							"duid": "01020304",
+							"ip-addresses": [ "2001:db8:1::10" ],
+							"prefixes": [ "3000:1::/64" ],
+							"hostname": "second.example.org"
+						}
+					]
+				}
+			]
+		}
+	]`, buffer.String())
+}
+
+// Test that an empty list is written when there are no reservations.
+func TestWriteKeaEmpty(t *testing.T) {
+	var buffer bytes.Buffer
+	err := writeKea(&buffer, nil)
+	require.NoError(t, err)
+	require.JSONEq(t, "[]", buffer.String())
+}
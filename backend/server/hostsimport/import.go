@@ -0,0 +1,454 @@
+package hostsimport
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	pkgerrors "github.com/pkg/errors"
+	keaconfig "isc.org/stork/appcfg/kea"
+	"isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+	storkutil "isc.org/stork/util"
+)
+
+// Default number of the host reservations committed in a single
+// configuration transaction.
+const DefaultBatchSize = 100
+
+// Hosts import options.
+type Options struct {
+	// IDs of the daemons to which the reservations are imported. The
+	// subnet-level reservations are imported to all daemons serving the
+	// subnet when this list is empty. The global reservations require
+	// this list to be specified.
+	DaemonIDs []int64
+	// Indicates that the rows should be validated but not imported.
+	DryRun bool
+	// Number of the host reservations committed in a single configuration
+	// transaction. The DefaultBatchSize is used when it is not positive.
+	BatchSize int
+}
+
+// Describes a problem with importing a single row.
+type RowError struct {
+	// Row number as returned by the parser.
+	Row int
+	// Problem description.
+	Message string
+}
+
+// Hosts import summary.
+type Result struct {
+	// Total number of the parsed rows.
+	Total int
+	// Number of the rows that passed the validation.
+	Valid int
+	// Number of the host reservations successfully imported.
+	Imported int
+	// Errors found in the individual rows.
+	Errors []RowError
+}
+
+// Validates the parsed host reservations and imports them into the Kea
+// servers using the config manager.
+type Importer struct {
+	db      *pg.DB
+	manager config.Manager
+	lookup  keaconfig.DHCPOptionDefinitionLookup
+	// Subnets fetched from the database by prefix.
+	subnets map[string]*dbmodel.Subnet
+	// Daemons fetched from the database by ID.
+	daemons map[int64]*dbmodel.Daemon
+}
+
+// A host reservation that passed the validation along with the row
+// it was created from.
+type validatedHost struct {
+	row  *Row
+	host *dbmodel.Host
+}
+
+// Creates new importer instance.
+func NewImporter(db *pg.DB, manager config.Manager, lookup keaconfig.DHCPOptionDefinitionLookup) *Importer {
+	return &Importer{
+		db:      db,
+		manager: manager,
+		lookup:  lookup,
+		subnets: make(map[string]*dbmodel.Subnet),
+		daemons: make(map[int64]*dbmodel.Daemon),
+	}
+}
+
+// Validates the rows and imports the valid ones into the Kea servers in
+// batches. The rows with errors are skipped and their errors are returned
+// in the result. The rows belonging to a batch that failed to commit are
+// marked with the commit error. The returned error indicates a problem
+// that prevented the import as a whole, e.g., a database failure.
+func (importer *Importer) Import(userID int64, rows []*Row, options Options) (*Result, error) {
+	result := &Result{
+		Total: len(rows),
+	}
+	var hosts []*validatedHost
+	// The keys of the identifiers found so far mapped to the row numbers.
+	identifiers := make(map[string]int)
+	for _, row := range rows {
+		if row.Err != nil {
+			result.addError(row, row.Err)
+			continue
+		}
+		host, err := importer.validate(row, options, identifiers)
+		if err != nil {
+			if isDatabaseError(err) {
+				return nil, err
+			}
+			result.addError(row, err)
+			continue
+		}
+		hosts = append(hosts, &validatedHost{row: row, host: host})
+	}
+	result.Valid = len(hosts)
+	if options.DryRun {
+		return result, nil
+	}
+
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	for start := 0; start < len(hosts); start += batchSize {
+		end := start + batchSize
+		if end > len(hosts) {
+			end = len(hosts)
+		}
+		batch := hosts[start:end]
+		if err := importer.commit(userID, batch); err != nil {
+			for _, vh := range batch {
+				result.addError(vh.row, err)
+			}
+			continue
+		}
+		result.Imported += len(batch)
+	}
+	return result, nil
+}
+
+// Commits a batch of the host reservations in a single configuration
+// transaction. The Kea module deletes the reservations it has already
+// added when adding any other reservation from the batch fails, so the
+// returned error applies to all reservations in the batch.
+func (importer *Importer) commit(userID int64, batch []*validatedHost) error {
+	ctx, err := importer.manager.CreateContext(userID)
+	if err != nil {
+		return err
+	}
+	// The context is replaced when the daemons are locked. Done must use
+	// the latest context to release the locks.
+	defer func() { importer.manager.Done(ctx) }()
+
+	var (
+		hosts     []*dbmodel.Host
+		daemonIDs []int64
+	)
+	for _, vh := range batch {
+		hosts = append(hosts, vh.host)
+		for _, lh := range vh.host.LocalHosts {
+			if !containsID(daemonIDs, lh.DaemonID) {
+				daemonIDs = append(daemonIDs, lh.DaemonID)
+			}
+		}
+	}
+	if ctx, err = importer.manager.Lock(ctx, daemonIDs...); err != nil {
+		return pkgerrors.WithMessage(err, "unable to lock daemons for configuration update")
+	}
+	module := importer.manager.GetKeaModule()
+	if ctx, err = module.BeginHostsImport(ctx); err != nil {
+		return err
+	}
+	if ctx, err = module.ApplyHostsImport(ctx, hosts); err != nil {
+		return err
+	}
+	_, err = importer.manager.Commit(ctx)
+	return err
+}
+
+// Validates a single row and converts it to the host reservation. The
+// identifiers map holds the identifiers found in the previous rows and
+// it is updated with the identifier from the current row.
+func (importer *Importer) validate(row *Row, options Options, identifiers map[string]int) (*dbmodel.Host, error) {
+	reservation := row.Reservation
+	identifierCount := 0
+	for _, identifier := range []string{reservation.HWAddress, reservation.DUID, reservation.CircuitID, reservation.ClientID, reservation.FlexID} {
+		if strings.TrimSpace(identifier) != "" {
+			identifierCount++
+		}
+	}
+	switch {
+	case identifierCount == 0:
+		return nil, pkgerrors.New("missing host identifier")
+	case identifierCount > 1:
+		return nil, pkgerrors.New("multiple host identifiers specified")
+	}
+
+	var (
+		subnet *dbmodel.Subnet
+		err    error
+	)
+	if row.Subnet != "" {
+		subnet, err = importer.getSubnet(row.Subnet)
+		if err != nil {
+			return nil, err
+		}
+		if err = validateSubnetReservations(subnet, &reservation); err != nil {
+			return nil, err
+		}
+	} else if len(reservation.Prefixes) > 0 {
+		// Prefixes can only be validated against the prefix pools.
+		return nil, pkgerrors.New("delegated prefixes can only be reserved in a subnet")
+	}
+	daemons, err := importer.getTargetDaemons(subnet, &reservation, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var host *dbmodel.Host
+	for _, daemon := range daemons {
+		daemonReservation := reservation
+		daemonReservation.OptionData = nil
+		for _, option := range reservation.OptionData {
+			if option.Space == "" {
+				option.Space = daemon.Name
+			}
+			daemonReservation.OptionData = append(daemonReservation.OptionData, option)
+		}
+		daemonHost, err := dbmodel.NewHostFromKeaConfigReservation(daemonReservation, daemon, dbmodel.HostDataSourceAPI, importer.lookup)
+		if err != nil {
+			return nil, pkgerrors.WithMessage(err, "invalid reservation")
+		}
+		daemonHost.LocalHosts[0].Daemon = daemon
+		if host == nil {
+			host = daemonHost
+			continue
+		}
+		host.LocalHosts = append(host.LocalHosts, daemonHost.LocalHosts...)
+	}
+	if subnet != nil {
+		host.SubnetID = subnet.ID
+		host.Subnet = subnet
+	}
+
+	// Check the identifier uniqueness in the file and in the database.
+	identifier := host.HostIdentifiers[0]
+	key := fmt.Sprintf("%s:%s:%d", identifier.Type, hex.EncodeToString(identifier.Value), host.SubnetID)
+	if previous, ok := identifiers[key]; ok {
+		return nil, pkgerrors.Errorf("duplicated %s identifier; it was already specified in row %d", identifier.Type, previous)
+	}
+	identifiers[key] = row.Number
+	existing, err := dbmodel.GetHostsByIdentifier(importer.db, identifier.Type, identifier.Value, host.SubnetID)
+	if err != nil {
+		return nil, newDatabaseError(err)
+	}
+	if len(existing) > 0 {
+		if subnet != nil {
+			return nil, pkgerrors.Errorf("host reservation with this %s identifier already exists in subnet %s", identifier.Type, subnet.Prefix)
+		}
+		return nil, pkgerrors.Errorf("global host reservation with this %s identifier already exists", identifier.Type)
+	}
+	return host, nil
+}
+
+// Checks that the reserved addresses and prefixes belong to the subnet.
+// The reserved addresses must be outside the address pools when the subnet
+// is explicitly configured to allow only the out-of-pool reservations.
+// The reserved prefixes must belong to the prefix pools.
+func validateSubnetReservations(subnet *dbmodel.Subnet, reservation *keaconfig.Reservation) error {
+	parsedSubnet := storkutil.ParseIP(subnet.Prefix)
+	if parsedSubnet == nil {
+		return pkgerrors.Errorf("invalid subnet prefix %s", subnet.Prefix)
+	}
+	var addresses []string
+	if reservation.IPAddress != "" {
+		addresses = append(addresses, reservation.IPAddress)
+	}
+	addresses = append(addresses, reservation.IPAddresses...)
+	for _, address := range addresses {
+		parsed := storkutil.ParseIP(address)
+		if parsed == nil || parsed.Prefix {
+			return pkgerrors.Errorf("invalid IP address %s", address)
+		}
+		if !parsedSubnet.IPNet.Contains(parsed.IP) {
+			return pkgerrors.Errorf("IP address %s does not belong to subnet %s", address, subnet.Prefix)
+		}
+		for _, ls := range subnet.LocalSubnets {
+			if ls.KeaParameters == nil {
+				continue
+			}
+			if outOfPool, explicit := ls.KeaParameters.IsOutOfPool(); !outOfPool || !explicit {
+				continue
+			}
+			for _, pool := range ls.AddressPools {
+				lb, ub, err := storkutil.ParseIPRange(fmt.Sprintf("%s-%s", pool.LowerBound, pool.UpperBound))
+				if err != nil {
+					continue
+				}
+				if parsed.IsInRange(lb, ub) {
+					return pkgerrors.Errorf("IP address %s is in pool %s-%s but subnet %s allows only out-of-pool reservations",
+						address, pool.LowerBound, pool.UpperBound, subnet.Prefix)
+				}
+			}
+		}
+	}
+	for _, prefix := range reservation.Prefixes {
+		parsed := storkutil.ParseIP(prefix)
+		if parsed == nil || !parsed.Prefix {
+			return pkgerrors.Errorf("invalid delegated prefix %s", prefix)
+		}
+		if !isInPrefixPool(subnet, parsed) {
+			return pkgerrors.Errorf("delegated prefix %s does not belong to any prefix pool in subnet %s", prefix, subnet.Prefix)
+		}
+	}
+	return nil
+}
+
+// Checks if the delegated prefix belongs to any of the subnet's prefix pools.
+func isInPrefixPool(subnet *dbmodel.Subnet, parsed *storkutil.ParsedIP) bool {
+	for _, ls := range subnet.LocalSubnets {
+		for _, pool := range ls.PrefixPools {
+			parsedPool := storkutil.ParseIP(pool.Prefix)
+			if parsedPool == nil {
+				continue
+			}
+			if parsed.IsInPrefixRange(parsedPool.NetworkPrefix, parsedPool.PrefixLength, pool.DelegatedLen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the daemons to which the reservation should be imported. The
+// subnet-level reservations are imported to the daemons serving the subnet,
+// optionally narrowed to the daemons specified in the options. The global
+// reservations are imported to the daemons specified in the options. The
+// daemons must use the host_cmds hook library.
+func (importer *Importer) getTargetDaemons(subnet *dbmodel.Subnet, reservation *keaconfig.Reservation, options Options) ([]*dbmodel.Daemon, error) {
+	var daemonIDs []int64
+	if subnet != nil {
+		for _, ls := range subnet.LocalSubnets {
+			if len(options.DaemonIDs) == 0 || containsID(options.DaemonIDs, ls.DaemonID) {
+				daemonIDs = append(daemonIDs, ls.DaemonID)
+			}
+		}
+		if len(daemonIDs) == 0 {
+			return nil, pkgerrors.Errorf("subnet %s is not served by any of the selected daemons", subnet.Prefix)
+		}
+	} else {
+		if len(options.DaemonIDs) == 0 {
+			return nil, pkgerrors.New("daemons must be selected to import a global reservation")
+		}
+		daemonIDs = options.DaemonIDs
+	}
+	var daemons []*dbmodel.Daemon
+	for _, daemonID := range daemonIDs {
+		daemon, err := importer.getDaemon(daemonID)
+		if err != nil {
+			return nil, err
+		}
+		if subnet == nil {
+			// Skip the daemons of the other family than the reserved
+			// addresses. Global reservations without addresses are
+			// imported to all selected daemons.
+			if reservation.IPAddress != "" && daemon.Name != dbmodel.DaemonNameDHCPv4 {
+				continue
+			}
+			if len(reservation.IPAddresses) > 0 && daemon.Name != dbmodel.DaemonNameDHCPv6 {
+				continue
+			}
+		}
+		if daemon.KeaDaemon == nil || daemon.KeaDaemon.Config == nil {
+			return nil, pkgerrors.Errorf("configuration of daemon %d is not available", daemonID)
+		}
+		if _, _, exists := daemon.KeaDaemon.Config.GetHookLibrary("libdhcp_host_cmds"); !exists {
+			return nil, pkgerrors.Errorf("daemon %d does not use the host_cmds hook library", daemonID)
+		}
+		daemons = append(daemons, daemon)
+	}
+	if len(daemons) == 0 {
+		return nil, pkgerrors.New("none of the selected daemons can serve the reserved addresses")
+	}
+	return daemons, nil
+}
+
+// Returns the subnet with the specified prefix. The subnets are cached
+// because many reservations typically belong to the same subnet.
+func (importer *Importer) getSubnet(prefix string) (*dbmodel.Subnet, error) {
+	parsed := storkutil.ParseIP(prefix)
+	if parsed == nil || !parsed.Prefix {
+		return nil, pkgerrors.Errorf("invalid subnet prefix %s", prefix)
+	}
+	if subnet, ok := importer.subnets[parsed.NetworkAddress]; ok {
+		return subnet, nil
+	}
+	subnets, err := dbmodel.GetSubnetsByPrefix(importer.db, parsed.NetworkAddress)
+	if err != nil {
+		return nil, newDatabaseError(err)
+	}
+	if len(subnets) == 0 {
+		return nil, pkgerrors.Errorf("subnet %s not found", parsed.NetworkAddress)
+	}
+	subnet := &subnets[0]
+	importer.subnets[parsed.NetworkAddress] = subnet
+	return subnet, nil
+}
+
+// Returns the daemon with the specified ID. The daemons are cached.
+func (importer *Importer) getDaemon(daemonID int64) (*dbmodel.Daemon, error) {
+	if daemon, ok := importer.daemons[daemonID]; ok {
+		return daemon, nil
+	}
+	daemon, err := dbmodel.GetDaemonByID(importer.db, daemonID)
+	if err != nil {
+		return nil, newDatabaseError(err)
+	}
+	if daemon == nil {
+		return nil, pkgerrors.Errorf("daemon %d not found", daemonID)
+	}
+	importer.daemons[daemonID] = daemon
+	return daemon, nil
+}
+
+// Appends the row error to the result.
+func (result *Result) addError(row *Row, err error) {
+	result.Errors = append(result.Errors, RowError{
+		Row:     row.Number,
+		Message: err.Error(),
+	})
+}
+
+// An error returned when communicating with the database fails. It
+// interrupts the import rather than being reported for a row.
+type databaseError struct {
+	error
+}
+
+// Wraps the error in the databaseError.
+func newDatabaseError(err error) error {
+	return &databaseError{err}
+}
+
+// Checks if the error is the databaseError.
+func isDatabaseError(err error) bool {
+	_, ok := err.(*databaseError)
+	return ok
+}
+
+// Checks if the list of IDs contains the specified ID.
+func containsID(ids []int64, id int64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package hostsimport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/require"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	"isc.org/stork/server/apps"
+	appstest "isc.org/stork/server/apps/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	storktestdbmodel "isc.org/stork/server/test/dbmodel"
+)
+
+// Creates the importer instance connected to the fake agents.
+func newTestImporter(db *pg.DB) (*Importer, *agentcommtest.FakeAgents) {
+	agents := agentcommtest.NewKeaFakeAgents()
+	lookup := dbmodel.NewDHCPOptionDefinitionLookup()
+	manager := apps.NewManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    agents,
+		DefLookup: lookup,
+	})
+	return NewImporter(db, manager, lookup), agents
+}
+
+// Test validating the host reservations and importing the valid ones.
+func TestImport(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	importer, agents := newTestImporter(db)
+	_, keaApps := storktestdbmodel.AddTestHosts(t, db)
+
+	contents := `identifier-type,identifier,ip-addresses,hostname,subnet,options
+hw-address,0a:0b:0c:0d:0e:0f,192.0.2.10,first.example.org,192.0.2.0/24,3=192.0.2.1
+hw-address,0a:0b:0c:0d:0e:0f,192.0.2.11,,192.0.2.0/24,
+hw-address,01:02:03:04:05:06,192.0.2.12,,192.0.2.0/24,
+hw-address,0a:0b:0c:0d:0e:01,192.0.3.1,,192.0.2.0/24,
+hw-address,0a:0b:0c:0d:0e:02,192.0.3.1,,192.0.3.0/24,
+hw-address,0a:0b:0c:0d:0e:03,192.0.2.13,,,
+duid,01:02:03:04:05,2001:db8:1::10,second.example.org,2001:db8:1::/64,
+foo,01:02,,,,`
+	rows, err := ParseCSV(strings.NewReader(contents))
+	require.NoError(t, err)
+
+	result, err := importer.Import(1, rows, Options{})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.EqualValues(t, 8, result.Total)
+	require.EqualValues(t, 2, result.Valid)
+	require.EqualValues(t, 2, result.Imported)
+
+	require.Len(t, result.Errors, 6)
+	require.EqualValues(t, 3, result.Errors[0].Row)
+	require.Contains(t, result.Errors[0].Message, "already specified in row 2")
+	require.EqualValues(t, 4, result.Errors[1].Row)
+	require.Contains(t, result.Errors[1].Message, "already exists in subnet 192.0.2.0/24")
+	require.EqualValues(t, 5, result.Errors[2].Row)
+	require.Contains(t, result.Errors[2].Message, "does not belong to subnet 192.0.2.0/24")
+	require.EqualValues(t, 6, result.Errors[3].Row)
+	require.Contains(t, result.Errors[3].Message, "subnet 192.0.3.0/24 not found")
+	require.EqualValues(t, 7, result.Errors[4].Row)
+	require.Contains(t, result.Errors[4].Message, "daemons must be selected")
+	require.EqualValues(t, 9, result.Errors[5].Row)
+	require.Contains(t, result.Errors[5].Message, "unsupported identifier type foo")
+
+	// Each reservation is sent to two servers in a single batch.
+	require.Len(t, agents.RecordedCommands, 4)
+	for _, command := range agents.RecordedCommands {
+		require.Equal(t, "reservation-add", command.GetCommand())
+	}
+
+	// The imported hosts should be in the database.
+	hosts, err := dbmodel.GetHostsByIdentifier(db, "hw-address", []byte{0xa, 0xb, 0xc, 0xd, 0xe, 0xf}, 1)
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	require.Equal(t, "first.example.org", hosts[0].Hostname)
+	require.Len(t, hosts[0].LocalHosts, 2)
+	require.Len(t, hosts[0].LocalHosts[0].DHCPOptionSet, 1)
+
+	hosts, err = dbmodel.GetHostsByIdentifier(db, "duid", []byte{1, 2, 3, 4, 5}, 2)
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	require.Len(t, hosts[0].LocalHosts, 2)
+
+	// Import a global reservation to the selected daemon.
+	rows, err = ParseJSON(strings.NewReader(`[ { "hw-address": "0a:0b:0c:0d:0e:03", "ip-address": "192.0.2.13" } ]`))
+	require.NoError(t, err)
+	result, err = importer.Import(1, rows, Options{
+		DaemonIDs: []int64{keaApps[0].Daemons[0].ID, keaApps[0].Daemons[1].ID},
+	})
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+	require.EqualValues(t, 1, result.Imported)
+
+	// The IPv4 reservation should only be sent to the DHCPv4 server.
+	hosts, err = dbmodel.GetHostsByIdentifier(db, "hw-address", []byte{0xa, 0xb, 0xc, 0xd, 0xe, 0x3}, 0)
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	require.Len(t, hosts[0].LocalHosts, 1)
+	require.Equal(t, keaApps[0].Daemons[0].ID, hosts[0].LocalHosts[0].DaemonID)
+}
+
+// Test that the rows are validated but not imported in the dry run mode.
+func TestImportDryRun(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	importer, agents := newTestImporter(db)
+	storktestdbmodel.AddTestHosts(t, db)
+
+	rows, err := ParseJSON(strings.NewReader(`[ { "hw-address": "0a:0b:0c:0d:0e:0f", "subnet": "192.0.2.0/24" } ]`))
+	require.NoError(t, err)
+
+	result, err := importer.Import(1, rows, Options{DryRun: true})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, result.Total)
+	require.EqualValues(t, 1, result.Valid)
+	require.Zero(t, result.Imported)
+	require.Empty(t, result.Errors)
+	require.Empty(t, agents.RecordedCommands)
+
+	hosts, err := dbmodel.GetHostsByIdentifier(db, "hw-address", []byte{0xa, 0xb, 0xc, 0xd, 0xe, 0xf}, 1)
+	require.NoError(t, err)
+	require.Empty(t, hosts)
+}
+
+// Test that the host reservations are committed in batches of the
+// specified size.
+func TestImportBatches(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	importer, agents := newTestImporter(db)
+	_, keaApps := storktestdbmodel.AddTestHosts(t, db)
+
+	contents := `identifier-type,identifier,subnet
+hw-address,0a:0b:0c:0d:0e:01,192.0.2.0/24
+hw-address,0a:0b:0c:0d:0e:02,192.0.2.0/24
+hw-address,0a:0b:0c:0d:0e:03,192.0.2.0/24`
+	rows, err := ParseCSV(strings.NewReader(contents))
+	require.NoError(t, err)
+
+	result, err := importer.Import(1, rows, Options{
+		DaemonIDs: []int64{keaApps[0].Daemons[0].ID},
+		BatchSize: 2,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, result.Valid)
+	require.EqualValues(t, 3, result.Imported)
+	require.Empty(t, result.Errors)
+
+	// The reservations should only be sent to the selected daemon.
+	require.Len(t, agents.RecordedCommands, 3)
+	for _, url := range agents.RecordedURLs {
+		require.Equal(t, "http://localhost:1234/", url)
+	}
+}
+
+// Test that the daemon locks are released after each batch so that the
+// subsequent batches and imports to the same daemon succeed.
+func TestImportBatchesReleaseLocks(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	importer, agents := newTestImporter(db)
+	_, keaApps := storktestdbmodel.AddTestHosts(t, db)
+	daemonIDs := []int64{keaApps[0].Daemons[0].ID}
+
+	contents := `identifier-type,identifier,subnet
+hw-address,0a:0b:0c:0d:0e:01,192.0.2.0/24
+hw-address,0a:0b:0c:0d:0e:02,192.0.2.0/24
+hw-address,0a:0b:0c:0d:0e:03,192.0.2.0/24`
+	rows, err := ParseCSV(strings.NewReader(contents))
+	require.NoError(t, err)
+
+	result, err := importer.Import(1, rows, Options{
+		DaemonIDs: daemonIDs,
+		BatchSize: 1,
+	})
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+	require.EqualValues(t, 3, result.Imported)
+
+	// Another import to the same daemon should not find it locked.
+	rows, err = ParseJSON(strings.NewReader(`[ { "hw-address": "0a:0b:0c:0d:0e:04", "subnet": "192.0.2.0/24" } ]`))
+	require.NoError(t, err)
+	result, err = importer.Import(1, rows, Options{
+		DaemonIDs: daemonIDs,
+		BatchSize: 1,
+	})
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+	require.EqualValues(t, 1, result.Imported)
+
+	require.Len(t, agents.RecordedCommands, 4)
+}
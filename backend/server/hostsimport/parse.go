@@ -0,0 +1,284 @@
+package hostsimport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	keaconfig "isc.org/stork/appcfg/kea"
+	storkutil "isc.org/stork/util"
+)
+
+// Formats of the imported host reservations.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Names of the columns in the imported CSV file. The first line of the
+// file must contain the names of the columns. The identifier type and
+// identifier columns are mandatory.
+const (
+	ColumnIdentifierType = "identifier-type"
+	ColumnIdentifier     = "identifier"
+	ColumnIPAddresses    = "ip-addresses"
+	ColumnPrefixes       = "prefixes"
+	ColumnHostname       = "hostname"
+	ColumnSubnet         = "subnet"
+	ColumnOptions        = "options"
+	ColumnClientClasses  = "client-classes"
+)
+
+// Separator of the values in the CSV columns holding multiple values,
+// e.g., multiple IP addresses or client classes.
+const listSeparator = ";"
+
+// Represents a single host reservation read from the imported file.
+type Row struct {
+	// Row number. It is the line number in the CSV file or the position
+	// of the reservation in the JSON list (starting from 1).
+	Number int
+	// Host reservation in the Kea format.
+	Reservation keaconfig.Reservation
+	// Prefix of the subnet the reservation belongs to. It is empty for
+	// the global reservations.
+	Subnet string
+	// Error parsing the row. The rows with errors are not imported.
+	Err error
+}
+
+// Kea host reservation with the Stork-specific subnet parameter. The
+// option data are decoded separately because the csv-format defaults
+// to true in Kea.
+type jsonReservation struct {
+	keaconfig.Reservation
+	OptionData []jsonOptionData `json:"option-data,omitempty"`
+	Subnet     string           `json:"subnet,omitempty"`
+}
+
+// Kea option data with the optional csv-format parameter.
+type jsonOptionData struct {
+	keaconfig.SingleOptionData
+	CSVFormat *bool `json:"csv-format,omitempty"`
+}
+
+// Parses the host reservations in the specified format.
+func Parse(format string, reader io.Reader) ([]*Row, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return ParseCSV(reader)
+	case FormatJSON:
+		return ParseJSON(reader)
+	default:
+		return nil, pkgerrors.Errorf("unsupported hosts import format %s", format)
+	}
+}
+
+// Parses the host reservations from the CSV file. The first line of the
+// file must contain the column names. The columns holding multiple values
+// separate them with semicolons. The DHCP options are specified as code
+// and data pairs, e.g., 3=192.0.2.1;6=192.0.2.2,192.0.2.3. It returns an
+// error if the file header is invalid. The errors found in the subsequent
+// lines are returned in the respective rows.
+func ParseCSV(reader io.Reader) ([]*Row, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+	csvReader.Comment = '#'
+
+	header, err := csvReader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, pkgerrors.New("hosts import file is empty")
+		}
+		return nil, pkgerrors.Wrap(err, "problem reading hosts import file header")
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case ColumnIdentifierType, ColumnIdentifier, ColumnIPAddresses, ColumnPrefixes,
+			ColumnHostname, ColumnSubnet, ColumnOptions, ColumnClientClasses:
+		default:
+			return nil, pkgerrors.Errorf("unknown column %s in hosts import file header", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, pkgerrors.Errorf("duplicated column %s in hosts import file header", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{ColumnIdentifierType, ColumnIdentifier} {
+		if _, ok := columns[name]; !ok {
+			return nil, pkgerrors.Errorf("missing column %s in hosts import file header", name)
+		}
+	}
+
+	var rows []*Row
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, pkgerrors.Wrap(err, "problem reading hosts import file")
+		}
+		// The record is returned along with the field count error, so
+		// the line number is always available here.
+		line, _ := csvReader.FieldPos(0)
+		row := &Row{
+			Number: line,
+		}
+		rows = append(rows, row)
+		if err != nil {
+			row.Err = pkgerrors.Errorf("expected %d columns but found %d", len(header), len(record))
+			continue
+		}
+		getValue := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row.Subnet = getValue(ColumnSubnet)
+		row.Err = parseCSVRecord(&row.Reservation, getValue)
+	}
+	return rows, nil
+}
+
+// Sets the reservation parameters from the CSV record. The getValue
+// function returns the value in the specified column.
+func parseCSVRecord(reservation *keaconfig.Reservation, getValue func(string) string) error {
+	if err := setIdentifier(reservation, getValue(ColumnIdentifierType), getValue(ColumnIdentifier)); err != nil {
+		return err
+	}
+	for _, address := range splitList(getValue(ColumnIPAddresses)) {
+		parsed := storkutil.ParseIP(address)
+		if parsed == nil || parsed.Prefix {
+			return pkgerrors.Errorf("invalid IP address %s", address)
+		}
+		if parsed.Protocol == storkutil.IPv4 {
+			if reservation.IPAddress != "" {
+				return pkgerrors.Errorf("multiple IPv4 addresses reserved")
+			}
+			reservation.IPAddress = parsed.NetworkAddress
+			continue
+		}
+		reservation.IPAddresses = append(reservation.IPAddresses, parsed.NetworkAddress)
+	}
+	for _, prefix := range splitList(getValue(ColumnPrefixes)) {
+		parsed := storkutil.ParseIP(prefix)
+		if parsed == nil || !parsed.Prefix || parsed.Protocol != storkutil.IPv6 {
+			return pkgerrors.Errorf("invalid IPv6 prefix %s", prefix)
+		}
+		reservation.Prefixes = append(reservation.Prefixes, parsed.NetworkAddress)
+	}
+	reservation.Hostname = getValue(ColumnHostname)
+	for _, option := range splitList(getValue(ColumnOptions)) {
+		code, data, found := strings.Cut(option, "=")
+		if !found {
+			return pkgerrors.Errorf("invalid option %s; expected code=data", option)
+		}
+		parsedCode, err := strconv.ParseUint(strings.TrimSpace(code), 10, 16)
+		if err != nil || parsedCode == 0 {
+			return pkgerrors.Errorf("invalid option code %s", code)
+		}
+		reservation.OptionData = append(reservation.OptionData, keaconfig.SingleOptionData{
+			Code:      uint16(parsedCode),
+			CSVFormat: true,
+			Data:      strings.TrimSpace(data),
+		})
+	}
+	reservation.ClientClasses = splitList(getValue(ColumnClientClasses))
+	return nil
+}
+
+// Sets the host identifier of the specified type in the reservation.
+func setIdentifier(reservation *keaconfig.Reservation, identifierType, identifier string) error {
+	if identifier == "" {
+		return pkgerrors.New("missing host identifier")
+	}
+	switch strings.ToLower(identifierType) {
+	case "hw-address":
+		reservation.HWAddress = identifier
+	case "duid":
+		reservation.DUID = identifier
+	case "circuit-id":
+		reservation.CircuitID = identifier
+	case "client-id":
+		reservation.ClientID = identifier
+	case "flex-id":
+		reservation.FlexID = identifier
+	default:
+		return pkgerrors.Errorf("unsupported identifier type %s", identifierType)
+	}
+	return nil
+}
+
+// Splits the list of the values in a CSV column. It removes the empty
+// values.
+func splitList(value string) (values []string) {
+	for _, v := range strings.Split(value, listSeparator) {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return
+}
+
+// Parses the host reservations from the JSON file. The file contains a
+// list of the reservations in the Kea format or a map with such a list
+// under the reservations key. Besides the Kea parameters, a reservation
+// may contain the subnet parameter holding the prefix of the subnet the
+// reservation belongs to. It returns an error if the file structure is
+// invalid. The errors in the individual reservations are returned in the
+// respective rows.
+func ParseJSON(reader io.Reader) ([]*Row, error) {
+	contents, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "problem reading hosts import file")
+	}
+	var list []json.RawMessage
+	if trimmed := bytes.TrimSpace(contents); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &list)
+	} else {
+		var container struct {
+			Reservations []json.RawMessage `json:"reservations"`
+		}
+		err = json.Unmarshal(trimmed, &container)
+		list = container.Reservations
+	}
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "problem parsing hosts import file")
+	}
+
+	var rows []*Row
+	for i, raw := range list {
+		row := &Row{
+			Number: i + 1,
+		}
+		rows = append(rows, row)
+		var reservation jsonReservation
+		if err := json.Unmarshal(raw, &reservation); err != nil {
+			row.Err = pkgerrors.Wrap(err, "invalid reservation")
+			continue
+		}
+		row.Reservation = reservation.Reservation
+		row.Reservation.OptionData = nil
+		for _, option := range reservation.OptionData {
+			optionData := option.SingleOptionData
+			optionData.CSVFormat = option.CSVFormat == nil || *option.CSVFormat
+			if optionData.Code == 0 {
+				row.Err = pkgerrors.Errorf("missing code of the option %s", optionData.Name)
+				break
+			}
+			row.Reservation.OptionData = append(row.Reservation.OptionData, optionData)
+		}
+		row.Subnet = strings.TrimSpace(reservation.Subnet)
+	}
+	return rows, nil
+}
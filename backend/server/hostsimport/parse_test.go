@@ -0,0 +1,179 @@
+package hostsimport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	keaconfig "isc.org/stork/appcfg/kea"
+)
+
+// Test parsing host reservations from a valid CSV file.
+func TestParseCSV(t *testing.T) {
+	contents := `identifier-type,identifier,ip-addresses,prefixes,hostname,subnet,options,client-classes
+# Comments are skipped.
+hw-address,01:02:03:04:05:06,192.0.2.10,,first.example.org,192.0.2.0/24,3=192.0.2.1;6=192.0.2.2,foo;bar
+duid,01:02:03:04,2001:db8:1::10;2001:db8:1::11,3000:1::/64,,2001:db8:1::/64,,
+client-id, 01:01:01 ,,,,,,`
+
+	rows, err := ParseCSV(strings.NewReader(contents))
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	require.EqualValues(t, 3, rows[0].Number)
+	require.NoError(t, rows[0].Err)
+	require.Equal(t, "192.0.2.0/24", rows[0].Subnet)
+	require.Equal(t, "01:02:03:04:05:06", rows[0].Reservation.HWAddress)
+	require.Equal(t, "192.0.2.10", rows[0].Reservation.IPAddress)
+	require.Empty(t, rows[0].Reservation.IPAddresses)
+	require.Equal(t, "first.example.org", rows[0].Reservation.Hostname)
+	require.Equal(t, []string{"foo", "bar"}, rows[0].Reservation.ClientClasses)
+	require.Equal(t, []keaconfig.SingleOptionData{
+		{Code: 3, CSVFormat: true, Data: "192.0.2.1"},
+		{Code: 6, CSVFormat: true, Data: "192.0.2.2"},
+	}, rows[0].Reservation.OptionData)
+
+	require.EqualValues(t, 4, rows[1].Number)
+	require.NoError(t, rows[1].Err)
+	require.Equal(t, "2001:db8:1::/64", rows[1].Subnet)
+	require.Equal(t, "01:02:03:04", rows[1].Reservation.DUID)
+	require.Empty(t, rows[1].Reservation.IPAddress)
+	require.Equal(t, []string{"2001:db8:1::10", "2001:db8:1::11"}, rows[1].Reservation.IPAddresses)
+	require.Equal(t, []string{"3000:1::/64"}, rows[1].Reservation.Prefixes)
+	require.Empty(t, rows[1].Reservation.ClientClasses)
+
+	require.EqualValues(t, 5, rows[2].Number)
+	require.NoError(t, rows[2].Err)
+	require.Empty(t, rows[2].Subnet)
+	require.Equal(t, "01:01:01", rows[2].Reservation.ClientID)
+}
+
+// Test that the CSV file must contain the mandatory columns and that
+// unknown columns are rejected.
+func TestParseCSVInvalidHeader(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader(""))
+	require.ErrorContains(t, err, "empty")
+
+	_, err = ParseCSV(strings.NewReader("identifier,hostname\n"))
+	require.ErrorContains(t, err, "missing column identifier-type")
+
+	_, err = ParseCSV(strings.NewReader("identifier-type,identifier,foo\n"))
+	require.ErrorContains(t, err, "unknown column foo")
+
+	_, err = ParseCSV(strings.NewReader("identifier-type,identifier,identifier\n"))
+	require.ErrorContains(t, err, "duplicated column identifier")
+}
+
+// Test that the errors in the individual CSV lines are returned in the
+// respective rows.
+func TestParseCSVInvalidRows(t *testing.T) {
+	contents := `identifier-type,identifier,ip-addresses,prefixes,options
+foo,01:02,,,
+hw-address,,,,
+hw-address,01:02,192.0.2.1;192.0.2.2,,
+hw-address,01:02,foo,,
+duid,01:02,,192.0.2.0/24,
+hw-address,01:02,,,3
+hw-address,01:02,,,abc=1
+hw-address,01:02
+hw-address,01:02,192.0.2.1,,`
+
+	rows, err := ParseCSV(strings.NewReader(contents))
+	require.NoError(t, err)
+	require.Len(t, rows, 9)
+
+	expected := []string{
+		"unsupported identifier type foo",
+		"missing host identifier",
+		"multiple IPv4 addresses reserved",
+		"invalid IP address foo",
+		"invalid IPv6 prefix 192.0.2.0/24",
+		"invalid option 3",
+		"invalid option code abc",
+		"expected 5 columns but found 2",
+	}
+	for i, message := range expected {
+		require.EqualValues(t, i+2, rows[i].Number)
+		require.ErrorContains(t, rows[i].Err, message)
+	}
+	require.NoError(t, rows[8].Err)
+}
+
+// Test parsing host reservations from a JSON file holding the map with
+// the reservations list.
+func TestParseJSON(t *testing.T) {
+	contents := `{
+		"reservations": [
+			{
+				"hw-address": "01:02:03:04:05:06",
+				"ip-address": "192.0.2.10",
+				"hostname": "first.example.org",
+				"subnet": "192.0.2.0/24",
+				"option-data": [
+					{
+						"code": 3,
+						"data": "192.0.2.1"
+					},
+					{
+						"code": 6,
+						"data": "C0000202",
+						"csv-format": false
+					}
+				],
+				"client-classes": [ "foo" ]
+			},
+			{
+				"duid": "01:02:03:04",
+				"prefixes": [ "3000:1::/64" ]
+			},
+			{
+				"hw-address": 1
+			}
+		]
+	}`
+	rows, err := ParseJSON(strings.NewReader(contents))
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	require.EqualValues(t, 1, rows[0].Number)
+	require.NoError(t, rows[0].Err)
+	require.Equal(t, "192.0.2.0/24", rows[0].Subnet)
+	require.Equal(t, "01:02:03:04:05:06", rows[0].Reservation.HWAddress)
+	require.Equal(t, "192.0.2.10", rows[0].Reservation.IPAddress)
+	require.Equal(t, "first.example.org", rows[0].Reservation.Hostname)
+	require.Equal(t, []string{"foo"}, rows[0].Reservation.ClientClasses)
+	require.Equal(t, []keaconfig.SingleOptionData{
+		{Code: 3, CSVFormat: true, Data: "192.0.2.1"},
+		{Code: 6, CSVFormat: false, Data: "C0000202"},
+	}, rows[0].Reservation.OptionData)
+
+	require.EqualValues(t, 2, rows[1].Number)
+	require.NoError(t, rows[1].Err)
+	require.Empty(t, rows[1].Subnet)
+	require.Equal(t, "01:02:03:04", rows[1].Reservation.DUID)
+	require.Equal(t, []string{"3000:1::/64"}, rows[1].Reservation.Prefixes)
+
+	require.EqualValues(t, 3, rows[2].Number)
+	require.ErrorContains(t, rows[2].Err, "invalid reservation")
+}
+
+// Test parsing host reservations from a JSON file holding the list of
+// reservations.
+func TestParseJSONList(t *testing.T) {
+	rows, err := Parse(FormatJSON, strings.NewReader(`[ { "flex-id": "01:02" }, { "hw-address": "01:03", "option-data": [ { "name": "routers" } ] } ]`))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.NoError(t, rows[0].Err)
+	require.Equal(t, "01:02", rows[0].Reservation.FlexID)
+	require.ErrorContains(t, rows[1].Err, "missing code of the option routers")
+}
+
+// Test that an error is returned for a malformed JSON file or an unsupported
+// format.
+func TestParseInvalidFile(t *testing.T) {
+	_, err := Parse(FormatJSON, strings.NewReader(`{ "reservations": `))
+	require.Error(t, err)
+
+	_, err = Parse("xml", strings.NewReader(""))
+	require.ErrorContains(t, err, "unsupported hosts import format xml")
+}
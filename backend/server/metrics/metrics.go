@@ -29,6 +29,19 @@ type metrics struct {
 	SubnetPdUtilization             *prometheus.GaugeVec
 	SharedNetworkAddressUtilization *prometheus.GaugeVec
 	SharedNetworkPdUtilization      *prometheus.GaugeVec
+	Bind9ViewZones                  *prometheus.GaugeVec
+	Bind9ViewQueryHitRatio          *prometheus.GaugeVec
+	Bind9ViewCacheStats             *prometheus.GaugeVec
+	Bind9ViewResolverStats          *prometheus.GaugeVec
+	Bind9ViewResolverQueries        *prometheus.GaugeVec
+	Bind9Rcodes                     *prometheus.GaugeVec
+	PoolTotalAddresses              *prometheus.GaugeVec
+	PoolAssignedAddresses           *prometheus.GaugeVec
+	PoolDeclinedAddresses           *prometheus.GaugeVec
+	PoolAddressUtilization          *prometheus.GaugeVec
+	PoolTotalPds                    *prometheus.GaugeVec
+	PoolAssignedPds                 *prometheus.GaugeVec
+	PoolPdUtilization               *prometheus.GaugeVec
 }
 
 // Constructor of the metrics. They are automatically
@@ -85,6 +98,84 @@ func newMetrics(db *pg.DB) *metrics {
 			Subsystem: "shared_network",
 			Help:      "Shared-network delegated-prefix utilization",
 		}, []string{"name"}),
+		Bind9ViewZones: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "view_zones",
+			Subsystem: "bind9",
+			Help:      "Number of the zones configured in the BIND 9 view",
+		}, []string{"app", "view"}),
+		Bind9ViewQueryHitRatio: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "view_query_hit_ratio",
+			Subsystem: "bind9",
+			Help:      "BIND 9 view cache query hit ratio",
+		}, []string{"app", "view"}),
+		Bind9ViewCacheStats: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "view_cache_stats",
+			Subsystem: "bind9",
+			Help:      "BIND 9 view cache statistics",
+		}, []string{"app", "view", "stat"}),
+		Bind9ViewResolverStats: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "view_resolver_stats",
+			Subsystem: "bind9",
+			Help:      "BIND 9 view resolver statistics",
+		}, []string{"app", "view", "stat"}),
+		Bind9ViewResolverQueries: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "view_resolver_queries",
+			Subsystem: "bind9",
+			Help:      "BIND 9 view outgoing queries by query type",
+		}, []string{"app", "view", "type"}),
+		Bind9Rcodes: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "rcodes",
+			Subsystem: "bind9",
+			Help:      "BIND 9 responses by RCODE",
+		}, []string{"app", "rcode"}),
+		PoolTotalAddresses: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "total_addresses",
+			Subsystem: "pool",
+			Help:      "Total addresses in the pool",
+		}, []string{"subnet", "pool", "app"}),
+		PoolAssignedAddresses: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "assigned_addresses",
+			Subsystem: "pool",
+			Help:      "Assigned addresses in the pool",
+		}, []string{"subnet", "pool", "app"}),
+		PoolDeclinedAddresses: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "declined_addresses",
+			Subsystem: "pool",
+			Help:      "Declined addresses in the pool",
+		}, []string{"subnet", "pool", "app"}),
+		PoolAddressUtilization: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "address_utilization",
+			Subsystem: "pool",
+			Help:      "Pool address utilization",
+		}, []string{"subnet", "pool", "app"}),
+		PoolTotalPds: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "total_pds",
+			Subsystem: "pool",
+			Help:      "Total delegated prefixes in the prefix pool",
+		}, []string{"subnet", "pool", "app"}),
+		PoolAssignedPds: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "assigned_pds",
+			Subsystem: "pool",
+			Help:      "Assigned delegated prefixes in the prefix pool",
+		}, []string{"subnet", "pool", "app"}),
+		PoolPdUtilization: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "pd_utilization",
+			Subsystem: "pool",
+			Help:      "Prefix pool delegated-prefix utilization",
+		}, []string{"subnet", "pool", "app"}),
 	}
 
 	return &metrics
@@ -119,9 +210,101 @@ func (m *metrics) Update() error {
 			Set(float64(networkMetrics.PdUtilization) / 1000.)
 	}
 
+	m.updateBind9Metrics(calculatedMetrics.Bind9Metrics)
+	m.updatePoolMetrics(calculatedMetrics.AddressPoolMetrics, calculatedMetrics.PrefixPoolMetrics)
+
 	return nil
 }
 
+// Sets the metrics of the address and prefix pools. The previous values
+// are removed to not report the pools that no longer exist. The DHCPv4
+// and DHCPv6 address statistics are reported using the same metrics.
+func (m *metrics) updatePoolMetrics(addressPoolMetrics, prefixPoolMetrics []dbmodel.CalculatedPoolMetrics) {
+	m.PoolTotalAddresses.Reset()
+	m.PoolAssignedAddresses.Reset()
+	m.PoolDeclinedAddresses.Reset()
+	m.PoolAddressUtilization.Reset()
+	m.PoolTotalPds.Reset()
+	m.PoolAssignedPds.Reset()
+	m.PoolPdUtilization.Reset()
+
+	for _, poolMetrics := range addressPoolMetrics {
+		labels := prometheus.Labels{"subnet": poolMetrics.Subnet, "pool": poolMetrics.Pool, "app": poolMetrics.AppName}
+		m.PoolTotalAddresses.With(labels).Set(poolMetrics.GetStat("total-addresses", "total-nas"))
+		m.PoolAssignedAddresses.With(labels).Set(poolMetrics.GetStat("assigned-addresses", "assigned-nas"))
+		m.PoolDeclinedAddresses.With(labels).Set(poolMetrics.GetStat("declined-addresses", "declined-nas"))
+		m.PoolAddressUtilization.With(labels).Set(float64(poolMetrics.Utilization) / 1000.)
+	}
+
+	for _, poolMetrics := range prefixPoolMetrics {
+		labels := prometheus.Labels{"subnet": poolMetrics.Subnet, "pool": poolMetrics.Pool, "app": poolMetrics.AppName}
+		m.PoolTotalPds.With(labels).Set(poolMetrics.GetStat("total-pds"))
+		m.PoolAssignedPds.With(labels).Set(poolMetrics.GetStat("assigned-pds"))
+		m.PoolPdUtilization.With(labels).Set(float64(poolMetrics.Utilization) / 1000.)
+	}
+}
+
+// Sets the BIND 9 metrics for all views of all daemons. The previous
+// values are removed to not report the views that no longer exist.
+func (m *metrics) updateBind9Metrics(bind9Metrics []dbmodel.CalculatedBind9Metrics) {
+	m.Bind9ViewZones.Reset()
+	m.Bind9ViewQueryHitRatio.Reset()
+	m.Bind9ViewCacheStats.Reset()
+	m.Bind9ViewResolverStats.Reset()
+	m.Bind9ViewResolverQueries.Reset()
+	m.Bind9Rcodes.Reset()
+
+	for _, daemonMetrics := range bind9Metrics {
+		namedStats := daemonMetrics.Stats.NamedStats
+		if namedStats == nil {
+			continue
+		}
+		app := daemonMetrics.AppName
+
+		for rcode, value := range namedStats.Rcodes {
+			m.Bind9Rcodes.
+				With(prometheus.Labels{"app": app, "rcode": rcode}).
+				Set(float64(value))
+		}
+
+		for viewName, view := range namedStats.Views {
+			if view == nil {
+				continue
+			}
+			m.Bind9ViewZones.
+				With(prometheus.Labels{"app": app, "view": viewName}).
+				Set(float64(view.ZoneCount))
+
+			if view.Resolver == nil {
+				continue
+			}
+			for stat, value := range view.Resolver.CacheStats {
+				m.Bind9ViewCacheStats.
+					With(prometheus.Labels{"app": app, "view": viewName, "stat": stat}).
+					Set(float64(value))
+			}
+			for stat, value := range view.Resolver.Stats {
+				m.Bind9ViewResolverStats.
+					With(prometheus.Labels{"app": app, "view": viewName, "stat": stat}).
+					Set(float64(value))
+			}
+			for qtype, value := range view.Resolver.Qtypes {
+				m.Bind9ViewResolverQueries.
+					With(prometheus.Labels{"app": app, "view": viewName, "type": qtype}).
+					Set(float64(value))
+			}
+
+			hits := view.Resolver.CacheStats["QueryHits"]
+			misses := view.Resolver.CacheStats["QueryMisses"]
+			if hits+misses > 0 {
+				m.Bind9ViewQueryHitRatio.
+					With(prometheus.Labels{"app": app, "view": viewName}).
+					Set(float64(hits) / float64(hits+misses))
+			}
+		}
+	}
+}
+
 // Unregister all metrics from the Prometheus registry.
 func (m *metrics) UnregisterAll() {
 	v := reflect.ValueOf(*m)
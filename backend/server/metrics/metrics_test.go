@@ -3,7 +3,9 @@ package metrics
 import (
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
+	dbmodel "isc.org/stork/server/database/model"
 )
 
 // All metrics should be properly constructed.
@@ -36,3 +38,126 @@ func TestUnregisterAllMetrics(t *testing.T) {
 	// Arrange
 	require.Empty(t, mfs)
 }
+
+// BIND 9 metrics should be set for all views and the stale values
+// should be removed.
+func TestUpdateBind9Metrics(t *testing.T) {
+	// Arrange
+	metrics := newMetrics(nil)
+	bind9Metrics := []dbmodel.CalculatedBind9Metrics{
+		{
+			AppName: "bind9@localhost",
+			Stats: dbmodel.Bind9DaemonStats{
+				NamedStats: &dbmodel.Bind9NamedStats{
+					Rcodes: map[string]int64{"NOERROR": 10, "NXDOMAIN": 2},
+					Views: map[string]*dbmodel.Bind9StatsView{
+						"_default": {
+							ZoneCount: 3,
+							Resolver: &dbmodel.Bind9StatsResolver{
+								Stats:      map[string]int64{"Queryv4": 5},
+								Qtypes:     map[string]int64{"A": 4},
+								CacheStats: map[string]int64{"QueryHits": 3, "QueryMisses": 1},
+							},
+						},
+						"internal": {
+							ZoneCount: 1,
+						},
+					},
+				},
+			},
+		},
+		{
+			AppName: "bind9@other",
+		},
+	}
+
+	// Act
+	metrics.updateBind9Metrics(bind9Metrics)
+
+	// Assert
+	require.EqualValues(t, 3, testutil.ToFloat64(metrics.Bind9ViewZones.WithLabelValues("bind9@localhost", "_default")))
+	require.EqualValues(t, 1, testutil.ToFloat64(metrics.Bind9ViewZones.WithLabelValues("bind9@localhost", "internal")))
+	require.EqualValues(t, 0.75, testutil.ToFloat64(metrics.Bind9ViewQueryHitRatio.WithLabelValues("bind9@localhost", "_default")))
+	require.EqualValues(t, 3, testutil.ToFloat64(metrics.Bind9ViewCacheStats.WithLabelValues("bind9@localhost", "_default", "QueryHits")))
+	require.EqualValues(t, 5, testutil.ToFloat64(metrics.Bind9ViewResolverStats.WithLabelValues("bind9@localhost", "_default", "Queryv4")))
+	require.EqualValues(t, 4, testutil.ToFloat64(metrics.Bind9ViewResolverQueries.WithLabelValues("bind9@localhost", "_default", "A")))
+	require.EqualValues(t, 2, testutil.ToFloat64(metrics.Bind9Rcodes.WithLabelValues("bind9@localhost", "NXDOMAIN")))
+	require.Equal(t, 2, testutil.CollectAndCount(metrics.Bind9ViewZones))
+	require.Equal(t, 1, testutil.CollectAndCount(metrics.Bind9ViewQueryHitRatio))
+
+	// Act
+	metrics.updateBind9Metrics(bind9Metrics[1:])
+
+	// Assert
+	require.Zero(t, testutil.CollectAndCount(metrics.Bind9ViewZones))
+	require.Zero(t, testutil.CollectAndCount(metrics.Bind9Rcodes))
+}
+
+// Pool metrics should be set for all pools and the stale values should
+// be removed.
+func TestUpdatePoolMetrics(t *testing.T) {
+	// Arrange
+	metrics := newMetrics(nil)
+	addressPoolMetrics := []dbmodel.CalculatedPoolMetrics{
+		{
+			Subnet:  "192.0.2.0/24",
+			Pool:    "192.0.2.1-192.0.2.10",
+			AppName: "kea@localhost",
+			Stats: dbmodel.SubnetStats{
+				"total-addresses":    uint64(10),
+				"assigned-addresses": uint64(9),
+				"declined-addresses": uint64(1),
+			},
+			Utilization: 900,
+		},
+		{
+			Subnet:  "2001:db8:1::/64",
+			Pool:    "2001:db8:1::1-2001:db8:1::ffff",
+			AppName: "kea@localhost",
+			Stats: dbmodel.SubnetStats{
+				"total-nas":    uint64(65535),
+				"assigned-nas": uint64(100),
+			},
+			Utilization: 1,
+		},
+	}
+	prefixPoolMetrics := []dbmodel.CalculatedPoolMetrics{
+		{
+			Subnet:  "2001:db8:1::/48",
+			Pool:    "2001:db8:1:8000::/56",
+			AppName: "kea@localhost",
+			Stats: dbmodel.SubnetStats{
+				"total-pds":    uint64(256),
+				"assigned-pds": uint64(64),
+			},
+			Utilization: 250,
+		},
+	}
+
+	// Act
+	metrics.updatePoolMetrics(addressPoolMetrics, prefixPoolMetrics)
+
+	// Assert
+	labels := []string{"192.0.2.0/24", "192.0.2.1-192.0.2.10", "kea@localhost"}
+	require.EqualValues(t, 10, testutil.ToFloat64(metrics.PoolTotalAddresses.WithLabelValues(labels...)))
+	require.EqualValues(t, 9, testutil.ToFloat64(metrics.PoolAssignedAddresses.WithLabelValues(labels...)))
+	require.EqualValues(t, 1, testutil.ToFloat64(metrics.PoolDeclinedAddresses.WithLabelValues(labels...)))
+	require.EqualValues(t, 0.9, testutil.ToFloat64(metrics.PoolAddressUtilization.WithLabelValues(labels...)))
+
+	labels = []string{"2001:db8:1::/64", "2001:db8:1::1-2001:db8:1::ffff", "kea@localhost"}
+	require.EqualValues(t, 65535, testutil.ToFloat64(metrics.PoolTotalAddresses.WithLabelValues(labels...)))
+	require.EqualValues(t, 100, testutil.ToFloat64(metrics.PoolAssignedAddresses.WithLabelValues(labels...)))
+	require.Zero(t, testutil.ToFloat64(metrics.PoolDeclinedAddresses.WithLabelValues(labels...)))
+
+	labels = []string{"2001:db8:1::/48", "2001:db8:1:8000::/56", "kea@localhost"}
+	require.EqualValues(t, 256, testutil.ToFloat64(metrics.PoolTotalPds.WithLabelValues(labels...)))
+	require.EqualValues(t, 64, testutil.ToFloat64(metrics.PoolAssignedPds.WithLabelValues(labels...)))
+	require.EqualValues(t, 0.25, testutil.ToFloat64(metrics.PoolPdUtilization.WithLabelValues(labels...)))
+
+	// Act
+	metrics.updatePoolMetrics(addressPoolMetrics[:1], nil)
+
+	// Assert
+	require.Equal(t, 1, testutil.CollectAndCount(metrics.PoolTotalAddresses))
+	require.Zero(t, testutil.CollectAndCount(metrics.PoolTotalPds))
+}
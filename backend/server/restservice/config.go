@@ -2,13 +2,19 @@ package restservice
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-openapi/runtime/middleware"
 	"github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	keaconfig "isc.org/stork/appcfg/kea"
+	"isc.org/stork/server/apps/kea"
+	"isc.org/stork/server/config"
 	"isc.org/stork/server/configreview"
 	dbmodel "isc.org/stork/server/database/model"
 	"isc.org/stork/server/gen/models"
@@ -152,6 +158,9 @@ func (r *RestAPI) GetDaemonConfigReports(ctx context.Context, params services.Ge
 			Checker:   dbReport.CheckerName,
 			Content:   dbReport.Content,
 		}
+		if dbReport.Fix != nil {
+			report.FixDescription = dbReport.Fix.Description
+		}
 		configReports.Items = append(configReports.Items, report)
 	}
 
@@ -451,3 +460,498 @@ func (r *RestAPI) PutGlobalConfigCheckerPreferences(ctx context.Context, params
 	rsp := services.NewGetDaemonConfigCheckersOK().WithPayload(payload)
 	return rsp
 }
+
+// Converts the global DHCP parameters of the daemon's configuration to the
+// REST API format. The global options which cannot be converted are skipped.
+func (r *RestAPI) globalParametersToRestAPI(cfg *dbmodel.KeaConfig) (*models.KeaConfigGlobalParameters, error) {
+	params := cfg.GetSettableGlobalParameters()
+	if params == nil {
+		return nil, errors.New("configuration does not belong to a DHCP server")
+	}
+	restParams := &models.KeaConfigGlobalParameters{
+		KeaConfigDdnsParameters: models.KeaConfigDdnsParameters{
+			DdnsGeneratedPrefix:       params.DDNSGeneratedPrefix,
+			DdnsOverrideClientUpdate:  params.DDNSOverrideClientUpdate,
+			DdnsOverrideNoUpdate:      params.DDNSOverrideNoUpdate,
+			DdnsQualifyingSuffix:      params.DDNSQualifyingSuffix,
+			DdnsReplaceClientName:     params.DDNSReplaceClientName,
+			DdnsSendUpdates:           params.DDNSSendUpdates,
+			DdnsUpdateOnRenew:         params.DDNSUpdateOnRenew,
+			DdnsUseConflictResolution: params.DDNSUseConflictResolution,
+		},
+		KeaConfigPreferredLifetimeParameters: models.KeaConfigPreferredLifetimeParameters{
+			MaxPreferredLifetime: params.MaxPreferredLifetime,
+			MinPreferredLifetime: params.MinPreferredLifetime,
+			PreferredLifetime:    params.PreferredLifetime,
+		},
+		KeaConfigTimerParameters: models.KeaConfigTimerParameters{
+			CalculateTeeTimes: params.CalculateTeeTimes,
+			RebindTimer:       params.RebindTimer,
+			RenewTimer:        params.RenewTimer,
+			T1Percent:         params.T1Percent,
+			T2Percent:         params.T2Percent,
+		},
+		KeaConfigValidLifetimeParameters: models.KeaConfigValidLifetimeParameters{
+			MaxValidLifetime: params.MaxValidLifetime,
+			MinValidLifetime: params.MinValidLifetime,
+			ValidLifetime:    params.ValidLifetime,
+		},
+	}
+	// The client classes are held in the raw form.
+	if len(params.ClientClasses) > 0 {
+		marshalled, err := json.Marshal(params.ClientClasses)
+		if err != nil {
+			return nil, errors.Wrap(err, "problem marshalling client classes")
+		}
+		if err = json.Unmarshal(marshalled, &restParams.ClientClasses); err != nil {
+			return nil, errors.Wrap(err, "problem unmarshalling client classes")
+		}
+	}
+	universe := storkutil.IPv4
+	if cfg.IsDHCPv6() {
+		universe = storkutil.IPv6
+	}
+	var convertedOptions []dbmodel.DHCPOption
+	for _, option := range params.OptionData {
+		convertedOption, err := dbmodel.NewDHCPOptionFromKea(option, universe, r.DHCPOptionDefinitionLookup)
+		if err != nil {
+			continue
+		}
+		convertedOptions = append(convertedOptions, *convertedOption)
+	}
+	restParams.OptionsHash = storkutil.Fnv128(convertedOptions)
+	restParams.Options = r.unflattenDHCPOptions(convertedOptions, "", 0)
+	return restParams, nil
+}
+
+// Converts the global DHCP parameters from the REST API format to the
+// format used in the Kea configuration. The daemon ID is used to find
+// the suitable option definitions.
+func (r *RestAPI) convertToGlobalParameters(daemonID int64, restParams *models.KeaConfigGlobalParameters) (*keaconfig.SettableGlobalParameters, error) {
+	params := &keaconfig.SettableGlobalParameters{
+		DDNSParameters: keaconfig.DDNSParameters{
+			DDNSGeneratedPrefix:       restParams.DdnsGeneratedPrefix,
+			DDNSOverrideClientUpdate:  restParams.DdnsOverrideClientUpdate,
+			DDNSOverrideNoUpdate:      restParams.DdnsOverrideNoUpdate,
+			DDNSQualifyingSuffix:      restParams.DdnsQualifyingSuffix,
+			DDNSReplaceClientName:     restParams.DdnsReplaceClientName,
+			DDNSSendUpdates:           restParams.DdnsSendUpdates,
+			DDNSUpdateOnRenew:         restParams.DdnsUpdateOnRenew,
+			DDNSUseConflictResolution: restParams.DdnsUseConflictResolution,
+		},
+		PreferredLifetimeParameters: keaconfig.PreferredLifetimeParameters{
+			MaxPreferredLifetime: restParams.MaxPreferredLifetime,
+			MinPreferredLifetime: restParams.MinPreferredLifetime,
+			PreferredLifetime:    restParams.PreferredLifetime,
+		},
+		TimerParameters: keaconfig.TimerParameters{
+			CalculateTeeTimes: restParams.CalculateTeeTimes,
+			RebindTimer:       restParams.RebindTimer,
+			RenewTimer:        restParams.RenewTimer,
+			T1Percent:         restParams.T1Percent,
+			T2Percent:         restParams.T2Percent,
+		},
+		ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+			MaxValidLifetime: restParams.MaxValidLifetime,
+			MinValidLifetime: restParams.MinValidLifetime,
+			ValidLifetime:    restParams.ValidLifetime,
+		},
+	}
+	if len(restParams.ClientClasses) > 0 {
+		marshalled, err := json.Marshal(restParams.ClientClasses)
+		if err != nil {
+			return nil, errors.Wrap(err, "problem marshalling client classes")
+		}
+		if err = json.Unmarshal(marshalled, &params.ClientClasses); err != nil {
+			return nil, errors.Wrap(err, "client classes have invalid format")
+		}
+		for _, class := range params.ClientClasses {
+			if name, ok := class["name"].(string); !ok || name == "" {
+				return nil, errors.New("client class name must be specified")
+			}
+		}
+	}
+	options, err := r.flattenDHCPOptions("", restParams.Options, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range options {
+		optionData, err := keaconfig.CreateSingleOptionData(daemonID, r.DHCPOptionDefinitionLookup, options[i])
+		if err != nil {
+			return nil, err
+		}
+		params.OptionData = append(params.OptionData, *optionData)
+	}
+	return params, nil
+}
+
+// Implements the POST call to create new transaction for updating the
+// global parameters of a Kea DHCP server (daemons/{id}/config/transaction).
+// It locks the daemon configuration for updates and returns the current
+// global parameters.
+func (r *RestAPI) UpdateDaemonGlobalParametersBegin(ctx context.Context, params services.UpdateDaemonGlobalParametersBeginParams) middleware.Responder {
+	cctx, code, msg := r.createTransactionContext(ctx)
+	if code != 0 {
+		// Error case.
+		rsp := services.NewUpdateDaemonGlobalParametersBeginDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Begin global parameters update transaction. It retrieves current daemon
+	// information and locks the daemon for updates.
+	var err error
+	cctx, err = r.ConfigManager.GetKeaModule().BeginGlobalParametersUpdate(cctx, []int64{params.ID})
+	if err != nil {
+		var (
+			daemonNotFound *config.DaemonNotFoundError
+			lock           *config.LockError
+		)
+		switch {
+		case errors.As(err, &daemonNotFound):
+			// Failed to find daemon.
+			msg := err.Error()
+			log.Error(err)
+			rsp := services.NewUpdateDaemonGlobalParametersBeginDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		case errors.As(err, &lock):
+			// Failed to lock daemons.
+			msg := err.Error()
+			log.Error(err)
+			rsp := services.NewUpdateDaemonGlobalParametersBeginDefault(http.StatusLocked).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		default:
+			// Other error.
+			msg := "problem with initializing transaction for global parameters update"
+			log.Error(err)
+			rsp := services.NewUpdateDaemonGlobalParametersBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		}
+	}
+	state, _ := config.GetTransactionState[kea.ConfigRecipe](cctx)
+	daemon := state.Updates[0].Recipe.DaemonsBeforeUpdate[0]
+
+	restParams, err := r.globalParametersToRestAPI(daemon.KeaDaemon.Config)
+	if err != nil {
+		r.ConfigManager.Done(cctx)
+		msg := "problem with getting global parameters from the daemon configuration"
+		log.Error(err)
+		rsp := services.NewUpdateDaemonGlobalParametersBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	// Retrieve the generated context ID.
+	cctxID, ok := config.GetValueAsInt64(cctx, config.ContextIDKey)
+	if !ok {
+		msg := "problem with retrieving context ID for a transaction"
+		log.Error(msg)
+		rsp := services.NewUpdateDaemonGlobalParametersBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Remember the context, i.e. new transaction has been successfully created.
+	_ = r.ConfigManager.RememberContext(cctx, time.Minute*10)
+
+	// Return transaction ID and the global parameters to the user.
+	contents := &models.UpdateDaemonGlobalParametersBeginResponse{
+		ID:         cctxID,
+		DaemonName: daemon.Name,
+		Parameters: restParams,
+	}
+	rsp := services.NewUpdateDaemonGlobalParametersBeginOK().WithPayload(contents)
+	return rsp
+}
+
+// Applies and commits the updated global parameters of the daemon. The
+// ctx parameter is the REST API context. The transactionID is the identifier
+// of the current configuration transaction used by the function to recover
+// the transaction context. This function returns the HTTP error code if an
+// error occurs or 0 when there is no error. In addition it returns an error
+// string to be included in the HTTP response or an empty string if there is
+// no error.
+func (r *RestAPI) submitGlobalParameters(ctx context.Context, daemonID, transactionID int64, restParams *models.KeaConfigGlobalParameters) (int, string) {
+	// Make sure that the parameters are present.
+	if restParams == nil {
+		msg := "global parameters not specified"
+		log.Errorf(msg)
+		return http.StatusBadRequest, msg
+	}
+	// Get the user ID and recover the transaction context.
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		msg := "unable to submit because user is not logged in"
+		log.Error("Problem with recovering transaction context because user has no session")
+		return http.StatusForbidden, msg
+	}
+	// Retrieve the context from the config manager.
+	cctx, _ := r.ConfigManager.RecoverContext(transactionID, int64(user.ID))
+	if cctx == nil {
+		msg := "transaction expired"
+		log.Errorf("Problem with recovering transaction context for transaction ID %d and user ID %d", transactionID, user.ID)
+		return http.StatusNotFound, msg
+	}
+	// Convert the parameters from REST API to Kea format.
+	globalParameters, err := r.convertToGlobalParameters(daemonID, restParams)
+	if err != nil {
+		msg := fmt.Sprintf("error parsing specified global parameters: %s", err)
+		log.Error(err)
+		return http.StatusBadRequest, msg
+	}
+	// Apply the parameters (create Kea commands).
+	cctx, err = r.ConfigManager.GetKeaModule().ApplyGlobalParametersUpdate(cctx, map[int64]*keaconfig.SettableGlobalParameters{
+		daemonID: globalParameters,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("problem with applying global parameters: %s", err)
+		log.Error(err)
+		return http.StatusBadRequest, msg
+	}
+	// Send the commands to Kea servers.
+	cctx, err = r.ConfigManager.Commit(cctx)
+	if err != nil {
+		msg := fmt.Sprintf("problem with committing global parameters: %s", err)
+		log.Error(err)
+		return http.StatusConflict, msg
+	}
+	// Everything ok. Cleanup and send OK to the client.
+	r.ConfigManager.Done(cctx)
+	return 0, ""
+}
+
+// Implements the POST call to apply and commit the updated global parameters
+// (daemons/{id}/config/transaction/{txId}/submit).
+func (r *RestAPI) UpdateDaemonGlobalParametersSubmit(ctx context.Context, params services.UpdateDaemonGlobalParametersSubmitParams) middleware.Responder {
+	if code, msg := r.submitGlobalParameters(ctx, params.ID, params.TxID, params.Parameters); code != 0 {
+		// Error case.
+		rsp := services.NewUpdateDaemonGlobalParametersSubmitDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := services.NewUpdateDaemonGlobalParametersSubmitOK()
+	return rsp
+}
+
+// Implements the DELETE call to cancel updating the global parameters
+// (daemons/{id}/config/transaction/{txId}). It removes the specified
+// transaction from the config manager, if the transaction exists.
+func (r *RestAPI) UpdateDaemonGlobalParametersDelete(ctx context.Context, params services.UpdateDaemonGlobalParametersDeleteParams) middleware.Responder {
+	if code, msg := r.commonCreateOrUpdateDelete(ctx, params.TxID); code != 0 {
+		// Error case.
+		rsp := services.NewUpdateDaemonGlobalParametersDeleteDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := services.NewUpdateDaemonGlobalParametersDeleteOK()
+	return rsp
+}
+
+// Implements the POST call to begin applying the fix proposed in the config
+// report (daemons/{id}/config-reports/{reportId}/fix/transaction). It locks
+// the daemon configuration and applies the fix to a copy of the configuration.
+// It returns the changes made by the fix, so the user can review them before
+// submitting the transaction.
+func (r *RestAPI) ApplyConfigReportFixBegin(ctx context.Context, params services.ApplyConfigReportFixBeginParams) middleware.Responder {
+	cctx, code, msg := r.createTransactionContext(ctx)
+	if code != 0 {
+		// Error case.
+		rsp := services.NewApplyConfigReportFixBeginDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Begin the transaction. It retrieves the report and the daemon
+	// information and locks the daemon for updates.
+	var err error
+	cctx, err = r.ConfigManager.GetKeaModule().BeginConfigReportFix(cctx, params.ReportID)
+	if err == nil {
+		state, _ := config.GetTransactionState[kea.ConfigRecipe](cctx)
+		if state.Updates[0].Recipe.DaemonsBeforeUpdate[0].ID != params.ID {
+			// The report belongs to another daemon.
+			r.ConfigManager.Done(cctx)
+			err = errors.WithStack(config.NewConfigReportFixNotFoundError(params.ReportID))
+		}
+	}
+	if err != nil {
+		var (
+			fixNotFound    *config.ConfigReportFixNotFoundError
+			daemonNotFound *config.DaemonNotFoundError
+			outdated       *config.ConfigReportFixOutdatedError
+			lock           *config.LockError
+		)
+		var code int
+		msg := err.Error()
+		switch {
+		case errors.As(err, &fixNotFound), errors.As(err, &daemonNotFound):
+			// Failed to find the fix or daemon.
+			code = http.StatusNotFound
+		case errors.As(err, &outdated):
+			// The configuration has changed since the review.
+			code = http.StatusConflict
+		case errors.As(err, &lock):
+			// Failed to lock daemons.
+			code = http.StatusLocked
+		default:
+			// Other error.
+			code = http.StatusInternalServerError
+			msg = "problem with initializing transaction for applying config report fix"
+		}
+		log.Error(err)
+		rsp := services.NewApplyConfigReportFixBeginDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	// Apply the fix to a copy of the configuration (create Kea commands).
+	cctx, err = r.ConfigManager.GetKeaModule().ApplyConfigReportFix(cctx)
+	if err != nil {
+		r.ConfigManager.Done(cctx)
+		msg := fmt.Sprintf("problem with applying config report fix: %s", err)
+		log.Error(err)
+		rsp := services.NewApplyConfigReportFixBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	state, _ := config.GetTransactionState[kea.ConfigRecipe](cctx)
+	recipe := state.Updates[0].Recipe
+	daemon := recipe.DaemonsBeforeUpdate[0]
+
+	// The paths of the differences begin with the daemon ID, like in the
+	// config change log.
+	daemonKey := fmt.Sprint(daemon.ID)
+	differences, err := storkutil.CompareJSON(
+		map[string]any{daemonKey: daemon.KeaDaemon.Config},
+		map[string]any{daemonKey: recipe.ConfigsAfterUpdate[daemon.ID]},
+	)
+	if err != nil {
+		r.ConfigManager.Done(cctx)
+		msg := "problem with comparing the configurations before and after applying config report fix"
+		log.Error(err)
+		rsp := services.NewApplyConfigReportFixBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	// Retrieve the generated context ID.
+	cctxID, ok := config.GetValueAsInt64(cctx, config.ContextIDKey)
+	if !ok {
+		r.ConfigManager.Done(cctx)
+		msg := "problem with retrieving context ID for a transaction"
+		log.Error(msg)
+		rsp := services.NewApplyConfigReportFixBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Remember the context, i.e. new transaction has been successfully created.
+	_ = r.ConfigManager.RememberContext(cctx, time.Minute*10)
+
+	// Return transaction ID and the changes to the user. The sensitive
+	// data are hidden from the users other than super-admin, like in the
+	// daemon configuration.
+	_, dbUser := r.SessionManager.Logged(ctx)
+	hideSensitiveData := !dbUser.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID})
+	contents := &models.ApplyConfigReportFixBeginResponse{
+		ID:          cctxID,
+		DaemonName:  daemon.Name,
+		Description: recipe.ConfigReportFix.Description,
+	}
+	for _, operation := range recipe.ConfigReportFix.Patch {
+		restOperation := &models.ConfigReportFixOperation{
+			Op:    operation.Op,
+			Path:  operation.Path,
+			Value: operation.Value,
+		}
+		if hideSensitiveData {
+			restOperation.Value = nil
+			if !isSensitiveJSONPointer(operation.Path) {
+				restOperation.Value = hideConfigChangeSensitiveData(operation.Value)
+			}
+		}
+		contents.Patch = append(contents.Patch, restOperation)
+	}
+	for _, difference := range differences {
+		contents.Diff = append(contents.Diff, newRestConfigChangeDiff(difference, hideSensitiveData))
+	}
+	rsp := services.NewApplyConfigReportFixBeginOK().WithPayload(contents)
+	return rsp
+}
+
+// Implements the POST call to commit the config report fix
+// (daemons/{id}/config-reports/{reportId}/fix/transaction/{txId}/submit).
+func (r *RestAPI) ApplyConfigReportFixSubmit(ctx context.Context, params services.ApplyConfigReportFixSubmitParams) middleware.Responder {
+	// Get the user ID and recover the transaction context.
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		msg := "unable to submit because user is not logged in"
+		log.Error("Problem with recovering transaction context because user has no session")
+		rsp := services.NewApplyConfigReportFixSubmitDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Retrieve the context from the config manager.
+	cctx, _ := r.ConfigManager.RecoverContext(params.TxID, int64(user.ID))
+	if cctx == nil {
+		msg := "transaction expired"
+		log.Errorf("Problem with recovering transaction context for transaction ID %d and user ID %d", params.TxID, user.ID)
+		rsp := services.NewApplyConfigReportFixSubmitDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Make sure that the transaction applies the specified fix.
+	state, ok := config.GetTransactionState[kea.ConfigRecipe](cctx)
+	if !ok || len(state.Updates) != 1 || state.Updates[0].Recipe.ConfigReportID == nil ||
+		*state.Updates[0].Recipe.ConfigReportID != params.ReportID {
+		msg := fmt.Sprintf("transaction %d does not apply fix for config report with ID %d", params.TxID, params.ReportID)
+		log.Error(msg)
+		rsp := services.NewApplyConfigReportFixSubmitDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Send the commands to Kea servers.
+	cctx, err := r.ConfigManager.Commit(cctx)
+	if err != nil {
+		msg := fmt.Sprintf("problem with committing config report fix: %s", err)
+		log.Error(err)
+		rsp := services.NewApplyConfigReportFixSubmitDefault(http.StatusConflict).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Everything ok. Cleanup and send OK to the client.
+	r.ConfigManager.Done(cctx)
+	rsp := services.NewApplyConfigReportFixSubmitOK()
+	return rsp
+}
+
+// Implements the DELETE call to cancel applying the config report fix
+// (daemons/{id}/config-reports/{reportId}/fix/transaction/{txId}). It
+// removes the specified transaction from the config manager, if the
+// transaction exists.
+func (r *RestAPI) ApplyConfigReportFixDelete(ctx context.Context, params services.ApplyConfigReportFixDeleteParams) middleware.Responder {
+	if code, msg := r.commonCreateOrUpdateDelete(ctx, params.TxID); code != 0 {
+		// Error case.
+		rsp := services.NewApplyConfigReportFixDeleteDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := services.NewApplyConfigReportFixDeleteOK()
+	return rsp
+}
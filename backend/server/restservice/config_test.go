@@ -9,11 +9,13 @@ import (
 	"github.com/stretchr/testify/require"
 	agentcommtest "isc.org/stork/server/agentcomm/test"
 	"isc.org/stork/server/configreview"
+	dbops "isc.org/stork/server/database"
 	dbmodel "isc.org/stork/server/database/model"
 	dbtest "isc.org/stork/server/database/test"
 	"isc.org/stork/server/gen/models"
 	"isc.org/stork/server/gen/restapi/operations/services"
 	storktest "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
 )
 
 // Test that GetDaemonConfig works for Kea daemon with assigned configuration.
@@ -1377,3 +1379,351 @@ func TestPutDaemonConfigCheckerPreferencesForMissingDaemon(t *testing.T) {
 	preferences, _ := dbmodel.GetCheckerPreferences(db, daemonID)
 	require.Empty(t, preferences)
 }
+
+// Test converting the global parameters from the Kea configuration to
+// the REST API format and back.
+func TestGlobalParametersConversion(t *testing.T) {
+	rapi := &RestAPI{
+		DHCPOptionDefinitionLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	}
+	cfg, err := dbmodel.NewKeaConfigFromJSON(`{
+        "Dhcp4": {
+            "valid-lifetime": 3600,
+            "rebind-timer": 1800,
+            "ddns-qualifying-suffix": "example.org",
+            "option-data": [
+                {
+                    "code": 6,
+                    "csv-format": true,
+                    "data": "192.0.2.1, 192.0.2.2"
+                }
+            ],
+            "client-classes": [
+                {
+                    "name": "foo",
+                    "test": "member('ALL')"
+                }
+            ]
+        }
+    }`)
+	require.NoError(t, err)
+
+	restParams, err := rapi.globalParametersToRestAPI(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, restParams)
+	require.EqualValues(t, 3600, *restParams.ValidLifetime)
+	require.EqualValues(t, 1800, *restParams.RebindTimer)
+	require.Equal(t, "example.org", *restParams.DdnsQualifyingSuffix)
+	require.Nil(t, restParams.PreferredLifetime)
+	require.Len(t, restParams.ClientClasses, 1)
+	require.Len(t, restParams.Options, 1)
+	require.EqualValues(t, 6, restParams.Options[0].Code)
+	require.NotEmpty(t, restParams.OptionsHash)
+
+	params, err := rapi.convertToGlobalParameters(1, restParams)
+	require.NoError(t, err)
+	require.NotNil(t, params)
+	require.EqualValues(t, 3600, *params.ValidLifetime)
+	require.EqualValues(t, 1800, *params.RebindTimer)
+	require.Equal(t, "example.org", *params.DDNSQualifyingSuffix)
+	require.Len(t, params.ClientClasses, 1)
+	require.Equal(t, "foo", params.ClientClasses[0]["name"])
+	require.Equal(t, "member('ALL')", params.ClientClasses[0]["test"])
+	require.Len(t, params.OptionData, 1)
+	require.EqualValues(t, 6, params.OptionData[0].Code)
+	require.Equal(t, "192.0.2.1,192.0.2.2", params.OptionData[0].Data)
+}
+
+// Test that the client classes without names are rejected.
+func TestConvertToGlobalParametersClientClassNoName(t *testing.T) {
+	rapi := &RestAPI{
+		DHCPOptionDefinitionLookup: dbmodel.NewDHCPOptionDefinitionLookup(),
+	}
+	restParams := &models.KeaConfigGlobalParameters{}
+	restParams.ValidLifetime = storkutil.Ptr(int64(1000))
+	restParams.ClientClasses = append(restParams.ClientClasses, map[string]any{
+		"test": "member('ALL')",
+	})
+	_, err := rapi.convertToGlobalParameters(1, restParams)
+	require.Error(t, err)
+}
+
+// Test the calls for creating new transaction and submitting updated
+// global parameters.
+func TestUpdateDaemonGlobalParametersBeginSubmit(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, cm, ctx, user := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	app := addTestSubnetCmdsServer(t, db)
+	daemonID := app.Daemons[0].ID
+
+	// Begin transaction.
+	rsp := rapi.UpdateDaemonGlobalParametersBegin(ctx, services.UpdateDaemonGlobalParametersBeginParams{
+		ID: daemonID,
+	})
+	require.IsType(t, &services.UpdateDaemonGlobalParametersBeginOK{}, rsp)
+	contents := rsp.(*services.UpdateDaemonGlobalParametersBeginOK).Payload
+
+	transactionID := contents.ID
+	require.NotZero(t, transactionID)
+	require.Equal(t, dbmodel.DaemonNameDHCPv4, contents.DaemonName)
+	require.NotNil(t, contents.Parameters)
+	require.Nil(t, contents.Parameters.ValidLifetime)
+	require.Len(t, contents.Parameters.ClientClasses, 1)
+
+	// Modify the parameters and submit them.
+	parameters := contents.Parameters
+	parameters.ValidLifetime = storkutil.Ptr(int64(7200))
+	parameters.ClientClasses = nil
+	rsp2 := rapi.UpdateDaemonGlobalParametersSubmit(ctx, services.UpdateDaemonGlobalParametersSubmitParams{
+		ID:         daemonID,
+		TxID:       transactionID,
+		Parameters: parameters,
+	})
+	require.IsType(t, &services.UpdateDaemonGlobalParametersSubmitOK{}, rsp2)
+
+	require.Len(t, fa.RecordedCommands, 3)
+	require.Equal(t, "config-test", fa.RecordedCommands[0].GetCommand())
+	require.Equal(t, "config-set", fa.RecordedCommands[1].GetCommand())
+	require.Equal(t, "config-write", fa.RecordedCommands[2].GetCommand())
+
+	// Make sure that the transaction is done.
+	cctx, _ := cm.RecoverContext(transactionID, int64(user.ID))
+	if cctx != nil {
+		cm.Done(cctx)
+	}
+	require.Nil(t, cctx)
+
+	// Make sure that the configuration has been updated in the database.
+	daemon, err := dbmodel.GetDaemonByID(db, daemonID)
+	require.NoError(t, err)
+	require.NotNil(t, daemon)
+	require.EqualValues(t, 7200, *daemon.KeaDaemon.Config.GetValidLifetimeParameters().ValidLifetime)
+	require.Empty(t, daemon.KeaDaemon.Config.GetClientClasses())
+	require.Len(t, daemon.KeaDaemon.Config.GetSubnets(), 1)
+}
+
+// Test that the transaction for updating the global parameters of a
+// non-existing daemon cannot be started.
+func TestUpdateDaemonGlobalParametersBeginNonExistingDaemon(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, _, ctx, _ := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	rsp := rapi.UpdateDaemonGlobalParametersBegin(ctx, services.UpdateDaemonGlobalParametersBeginParams{
+		ID: 1024,
+	})
+	require.IsType(t, &services.UpdateDaemonGlobalParametersBeginDefault{}, rsp)
+	defaultRsp := rsp.(*services.UpdateDaemonGlobalParametersBeginDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+}
+
+// Test that the preferred lifetime cannot be submitted for a DHCPv4 server.
+func TestUpdateDaemonGlobalParametersSubmitInvalid(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, cm, ctx, user := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	app := addTestSubnetCmdsServer(t, db)
+	daemonID := app.Daemons[0].ID
+
+	rsp := rapi.UpdateDaemonGlobalParametersBegin(ctx, services.UpdateDaemonGlobalParametersBeginParams{
+		ID: daemonID,
+	})
+	require.IsType(t, &services.UpdateDaemonGlobalParametersBeginOK{}, rsp)
+	contents := rsp.(*services.UpdateDaemonGlobalParametersBeginOK).Payload
+
+	parameters := contents.Parameters
+	parameters.PreferredLifetime = storkutil.Ptr(int64(1000))
+	rsp2 := rapi.UpdateDaemonGlobalParametersSubmit(ctx, services.UpdateDaemonGlobalParametersSubmitParams{
+		ID:         daemonID,
+		TxID:       contents.ID,
+		Parameters: parameters,
+	})
+	require.IsType(t, &services.UpdateDaemonGlobalParametersSubmitDefault{}, rsp2)
+	defaultRsp := rsp2.(*services.UpdateDaemonGlobalParametersSubmitDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+	require.Empty(t, fa.RecordedCommands)
+
+	// Cancel the transaction.
+	rsp3 := rapi.UpdateDaemonGlobalParametersDelete(ctx, services.UpdateDaemonGlobalParametersDeleteParams{
+		ID:   daemonID,
+		TxID: contents.ID,
+	})
+	require.IsType(t, &services.UpdateDaemonGlobalParametersDeleteOK{}, rsp3)
+
+	cctx, _ := cm.RecoverContext(contents.ID, int64(user.ID))
+	require.Nil(t, cctx)
+}
+
+// Adds a config report with a fix for the specified daemon. It sets the
+// daemon's configuration hash to the hash the fix was generated for.
+func addTestConfigReportWithFix(t *testing.T, db *dbops.PgDB, daemonID int64) *dbmodel.ConfigReport {
+	daemon, err := dbmodel.GetDaemonByID(db, daemonID)
+	require.NoError(t, err)
+	daemon.KeaDaemon.ConfigHash = "1234"
+	err = dbmodel.UpdateDaemon(db, daemon)
+	require.NoError(t, err)
+
+	report := &dbmodel.ConfigReport{
+		CheckerName: "test",
+		Content:     storkutil.Ptr("test report for {daemon}"),
+		DaemonID:    daemonID,
+		RefDaemons:  []*dbmodel.Daemon{daemon},
+		Fix: &dbmodel.ConfigReportFix{
+			Description: "Set the valid lifetime.",
+			ConfigHash:  "1234",
+			Patch: []storkutil.JSONPatchOperation{
+				{Op: "add", Path: "/Dhcp4/valid-lifetime", Value: 7200},
+			},
+		},
+	}
+	err = dbmodel.AddConfigReport(db, report)
+	require.NoError(t, err)
+	return report
+}
+
+// Test beginning and submitting the transaction applying the config
+// report fix.
+func TestApplyConfigReportFixBeginSubmit(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, cm, ctx, user := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	app := addTestSubnetCmdsServer(t, db)
+	daemonID := app.Daemons[0].ID
+	report := addTestConfigReportWithFix(t, db, daemonID)
+
+	// The fix description is returned with the report.
+	rsp := rapi.GetDaemonConfigReports(ctx, services.GetDaemonConfigReportsParams{
+		ID: daemonID,
+	})
+	require.IsType(t, &services.GetDaemonConfigReportsOK{}, rsp)
+	reports := rsp.(*services.GetDaemonConfigReportsOK).Payload
+	require.Len(t, reports.Items, 1)
+	require.Equal(t, "Set the valid lifetime.", reports.Items[0].FixDescription)
+
+	// Begin transaction.
+	rsp = rapi.ApplyConfigReportFixBegin(ctx, services.ApplyConfigReportFixBeginParams{
+		ID:       daemonID,
+		ReportID: report.ID,
+	})
+	require.IsType(t, &services.ApplyConfigReportFixBeginOK{}, rsp)
+	contents := rsp.(*services.ApplyConfigReportFixBeginOK).Payload
+	require.NotZero(t, contents.ID)
+	require.Equal(t, dbmodel.DaemonNameDHCPv4, contents.DaemonName)
+	require.Equal(t, "Set the valid lifetime.", contents.Description)
+	require.Len(t, contents.Patch, 1)
+	require.NotEmpty(t, contents.Diff)
+
+	// Submit the fix.
+	rsp2 := rapi.ApplyConfigReportFixSubmit(ctx, services.ApplyConfigReportFixSubmitParams{
+		ID:       daemonID,
+		ReportID: report.ID,
+		TxID:     contents.ID,
+	})
+	require.IsType(t, &services.ApplyConfigReportFixSubmitOK{}, rsp2)
+
+	require.Len(t, fa.RecordedCommands, 3)
+	require.Equal(t, "config-test", fa.RecordedCommands[0].GetCommand())
+	require.Equal(t, "config-set", fa.RecordedCommands[1].GetCommand())
+	require.Equal(t, "config-write", fa.RecordedCommands[2].GetCommand())
+
+	// Make sure that the transaction is done.
+	cctx, _ := cm.RecoverContext(contents.ID, int64(user.ID))
+	if cctx != nil {
+		cm.Done(cctx)
+	}
+	require.Nil(t, cctx)
+
+	// Make sure that the configuration has been updated in the database.
+	daemon, err := dbmodel.GetDaemonByID(db, daemonID)
+	require.NoError(t, err)
+	require.EqualValues(t, 7200, *daemon.KeaDaemon.Config.GetValidLifetimeParameters().ValidLifetime)
+	require.Len(t, daemon.KeaDaemon.Config.GetSubnets(), 1)
+}
+
+// Test that the transaction applying the config report fix is not started
+// when the fix doesn't exist or is outdated.
+func TestApplyConfigReportFixBeginErrors(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, _, ctx, _ := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	app := addTestSubnetCmdsServer(t, db)
+	daemonID := app.Daemons[0].ID
+	report := addTestConfigReportWithFix(t, db, daemonID)
+
+	// Non-existing report.
+	rsp := rapi.ApplyConfigReportFixBegin(ctx, services.ApplyConfigReportFixBeginParams{
+		ID:       daemonID,
+		ReportID: report.ID + 1,
+	})
+	require.IsType(t, &services.ApplyConfigReportFixBeginDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*services.ApplyConfigReportFixBeginDefault)))
+
+	// The report belongs to another daemon.
+	rsp = rapi.ApplyConfigReportFixBegin(ctx, services.ApplyConfigReportFixBeginParams{
+		ID:       daemonID + 1,
+		ReportID: report.ID,
+	})
+	require.IsType(t, &services.ApplyConfigReportFixBeginDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*services.ApplyConfigReportFixBeginDefault)))
+
+	// The configuration has changed since the fix was generated.
+	daemon, err := dbmodel.GetDaemonByID(db, daemonID)
+	require.NoError(t, err)
+	daemon.KeaDaemon.ConfigHash = "5678"
+	err = dbmodel.UpdateDaemon(db, daemon)
+	require.NoError(t, err)
+
+	rsp = rapi.ApplyConfigReportFixBegin(ctx, services.ApplyConfigReportFixBeginParams{
+		ID:       daemonID,
+		ReportID: report.ID,
+	})
+	require.IsType(t, &services.ApplyConfigReportFixBeginDefault{}, rsp)
+	require.Equal(t, http.StatusConflict, getStatusCode(*rsp.(*services.ApplyConfigReportFixBeginDefault)))
+	require.Empty(t, fa.RecordedCommands)
+}
+
+// Test that the transaction applying the config report fix can be cancelled.
+func TestApplyConfigReportFixDelete(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, cm, ctx, user := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	app := addTestSubnetCmdsServer(t, db)
+	daemonID := app.Daemons[0].ID
+	report := addTestConfigReportWithFix(t, db, daemonID)
+
+	rsp := rapi.ApplyConfigReportFixBegin(ctx, services.ApplyConfigReportFixBeginParams{
+		ID:       daemonID,
+		ReportID: report.ID,
+	})
+	require.IsType(t, &services.ApplyConfigReportFixBeginOK{}, rsp)
+	contents := rsp.(*services.ApplyConfigReportFixBeginOK).Payload
+
+	rsp2 := rapi.ApplyConfigReportFixDelete(ctx, services.ApplyConfigReportFixDeleteParams{
+		ID:       daemonID,
+		ReportID: report.ID,
+		TxID:     contents.ID,
+	})
+	require.IsType(t, &services.ApplyConfigReportFixDeleteOK{}, rsp2)
+	require.Empty(t, fa.RecordedCommands)
+
+	cctx, _ := cm.RecoverContext(contents.ID, int64(user.ID))
+	require.Nil(t, cctx)
+}
@@ -0,0 +1,444 @@
+package restservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	log "github.com/sirupsen/logrus"
+
+	keaconfig "isc.org/stork/appcfg/kea"
+	"isc.org/stork/server/config"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/services"
+	storkutil "isc.org/stork/util"
+)
+
+// Converts the config change log entry fetched from the database to the
+// REST API format. The sensitive data, e.g., passwords, are hidden in the
+// returned commands, states and differences. They are hidden before the
+// entries are recorded but the entries recorded by the older Stork versions
+// may still contain them.
+func newRestConfigChangeLogEntry(dbEntry *dbmodel.ConfigChangeLogEntry) *models.ConfigChangeLogEntry {
+	entry := &models.ConfigChangeLogEntry{
+		ID:           dbEntry.ID,
+		CreatedAt:    strfmt.DateTime(dbEntry.CreatedAt),
+		UserID:       dbEntry.UserID,
+		UserIdentity: dbEntry.UserIdentity,
+		Target:       string(dbEntry.Target),
+		Operation:    dbEntry.Operation,
+		DaemonIds:    dbEntry.DaemonIDs,
+		ObjectType:   dbEntry.ObjectType,
+		ObjectID:     dbEntry.ObjectID,
+		Scheduled:    dbEntry.Scheduled,
+		DeadlineAt:   convertToOptionalDatetime(dbEntry.DeadlineAt),
+		StateBefore:  hideConfigChangeSensitiveData(dbEntry.StateBefore),
+		StateAfter:   hideConfigChangeSensitiveData(dbEntry.StateAfter),
+		Error:        dbEntry.Error,
+	}
+	if recipe, ok := hideConfigChangeSensitiveData(dbEntry.Recipe).([]any); ok {
+		entry.Recipe = recipe
+	}
+	for _, diff := range dbEntry.Diff {
+		entry.Diff = append(entry.Diff, newRestConfigChangeDiff(diff, true))
+	}
+	return entry
+}
+
+// Converts the difference between the configurations to the REST API
+// format. If the hideSensitiveData flag is set, the sensitive data are
+// hidden in the compared values.
+func newRestConfigChangeDiff(diff storkutil.JSONDifference, hideSensitiveData bool) *models.ConfigChangeDiff {
+	if !hideSensitiveData {
+		return &models.ConfigChangeDiff{
+			Path:   diff.Path,
+			Before: diff.Before,
+			After:  diff.After,
+		}
+	}
+	restDiff := &models.ConfigChangeDiff{
+		Path: diff.Path,
+	}
+	if !isSensitiveJSONPointer(diff.Path) {
+		restDiff.Before = hideConfigChangeSensitiveData(diff.Before)
+		restDiff.After = hideConfigChangeSensitiveData(diff.After)
+	}
+	return restDiff
+}
+
+// Returns a copy of the value recorded in the config change log with the
+// sensitive data hidden. It returns nil if the value can't be processed.
+func hideConfigChangeSensitiveData(value any) any {
+	hidden, err := keaconfig.HideSensitiveDataInValue(value)
+	if err != nil {
+		log.WithError(err).Warn("Problem hiding the sensitive data in the config change log entry")
+		return nil
+	}
+	return hidden
+}
+
+// Checks if the JSON pointer points to a value holding sensitive data or
+// to a value nested in it.
+func isSensitiveJSONPointer(pointer string) bool {
+	for _, segment := range strings.Split(pointer, "/") {
+		if keaconfig.IsSensitiveDataKey(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the audit log of the configuration changes. The entries can be
+// filtered by user, daemon and configured object. The most recent entries
+// are returned first.
+func (r *RestAPI) GetConfigChangeLog(ctx context.Context, params services.GetConfigChangeLogParams) middleware.Responder {
+	var start int64
+	if params.Start != nil {
+		start = *params.Start
+	}
+
+	var limit int64 = 10
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	filter := &dbmodel.ConfigChangeLogFilter{
+		UserID:     params.UserID,
+		DaemonID:   params.DaemonID,
+		ObjectType: params.ObjectType,
+		ObjectID:   params.ObjectID,
+	}
+
+	dbEntries, total, err := dbmodel.GetConfigChangeLogEntriesByPage(r.DB, start, limit, filter, "created_at", dbmodel.SortDirDesc)
+	if err != nil {
+		log.WithError(err).Error("Failed to get the config change log from the database")
+		msg := "Problem fetching the config change log from the database"
+		rsp := services.NewGetConfigChangeLogDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	entries := &models.ConfigChangeLogEntries{
+		Total: total,
+	}
+	for i := range dbEntries {
+		entries.Items = append(entries.Items, newRestConfigChangeLogEntry(&dbEntries[i]))
+	}
+
+	rsp := services.NewGetConfigChangeLogOK().WithPayload(entries)
+	return rsp
+}
+
+// Returns the config change log entry by ID.
+func (r *RestAPI) GetConfigChangeLogEntry(ctx context.Context, params services.GetConfigChangeLogEntryParams) middleware.Responder {
+	dbEntry, err := dbmodel.GetConfigChangeLogEntryByID(r.DB, params.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to get the config change log entry %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem fetching the config change log entry with ID %d from the database", params.ID)
+		rsp := services.NewGetConfigChangeLogEntryDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbEntry == nil {
+		msg := fmt.Sprintf("Cannot find config change log entry with ID %d", params.ID)
+		rsp := services.NewGetConfigChangeLogEntryDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rsp := services.NewGetConfigChangeLogEntryOK().WithPayload(newRestConfigChangeLogEntry(dbEntry))
+	return rsp
+}
+
+// Converts the scheduled config change fetched from the database to the
+// REST API format.
+func newRestScheduledConfigChange(dbChange *dbmodel.ScheduledConfigChange) *models.ScheduledConfigChange {
+	change := &models.ScheduledConfigChange{
+		ID:         dbChange.ID,
+		CreatedAt:  strfmt.DateTime(dbChange.CreatedAt),
+		DeadlineAt: strfmt.DateTime(dbChange.DeadlineAt),
+		UserID:     dbChange.UserID,
+		Executed:   dbChange.Executed,
+		Error:      dbChange.Error,
+	}
+	if dbChange.User != nil {
+		change.UserIdentity = dbChange.User.Identity()
+	}
+	for _, dbUpdate := range dbChange.Updates {
+		update := &models.ConfigUpdate{
+			Target:    string(dbUpdate.Target),
+			Operation: dbUpdate.Operation,
+			DaemonIds: dbUpdate.DaemonIDs,
+		}
+		if dbUpdate.Recipe != nil {
+			var recipe any
+			if err := json.Unmarshal(*dbUpdate.Recipe, &recipe); err != nil {
+				log.WithError(err).Warnf("Problem parsing the recipe of the scheduled config change %d", dbChange.ID)
+			} else {
+				update.Recipe = recipe
+			}
+		}
+		change.Updates = append(change.Updates, update)
+	}
+	return change
+}
+
+// Returns the scheduled config changes. The changes can be filtered by
+// the execution state. They are ordered by deadline.
+func (r *RestAPI) GetScheduledConfigChanges(ctx context.Context, params services.GetScheduledConfigChangesParams) middleware.Responder {
+	var start int64
+	if params.Start != nil {
+		start = *params.Start
+	}
+
+	var limit int64 = 10
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	dbChanges, total, err := dbmodel.GetScheduledConfigChangesByPage(r.DB, start, limit, params.Executed)
+	if err != nil {
+		log.WithError(err).Error("Failed to get the scheduled config changes from the database")
+		msg := "Problem fetching the scheduled config changes from the database"
+		rsp := services.NewGetScheduledConfigChangesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	changes := &models.ScheduledConfigChanges{
+		Total: total,
+	}
+	for i := range dbChanges {
+		changes.Items = append(changes.Items, newRestScheduledConfigChange(&dbChanges[i]))
+	}
+
+	rsp := services.NewGetScheduledConfigChangesOK().WithPayload(changes)
+	return rsp
+}
+
+// Returns a function of the Kea config module applying the host reservation
+// in the transaction. The function is selected according to the operation
+// of the transaction begun for adding or updating a host reservation.
+func (r *RestAPI) getHostApplyFunc(cctx context.Context) func(context.Context, *dbmodel.Host) (context.Context, error) {
+	state, ok := config.GetAnyTransactionState(cctx)
+	if !ok || len(state.GetUpdates()) == 0 {
+		return nil
+	}
+	switch state.GetUpdates()[0].Operation {
+	case "host_add":
+		return r.ConfigManager.GetKeaModule().ApplyHostAdd
+	case "host_update":
+		return r.ConfigManager.GetKeaModule().ApplyHostUpdate
+	default:
+		return nil
+	}
+}
+
+// Common function for scheduling the host reservation change. It applies
+// the host reservation in the specified transaction and schedules the
+// commit at the specified deadline. It returns the ID of the scheduled
+// change. If an error occurs, it returns an HTTP error code and an error
+// string to be included in the HTTP response.
+func (r *RestAPI) scheduleHostChange(ctx context.Context, newChange *models.NewScheduledConfigChange) (int64, int, string) {
+	if newChange == nil || newChange.TransactionID == nil || newChange.DeadlineAt == nil {
+		msg := "transaction ID and deadline must be specified"
+		log.Error(msg)
+		return 0, http.StatusBadRequest, msg
+	}
+	if newChange.Host == nil {
+		msg := "host information not specified"
+		log.Error(msg)
+		return 0, http.StatusBadRequest, msg
+	}
+	// Get the user ID and recover the transaction context.
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		msg := "unable to schedule config change because user is not logged in"
+		log.Error("Problem with recovering transaction context because user has no session")
+		return 0, http.StatusForbidden, msg
+	}
+	transactionID := *newChange.TransactionID
+	cctx, _ := r.ConfigManager.RecoverContext(transactionID, int64(user.ID))
+	if cctx == nil {
+		msg := "transaction expired"
+		log.Errorf("Problem with recovering transaction context for transaction ID %d and user ID %d", transactionID, user.ID)
+		return 0, http.StatusNotFound, msg
+	}
+	applyFunc := r.getHostApplyFunc(cctx)
+	if applyFunc == nil {
+		msg := "only the transactions adding or updating a host reservation can be scheduled"
+		log.Errorf("Problem with scheduling transaction %d which is not a host reservation transaction", transactionID)
+		return 0, http.StatusBadRequest, msg
+	}
+	// Apply the host information in the transaction.
+	cctx, code, msg := r.applyHost(ctx, cctx, newChange.Host, applyFunc)
+	if code != 0 {
+		return 0, code, msg
+	}
+	// Store the change in the database to commit it at the deadline.
+	cctx, err := r.ConfigManager.Schedule(cctx, time.Time(*newChange.DeadlineAt).UTC())
+	if err != nil {
+		msg := fmt.Sprintf("problem with scheduling host reservation change: %s", err)
+		log.Error(err)
+		return 0, http.StatusInternalServerError, msg
+	}
+	changeID, _ := config.GetValueAsInt64(cctx, config.ScheduledChangeContextKey)
+	// Everything ok. Cleanup and return the change ID.
+	r.ConfigManager.Done(cctx)
+	return changeID, 0, ""
+}
+
+// Implements the POST call scheduling a host reservation change
+// (config-changes). The host reservation is applied in the transaction
+// begun for adding or updating the reservation. Instead of committing
+// the transaction, the change is stored in the database and committed
+// at the specified deadline.
+func (r *RestAPI) CreateScheduledConfigChange(ctx context.Context, params services.CreateScheduledConfigChangeParams) middleware.Responder {
+	changeID, code, msg := r.scheduleHostChange(ctx, params.Change)
+	if code != 0 {
+		// Error case.
+		rsp := services.NewCreateScheduledConfigChangeDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	dbChange, err := dbmodel.GetScheduledConfigChange(r.DB, changeID)
+	if err != nil || dbChange == nil {
+		msg := fmt.Sprintf("Problem fetching the scheduled config change with ID %d from the database", changeID)
+		log.WithError(err).Error(msg)
+		rsp := services.NewCreateScheduledConfigChangeDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := services.NewCreateScheduledConfigChangeOK().WithPayload(newRestScheduledConfigChange(dbChange))
+	return rsp
+}
+
+// Returns the scheduled config change by ID.
+func (r *RestAPI) GetScheduledConfigChange(ctx context.Context, params services.GetScheduledConfigChangeParams) middleware.Responder {
+	dbChange, err := dbmodel.GetScheduledConfigChange(r.DB, params.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to get the scheduled config change %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem fetching the scheduled config change with ID %d from the database", params.ID)
+		rsp := services.NewGetScheduledConfigChangeDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbChange == nil {
+		msg := fmt.Sprintf("Cannot find scheduled config change with ID %d", params.ID)
+		rsp := services.NewGetScheduledConfigChangeDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := services.NewGetScheduledConfigChangeOK().WithPayload(newRestScheduledConfigChange(dbChange))
+	return rsp
+}
+
+// Fetches the scheduled config change and checks if it is still pending.
+// If the change does not exist or it has been executed, it returns an HTTP
+// error code and an error string to be included in the HTTP response.
+func (r *RestAPI) getPendingConfigChange(changeID int64) (*dbmodel.ScheduledConfigChange, int, string) {
+	dbChange, err := dbmodel.GetScheduledConfigChange(r.DB, changeID)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to get the scheduled config change %d from the database", changeID)
+		msg := fmt.Sprintf("Problem fetching the scheduled config change with ID %d from the database", changeID)
+		return nil, http.StatusInternalServerError, msg
+	}
+	if dbChange == nil {
+		msg := fmt.Sprintf("Cannot find scheduled config change with ID %d", changeID)
+		return nil, http.StatusNotFound, msg
+	}
+	if dbChange.Executed {
+		msg := fmt.Sprintf("Scheduled config change with ID %d has already been executed", changeID)
+		return nil, http.StatusConflict, msg
+	}
+	return dbChange, 0, ""
+}
+
+// Sets new deadline for the pending config change.
+func (r *RestAPI) RescheduleConfigChange(ctx context.Context, params services.RescheduleConfigChangeParams) middleware.Responder {
+	if params.Deadline == nil || params.Deadline.DeadlineAt == nil {
+		msg := "Deadline must be specified"
+		rsp := services.NewRescheduleConfigChangeDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if _, code, msg := r.getPendingConfigChange(params.ID); code != 0 {
+		rsp := services.NewRescheduleConfigChangeDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	deadline := time.Time(*params.Deadline.DeadlineAt).UTC()
+	if err := r.ConfigManager.Reschedule(params.ID, deadline); err != nil {
+		// The change could have been executed in the meantime.
+		code := http.StatusInternalServerError
+		msg := fmt.Sprintf("Problem rescheduling the config change with ID %d", params.ID)
+		if errors.Is(err, dbmodel.ErrNotExists) {
+			code = http.StatusConflict
+			msg = fmt.Sprintf("Scheduled config change with ID %d has already been executed", params.ID)
+		}
+		log.WithError(err).Error(msg)
+		rsp := services.NewRescheduleConfigChangeDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	dbChange, err := dbmodel.GetScheduledConfigChange(r.DB, params.ID)
+	if err != nil || dbChange == nil {
+		msg := fmt.Sprintf("Problem fetching the scheduled config change with ID %d from the database", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := services.NewRescheduleConfigChangeDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := services.NewRescheduleConfigChangeOK().WithPayload(newRestScheduledConfigChange(dbChange))
+	return rsp
+}
+
+// Cancels the pending config change. The change is marked executed, so
+// it is no longer committed, and the cancellation is recorded in the
+// change's error text.
+func (r *RestAPI) CancelScheduledConfigChange(ctx context.Context, params services.CancelScheduledConfigChangeParams) middleware.Responder {
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		msg := "Unable to cancel config change because user is not logged in"
+		rsp := services.NewCancelScheduledConfigChangeDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if _, code, msg := r.getPendingConfigChange(params.ID); code != 0 {
+		rsp := services.NewCancelScheduledConfigChangeDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	errtext := fmt.Sprintf("canceled by %s", user.Identity())
+	if err := dbmodel.SetScheduledConfigChangeExecuted(r.DB, params.ID, errtext); err != nil {
+		msg := fmt.Sprintf("Problem canceling the config change with ID %d", params.ID)
+		log.WithError(err).Error(msg)
+		rsp := services.NewCancelScheduledConfigChangeDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := services.NewCancelScheduledConfigChangeOK()
+	return rsp
+}
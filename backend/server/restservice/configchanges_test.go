@@ -0,0 +1,395 @@
+package restservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/require"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	apps "isc.org/stork/server/apps"
+	appstest "isc.org/stork/server/apps/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	"isc.org/stork/server/gen/restapi/operations/services"
+	storktestdbmodel "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
+)
+
+// Test converting the config change log entry to the REST API format.
+func TestNewRestConfigChangeLogEntry(t *testing.T) {
+	createdAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	dbEntry := &dbmodel.ConfigChangeLogEntry{
+		ID:           5,
+		CreatedAt:    createdAt,
+		UserID:       2,
+		UserIdentity: "admin",
+		Target:       dbmodel.AppTypeKea,
+		Operation:    "host_update",
+		DaemonIDs:    []int64{1, 2},
+		ObjectType:   "host",
+		ObjectID:     3,
+		Scheduled:    true,
+		DeadlineAt:   createdAt.Add(time.Hour),
+		Recipe: []any{
+			map[string]any{"command": "reservation-del"},
+		},
+		StateBefore: map[string]any{"1": map[string]any{"hostname": "foo"}},
+		StateAfter:  map[string]any{"1": map[string]any{"hostname": "bar"}},
+		Diff: []storkutil.JSONDifference{
+			{Path: "/1/hostname", Before: "foo", After: "bar"},
+		},
+		Error: "some error",
+	}
+
+	entry := newRestConfigChangeLogEntry(dbEntry)
+	require.NotNil(t, entry)
+	require.EqualValues(t, 5, entry.ID)
+	require.Equal(t, createdAt, time.Time(entry.CreatedAt))
+	require.EqualValues(t, 2, entry.UserID)
+	require.Equal(t, "admin", entry.UserIdentity)
+	require.Equal(t, "kea", entry.Target)
+	require.Equal(t, "host_update", entry.Operation)
+	require.Equal(t, []int64{1, 2}, entry.DaemonIds)
+	require.Equal(t, "host", entry.ObjectType)
+	require.EqualValues(t, 3, entry.ObjectID)
+	require.True(t, entry.Scheduled)
+	require.NotNil(t, entry.DeadlineAt)
+	require.Equal(t, createdAt.Add(time.Hour), time.Time(*entry.DeadlineAt))
+	require.Len(t, entry.Recipe, 1)
+	require.Equal(t, dbEntry.StateBefore, entry.StateBefore)
+	require.Equal(t, dbEntry.StateAfter, entry.StateAfter)
+	require.Len(t, entry.Diff, 1)
+	require.Equal(t, &models.ConfigChangeDiff{Path: "/1/hostname", Before: "foo", After: "bar"}, entry.Diff[0])
+	require.Equal(t, "some error", entry.Error)
+}
+
+// Test that the sensitive data are hidden in the config change log entry
+// returned over the REST API.
+func TestNewRestConfigChangeLogEntryHideSensitiveData(t *testing.T) {
+	dbEntry := &dbmodel.ConfigChangeLogEntry{
+		Recipe: []any{
+			map[string]any{
+				"command": "config-set",
+				"arguments": map[string]any{
+					"Dhcp4": map[string]any{
+						"lease-database": map[string]any{"password": "xyz"},
+					},
+				},
+			},
+		},
+		StateBefore: map[string]any{
+			"1": map[string]any{
+				"lease-database": map[string]any{"user": "kea", "password": "xyz"},
+			},
+		},
+		StateAfter: map[string]any{
+			"1": map[string]any{
+				"lease-database": map[string]any{"user": "kea", "password": "abc"},
+			},
+		},
+		Diff: []storkutil.JSONDifference{
+			{Path: "/1/lease-database/password", Before: "xyz", After: "abc"},
+			{Path: "/1/hosts-database", After: map[string]any{"secret": "abc", "type": "mysql"}},
+		},
+	}
+
+	entry := newRestConfigChangeLogEntry(dbEntry)
+	require.NotNil(t, entry)
+
+	require.Equal(t, []any{
+		map[string]any{
+			"command": "config-set",
+			"arguments": map[string]any{
+				"Dhcp4": map[string]any{
+					"lease-database": map[string]any{"password": nil},
+				},
+			},
+		},
+	}, entry.Recipe)
+	require.Equal(t, map[string]any{
+		"1": map[string]any{
+			"lease-database": map[string]any{"user": "kea", "password": nil},
+		},
+	}, entry.StateBefore)
+	require.Equal(t, map[string]any{
+		"1": map[string]any{
+			"lease-database": map[string]any{"user": "kea", "password": nil},
+		},
+	}, entry.StateAfter)
+	require.Len(t, entry.Diff, 2)
+	require.Equal(t, &models.ConfigChangeDiff{Path: "/1/lease-database/password"}, entry.Diff[0])
+	require.Equal(t, &models.ConfigChangeDiff{
+		Path:  "/1/hosts-database",
+		After: map[string]any{"secret": nil, "type": "mysql"},
+	}, entry.Diff[1])
+
+	// The entry fetched from the database is not modified.
+	require.Equal(t, "xyz", dbEntry.Diff[0].Before)
+}
+
+// Test getting the config change log over the REST API.
+func TestGetConfigChangeLog(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	for i := 0; i < 3; i++ {
+		entry := &dbmodel.ConfigChangeLogEntry{
+			Target:     dbmodel.AppTypeKea,
+			Operation:  "host_add",
+			DaemonIDs:  []int64{int64(i + 1)},
+			ObjectType: "host",
+			ObjectID:   int64(i + 10),
+		}
+		err := dbmodel.AddConfigChangeLogEntry(db, entry)
+		require.NoError(t, err)
+	}
+
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Get all entries.
+	rsp := rapi.GetConfigChangeLog(ctx, services.GetConfigChangeLogParams{})
+	require.IsType(t, &services.GetConfigChangeLogOK{}, rsp)
+	okRsp := rsp.(*services.GetConfigChangeLogOK)
+	require.EqualValues(t, 3, okRsp.Payload.Total)
+	require.Len(t, okRsp.Payload.Items, 3)
+	// The most recent entry goes first.
+	require.EqualValues(t, 12, okRsp.Payload.Items[0].ObjectID)
+
+	// Filter by daemon.
+	daemonID := int64(2)
+	rsp = rapi.GetConfigChangeLog(ctx, services.GetConfigChangeLogParams{
+		DaemonID: &daemonID,
+	})
+	require.IsType(t, &services.GetConfigChangeLogOK{}, rsp)
+	okRsp = rsp.(*services.GetConfigChangeLogOK)
+	require.EqualValues(t, 1, okRsp.Payload.Total)
+	require.Len(t, okRsp.Payload.Items, 1)
+	require.EqualValues(t, 11, okRsp.Payload.Items[0].ObjectID)
+
+	// Filter by object.
+	objectType := "host"
+	objectID := int64(10)
+	rsp = rapi.GetConfigChangeLog(ctx, services.GetConfigChangeLogParams{
+		ObjectType: &objectType,
+		ObjectID:   &objectID,
+	})
+	require.IsType(t, &services.GetConfigChangeLogOK{}, rsp)
+	okRsp = rsp.(*services.GetConfigChangeLogOK)
+	require.EqualValues(t, 1, okRsp.Payload.Total)
+	require.Equal(t, []int64{1}, okRsp.Payload.Items[0].DaemonIds)
+
+	// Get a single entry.
+	rsp = rapi.GetConfigChangeLogEntry(ctx, services.GetConfigChangeLogEntryParams{
+		ID: okRsp.Payload.Items[0].ID,
+	})
+	require.IsType(t, &services.GetConfigChangeLogEntryOK{}, rsp)
+	entryRsp := rsp.(*services.GetConfigChangeLogEntryOK)
+	require.EqualValues(t, 10, entryRsp.Payload.ObjectID)
+
+	// Get a non-existing entry.
+	rsp = rapi.GetConfigChangeLogEntry(ctx, services.GetConfigChangeLogEntryParams{
+		ID: 1000,
+	})
+	require.IsType(t, &services.GetConfigChangeLogEntryDefault{}, rsp)
+	defaultRsp := rsp.(*services.GetConfigChangeLogEntryDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*defaultRsp))
+}
+
+// Test converting the scheduled config change to the REST API format.
+func TestNewRestScheduledConfigChange(t *testing.T) {
+	createdAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	recipe := json.RawMessage(`{"hostId": 3}`)
+	dbChange := &dbmodel.ScheduledConfigChange{
+		ID:         4,
+		CreatedAt:  createdAt,
+		DeadlineAt: createdAt.Add(time.Hour),
+		UserID:     2,
+		User: &dbmodel.SystemUser{
+			ID:    2,
+			Login: "operator",
+		},
+		Updates: []*dbmodel.ConfigUpdate{
+			{
+				Target:    dbmodel.AppTypeKea,
+				Operation: "host_update",
+				DaemonIDs: []int64{1, 2},
+				Recipe:    &recipe,
+			},
+		},
+		Executed: true,
+		Error:    "some error",
+	}
+
+	change := newRestScheduledConfigChange(dbChange)
+	require.NotNil(t, change)
+	require.EqualValues(t, 4, change.ID)
+	require.Equal(t, createdAt, time.Time(change.CreatedAt))
+	require.Equal(t, createdAt.Add(time.Hour), time.Time(change.DeadlineAt))
+	require.EqualValues(t, 2, change.UserID)
+	require.Equal(t, "login=operator", change.UserIdentity)
+	require.True(t, change.Executed)
+	require.Equal(t, "some error", change.Error)
+	require.Len(t, change.Updates, 1)
+	require.Equal(t, "kea", change.Updates[0].Target)
+	require.Equal(t, "host_update", change.Updates[0].Operation)
+	require.Equal(t, []int64{1, 2}, change.Updates[0].DaemonIds)
+	require.Equal(t, map[string]any{"hostId": float64(3)}, change.Updates[0].Recipe)
+}
+
+// Test scheduling a host reservation change, listing, inspecting,
+// rescheduling and canceling it over the REST API.
+func TestScheduleHostConfigChange(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	lookup := dbmodel.NewDHCPOptionDefinitionLookup()
+	cm := apps.NewManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    fa,
+		DefLookup: lookup,
+	})
+	require.NotNil(t, cm)
+
+	rapi, err := NewRestAPI(dbSettings, db, fa, cm, lookup)
+	require.NoError(t, err)
+
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+
+	// The scheduled changes must be associated with an existing user.
+	user, err := dbmodel.GetUserByID(db, 1)
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	err = rapi.SessionManager.LoginHandler(ctx, user)
+	require.NoError(t, err)
+
+	_, keaApps := storktestdbmodel.AddTestHosts(t, db)
+
+	// Begin transaction.
+	rsp := rapi.CreateHostBegin(ctx, dhcp.CreateHostBeginParams{})
+	require.IsType(t, &dhcp.CreateHostBeginOK{}, rsp)
+	transactionID := rsp.(*dhcp.CreateHostBeginOK).Payload.ID
+
+	// Schedule the change instead of submitting it.
+	deadline := strfmt.DateTime(storkutil.UTCNow().Add(time.Hour))
+	rsp = rapi.CreateScheduledConfigChange(ctx, services.CreateScheduledConfigChangeParams{
+		Change: &models.NewScheduledConfigChange{
+			TransactionID: &transactionID,
+			DeadlineAt:    &deadline,
+			Host: &models.Host{
+				SubnetID: 1,
+				Hostname: "example.org",
+				HostIdentifiers: []*models.HostIdentifier{
+					{
+						IDType:     "hw-address",
+						IDHexValue: "010203040506",
+					},
+				},
+				LocalHosts: []*models.LocalHost{
+					{
+						DaemonID:   keaApps[0].Daemons[0].ID,
+						DataSource: dbmodel.HostDataSourceAPI.String(),
+					},
+				},
+			},
+		},
+	})
+	require.IsType(t, &services.CreateScheduledConfigChangeOK{}, rsp)
+	change := rsp.(*services.CreateScheduledConfigChangeOK).Payload
+	require.NotZero(t, change.ID)
+	require.False(t, change.Executed)
+	require.Equal(t, user.Identity(), change.UserIdentity)
+	require.WithinDuration(t, time.Time(deadline), time.Time(change.DeadlineAt), time.Millisecond)
+	require.Len(t, change.Updates, 1)
+	require.Equal(t, "host_add", change.Updates[0].Operation)
+
+	// No commands should be sent yet.
+	require.Empty(t, fa.RecordedCommands)
+
+	// The transaction is closed.
+	rsp = rapi.CreateScheduledConfigChange(ctx, services.CreateScheduledConfigChangeParams{
+		Change: &models.NewScheduledConfigChange{
+			TransactionID: &transactionID,
+			DeadlineAt:    &deadline,
+			Host:          &models.Host{},
+		},
+	})
+	require.IsType(t, &services.CreateScheduledConfigChangeDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*services.CreateScheduledConfigChangeDefault)))
+
+	// List pending changes.
+	executed := false
+	rsp = rapi.GetScheduledConfigChanges(ctx, services.GetScheduledConfigChangesParams{
+		Executed: &executed,
+	})
+	require.IsType(t, &services.GetScheduledConfigChangesOK{}, rsp)
+	changes := rsp.(*services.GetScheduledConfigChangesOK).Payload
+	require.EqualValues(t, 1, changes.Total)
+	require.Len(t, changes.Items, 1)
+	require.Equal(t, change.ID, changes.Items[0].ID)
+
+	// Inspect the change.
+	rsp = rapi.GetScheduledConfigChange(ctx, services.GetScheduledConfigChangeParams{
+		ID: change.ID,
+	})
+	require.IsType(t, &services.GetScheduledConfigChangeOK{}, rsp)
+	require.Equal(t, change.ID, rsp.(*services.GetScheduledConfigChangeOK).Payload.ID)
+
+	// Reschedule the change.
+	newDeadline := strfmt.DateTime(storkutil.UTCNow().Add(2 * time.Hour))
+	rsp = rapi.RescheduleConfigChange(ctx, services.RescheduleConfigChangeParams{
+		ID: change.ID,
+		Deadline: &models.ConfigChangeDeadline{
+			DeadlineAt: &newDeadline,
+		},
+	})
+	require.IsType(t, &services.RescheduleConfigChangeOK{}, rsp)
+	rescheduled := rsp.(*services.RescheduleConfigChangeOK).Payload
+	require.WithinDuration(t, time.Time(newDeadline), time.Time(rescheduled.DeadlineAt), time.Millisecond)
+
+	// Cancel the change.
+	rsp = rapi.CancelScheduledConfigChange(ctx, services.CancelScheduledConfigChangeParams{
+		ID: change.ID,
+	})
+	require.IsType(t, &services.CancelScheduledConfigChangeOK{}, rsp)
+
+	rsp = rapi.GetScheduledConfigChange(ctx, services.GetScheduledConfigChangeParams{
+		ID: change.ID,
+	})
+	require.IsType(t, &services.GetScheduledConfigChangeOK{}, rsp)
+	canceled := rsp.(*services.GetScheduledConfigChangeOK).Payload
+	require.True(t, canceled.Executed)
+	require.Contains(t, canceled.Error, "canceled by")
+
+	// The canceled change can't be canceled or rescheduled again.
+	rsp = rapi.CancelScheduledConfigChange(ctx, services.CancelScheduledConfigChangeParams{
+		ID: change.ID,
+	})
+	require.IsType(t, &services.CancelScheduledConfigChangeDefault{}, rsp)
+	require.Equal(t, http.StatusConflict, getStatusCode(*rsp.(*services.CancelScheduledConfigChangeDefault)))
+
+	rsp = rapi.RescheduleConfigChange(ctx, services.RescheduleConfigChangeParams{
+		ID: change.ID,
+		Deadline: &models.ConfigChangeDeadline{
+			DeadlineAt: &newDeadline,
+		},
+	})
+	require.IsType(t, &services.RescheduleConfigChangeDefault{}, rsp)
+	require.Equal(t, http.StatusConflict, getStatusCode(*rsp.(*services.RescheduleConfigChangeDefault)))
+
+	// Non-existing change.
+	rsp = rapi.GetScheduledConfigChange(ctx, services.GetScheduledConfigChangeParams{
+		ID: change.ID + 100,
+	})
+	require.IsType(t, &services.GetScheduledConfigChangeDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*services.GetScheduledConfigChangeDefault)))
+}
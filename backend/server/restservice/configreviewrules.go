@@ -0,0 +1,249 @@
+package restservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	log "github.com/sirupsen/logrus"
+
+	"isc.org/stork/server/configreview"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/services"
+)
+
+// Converts the config review rule fetched from the database to the REST
+// API format.
+func newRestConfigReviewRule(dbRule *dbmodel.ConfigReviewRule) *models.ConfigReviewRule {
+	return &models.ConfigReviewRule{
+		ID:          dbRule.ID,
+		CreatedAt:   strfmt.DateTime(dbRule.CreatedAt),
+		Name:        &dbRule.Name,
+		Description: dbRule.Description,
+		Selector:    &dbRule.Selector,
+		Scope:       &dbRule.Scope,
+		Expression:  &dbRule.Expression,
+	}
+}
+
+// Converts the config review rule received over the REST API to the
+// database model and validates it. It returns an error describing the
+// invalid rule.
+func newDBConfigReviewRule(restRule *models.ConfigReviewRule) (*dbmodel.ConfigReviewRule, error) {
+	if restRule == nil || restRule.Name == nil || restRule.Selector == nil || restRule.Scope == nil || restRule.Expression == nil {
+		return nil, errors.New("config review rule name, selector, scope and expression must be specified")
+	}
+	dbRule := &dbmodel.ConfigReviewRule{
+		Name:        *restRule.Name,
+		Description: restRule.Description,
+		Selector:    *restRule.Selector,
+		Scope:       *restRule.Scope,
+		Expression:  *restRule.Expression,
+	}
+	if err := configreview.ValidateConfigReviewRule(dbRule); err != nil {
+		return nil, err
+	}
+	return dbRule, nil
+}
+
+// Checks if the config checker with the specified name is registered.
+// The user-defined rules share the namespace with the built-in checkers.
+func (r *RestAPI) isConfigCheckerNameTaken(name string) (bool, error) {
+	metadata, err := r.ReviewDispatcher.GetCheckersMetadata(nil)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range metadata {
+		if m.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Returns all user-defined config review rules.
+func (r *RestAPI) GetConfigReviewRules(ctx context.Context, params services.GetConfigReviewRulesParams) middleware.Responder {
+	dbRules, err := dbmodel.GetConfigReviewRules(r.DB)
+	if err != nil {
+		log.WithError(err).Error("Failed to get config review rules from the database")
+		msg := "Problem fetching config review rules from the database"
+		rsp := services.NewGetConfigReviewRulesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rules := &models.ConfigReviewRules{
+		Items: []*models.ConfigReviewRule{},
+		Total: int64(len(dbRules)),
+	}
+	for i := range dbRules {
+		rules.Items = append(rules.Items, newRestConfigReviewRule(&dbRules[i]))
+	}
+
+	rsp := services.NewGetConfigReviewRulesOK().WithPayload(rules)
+	return rsp
+}
+
+// Returns the user-defined config review rule by ID.
+func (r *RestAPI) GetConfigReviewRule(ctx context.Context, params services.GetConfigReviewRuleParams) middleware.Responder {
+	dbRule, err := dbmodel.GetConfigReviewRuleByID(r.DB, params.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to get config review rule %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem fetching config review rule with ID %d from the database", params.ID)
+		rsp := services.NewGetConfigReviewRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbRule == nil {
+		msg := fmt.Sprintf("Cannot find config review rule with ID %d", params.ID)
+		rsp := services.NewGetConfigReviewRuleDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rsp := services.NewGetConfigReviewRuleOK().WithPayload(newRestConfigReviewRule(dbRule))
+	return rsp
+}
+
+// Adds a new user-defined config review rule and registers it in the
+// config review dispatcher.
+func (r *RestAPI) CreateConfigReviewRule(ctx context.Context, params services.CreateConfigReviewRuleParams) middleware.Responder {
+	dbRule, err := newDBConfigReviewRule(params.Rule)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid config review rule: %s", err)
+		rsp := services.NewCreateConfigReviewRuleDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	taken, err := r.isConfigCheckerNameTaken(dbRule.Name)
+	if err == nil && taken {
+		msg := fmt.Sprintf("Config checker %s already exists", dbRule.Name)
+		rsp := services.NewCreateConfigReviewRuleDefault(http.StatusConflict).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if err == nil {
+		err = dbmodel.AddConfigReviewRule(r.DB, dbRule)
+	}
+	if err != nil {
+		log.WithError(err).Errorf("Failed to add config review rule %s to the database", dbRule.Name)
+		msg := fmt.Sprintf("Problem adding config review rule %s to the database", dbRule.Name)
+		rsp := services.NewCreateConfigReviewRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	if err = configreview.RegisterConfigReviewRule(r.ReviewDispatcher, dbRule); err != nil {
+		log.WithError(err).Errorf("Failed to register config review rule %s", dbRule.Name)
+		if _, err := dbmodel.DeleteConfigReviewRule(r.DB, dbRule.ID); err != nil {
+			log.WithError(err).Errorf("Failed to delete unregistered config review rule %s from the database", dbRule.Name)
+		}
+		msg := fmt.Sprintf("Problem registering config review rule %s", dbRule.Name)
+		rsp := services.NewCreateConfigReviewRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rsp := services.NewCreateConfigReviewRuleOK().WithPayload(newRestConfigReviewRule(dbRule))
+	return rsp
+}
+
+// Updates the user-defined config review rule and replaces its checker in
+// the config review dispatcher. The rule name can't be changed.
+func (r *RestAPI) UpdateConfigReviewRule(ctx context.Context, params services.UpdateConfigReviewRuleParams) middleware.Responder {
+	existing, err := dbmodel.GetConfigReviewRuleByID(r.DB, params.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to get config review rule %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem fetching config review rule with ID %d from the database", params.ID)
+		rsp := services.NewUpdateConfigReviewRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if existing == nil {
+		msg := fmt.Sprintf("Cannot find config review rule with ID %d", params.ID)
+		rsp := services.NewUpdateConfigReviewRuleDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	dbRule, err := newDBConfigReviewRule(params.Rule)
+	if err == nil && dbRule.Name != existing.Name {
+		err = errors.New("config review rule name cannot be changed")
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Invalid config review rule: %s", err)
+		rsp := services.NewUpdateConfigReviewRuleDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	dbRule.ID = existing.ID
+
+	err = dbmodel.UpdateConfigReviewRule(r.DB, dbRule)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to update config review rule %d in the database", params.ID)
+		msg := fmt.Sprintf("Problem updating config review rule with ID %d in the database", params.ID)
+		rsp := services.NewUpdateConfigReviewRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	// Replace the checker. Unregistering the checker removes its states
+	// from the dispatcher, so the preferences are loaded again.
+	configreview.UnregisterConfigReviewRule(r.ReviewDispatcher, existing)
+	err = configreview.RegisterConfigReviewRule(r.ReviewDispatcher, dbRule)
+	if err == nil {
+		err = configreview.LoadAndValidateCheckerPreferences(r.DB, r.ReviewDispatcher)
+	}
+	if err != nil {
+		log.WithError(err).Errorf("Failed to register updated config review rule %s", dbRule.Name)
+		msg := fmt.Sprintf("Problem registering updated config review rule %s", dbRule.Name)
+		rsp := services.NewUpdateConfigReviewRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rsp := services.NewUpdateConfigReviewRuleOK().WithPayload(newRestConfigReviewRule(dbRule))
+	return rsp
+}
+
+// Deletes the user-defined config review rule and unregisters it from the
+// config review dispatcher.
+func (r *RestAPI) DeleteConfigReviewRule(ctx context.Context, params services.DeleteConfigReviewRuleParams) middleware.Responder {
+	dbRule, err := dbmodel.DeleteConfigReviewRule(r.DB, params.ID)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrNotExists) {
+			msg := fmt.Sprintf("Cannot find config review rule with ID %d", params.ID)
+			rsp := services.NewDeleteConfigReviewRuleDefault(http.StatusNotFound).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		}
+		log.WithError(err).Errorf("Failed to delete config review rule %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem deleting config review rule with ID %d from the database", params.ID)
+		rsp := services.NewDeleteConfigReviewRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	configreview.UnregisterConfigReviewRule(r.ReviewDispatcher, dbRule)
+
+	rsp := services.NewDeleteConfigReviewRuleOK()
+	return rsp
+}
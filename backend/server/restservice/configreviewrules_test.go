@@ -0,0 +1,164 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"isc.org/stork/server/configreview"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/services"
+)
+
+// Returns the config review rule used in the tests.
+func createTestConfigReviewRule() *models.ConfigReviewRule {
+	name := "subnet_routers"
+	selector := "kea-dhcp-daemon"
+	scope := "subnet"
+	expression := "any(subnet['option-data'], item.name == 'routers')"
+	return &models.ConfigReviewRule{
+		Name:        &name,
+		Description: "every subnet must have option routers",
+		Selector:    &selector,
+		Scope:       &scope,
+		Expression:  &expression,
+	}
+}
+
+// Checks if the config checker with the specified name is registered in
+// the dispatcher.
+func isConfigCheckerRegistered(t *testing.T, dispatcher configreview.Dispatcher, name string) bool {
+	metadata, err := dispatcher.GetCheckersMetadata(nil)
+	require.NoError(t, err)
+	for _, m := range metadata {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Test adding, getting, updating and deleting the config review rules.
+func TestConfigReviewRules(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	dispatcher := configreview.NewDispatcher(db)
+	configreview.RegisterDefaultCheckers(dispatcher)
+	rapi, err := NewRestAPI(dbSettings, db, dispatcher)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Add the rule.
+	rsp := rapi.CreateConfigReviewRule(ctx, services.CreateConfigReviewRuleParams{
+		Rule: createTestConfigReviewRule(),
+	})
+	require.IsType(t, &services.CreateConfigReviewRuleOK{}, rsp)
+	created := rsp.(*services.CreateConfigReviewRuleOK).Payload
+	require.NotZero(t, created.ID)
+	require.True(t, isConfigCheckerRegistered(t, dispatcher, "subnet_routers"))
+
+	// The names must be unique.
+	rsp = rapi.CreateConfigReviewRule(ctx, services.CreateConfigReviewRuleParams{
+		Rule: createTestConfigReviewRule(),
+	})
+	require.IsType(t, &services.CreateConfigReviewRuleDefault{}, rsp)
+	defaultRsp := rsp.(*services.CreateConfigReviewRuleDefault)
+	require.Equal(t, http.StatusConflict, getStatusCode(*defaultRsp))
+
+	// The rule must not replace a built-in checker.
+	restRule := createTestConfigReviewRule()
+	name := "stat_cmds_presence"
+	restRule.Name = &name
+	rsp = rapi.CreateConfigReviewRule(ctx, services.CreateConfigReviewRuleParams{
+		Rule: restRule,
+	})
+	require.IsType(t, &services.CreateConfigReviewRuleDefault{}, rsp)
+	defaultRsp = rsp.(*services.CreateConfigReviewRuleDefault)
+	require.Equal(t, http.StatusConflict, getStatusCode(*defaultRsp))
+
+	// Invalid expression.
+	restRule = createTestConfigReviewRule()
+	name = "other"
+	expression := "any(subnet.pools"
+	restRule.Name = &name
+	restRule.Expression = &expression
+	rsp = rapi.CreateConfigReviewRule(ctx, services.CreateConfigReviewRuleParams{
+		Rule: restRule,
+	})
+	require.IsType(t, &services.CreateConfigReviewRuleDefault{}, rsp)
+	defaultRsp = rsp.(*services.CreateConfigReviewRuleDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+
+	// Get all rules.
+	rsp = rapi.GetConfigReviewRules(ctx, services.GetConfigReviewRulesParams{})
+	require.IsType(t, &services.GetConfigReviewRulesOK{}, rsp)
+	rules := rsp.(*services.GetConfigReviewRulesOK).Payload
+	require.EqualValues(t, 1, rules.Total)
+	require.Len(t, rules.Items, 1)
+	require.Equal(t, "subnet_routers", *rules.Items[0].Name)
+
+	// Disable the rule checker globally.
+	err = dbmodel.CommitCheckerPreferences(db, []*dbmodel.ConfigCheckerPreference{
+		dbmodel.NewGlobalConfigCheckerPreference("subnet_routers"),
+	}, nil)
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.SetCheckerState(nil, "subnet_routers", configreview.CheckerStateDisabled))
+
+	// Update the rule. The checker preference should be preserved.
+	restRule = createTestConfigReviewRule()
+	expression = "any(subnet['option-data'], item.code == 3)"
+	restRule.Expression = &expression
+	rsp = rapi.UpdateConfigReviewRule(ctx, services.UpdateConfigReviewRuleParams{
+		ID:   created.ID,
+		Rule: restRule,
+	})
+	require.IsType(t, &services.UpdateConfigReviewRuleOK{}, rsp)
+
+	metadata, err := dispatcher.GetCheckersMetadata(nil)
+	require.NoError(t, err)
+	for _, m := range metadata {
+		if m.Name == "subnet_routers" {
+			require.False(t, m.GloballyEnabled)
+		}
+	}
+
+	// Get the rule.
+	rsp = rapi.GetConfigReviewRule(ctx, services.GetConfigReviewRuleParams{ID: created.ID})
+	require.IsType(t, &services.GetConfigReviewRuleOK{}, rsp)
+	require.Equal(t, expression, *rsp.(*services.GetConfigReviewRuleOK).Payload.Expression)
+
+	// The rule name cannot be changed.
+	restRule = createTestConfigReviewRule()
+	restRule.Name = &name
+	rsp = rapi.UpdateConfigReviewRule(ctx, services.UpdateConfigReviewRuleParams{
+		ID:   created.ID,
+		Rule: restRule,
+	})
+	require.IsType(t, &services.UpdateConfigReviewRuleDefault{}, rsp)
+	defaultRsp2 := rsp.(*services.UpdateConfigReviewRuleDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp2))
+
+	// Delete the rule.
+	rsp = rapi.DeleteConfigReviewRule(ctx, services.DeleteConfigReviewRuleParams{ID: created.ID})
+	require.IsType(t, &services.DeleteConfigReviewRuleOK{}, rsp)
+	require.False(t, isConfigCheckerRegistered(t, dispatcher, "subnet_routers"))
+
+	preferences, err := dbmodel.GetAllCheckerPreferences(db)
+	require.NoError(t, err)
+	require.Empty(t, preferences)
+
+	// The rule no longer exists.
+	rsp = rapi.GetConfigReviewRule(ctx, services.GetConfigReviewRuleParams{ID: created.ID})
+	require.IsType(t, &services.GetConfigReviewRuleDefault{}, rsp)
+	defaultRsp3 := rsp.(*services.GetConfigReviewRuleDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*defaultRsp3))
+
+	rsp = rapi.DeleteConfigReviewRule(ctx, services.DeleteConfigReviewRuleParams{ID: created.ID})
+	require.IsType(t, &services.DeleteConfigReviewRuleDefault{}, rsp)
+	defaultRsp4 := rsp.(*services.DeleteConfigReviewRuleDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*defaultRsp4))
+}
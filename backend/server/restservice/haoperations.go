@@ -0,0 +1,108 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"isc.org/stork/server/apps/kea"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/services"
+)
+
+// Texts of the events recorded when the HA operations are performed.
+var haOperationEventTexts = map[kea.HAOperation]string{
+	kea.HAOperationMaintenanceStart:  "{user} put {daemon} into the HA maintenance",
+	kea.HAOperationMaintenanceCancel: "{user} canceled the HA maintenance of {daemon}",
+	kea.HAOperationPartnerDown:       "{user} put {daemon} into the HA partner-down state",
+	kea.HAOperationSync:              "{user} synchronized the leases of {daemon} with its HA partner",
+	kea.HAOperationScopes:            "{user} changed the HA scopes served by {daemon}",
+	kea.HAOperationContinue:          "{user} resumed the HA state machine of {daemon}",
+}
+
+// Prepares the operation on the HA service and performs it when it is
+// confirmed. The unconfirmed operation returns the command to be sent and
+// the warnings the user should review. The confirmed operation sends the
+// command to the Kea server and records an event.
+func (r *RestAPI) PerformHAOperation(ctx context.Context, params services.PerformHAOperationParams) middleware.Responder {
+	if params.Operation == nil || params.Operation.Operation == nil || params.Operation.DaemonID == nil {
+		msg := "Missing HA operation or daemon ID"
+		rsp := services.NewPerformHAOperationDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		msg := "Unable to perform the HA operation because user is not logged in"
+		rsp := services.NewPerformHAOperationDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	operation := *params.Operation.Operation
+	plan, err := kea.PlanHAOperation(r.DB, params.ID, *params.Operation.DaemonID, operation, kea.HAOperationParams{
+		Scopes:    params.Operation.Scopes,
+		MaxPeriod: params.Operation.MaxPeriod,
+	})
+	if err != nil {
+		var invalid *kea.InvalidHAOperationError
+		if errors.As(err, &invalid) {
+			msg := fmt.Sprintf("Invalid HA operation: %s", err)
+			rsp := services.NewPerformHAOperationDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		}
+		log.Error(err)
+		msg := fmt.Sprintf("Problem preparing the HA operation %s for the service with ID %d", operation, params.ID)
+		rsp := services.NewPerformHAOperationDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if plan == nil {
+		msg := fmt.Sprintf("Cannot find service with ID %d", params.ID)
+		rsp := services.NewPerformHAOperationDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	result := &models.HAOperationResponse{
+		Operation:      operation,
+		DaemonID:       plan.Daemon.ID,
+		TargetDaemonID: plan.Target.ID,
+		TargetAppID:    plan.Target.App.ID,
+		TargetAppName:  plan.Target.App.Name,
+		Command:        plan.Command.Marshal(),
+		Warnings:       plan.Warnings,
+	}
+	if !params.Operation.Confirm {
+		rsp := services.NewPerformHAOperationOK().WithPayload(result)
+		return rsp
+	}
+
+	text, err := kea.ExecuteHAOperation(ctx, r.Agents, plan)
+	details := fmt.Sprintf("Command sent to the %s server of %s: %s", plan.Target.Name, plan.Target.App.Name, result.Command)
+	if err != nil {
+		log.Error(err)
+		r.EventCenter.AddErrorEvent(fmt.Sprintf("{user} failed to perform the HA %s operation on {daemon}", operation), user, plan.Daemon, fmt.Sprintf("%s\nError: %s", details, err))
+		msg := fmt.Sprintf("Problem performing the HA operation %s: %s", operation, err)
+		rsp := services.NewPerformHAOperationDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	r.EventCenter.AddInfoEvent(haOperationEventTexts[operation], user, plan.Daemon, fmt.Sprintf("%s\nResponse: %s", details, text))
+
+	result.Confirmed = true
+	result.Text = text
+	rsp := services.NewPerformHAOperationOK().WithPayload(result)
+	return rsp
+}
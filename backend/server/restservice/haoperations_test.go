@@ -0,0 +1,174 @@
+package restservice
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	require "github.com/stretchr/testify/require"
+	keactrl "isc.org/stork/appctrl/kea"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/services"
+)
+
+// Adds two Kea servers to the database and the load balancing HA service
+// they belong to.
+func addTestHAService(t *testing.T, db *dbops.PgDB) *dbmodel.Service {
+	var daemons []*dbmodel.Daemon
+	for i, name := range []string{"server1", "server2"} {
+		machine := &dbmodel.Machine{
+			Address:   name,
+			AgentPort: 8080,
+		}
+		err := dbmodel.AddMachine(db, machine)
+		require.NoError(t, err)
+
+		accessPoints := []*dbmodel.AccessPoint{}
+		accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, fmt.Sprintf("192.0.2.%d", i+1), "", 8000, false)
+		daemon := dbmodel.NewKeaDaemon(dbmodel.DaemonNameDHCPv4, true)
+		err = daemon.SetConfigFromJSON(fmt.Sprintf(`{
+			"Dhcp4": {
+				"hooks-libraries": [{
+					"library": "libdhcp_ha.so",
+					"parameters": {
+						"high-availability": [{
+							"this-server-name": "%s",
+							"mode": "load-balancing",
+							"peers": [
+								{"name": "server1", "url": "http://192.0.2.1:8001", "role": "primary"},
+								{"name": "server2", "url": "http://192.0.2.2:8001", "role": "secondary"}
+							]
+						}]
+					}
+				}]
+			}
+		}`, name))
+		require.NoError(t, err)
+		app := &dbmodel.App{
+			Name:         "kea-" + name,
+			MachineID:    machine.ID,
+			Type:         dbmodel.AppTypeKea,
+			AccessPoints: accessPoints,
+			Daemons:      []*dbmodel.Daemon{daemon},
+		}
+		_, err = dbmodel.AddApp(db, app)
+		require.NoError(t, err)
+		daemons = append(daemons, app.Daemons[0])
+	}
+	service := &dbmodel.Service{
+		BaseService: dbmodel.BaseService{
+			ServiceType: "ha_dhcp",
+			Daemons:     daemons,
+		},
+		HAService: &dbmodel.BaseHAService{
+			HAType:             dbmodel.HATypeDhcp4,
+			HAMode:             dbmodel.HAModeLoadBalancing,
+			PrimaryID:          daemons[0].ID,
+			SecondaryID:        daemons[1].ID,
+			PrimaryLastState:   dbmodel.HAStateLoadBalancing,
+			SecondaryLastState: dbmodel.HAStateLoadBalancing,
+			PrimaryReachable:   true,
+			SecondaryReachable: true,
+		},
+	}
+	err := dbmodel.AddService(db, service)
+	require.NoError(t, err)
+	return service
+}
+
+// Test that the HA operation is previewed before it is confirmed and that
+// the confirmed operation sends the command and records an event.
+func TestPerformHAOperation(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	service := addTestHAService(t, db)
+	agents := agentcommtest.NewFakeAgents(func(callNo int, cmdResponses []interface{}) {
+		json := []byte(`[{"result": 0, "text": "Server is in partner-in-maintenance state."}]`)
+		command := keactrl.NewCommand("ha-maintenance-start", []string{"dhcp4"}, nil)
+		_ = keactrl.UnmarshalResponseList(command, json, cmdResponses[0])
+	}, nil)
+	rapi, fec, ctx := setupLeaseRestAPI(t, db, dbSettings, agents)
+
+	operation := "maintenance-start"
+	daemonID := service.HAService.PrimaryID
+	params := services.PerformHAOperationParams{
+		ID: service.ID,
+		Operation: &models.HAOperationRequest{
+			Operation: &operation,
+			DaemonID:  &daemonID,
+		},
+	}
+
+	// Preview the operation.
+	rsp := rapi.PerformHAOperation(ctx, params)
+	require.IsType(t, &services.PerformHAOperationOK{}, rsp)
+	result := rsp.(*services.PerformHAOperationOK).Payload
+	require.False(t, result.Confirmed)
+	require.Equal(t, daemonID, result.DaemonID)
+	require.Equal(t, service.HAService.SecondaryID, result.TargetDaemonID)
+	require.Equal(t, "kea-server2", result.TargetAppName)
+	require.JSONEq(t, `{"command": "ha-maintenance-start", "service": ["dhcp4"]}`, result.Command)
+	require.Empty(t, result.Warnings)
+	require.Empty(t, agents.RecordedCommands)
+	require.Empty(t, fec.Events)
+
+	// Confirm the operation.
+	params.Operation.Confirm = true
+	rsp = rapi.PerformHAOperation(ctx, params)
+	require.IsType(t, &services.PerformHAOperationOK{}, rsp)
+	result = rsp.(*services.PerformHAOperationOK).Payload
+	require.True(t, result.Confirmed)
+	require.Equal(t, "Server is in partner-in-maintenance state.", result.Text)
+	require.Equal(t, []string{"http://192.0.2.2:8000/"}, agents.RecordedURLs)
+	require.Len(t, fec.Events, 1)
+	require.Contains(t, fec.Events[0].Text, "into the HA maintenance")
+	require.Equal(t, daemonID, fec.Events[0].Relations.DaemonID)
+	require.EqualValues(t, 1234, fec.Events[0].Relations.UserID)
+	require.Contains(t, fec.Events[0].Details, "kea-server2")
+}
+
+// Test that the invalid HA operations are rejected.
+func TestPerformHAOperationInvalid(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	service := addTestHAService(t, db)
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, _, ctx := setupLeaseRestAPI(t, db, dbSettings, agents)
+
+	operation := "scopes"
+	daemonID := service.HAService.PrimaryID
+	params := services.PerformHAOperationParams{
+		ID: service.ID,
+		Operation: &models.HAOperationRequest{
+			Operation: &operation,
+			DaemonID:  &daemonID,
+			Scopes:    []string{"server3"},
+		},
+	}
+	rsp := rapi.PerformHAOperation(ctx, params)
+	require.IsType(t, &services.PerformHAOperationDefault{}, rsp)
+	defaultRsp := rsp.(*services.PerformHAOperationDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+
+	// Non-existing service.
+	params.ID = service.ID + 1
+	rsp = rapi.PerformHAOperation(ctx, params)
+	require.IsType(t, &services.PerformHAOperationDefault{}, rsp)
+	defaultRsp = rsp.(*services.PerformHAOperationDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*defaultRsp))
+
+	// Missing daemon ID.
+	params.Operation.DaemonID = nil
+	rsp = rapi.PerformHAOperation(ctx, params)
+	require.IsType(t, &services.PerformHAOperationDefault{}, rsp)
+	defaultRsp = rsp.(*services.PerformHAOperationDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+
+	require.Empty(t, agents.RecordedCommands)
+}
@@ -1,10 +1,13 @@
 package restservice
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/go-openapi/runtime/middleware"
@@ -16,6 +19,8 @@ import (
 	dbmodel "isc.org/stork/server/database/model"
 	"isc.org/stork/server/gen/models"
 	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	"isc.org/stork/server/hostsexport"
+	"isc.org/stork/server/hostsimport"
 	storkutil "isc.org/stork/util"
 )
 
@@ -136,7 +141,7 @@ func (r *RestAPI) convertToHost(restHost *models.Host) (*dbmodel.Host, error) {
 
 // Fetches host reservations from the database and converts to the data formats
 // used in REST API.
-func (r *RestAPI) getHosts(offset, limit, appID int64, subnetID *int64, localSubnetID *int64, filterText *string, global *bool, sortField string, sortDir dbmodel.SortDirEnum) (*models.Hosts, error) {
+func (r *RestAPI) getHosts(offset, limit, appID int64, subnetID *int64, localSubnetID *int64, filterText *string, global *bool, scope *dbmodel.ScopeFilter, sortField string, sortDir dbmodel.SortDirEnum) (*models.Hosts, error) {
 	// Get the hosts from the database.
 	filters := dbmodel.HostsByPageFilters{
 		AppID:         &appID,
@@ -144,6 +149,7 @@ func (r *RestAPI) getHosts(offset, limit, appID int64, subnetID *int64, localSub
 		LocalSubnetID: localSubnetID,
 		FilterText:    filterText,
 		Global:        global,
+		Scope:         scope,
 	}
 	dbHosts, total, err := dbmodel.GetHostsByPage(r.DB, offset, limit, filters, sortField, sortDir)
 	if err != nil {
@@ -182,7 +188,7 @@ func (r *RestAPI) GetHosts(ctx context.Context, params dhcp.GetHostsParams) midd
 	}
 
 	// get hosts from db
-	hosts, err := r.getHosts(start, limit, appID, params.SubnetID, params.LocalSubnetID, params.Text, params.Global, "", dbmodel.SortDirAny)
+	hosts, err := r.getHosts(start, limit, appID, params.SubnetID, params.LocalSubnetID, params.Text, params.Global, getScopeFilter(ctx), "", dbmodel.SortDirAny)
 	if err != nil {
 		msg := "Problem fetching hosts from the database"
 		log.Error(err)
@@ -381,42 +387,70 @@ func (r *RestAPI) commonCreateOrUpdateHostSubmit(ctx context.Context, transactio
 		return http.StatusNotFound, msg
 	}
 
+	// Apply the host information in the transaction.
+	cctx, code, msg := r.applyHost(ctx, cctx, restHost, applyFunc)
+	if code != 0 {
+		return code, msg
+	}
+	// Send the commands to Kea servers.
+	cctx, err := r.ConfigManager.Commit(cctx)
+	if err != nil {
+		msg := fmt.Sprintf("problem with committing host information: %s", err)
+		log.Error(err)
+		return http.StatusConflict, msg
+	}
+	// Everything ok. Cleanup and send OK to the client.
+	r.ConfigManager.Done(cctx)
+	return 0, ""
+}
+
+// Converts the host reservation specified by the user to the database
+// model and applies it in the transaction using the specified apply
+// function (ApplyHostAdd or ApplyHostUpdate). It returns the updated
+// transaction context. The request context is used to verify that the
+// host reservation belongs to the user's scope. If an error occurs, it
+// returns an HTTP error code and an error string to be included in the
+// HTTP response.
+func (r *RestAPI) applyHost(ctx, cctx context.Context, restHost *models.Host, applyFunc func(context.Context, *dbmodel.Host) (context.Context, error)) (context.Context, int, string) {
 	// Convert host information from REST API to database format.
 	host, err := r.convertToHost(restHost)
 	if err != nil {
 		msg := "error parsing specified host reservation"
 		log.Error(err)
-		return http.StatusBadRequest, msg
+		return cctx, http.StatusBadRequest, msg
 	}
 	err = host.PopulateDaemons(r.DB)
 	if err != nil {
 		msg := "specified host is associated with daemons that no longer exist"
 		log.Error(err)
-		return http.StatusNotFound, msg
+		return cctx, http.StatusNotFound, msg
 	}
 	err = host.PopulateSubnet(r.DB)
 	if err != nil {
 		msg := "problem with retrieving subnet association with the host"
 		log.Error(err)
-		return http.StatusInternalServerError, msg
+		return cctx, http.StatusInternalServerError, msg
+	}
+	// Make sure that the user does not add the host to the daemons and
+	// subnets out of the user's scope.
+	var daemonIDs, subnetIDs []int64
+	for _, lh := range host.LocalHosts {
+		daemonIDs = append(daemonIDs, lh.DaemonID)
+	}
+	if host.SubnetID != 0 {
+		subnetIDs = append(subnetIDs, host.SubnetID)
+	}
+	if code, msg := r.checkObjectsInScope(ctx, daemonIDs, subnetIDs); code != 0 {
+		return cctx, code, msg
 	}
 	// Apply the host information (create Kea commands).
 	cctx, err = applyFunc(cctx, host)
 	if err != nil {
 		msg := "problem with applying host information"
 		log.Error(err)
-		return http.StatusInternalServerError, msg
+		return cctx, http.StatusInternalServerError, msg
 	}
-	// Send the commands to Kea servers.
-	cctx, err = r.ConfigManager.Commit(cctx)
-	if err != nil {
-		msg := fmt.Sprintf("problem with committing host information: %s", err)
-		log.Error(err)
-		return http.StatusConflict, msg
-	}
-	// Everything ok. Cleanup and send OK to the client.
-	r.ConfigManager.Done(cctx)
-	return 0, ""
+	return cctx, 0, ""
 }
 
 // Implements the POST call to apply and commit host reservation (hosts/new/transaction/{id}/submit).
@@ -433,12 +467,12 @@ func (r *RestAPI) CreateHostSubmit(ctx context.Context, params dhcp.CreateHostSu
 }
 
 // Common function that implements the DELETE calls to cancel adding new
-// or updating a host reservation. It removes the specified transaction
-// from the config manager, if the transaction exists. It  returns the
+// or updating a host reservation or a subnet. It removes the specified
+// transaction from the config manager, if the transaction exists. It returns the
 // HTTP error code if an error occurs or 0 when there is no error.
 // In addition it returns an error string to be included in the HTTP response
 // or an empty string if there is no error.
-func (r *RestAPI) commonCreateOrUpdateHostDelete(ctx context.Context, transactionID int64) (int, string) {
+func (r *RestAPI) commonCreateOrUpdateDelete(ctx context.Context, transactionID int64) (int, string) {
 	// Get the user ID and recover the transaction context.
 	ok, user := r.SessionManager.Logged(ctx)
 	if !ok {
@@ -460,7 +494,7 @@ func (r *RestAPI) commonCreateOrUpdateHostDelete(ctx context.Context, transactio
 // Implements the DELETE call to cancel adding new reservation (hosts/new/transaction/{id}). It
 // removes the specified transaction from the config manager, if the transaction exists.
 func (r *RestAPI) CreateHostDelete(ctx context.Context, params dhcp.CreateHostDeleteParams) middleware.Responder {
-	if code, msg := r.commonCreateOrUpdateHostDelete(ctx, params.ID); code != 0 {
+	if code, msg := r.commonCreateOrUpdateDelete(ctx, params.ID); code != 0 {
 		// Error case.
 		rsp := dhcp.NewCreateHostDeleteDefault(code).WithPayload(&models.APIError{
 			Message: &msg,
@@ -564,7 +598,7 @@ func (r *RestAPI) UpdateHostSubmit(ctx context.Context, params dhcp.UpdateHostSu
 // Implements the DELETE call to cancel updating host reservation (hosts/{hostId}/transaction/{id}).
 // It removes the specified transaction from the config manager, if the transaction exists.
 func (r *RestAPI) UpdateHostDelete(ctx context.Context, params dhcp.UpdateHostDeleteParams) middleware.Responder {
-	if code, msg := r.commonCreateOrUpdateHostDelete(ctx, params.ID); code != 0 {
+	if code, msg := r.commonCreateOrUpdateDelete(ctx, params.ID); code != 0 {
 		// Error case.
 		rsp := dhcp.NewUpdateHostDeleteDefault(code).WithPayload(&models.APIError{
 			Message: &msg,
@@ -644,3 +678,115 @@ func (r *RestAPI) DeleteHost(ctx context.Context, params dhcp.DeleteHostParams)
 	rsp := dhcp.NewDeleteHostOK()
 	return rsp
 }
+
+// Imports host reservations from the CSV or Kea JSON file contents. The
+// reservations are validated and the valid ones are added to the DHCP
+// servers in batches. The errors in the individual rows are returned in
+// the response.
+func (r *RestAPI) ImportHosts(ctx context.Context, params dhcp.ImportHostsParams) middleware.Responder {
+	request := params.HostsImport
+	if request == nil || request.Format == nil || request.Contents == nil {
+		msg := "Missing hosts import format or contents"
+		rsp := dhcp.NewImportHostsDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Get the logged user's ID.
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		msg := "Unable to import host reservations because user is not logged in"
+		log.Error("Problem with importing host reservations because user has no session")
+		rsp := dhcp.NewImportHostsDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rows, err := hostsimport.Parse(*request.Format, strings.NewReader(*request.Contents))
+	if err != nil {
+		msg := fmt.Sprintf("Problem with parsing host reservations: %s", err)
+		log.Error(err)
+		rsp := dhcp.NewImportHostsDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	importer := hostsimport.NewImporter(r.DB, r.ConfigManager, r.DHCPOptionDefinitionLookup)
+	result, err := importer.Import(int64(user.ID), rows, hostsimport.Options{
+		DaemonIDs: request.DaemonIds,
+		DryRun:    request.DryRun,
+		BatchSize: int(request.BatchSize),
+	})
+	if err != nil {
+		msg := "Problem with importing host reservations"
+		log.Error(err)
+		rsp := dhcp.NewImportHostsDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	payload := &models.HostsImportResult{
+		Total:    int64(result.Total),
+		Valid:    int64(result.Valid),
+		Imported: int64(result.Imported),
+	}
+	for _, rowError := range result.Errors {
+		payload.Errors = append(payload.Errors, &models.HostsImportRowError{
+			Row:     int64(rowError.Row),
+			Message: rowError.Message,
+		})
+	}
+	rsp := dhcp.NewImportHostsOK().WithPayload(payload)
+	return rsp
+}
+
+// Exports host reservations matching the specified filters in the Kea
+// JSON, CSV or ISC DHCP format. The exported data is returned as an
+// attachment.
+func (r *RestAPI) ExportHosts(ctx context.Context, params dhcp.ExportHostsParams) middleware.Responder {
+	var contentType, extension string
+	switch params.Format {
+	case hostsexport.FormatKea:
+		contentType, extension = "application/json", "json"
+	case hostsexport.FormatCSV:
+		contentType, extension = "text/csv", "csv"
+	case hostsexport.FormatDhcpd:
+		contentType, extension = "text/plain", "conf"
+	default:
+		msg := fmt.Sprintf("Unsupported hosts export format %s", params.Format)
+		rsp := dhcp.NewExportHostsDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	filters := dbmodel.HostsByPageFilters{
+		AppID:         params.AppID,
+		SubnetID:      params.SubnetID,
+		LocalSubnetID: params.LocalSubnetID,
+		FilterText:    params.Text,
+		Global:        params.Global,
+	}
+	var buffer bytes.Buffer
+	exporter := hostsexport.NewExporter(r.DB, r.DHCPOptionDefinitionLookup)
+	if err := exporter.Export(&buffer, params.Format, filters); err != nil {
+		msg := "Problem with exporting host reservations"
+		log.Error(err)
+		rsp := dhcp.NewExportHostsDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	dispositionHeaderValue := fmt.Sprintf(
+		"attachment; filename=\"stork-hosts_%s.%s\"",
+		strings.ReplaceAll(time.Now().UTC().Format(time.RFC3339), ":", "-"),
+		extension,
+	)
+
+	rsp := dhcp.
+		NewExportHostsOK().
+		WithContentType(contentType).
+		WithContentDisposition(dispositionHeaderValue).
+		WithPayload(io.NopCloser(&buffer))
+	return rsp
+}
@@ -2,7 +2,9 @@ package restservice
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -1604,3 +1606,151 @@ func TestDeleteHostError(t *testing.T) {
 		require.Equal(t, http.StatusForbidden, getStatusCode(*defaultRsp))
 	})
 }
+
+// Test importing host reservations over the REST API.
+func TestImportHosts(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	lookup := dbmodel.NewDHCPOptionDefinitionLookup()
+	cm := apps.NewManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    fa,
+		DefLookup: lookup,
+	})
+	rapi, err := NewRestAPI(dbSettings, db, fa, cm, lookup)
+	require.NoError(t, err)
+
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+
+	storktestdbmodel.AddTestHosts(t, db)
+
+	format := "csv"
+	contents := `identifier-type,identifier,ip-addresses,subnet
+hw-address,0a:0b:0c:0d:0e:0f,192.0.2.10,192.0.2.0/24
+hw-address,01:02:03:04:05:06,192.0.2.11,192.0.2.0/24`
+	params := dhcp.ImportHostsParams{
+		HostsImport: &models.HostsImportRequest{
+			Format:   &format,
+			Contents: &contents,
+		},
+	}
+
+	t.Run("no session", func(t *testing.T) {
+		rsp := rapi.ImportHosts(ctx, params)
+		require.IsType(t, &dhcp.ImportHostsDefault{}, rsp)
+		defaultRsp := rsp.(*dhcp.ImportHostsDefault)
+		require.Equal(t, http.StatusForbidden, getStatusCode(*defaultRsp))
+	})
+
+	user := &dbmodel.SystemUser{
+		ID: 1234,
+	}
+	err = rapi.SessionManager.LoginHandler(ctx, user)
+	require.NoError(t, err)
+
+	t.Run("invalid file", func(t *testing.T) {
+		invalidFormat := "xml"
+		rsp := rapi.ImportHosts(ctx, dhcp.ImportHostsParams{
+			HostsImport: &models.HostsImportRequest{
+				Format:   &invalidFormat,
+				Contents: &contents,
+			},
+		})
+		require.IsType(t, &dhcp.ImportHostsDefault{}, rsp)
+		defaultRsp := rsp.(*dhcp.ImportHostsDefault)
+		require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		dryRunParams := params
+		dryRunParams.HostsImport.DryRun = true
+		defer func() { dryRunParams.HostsImport.DryRun = false }()
+
+		rsp := rapi.ImportHosts(ctx, dryRunParams)
+		require.IsType(t, &dhcp.ImportHostsOK{}, rsp)
+		payload := rsp.(*dhcp.ImportHostsOK).Payload
+		require.EqualValues(t, 2, payload.Total)
+		require.EqualValues(t, 1, payload.Valid)
+		require.Zero(t, payload.Imported)
+		require.Empty(t, fa.RecordedCommands)
+	})
+
+	t.Run("import", func(t *testing.T) {
+		rsp := rapi.ImportHosts(ctx, params)
+		require.IsType(t, &dhcp.ImportHostsOK{}, rsp)
+		payload := rsp.(*dhcp.ImportHostsOK).Payload
+		require.EqualValues(t, 2, payload.Total)
+		require.EqualValues(t, 1, payload.Valid)
+		require.EqualValues(t, 1, payload.Imported)
+		require.Len(t, payload.Errors, 1)
+		require.EqualValues(t, 3, payload.Errors[0].Row)
+		require.Contains(t, payload.Errors[0].Message, "already exists")
+
+		// The reservation should be sent to both DHCPv4 servers.
+		require.Len(t, fa.RecordedCommands, 2)
+
+		hosts, err := dbmodel.GetHostsByIdentifier(db, "hw-address", []byte{0xa, 0xb, 0xc, 0xd, 0xe, 0xf}, 1)
+		require.NoError(t, err)
+		require.Len(t, hosts, 1)
+	})
+}
+
+// Test exporting host reservations over the REST API.
+func TestExportHosts(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	lookup := dbmodel.NewDHCPOptionDefinitionLookup()
+	rapi, err := NewRestAPI(dbSettings, db, lookup)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, apps := storktestdbmodel.AddTestHosts(t, db)
+
+	t.Run("csv", func(t *testing.T) {
+		rsp := rapi.ExportHosts(ctx, dhcp.ExportHostsParams{
+			Format: "csv",
+			AppID:  &apps[0].ID,
+		})
+		require.IsType(t, &dhcp.ExportHostsOK{}, rsp)
+		okRsp := rsp.(*dhcp.ExportHostsOK)
+		require.Equal(t, "text/csv", okRsp.ContentType)
+		require.Regexp(t, `^attachment; filename="stork-hosts_.*\.csv"$`, okRsp.ContentDisposition)
+
+		contents, err := io.ReadAll(okRsp.Payload)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+		// Header and five hosts of the first app.
+		require.Len(t, lines, 6)
+		require.True(t, strings.HasPrefix(lines[0], "daemon-id,daemon-name,app-name"))
+	})
+
+	t.Run("kea", func(t *testing.T) {
+		global := true
+		rsp := rapi.ExportHosts(ctx, dhcp.ExportHostsParams{
+			Format: "kea",
+			Global: &global,
+		})
+		require.IsType(t, &dhcp.ExportHostsOK{}, rsp)
+		okRsp := rsp.(*dhcp.ExportHostsOK)
+		require.Equal(t, "application/json", okRsp.ContentType)
+		require.Regexp(t, `\.json"$`, okRsp.ContentDisposition)
+
+		contents, err := io.ReadAll(okRsp.Payload)
+		require.NoError(t, err)
+		require.NotContains(t, string(contents), "subnet4")
+		require.NotContains(t, string(contents), "subnet6")
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		rsp := rapi.ExportHosts(ctx, dhcp.ExportHostsParams{
+			Format: "xml",
+		})
+		require.IsType(t, &dhcp.ExportHostsDefault{}, rsp)
+		defaultRsp := rsp.(*dhcp.ExportHostsDefault)
+		require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+	})
+}
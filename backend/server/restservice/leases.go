@@ -2,19 +2,62 @@ package restservice
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
 
 	"github.com/go-openapi/runtime/middleware"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	keadata "isc.org/stork/appdata/kea"
 	"isc.org/stork/server/apps/kea"
 	dbmodel "isc.org/stork/server/database/model"
 	"isc.org/stork/server/gen/models"
 	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
 )
 
+// Converts a lease fetched from Kea to the format used in REST API.
+func convertLeaseToRestAPI(l dbmodel.Lease) *models.Lease {
+	var appName string
+	if l.App != nil {
+		appName = l.App.Name
+	}
+	cltt := int64(l.CLTT)
+	state := int64(l.State)
+	subnetID := int64(l.SubnetID)
+	validLifetime := int64(l.ValidLifetime)
+
+	// Handle a special case when returned DUID is equal to 00. Kea returns such DUID
+	// in declined DHCPv6 leases. We treat is as empty DUID.
+	duid := ""
+	if len(l.DUID) > 0 && l.DUID != "00" {
+		duid = l.DUID
+	}
+	return &models.Lease{
+		ID:                &l.ID,
+		AppID:             &l.AppID,
+		AppName:           &appName,
+		ClientID:          l.ClientID,
+		Cltt:              &cltt,
+		Duid:              duid,
+		FqdnFwd:           l.FqdnFwd,
+		FqdnRev:           l.FqdnRev,
+		Hostname:          l.Hostname,
+		HwAddress:         l.HWAddress,
+		Iaid:              int64(l.IAID),
+		IPAddress:         &l.IPAddress,
+		LeaseType:         l.Type,
+		PreferredLifetime: int64(l.PreferredLifetime),
+		PrefixLength:      int64(l.PrefixLength),
+		State:             &state,
+		SubnetID:          &subnetID,
+		ValidLifetime:     &validLifetime,
+		UserContext:       l.UserContext,
+	}
+}
+
 // This call searches for leases allocated by monitored DHCP servers.
 // The text parameter may contain an IP address, delegated prefix,
 // MAC address, client identifier, hostname or the text state:declined.
@@ -77,44 +120,7 @@ func (r *RestAPI) GetLeases(ctx context.Context, params dhcp.GetLeasesParams) mi
 
 	// Return leases over the REST API.
 	for i := range keaLeases {
-		l := keaLeases[i]
-		var appName string
-		if l.App != nil {
-			appName = l.App.Name
-		}
-		cltt := int64(l.CLTT)
-		state := int64(l.State)
-		subnetID := int64(l.SubnetID)
-		validLifetime := int64(l.ValidLifetime)
-
-		// Handle a special case when returned DUID is equal to 00. Kea returns such DUID
-		// in declined DHCPv6 leases. We treat is as empty DUID.
-		duid := ""
-		if len(l.DUID) > 0 && l.DUID != "00" {
-			duid = l.DUID
-		}
-		lease := models.Lease{
-			ID:                &l.ID,
-			AppID:             &l.AppID,
-			AppName:           &appName,
-			ClientID:          l.ClientID,
-			Cltt:              &cltt,
-			Duid:              duid,
-			FqdnFwd:           l.FqdnFwd,
-			FqdnRev:           l.FqdnRev,
-			Hostname:          l.Hostname,
-			HwAddress:         l.HWAddress,
-			Iaid:              int64(l.IAID),
-			IPAddress:         &l.IPAddress,
-			LeaseType:         l.Type,
-			PreferredLifetime: int64(l.PreferredLifetime),
-			PrefixLength:      int64(l.PrefixLength),
-			State:             &state,
-			SubnetID:          &subnetID,
-			ValidLifetime:     &validLifetime,
-			UserContext:       l.UserContext,
-		}
-		leases.Items = append(leases.Items, &lease)
+		leases.Items = append(leases.Items, convertLeaseToRestAPI(keaLeases[i]))
 	}
 
 	// Record conflicting leases and leases count.
@@ -132,3 +138,248 @@ func (r *RestAPI) GetLeases(ctx context.Context, params dhcp.GetLeasesParams) mi
 	rsp := dhcp.NewGetLeasesOK().WithPayload(leases)
 	return rsp
 }
+
+// Converts a lease received over the REST API to the format used by Kea.
+func convertLeaseFromRestAPI(restLease *models.LeaseChange) (*keadata.Lease, error) {
+	lease := &keadata.Lease{
+		ClientID:          restLease.ClientID,
+		CLTT:              uint64(restLease.Cltt),
+		DUID:              restLease.Duid,
+		FqdnFwd:           restLease.FqdnFwd,
+		FqdnRev:           restLease.FqdnRev,
+		Hostname:          restLease.Hostname,
+		HWAddress:         restLease.HwAddress,
+		IAID:              uint32(restLease.Iaid),
+		IPAddress:         *restLease.IPAddress,
+		PreferredLifetime: uint32(restLease.PreferredLifetime),
+		PrefixLength:      uint8(restLease.PrefixLength),
+		State:             int(restLease.State),
+		SubnetID:          uint32(restLease.SubnetID),
+		Type:              restLease.LeaseType,
+		ValidLifetime:     uint32(restLease.ValidLifetime),
+	}
+	if restLease.UserContext != nil {
+		userContext, ok := restLease.UserContext.(map[string]any)
+		if !ok {
+			return nil, errors.New("lease user context must be a map")
+		}
+		lease.UserContext = userContext
+	}
+	return lease, nil
+}
+
+// Common function fetching the app holding the lease being modified and
+// the logged user. It returns an HTTP status code and an error message
+// if the app or the user could not be fetched.
+func (r *RestAPI) getLeaseAppAndUser(ctx context.Context, appID int64) (*dbmodel.App, *dbmodel.SystemUser, int, string) {
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		return nil, nil, http.StatusForbidden, "Unable to modify the lease because user is not logged in"
+	}
+	app, err := dbmodel.GetAppByID(r.DB, appID)
+	if err != nil {
+		log.Error(err)
+		return nil, nil, http.StatusInternalServerError, fmt.Sprintf("Problem fetching app with ID %d from the database", appID)
+	}
+	if app == nil || app.Type != dbmodel.AppTypeKea {
+		return nil, nil, http.StatusBadRequest, fmt.Sprintf("Cannot find Kea app with ID %d", appID)
+	}
+	return app, user, http.StatusOK, ""
+}
+
+// Adds a lease on the Kea server using the lease4-add or lease6-add command.
+func (r *RestAPI) AddLease(ctx context.Context, params dhcp.AddLeaseParams) middleware.Responder {
+	if params.Lease == nil || params.Lease.AppID == nil || params.Lease.IPAddress == nil {
+		msg := "Missing app ID or IP address of the added lease"
+		rsp := dhcp.NewAddLeaseDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	lease, err := convertLeaseFromRestAPI(params.Lease)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid lease: %s", err)
+		rsp := dhcp.NewAddLeaseDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	app, user, status, msg := r.getLeaseAppAndUser(ctx, *params.Lease.AppID)
+	if app == nil {
+		rsp := dhcp.NewAddLeaseDefault(status).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if err = kea.AddLease(r.Agents, app, lease); err != nil {
+		msg := fmt.Sprintf("Problem adding lease %s: %s", lease.IPAddress, err)
+		log.Error(err)
+		rsp := dhcp.NewAddLeaseDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	r.EventCenter.AddInfoEvent(fmt.Sprintf("{user} added lease %s in {app}", lease.IPAddress), user, app)
+	rsp := dhcp.NewAddLeaseOK()
+	return rsp
+}
+
+// Updates a lease on the Kea server using the lease4-update or lease6-update
+// command.
+func (r *RestAPI) UpdateLease(ctx context.Context, params dhcp.UpdateLeaseParams) middleware.Responder {
+	if params.Lease == nil || params.Lease.AppID == nil || params.Lease.IPAddress == nil {
+		msg := "Missing app ID or IP address of the updated lease"
+		rsp := dhcp.NewUpdateLeaseDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	lease, err := convertLeaseFromRestAPI(params.Lease)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid lease: %s", err)
+		rsp := dhcp.NewUpdateLeaseDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	app, user, status, msg := r.getLeaseAppAndUser(ctx, *params.Lease.AppID)
+	if app == nil {
+		rsp := dhcp.NewUpdateLeaseDefault(status).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if err = kea.UpdateLease(r.Agents, app, lease, params.Lease.ForceCreate); err != nil {
+		msg := fmt.Sprintf("Problem updating lease %s: %s", lease.IPAddress, err)
+		log.Error(err)
+		rsp := dhcp.NewUpdateLeaseDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	r.EventCenter.AddInfoEvent(fmt.Sprintf("{user} updated lease %s in {app}", lease.IPAddress), user, app)
+	rsp := dhcp.NewUpdateLeaseOK()
+	return rsp
+}
+
+// Deletes a lease from the Kea server using the lease4-del or lease6-del
+// command.
+func (r *RestAPI) DeleteLease(ctx context.Context, params dhcp.DeleteLeaseParams) middleware.Responder {
+	app, user, status, msg := r.getLeaseAppAndUser(ctx, params.AppID)
+	if app == nil {
+		rsp := dhcp.NewDeleteLeaseDefault(status).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	var leaseType string
+	if params.LeaseType != nil {
+		leaseType = *params.LeaseType
+	}
+	deleted, err := kea.DeleteLease(r.Agents, app, leaseType, params.IPAddress)
+	if err != nil {
+		msg := fmt.Sprintf("Problem deleting lease %s: %s", params.IPAddress, err)
+		log.Error(err)
+		rsp := dhcp.NewDeleteLeaseDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if !deleted {
+		msg := fmt.Sprintf("Cannot find lease %s", params.IPAddress)
+		rsp := dhcp.NewDeleteLeaseDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	r.EventCenter.AddInfoEvent(fmt.Sprintf("{user} deleted lease %s from {app}", params.IPAddress), user, app)
+	rsp := dhcp.NewDeleteLeaseOK()
+	return rsp
+}
+
+// Deletes all leases in the subnet from the Kea servers serving it using
+// the lease4-wipe or lease6-wipe command.
+func (r *RestAPI) WipeLeases(ctx context.Context, params dhcp.WipeLeasesParams) middleware.Responder {
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		msg := "Unable to wipe leases because user is not logged in"
+		rsp := dhcp.NewWipeLeasesDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	subnet, wipedDaemons, erredDaemons, err := kea.WipeSubnetLeases(r.DB, r.Agents, params.SubnetID)
+	if err != nil {
+		msg := fmt.Sprintf("Problem wiping leases in subnet with ID %d", params.SubnetID)
+		log.Error(err)
+		rsp := dhcp.NewWipeLeasesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if subnet == nil {
+		msg := fmt.Sprintf("Cannot find subnet with ID %d", params.SubnetID)
+		rsp := dhcp.NewWipeLeasesDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	result := &models.LeasesWipeResult{}
+	var details []string
+	for _, daemon := range wipedDaemons {
+		result.WipedDaemonIds = append(result.WipedDaemonIds, daemon.ID)
+	}
+	for _, daemon := range erredDaemons {
+		result.ErredDaemonIds = append(result.ErredDaemonIds, daemon.ID)
+		details = append(details, fmt.Sprintf("failed to wipe leases in %s daemon with ID %d", daemon.Name, daemon.ID))
+	}
+	if len(wipedDaemons) > 0 || len(erredDaemons) > 0 {
+		r.EventCenter.AddWarningEvent("{user} wiped leases in {subnet}", user, subnet, strings.Join(details, "\n"))
+	}
+	rsp := dhcp.NewWipeLeasesOK().WithPayload(result)
+	return rsp
+}
+
+// Finds the declined leases and deletes them from the Kea servers, so
+// the declined addresses can be allocated again.
+func (r *RestAPI) ReclaimDeclinedLeases(ctx context.Context, params dhcp.ReclaimDeclinedLeasesParams) middleware.Responder {
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		msg := "Unable to reclaim declined leases because user is not logged in"
+		rsp := dhcp.NewReclaimDeclinedLeasesDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	var appID int64
+	if params.AppID != nil {
+		appID = *params.AppID
+	}
+	reclaimed, erredApps, err := kea.ReclaimDeclinedLeases(r.DB, r.Agents, appID)
+	if err != nil {
+		msg := "Problem reclaiming declined leases due to Stork database errors"
+		log.Error(err)
+		rsp := dhcp.NewReclaimDeclinedLeasesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	leases := &models.Leases{}
+	var addresses []string
+	for i := range reclaimed {
+		leases.Items = append(leases.Items, convertLeaseToRestAPI(reclaimed[i]))
+		addresses = append(addresses, reclaimed[i].IPAddress)
+	}
+	leases.Total = int64(len(leases.Items))
+	for i := range erredApps {
+		leases.ErredApps = append(leases.ErredApps, &models.LeasesSearchErredApp{
+			ID:   &erredApps[i].ID,
+			Name: &erredApps[i].Name,
+		})
+	}
+	if len(reclaimed) > 0 {
+		r.EventCenter.AddInfoEvent(fmt.Sprintf("{user} reclaimed %d declined leases", len(reclaimed)), user, strings.Join(addresses, ", "))
+	}
+	rsp := dhcp.NewReclaimDeclinedLeasesOK().WithPayload(leases)
+	return rsp
+}
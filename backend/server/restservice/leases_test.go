@@ -2,14 +2,18 @@ package restservice
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	keactrl "isc.org/stork/appctrl/kea"
 	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbops "isc.org/stork/server/database"
 	dbmodel "isc.org/stork/server/database/model"
 	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
 	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storktestdbmodel "isc.org/stork/server/test/dbmodel"
 )
 
 // Generates a success mock response to a command fetching a DHCPv4
@@ -716,3 +720,297 @@ func TestFindLeasesByHostID(t *testing.T) {
 	require.Len(t, okRsp.Payload.Conflicts, 1)
 	require.EqualValues(t, *okRsp.Payload.Items[1].ID, okRsp.Payload.Conflicts[0])
 }
+
+// Adds a Kea app with the DHCPv4 and DHCPv6 daemons using the lease_cmds
+// hooks library. It is used in the tests of the calls modifying leases.
+func addTestLeaseApp(t *testing.T, db *dbops.PgDB) *dbmodel.App {
+	machine := &dbmodel.Machine{
+		Address:   "machine",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, machine)
+	require.NoError(t, err)
+
+	accessPoints := []*dbmodel.AccessPoint{}
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, true)
+	app := &dbmodel.App{
+		Name:         "kea",
+		MachineID:    machine.ID,
+		Type:         dbmodel.AppTypeKea,
+		AccessPoints: accessPoints,
+	}
+	for _, name := range []string{dbmodel.DaemonNameDHCPv4, dbmodel.DaemonNameDHCPv6} {
+		rootName := "Dhcp4"
+		if name == dbmodel.DaemonNameDHCPv6 {
+			rootName = "Dhcp6"
+		}
+		app.Daemons = append(app.Daemons, &dbmodel.Daemon{
+			Name: name,
+			KeaDaemon: &dbmodel.KeaDaemon{
+				Config: dbmodel.NewKeaConfig(&map[string]interface{}{
+					rootName: map[string]interface{}{
+						"hooks-libraries": []interface{}{
+							map[string]interface{}{
+								"library": "libdhcp_lease_cmds.so",
+							},
+						},
+					},
+				}),
+			},
+		})
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+	return app
+}
+
+// Creates REST API instance with a logged user for the tests of the calls
+// modifying leases.
+func setupLeaseRestAPI(t *testing.T, db *dbops.PgDB, dbSettings *dbops.DatabaseSettings, agents *agentcommtest.FakeAgents) (*RestAPI, *storktestdbmodel.FakeEventCenter, context.Context) {
+	fec := &storktestdbmodel.FakeEventCenter{}
+	rapi, err := NewRestAPI(dbSettings, db, agents, fec)
+	require.NoError(t, err)
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+	user := &dbmodel.SystemUser{
+		ID:    1234,
+		Login: "operator",
+	}
+	err = rapi.SessionManager.LoginHandler(ctx, user)
+	require.NoError(t, err)
+	return rapi, fec, ctx
+}
+
+// Test adding a lease over the REST API.
+func TestAddLease(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestLeaseApp(t, db)
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, fec, ctx := setupLeaseRestAPI(t, db, dbSettings, agents)
+
+	ipAddress := "192.0.2.10"
+	rsp := rapi.AddLease(ctx, dhcp.AddLeaseParams{
+		Lease: &models.LeaseChange{
+			AppID:         &app.ID,
+			IPAddress:     &ipAddress,
+			HwAddress:     "01:02:03:04:05:06",
+			SubnetID:      1,
+			ValidLifetime: 3600,
+			UserContext:   map[string]any{"foo": "bar"},
+		},
+	})
+	require.IsType(t, &dhcp.AddLeaseOK{}, rsp)
+
+	require.Len(t, agents.RecordedCommands, 1)
+	command := agents.RecordedCommands[0].(*keactrl.Command)
+	require.Equal(t, "lease4-add", command.Command)
+	arguments := command.Arguments.(map[string]interface{})
+	require.Equal(t, "192.0.2.10", arguments["ip-address"])
+	require.Equal(t, "01:02:03:04:05:06", arguments["hw-address"])
+	require.Equal(t, map[string]any{"foo": "bar"}, arguments["user-context"])
+
+	require.Len(t, fec.Events, 1)
+	require.Contains(t, fec.Events[0].Text, "added lease 192.0.2.10")
+	require.EqualValues(t, 1234, fec.Events[0].Relations.UserID)
+	require.Equal(t, app.ID, fec.Events[0].Relations.AppID)
+
+	t.Run("non-existing app", func(t *testing.T) {
+		appID := app.ID + 1
+		rsp := rapi.AddLease(ctx, dhcp.AddLeaseParams{
+			Lease: &models.LeaseChange{
+				AppID:     &appID,
+				IPAddress: &ipAddress,
+			},
+		})
+		require.IsType(t, &dhcp.AddLeaseDefault{}, rsp)
+		defaultRsp := rsp.(*dhcp.AddLeaseDefault)
+		require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+	})
+
+	t.Run("invalid user context", func(t *testing.T) {
+		rsp := rapi.AddLease(ctx, dhcp.AddLeaseParams{
+			Lease: &models.LeaseChange{
+				AppID:       &app.ID,
+				IPAddress:   &ipAddress,
+				UserContext: "foo",
+			},
+		})
+		require.IsType(t, &dhcp.AddLeaseDefault{}, rsp)
+		defaultRsp := rsp.(*dhcp.AddLeaseDefault)
+		require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+	})
+}
+
+// Test that the lease cannot be modified when the user is not logged in.
+func TestAddLeaseNoSession(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestLeaseApp(t, db)
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, err := NewRestAPI(dbSettings, db, agents)
+	require.NoError(t, err)
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+
+	ipAddress := "192.0.2.10"
+	rsp := rapi.AddLease(ctx, dhcp.AddLeaseParams{
+		Lease: &models.LeaseChange{
+			AppID:     &app.ID,
+			IPAddress: &ipAddress,
+		},
+	})
+	require.IsType(t, &dhcp.AddLeaseDefault{}, rsp)
+	defaultRsp := rsp.(*dhcp.AddLeaseDefault)
+	require.Equal(t, http.StatusForbidden, getStatusCode(*defaultRsp))
+	require.Empty(t, agents.RecordedCommands)
+}
+
+// Test updating a lease over the REST API.
+func TestUpdateLease(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestLeaseApp(t, db)
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, fec, ctx := setupLeaseRestAPI(t, db, dbSettings, agents)
+
+	ipAddress := "2001:db8:1::10"
+	rsp := rapi.UpdateLease(ctx, dhcp.UpdateLeaseParams{
+		Lease: &models.LeaseChange{
+			AppID:       &app.ID,
+			IPAddress:   &ipAddress,
+			Duid:        "01:02:03:04",
+			Iaid:        1,
+			ForceCreate: true,
+		},
+	})
+	require.IsType(t, &dhcp.UpdateLeaseOK{}, rsp)
+
+	require.Len(t, agents.RecordedCommands, 1)
+	command := agents.RecordedCommands[0].(*keactrl.Command)
+	require.Equal(t, "lease6-update", command.Command)
+	arguments := command.Arguments.(map[string]interface{})
+	require.Equal(t, true, arguments["force-create"])
+	require.Equal(t, "01:02:03:04", arguments["duid"])
+
+	require.Len(t, fec.Events, 1)
+	require.Contains(t, fec.Events[0].Text, "updated lease 2001:db8:1::10")
+}
+
+// Test deleting a lease over the REST API.
+func TestDeleteLease(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestLeaseApp(t, db)
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, fec, ctx := setupLeaseRestAPI(t, db, dbSettings, agents)
+
+	rsp := rapi.DeleteLease(ctx, dhcp.DeleteLeaseParams{
+		AppID:     app.ID,
+		IPAddress: "192.0.2.10",
+	})
+	require.IsType(t, &dhcp.DeleteLeaseOK{}, rsp)
+
+	require.Len(t, agents.RecordedCommands, 1)
+	require.Equal(t, "lease4-del", agents.RecordedCommands[0].GetCommand())
+
+	require.Len(t, fec.Events, 1)
+	require.Contains(t, fec.Events[0].Text, "deleted lease 192.0.2.10")
+
+	t.Run("not found", func(t *testing.T) {
+		agents := agentcommtest.NewFakeAgents(func(callNo int, responses []interface{}) {
+			json := []byte(`[ { "result": 3, "text": "IPv4 lease not found." } ]`)
+			command := keactrl.NewCommand("lease4-del", []string{"dhcp4"}, nil)
+			_ = keactrl.UnmarshalResponseList(command, json, responses[0])
+		}, nil)
+		rapi, fec, ctx := setupLeaseRestAPI(t, db, dbSettings, agents)
+		rsp := rapi.DeleteLease(ctx, dhcp.DeleteLeaseParams{
+			AppID:     app.ID,
+			IPAddress: "192.0.2.10",
+		})
+		require.IsType(t, &dhcp.DeleteLeaseDefault{}, rsp)
+		defaultRsp := rsp.(*dhcp.DeleteLeaseDefault)
+		require.Equal(t, http.StatusNotFound, getStatusCode(*defaultRsp))
+		require.Empty(t, fec.Events)
+	})
+}
+
+// Test wiping the subnet leases over the REST API.
+func TestWipeLeases(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	app := addTestLeaseApp(t, db)
+	subnet := &dbmodel.Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err := dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+	subnet.LocalSubnets = []*dbmodel.LocalSubnet{
+		{
+			DaemonID:      app.Daemons[0].ID,
+			LocalSubnetID: 7,
+		},
+	}
+	err = dbmodel.AddLocalSubnets(db, subnet)
+	require.NoError(t, err)
+
+	agents := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, fec, ctx := setupLeaseRestAPI(t, db, dbSettings, agents)
+
+	rsp := rapi.WipeLeases(ctx, dhcp.WipeLeasesParams{
+		SubnetID: subnet.ID,
+	})
+	require.IsType(t, &dhcp.WipeLeasesOK{}, rsp)
+	okRsp := rsp.(*dhcp.WipeLeasesOK)
+	require.Equal(t, []int64{app.Daemons[0].ID}, okRsp.Payload.WipedDaemonIds)
+	require.Empty(t, okRsp.Payload.ErredDaemonIds)
+
+	require.Len(t, agents.RecordedCommands, 1)
+	command := agents.RecordedCommands[0].(*keactrl.Command)
+	require.Equal(t, "lease4-wipe", command.Command)
+	require.Equal(t, map[string]interface{}{"subnet-id": int64(7)}, command.Arguments)
+
+	require.Len(t, fec.Events, 1)
+	require.Equal(t, dbmodel.EvWarning, fec.Events[0].Level)
+	require.Equal(t, subnet.ID, fec.Events[0].Relations.SubnetID)
+
+	t.Run("non-existing subnet", func(t *testing.T) {
+		rsp := rapi.WipeLeases(ctx, dhcp.WipeLeasesParams{
+			SubnetID: subnet.ID + 1,
+		})
+		require.IsType(t, &dhcp.WipeLeasesDefault{}, rsp)
+		defaultRsp := rsp.(*dhcp.WipeLeasesDefault)
+		require.Equal(t, http.StatusNotFound, getStatusCode(*defaultRsp))
+	})
+}
+
+// Test reclaiming declined leases over the REST API.
+func TestReclaimDeclinedLeases(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	addTestLeaseApp(t, db)
+	agents := agentcommtest.NewKeaFakeAgents(mockLeasesGetDeclined, func(int, []interface{}) {})
+	rapi, fec, ctx := setupLeaseRestAPI(t, db, dbSettings, agents)
+
+	rsp := rapi.ReclaimDeclinedLeases(ctx, dhcp.ReclaimDeclinedLeasesParams{})
+	require.IsType(t, &dhcp.ReclaimDeclinedLeasesOK{}, rsp)
+	okRsp := rsp.(*dhcp.ReclaimDeclinedLeasesOK)
+	require.EqualValues(t, 2, okRsp.Payload.Total)
+	require.Len(t, okRsp.Payload.Items, 2)
+	require.Empty(t, okRsp.Payload.ErredApps)
+
+	// Two search commands and two delete commands.
+	require.Len(t, agents.RecordedCommands, 4)
+	require.Equal(t, "lease4-del", agents.RecordedCommands[2].GetCommand())
+	require.Equal(t, "lease6-del", agents.RecordedCommands[3].GetCommand())
+
+	require.Len(t, fec.Events, 1)
+	require.Contains(t, fec.Events[0].Text, "reclaimed 2 declined leases")
+	require.Equal(t, "192.0.2.1, 2001:db8:2::1", fec.Events[0].Details)
+}
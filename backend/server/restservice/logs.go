@@ -2,41 +2,58 @@ package restservice
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-openapi/runtime/middleware"
+	pkgerrors "github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	agentapi "isc.org/stork/api"
+	"isc.org/stork/server/agentcomm"
 	dbmodel "isc.org/stork/server/database/model"
 	"isc.org/stork/server/gen/models"
 	"isc.org/stork/server/gen/restapi/operations/services"
 	storkutil "isc.org/stork/util"
 )
 
-// Get tail of the specified log file.
-func (r *RestAPI) GetLogTail(ctx context.Context, params services.GetLogTailParams) middleware.Responder {
+// Margin by which the log stream is closed before the HTTP write timeout
+// elapses, so the browser reconnects rather than loses the connection.
+const logStreamTimeoutMargin = 5 * time.Second
+
+// Lines of the log file sent in a single server-sent event.
+type logStreamChunk struct {
+	Lines   []string `json:"lines"`
+	Rotated bool     `json:"rotated"`
+}
+
+// Payload of the server-sent event ending the log stream.
+type logStreamEnd struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Returns the log target with the specified ID if it can be viewed.
+// Otherwise, it returns nil, the HTTP status code and the error message.
+func (r *RestAPI) getViewableLogTarget(id int64) (*dbmodel.LogTarget, int, string) {
 	// We have ID of the log file to display. We need to get the details
 	// of the file from the database.
-	dbLogTarget, err := dbmodel.GetLogTargetByID(r.DB, params.ID)
+	dbLogTarget, err := dbmodel.GetLogTargetByID(r.DB, id)
 	if err != nil {
-		msg := fmt.Sprintf("Cannot get information about log file with ID %d from the database", params.ID)
+		msg := fmt.Sprintf("Cannot get information about log file with ID %d from the database", id)
 		log.Error(msg)
-		rsp := services.NewGetLogTailDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
-			Message: &msg,
-		})
-		return rsp
+		return nil, http.StatusInternalServerError, msg
 	}
 
 	// Handle the case when referencing the non-existing file.
 	if dbLogTarget == nil {
-		msg := fmt.Sprintf("Log file with ID %d does not exist", params.ID)
+		msg := fmt.Sprintf("Log file with ID %d does not exist", id)
 		log.Warn(msg)
-		rsp := services.NewGetLogTailDefault(http.StatusNotFound).WithPayload(&models.APIError{
-			Message: &msg,
-		})
-		return rsp
+		return nil, http.StatusNotFound, msg
 	}
 
 	// Currently we only support viewing log files.
@@ -44,7 +61,16 @@ func (r *RestAPI) GetLogTail(ctx context.Context, params services.GetLogTailPara
 		strings.HasPrefix(dbLogTarget.Output, "syslog") {
 		msg := fmt.Sprintf("Viewing log from %s is not supported", dbLogTarget.Output)
 		log.Warn(msg)
-		rsp := services.NewGetLogTailDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+		return nil, http.StatusBadRequest, msg
+	}
+	return dbLogTarget, http.StatusOK, ""
+}
+
+// Get tail of the specified log file.
+func (r *RestAPI) GetLogTail(ctx context.Context, params services.GetLogTailParams) middleware.Responder {
+	dbLogTarget, status, msg := r.getViewableLogTarget(params.ID)
+	if dbLogTarget == nil {
+		rsp := services.NewGetLogTailDefault(status).WithPayload(&models.APIError{
 			Message: &msg,
 		})
 		return rsp
@@ -83,3 +109,123 @@ func (r *RestAPI) GetLogTail(ctx context.Context, params services.GetLogTailPara
 
 	return rsp
 }
+
+// Streams the lines of the specified log file as server-sent events. It
+// is served outside of the REST API handler because the session middleware
+// buffers the responses, so the session is loaded and the user is
+// authorized here. Each event carries the lines read from the file and
+// the position in the file following these lines as the event ID. The
+// browser sends the last received ID in the Last-Event-ID header when it
+// reconnects, so the stream is resumed where it was interrupted. The
+// stream ends with the "end" event when the file has been sent without the
+// follow mode or the agent has failed. In the follow mode, the stream is
+// closed before the HTTP write timeout elapses, and the browser reconnects.
+func (r *RestAPI) streamLog(w http.ResponseWriter, req *http.Request, id int64) {
+	ctx, err := r.SessionManager.LoadSession(req)
+	if err != nil {
+		log.WithError(err).Error("Failed to load the session for the log stream")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	req = req.WithContext(ctx)
+	if ok, _ := r.SessionManager.Logged(ctx); !ok {
+		http.Error(w, "user unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err = r.Authorizer(req); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	dbLogTarget, status, msg := r.getViewableLogTarget(id)
+	if dbLogTarget == nil {
+		http.Error(w, msg, status)
+		return
+	}
+
+	// The offset from the end of the file is the same as for the tail.
+	options := agentcomm.FollowTextFileOptions{
+		Path:   dbLogTarget.Output,
+		Offset: 4000,
+		Filter: req.URL.Query().Get("filter"),
+	}
+	if value := req.URL.Query().Get("maxLength"); value != "" {
+		if options.Offset, err = strconv.ParseInt(value, 10, 64); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid maxLength value %s", value), http.StatusBadRequest)
+			return
+		}
+	}
+	if value := req.URL.Query().Get("follow"); value != "" {
+		if options.Follow, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid follow value %s", value), http.StatusBadRequest)
+			return
+		}
+	}
+	if value := req.Header.Get("Last-Event-ID"); value != "" {
+		if options.Position, err = strconv.ParseInt(value, 10, 64); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid Last-Event-ID value %s", value), http.StatusBadRequest)
+			return
+		}
+	}
+	if _, err = regexp.Compile(options.Filter); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid filter %s: %s", options.Filter, err), http.StatusBadRequest)
+		return
+	}
+
+	streamCtx := req.Context()
+	if options.Follow && r.Settings != nil && r.Settings.WriteTimeout > logStreamTimeoutMargin {
+		var cancel context.CancelFunc
+		streamCtx, cancel = context.WithTimeout(streamCtx, r.Settings.WriteTimeout-logStreamTimeoutMargin)
+		defer cancel()
+	}
+
+	// Prepare proper HTTP headers for SSE response.
+	h := w.Header()
+	h.Set("Connection", "keep-alive")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Not all ResponseWriter instances implement http.Flusher interface.
+	flush := func() {
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+	flush()
+
+	machine := dbLogTarget.Daemon.App.Machine
+	err = r.Agents.FollowTextFile(streamCtx, machine.Address, machine.AgentPort, options, func(rsp *agentapi.FollowTextFileRsp) error {
+		chunk := &logStreamChunk{
+			Lines:   rsp.Lines,
+			Rotated: rsp.Rotated,
+		}
+		if chunk.Lines == nil {
+			chunk.Lines = []string{}
+		}
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return pkgerrors.Wrap(err, "failed to serialize the log lines")
+		}
+		if _, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", rsp.Position, data); err != nil {
+			return pkgerrors.Wrap(err, "failed to send the log lines")
+		}
+		flush()
+		return nil
+	})
+
+	// The browser is gone or should reconnect.
+	if streamCtx.Err() != nil {
+		return
+	}
+
+	end := &logStreamEnd{}
+	if err != nil {
+		log.WithError(err).Warnf("Failed to stream the log file %s", dbLogTarget.Output)
+		end.Error = err.Error()
+	}
+	data, _ := json.Marshal(end)
+	fmt.Fprintf(w, "event: end\ndata: %s\n\n", data)
+	flush()
+}
@@ -3,7 +3,9 @@ package restservice
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -156,3 +158,117 @@ func TestLogTailBadParams(t *testing.T) {
 			*defaultRsp.Payload.Message)
 	}
 }
+
+// Test that the log file is streamed as server-sent events to the logged
+// user.
+func TestStreamLog(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	m := &dbmodel.Machine{
+		Address:   "localhost",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, m)
+	require.NoError(t, err)
+
+	a := &dbmodel.App{
+		MachineID: m.ID,
+		Type:      dbmodel.AppTypeKea,
+		Active:    true,
+		Daemons: []*dbmodel.Daemon{
+			{
+				Name:   "kea-dhcp4",
+				Active: true,
+				LogTargets: []*dbmodel.LogTarget{
+					{
+						Output: "/tmp/filename.log",
+					},
+					{
+						Output: "stdout",
+					},
+				},
+			},
+		},
+	}
+	_, err = dbmodel.AddApp(db, a)
+	require.NoError(t, err)
+	logTargetID := a.Daemons[0].LogTargets[0].ID
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, err := NewRestAPI(dbSettings, db, fa)
+	require.NoError(t, err)
+
+	handler := logStreamMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.FailNow(t, "request should not be passed to the next handler")
+	}), rapi)
+
+	// Sends the request to the handler and returns the response.
+	get := func(url string, cookie *http.Cookie, lastEventID string) (int, string) {
+		req := httptest.NewRequest("GET", url, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		resp := w.Result()
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	url := fmt.Sprintf("http://localhost/api/logs/%d/stream", logTargetID)
+
+	// The user is not logged in.
+	status, _ := get(url, nil, "")
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	// Log in the user.
+	user, err := dbmodel.GetUserByID(db, 1)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	rapi.SessionManager.SessionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := rapi.SessionManager.LoginHandler(r.Context(), user)
+		require.NoError(t, err)
+	})).ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/api/sessions", nil))
+	resp := w.Result()
+	resp.Body.Close()
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "session" {
+			cookie = c
+		}
+	}
+	require.NotNil(t, cookie)
+
+	// The stream is resumed at the position from the Last-Event-ID.
+	status, body := get(url+"?filter=lorem&maxLength=100", cookie, "5")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "id: 17\ndata: {\"lines\":[\"lorem ipsum\"],\"rotated\":false}\n\nevent: end\ndata: {}\n\n", body)
+	require.NotNil(t, fa.RecordedFollowTextFileOptions)
+	require.Equal(t, "/tmp/filename.log", fa.RecordedFollowTextFileOptions.Path)
+	require.EqualValues(t, 100, fa.RecordedFollowTextFileOptions.Offset)
+	require.EqualValues(t, 5, fa.RecordedFollowTextFileOptions.Position)
+	require.Equal(t, "lorem", fa.RecordedFollowTextFileOptions.Filter)
+	require.False(t, fa.RecordedFollowTextFileOptions.Follow)
+
+	// Invalid parameters.
+	status, _ = get(url+"?filter=(", cookie, "")
+	require.Equal(t, http.StatusBadRequest, status)
+	status, _ = get(url+"?follow=maybe", cookie, "")
+	require.Equal(t, http.StatusBadRequest, status)
+	status, _ = get(url, cookie, "foo")
+	require.Equal(t, http.StatusBadRequest, status)
+
+	// The log file doesn't exist.
+	status, _ = get(fmt.Sprintf("http://localhost/api/logs/%d/stream", logTargetID+10), cookie, "")
+	require.Equal(t, http.StatusNotFound, status)
+
+	// Only the log files can be streamed.
+	status, _ = get(fmt.Sprintf("http://localhost/api/logs/%d/stream", a.Daemons[0].LogTargets[1].ID), cookie, "")
+	require.Equal(t, http.StatusBadRequest, status)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -107,14 +108,28 @@ func (r *RestAPI) GetMachineState(ctx context.Context, params services.GetMachin
 	}
 
 	m := r.machineToRestAPI(*dbMachine)
+	r.hideAgentTokens(ctx, m)
 	rsp := services.NewGetMachineStateOK().WithPayload(m)
 
 	return rsp
 }
 
+// Hides the agent tokens from the users other than super-admin. The token
+// is used to verify the agent identity before authorizing the machine and
+// only the super-admin can authorize the machines.
+func (r *RestAPI) hideAgentTokens(ctx context.Context, machines ...*models.Machine) {
+	_, dbUser := r.SessionManager.Logged(ctx)
+	if dbUser != nil && dbUser.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID}) {
+		return
+	}
+	for _, m := range machines {
+		m.AgentToken = ""
+	}
+}
+
 // Get machines from database based on params and convert them to rest structures.
-func (r *RestAPI) getMachines(offset, limit int64, filterText *string, authorized *bool, sortField string, sortDir dbmodel.SortDirEnum) (*models.Machines, error) {
-	dbMachines, total, err := dbmodel.GetMachinesByPage(r.DB, offset, limit, filterText, authorized, sortField, sortDir)
+func (r *RestAPI) getMachines(offset, limit int64, filterText *string, authorized *bool, scope *dbmodel.ScopeFilter, sortField string, sortDir dbmodel.SortDirEnum) (*models.Machines, error) {
+	dbMachines, total, err := dbmodel.GetMachinesByPage(r.DB, offset, limit, filterText, authorized, scope, sortField, sortDir)
 	if err != nil {
 		return nil, err
 	}
@@ -160,7 +175,7 @@ func (r *RestAPI) GetMachines(ctx context.Context, params services.GetMachinesPa
 		"app":   app,
 	}).Info("query machines")
 
-	machines, err := r.getMachines(start, limit, params.Text, params.Authorized, "", dbmodel.SortDirAny)
+	machines, err := r.getMachines(start, limit, params.Text, params.Authorized, getScopeFilter(ctx), "", dbmodel.SortDirAny)
 	if err != nil {
 		log.Error(err)
 		msg := "Cannot get machines from db"
@@ -169,6 +184,7 @@ func (r *RestAPI) GetMachines(ctx context.Context, params services.GetMachinesPa
 		})
 		return rsp
 	}
+	r.hideAgentTokens(ctx, machines.Items...)
 	rsp := services.NewGetMachinesOK().WithPayload(machines)
 	return rsp
 }
@@ -257,6 +273,7 @@ func (r *RestAPI) GetMachine(ctx context.Context, params services.GetMachinePara
 		return rsp
 	}
 	m := r.machineToRestAPI(*dbMachine)
+	r.hideAgentTokens(ctx, m)
 	rsp := services.NewGetMachineOK().WithPayload(m)
 	return rsp
 }
@@ -598,6 +615,7 @@ func (r *RestAPI) UpdateMachine(ctx context.Context, params services.UpdateMachi
 	}
 
 	m := r.machineToRestAPI(*dbMachine)
+	r.hideAgentTokens(ctx, m)
 	rsp := services.NewUpdateMachineOK().WithPayload(m)
 	return rsp
 }
@@ -837,6 +855,37 @@ func baseAppToRestAPI(dbApp *dbmodel.App) *models.AppBase {
 	return app
 }
 
+// Converts the BIND 9 statistics of the views to REST API format. The
+// views are sorted by name.
+func convertBind9ViewStatsToRestAPI(viewStats map[string]*dbmodel.Bind9StatsView) []*models.Bind9DaemonView {
+	var views []*models.Bind9DaemonView
+	for name, viewStat := range viewStats {
+		if viewStat == nil {
+			continue
+		}
+		view := &models.Bind9DaemonView{
+			Name:      name,
+			ZoneCount: viewStat.ZoneCount,
+		}
+		if viewStat.Resolver != nil {
+			view.QueryHits = viewStat.Resolver.CacheStats["QueryHits"]
+			view.QueryMisses = viewStat.Resolver.CacheStats["QueryMisses"]
+			queryTotal := float64(view.QueryHits) + float64(view.QueryMisses)
+			if queryTotal > 0 {
+				view.QueryHitRatio = float64(view.QueryHits) / queryTotal
+			}
+			view.ResolverStats = viewStat.Resolver.Stats
+			view.ResolverQueryTypes = viewStat.Resolver.Qtypes
+			view.CacheStats = viewStat.Resolver.CacheStats
+		}
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].Name < views[j].Name
+	})
+	return views
+}
+
 // Converts App structure to REST API format, with the data specific to
 // an app type (including daemons).
 func (r *RestAPI) appToRestAPI(dbApp *dbmodel.App) *models.App {
@@ -903,18 +952,22 @@ func (r *RestAPI) appToRestAPI(dbApp *dbmodel.App) *models.App {
 			namedStats = bind9DaemonDB.Bind9Daemon.Stats.NamedStats
 		}
 
+		// The daemon's query hits and misses are the sums over all views.
 		var queryHitRatio float64
 		var queryHits int64
 		var queryMisses int64
+		var rcodes map[string]int64
+		var views []*models.Bind9DaemonView
 		if namedStats != nil {
-			view, okView := namedStats.Views["_default"]
-			if okView {
-				queryHits = view.Resolver.CacheStats["QueryHits"]
-				queryMisses = view.Resolver.CacheStats["QueryMisses"]
-				queryTotal := float64(queryHits) + float64(queryMisses)
-				if queryTotal > 0 {
-					queryHitRatio = float64(queryHits) / queryTotal
-				}
+			rcodes = namedStats.Rcodes
+			views = convertBind9ViewStatsToRestAPI(namedStats.Views)
+			for _, view := range views {
+				queryHits += view.QueryHits
+				queryMisses += view.QueryMisses
+			}
+			queryTotal := float64(queryHits) + float64(queryMisses)
+			if queryTotal > 0 {
+				queryHitRatio = float64(queryHits) / queryTotal
 			}
 		}
 
@@ -939,6 +992,8 @@ func (r *RestAPI) appToRestAPI(dbApp *dbmodel.App) *models.App {
 				QueryMisses:     queryMisses,
 				QueryHitRatio:   queryHitRatio,
 				AgentCommErrors: agentErrors,
+				Rcodes:          rcodes,
+				Views:           views,
 			}
 		}
 
@@ -1014,8 +1069,8 @@ func keaDaemonToRestAPI(dbDaemon *dbmodel.Daemon) *models.KeaDaemon {
 	return daemon
 }
 
-func (r *RestAPI) getApps(offset, limit int64, filterText *string, appType string, sortField string, sortDir dbmodel.SortDirEnum) (*models.Apps, error) {
-	dbApps, total, err := dbmodel.GetAppsByPage(r.DB, offset, limit, filterText, dbmodel.AppType(appType), sortField, sortDir)
+func (r *RestAPI) getApps(offset, limit int64, filterText *string, appType string, scope *dbmodel.ScopeFilter, sortField string, sortDir dbmodel.SortDirEnum) (*models.Apps, error) {
+	dbApps, total, err := dbmodel.GetAppsByPage(r.DB, offset, limit, filterText, dbmodel.AppType(appType), scope, sortField, sortDir)
 	if err != nil {
 		return nil, err
 	}
@@ -1055,7 +1110,7 @@ func (r *RestAPI) GetApps(ctx context.Context, params services.GetAppsParams) mi
 		"app":   appType,
 	}).Info("query apps")
 
-	apps, err := r.getApps(start, limit, params.Text, appType, "", dbmodel.SortDirAny)
+	apps, err := r.getApps(start, limit, params.Text, appType, getScopeFilter(ctx), "", dbmodel.SortDirAny)
 	if err != nil {
 		log.Error(err)
 		msg := "Cannot get apps from db"
@@ -1141,7 +1196,9 @@ func getKeaServicesStatus(db *dbops.PgDB, app *dbmodel.App) *models.ServicesStat
 		}
 		ha := s.HAService
 		keaStatus := models.KeaStatus{
-			Daemon: ha.HAType,
+			ServiceID:    s.ID,
+			Daemon:       ha.HAType,
+			Relationship: ha.Relationship,
 		}
 		secondaryRole := "secondary"
 		if ha.HAMode == dbmodel.HAModeHotStandby {
@@ -1367,7 +1424,7 @@ func (r *RestAPI) GetDhcpOverview(ctx context.Context, params dhcp.GetDhcpOvervi
 	}
 
 	// get list of mostly utilized shared networks
-	sharedNetworks4, err := r.getSharedNetworks(0, 5, 0, 4, nil, "addr_utilization", dbmodel.SortDirDesc)
+	sharedNetworks4, err := r.getSharedNetworks(0, 5, 0, 4, nil, nil, "addr_utilization", dbmodel.SortDirDesc)
 	if err != nil {
 		log.Error(err)
 		msg := "Cannot get IPv4 shared networks from db"
@@ -1377,7 +1434,7 @@ func (r *RestAPI) GetDhcpOverview(ctx context.Context, params dhcp.GetDhcpOvervi
 		return rsp
 	}
 
-	sharedNetworks6, err := r.getSharedNetworks(0, 5, 0, 6, nil, "addr_utilization", dbmodel.SortDirDesc)
+	sharedNetworks6, err := r.getSharedNetworks(0, 5, 0, 6, nil, nil, "addr_utilization", dbmodel.SortDirDesc)
 	if err != nil {
 		log.Error(err)
 		msg := "Cannot get IPv6 shared networks from db"
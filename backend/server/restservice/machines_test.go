@@ -61,7 +61,8 @@ func TestGetMachineStateOnly(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, fd)
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
 
 	// get state of non-existing machine
 	params := services.GetMachineStateParams{
@@ -193,7 +194,8 @@ func TestGetMachineAndAppsState(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, fd)
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
 
 	// add machine
 	m := &dbmodel.Machine{
@@ -529,7 +531,8 @@ func TestGetMachines(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, fd)
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
 
 	var start, limit int64 = 0, 10
 	params := services.GetMachinesParams{
@@ -553,7 +556,8 @@ func TestGetMachinesEmptyList(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, fd)
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
 
 	var start, limit int64 = 0, 10
 	params := services.GetMachinesParams{
@@ -629,7 +633,8 @@ func TestGetMachine(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, fd)
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
 
 	// get non-existing machine
 	params := services.GetMachineParams{
@@ -645,6 +650,7 @@ func TestGetMachine(t *testing.T) {
 	m := &dbmodel.Machine{
 		Address:       "localhost",
 		AgentPort:     8080,
+		AgentToken:    "secret",
 		LastVisitedAt: time.Now(),
 	}
 	err = dbmodel.AddMachine(db, m)
@@ -659,6 +665,18 @@ func TestGetMachine(t *testing.T) {
 	okRsp := rsp.(*services.GetMachineOK)
 	require.Equal(t, m.ID, okRsp.Payload.ID)
 	require.NotNil(t, okRsp.Payload.LastVisitedAt)
+	// The agent token is only returned to the super-admin.
+	require.Empty(t, okRsp.Payload.AgentToken)
+
+	user, err := dbmodel.GetUserByID(rapi.DB, 1)
+	require.NoError(t, err)
+	err = rapi.SessionManager.LoginHandler(ctx, user)
+	require.NoError(t, err)
+
+	rsp = rapi.GetMachine(ctx, params)
+	require.IsType(t, &services.GetMachineOK{}, rsp)
+	okRsp = rsp.(*services.GetMachineOK)
+	require.Equal(t, "secret", okRsp.Payload.AgentToken)
 
 	// add machine 2
 	m2 := &dbmodel.Machine{
@@ -707,7 +725,8 @@ func TestUpdateMachine(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, fd)
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
 
 	// empty request, variant 1 - should raise an error
 	params := services.UpdateMachineParams{}
@@ -840,7 +859,8 @@ func TestDeleteMachine(t *testing.T) {
 	fd := &storktest.FakeDispatcher{}
 	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec, fd)
 	require.NoError(t, err)
-	ctx := context.Background()
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
 
 	// delete non-existing machine
 	params := services.DeleteMachineParams{
@@ -1004,7 +1024,35 @@ func TestRestGetApp(t *testing.T) {
 		AccessPoints: bind9Points,
 		Daemons: []*dbmodel.Daemon{
 			{
-				Bind9Daemon: &dbmodel.Bind9Daemon{},
+				Bind9Daemon: &dbmodel.Bind9Daemon{
+					Stats: dbmodel.Bind9DaemonStats{
+						NamedStats: &dbmodel.Bind9NamedStats{
+							Rcodes: map[string]int64{
+								"NXDOMAIN": 3,
+							},
+							Views: map[string]*dbmodel.Bind9StatsView{
+								"internal": {
+									ZoneCount: 2,
+									Resolver: &dbmodel.Bind9StatsResolver{
+										CacheStats: map[string]int64{
+											"QueryHits":   30,
+											"QueryMisses": 10,
+										},
+									},
+								},
+								"external": {
+									ZoneCount: 1,
+									Resolver: &dbmodel.Bind9StatsResolver{
+										CacheStats: map[string]int64{
+											"QueryHits":   10,
+											"QueryMisses": 30,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
 			},
 		},
 	}
@@ -1020,6 +1068,61 @@ func TestRestGetApp(t *testing.T) {
 	okRsp = rsp.(*services.GetAppOK)
 	require.Equal(t, bind9App.ID, okRsp.Payload.ID)
 	require.Equal(t, bind9App.Name, okRsp.Payload.Name)
+
+	// The statistics of all views should be returned.
+	daemon := okRsp.Payload.Details.AppBind9.Daemon
+	require.NotNil(t, daemon)
+	require.EqualValues(t, 40, daemon.QueryHits)
+	require.EqualValues(t, 40, daemon.QueryMisses)
+	require.EqualValues(t, 0.5, daemon.QueryHitRatio)
+	require.EqualValues(t, 3, daemon.Rcodes["NXDOMAIN"])
+	require.Len(t, daemon.Views, 2)
+	require.Equal(t, "external", daemon.Views[0].Name)
+	require.EqualValues(t, 1, daemon.Views[0].ZoneCount)
+	require.EqualValues(t, 0.25, daemon.Views[0].QueryHitRatio)
+	require.Equal(t, "internal", daemon.Views[1].Name)
+	require.EqualValues(t, 2, daemon.Views[1].ZoneCount)
+	require.EqualValues(t, 0.75, daemon.Views[1].QueryHitRatio)
+}
+
+// Test converting the BIND 9 view statistics to REST API format.
+func TestConvertBind9ViewStatsToRestAPI(t *testing.T) {
+	views := convertBind9ViewStatsToRestAPI(map[string]*dbmodel.Bind9StatsView{
+		"guest": {
+			ZoneCount: 3,
+			Resolver: &dbmodel.Bind9StatsResolver{
+				Stats: map[string]int64{
+					"Queryv4":  12,
+					"SERVFAIL": 1,
+				},
+				Qtypes: map[string]int64{
+					"AAAA": 12,
+				},
+				CacheStats: map[string]int64{
+					"QueryHits":   1,
+					"QueryMisses": 3,
+				},
+			},
+		},
+		"_default": {
+			ZoneCount: 1,
+		},
+		"nil": nil,
+	})
+	require.Len(t, views, 2)
+
+	require.Equal(t, "_default", views[0].Name)
+	require.EqualValues(t, 1, views[0].ZoneCount)
+	require.Zero(t, views[0].QueryHitRatio)
+
+	require.Equal(t, "guest", views[1].Name)
+	require.EqualValues(t, 3, views[1].ZoneCount)
+	require.EqualValues(t, 1, views[1].QueryHits)
+	require.EqualValues(t, 3, views[1].QueryMisses)
+	require.EqualValues(t, 0.25, views[1].QueryHitRatio)
+	require.EqualValues(t, 1, views[1].ResolverStats["SERVFAIL"])
+	require.EqualValues(t, 12, views[1].ResolverQueryTypes["AAAA"])
+	require.EqualValues(t, 3, views[1].CacheStats["QueryMisses"])
 }
 
 func TestRestGetApps(t *testing.T) {
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -13,6 +14,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"isc.org/stork/server/auth"
+	dbmodel "isc.org/stork/server/database/model"
 	"isc.org/stork/server/eventcenter"
 	"isc.org/stork/server/metrics"
 )
@@ -54,6 +56,14 @@ func (r *loggingResponseWriter) Header() http.Header {
 	return r.rw.Header()
 }
 
+// http.Flusher implementation wrapper that flushes the buffered data
+// to the client, e.g., the server-sent events.
+func (r *loggingResponseWriter) Flush() {
+	if flusher, ok := r.rw.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // Install a middleware that traces ReST calls using logrus.
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -145,6 +155,23 @@ func sseMiddleware(next http.Handler, eventCenter eventcenter.EventCenter) http.
 	})
 }
 
+// Install a middleware that is streaming the log files as `server-sent
+// events` (SSE) at /api/logs/{id}/stream.
+func logStreamMiddleware(next http.Handler, r *RestAPI) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+		if req.Method == http.MethodGet && len(segments) == 4 &&
+			segments[0] == "api" && segments[1] == "logs" && segments[3] == "stream" {
+			if id, err := strconv.ParseInt(segments[2], 10, 64); err == nil {
+				r.streamLog(w, req, id)
+				return
+			}
+		}
+		// pass request to another handler
+		next.ServeHTTP(w, req)
+	})
+}
+
 // Install a middleware that is serving Agent installer.
 func agentInstallerMiddleware(next http.Handler, staticFilesDir string) http.Handler {
 	// Agent installer as Bash script.
@@ -314,6 +341,7 @@ func (r *RestAPI) GlobalMiddleware(handler http.Handler, staticFilesDir, baseURL
 	handler = fileServerMiddleware(handler, staticFilesDir)
 	handler = agentInstallerMiddleware(handler, staticFilesDir)
 	handler = sseMiddleware(handler, eventCenter)
+	handler = logStreamMiddleware(handler, r)
 	handler = metricsMiddleware(handler, r.MetricsCollector)
 	handler = trimBaseURLMiddleware(handler, baseURL)
 	handler = loggingMiddleware(handler)
@@ -335,10 +363,42 @@ func (r *RestAPI) Authorizer(req *http.Request) error {
 		return errors.Errorf("user unauthorized")
 	}
 
-	ok, _ = auth.Authorize(u, req)
+	// The session holds only the IDs of the user's groups. The members
+	// of the groups other than super-admin and admin are authorized
+	// according to the groups' permissions and scopes. They have to be
+	// fetched from the database.
+	if !u.InGroup(&dbmodel.SystemGroup{ID: dbmodel.SuperAdminGroupID}) &&
+		!u.InGroup(&dbmodel.SystemGroup{ID: dbmodel.AdminGroupID}) &&
+		len(u.Groups) > 0 && r.DB != nil {
+		var groupIDs []int
+		for _, g := range u.Groups {
+			groupIDs = append(groupIDs, g.ID)
+		}
+		groups, err := dbmodel.GetGroupsByIDs(r.DB, groupIDs)
+		if err != nil {
+			log.WithError(err).Error("Failed to get the user's groups")
+			return errors.Errorf("failed to authorize the user")
+		}
+		user := *u
+		user.Groups = []*dbmodel.SystemGroup{}
+		for i := range groups {
+			user.Groups = append(user.Groups, &groups[i])
+		}
+		u = &user
+	}
+
+	ok, err := auth.AuthorizeWithScope(u, req, &dbScopeResolver{db: r.DB})
+	if err != nil {
+		log.WithError(err).Error("Failed to authorize the user")
+	}
 	if !ok {
 		return errors.Errorf("user logged in but not allowed to access the resource")
 	}
 
+	// The handlers returning the collections of objects and accepting
+	// the objects in the request bodies limit them to the user's scope.
+	// The runtime passes the request context modified here to the handlers.
+	*req = *req.WithContext(withScopeFilter(req.Context(), auth.GetScopeFilter(u, req)))
+
 	return nil
 }
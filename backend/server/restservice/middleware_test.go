@@ -309,6 +309,45 @@ func TestLoggingMiddlewareHelpers(t *testing.T) {
 	// check Header
 	hdr := lrw.Header()
 	require.Empty(t, hdr)
+
+	// check Flush
+	recorder := httptest.NewRecorder()
+	lrw = &loggingResponseWriter{
+		rw:           recorder,
+		responseData: &responseData{},
+	}
+	lrw.Flush()
+	require.True(t, recorder.Flushed)
+}
+
+// Check that logStreamMiddleware passes the requests other than the log
+// streams to the next handler.
+func TestLogStreamMiddlewareOtherRequests(t *testing.T) {
+	requestReceived := false
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestReceived = true
+	})
+
+	handler := logStreamMiddleware(nextHandler, &RestAPI{})
+
+	for _, url := range []string{
+		"http://localhost/api/logs/1",
+		"http://localhost/api/logs/abc/stream",
+		"http://localhost/api/logs/1/stream/foo",
+		"http://localhost/api/machines/1/stream",
+	} {
+		requestReceived = false
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		require.True(t, requestReceived, url)
+	}
+
+	requestReceived = false
+	req := httptest.NewRequest("POST", "http://localhost/api/logs/1/stream", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.True(t, requestReceived)
 }
 
 // Test the file middleware. Includes the test to check if the middleware
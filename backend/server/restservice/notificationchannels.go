@@ -0,0 +1,319 @@
+package restservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	log "github.com/sirupsen/logrus"
+
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/eventcenter"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/events"
+)
+
+// Converts the notification channel fetched from the database to the
+// REST API format. The SMTP password is not returned.
+func newRestNotificationChannel(dbChannel *dbmodel.NotificationChannel) *models.NotificationChannel {
+	channelType := string(dbChannel.ChannelType)
+	return &models.NotificationChannel{
+		ID:                dbChannel.ID,
+		CreatedAt:         strfmt.DateTime(dbChannel.CreatedAt),
+		Name:              &dbChannel.Name,
+		Type:              &channelType,
+		Enabled:           dbChannel.Enabled,
+		Level:             int64(dbChannel.Level),
+		MachineID:         dbChannel.MachineID,
+		AppID:             dbChannel.AppID,
+		DaemonID:          dbChannel.DaemonID,
+		SubnetID:          dbChannel.SubnetID,
+		SharedNetworkID:   dbChannel.SharedNetworkID,
+		RateLimit:         dbChannel.RateLimit,
+		RateLimitInterval: dbChannel.RateLimitInterval,
+		URL:               dbChannel.Settings.URL,
+		SMTPHost:          dbChannel.Settings.SMTPHost,
+		SMTPPort:          dbChannel.Settings.SMTPPort,
+		SMTPUsername:      dbChannel.Settings.SMTPUsername,
+		SMTPFrom:          dbChannel.Settings.SMTPFrom,
+		SMTPTo:            dbChannel.Settings.SMTPTo,
+		SyslogNetwork:     dbChannel.Settings.SyslogNetwork,
+		SyslogAddress:     dbChannel.Settings.SyslogAddress,
+		SyslogFacility:    dbChannel.Settings.SyslogFacility,
+	}
+}
+
+// Converts the notification channel received over the REST API to the
+// database model and validates it. It returns an error describing the
+// invalid settings.
+func newDBNotificationChannel(restChannel *models.NotificationChannel) (*dbmodel.NotificationChannel, error) {
+	if restChannel == nil || restChannel.Name == nil || *restChannel.Name == "" {
+		return nil, errors.New("notification channel name must be specified")
+	}
+	if restChannel.Type == nil {
+		return nil, errors.New("notification channel type must be specified")
+	}
+	if restChannel.Level < int64(dbmodel.EvInfo) || restChannel.Level > int64(dbmodel.EvError) {
+		return nil, fmt.Errorf("invalid event level %d", restChannel.Level)
+	}
+	if restChannel.RateLimit < 0 || restChannel.RateLimitInterval < 0 {
+		return nil, errors.New("rate limit and rate limit interval must not be negative")
+	}
+	dbChannel := &dbmodel.NotificationChannel{
+		Name:              *restChannel.Name,
+		ChannelType:       dbmodel.NotificationChannelType(*restChannel.Type),
+		Enabled:           restChannel.Enabled,
+		Level:             dbmodel.EventLevel(restChannel.Level),
+		MachineID:         restChannel.MachineID,
+		AppID:             restChannel.AppID,
+		DaemonID:          restChannel.DaemonID,
+		SubnetID:          restChannel.SubnetID,
+		SharedNetworkID:   restChannel.SharedNetworkID,
+		RateLimit:         restChannel.RateLimit,
+		RateLimitInterval: restChannel.RateLimitInterval,
+		Settings: dbmodel.NotificationChannelSettings{
+			URL:            restChannel.URL,
+			SMTPHost:       restChannel.SMTPHost,
+			SMTPPort:       restChannel.SMTPPort,
+			SMTPUsername:   restChannel.SMTPUsername,
+			SMTPPassword:   restChannel.SMTPPassword,
+			SMTPFrom:       restChannel.SMTPFrom,
+			SMTPTo:         restChannel.SMTPTo,
+			SyslogNetwork:  restChannel.SyslogNetwork,
+			SyslogAddress:  restChannel.SyslogAddress,
+			SyslogFacility: restChannel.SyslogFacility,
+		},
+	}
+	if _, err := eventcenter.NewNotifier(dbChannel); err != nil {
+		return nil, err
+	}
+	return dbChannel, nil
+}
+
+// Checks if another notification channel has the specified name.
+func (r *RestAPI) isNotificationChannelNameTaken(name string, id int64) (bool, error) {
+	channels, err := dbmodel.GetNotificationChannels(r.DB)
+	if err != nil {
+		return false, err
+	}
+	for _, channel := range channels {
+		if channel.Name == name && channel.ID != id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Returns all notification channels.
+func (r *RestAPI) GetNotificationChannels(ctx context.Context, params events.GetNotificationChannelsParams) middleware.Responder {
+	dbChannels, err := dbmodel.GetNotificationChannels(r.DB)
+	if err != nil {
+		log.WithError(err).Error("Failed to get notification channels from the database")
+		msg := "Problem fetching notification channels from the database"
+		rsp := events.NewGetNotificationChannelsDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	channels := &models.NotificationChannels{
+		Items: []*models.NotificationChannel{},
+		Total: int64(len(dbChannels)),
+	}
+	for i := range dbChannels {
+		channels.Items = append(channels.Items, newRestNotificationChannel(&dbChannels[i]))
+	}
+
+	rsp := events.NewGetNotificationChannelsOK().WithPayload(channels)
+	return rsp
+}
+
+// Returns the notification channel by ID.
+func (r *RestAPI) GetNotificationChannel(ctx context.Context, params events.GetNotificationChannelParams) middleware.Responder {
+	dbChannel, err := dbmodel.GetNotificationChannelByID(r.DB, params.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to get notification channel %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem fetching notification channel with ID %d from the database", params.ID)
+		rsp := events.NewGetNotificationChannelDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbChannel == nil {
+		msg := fmt.Sprintf("Cannot find notification channel with ID %d", params.ID)
+		rsp := events.NewGetNotificationChannelDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rsp := events.NewGetNotificationChannelOK().WithPayload(newRestNotificationChannel(dbChannel))
+	return rsp
+}
+
+// Adds a new notification channel.
+func (r *RestAPI) CreateNotificationChannel(ctx context.Context, params events.CreateNotificationChannelParams) middleware.Responder {
+	dbChannel, err := newDBNotificationChannel(params.Channel)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid notification channel: %s", err)
+		rsp := events.NewCreateNotificationChannelDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	taken, err := r.isNotificationChannelNameTaken(dbChannel.Name, 0)
+	if err == nil && taken {
+		msg := fmt.Sprintf("Notification channel %s already exists", dbChannel.Name)
+		rsp := events.NewCreateNotificationChannelDefault(http.StatusConflict).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if err == nil {
+		err = dbmodel.AddNotificationChannel(r.DB, dbChannel)
+	}
+	if err != nil {
+		log.WithError(err).Errorf("Failed to add notification channel %s to the database", dbChannel.Name)
+		msg := fmt.Sprintf("Problem adding notification channel %s to the database", dbChannel.Name)
+		rsp := events.NewCreateNotificationChannelDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rsp := events.NewCreateNotificationChannelOK().WithPayload(newRestNotificationChannel(dbChannel))
+	return rsp
+}
+
+// Updates the notification channel. The SMTP password is preserved when
+// it is not specified.
+func (r *RestAPI) UpdateNotificationChannel(ctx context.Context, params events.UpdateNotificationChannelParams) middleware.Responder {
+	existing, err := dbmodel.GetNotificationChannelByID(r.DB, params.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to get notification channel %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem fetching notification channel with ID %d from the database", params.ID)
+		rsp := events.NewUpdateNotificationChannelDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if existing == nil {
+		msg := fmt.Sprintf("Cannot find notification channel with ID %d", params.ID)
+		rsp := events.NewUpdateNotificationChannelDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	if params.Channel != nil && params.Channel.SMTPPassword == "" {
+		params.Channel.SMTPPassword = existing.Settings.SMTPPassword
+	}
+	dbChannel, err := newDBNotificationChannel(params.Channel)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid notification channel: %s", err)
+		rsp := events.NewUpdateNotificationChannelDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	dbChannel.ID = existing.ID
+	dbChannel.CreatedAt = existing.CreatedAt
+
+	taken, err := r.isNotificationChannelNameTaken(dbChannel.Name, dbChannel.ID)
+	if err == nil && taken {
+		msg := fmt.Sprintf("Notification channel %s already exists", dbChannel.Name)
+		rsp := events.NewUpdateNotificationChannelDefault(http.StatusConflict).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if err == nil {
+		err = dbmodel.UpdateNotificationChannel(r.DB, dbChannel)
+	}
+	if err != nil {
+		log.WithError(err).Errorf("Failed to update notification channel %d in the database", params.ID)
+		msg := fmt.Sprintf("Problem updating notification channel with ID %d in the database", params.ID)
+		rsp := events.NewUpdateNotificationChannelDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rsp := events.NewUpdateNotificationChannelOK().WithPayload(newRestNotificationChannel(dbChannel))
+	return rsp
+}
+
+// Deletes the notification channel.
+func (r *RestAPI) DeleteNotificationChannel(ctx context.Context, params events.DeleteNotificationChannelParams) middleware.Responder {
+	err := dbmodel.DeleteNotificationChannel(r.DB, params.ID)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrNotExists) {
+			msg := fmt.Sprintf("Cannot find notification channel with ID %d", params.ID)
+			rsp := events.NewDeleteNotificationChannelDefault(http.StatusNotFound).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		}
+		log.WithError(err).Errorf("Failed to delete notification channel %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem deleting notification channel with ID %d from the database", params.ID)
+		rsp := events.NewDeleteNotificationChannelDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rsp := events.NewDeleteNotificationChannelOK()
+	return rsp
+}
+
+// Sends a test notification over the channel. The notification is sent
+// regardless of the channel filters, rate limit and whether the channel
+// is enabled. The test event is not stored in the database.
+func (r *RestAPI) SendTestNotification(ctx context.Context, params events.SendTestNotificationParams) middleware.Responder {
+	dbChannel, err := dbmodel.GetNotificationChannelByID(r.DB, params.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to get notification channel %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem fetching notification channel with ID %d from the database", params.ID)
+		rsp := events.NewSendTestNotificationDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbChannel == nil {
+		msg := fmt.Sprintf("Cannot find notification channel with ID %d", params.ID)
+		rsp := events.NewSendTestNotificationDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	text := fmt.Sprintf("Test notification sent over the %s channel", dbChannel.Name)
+	var objects []any
+	if ok, user := r.SessionManager.Logged(ctx); ok && user != nil {
+		text += " by {user}"
+		objects = append(objects, user)
+	}
+	event := eventcenter.CreateEvent(dbmodel.EvInfo, text, objects...)
+	event.CreatedAt = time.Now().UTC()
+
+	notifier, err := eventcenter.NewNotifier(dbChannel)
+	if err == nil {
+		err = notifier.Notify(event)
+	}
+	if err != nil {
+		log.WithError(err).Errorf("Failed to send test notification over channel %s", dbChannel.Name)
+		msg := fmt.Sprintf("Problem sending test notification over channel %s: %s", dbChannel.Name, err)
+		rsp := events.NewSendTestNotificationDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rsp := events.NewSendTestNotificationOK()
+	return rsp
+}
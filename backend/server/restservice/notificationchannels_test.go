@@ -0,0 +1,222 @@
+package restservice
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/events"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Returns the SMTP notification channel used in the tests.
+func createTestSMTPNotificationChannel() *models.NotificationChannel {
+	name := "mail"
+	channelType := "smtp"
+	return &models.NotificationChannel{
+		Name:            &name,
+		Type:            &channelType,
+		Enabled:         true,
+		Level:           1,
+		MachineID:       3,
+		SharedNetworkID: 4,
+		RateLimit:       5,
+		SMTPHost:        "mail.example.org",
+		SMTPUsername:    "stork",
+		SMTPPassword:    "secret",
+		SMTPFrom:        "stork@example.org",
+		SMTPTo:          []string{"admin@example.org"},
+	}
+}
+
+// Test converting the notification channel received over the REST API
+// to the database model.
+func TestNewDBNotificationChannel(t *testing.T) {
+	channel, err := newDBNotificationChannel(createTestSMTPNotificationChannel())
+	require.NoError(t, err)
+	require.Equal(t, "mail", channel.Name)
+	require.Equal(t, dbmodel.NotificationChannelSMTP, channel.ChannelType)
+	require.True(t, channel.Enabled)
+	require.Equal(t, dbmodel.EvWarning, channel.Level)
+	require.EqualValues(t, 3, channel.MachineID)
+	require.EqualValues(t, 4, channel.SharedNetworkID)
+	require.EqualValues(t, 5, channel.RateLimit)
+	require.Equal(t, "secret", channel.Settings.SMTPPassword)
+	require.Equal(t, []string{"admin@example.org"}, channel.Settings.SMTPTo)
+
+	// Missing name.
+	restChannel := createTestSMTPNotificationChannel()
+	restChannel.Name = nil
+	_, err = newDBNotificationChannel(restChannel)
+	require.Error(t, err)
+
+	// Invalid level.
+	restChannel = createTestSMTPNotificationChannel()
+	restChannel.Level = 3
+	_, err = newDBNotificationChannel(restChannel)
+	require.Error(t, err)
+
+	// Invalid type-specific settings.
+	restChannel = createTestSMTPNotificationChannel()
+	restChannel.SMTPTo = nil
+	_, err = newDBNotificationChannel(restChannel)
+	require.Error(t, err)
+}
+
+// Test adding, getting, updating and deleting the notification channels.
+func TestNotificationChannels(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	settings := RestAPISettings{}
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	fec := &storktest.FakeEventCenter{}
+	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Add the channel.
+	rsp := rapi.CreateNotificationChannel(ctx, events.CreateNotificationChannelParams{
+		Channel: createTestSMTPNotificationChannel(),
+	})
+	require.IsType(t, &events.CreateNotificationChannelOK{}, rsp)
+	created := rsp.(*events.CreateNotificationChannelOK).Payload
+	require.NotZero(t, created.ID)
+	require.Empty(t, created.SMTPPassword)
+	require.EqualValues(t, 60, created.RateLimitInterval)
+
+	// The names must be unique.
+	rsp = rapi.CreateNotificationChannel(ctx, events.CreateNotificationChannelParams{
+		Channel: createTestSMTPNotificationChannel(),
+	})
+	require.IsType(t, &events.CreateNotificationChannelDefault{}, rsp)
+	defaultRsp := rsp.(*events.CreateNotificationChannelDefault)
+	require.Equal(t, http.StatusConflict, getStatusCode(*defaultRsp))
+
+	// Invalid channel.
+	restChannel := createTestSMTPNotificationChannel()
+	restChannel.SMTPHost = ""
+	rsp = rapi.CreateNotificationChannel(ctx, events.CreateNotificationChannelParams{
+		Channel: restChannel,
+	})
+	require.IsType(t, &events.CreateNotificationChannelDefault{}, rsp)
+	defaultRsp = rsp.(*events.CreateNotificationChannelDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+
+	// Get all channels.
+	rsp = rapi.GetNotificationChannels(ctx, events.GetNotificationChannelsParams{})
+	require.IsType(t, &events.GetNotificationChannelsOK{}, rsp)
+	channels := rsp.(*events.GetNotificationChannelsOK).Payload
+	require.EqualValues(t, 1, channels.Total)
+	require.Len(t, channels.Items, 1)
+	require.Equal(t, "mail", *channels.Items[0].Name)
+	require.Empty(t, channels.Items[0].SMTPPassword)
+
+	// Update the channel without specifying the password.
+	restChannel = createTestSMTPNotificationChannel()
+	restChannel.SMTPPassword = ""
+	restChannel.Enabled = false
+	rsp = rapi.UpdateNotificationChannel(ctx, events.UpdateNotificationChannelParams{
+		ID:      created.ID,
+		Channel: restChannel,
+	})
+	require.IsType(t, &events.UpdateNotificationChannelOK{}, rsp)
+
+	dbChannel, err := dbmodel.GetNotificationChannelByID(db, created.ID)
+	require.NoError(t, err)
+	require.NotNil(t, dbChannel)
+	require.False(t, dbChannel.Enabled)
+	require.Equal(t, "secret", dbChannel.Settings.SMTPPassword)
+
+	// Get the channel.
+	rsp = rapi.GetNotificationChannel(ctx, events.GetNotificationChannelParams{ID: created.ID})
+	require.IsType(t, &events.GetNotificationChannelOK{}, rsp)
+	require.False(t, rsp.(*events.GetNotificationChannelOK).Payload.Enabled)
+
+	// Update non-existing channel.
+	rsp = rapi.UpdateNotificationChannel(ctx, events.UpdateNotificationChannelParams{
+		ID:      created.ID + 1,
+		Channel: createTestSMTPNotificationChannel(),
+	})
+	require.IsType(t, &events.UpdateNotificationChannelDefault{}, rsp)
+	updateRsp := rsp.(*events.UpdateNotificationChannelDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*updateRsp))
+
+	// Delete the channel.
+	rsp = rapi.DeleteNotificationChannel(ctx, events.DeleteNotificationChannelParams{ID: created.ID})
+	require.IsType(t, &events.DeleteNotificationChannelOK{}, rsp)
+
+	rsp = rapi.GetNotificationChannel(ctx, events.GetNotificationChannelParams{ID: created.ID})
+	require.IsType(t, &events.GetNotificationChannelDefault{}, rsp)
+	getRsp := rsp.(*events.GetNotificationChannelDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*getRsp))
+
+	rsp = rapi.DeleteNotificationChannel(ctx, events.DeleteNotificationChannelParams{ID: created.ID})
+	require.IsType(t, &events.DeleteNotificationChannelDefault{}, rsp)
+	deleteRsp := rsp.(*events.DeleteNotificationChannelDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*deleteRsp))
+}
+
+// Test sending the test notification over the webhook channel.
+func TestSendTestNotification(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	var bodies []map[string]any
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		_ = json.Unmarshal(data, &body)
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	settings := RestAPISettings{}
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	fec := &storktest.FakeEventCenter{}
+	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec)
+	require.NoError(t, err)
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+
+	// The test notification is sent even if the channel is disabled.
+	channel := &dbmodel.NotificationChannel{
+		Name:        "hook",
+		ChannelType: dbmodel.NotificationChannelWebhook,
+		Settings: dbmodel.NotificationChannelSettings{
+			URL: server.URL,
+		},
+	}
+	err = dbmodel.AddNotificationChannel(db, channel)
+	require.NoError(t, err)
+
+	rsp := rapi.SendTestNotification(ctx, events.SendTestNotificationParams{ID: channel.ID})
+	require.IsType(t, &events.SendTestNotificationOK{}, rsp)
+	require.Len(t, bodies, 1)
+	require.Equal(t, "Test notification sent over the hook channel", bodies[0]["text"])
+	require.Equal(t, "info", bodies[0]["level"])
+
+	// Delivery failure.
+	status = http.StatusInternalServerError
+	rsp = rapi.SendTestNotification(ctx, events.SendTestNotificationParams{ID: channel.ID})
+	require.IsType(t, &events.SendTestNotificationDefault{}, rsp)
+	defaultRsp := rsp.(*events.SendTestNotificationDefault)
+	require.Equal(t, http.StatusInternalServerError, getStatusCode(*defaultRsp))
+	require.Contains(t, *defaultRsp.Payload.Message, "500")
+
+	// Non-existing channel.
+	rsp = rapi.SendTestNotification(ctx, events.SendTestNotificationParams{ID: channel.ID + 1})
+	require.IsType(t, &events.SendTestNotificationDefault{}, rsp)
+	defaultRsp = rsp.(*events.SendTestNotificationDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*defaultRsp))
+}
@@ -0,0 +1,188 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"isc.org/stork/server/auth"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+)
+
+// Type of the key used to store the scope filter in the request context.
+type scopeFilterContextKeyType int
+
+// Key used to store the scope filter in the request context.
+const scopeFilterContextKey scopeFilterContextKeyType = iota
+
+// Returns a copy of the context holding the filter limiting the objects
+// returned to the user to those belonging to the user's scope.
+func withScopeFilter(ctx context.Context, filter *dbmodel.ScopeFilter) context.Context {
+	return context.WithValue(ctx, scopeFilterContextKey, filter)
+}
+
+// Returns the filter limiting the objects returned to the user to those
+// belonging to the user's scope. The filter is stored in the request
+// context by the authorizer. It returns nil if the objects should not
+// be limited.
+func getScopeFilter(ctx context.Context) *dbmodel.ScopeFilter {
+	filter, _ := ctx.Value(scopeFilterContextKey).(*dbmodel.ScopeFilter)
+	return filter
+}
+
+// Resolves the scope of the objects referenced in the requests using
+// the database. The scope is used to check if the objects belong to
+// the scope of the user's groups.
+type dbScopeResolver struct {
+	db *dbops.PgDB
+}
+
+// Makes sure that the resolver implements the expected interface.
+var _ auth.ScopeResolver = (*dbScopeResolver)(nil)
+
+// Wrapper around the auth.ObjectScope with the convenience functions
+// for collecting the IDs.
+type objectScope struct {
+	auth.ObjectScope
+}
+
+// Appends the ID to the list unless it is already present.
+func appendUniqueID(ids []int64, id int64) []int64 {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+// Adds the daemon's app and machine to the scope.
+func (scope *objectScope) addDaemon(daemon *dbmodel.Daemon) {
+	if daemon == nil {
+		return
+	}
+	scope.AppIDs = appendUniqueID(scope.AppIDs, daemon.AppID)
+	if daemon.App != nil {
+		scope.MachineIDs = appendUniqueID(scope.MachineIDs, daemon.App.MachineID)
+	}
+}
+
+// Adds the subnet, its apps and machines to the scope.
+func (scope *objectScope) addSubnet(subnet *dbmodel.Subnet) {
+	scope.SubnetIDs = appendUniqueID(scope.SubnetIDs, subnet.ID)
+	for _, ls := range subnet.LocalSubnets {
+		scope.addDaemon(ls.Daemon)
+	}
+}
+
+// Returns the IDs of the machines, apps and subnets the specified object
+// is associated with. It returns nil scope if the object does not exist.
+func (r *dbScopeResolver) ResolveScope(kind auth.ObjectKind, id int64) (*auth.ObjectScope, error) {
+	scope := &objectScope{}
+	switch kind {
+	case auth.ObjectKindMachine:
+		machine, err := dbmodel.GetMachineByIDWithRelations(r.db, id)
+		if err != nil || machine == nil {
+			return nil, err
+		}
+		scope.MachineIDs = []int64{machine.ID}
+		apps, err := dbmodel.GetAppsByMachine(r.db, machine.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, app := range apps {
+			scope.AppIDs = appendUniqueID(scope.AppIDs, app.ID)
+		}
+	case auth.ObjectKindApp:
+		app, err := dbmodel.GetAppByID(r.db, id)
+		if err != nil || app == nil {
+			return nil, err
+		}
+		scope.AppIDs = []int64{app.ID}
+		scope.MachineIDs = []int64{app.MachineID}
+	case auth.ObjectKindDaemon:
+		daemon, err := dbmodel.GetDaemonByID(r.db, id)
+		if err != nil || daemon == nil {
+			return nil, err
+		}
+		scope.addDaemon(daemon)
+	case auth.ObjectKindLogTarget:
+		logTarget, err := dbmodel.GetLogTargetByID(r.db, id)
+		if err != nil || logTarget == nil {
+			return nil, err
+		}
+		scope.addDaemon(logTarget.Daemon)
+	case auth.ObjectKindHost:
+		host, err := dbmodel.GetHost(r.db, id)
+		if err != nil || host == nil {
+			return nil, err
+		}
+		if host.Subnet != nil {
+			scope.addSubnet(host.Subnet)
+		}
+		for _, lh := range host.LocalHosts {
+			scope.addDaemon(lh.Daemon)
+		}
+	case auth.ObjectKindSubnet:
+		subnet, err := dbmodel.GetSubnet(r.db, id)
+		if err != nil || subnet == nil {
+			return nil, err
+		}
+		scope.addSubnet(subnet)
+	case auth.ObjectKindSharedNetwork:
+		network, err := dbmodel.GetSharedNetwork(r.db, id)
+		if err != nil || network == nil {
+			return nil, err
+		}
+		for i := range network.Subnets {
+			scope.addSubnet(&network.Subnets[i])
+		}
+		for _, lsn := range network.LocalSharedNetworks {
+			scope.addDaemon(lsn.Daemon)
+		}
+	default:
+		return nil, errors.Errorf("unsupported object kind %s", kind)
+	}
+	return &scope.ObjectScope, nil
+}
+
+// Checks if all specified daemons and subnets belong to the user's scope
+// stored in the context. It is used to verify the objects referenced in the
+// request bodies, e.g., the daemons and the subnet of a host reservation.
+// The objects referenced in the URLs are verified by the authorizer. It
+// returns an HTTP error code and an error string to be included in the HTTP
+// response if any of the objects is out of the scope. Otherwise, it returns
+// 0 and an empty string.
+func (r *RestAPI) checkObjectsInScope(ctx context.Context, daemonIDs, subnetIDs []int64) (int, string) {
+	filter := getScopeFilter(ctx)
+	if filter == nil {
+		return 0, ""
+	}
+	resolver := &dbScopeResolver{db: r.DB}
+	for _, objects := range []struct {
+		kind auth.ObjectKind
+		ids  []int64
+	}{
+		{auth.ObjectKindDaemon, daemonIDs},
+		{auth.ObjectKindSubnet, subnetIDs},
+	} {
+		for _, id := range objects.ids {
+			scope, err := resolver.ResolveScope(objects.kind, id)
+			if err != nil {
+				msg := "problem with verifying the scope of the specified objects"
+				log.WithError(err).Error("Failed to resolve the scope of the object")
+				return http.StatusInternalServerError, msg
+			}
+			if scope == nil || !filter.Includes(scope.MachineIDs, scope.AppIDs, scope.SubnetIDs) {
+				msg := fmt.Sprintf("%s %d is out of the user's scope", objects.kind, id)
+				log.Error(msg)
+				return http.StatusForbidden, msg
+			}
+		}
+	}
+	return 0, ""
+}
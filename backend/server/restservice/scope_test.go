@@ -0,0 +1,145 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"isc.org/stork/server/auth"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+)
+
+// Test that the scope of the machines, apps and daemons is resolved
+// from the database.
+func TestResolveScope(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	m := &dbmodel.Machine{
+		Address:   "localhost",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, m)
+	require.NoError(t, err)
+
+	var accessPoints []*dbmodel.AccessPoint
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, false)
+	app := &dbmodel.App{
+		MachineID:    m.ID,
+		Type:         dbmodel.AppTypeKea,
+		AccessPoints: accessPoints,
+		Daemons: []*dbmodel.Daemon{
+			dbmodel.NewKeaDaemon(dbmodel.DaemonNameDHCPv4, true),
+		},
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	resolver := &dbScopeResolver{db: db}
+
+	scope, err := resolver.ResolveScope(auth.ObjectKindMachine, m.ID)
+	require.NoError(t, err)
+	require.NotNil(t, scope)
+	require.Equal(t, []int64{m.ID}, scope.MachineIDs)
+	require.Equal(t, []int64{app.ID}, scope.AppIDs)
+
+	scope, err = resolver.ResolveScope(auth.ObjectKindApp, app.ID)
+	require.NoError(t, err)
+	require.NotNil(t, scope)
+	require.Equal(t, []int64{m.ID}, scope.MachineIDs)
+	require.Equal(t, []int64{app.ID}, scope.AppIDs)
+
+	scope, err = resolver.ResolveScope(auth.ObjectKindDaemon, app.Daemons[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, scope)
+	require.Equal(t, []int64{m.ID}, scope.MachineIDs)
+	require.Equal(t, []int64{app.ID}, scope.AppIDs)
+
+	// Non-existing objects have no scope.
+	scope, err = resolver.ResolveScope(auth.ObjectKindHost, 1024)
+	require.NoError(t, err)
+	require.Nil(t, scope)
+}
+
+// Test that resolving the scope of an unsupported object kind fails.
+func TestResolveScopeUnsupportedKind(t *testing.T) {
+	resolver := &dbScopeResolver{}
+	scope, err := resolver.ResolveScope(auth.ObjectKind("zone"), 1)
+	require.Error(t, err)
+	require.Nil(t, scope)
+}
+
+// Test that the scope filter is stored in and returned from the context.
+func TestScopeFilterContext(t *testing.T) {
+	require.Nil(t, getScopeFilter(context.Background()))
+
+	filter := &dbmodel.ScopeFilter{MachineIDs: []int64{1}}
+	ctx := withScopeFilter(context.Background(), filter)
+	require.Equal(t, filter, getScopeFilter(ctx))
+}
+
+// Test that the daemons and subnets referenced in the request bodies
+// are verified against the scope stored in the context.
+func TestCheckObjectsInScope(t *testing.T) {
+	db, _, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	m := &dbmodel.Machine{
+		Address:   "localhost",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, m)
+	require.NoError(t, err)
+
+	var accessPoints []*dbmodel.AccessPoint
+	accessPoints = dbmodel.AppendAccessPoint(accessPoints, dbmodel.AccessPointControl, "localhost", "", 8000, false)
+	app := &dbmodel.App{
+		MachineID:    m.ID,
+		Type:         dbmodel.AppTypeKea,
+		AccessPoints: accessPoints,
+		Daemons: []*dbmodel.Daemon{
+			dbmodel.NewKeaDaemon(dbmodel.DaemonNameDHCPv4, true),
+		},
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	subnet := &dbmodel.Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err = dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	rapi := &RestAPI{DB: db}
+	daemonIDs := []int64{app.Daemons[0].ID}
+
+	// No scope.
+	code, msg := rapi.checkObjectsInScope(context.Background(), daemonIDs, []int64{subnet.ID})
+	require.Zero(t, code)
+	require.Empty(t, msg)
+
+	// The daemon belongs to the scope.
+	ctx := withScopeFilter(context.Background(), &dbmodel.ScopeFilter{MachineIDs: []int64{m.ID}})
+	code, _ = rapi.checkObjectsInScope(ctx, daemonIDs, nil)
+	require.Zero(t, code)
+
+	// The subnet is not associated with the daemon.
+	code, msg = rapi.checkObjectsInScope(ctx, daemonIDs, []int64{subnet.ID})
+	require.Equal(t, http.StatusForbidden, code)
+	require.Contains(t, msg, "subnet")
+
+	// The subnet belongs to the scope but the daemon does not.
+	ctx = withScopeFilter(context.Background(), &dbmodel.ScopeFilter{SubnetIDs: []int64{subnet.ID}})
+	code, _ = rapi.checkObjectsInScope(ctx, nil, []int64{subnet.ID})
+	require.Zero(t, code)
+	code, msg = rapi.checkObjectsInScope(ctx, daemonIDs, []int64{subnet.ID})
+	require.Equal(t, http.StatusForbidden, code)
+	require.Contains(t, msg, "daemon")
+
+	// Non-existing objects are out of the scope.
+	code, _ = rapi.checkObjectsInScope(ctx, []int64{1024}, nil)
+	require.Equal(t, http.StatusForbidden, code)
+}
@@ -22,7 +22,7 @@ func handleSearchError(err error, text string) middleware.Responder {
 }
 
 // Search through different tables in database. Currently supported tables are:
-// machines, apps, subnets, shared networks, hosts, users, groups.
+// machines, apps, subnets, shared networks, hosts, users, groups, zones.
 // If filter text is empty then empty result is returned.
 func (r *RestAPI) SearchRecords(ctx context.Context, params search.SearchRecordsParams) middleware.Responder {
 	// if empty text is provided then empty result is returned
@@ -35,12 +35,14 @@ func (r *RestAPI) SearchRecords(ctx context.Context, params search.SearchRecords
 			Apps:           &models.Apps{},
 			Users:          &models.Users{},
 			Groups:         &models.Groups{},
+			Zones:          &models.Bind9Zones{},
 		}
 		rsp := search.NewSearchRecordsOK().WithPayload(result)
 		return rsp
 	}
 	text := strings.TrimSpace(*params.Text)
-	filters := &dbmodel.SubnetsByPageFilters{Text: &text}
+	scope := getScopeFilter(ctx)
+	filters := &dbmodel.SubnetsByPageFilters{Text: &text, Scope: scope}
 
 	// get list of subnets
 	subnets, err := r.getSubnets(0, 5, filters, "", dbmodel.SortDirAny)
@@ -49,26 +51,26 @@ func (r *RestAPI) SearchRecords(ctx context.Context, params search.SearchRecords
 	}
 
 	// get list of shared networks
-	sharedNetworks, err := r.getSharedNetworks(0, 5, 0, 0, &text, "", dbmodel.SortDirAny)
+	sharedNetworks, err := r.getSharedNetworks(0, 5, 0, 0, &text, scope, "", dbmodel.SortDirAny)
 	if err != nil {
 		return handleSearchError(err, "Cannot get shared networks from the db")
 	}
 
 	// get list of hosts
-	hosts, err := r.getHosts(0, 5, 0, nil, nil, &text, nil, "", dbmodel.SortDirAny)
+	hosts, err := r.getHosts(0, 5, 0, nil, nil, &text, nil, scope, "", dbmodel.SortDirAny)
 	if err != nil {
 		return handleSearchError(err, "Cannot get hosts from the db")
 	}
 
 	// get list of machines
 	authorized := true
-	machines, err := r.getMachines(0, 5, &text, &authorized, "", dbmodel.SortDirAny)
+	machines, err := r.getMachines(0, 5, &text, &authorized, scope, "", dbmodel.SortDirAny)
 	if err != nil {
 		return handleSearchError(err, "Cannot get machines from the db")
 	}
 
 	// get list of apps
-	apps, err := r.getApps(0, 5, &text, "", "", dbmodel.SortDirAny)
+	apps, err := r.getApps(0, 5, &text, "", scope, "", dbmodel.SortDirAny)
 	if err != nil {
 		return handleSearchError(err, "Cannot get apps from the db")
 	}
@@ -85,6 +87,12 @@ func (r *RestAPI) SearchRecords(ctx context.Context, params search.SearchRecords
 		return handleSearchError(err, "Cannot get groups from the db")
 	}
 
+	// get list of zones
+	zones, err := r.getZones(0, 5, &dbmodel.Bind9ZonesByPageFilters{Text: &text}, "", dbmodel.SortDirAny)
+	if err != nil {
+		return handleSearchError(err, "Cannot get zones from the db")
+	}
+
 	// combine gathered information
 	result := &models.SearchResult{
 		Subnets:        subnets,
@@ -94,6 +102,7 @@ func (r *RestAPI) SearchRecords(ctx context.Context, params search.SearchRecords
 		Apps:           apps,
 		Users:          users,
 		Groups:         groups,
+		Zones:          zones,
 	}
 
 	rsp := search.NewSearchRecordsOK().WithPayload(result)
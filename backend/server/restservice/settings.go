@@ -25,14 +25,22 @@ func (r *RestAPI) GetSettings(ctx context.Context, params settings.GetSettingsPa
 	}
 
 	s := &models.Settings{
-		Bind9StatsPullerInterval: dbSettingsMap["bind9_stats_puller_interval"].(int64),
-		GrafanaURL:               dbSettingsMap["grafana_url"].(string),
-		KeaHostsPullerInterval:   dbSettingsMap["kea_hosts_puller_interval"].(int64),
-		KeaStatsPullerInterval:   dbSettingsMap["kea_stats_puller_interval"].(int64),
-		KeaStatusPullerInterval:  dbSettingsMap["kea_status_puller_interval"].(int64),
-		AppsStatePullerInterval:  dbSettingsMap["apps_state_puller_interval"].(int64),
-		PrometheusURL:            dbSettingsMap["prometheus_url"].(string),
-		MetricsCollectorInterval: dbSettingsMap["metrics_collector_interval"].(int64),
+		Bind9StatsPullerInterval:   dbSettingsMap["bind9_stats_puller_interval"].(int64),
+		Bind9ZonesPullerInterval:   dbSettingsMap["bind9_zones_puller_interval"].(int64),
+		GrafanaURL:                 dbSettingsMap["grafana_url"].(string),
+		KeaHostsPullerInterval:     dbSettingsMap["kea_hosts_puller_interval"].(int64),
+		KeaStatsPullerInterval:     dbSettingsMap["kea_stats_puller_interval"].(int64),
+		KeaStatusPullerInterval:    dbSettingsMap["kea_status_puller_interval"].(int64),
+		KeaLogEventsPullerInterval: dbSettingsMap["kea_log_events_puller_interval"].(int64),
+		AppsStatePullerInterval:    dbSettingsMap["apps_state_puller_interval"].(int64),
+		PrometheusURL:              dbSettingsMap["prometheus_url"].(string),
+		MetricsCollectorInterval:   dbSettingsMap["metrics_collector_interval"].(int64),
+		KeaTwoPhaseCommit:          dbSettingsMap["kea_two_phase_commit"].(bool),
+		UtilizationRawRetention:    dbSettingsMap[dbmodel.UtilizationRawRetentionSetting].(int64),
+		UtilizationHourlyRetention: dbSettingsMap[dbmodel.UtilizationHourlyRetentionSetting].(int64),
+		UtilizationDailyRetention:  dbSettingsMap[dbmodel.UtilizationDailyRetentionSetting].(int64),
+		UtilizationForecastWindow:  dbSettingsMap[dbmodel.UtilizationForecastWindowSetting].(int64),
+		UtilizationForecastHorizon: dbSettingsMap[dbmodel.UtilizationForecastHorizonSetting].(int64),
 	}
 	rsp := settings.NewGetSettingsOK().WithPayload(s)
 
@@ -61,6 +69,11 @@ func (r *RestAPI) UpdateSettings(ctx context.Context, params settings.UpdateSett
 		log.Error(err)
 		return errRsp
 	}
+	err = dbmodel.SetSettingInt(r.DB, "bind9_zones_puller_interval", s.Bind9ZonesPullerInterval)
+	if err != nil {
+		log.Error(err)
+		return errRsp
+	}
 	err = dbmodel.SetSettingStr(r.DB, "grafana_url", s.GrafanaURL)
 	if err != nil {
 		log.Error(err)
@@ -81,6 +94,11 @@ func (r *RestAPI) UpdateSettings(ctx context.Context, params settings.UpdateSett
 		log.Error(err)
 		return errRsp
 	}
+	err = dbmodel.SetSettingInt(r.DB, "kea_log_events_puller_interval", s.KeaLogEventsPullerInterval)
+	if err != nil {
+		log.Error(err)
+		return errRsp
+	}
 	err = dbmodel.SetSettingInt(r.DB, "apps_state_puller_interval", s.KeaStatusPullerInterval)
 	if err != nil {
 		log.Error(err)
@@ -96,6 +114,36 @@ func (r *RestAPI) UpdateSettings(ctx context.Context, params settings.UpdateSett
 		log.Error(err)
 		return errRsp
 	}
+	err = dbmodel.SetSettingBool(r.DB, "kea_two_phase_commit", s.KeaTwoPhaseCommit)
+	if err != nil {
+		log.Error(err)
+		return errRsp
+	}
+	err = dbmodel.SetSettingInt(r.DB, dbmodel.UtilizationRawRetentionSetting, s.UtilizationRawRetention)
+	if err != nil {
+		log.Error(err)
+		return errRsp
+	}
+	err = dbmodel.SetSettingInt(r.DB, dbmodel.UtilizationHourlyRetentionSetting, s.UtilizationHourlyRetention)
+	if err != nil {
+		log.Error(err)
+		return errRsp
+	}
+	err = dbmodel.SetSettingInt(r.DB, dbmodel.UtilizationDailyRetentionSetting, s.UtilizationDailyRetention)
+	if err != nil {
+		log.Error(err)
+		return errRsp
+	}
+	err = dbmodel.SetSettingInt(r.DB, dbmodel.UtilizationForecastWindowSetting, s.UtilizationForecastWindow)
+	if err != nil {
+		log.Error(err)
+		return errRsp
+	}
+	err = dbmodel.SetSettingInt(r.DB, dbmodel.UtilizationForecastHorizonSetting, s.UtilizationForecastHorizon)
+	if err != nil {
+		log.Error(err)
+		return errRsp
+	}
 
 	rsp := settings.NewUpdateSettingsOK()
 	return rsp
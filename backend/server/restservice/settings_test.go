@@ -37,13 +37,23 @@ func TestSettings(t *testing.T) {
 	require.IsType(t, &settings.GetSettingsOK{}, rsp)
 	okRsp := rsp.(*settings.GetSettingsOK)
 	require.EqualValues(t, 60, okRsp.Payload.Bind9StatsPullerInterval)
+	require.EqualValues(t, 60, okRsp.Payload.Bind9ZonesPullerInterval)
 	require.Empty(t, okRsp.Payload.GrafanaURL)
+	require.False(t, okRsp.Payload.KeaTwoPhaseCommit)
+	require.EqualValues(t, 48, okRsp.Payload.UtilizationRawRetention)
+	require.EqualValues(t, 30, okRsp.Payload.UtilizationHourlyRetention)
+	require.EqualValues(t, 730, okRsp.Payload.UtilizationDailyRetention)
+	require.EqualValues(t, 7, okRsp.Payload.UtilizationForecastWindow)
+	require.EqualValues(t, 14, okRsp.Payload.UtilizationForecastHorizon)
 
 	// update settings
 	paramsUS := settings.UpdateSettingsParams{
 		Settings: &models.Settings{
 			Bind9StatsPullerInterval: 10,
+			Bind9ZonesPullerInterval: 20,
 			GrafanaURL:               "http://localhost:3000",
+			KeaTwoPhaseCommit:        true,
+			UtilizationRawRetention:  24,
 		},
 	}
 	rsp = rapi.UpdateSettings(ctx, paramsUS)
@@ -55,5 +65,8 @@ func TestSettings(t *testing.T) {
 	require.IsType(t, &settings.GetSettingsOK{}, rsp)
 	okRsp = rsp.(*settings.GetSettingsOK)
 	require.EqualValues(t, 10, okRsp.Payload.Bind9StatsPullerInterval)
+	require.EqualValues(t, 20, okRsp.Payload.Bind9ZonesPullerInterval)
 	require.EqualValues(t, "http://localhost:3000", okRsp.Payload.GrafanaURL)
+	require.True(t, okRsp.Payload.KeaTwoPhaseCommit)
+	require.EqualValues(t, 24, okRsp.Payload.UtilizationRawRetention)
 }
@@ -4,9 +4,15 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
+	"time"
 
 	"github.com/go-openapi/runtime/middleware"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	keaconfig "isc.org/stork/appcfg/kea"
+	"isc.org/stork/server/apps/kea"
+	"isc.org/stork/server/config"
 	dbmodel "isc.org/stork/server/database/model"
 	storkutil "isc.org/stork/util"
 
@@ -17,13 +23,17 @@ import (
 // Creates a REST API representation of a subnet from a database model.
 func (r *RestAPI) subnetToRestAPI(sn *dbmodel.Subnet) *models.Subnet {
 	subnet := &models.Subnet{
-		ID:               sn.ID,
-		Subnet:           sn.Prefix,
-		ClientClass:      sn.ClientClass,
-		AddrUtilization:  float64(sn.AddrUtilization) / 10,
-		PdUtilization:    float64(sn.PdUtilization) / 10,
-		Stats:            sn.Stats,
-		StatsCollectedAt: convertToOptionalDatetime(sn.StatsCollectedAt),
+		ID:                   sn.ID,
+		Subnet:               sn.Prefix,
+		ClientClass:          sn.ClientClass,
+		AddrUtilization:      float64(sn.AddrUtilization) / 10,
+		PdUtilization:        float64(sn.PdUtilization) / 10,
+		AddrUtilizationAlert: models.UtilizationAlertLevel(sn.AddrUtilizationAlert.String()),
+		PdUtilizationAlert:   models.UtilizationAlertLevel(sn.PdUtilizationAlert.String()),
+		AddrExhaustionDays:   sn.AddrExhaustionDays,
+		PdExhaustionDays:     sn.PdExhaustionDays,
+		Stats:                sn.Stats,
+		StatsCollectedAt:     convertToOptionalDatetime(sn.StatsCollectedAt),
 	}
 
 	if sn.SharedNetwork != nil {
@@ -45,6 +55,15 @@ func (r *RestAPI) subnetToRestAPI(sn *dbmodel.Subnet) *models.Subnet {
 		for _, poolDetails := range lsn.AddressPools {
 			pool := poolDetails.LowerBound + "-" + poolDetails.UpperBound
 			localSubnet.Pools = append(localSubnet.Pools, pool)
+			if poolDetails.ExhaustionDays != nil {
+				localSubnet.PoolExhaustionForecasts = append(
+					localSubnet.PoolExhaustionForecasts,
+					&models.PoolExhaustionForecast{
+						Pool:           &pool,
+						ExhaustionDays: poolDetails.ExhaustionDays,
+					},
+				)
+			}
 		}
 
 		for _, prefixPoolDetails := range lsn.PrefixPools {
@@ -58,6 +77,16 @@ func (r *RestAPI) subnetToRestAPI(sn *dbmodel.Subnet) *models.Subnet {
 					ExcludedPrefix:  prefixPoolDetails.ExcludedPrefix,
 				},
 			)
+			if prefixPoolDetails.ExhaustionDays != nil {
+				localSubnet.PoolExhaustionForecasts = append(
+					localSubnet.PoolExhaustionForecasts,
+					&models.PoolExhaustionForecast{
+						Pool:            &prefix,
+						DelegatedLength: delegatedLength,
+						ExhaustionDays:  prefixPoolDetails.ExhaustionDays,
+					},
+				)
+			}
 		}
 
 		// Subnet level Kea DHCP parameters.
@@ -335,6 +364,7 @@ func (r *RestAPI) GetSubnets(ctx context.Context, params dhcp.GetSubnetsParams)
 		Family:        params.DhcpVersion,
 		Text:          params.Text,
 		LocalSubnetID: params.LocalSubnetID,
+		Scope:         getScopeFilter(ctx),
 	}
 
 	subnets, err := r.getSubnets(start, limit, filters, "", dbmodel.SortDirAsc)
@@ -392,14 +422,18 @@ func (r *RestAPI) sharedNetworkToRestAPI(sn *dbmodel.SharedNetwork) *models.Shar
 	}
 	// Create shared network.
 	sharedNetwork := &models.SharedNetwork{
-		ID:               sn.ID,
-		Name:             sn.Name,
-		Universe:         int64(sn.Family),
-		Subnets:          subnets,
-		AddrUtilization:  float64(sn.AddrUtilization) / 10,
-		PdUtilization:    float64(sn.PdUtilization) / 10,
-		Stats:            sn.Stats,
-		StatsCollectedAt: convertToOptionalDatetime(sn.StatsCollectedAt),
+		ID:                   sn.ID,
+		Name:                 sn.Name,
+		Universe:             int64(sn.Family),
+		Subnets:              subnets,
+		AddrUtilization:      float64(sn.AddrUtilization) / 10,
+		PdUtilization:        float64(sn.PdUtilization) / 10,
+		AddrUtilizationAlert: models.UtilizationAlertLevel(sn.AddrUtilizationAlert.String()),
+		PdUtilizationAlert:   models.UtilizationAlertLevel(sn.PdUtilizationAlert.String()),
+		AddrExhaustionDays:   sn.AddrExhaustionDays,
+		PdExhaustionDays:     sn.PdExhaustionDays,
+		Stats:                sn.Stats,
+		StatsCollectedAt:     convertToOptionalDatetime(sn.StatsCollectedAt),
 	}
 
 	for _, lsn := range sn.LocalSharedNetworks {
@@ -560,9 +594,9 @@ func (r *RestAPI) sharedNetworkToRestAPI(sn *dbmodel.SharedNetwork) *models.Shar
 	return sharedNetwork
 }
 
-func (r *RestAPI) getSharedNetworks(offset, limit, appID, family int64, filterText *string, sortField string, sortDir dbmodel.SortDirEnum) (*models.SharedNetworks, error) {
+func (r *RestAPI) getSharedNetworks(offset, limit, appID, family int64, filterText *string, scope *dbmodel.ScopeFilter, sortField string, sortDir dbmodel.SortDirEnum) (*models.SharedNetworks, error) {
 	// get shared networks from db
-	dbSharedNetworks, total, err := dbmodel.GetSharedNetworksByPage(r.DB, offset, limit, appID, family, filterText, sortField, sortDir)
+	dbSharedNetworks, total, err := dbmodel.GetSharedNetworksByPage(r.DB, offset, limit, appID, family, filterText, scope, sortField, sortDir)
 	if err != nil {
 		return nil, err
 	}
@@ -606,7 +640,7 @@ func (r *RestAPI) GetSharedNetworks(ctx context.Context, params dhcp.GetSharedNe
 	}
 
 	// get shared networks from db
-	sharedNetworks, err := r.getSharedNetworks(start, limit, appID, dhcpVer, params.Text, "", dbmodel.SortDirAsc)
+	sharedNetworks, err := r.getSharedNetworks(start, limit, appID, dhcpVer, params.Text, getScopeFilter(ctx), "", dbmodel.SortDirAsc)
 	if err != nil {
 		msg := "Cannot get shared network from db"
 		log.Error(err)
@@ -649,3 +683,1054 @@ func (r *RestAPI) GetSharedNetwork(ctx context.Context, params dhcp.GetSharedNet
 	rsp := dhcp.NewGetSharedNetworkOK().WithPayload(sharedNetwork)
 	return rsp
 }
+
+// Converts subnet-level Kea parameters from the REST API format to the
+// format used in the database.
+func convertToSubnetKeaParameters(params *models.KeaConfigSubnetDerivedParameters) *keaconfig.SubnetParameters {
+	keaParameters := &keaconfig.SubnetParameters{
+		CacheParameters: keaconfig.CacheParameters{
+			CacheThreshold: params.CacheThreshold,
+			CacheMaxAge:    params.CacheMaxAge,
+		},
+		ClientClassParameters: keaconfig.ClientClassParameters{
+			ClientClass:          params.ClientClass,
+			RequireClientClasses: params.RequireClientClasses,
+		},
+		DDNSParameters: keaconfig.DDNSParameters{
+			DDNSGeneratedPrefix:       params.DdnsGeneratedPrefix,
+			DDNSOverrideClientUpdate:  params.DdnsOverrideClientUpdate,
+			DDNSOverrideNoUpdate:      params.DdnsOverrideNoUpdate,
+			DDNSQualifyingSuffix:      params.DdnsQualifyingSuffix,
+			DDNSReplaceClientName:     params.DdnsReplaceClientName,
+			DDNSSendUpdates:           params.DdnsSendUpdates,
+			DDNSUpdateOnRenew:         params.DdnsUpdateOnRenew,
+			DDNSUseConflictResolution: params.DdnsUseConflictResolution,
+		},
+		FourOverSixParameters: keaconfig.FourOverSixParameters{
+			FourOverSixInterface:   params.FourOverSixInterface,
+			FourOverSixInterfaceID: params.FourOverSixInterfaceID,
+			FourOverSixSubnet:      params.FourOverSixSubnet,
+		},
+		HostnameCharParameters: keaconfig.HostnameCharParameters{
+			HostnameCharReplacement: params.HostnameCharReplacement,
+			HostnameCharSet:         params.HostnameCharSet,
+		},
+		PreferredLifetimeParameters: keaconfig.PreferredLifetimeParameters{
+			MaxPreferredLifetime: params.MaxPreferredLifetime,
+			MinPreferredLifetime: params.MinPreferredLifetime,
+			PreferredLifetime:    params.PreferredLifetime,
+		},
+		ReservationParameters: keaconfig.ReservationParameters{
+			ReservationMode:       params.ReservationMode,
+			ReservationsGlobal:    params.ReservationsGlobal,
+			ReservationsInSubnet:  params.ReservationsInSubnet,
+			ReservationsOutOfPool: params.ReservationsOutOfPool,
+		},
+		TimerParameters: keaconfig.TimerParameters{
+			CalculateTeeTimes: params.CalculateTeeTimes,
+			RebindTimer:       params.RebindTimer,
+			RenewTimer:        params.RenewTimer,
+			T1Percent:         params.T1Percent,
+			T2Percent:         params.T2Percent,
+		},
+		ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+			MaxValidLifetime: params.MaxValidLifetime,
+			MinValidLifetime: params.MinValidLifetime,
+			ValidLifetime:    params.ValidLifetime,
+		},
+		Allocator:         params.Allocator,
+		Authoritative:     params.Authoritative,
+		BootFileName:      params.BootFileName,
+		Interface:         params.Interface,
+		InterfaceID:       params.InterfaceID,
+		MatchClientID:     params.MatchClientID,
+		NextServer:        params.NextServer,
+		PDAllocator:       params.PdAllocator,
+		RapidCommit:       params.RapidCommit,
+		ServerHostname:    params.ServerHostname,
+		StoreExtendedInfo: params.StoreExtendedInfo,
+	}
+	if params.Relay != nil {
+		keaParameters.Relay = &keaconfig.Relay{
+			IPAddresses: params.Relay.IPAddresses,
+		}
+	}
+	return keaParameters
+}
+
+// Converts subnet data from the REST API format to the format used in the
+// database. The daemon and shared network information is not populated
+// by this function. The caller should use the PopulateDaemons and
+// PopulateSharedNetwork functions to fetch it from the database.
+func (r *RestAPI) convertToSubnet(restSubnet *models.Subnet) (*dbmodel.Subnet, error) {
+	subnet := &dbmodel.Subnet{
+		ID:              restSubnet.ID,
+		Prefix:          restSubnet.Subnet,
+		ClientClass:     restSubnet.ClientClass,
+		SharedNetworkID: restSubnet.SharedNetworkID,
+	}
+	for _, ls := range restSubnet.LocalSubnets {
+		localSubnet := &dbmodel.LocalSubnet{
+			DaemonID:      ls.DaemonID,
+			LocalSubnetID: ls.ID,
+		}
+		// Convert address pools.
+		for _, p := range ls.Pools {
+			pool, err := dbmodel.NewAddressPoolFromRange(p)
+			if err != nil {
+				return nil, err
+			}
+			localSubnet.AddressPools = append(localSubnet.AddressPools, *pool)
+		}
+		// Convert delegated prefix pools.
+		for _, p := range ls.PrefixDelegationPools {
+			if p.Prefix == nil || p.DelegatedLength == nil {
+				return nil, errors.New("delegated prefix pool lacks prefix or delegated length")
+			}
+			pool, err := dbmodel.NewPrefixPool(*p.Prefix, int(*p.DelegatedLength), p.ExcludedPrefix)
+			if err != nil {
+				return nil, err
+			}
+			localSubnet.PrefixPools = append(localSubnet.PrefixPools, *pool)
+		}
+		// Convert subnet-level Kea parameters and DHCP options. The shared
+		// network level and global parameters are ignored because they
+		// are not configured within the subnet.
+		if ls.KeaConfigSubnetParameters != nil && ls.KeaConfigSubnetParameters.SubnetLevelParameters != nil {
+			params := ls.KeaConfigSubnetParameters.SubnetLevelParameters
+			localSubnet.KeaParameters = convertToSubnetKeaParameters(params)
+			var err error
+			localSubnet.DHCPOptionSet, err = r.flattenDHCPOptions("", params.Options, 0)
+			if err != nil {
+				return nil, err
+			}
+			if len(localSubnet.DHCPOptionSet) > 0 {
+				localSubnet.DHCPOptionSetHash = storkutil.Fnv128(localSubnet.DHCPOptionSet)
+			}
+		}
+		subnet.SetLocalSubnet(localSubnet)
+	}
+	return subnet, nil
+}
+
+// Fetches the Kea daemons having the subnet_cmds hook library loaded and
+// the names of the client classes configured in all Kea daemons. The daemons
+// and the client classes are displayed in the forms for adding and editing
+// subnets and shared networks. If an error occurs, an http error code and
+// message are returned.
+func (r *RestAPI) getSubnetCmdsDaemonsAndClientClasses() ([]*models.KeaDaemon, []string, int, string) {
+	// A list of Kea DHCP daemons will be needed in the user form,
+	// so the user can select which servers send the configuration to.
+	daemons, err := dbmodel.GetKeaDHCPDaemons(r.DB)
+	if err != nil {
+		msg := "problem with fetching Kea daemons from the database"
+		log.Error(err)
+		return nil, nil, http.StatusInternalServerError, msg
+	}
+	// Convert daemons list to REST API format and extract their configured
+	// client classes.
+	respDaemons := []*models.KeaDaemon{}
+	respClientClasses := []string{}
+	clientClassesMap := make(map[string]bool)
+	for i := range daemons {
+		if daemons[i].KeaDaemon != nil && daemons[i].KeaDaemon.Config != nil {
+			// Filter the daemons with subnet_cmds hook library.
+			if _, _, exists := daemons[i].KeaDaemon.Config.GetHookLibrary("libdhcp_subnet_cmds"); exists {
+				respDaemons = append(respDaemons, keaDaemonToRestAPI(&daemons[i]))
+			}
+			clientClasses := daemons[i].KeaDaemon.Config.GetClientClasses()
+			for _, c := range clientClasses {
+				clientClassesMap[c.Name] = true
+			}
+		}
+	}
+	// Turn the class map to a slice and sort it by a class name.
+	for c := range clientClassesMap {
+		respClientClasses = append(respClientClasses, c)
+	}
+	sort.Strings(respClientClasses)
+	return respDaemons, respClientClasses, 0, ""
+}
+
+// Creates the transaction context for the logged user. If an error occurs,
+// an http error code and message are returned.
+func (r *RestAPI) createTransactionContext(ctx context.Context) (context.Context, int, string) {
+	// Get the logged user's ID.
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		msg := "unable to begin transaction because user is not logged in"
+		log.Error("Problem with creating transaction context because user has no session")
+		return nil, http.StatusForbidden, msg
+	}
+	// Create configuration context.
+	cctx, err := r.ConfigManager.CreateContext(int64(user.ID))
+	if err != nil {
+		msg := "problem with creating transaction context"
+		log.Error(err)
+		return nil, http.StatusInternalServerError, msg
+	}
+	return cctx, 0, ""
+}
+
+// Common function for executed when creating a new transaction for when the
+// subnet is created or updated. It fetches available DHCP daemons, shared
+// networks and client classes. It also creates transaction context. If an
+// error occurs, an http error code and message are returned.
+func (r *RestAPI) commonCreateOrUpdateSubnetBegin(ctx context.Context) ([]*models.KeaDaemon, []*models.SharedNetwork, []string, context.Context, int, string) {
+	respDaemons, respClientClasses, code, msg := r.getSubnetCmdsDaemonsAndClientClasses()
+	if code != 0 {
+		return nil, nil, nil, nil, code, msg
+	}
+	// If there are no daemons with subnet_cmds hooks library loaded there is
+	// no way to add or update subnets. In that case, we don't begin a transaction.
+	if len(respDaemons) == 0 {
+		msg := "unable to begin transaction for the subnet because there are no Kea servers with subnet_cmds hooks library available"
+		log.Error(msg)
+		return nil, nil, nil, nil, http.StatusBadRequest, msg
+	}
+	// Subnets can be associated with shared networks. The user needs
+	// a current list of available shared networks.
+	sharedNetworks, err := dbmodel.GetAllSharedNetworks(r.DB, 0)
+	if err != nil {
+		msg := "problem with fetching shared networks from the database"
+		log.Error(err)
+		return nil, nil, nil, nil, http.StatusInternalServerError, msg
+	}
+	// Convert shared networks list to REST API format.
+	respSharedNetworks := []*models.SharedNetwork{}
+	for i := range sharedNetworks {
+		respSharedNetworks = append(respSharedNetworks, r.sharedNetworkToRestAPI(&sharedNetworks[i]))
+	}
+	cctx, code, msg := r.createTransactionContext(ctx)
+	if code != 0 {
+		return nil, nil, nil, nil, code, msg
+	}
+	return respDaemons, respSharedNetworks, respClientClasses, cctx, 0, ""
+}
+
+// Implements the POST call to create new transaction for adding a new
+// subnet (subnets/new/transaction).
+func (r *RestAPI) CreateSubnetBegin(ctx context.Context, params dhcp.CreateSubnetBeginParams) middleware.Responder {
+	// Execute the common part between create and update operations. It retrieves,
+	// daemons, shared networks, client classes and creates the transaction context.
+	respDaemons, respSharedNetworks, respClientClasses, cctx, code, msg := r.commonCreateOrUpdateSubnetBegin(ctx)
+	if code != 0 {
+		// Error case.
+		rsp := dhcp.NewCreateSubnetBeginDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Begin subnet add transaction.
+	var err error
+	if cctx, err = r.ConfigManager.GetKeaModule().BeginSubnetAdd(cctx); err != nil {
+		msg := "problem with initializing transaction for creating new subnet"
+		log.Error(msg)
+		rsp := dhcp.NewCreateSubnetBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	// Retrieve the generated context ID.
+	cctxID, ok := config.GetValueAsInt64(cctx, config.ContextIDKey)
+	if !ok {
+		msg := "problem with retrieving context ID for a transaction"
+		log.Error(msg)
+		rsp := dhcp.NewCreateSubnetBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Remember the context, i.e. new transaction has been successfully created.
+	_ = r.ConfigManager.RememberContext(cctx, time.Minute*10)
+
+	// Return transaction ID, daemons and shared networks to the user.
+	contents := &models.CreateSubnetBeginResponse{
+		ID:             cctxID,
+		Daemons:        respDaemons,
+		SharedNetworks: respSharedNetworks,
+		ClientClasses:  respClientClasses,
+	}
+	rsp := dhcp.NewCreateSubnetBeginOK().WithPayload(contents)
+	return rsp
+}
+
+// Common function that implements the POST calls to apply and commit a new
+// or updated subnet. The ctx parameter is the REST API context. The
+// transactionID is the identifier of the current configuration transaction
+// used by the function to recover the transaction context. The restSubnet is
+// the pointer to the subnet specified by the user. It is converted by this
+// function to the database model. The applyFunc is the function of the Kea
+// config module that applies the specified subnet. It is one of the
+// ApplySubnetAdd or ApplySubnetUpdate. This function returns the HTTP error
+// code if an error occurs or 0 when there is no error. In addition it returns
+// an error string to be included in the HTTP response or an empty string if
+// there is no error.
+func (r *RestAPI) commonCreateOrUpdateSubnetSubmit(ctx context.Context, transactionID int64, restSubnet *models.Subnet, applyFunc func(context.Context, *dbmodel.Subnet) (context.Context, error)) (int, string) {
+	// Make sure that the subnet information is present.
+	if restSubnet == nil {
+		msg := "subnet information not specified"
+		log.Errorf(msg)
+		return http.StatusBadRequest, msg
+	}
+	// Get the user ID and recover the transaction context.
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		msg := "unable to submit because user is not logged in"
+		log.Error("Problem with recovering transaction context because user has no session")
+		return http.StatusForbidden, msg
+	}
+	// Retrieve the context from the config manager.
+	cctx, _ := r.ConfigManager.RecoverContext(transactionID, int64(user.ID))
+	if cctx == nil {
+		msg := "transaction expired"
+		log.Errorf("Problem with recovering transaction context for transaction ID %d and user ID %d", transactionID, user.ID)
+		return http.StatusNotFound, msg
+	}
+
+	// Convert subnet information from REST API to database format.
+	subnet, err := r.convertToSubnet(restSubnet)
+	if err != nil {
+		msg := "error parsing specified subnet"
+		log.Error(err)
+		return http.StatusBadRequest, msg
+	}
+	err = subnet.PopulateDaemons(r.DB)
+	if err != nil {
+		msg := "specified subnet is associated with daemons that no longer exist"
+		log.Error(err)
+		return http.StatusNotFound, msg
+	}
+	err = subnet.PopulateSharedNetwork(r.DB)
+	if err != nil {
+		msg := "problem with retrieving shared network association with the subnet"
+		log.Error(err)
+		return http.StatusInternalServerError, msg
+	}
+	// Make sure that the user does not add the subnet to the daemons out
+	// of the user's scope.
+	var daemonIDs []int64
+	for _, ls := range subnet.LocalSubnets {
+		daemonIDs = append(daemonIDs, ls.DaemonID)
+	}
+	if code, msg := r.checkObjectsInScope(ctx, daemonIDs, nil); code != 0 {
+		return code, msg
+	}
+	// Apply the subnet information (create Kea commands).
+	cctx, err = applyFunc(cctx, subnet)
+	if err != nil {
+		msg := "problem with applying subnet information"
+		log.Error(err)
+		return http.StatusInternalServerError, msg
+	}
+	// Send the commands to Kea servers.
+	cctx, err = r.ConfigManager.Commit(cctx)
+	if err != nil {
+		msg := fmt.Sprintf("problem with committing subnet information: %s", err)
+		log.Error(err)
+		return http.StatusConflict, msg
+	}
+	// Everything ok. Cleanup and send OK to the client.
+	r.ConfigManager.Done(cctx)
+	return 0, ""
+}
+
+// Implements the POST call to apply and commit a new subnet (subnets/new/transaction/{id}/submit).
+func (r *RestAPI) CreateSubnetSubmit(ctx context.Context, params dhcp.CreateSubnetSubmitParams) middleware.Responder {
+	if code, msg := r.commonCreateOrUpdateSubnetSubmit(ctx, params.ID, params.Subnet, r.ConfigManager.GetKeaModule().ApplySubnetAdd); code != 0 {
+		// Error case.
+		rsp := dhcp.NewCreateSubnetSubmitDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewCreateSubnetSubmitOK()
+	return rsp
+}
+
+// Implements the DELETE call to cancel adding new subnet (subnets/new/transaction/{id}). It
+// removes the specified transaction from the config manager, if the transaction exists.
+func (r *RestAPI) CreateSubnetDelete(ctx context.Context, params dhcp.CreateSubnetDeleteParams) middleware.Responder {
+	if code, msg := r.commonCreateOrUpdateDelete(ctx, params.ID); code != 0 {
+		// Error case.
+		rsp := dhcp.NewCreateSubnetDeleteDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewCreateSubnetDeleteOK()
+	return rsp
+}
+
+// Implements the POST call to create new transaction for updating an
+// existing subnet (subnets/{subnetId}/transaction).
+func (r *RestAPI) UpdateSubnetBegin(ctx context.Context, params dhcp.UpdateSubnetBeginParams) middleware.Responder {
+	// Execute the common part between create and update operations. It retrieves,
+	// daemons, shared networks, client classes and creates the transaction context.
+	respDaemons, respSharedNetworks, respClientClasses, cctx, code, msg := r.commonCreateOrUpdateSubnetBegin(ctx)
+	if code != 0 {
+		// Error case.
+		rsp := dhcp.NewUpdateSubnetBeginDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Begin subnet update transaction. It retrieves current subnet information and
+	// locks daemons for updates.
+	var err error
+	cctx, err = r.ConfigManager.GetKeaModule().BeginSubnetUpdate(cctx, params.SubnetID)
+	if err != nil {
+		var (
+			subnetNotFound *config.SubnetNotFoundError
+			lock           *config.LockError
+		)
+		switch {
+		case errors.As(err, &subnetNotFound):
+			// Failed to find subnet.
+			msg := err.Error()
+			log.Error(err)
+			rsp := dhcp.NewUpdateSubnetBeginDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		case errors.As(err, &lock):
+			// Failed to lock daemons.
+			msg := err.Error()
+			log.Error(err)
+			rsp := dhcp.NewUpdateSubnetBeginDefault(http.StatusLocked).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		default:
+			// Other error.
+			msg := "problem with initializing transaction for subnet update"
+			log.Error(msg)
+			rsp := dhcp.NewUpdateSubnetBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		}
+	}
+	state, _ := config.GetTransactionState[kea.ConfigRecipe](cctx)
+	subnet := state.Updates[0].Recipe.SubnetBeforeUpdate
+
+	// Retrieve the generated context ID.
+	cctxID, ok := config.GetValueAsInt64(cctx, config.ContextIDKey)
+	if !ok {
+		msg := "problem with retrieving context ID for a transaction"
+		log.Error(msg)
+		rsp := dhcp.NewUpdateSubnetBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Remember the context, i.e. new transaction has been successfully created.
+	_ = r.ConfigManager.RememberContext(cctx, time.Minute*10)
+
+	// Return transaction ID, subnet, daemons and shared networks to the user.
+	contents := &models.UpdateSubnetBeginResponse{
+		ID:             cctxID,
+		Subnet:         r.subnetToRestAPI(subnet),
+		Daemons:        respDaemons,
+		SharedNetworks: respSharedNetworks,
+		ClientClasses:  respClientClasses,
+	}
+	rsp := dhcp.NewUpdateSubnetBeginOK().WithPayload(contents)
+	return rsp
+}
+
+// Implements the POST call and commit an updated subnet (subnets/{subnetId}/transaction/{id}/submit).
+func (r *RestAPI) UpdateSubnetSubmit(ctx context.Context, params dhcp.UpdateSubnetSubmitParams) middleware.Responder {
+	// The subnet in the request body must be the one specified in the URL.
+	if params.Subnet != nil && params.Subnet.ID != params.SubnetID {
+		msg := fmt.Sprintf("subnet ID %d in the request body does not match the subnet ID %d in the URL",
+			params.Subnet.ID, params.SubnetID)
+		log.Error(msg)
+		rsp := dhcp.NewUpdateSubnetSubmitDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if code, msg := r.commonCreateOrUpdateSubnetSubmit(ctx, params.ID, params.Subnet, r.ConfigManager.GetKeaModule().ApplySubnetUpdate); code != 0 {
+		// Error case.
+		rsp := dhcp.NewUpdateSubnetSubmitDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewUpdateSubnetSubmitOK()
+	return rsp
+}
+
+// Implements the DELETE call to cancel updating a subnet (subnets/{subnetId}/transaction/{id}).
+// It removes the specified transaction from the config manager, if the transaction exists.
+func (r *RestAPI) UpdateSubnetDelete(ctx context.Context, params dhcp.UpdateSubnetDeleteParams) middleware.Responder {
+	if code, msg := r.commonCreateOrUpdateDelete(ctx, params.ID); code != 0 {
+		// Error case.
+		rsp := dhcp.NewUpdateSubnetDeleteDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewUpdateSubnetDeleteOK()
+	return rsp
+}
+
+// Implements the DELETE call for a subnet (subnets/{id}). It sends suitable commands
+// to the Kea servers owning the subnet. Similarly to deleting a host reservation,
+// deleting a subnet is not transactional.
+func (r *RestAPI) DeleteSubnet(ctx context.Context, params dhcp.DeleteSubnetParams) middleware.Responder {
+	dbSubnet, err := dbmodel.GetSubnet(r.DB, params.ID)
+	if err != nil {
+		// Error while communicating with the database.
+		msg := fmt.Sprintf("Problem fetching subnet with ID %d from db", params.ID)
+		log.Error(err)
+		rsp := dhcp.NewDeleteSubnetDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbSubnet == nil {
+		// Subnet not found.
+		msg := fmt.Sprintf("Cannot find subnet with ID %d", params.ID)
+		rsp := dhcp.NewDeleteSubnetDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Get the logged user's ID.
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		msg := "unable to begin transaction because user is not logged in"
+		log.Error("Problem with creating transaction context because user has no session")
+		rsp := dhcp.NewDeleteSubnetDefault(http.StatusForbidden).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Create configuration context.
+	cctx, err := r.ConfigManager.CreateContext(int64(user.ID))
+	if err != nil {
+		msg := "problem with creating transaction context"
+		log.Error(err)
+		rsp := dhcp.NewDeleteSubnetDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Create Kea commands to delete the subnet.
+	cctx, err = r.ConfigManager.GetKeaModule().ApplySubnetDelete(cctx, dbSubnet)
+	if err != nil {
+		msg := "problem with preparing commands for deleting subnet"
+		log.Error(err)
+		rsp := dhcp.NewDeleteSubnetDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Send the commands to Kea servers.
+	_, err = r.ConfigManager.Commit(cctx)
+	if err != nil {
+		msg := fmt.Sprintf("problem with deleting subnet: %s", err)
+		log.Error(err)
+		rsp := dhcp.NewDeleteSubnetDefault(http.StatusConflict).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Send OK to the client.
+	rsp := dhcp.NewDeleteSubnetOK()
+	return rsp
+}
+
+// Converts shared network-level Kea parameters from the REST API format to
+// the format used in the database.
+func convertToSharedNetworkKeaParameters(params *models.KeaConfigSubnetDerivedParameters) *keaconfig.SharedNetworkParameters {
+	keaParameters := &keaconfig.SharedNetworkParameters{
+		CacheParameters: keaconfig.CacheParameters{
+			CacheThreshold: params.CacheThreshold,
+			CacheMaxAge:    params.CacheMaxAge,
+		},
+		ClientClassParameters: keaconfig.ClientClassParameters{
+			ClientClass:          params.ClientClass,
+			RequireClientClasses: params.RequireClientClasses,
+		},
+		DDNSParameters: keaconfig.DDNSParameters{
+			DDNSGeneratedPrefix:       params.DdnsGeneratedPrefix,
+			DDNSOverrideClientUpdate:  params.DdnsOverrideClientUpdate,
+			DDNSOverrideNoUpdate:      params.DdnsOverrideNoUpdate,
+			DDNSQualifyingSuffix:      params.DdnsQualifyingSuffix,
+			DDNSReplaceClientName:     params.DdnsReplaceClientName,
+			DDNSSendUpdates:           params.DdnsSendUpdates,
+			DDNSUpdateOnRenew:         params.DdnsUpdateOnRenew,
+			DDNSUseConflictResolution: params.DdnsUseConflictResolution,
+		},
+		HostnameCharParameters: keaconfig.HostnameCharParameters{
+			HostnameCharReplacement: params.HostnameCharReplacement,
+			HostnameCharSet:         params.HostnameCharSet,
+		},
+		PreferredLifetimeParameters: keaconfig.PreferredLifetimeParameters{
+			MaxPreferredLifetime: params.MaxPreferredLifetime,
+			MinPreferredLifetime: params.MinPreferredLifetime,
+			PreferredLifetime:    params.PreferredLifetime,
+		},
+		ReservationParameters: keaconfig.ReservationParameters{
+			ReservationMode:       params.ReservationMode,
+			ReservationsGlobal:    params.ReservationsGlobal,
+			ReservationsInSubnet:  params.ReservationsInSubnet,
+			ReservationsOutOfPool: params.ReservationsOutOfPool,
+		},
+		TimerParameters: keaconfig.TimerParameters{
+			CalculateTeeTimes: params.CalculateTeeTimes,
+			RebindTimer:       params.RebindTimer,
+			RenewTimer:        params.RenewTimer,
+			T1Percent:         params.T1Percent,
+			T2Percent:         params.T2Percent,
+		},
+		ValidLifetimeParameters: keaconfig.ValidLifetimeParameters{
+			MaxValidLifetime: params.MaxValidLifetime,
+			MinValidLifetime: params.MinValidLifetime,
+			ValidLifetime:    params.ValidLifetime,
+		},
+		Allocator:         params.Allocator,
+		Authoritative:     params.Authoritative,
+		BootFileName:      params.BootFileName,
+		Interface:         params.Interface,
+		InterfaceID:       params.InterfaceID,
+		MatchClientID:     params.MatchClientID,
+		NextServer:        params.NextServer,
+		PDAllocator:       params.PdAllocator,
+		RapidCommit:       params.RapidCommit,
+		ServerHostname:    params.ServerHostname,
+		StoreExtendedInfo: params.StoreExtendedInfo,
+	}
+	if params.Relay != nil {
+		keaParameters.Relay = &keaconfig.Relay{
+			IPAddresses: params.Relay.IPAddresses,
+		}
+	}
+	return keaParameters
+}
+
+// Converts shared network data from the REST API format to the format used
+// in the database. The subnets are merely represented by their IDs. The
+// daemon and subnet information is not populated by this function. The
+// caller should use the PopulateDaemons and PopulateSubnets functions to
+// fetch it from the database.
+func (r *RestAPI) convertToSharedNetwork(restSharedNetwork *models.SharedNetwork) (*dbmodel.SharedNetwork, error) {
+	sharedNetwork := &dbmodel.SharedNetwork{
+		ID:     restSharedNetwork.ID,
+		Name:   restSharedNetwork.Name,
+		Family: int(restSharedNetwork.Universe),
+	}
+	if sharedNetwork.Name == "" {
+		return nil, errors.New("shared network name must not be empty")
+	}
+	if sharedNetwork.Family != 4 && sharedNetwork.Family != 6 {
+		return nil, errors.Errorf("invalid shared network universe %d", restSharedNetwork.Universe)
+	}
+	for _, lsn := range restSharedNetwork.LocalSharedNetworks {
+		localSharedNetwork := &dbmodel.LocalSharedNetwork{
+			DaemonID: lsn.DaemonID,
+		}
+		// Convert shared network-level Kea parameters and DHCP options. The
+		// global parameters are ignored because they are not configured
+		// within the shared network.
+		if lsn.KeaConfigSharedNetworkParameters != nil && lsn.KeaConfigSharedNetworkParameters.SharedNetworkLevelParameters != nil {
+			params := lsn.KeaConfigSharedNetworkParameters.SharedNetworkLevelParameters
+			localSharedNetwork.KeaParameters = convertToSharedNetworkKeaParameters(params)
+			var err error
+			localSharedNetwork.DHCPOptionSet, err = r.flattenDHCPOptions("", params.Options, 0)
+			if err != nil {
+				return nil, err
+			}
+			if len(localSharedNetwork.DHCPOptionSet) > 0 {
+				localSharedNetwork.DHCPOptionSetHash = storkutil.Fnv128(localSharedNetwork.DHCPOptionSet)
+			}
+		}
+		sharedNetwork.SetLocalSharedNetwork(localSharedNetwork)
+	}
+	for _, subnet := range restSharedNetwork.Subnets {
+		sharedNetwork.Subnets = append(sharedNetwork.Subnets, dbmodel.Subnet{
+			ID: subnet.ID,
+		})
+	}
+	return sharedNetwork, nil
+}
+
+// Common function for executed when creating a new transaction for when the
+// shared network is created or updated. It fetches available DHCP daemons,
+// subnets and client classes. It also creates transaction context. If an
+// error occurs, an http error code and message are returned.
+func (r *RestAPI) commonCreateOrUpdateSharedNetworkBegin(ctx context.Context) ([]*models.KeaDaemon, []*models.Subnet, []string, context.Context, int, string) {
+	respDaemons, respClientClasses, code, msg := r.getSubnetCmdsDaemonsAndClientClasses()
+	if code != 0 {
+		return nil, nil, nil, nil, code, msg
+	}
+	// If there are no daemons with subnet_cmds hooks library loaded there is
+	// no way to add or update shared networks. In that case, we don't begin
+	// a transaction.
+	if len(respDaemons) == 0 {
+		msg := "unable to begin transaction for the shared network because there are no Kea servers with subnet_cmds hooks library available"
+		log.Error(msg)
+		return nil, nil, nil, nil, http.StatusBadRequest, msg
+	}
+	// Subnets can be moved to the shared network. The user needs a current
+	// list of available subnets.
+	subnets, err := dbmodel.GetAllSubnets(r.DB, 0)
+	if err != nil {
+		msg := "problem with fetching subnets from the database"
+		log.Error(err)
+		return nil, nil, nil, nil, http.StatusInternalServerError, msg
+	}
+	// Convert subnets list to REST API format.
+	respSubnets := []*models.Subnet{}
+	for i := range subnets {
+		respSubnets = append(respSubnets, r.subnetToRestAPI(&subnets[i]))
+	}
+	cctx, code, msg := r.createTransactionContext(ctx)
+	if code != 0 {
+		return nil, nil, nil, nil, code, msg
+	}
+	return respDaemons, respSubnets, respClientClasses, cctx, 0, ""
+}
+
+// Implements the POST call to create new transaction for adding a new
+// shared network (shared-networks/new/transaction).
+func (r *RestAPI) CreateSharedNetworkBegin(ctx context.Context, params dhcp.CreateSharedNetworkBeginParams) middleware.Responder {
+	// Execute the common part between create and update operations. It retrieves,
+	// daemons, subnets, client classes and creates the transaction context.
+	respDaemons, respSubnets, respClientClasses, cctx, code, msg := r.commonCreateOrUpdateSharedNetworkBegin(ctx)
+	if code != 0 {
+		// Error case.
+		rsp := dhcp.NewCreateSharedNetworkBeginDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Begin shared network add transaction.
+	var err error
+	if cctx, err = r.ConfigManager.GetKeaModule().BeginSharedNetworkAdd(cctx); err != nil {
+		msg := "problem with initializing transaction for creating new shared network"
+		log.Error(msg)
+		rsp := dhcp.NewCreateSharedNetworkBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	// Retrieve the generated context ID.
+	cctxID, ok := config.GetValueAsInt64(cctx, config.ContextIDKey)
+	if !ok {
+		msg := "problem with retrieving context ID for a transaction"
+		log.Error(msg)
+		rsp := dhcp.NewCreateSharedNetworkBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Remember the context, i.e. new transaction has been successfully created.
+	_ = r.ConfigManager.RememberContext(cctx, time.Minute*10)
+
+	// Return transaction ID, daemons and subnets to the user.
+	contents := &models.CreateSharedNetworkBeginResponse{
+		ID:            cctxID,
+		Daemons:       respDaemons,
+		Subnets:       respSubnets,
+		ClientClasses: respClientClasses,
+	}
+	rsp := dhcp.NewCreateSharedNetworkBeginOK().WithPayload(contents)
+	return rsp
+}
+
+// Common function that implements the POST calls to apply and commit a new
+// or updated shared network. The ctx parameter is the REST API context. The
+// transactionID is the identifier of the current configuration transaction
+// used by the function to recover the transaction context. The
+// restSharedNetwork is the pointer to the shared network specified by the
+// user. It is converted by this function to the database model. The applyFunc
+// is the function of the Kea config module that applies the specified shared
+// network. It is one of the ApplySharedNetworkAdd or ApplySharedNetworkUpdate.
+// This function returns the HTTP error code if an error occurs or 0 when there
+// is no error. In addition it returns an error string to be included in the
+// HTTP response or an empty string if there is no error.
+func (r *RestAPI) commonCreateOrUpdateSharedNetworkSubmit(ctx context.Context, transactionID int64, restSharedNetwork *models.SharedNetwork, applyFunc func(context.Context, *dbmodel.SharedNetwork) (context.Context, error)) (int, string) {
+	// Make sure that the shared network information is present.
+	if restSharedNetwork == nil {
+		msg := "shared network information not specified"
+		log.Errorf(msg)
+		return http.StatusBadRequest, msg
+	}
+	// Get the user ID and recover the transaction context.
+	ok, user := r.SessionManager.Logged(ctx)
+	if !ok {
+		msg := "unable to submit because user is not logged in"
+		log.Error("Problem with recovering transaction context because user has no session")
+		return http.StatusForbidden, msg
+	}
+	// Retrieve the context from the config manager.
+	cctx, _ := r.ConfigManager.RecoverContext(transactionID, int64(user.ID))
+	if cctx == nil {
+		msg := "transaction expired"
+		log.Errorf("Problem with recovering transaction context for transaction ID %d and user ID %d", transactionID, user.ID)
+		return http.StatusNotFound, msg
+	}
+
+	// Convert shared network information from REST API to database format.
+	sharedNetwork, err := r.convertToSharedNetwork(restSharedNetwork)
+	if err != nil {
+		msg := "error parsing specified shared network"
+		log.Error(err)
+		return http.StatusBadRequest, msg
+	}
+	err = sharedNetwork.PopulateDaemons(r.DB)
+	if err != nil {
+		msg := "specified shared network is associated with daemons that no longer exist"
+		log.Error(err)
+		return http.StatusNotFound, msg
+	}
+	err = sharedNetwork.PopulateSubnets(r.DB)
+	if err != nil {
+		msg := "specified shared network is associated with subnets that no longer exist"
+		log.Error(err)
+		return http.StatusNotFound, msg
+	}
+	// Make sure that the user does not add the shared network to the
+	// daemons out of the user's scope and does not modify the subnets
+	// out of the user's scope.
+	var daemonIDs, subnetIDs []int64
+	for _, lsn := range sharedNetwork.LocalSharedNetworks {
+		daemonIDs = append(daemonIDs, lsn.DaemonID)
+	}
+	for _, subnet := range sharedNetwork.Subnets {
+		if subnet.ID != 0 {
+			subnetIDs = append(subnetIDs, subnet.ID)
+		}
+		for _, ls := range subnet.LocalSubnets {
+			daemonIDs = append(daemonIDs, ls.DaemonID)
+		}
+	}
+	if code, msg := r.checkObjectsInScope(ctx, daemonIDs, subnetIDs); code != 0 {
+		return code, msg
+	}
+	// Apply the shared network information (create Kea commands).
+	cctx, err = applyFunc(cctx, sharedNetwork)
+	if err != nil {
+		msg := "problem with applying shared network information"
+		log.Error(err)
+		return http.StatusInternalServerError, msg
+	}
+	// Send the commands to Kea servers.
+	cctx, err = r.ConfigManager.Commit(cctx)
+	if err != nil {
+		msg := fmt.Sprintf("problem with committing shared network information: %s", err)
+		log.Error(err)
+		return http.StatusConflict, msg
+	}
+	// Everything ok. Cleanup and send OK to the client.
+	r.ConfigManager.Done(cctx)
+	return 0, ""
+}
+
+// Implements the POST call to apply and commit a new shared network
+// (shared-networks/new/transaction/{id}/submit).
+func (r *RestAPI) CreateSharedNetworkSubmit(ctx context.Context, params dhcp.CreateSharedNetworkSubmitParams) middleware.Responder {
+	if code, msg := r.commonCreateOrUpdateSharedNetworkSubmit(ctx, params.ID, params.SharedNetwork, r.ConfigManager.GetKeaModule().ApplySharedNetworkAdd); code != 0 {
+		// Error case.
+		rsp := dhcp.NewCreateSharedNetworkSubmitDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewCreateSharedNetworkSubmitOK()
+	return rsp
+}
+
+// Implements the DELETE call to cancel adding new shared network
+// (shared-networks/new/transaction/{id}). It removes the specified
+// transaction from the config manager, if the transaction exists.
+func (r *RestAPI) CreateSharedNetworkDelete(ctx context.Context, params dhcp.CreateSharedNetworkDeleteParams) middleware.Responder {
+	if code, msg := r.commonCreateOrUpdateDelete(ctx, params.ID); code != 0 {
+		// Error case.
+		rsp := dhcp.NewCreateSharedNetworkDeleteDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewCreateSharedNetworkDeleteOK()
+	return rsp
+}
+
+// Implements the POST call to create new transaction for updating an
+// existing shared network (shared-networks/{sharedNetworkId}/transaction).
+func (r *RestAPI) UpdateSharedNetworkBegin(ctx context.Context, params dhcp.UpdateSharedNetworkBeginParams) middleware.Responder {
+	// Execute the common part between create and update operations. It retrieves,
+	// daemons, subnets, client classes and creates the transaction context.
+	respDaemons, respSubnets, respClientClasses, cctx, code, msg := r.commonCreateOrUpdateSharedNetworkBegin(ctx)
+	if code != 0 {
+		// Error case.
+		rsp := dhcp.NewUpdateSharedNetworkBeginDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Begin shared network update transaction. It retrieves current shared
+	// network information and locks daemons for updates.
+	var err error
+	cctx, err = r.ConfigManager.GetKeaModule().BeginSharedNetworkUpdate(cctx, params.SharedNetworkID)
+	if err != nil {
+		var (
+			sharedNetworkNotFound *config.SharedNetworkNotFoundError
+			lock                  *config.LockError
+		)
+		switch {
+		case errors.As(err, &sharedNetworkNotFound):
+			// Failed to find shared network.
+			msg := err.Error()
+			log.Error(err)
+			rsp := dhcp.NewUpdateSharedNetworkBeginDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		case errors.As(err, &lock):
+			// Failed to lock daemons.
+			msg := err.Error()
+			log.Error(err)
+			rsp := dhcp.NewUpdateSharedNetworkBeginDefault(http.StatusLocked).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		default:
+			// Other error.
+			msg := "problem with initializing transaction for shared network update"
+			log.Error(msg)
+			rsp := dhcp.NewUpdateSharedNetworkBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		}
+	}
+	state, _ := config.GetTransactionState[kea.ConfigRecipe](cctx)
+	sharedNetwork := state.Updates[0].Recipe.SharedNetworkBeforeUpdate
+
+	// Retrieve the generated context ID.
+	cctxID, ok := config.GetValueAsInt64(cctx, config.ContextIDKey)
+	if !ok {
+		msg := "problem with retrieving context ID for a transaction"
+		log.Error(msg)
+		rsp := dhcp.NewUpdateSharedNetworkBeginDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Remember the context, i.e. new transaction has been successfully created.
+	_ = r.ConfigManager.RememberContext(cctx, time.Minute*10)
+
+	// Return transaction ID, shared network, daemons and subnets to the user.
+	contents := &models.UpdateSharedNetworkBeginResponse{
+		ID:            cctxID,
+		SharedNetwork: r.sharedNetworkToRestAPI(sharedNetwork),
+		Daemons:       respDaemons,
+		Subnets:       respSubnets,
+		ClientClasses: respClientClasses,
+	}
+	rsp := dhcp.NewUpdateSharedNetworkBeginOK().WithPayload(contents)
+	return rsp
+}
+
+// Implements the POST call and commit an updated shared network
+// (shared-networks/{sharedNetworkId}/transaction/{id}/submit).
+func (r *RestAPI) UpdateSharedNetworkSubmit(ctx context.Context, params dhcp.UpdateSharedNetworkSubmitParams) middleware.Responder {
+	if code, msg := r.commonCreateOrUpdateSharedNetworkSubmit(ctx, params.ID, params.SharedNetwork, r.ConfigManager.GetKeaModule().ApplySharedNetworkUpdate); code != 0 {
+		// Error case.
+		rsp := dhcp.NewUpdateSharedNetworkSubmitDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewUpdateSharedNetworkSubmitOK()
+	return rsp
+}
+
+// Implements the DELETE call to cancel updating a shared network
+// (shared-networks/{sharedNetworkId}/transaction/{id}). It removes the
+// specified transaction from the config manager, if the transaction exists.
+func (r *RestAPI) UpdateSharedNetworkDelete(ctx context.Context, params dhcp.UpdateSharedNetworkDeleteParams) middleware.Responder {
+	if code, msg := r.commonCreateOrUpdateDelete(ctx, params.ID); code != 0 {
+		// Error case.
+		rsp := dhcp.NewUpdateSharedNetworkDeleteDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewUpdateSharedNetworkDeleteOK()
+	return rsp
+}
+
+// Implements the DELETE call for a shared network (shared-networks/{id}). It
+// sends suitable commands to the Kea servers owning the shared network. The
+// daemons are locked for the time of deleting the shared network. The subnets
+// belonging to the shared network are preserved as top-level subnets.
+func (r *RestAPI) DeleteSharedNetwork(ctx context.Context, params dhcp.DeleteSharedNetworkParams) middleware.Responder {
+	dbSharedNetwork, err := dbmodel.GetSharedNetwork(r.DB, params.ID)
+	if err != nil {
+		// Error while communicating with the database.
+		msg := fmt.Sprintf("Problem fetching shared network with ID %d from db", params.ID)
+		log.Error(err)
+		rsp := dhcp.NewDeleteSharedNetworkDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbSharedNetwork == nil {
+		// Shared network not found.
+		msg := fmt.Sprintf("Cannot find shared network with ID %d", params.ID)
+		rsp := dhcp.NewDeleteSharedNetworkDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	cctx, code, msg := r.createTransactionContext(ctx)
+	if code != 0 {
+		rsp := dhcp.NewDeleteSharedNetworkDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Create Kea commands to delete the shared network.
+	cctx, err = r.ConfigManager.GetKeaModule().ApplySharedNetworkDelete(cctx, dbSharedNetwork)
+	if err != nil {
+		var lock *config.LockError
+		if errors.As(err, &lock) {
+			msg := err.Error()
+			log.Error(err)
+			rsp := dhcp.NewDeleteSharedNetworkDefault(http.StatusLocked).WithPayload(&models.APIError{
+				Message: &msg,
+			})
+			return rsp
+		}
+		msg := "problem with preparing commands for deleting shared network"
+		log.Error(err)
+		rsp := dhcp.NewDeleteSharedNetworkDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Unlock the daemons when done.
+	defer r.ConfigManager.Done(cctx)
+
+	// Send the commands to Kea servers.
+	_, err = r.ConfigManager.Commit(cctx)
+	if err != nil {
+		msg := fmt.Sprintf("problem with deleting shared network: %s", err)
+		log.Error(err)
+		rsp := dhcp.NewDeleteSharedNetworkDefault(http.StatusConflict).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	// Send OK to the client.
+	rsp := dhcp.NewDeleteSharedNetworkOK()
+	return rsp
+}
@@ -10,10 +10,15 @@ import (
 	"github.com/stretchr/testify/require"
 	dhcpmodel "isc.org/stork/datamodel/dhcp"
 	agentcommtest "isc.org/stork/server/agentcomm/test"
+	apps "isc.org/stork/server/apps"
 	"isc.org/stork/server/apps/kea"
+	appstest "isc.org/stork/server/apps/test"
+	"isc.org/stork/server/config"
+	dbops "isc.org/stork/server/database"
 	dbmodel "isc.org/stork/server/database/model"
 	dbmodeltest "isc.org/stork/server/database/model/test"
 	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
 	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
 	storktest "isc.org/stork/server/test/dbmodel"
 	"isc.org/stork/testutil"
@@ -1154,6 +1159,82 @@ func TestSubnetToRestAPIEmptyStatsCollectedTimestamp(t *testing.T) {
 	require.NotContains(t, string(subnetJSON), "statsCollectedAt")
 }
 
+// Test that the exhaustion forecasts are converted to the REST API subnet.
+func TestSubnetToRestAPIExhaustionForecast(t *testing.T) {
+	// Arrange
+	settings := RestAPISettings{}
+	rapi, _ := NewRestAPI(&settings)
+
+	addrDays := 12.5
+	subnetDB := &dbmodel.Subnet{
+		AddrExhaustionDays: &addrDays,
+	}
+
+	// Act
+	subnetAPI := rapi.subnetToRestAPI(subnetDB)
+
+	// Assert
+	require.NotNil(t, subnetAPI.AddrExhaustionDays)
+	require.EqualValues(t, 12.5, *subnetAPI.AddrExhaustionDays)
+	require.Nil(t, subnetAPI.PdExhaustionDays)
+}
+
+// Test that the pool exhaustion forecasts are converted to the REST API
+// local subnet.
+func TestSubnetToRestAPIPoolExhaustionForecast(t *testing.T) {
+	// Arrange
+	settings := RestAPISettings{}
+	rapi, _ := NewRestAPI(&settings)
+
+	addrDays := 12.5
+	pdDays := 3.0
+	subnetDB := &dbmodel.Subnet{
+		LocalSubnets: []*dbmodel.LocalSubnet{
+			{
+				Daemon: &dbmodel.Daemon{
+					App: &dbmodel.App{
+						Machine: &dbmodel.Machine{},
+					},
+				},
+				AddressPools: []dbmodel.AddressPool{
+					{
+						LowerBound:     "2001:db8:1::10",
+						UpperBound:     "2001:db8:1::ff",
+						ExhaustionDays: &addrDays,
+					},
+					{
+						LowerBound: "2001:db8:1::100",
+						UpperBound: "2001:db8:1::1ff",
+					},
+				},
+				PrefixPools: []dbmodel.PrefixPool{
+					{
+						Prefix:         "2001:db8:1:1::/80",
+						DelegatedLen:   96,
+						ExhaustionDays: &pdDays,
+					},
+				},
+			},
+		},
+	}
+
+	// Act
+	subnetAPI := rapi.subnetToRestAPI(subnetDB)
+
+	// Assert
+	require.Len(t, subnetAPI.LocalSubnets, 1)
+	forecasts := subnetAPI.LocalSubnets[0].PoolExhaustionForecasts
+	require.Len(t, forecasts, 2)
+
+	require.Equal(t, "2001:db8:1::10-2001:db8:1::ff", *forecasts[0].Pool)
+	require.Zero(t, forecasts[0].DelegatedLength)
+	require.EqualValues(t, 12.5, *forecasts[0].ExhaustionDays)
+
+	require.Equal(t, "2001:db8:1:1::/80", *forecasts[1].Pool)
+	require.EqualValues(t, 96, forecasts[1].DelegatedLength)
+	require.EqualValues(t, 3, *forecasts[1].ExhaustionDays)
+}
+
 // Test getting a shared network with a detailed DHCP configuration over the REST API.
 func TestGetSharedNetwork4(t *testing.T) {
 	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
@@ -1658,3 +1739,781 @@ func TestGetSharedNetwork6MinimalParameters(t *testing.T) {
 
 	require.NotNil(t, ls.KeaConfigSharedNetworkParameters)
 }
+
+// Creates a DHCPv4 server with the subnet_cmds hook library in the database
+// and populates its subnets.
+func addTestSubnetCmdsServer(t *testing.T, db *dbops.PgDB) *dbmodel.App {
+	dhcp4, err := dbmodeltest.NewKeaDHCPv4Server(db)
+	require.NoError(t, err)
+
+	err = dhcp4.Configure(`{
+        "Dhcp4": {
+            "client-classes": [
+                {
+                    "name": "foo"
+                }
+            ],
+            "shared-networks": [
+                {
+                    "name": "bar",
+                    "subnet4": [
+                        {
+                            "id": 222,
+                            "subnet": "192.0.3.0/24"
+                        }
+                    ]
+                }
+            ],
+            "subnet4": [
+                {
+                    "id": 111,
+                    "subnet": "192.0.2.0/24",
+                    "pools": [
+                        {
+                            "pool": "192.0.2.10-192.0.2.20"
+                        }
+                    ]
+                }
+            ],
+            "hooks-libraries": [
+                {
+                    "library": "libdhcp_subnet_cmds.so"
+                }
+            ]
+        }
+    }`)
+	require.NoError(t, err)
+
+	app, err := dhcp4.GetKea()
+	require.NoError(t, err)
+
+	err = kea.CommitAppIntoDB(db, app, &storktest.FakeEventCenter{}, nil, dbmodel.NewDHCPOptionDefinitionLookup())
+	require.NoError(t, err)
+	return app
+}
+
+// Creates the REST API instance with the config manager and logs in
+// the test user.
+func newTestSubnetCmdsRestAPI(t *testing.T, db *dbops.PgDB, dbSettings *dbops.DatabaseSettings, fa *agentcommtest.FakeAgents) (*RestAPI, config.Manager, context.Context, *dbmodel.SystemUser) {
+	lookup := dbmodel.NewDHCPOptionDefinitionLookup()
+	require.NotNil(t, lookup)
+
+	// Create the config manager.
+	cm := apps.NewManager(&appstest.ManagerAccessorsWrapper{
+		DB:        db,
+		Agents:    fa,
+		DefLookup: lookup,
+	})
+	require.NotNil(t, cm)
+
+	// Create API.
+	rapi, err := NewRestAPI(dbSettings, db, fa, cm, lookup)
+	require.NoError(t, err)
+
+	// Create session manager.
+	ctx, err := rapi.SessionManager.Load(context.Background(), "")
+	require.NoError(t, err)
+
+	// Create user session.
+	user := &dbmodel.SystemUser{
+		ID: 1234,
+	}
+	err = rapi.SessionManager.LoginHandler(ctx, user)
+	require.NoError(t, err)
+
+	return rapi, cm, ctx, user
+}
+
+// Test the calls for creating new transaction and submitting a new subnet.
+func TestCreateSubnetBeginSubmit(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, cm, ctx, user := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	app := addTestSubnetCmdsServer(t, db)
+
+	// Begin transaction.
+	rsp := rapi.CreateSubnetBegin(ctx, dhcp.CreateSubnetBeginParams{})
+	require.IsType(t, &dhcp.CreateSubnetBeginOK{}, rsp)
+	contents := rsp.(*dhcp.CreateSubnetBeginOK).Payload
+
+	// Make sure the server returned transaction ID, daemons, shared networks
+	// and client classes.
+	transactionID := contents.ID
+	require.NotZero(t, transactionID)
+	require.Len(t, contents.Daemons, 1)
+	require.Len(t, contents.SharedNetworks, 1)
+	require.Equal(t, "bar", contents.SharedNetworks[0].Name)
+	require.Equal(t, []string{"foo"}, contents.ClientClasses)
+
+	// Submit transaction.
+	validLifetime := int64(3600)
+	params := dhcp.CreateSubnetSubmitParams{
+		ID: transactionID,
+		Subnet: &models.Subnet{
+			Subnet:          "192.0.4.0/24",
+			SharedNetworkID: contents.SharedNetworks[0].ID,
+			LocalSubnets: []*models.LocalSubnet{
+				{
+					ID:       333,
+					DaemonID: app.Daemons[0].ID,
+					Pools:    []string{"192.0.4.10-192.0.4.20"},
+					KeaConfigSubnetParameters: &models.KeaConfigSubnetParameters{
+						SubnetLevelParameters: &models.KeaConfigSubnetDerivedParameters{
+							KeaConfigValidLifetimeParameters: models.KeaConfigValidLifetimeParameters{
+								ValidLifetime: &validLifetime,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	rsp2 := rapi.CreateSubnetSubmit(ctx, params)
+	require.IsType(t, &dhcp.CreateSubnetSubmitOK{}, rsp2)
+
+	// It should result in sending subnet4-add, network4-subnet-add and config-write.
+	require.Len(t, fa.RecordedCommands, 3)
+	require.JSONEq(t, `{
+        "command": "subnet4-add",
+        "service": ["dhcp4"],
+        "arguments": {
+            "subnet4": [
+                {
+                    "id": 333,
+                    "subnet": "192.0.4.0/24",
+                    "pools": [
+                        {
+                            "pool": "192.0.4.10-192.0.4.20"
+                        }
+                    ],
+                    "valid-lifetime": 3600
+                }
+            ]
+        }
+    }`, fa.RecordedCommands[0].Marshal())
+	require.JSONEq(t, `{
+        "command": "network4-subnet-add",
+        "service": ["dhcp4"],
+        "arguments": {
+            "name": "bar",
+            "id": 333
+        }
+    }`, fa.RecordedCommands[1].Marshal())
+	require.Equal(t, "config-write", fa.RecordedCommands[2].GetCommand())
+
+	// Make sure that the transaction is done.
+	cctx, _ := cm.RecoverContext(transactionID, int64(user.ID))
+	if cctx != nil {
+		cm.Done(cctx)
+	}
+	require.Nil(t, cctx)
+
+	// Make sure that the subnet has been added to the database.
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.4.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+	require.Equal(t, contents.SharedNetworks[0].ID, subnets[0].SharedNetworkID)
+	require.Len(t, subnets[0].LocalSubnets, 1)
+	require.EqualValues(t, 333, subnets[0].LocalSubnets[0].LocalSubnetID)
+	require.Len(t, subnets[0].LocalSubnets[0].AddressPools, 1)
+	require.NotNil(t, subnets[0].LocalSubnets[0].KeaParameters)
+	require.NotNil(t, subnets[0].LocalSubnets[0].KeaParameters.ValidLifetime)
+	require.EqualValues(t, 3600, *subnets[0].LocalSubnets[0].KeaParameters.ValidLifetime)
+}
+
+// Test error case when a user attempts to begin a new transaction when
+// there are no servers with subnet_cmds hook library found.
+func TestCreateSubnetBeginNoServers(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, _, ctx, _ := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	// The apps lack the subnet_cmds hook library.
+	_, _ = storktest.AddTestHosts(t, db)
+
+	rsp := rapi.CreateSubnetBegin(ctx, dhcp.CreateSubnetBeginParams{})
+	require.IsType(t, &dhcp.CreateSubnetBeginDefault{}, rsp)
+	defaultRsp := rsp.(*dhcp.CreateSubnetBeginDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+}
+
+// Test that the transaction to add a new subnet can be canceled.
+func TestCreateSubnetBeginCancel(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, cm, ctx, user := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	_ = addTestSubnetCmdsServer(t, db)
+
+	rsp := rapi.CreateSubnetBegin(ctx, dhcp.CreateSubnetBeginParams{})
+	require.IsType(t, &dhcp.CreateSubnetBeginOK{}, rsp)
+	transactionID := rsp.(*dhcp.CreateSubnetBeginOK).Payload.ID
+
+	rsp2 := rapi.CreateSubnetDelete(ctx, dhcp.CreateSubnetDeleteParams{
+		ID: transactionID,
+	})
+	require.IsType(t, &dhcp.CreateSubnetDeleteOK{}, rsp2)
+
+	// The transaction should no longer exist.
+	cctx, _ := cm.RecoverContext(transactionID, int64(user.ID))
+	if cctx != nil {
+		cm.Done(cctx)
+	}
+	require.Nil(t, cctx)
+
+	// Canceling the transaction again should fail.
+	rsp3 := rapi.CreateSubnetDelete(ctx, dhcp.CreateSubnetDeleteParams{
+		ID: transactionID,
+	})
+	require.IsType(t, &dhcp.CreateSubnetDeleteDefault{}, rsp3)
+	defaultRsp := rsp3.(*dhcp.CreateSubnetDeleteDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*defaultRsp))
+}
+
+// Test the calls for creating new transaction and updating a subnet.
+func TestUpdateSubnetBeginSubmit(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, cm, ctx, user := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	_ = addTestSubnetCmdsServer(t, db)
+
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.2.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+
+	// Begin transaction.
+	rsp := rapi.UpdateSubnetBegin(ctx, dhcp.UpdateSubnetBeginParams{
+		SubnetID: subnets[0].ID,
+	})
+	require.IsType(t, &dhcp.UpdateSubnetBeginOK{}, rsp)
+	contents := rsp.(*dhcp.UpdateSubnetBeginOK).Payload
+
+	transactionID := contents.ID
+	require.NotZero(t, transactionID)
+	require.NotNil(t, contents.Subnet)
+	require.Equal(t, "192.0.2.0/24", contents.Subnet.Subnet)
+	require.Len(t, contents.Daemons, 1)
+	require.Len(t, contents.SharedNetworks, 1)
+
+	// Modify the subnet pools and submit it.
+	subnet := contents.Subnet
+	subnet.LocalSubnets[0].Pools = []string{"192.0.2.30-192.0.2.40"}
+	rsp2 := rapi.UpdateSubnetSubmit(ctx, dhcp.UpdateSubnetSubmitParams{
+		SubnetID: subnets[0].ID,
+		ID:       transactionID,
+		Subnet:   subnet,
+	})
+	require.IsType(t, &dhcp.UpdateSubnetSubmitOK{}, rsp2)
+
+	require.Len(t, fa.RecordedCommands, 2)
+	require.JSONEq(t, `{
+        "command": "subnet4-update",
+        "service": ["dhcp4"],
+        "arguments": {
+            "subnet4": [
+                {
+                    "id": 111,
+                    "subnet": "192.0.2.0/24",
+                    "pools": [
+                        {
+                            "pool": "192.0.2.30-192.0.2.40"
+                        }
+                    ]
+                }
+            ]
+        }
+    }`, fa.RecordedCommands[0].Marshal())
+	require.Equal(t, "config-write", fa.RecordedCommands[1].GetCommand())
+
+	// Make sure that the transaction is done.
+	cctx, _ := cm.RecoverContext(transactionID, int64(user.ID))
+	if cctx != nil {
+		cm.Done(cctx)
+	}
+	require.Nil(t, cctx)
+
+	// Make sure that the subnet has been updated in the database.
+	returnedSubnet, err := dbmodel.GetSubnet(db, subnets[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, returnedSubnet)
+	require.Len(t, returnedSubnet.LocalSubnets, 1)
+	require.Len(t, returnedSubnet.LocalSubnets[0].AddressPools, 1)
+	require.Equal(t, "192.0.2.30", returnedSubnet.LocalSubnets[0].AddressPools[0].LowerBound)
+}
+
+// Test that the updated subnet is rejected when its ID does not match
+// the subnet ID specified in the URL.
+func TestUpdateSubnetSubmitSubnetIDMismatch(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, _, ctx, _ := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	_ = addTestSubnetCmdsServer(t, db)
+
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.2.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+
+	// Begin transaction.
+	rsp := rapi.UpdateSubnetBegin(ctx, dhcp.UpdateSubnetBeginParams{
+		SubnetID: subnets[0].ID,
+	})
+	require.IsType(t, &dhcp.UpdateSubnetBeginOK{}, rsp)
+	contents := rsp.(*dhcp.UpdateSubnetBeginOK).Payload
+
+	// Submit the subnet with a different ID.
+	subnet := contents.Subnet
+	subnet.ID = subnets[0].ID + 1
+	rsp2 := rapi.UpdateSubnetSubmit(ctx, dhcp.UpdateSubnetSubmitParams{
+		SubnetID: subnets[0].ID,
+		ID:       contents.ID,
+		Subnet:   subnet,
+	})
+	require.IsType(t, &dhcp.UpdateSubnetSubmitDefault{}, rsp2)
+	defaultRsp := rsp2.(*dhcp.UpdateSubnetSubmitDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+	require.Empty(t, fa.RecordedCommands)
+}
+
+// Test that an error is returned when beginning the update of a
+// non-existing subnet.
+func TestUpdateSubnetBeginNonExistingSubnetID(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, _, ctx, _ := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	_ = addTestSubnetCmdsServer(t, db)
+
+	rsp := rapi.UpdateSubnetBegin(ctx, dhcp.UpdateSubnetBeginParams{
+		SubnetID: 1024,
+	})
+	require.IsType(t, &dhcp.UpdateSubnetBeginDefault{}, rsp)
+	defaultRsp := rsp.(*dhcp.UpdateSubnetBeginDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+}
+
+// Test deleting a subnet.
+func TestDeleteSubnet(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, _, ctx, _ := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	_ = addTestSubnetCmdsServer(t, db)
+
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.2.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+
+	rsp := rapi.DeleteSubnet(ctx, dhcp.DeleteSubnetParams{
+		ID: subnets[0].ID,
+	})
+	require.IsType(t, &dhcp.DeleteSubnetOK{}, rsp)
+
+	require.Len(t, fa.RecordedCommands, 2)
+	require.JSONEq(t, `{
+        "command": "subnet4-del",
+        "service": ["dhcp4"],
+        "arguments": {
+            "id": 111
+        }
+    }`, fa.RecordedCommands[0].Marshal())
+	require.Equal(t, "config-write", fa.RecordedCommands[1].GetCommand())
+
+	returnedSubnet, err := dbmodel.GetSubnet(db, subnets[0].ID)
+	require.NoError(t, err)
+	require.Nil(t, returnedSubnet)
+
+	// Deleting non-existing subnet should fail.
+	rsp = rapi.DeleteSubnet(ctx, dhcp.DeleteSubnetParams{
+		ID: subnets[0].ID,
+	})
+	require.IsType(t, &dhcp.DeleteSubnetDefault{}, rsp)
+	defaultRsp := rsp.(*dhcp.DeleteSubnetDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*defaultRsp))
+}
+
+// Test the calls for creating new transaction and submitting a new shared
+// network. The new shared network takes over a top-level subnet.
+func TestCreateSharedNetworkBeginSubmit(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, cm, ctx, user := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	app := addTestSubnetCmdsServer(t, db)
+
+	// Begin transaction.
+	rsp := rapi.CreateSharedNetworkBegin(ctx, dhcp.CreateSharedNetworkBeginParams{})
+	require.IsType(t, &dhcp.CreateSharedNetworkBeginOK{}, rsp)
+	contents := rsp.(*dhcp.CreateSharedNetworkBeginOK).Payload
+
+	// Make sure the server returned transaction ID, daemons, subnets
+	// and client classes.
+	transactionID := contents.ID
+	require.NotZero(t, transactionID)
+	require.Len(t, contents.Daemons, 1)
+	require.Len(t, contents.Subnets, 2)
+	require.Equal(t, []string{"foo"}, contents.ClientClasses)
+
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.2.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+
+	// Submit transaction.
+	params := dhcp.CreateSharedNetworkSubmitParams{
+		ID: transactionID,
+		SharedNetwork: &models.SharedNetwork{
+			Name:     "baz",
+			Universe: 4,
+			LocalSharedNetworks: []*models.LocalSharedNetwork{
+				{
+					DaemonID: app.Daemons[0].ID,
+					KeaConfigSharedNetworkParameters: &models.KeaConfigSharedNetworkParameters{
+						SharedNetworkLevelParameters: &models.KeaConfigSubnetDerivedParameters{
+							KeaConfigAssortedSubnetParameters: models.KeaConfigAssortedSubnetParameters{
+								Interface: storkutil.Ptr("eth0"),
+							},
+						},
+					},
+				},
+			},
+			Subnets: []*models.Subnet{
+				{
+					ID: subnets[0].ID,
+				},
+			},
+		},
+	}
+	rsp2 := rapi.CreateSharedNetworkSubmit(ctx, params)
+	require.IsType(t, &dhcp.CreateSharedNetworkSubmitOK{}, rsp2)
+
+	// It should result in sending network4-add, network4-subnet-add and
+	// config-write.
+	require.Len(t, fa.RecordedCommands, 3)
+	require.JSONEq(t, `{
+        "command": "network4-add",
+        "service": ["dhcp4"],
+        "arguments": {
+            "shared-networks": [
+                {
+                    "name": "baz",
+                    "interface": "eth0"
+                }
+            ]
+        }
+    }`, fa.RecordedCommands[0].Marshal())
+	require.JSONEq(t, `{
+        "command": "network4-subnet-add",
+        "service": ["dhcp4"],
+        "arguments": {
+            "name": "baz",
+            "id": 111
+        }
+    }`, fa.RecordedCommands[1].Marshal())
+	require.Equal(t, "config-write", fa.RecordedCommands[2].GetCommand())
+
+	// Make sure that the transaction is done.
+	cctx, _ := cm.RecoverContext(transactionID, int64(user.ID))
+	if cctx != nil {
+		cm.Done(cctx)
+	}
+	require.Nil(t, cctx)
+
+	// Make sure that the shared network has been added to the database
+	// and the subnet belongs to it.
+	subnet, err := dbmodel.GetSubnet(db, subnets[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, subnet)
+	require.NotNil(t, subnet.SharedNetwork)
+	require.Equal(t, "baz", subnet.SharedNetwork.Name)
+
+	sharedNetwork, err := dbmodel.GetSharedNetwork(db, subnet.SharedNetworkID)
+	require.NoError(t, err)
+	require.NotNil(t, sharedNetwork)
+	require.Len(t, sharedNetwork.LocalSharedNetworks, 1)
+	require.NotNil(t, sharedNetwork.LocalSharedNetworks[0].KeaParameters)
+	require.Equal(t, "eth0", *sharedNetwork.LocalSharedNetworks[0].KeaParameters.Interface)
+}
+
+// Test error case when a user attempts to begin a new transaction for
+// adding a shared network when there are no servers with subnet_cmds hook
+// library found.
+func TestCreateSharedNetworkBeginNoServers(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, _, ctx, _ := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	// The apps lack the subnet_cmds hook library.
+	_, _ = storktest.AddTestHosts(t, db)
+
+	rsp := rapi.CreateSharedNetworkBegin(ctx, dhcp.CreateSharedNetworkBeginParams{})
+	require.IsType(t, &dhcp.CreateSharedNetworkBeginDefault{}, rsp)
+	defaultRsp := rsp.(*dhcp.CreateSharedNetworkBeginDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+}
+
+// Test that submitting a shared network without a name fails.
+func TestCreateSharedNetworkSubmitEmptyName(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, _, ctx, _ := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	app := addTestSubnetCmdsServer(t, db)
+
+	rsp := rapi.CreateSharedNetworkBegin(ctx, dhcp.CreateSharedNetworkBeginParams{})
+	require.IsType(t, &dhcp.CreateSharedNetworkBeginOK{}, rsp)
+	transactionID := rsp.(*dhcp.CreateSharedNetworkBeginOK).Payload.ID
+
+	rsp2 := rapi.CreateSharedNetworkSubmit(ctx, dhcp.CreateSharedNetworkSubmitParams{
+		ID: transactionID,
+		SharedNetwork: &models.SharedNetwork{
+			Universe: 4,
+			LocalSharedNetworks: []*models.LocalSharedNetwork{
+				{
+					DaemonID: app.Daemons[0].ID,
+				},
+			},
+		},
+	})
+	require.IsType(t, &dhcp.CreateSharedNetworkSubmitDefault{}, rsp2)
+	defaultRsp := rsp2.(*dhcp.CreateSharedNetworkSubmitDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+	require.Empty(t, fa.RecordedCommands)
+}
+
+// Test the calls for creating new transaction and updating a shared network.
+// The shared network is renamed and it takes over a top-level subnet.
+func TestUpdateSharedNetworkBeginSubmit(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, cm, ctx, user := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	_ = addTestSubnetCmdsServer(t, db)
+
+	sharedNetworks, err := dbmodel.GetAllSharedNetworks(db, 4)
+	require.NoError(t, err)
+	require.Len(t, sharedNetworks, 1)
+
+	// Begin transaction.
+	rsp := rapi.UpdateSharedNetworkBegin(ctx, dhcp.UpdateSharedNetworkBeginParams{
+		SharedNetworkID: sharedNetworks[0].ID,
+	})
+	require.IsType(t, &dhcp.UpdateSharedNetworkBeginOK{}, rsp)
+	contents := rsp.(*dhcp.UpdateSharedNetworkBeginOK).Payload
+
+	transactionID := contents.ID
+	require.NotZero(t, transactionID)
+	require.NotNil(t, contents.SharedNetwork)
+	require.Equal(t, "bar", contents.SharedNetwork.Name)
+	require.Len(t, contents.SharedNetwork.Subnets, 1)
+	require.Len(t, contents.Daemons, 1)
+	require.Len(t, contents.Subnets, 2)
+
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.2.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+
+	// Rename the shared network and add the top-level subnet to it.
+	sharedNetwork := contents.SharedNetwork
+	sharedNetwork.Name = "qux"
+	sharedNetwork.Subnets = append(sharedNetwork.Subnets, &models.Subnet{
+		ID: subnets[0].ID,
+	})
+	rsp2 := rapi.UpdateSharedNetworkSubmit(ctx, dhcp.UpdateSharedNetworkSubmitParams{
+		SharedNetworkID: sharedNetworks[0].ID,
+		ID:              transactionID,
+		SharedNetwork:   sharedNetwork,
+	})
+	require.IsType(t, &dhcp.UpdateSharedNetworkSubmitOK{}, rsp2)
+
+	require.Len(t, fa.RecordedCommands, 5)
+	require.JSONEq(t, `{
+        "command": "network4-del",
+        "service": ["dhcp4"],
+        "arguments": {
+            "name": "bar",
+            "subnets-action": "keep"
+        }
+    }`, fa.RecordedCommands[0].Marshal())
+	require.JSONEq(t, `{
+        "command": "network4-add",
+        "service": ["dhcp4"],
+        "arguments": {
+            "shared-networks": [
+                {
+                    "name": "qux"
+                }
+            ]
+        }
+    }`, fa.RecordedCommands[1].Marshal())
+	require.JSONEq(t, `{
+        "command": "network4-subnet-add",
+        "service": ["dhcp4"],
+        "arguments": {
+            "name": "qux",
+            "id": 222
+        }
+    }`, fa.RecordedCommands[2].Marshal())
+	require.JSONEq(t, `{
+        "command": "network4-subnet-add",
+        "service": ["dhcp4"],
+        "arguments": {
+            "name": "qux",
+            "id": 111
+        }
+    }`, fa.RecordedCommands[3].Marshal())
+	require.Equal(t, "config-write", fa.RecordedCommands[4].GetCommand())
+
+	// Make sure that the transaction is done.
+	cctx, _ := cm.RecoverContext(transactionID, int64(user.ID))
+	if cctx != nil {
+		cm.Done(cctx)
+	}
+	require.Nil(t, cctx)
+
+	// Make sure that the shared network has been updated in the database.
+	returnedSharedNetwork, err := dbmodel.GetSharedNetwork(db, sharedNetworks[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, returnedSharedNetwork)
+	require.Equal(t, "qux", returnedSharedNetwork.Name)
+	require.Len(t, returnedSharedNetwork.Subnets, 2)
+}
+
+// Test that an error is returned when beginning the update of a
+// non-existing shared network.
+func TestUpdateSharedNetworkBeginNonExistingSharedNetworkID(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, _, ctx, _ := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	_ = addTestSubnetCmdsServer(t, db)
+
+	rsp := rapi.UpdateSharedNetworkBegin(ctx, dhcp.UpdateSharedNetworkBeginParams{
+		SharedNetworkID: 1024,
+	})
+	require.IsType(t, &dhcp.UpdateSharedNetworkBeginDefault{}, rsp)
+	defaultRsp := rsp.(*dhcp.UpdateSharedNetworkBeginDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+}
+
+// Test that the transaction to update a shared network can be canceled.
+func TestUpdateSharedNetworkBeginCancel(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, cm, ctx, user := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	_ = addTestSubnetCmdsServer(t, db)
+
+	sharedNetworks, err := dbmodel.GetAllSharedNetworks(db, 4)
+	require.NoError(t, err)
+	require.Len(t, sharedNetworks, 1)
+
+	rsp := rapi.UpdateSharedNetworkBegin(ctx, dhcp.UpdateSharedNetworkBeginParams{
+		SharedNetworkID: sharedNetworks[0].ID,
+	})
+	require.IsType(t, &dhcp.UpdateSharedNetworkBeginOK{}, rsp)
+	transactionID := rsp.(*dhcp.UpdateSharedNetworkBeginOK).Payload.ID
+
+	// The daemons should be locked, so another transaction cannot begin.
+	rsp = rapi.UpdateSharedNetworkBegin(ctx, dhcp.UpdateSharedNetworkBeginParams{
+		SharedNetworkID: sharedNetworks[0].ID,
+	})
+	require.IsType(t, &dhcp.UpdateSharedNetworkBeginDefault{}, rsp)
+	defaultRsp := rsp.(*dhcp.UpdateSharedNetworkBeginDefault)
+	require.Equal(t, http.StatusLocked, getStatusCode(*defaultRsp))
+
+	rsp2 := rapi.UpdateSharedNetworkDelete(ctx, dhcp.UpdateSharedNetworkDeleteParams{
+		SharedNetworkID: sharedNetworks[0].ID,
+		ID:              transactionID,
+	})
+	require.IsType(t, &dhcp.UpdateSharedNetworkDeleteOK{}, rsp2)
+
+	// The transaction should no longer exist.
+	cctx, _ := cm.RecoverContext(transactionID, int64(user.ID))
+	if cctx != nil {
+		cm.Done(cctx)
+	}
+	require.Nil(t, cctx)
+
+	// The daemons should be unlocked.
+	rsp = rapi.UpdateSharedNetworkBegin(ctx, dhcp.UpdateSharedNetworkBeginParams{
+		SharedNetworkID: sharedNetworks[0].ID,
+	})
+	require.IsType(t, &dhcp.UpdateSharedNetworkBeginOK{}, rsp)
+}
+
+// Test deleting a shared network.
+func TestDeleteSharedNetwork(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	rapi, _, ctx, _ := newTestSubnetCmdsRestAPI(t, db, dbSettings, fa)
+
+	_ = addTestSubnetCmdsServer(t, db)
+
+	sharedNetworks, err := dbmodel.GetAllSharedNetworks(db, 4)
+	require.NoError(t, err)
+	require.Len(t, sharedNetworks, 1)
+
+	rsp := rapi.DeleteSharedNetwork(ctx, dhcp.DeleteSharedNetworkParams{
+		ID: sharedNetworks[0].ID,
+	})
+	require.IsType(t, &dhcp.DeleteSharedNetworkOK{}, rsp)
+
+	require.Len(t, fa.RecordedCommands, 2)
+	require.JSONEq(t, `{
+        "command": "network4-del",
+        "service": ["dhcp4"],
+        "arguments": {
+            "name": "bar",
+            "subnets-action": "keep"
+        }
+    }`, fa.RecordedCommands[0].Marshal())
+	require.Equal(t, "config-write", fa.RecordedCommands[1].GetCommand())
+
+	returnedSharedNetwork, err := dbmodel.GetSharedNetwork(db, sharedNetworks[0].ID)
+	require.NoError(t, err)
+	require.Nil(t, returnedSharedNetwork)
+
+	// The subnet should have been preserved as a top-level subnet.
+	subnets, err := dbmodel.GetSubnetsByPrefix(db, "192.0.3.0/24")
+	require.NoError(t, err)
+	require.Len(t, subnets, 1)
+	require.Zero(t, subnets[0].SharedNetworkID)
+
+	// Deleting non-existing shared network should fail.
+	rsp = rapi.DeleteSharedNetwork(ctx, dhcp.DeleteSharedNetworkParams{
+		ID: sharedNetworks[0].ID,
+	})
+	require.IsType(t, &dhcp.DeleteSharedNetworkDefault{}, rsp)
+	defaultRsp := rsp.(*dhcp.DeleteSharedNetworkDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*defaultRsp))
+}
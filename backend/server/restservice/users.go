@@ -49,11 +49,44 @@ func newRestGroup(g dbmodel.SystemGroup) *models.Group {
 		ID:          &id,
 		Name:        &g.Name,
 		Description: &g.Description,
+		Predefined:  g.IsPredefined(),
 	}
+	for _, p := range g.Permissions {
+		r.Permissions = append(r.Permissions, string(p))
+	}
+	r.ScopeMachineIds = g.ScopeMachineIDs
+	r.ScopeAppIds = g.ScopeAppIDs
+	r.ScopeSubnetIds = g.ScopeSubnetIDs
 
 	return r
 }
 
+// Creates new instance of the group used by the database from the group
+// received over the REST API. It returns an error if the group lacks
+// the name or contains unsupported permissions.
+func newDBGroup(g *models.Group) (*dbmodel.SystemGroup, error) {
+	if g == nil || g.Name == nil || len(strings.TrimSpace(*g.Name)) == 0 {
+		return nil, errors.New("group name is required")
+	}
+	group := &dbmodel.SystemGroup{
+		Name:            strings.TrimSpace(*g.Name),
+		ScopeMachineIDs: g.ScopeMachineIds,
+		ScopeAppIDs:     g.ScopeAppIds,
+		ScopeSubnetIDs:  g.ScopeSubnetIds,
+	}
+	if g.Description != nil {
+		group.Description = *g.Description
+	}
+	for _, p := range g.Permissions {
+		permission := dbmodel.Permission(p)
+		if !permission.IsValid() {
+			return nil, errors.Errorf("unsupported permission %s", p)
+		}
+		group.Permissions = append(group.Permissions, permission)
+	}
+	return group, nil
+}
+
 // The internal authentication flow based on the login and password stored in
 // the database.
 func (r *RestAPI) internalAuthentication(params users.CreateSessionParams) (*dbmodel.SystemUser, error) {
@@ -596,6 +629,86 @@ func (r *RestAPI) GetGroups(ctx context.Context, params users.GetGroupsParams) m
 	return rsp
 }
 
+// Creates a new group with the specified permissions and scope.
+func (r *RestAPI) CreateGroup(ctx context.Context, params users.CreateGroupParams) middleware.Responder {
+	group, err := newDBGroup(params.Group)
+	if err != nil {
+		log.WithError(err).Warn("Failed to create new group")
+
+		msg := fmt.Sprintf("Failed to create new group: %s", err)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewCreateGroupDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+
+	if err = dbmodel.AddGroup(r.DB, group); err != nil {
+		log.WithField("group", group.Name).WithError(err).Error("Failed to create new group")
+
+		msg := fmt.Sprintf("Failed to create new group %s", group.Name)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewCreateGroupDefault(http.StatusInternalServerError).WithPayload(&rspErr)
+	}
+
+	return users.NewCreateGroupOK().WithPayload(newRestGroup(*group))
+}
+
+// Updates an existing group. The predefined groups cannot be updated.
+func (r *RestAPI) UpdateGroup(ctx context.Context, params users.UpdateGroupParams) middleware.Responder {
+	group, err := newDBGroup(params.Group)
+	if err != nil {
+		log.WithField("groupID", params.ID).WithError(err).Warn("Failed to update group")
+
+		msg := fmt.Sprintf("Failed to update group: %s", err)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewUpdateGroupDefault(http.StatusBadRequest).WithPayload(&rspErr)
+	}
+	group.ID = int(params.ID)
+
+	if err = dbmodel.UpdateGroup(r.DB, group); err != nil {
+		code, msg := getGroupErrorResponse("update", group.ID, err)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewUpdateGroupDefault(code).WithPayload(&rspErr)
+	}
+
+	return users.NewUpdateGroupOK()
+}
+
+// Deletes an existing group. The predefined groups cannot be deleted.
+func (r *RestAPI) DeleteGroup(ctx context.Context, params users.DeleteGroupParams) middleware.Responder {
+	id := int(params.ID)
+	if err := dbmodel.DeleteGroup(r.DB, id); err != nil {
+		code, msg := getGroupErrorResponse("delete", id, err)
+		rspErr := models.APIError{
+			Message: &msg,
+		}
+		return users.NewDeleteGroupDefault(code).WithPayload(&rspErr)
+	}
+	return users.NewDeleteGroupOK()
+}
+
+// Returns the HTTP status code and the error message for a failed group
+// update or deletion. The status code depends on the error type.
+func getGroupErrorResponse(operation string, id int, err error) (int, string) {
+	switch {
+	case errors.Is(err, dbmodel.ErrPredefinedGroup):
+		log.WithField("groupID", id).WithError(err).Warnf("Failed to %s group", operation)
+		return http.StatusBadRequest, fmt.Sprintf("Failed to %s group with ID %d: predefined group cannot be modified", operation, id)
+	case errors.Is(err, dbmodel.ErrNotExists):
+		log.WithField("groupID", id).WithError(err).Warnf("Failed to %s group", operation)
+		return http.StatusNotFound, fmt.Sprintf("Failed to find group with ID %d", id)
+	default:
+		log.WithField("groupID", id).WithError(err).Errorf("Failed to %s group", operation)
+		return http.StatusInternalServerError, fmt.Sprintf("Failed to %s group with ID %d", operation, id)
+	}
+}
+
 // Get authentication methods supported by server. Endpoint is allowed without log in.
 func (r *RestAPI) GetAuthenticationMethods(ctx context.Context, params users.GetAuthenticationMethodsParams) middleware.Responder {
 	metadata := r.HookManager.GetAuthenticationMetadata()
@@ -773,7 +773,142 @@ func TestGetGroups(t *testing.T) {
 
 	groups := rspOK.Payload
 	require.NotNil(t, groups.Items)
-	require.GreaterOrEqual(t, 2, len(groups.Items))
+	require.Len(t, groups.Items, 4)
+	require.EqualValues(t, 4, groups.Total)
+	require.Equal(t, "read-only", *groups.Items[2].Name)
+	require.Equal(t, []string{"view-all"}, groups.Items[2].Permissions)
+	require.True(t, groups.Items[2].Predefined)
+}
+
+// Tests that the custom group can be created, updated and deleted via
+// the REST API.
+func TestCreateUpdateDeleteGroup(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	ctx := context.Background()
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	// Create the group.
+	rsp := rapi.CreateGroup(ctx, users.CreateGroupParams{
+		Group: &models.Group{
+			Name:            storkutil.Ptr("noc"),
+			Description:     storkutil.Ptr("NOC staff"),
+			Permissions:     []string{"view-all", "edit-hosts"},
+			ScopeMachineIds: []int64{1},
+		},
+	})
+	require.IsType(t, &users.CreateGroupOK{}, rsp)
+	created := rsp.(*users.CreateGroupOK).Payload
+	require.NotNil(t, created.ID)
+	require.False(t, created.Predefined)
+
+	group, err := dbmodel.GetGroupByID(db, int(*created.ID))
+	require.NoError(t, err)
+	require.NotNil(t, group)
+	require.Equal(t, "noc", group.Name)
+	require.Equal(t, []dbmodel.Permission{dbmodel.PermissionViewAll, dbmodel.PermissionEditHosts}, group.Permissions)
+	require.Equal(t, []int64{1}, group.ScopeMachineIDs)
+
+	// Update the group.
+	rsp = rapi.UpdateGroup(ctx, users.UpdateGroupParams{
+		ID: *created.ID,
+		Group: &models.Group{
+			Name:        storkutil.Ptr("noc"),
+			Permissions: []string{"view-all"},
+		},
+	})
+	require.IsType(t, &users.UpdateGroupOK{}, rsp)
+
+	group, err = dbmodel.GetGroupByID(db, int(*created.ID))
+	require.NoError(t, err)
+	require.Equal(t, []dbmodel.Permission{dbmodel.PermissionViewAll}, group.Permissions)
+	require.Empty(t, group.ScopeMachineIDs)
+
+	// Delete the group.
+	rsp = rapi.DeleteGroup(ctx, users.DeleteGroupParams{ID: *created.ID})
+	require.IsType(t, &users.DeleteGroupOK{}, rsp)
+
+	// The group no longer exists.
+	rsp = rapi.DeleteGroup(ctx, users.DeleteGroupParams{ID: *created.ID})
+	require.IsType(t, &users.DeleteGroupDefault{}, rsp)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*rsp.(*users.DeleteGroupDefault)))
+}
+
+// Tests that the predefined groups cannot be modified via the REST API.
+func TestUpdateDeletePredefinedGroup(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	ctx := context.Background()
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	rsp := rapi.UpdateGroup(ctx, users.UpdateGroupParams{
+		ID: int64(dbmodel.ReadOnlyGroupID),
+		Group: &models.Group{
+			Name:        storkutil.Ptr("read-only"),
+			Permissions: []string{"view-all", "edit-hosts"},
+		},
+	})
+	require.IsType(t, &users.UpdateGroupDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.UpdateGroupDefault)))
+
+	rsp = rapi.DeleteGroup(ctx, users.DeleteGroupParams{ID: int64(dbmodel.AdminGroupID)})
+	require.IsType(t, &users.DeleteGroupDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.DeleteGroupDefault)))
+}
+
+// Tests that the group with an unsupported permission or without a name
+// is rejected.
+func TestCreateGroupInvalid(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	ctx := context.Background()
+	rapi, err := NewRestAPI(dbSettings, db)
+	require.NoError(t, err)
+
+	rsp := rapi.CreateGroup(ctx, users.CreateGroupParams{
+		Group: &models.Group{
+			Name:        storkutil.Ptr("noc"),
+			Permissions: []string{"edit-everything"},
+		},
+	})
+	require.IsType(t, &users.CreateGroupDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.CreateGroupDefault)))
+
+	rsp = rapi.CreateGroup(ctx, users.CreateGroupParams{
+		Group: &models.Group{
+			Permissions: []string{"view-all"},
+		},
+	})
+	require.IsType(t, &users.CreateGroupDefault{}, rsp)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*rsp.(*users.CreateGroupDefault)))
+}
+
+// Tests converting the group received over the REST API to the database
+// model.
+func TestNewDBGroup(t *testing.T) {
+	group, err := newDBGroup(&models.Group{
+		Name:           storkutil.Ptr(" noc "),
+		Description:    storkutil.Ptr("NOC staff"),
+		Permissions:    []string{"view-hosts", "dump-machines"},
+		ScopeAppIds:    []int64{2},
+		ScopeSubnetIds: []int64{3},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "noc", group.Name)
+	require.Equal(t, "NOC staff", group.Description)
+	require.Equal(t, []dbmodel.Permission{dbmodel.PermissionViewHosts, dbmodel.PermissionDumpMachines}, group.Permissions)
+	require.Equal(t, []int64{2}, group.ScopeAppIDs)
+	require.Equal(t, []int64{3}, group.ScopeSubnetIDs)
+
+	_, err = newDBGroup(&models.Group{Name: storkutil.Ptr(" ")})
+	require.Error(t, err)
+	_, err = newDBGroup(nil)
+	require.Error(t, err)
 }
 
 // Tests that user information can be retrieved via REST API.
@@ -0,0 +1,229 @@
+package restservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-openapi/runtime/middleware"
+	log "github.com/sirupsen/logrus"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+)
+
+// Converts the utilization alert rule fetched from the database to the
+// REST API format.
+func newRestUtilizationAlertRule(dbRule *dbmodel.UtilizationAlertRule) *models.UtilizationAlertRule {
+	return &models.UtilizationAlertRule{
+		ID:              dbRule.ID,
+		SubnetID:        dbRule.SubnetID,
+		SharedNetworkID: dbRule.SharedNetworkID,
+		AddrWarning:     dbRule.AddrWarning,
+		AddrCritical:    dbRule.AddrCritical,
+		PdWarning:       dbRule.PdWarning,
+		PdCritical:      dbRule.PdCritical,
+		Hysteresis:      dbRule.Hysteresis,
+	}
+}
+
+// Converts the utilization alert rule received over the REST API to the
+// database model and validates it.
+func newDBUtilizationAlertRule(restRule *models.UtilizationAlertRule) (*dbmodel.UtilizationAlertRule, error) {
+	if restRule == nil {
+		return nil, errors.New("utilization alert rule must be specified")
+	}
+	dbRule := &dbmodel.UtilizationAlertRule{
+		SubnetID:        restRule.SubnetID,
+		SharedNetworkID: restRule.SharedNetworkID,
+		AddrWarning:     restRule.AddrWarning,
+		AddrCritical:    restRule.AddrCritical,
+		PdWarning:       restRule.PdWarning,
+		PdCritical:      restRule.PdCritical,
+		Hysteresis:      restRule.Hysteresis,
+	}
+	if err := dbRule.Validate(); err != nil {
+		return nil, err
+	}
+	return dbRule, nil
+}
+
+// Returns the global utilization alert rule followed by the rules for
+// the particular subnets and shared networks.
+func (r *RestAPI) GetUtilizationAlertRules(ctx context.Context, params dhcp.GetUtilizationAlertRulesParams) middleware.Responder {
+	dbRules, err := dbmodel.GetUtilizationAlertRules(r.DB)
+	if err != nil {
+		log.WithError(err).Error("Failed to get utilization alert rules from the database")
+		msg := "Problem fetching utilization alert rules from the database"
+		rsp := dhcp.NewGetUtilizationAlertRulesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	rules := &models.UtilizationAlertRules{
+		Items: []*models.UtilizationAlertRule{},
+		Total: int64(len(dbRules)),
+	}
+	for i := range dbRules {
+		rules.Items = append(rules.Items, newRestUtilizationAlertRule(&dbRules[i]))
+	}
+	rsp := dhcp.NewGetUtilizationAlertRulesOK().WithPayload(rules)
+	return rsp
+}
+
+// Checks if the subnet or the shared network the rule applies to exists
+// and doesn't have a rule yet. It returns the HTTP status code and the
+// error message when the rule cannot be added.
+func (r *RestAPI) checkUtilizationAlertRuleTarget(dbRule *dbmodel.UtilizationAlertRule) (int, string) {
+	if dbRule.SubnetID != 0 {
+		subnet, err := dbmodel.GetSubnet(r.DB, dbRule.SubnetID)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to get subnet %d from the database", dbRule.SubnetID)
+			return http.StatusInternalServerError, fmt.Sprintf("Problem fetching subnet with ID %d from the database", dbRule.SubnetID)
+		}
+		if subnet == nil {
+			return http.StatusBadRequest, fmt.Sprintf("Cannot find subnet with ID %d", dbRule.SubnetID)
+		}
+	} else {
+		network, err := dbmodel.GetSharedNetwork(r.DB, dbRule.SharedNetworkID)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to get shared network %d from the database", dbRule.SharedNetworkID)
+			return http.StatusInternalServerError, fmt.Sprintf("Problem fetching shared network with ID %d from the database", dbRule.SharedNetworkID)
+		}
+		if network == nil {
+			return http.StatusBadRequest, fmt.Sprintf("Cannot find shared network with ID %d", dbRule.SharedNetworkID)
+		}
+	}
+
+	dbRules, err := dbmodel.GetUtilizationAlertRules(r.DB)
+	if err != nil {
+		log.WithError(err).Error("Failed to get utilization alert rules from the database")
+		return http.StatusInternalServerError, "Problem fetching utilization alert rules from the database"
+	}
+	for _, existing := range dbRules {
+		if existing.SubnetID == dbRule.SubnetID && existing.SharedNetworkID == dbRule.SharedNetworkID {
+			return http.StatusConflict, "Utilization alert rule for this subnet or shared network already exists"
+		}
+	}
+	return http.StatusOK, ""
+}
+
+// Adds a utilization alert rule for a subnet or a shared network.
+func (r *RestAPI) CreateUtilizationAlertRule(ctx context.Context, params dhcp.CreateUtilizationAlertRuleParams) middleware.Responder {
+	dbRule, err := newDBUtilizationAlertRule(params.Rule)
+	if err == nil && dbRule.IsGlobal() {
+		err = errors.New("subnet or shared network ID must be specified")
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Invalid utilization alert rule: %s", err)
+		rsp := dhcp.NewCreateUtilizationAlertRuleDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	if code, msg := r.checkUtilizationAlertRuleTarget(dbRule); code != http.StatusOK {
+		rsp := dhcp.NewCreateUtilizationAlertRuleDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	if err = dbmodel.AddUtilizationAlertRule(r.DB, dbRule); err != nil {
+		log.WithError(err).Error("Failed to add utilization alert rule to the database")
+		msg := "Problem adding utilization alert rule to the database"
+		rsp := dhcp.NewCreateUtilizationAlertRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewCreateUtilizationAlertRuleOK().WithPayload(newRestUtilizationAlertRule(dbRule))
+	return rsp
+}
+
+// Updates the thresholds of the utilization alert rule. The subnet and
+// the shared network the rule applies to are preserved.
+func (r *RestAPI) UpdateUtilizationAlertRule(ctx context.Context, params dhcp.UpdateUtilizationAlertRuleParams) middleware.Responder {
+	existing, err := dbmodel.GetUtilizationAlertRuleByID(r.DB, params.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to get utilization alert rule %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem fetching utilization alert rule with ID %d from the database", params.ID)
+		rsp := dhcp.NewUpdateUtilizationAlertRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if existing == nil {
+		msg := fmt.Sprintf("Cannot find utilization alert rule with ID %d", params.ID)
+		rsp := dhcp.NewUpdateUtilizationAlertRuleDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	if params.Rule != nil {
+		params.Rule.SubnetID = existing.SubnetID
+		params.Rule.SharedNetworkID = existing.SharedNetworkID
+	}
+	dbRule, err := newDBUtilizationAlertRule(params.Rule)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid utilization alert rule: %s", err)
+		rsp := dhcp.NewUpdateUtilizationAlertRuleDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	dbRule.ID = existing.ID
+
+	if err = dbmodel.UpdateUtilizationAlertRule(r.DB, dbRule); err != nil {
+		log.WithError(err).Errorf("Failed to update utilization alert rule %d in the database", params.ID)
+		msg := fmt.Sprintf("Problem updating utilization alert rule with ID %d in the database", params.ID)
+		rsp := dhcp.NewUpdateUtilizationAlertRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewUpdateUtilizationAlertRuleOK().WithPayload(newRestUtilizationAlertRule(dbRule))
+	return rsp
+}
+
+// Deletes the utilization alert rule of a subnet or a shared network.
+// The global rule cannot be deleted.
+func (r *RestAPI) DeleteUtilizationAlertRule(ctx context.Context, params dhcp.DeleteUtilizationAlertRuleParams) middleware.Responder {
+	existing, err := dbmodel.GetUtilizationAlertRuleByID(r.DB, params.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to get utilization alert rule %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem fetching utilization alert rule with ID %d from the database", params.ID)
+		rsp := dhcp.NewDeleteUtilizationAlertRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if existing == nil {
+		msg := fmt.Sprintf("Cannot find utilization alert rule with ID %d", params.ID)
+		rsp := dhcp.NewDeleteUtilizationAlertRuleDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if existing.IsGlobal() {
+		msg := "Global utilization alert rule cannot be deleted"
+		rsp := dhcp.NewDeleteUtilizationAlertRuleDefault(http.StatusBadRequest).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	if err = dbmodel.DeleteUtilizationAlertRule(r.DB, params.ID); err != nil {
+		log.WithError(err).Errorf("Failed to delete utilization alert rule %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem deleting utilization alert rule with ID %d from the database", params.ID)
+		rsp := dhcp.NewDeleteUtilizationAlertRuleDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewDeleteUtilizationAlertRuleOK()
+	return rsp
+}
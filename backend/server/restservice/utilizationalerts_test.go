@@ -0,0 +1,151 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/models"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Test converting the utilization alert rule received over the REST API
+// to the database model.
+func TestNewDBUtilizationAlertRule(t *testing.T) {
+	rule, err := newDBUtilizationAlertRule(&models.UtilizationAlertRule{
+		SubnetID:     5,
+		AddrWarning:  70,
+		AddrCritical: 85,
+		Hysteresis:   3,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 5, rule.SubnetID)
+	require.EqualValues(t, 70, rule.AddrWarning)
+	require.EqualValues(t, 85, rule.AddrCritical)
+	require.EqualValues(t, 3, rule.Hysteresis)
+
+	_, err = newDBUtilizationAlertRule(nil)
+	require.Error(t, err)
+
+	_, err = newDBUtilizationAlertRule(&models.UtilizationAlertRule{
+		AddrWarning:  90,
+		AddrCritical: 85,
+	})
+	require.Error(t, err)
+}
+
+// Test adding, getting, updating and deleting the utilization alert rules
+// over the REST API.
+func TestUtilizationAlertRules(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	settings := RestAPISettings{}
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	fec := &storktest.FakeEventCenter{}
+	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	subnet := &dbmodel.Subnet{
+		Prefix: "192.0.2.0/24",
+	}
+	err = dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	// The global rule is returned first.
+	rsp := rapi.GetUtilizationAlertRules(ctx, dhcp.GetUtilizationAlertRulesParams{})
+	require.IsType(t, &dhcp.GetUtilizationAlertRulesOK{}, rsp)
+	rules := rsp.(*dhcp.GetUtilizationAlertRulesOK).Payload
+	require.EqualValues(t, 1, rules.Total)
+	require.Zero(t, rules.Items[0].SubnetID)
+	require.Zero(t, rules.Items[0].SharedNetworkID)
+	globalID := rules.Items[0].ID
+
+	// The global rule cannot be added.
+	rsp = rapi.CreateUtilizationAlertRule(ctx, dhcp.CreateUtilizationAlertRuleParams{
+		Rule: &models.UtilizationAlertRule{AddrWarning: 50},
+	})
+	require.IsType(t, &dhcp.CreateUtilizationAlertRuleDefault{}, rsp)
+	defaultRsp := rsp.(*dhcp.CreateUtilizationAlertRuleDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+
+	// The subnet must exist.
+	rsp = rapi.CreateUtilizationAlertRule(ctx, dhcp.CreateUtilizationAlertRuleParams{
+		Rule: &models.UtilizationAlertRule{SubnetID: subnet.ID + 100, AddrWarning: 50},
+	})
+	require.IsType(t, &dhcp.CreateUtilizationAlertRuleDefault{}, rsp)
+	defaultRsp = rsp.(*dhcp.CreateUtilizationAlertRuleDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+
+	// Add the subnet rule.
+	rsp = rapi.CreateUtilizationAlertRule(ctx, dhcp.CreateUtilizationAlertRuleParams{
+		Rule: &models.UtilizationAlertRule{SubnetID: subnet.ID, AddrWarning: 50},
+	})
+	require.IsType(t, &dhcp.CreateUtilizationAlertRuleOK{}, rsp)
+	created := rsp.(*dhcp.CreateUtilizationAlertRuleOK).Payload
+	require.NotZero(t, created.ID)
+	require.Equal(t, subnet.ID, created.SubnetID)
+
+	// There can be only one rule for the subnet.
+	rsp = rapi.CreateUtilizationAlertRule(ctx, dhcp.CreateUtilizationAlertRuleParams{
+		Rule: &models.UtilizationAlertRule{SubnetID: subnet.ID, AddrWarning: 60},
+	})
+	require.IsType(t, &dhcp.CreateUtilizationAlertRuleDefault{}, rsp)
+	defaultRsp = rsp.(*dhcp.CreateUtilizationAlertRuleDefault)
+	require.Equal(t, http.StatusConflict, getStatusCode(*defaultRsp))
+
+	// Update the rule. The subnet ID is preserved.
+	rsp = rapi.UpdateUtilizationAlertRule(ctx, dhcp.UpdateUtilizationAlertRuleParams{
+		ID:   created.ID,
+		Rule: &models.UtilizationAlertRule{AddrWarning: 65, AddrCritical: 75},
+	})
+	require.IsType(t, &dhcp.UpdateUtilizationAlertRuleOK{}, rsp)
+	updated := rsp.(*dhcp.UpdateUtilizationAlertRuleOK).Payload
+	require.Equal(t, subnet.ID, updated.SubnetID)
+	require.EqualValues(t, 65, updated.AddrWarning)
+	require.EqualValues(t, 75, updated.AddrCritical)
+
+	// Update the non-existing rule.
+	rsp = rapi.UpdateUtilizationAlertRule(ctx, dhcp.UpdateUtilizationAlertRuleParams{
+		ID:   created.ID + 100,
+		Rule: &models.UtilizationAlertRule{AddrWarning: 65},
+	})
+	require.IsType(t, &dhcp.UpdateUtilizationAlertRuleDefault{}, rsp)
+	updateRsp := rsp.(*dhcp.UpdateUtilizationAlertRuleDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*updateRsp))
+
+	// Invalid thresholds.
+	rsp = rapi.UpdateUtilizationAlertRule(ctx, dhcp.UpdateUtilizationAlertRuleParams{
+		ID:   globalID,
+		Rule: &models.UtilizationAlertRule{AddrWarning: 120},
+	})
+	require.IsType(t, &dhcp.UpdateUtilizationAlertRuleDefault{}, rsp)
+	updateRsp = rsp.(*dhcp.UpdateUtilizationAlertRuleDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*updateRsp))
+
+	// The global rule cannot be deleted.
+	rsp = rapi.DeleteUtilizationAlertRule(ctx, dhcp.DeleteUtilizationAlertRuleParams{
+		ID: globalID,
+	})
+	require.IsType(t, &dhcp.DeleteUtilizationAlertRuleDefault{}, rsp)
+	deleteRsp := rsp.(*dhcp.DeleteUtilizationAlertRuleDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*deleteRsp))
+
+	rsp = rapi.DeleteUtilizationAlertRule(ctx, dhcp.DeleteUtilizationAlertRuleParams{
+		ID: created.ID,
+	})
+	require.IsType(t, &dhcp.DeleteUtilizationAlertRuleOK{}, rsp)
+
+	rsp = rapi.DeleteUtilizationAlertRule(ctx, dhcp.DeleteUtilizationAlertRuleParams{
+		ID: created.ID,
+	})
+	require.IsType(t, &dhcp.DeleteUtilizationAlertRuleDefault{}, rsp)
+	deleteRsp = rsp.(*dhcp.DeleteUtilizationAlertRuleDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*deleteRsp))
+}
@@ -0,0 +1,157 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	log "github.com/sirupsen/logrus"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storkutil "isc.org/stork/util"
+)
+
+// Returns the resolution of the utilization samples to return. The
+// requested resolution is returned if specified. Otherwise, the finest
+// resolution whose samples cover the beginning of the time range is
+// selected. The daily samples are selected when the beginning of the
+// time range is not specified.
+func (r *RestAPI) getUtilizationResolution(requested *string, start *strfmt.DateTime) (dbmodel.UtilizationResolution, error) {
+	if requested != nil {
+		resolution := dbmodel.UtilizationResolution(*requested)
+		switch resolution {
+		case dbmodel.UtilizationResolutionRaw, dbmodel.UtilizationResolutionHourly, dbmodel.UtilizationResolutionDaily:
+			return resolution, nil
+		default:
+			return "", fmt.Errorf("invalid resolution %s", *requested)
+		}
+	}
+	if start == nil {
+		return dbmodel.UtilizationResolutionDaily, nil
+	}
+	age := storkutil.UTCNow().Sub(time.Time(*start))
+	for _, resolution := range []dbmodel.UtilizationResolution{dbmodel.UtilizationResolutionRaw, dbmodel.UtilizationResolutionHourly} {
+		if age <= dbmodel.GetUtilizationRetention(r.DB, resolution) {
+			return resolution, nil
+		}
+	}
+	return dbmodel.UtilizationResolutionDaily, nil
+}
+
+// Returns the utilization history fetched using the specified function.
+// The utilizations are converted to percent. It returns the HTTP status
+// code and the error message when the history cannot be returned.
+func (r *RestAPI) getUtilizationHistory(requested *string, start, end *strfmt.DateTime, getSamples func(dbops.DBI, dbmodel.UtilizationResolution, time.Time, time.Time) ([]dbmodel.UtilizationSample, error)) (*models.UtilizationHistory, int, string) {
+	resolution, err := r.getUtilizationResolution(requested, start)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Sprintf("Cannot get utilization history: %s", err)
+	}
+	var startTime, endTime time.Time
+	if start != nil {
+		startTime = time.Time(*start).UTC()
+	}
+	if end != nil {
+		endTime = time.Time(*end).UTC()
+	}
+	if !startTime.IsZero() && !endTime.IsZero() && endTime.Before(startTime) {
+		return nil, http.StatusBadRequest, "Cannot get utilization history: end of the time range precedes its beginning"
+	}
+
+	samples, err := getSamples(r.DB, resolution, startTime, endTime)
+	if err != nil {
+		log.WithError(err).Error("Failed to get utilization samples from the database")
+		return nil, http.StatusInternalServerError, "Problem fetching utilization history from the database"
+	}
+
+	history := &models.UtilizationHistory{
+		Resolution: string(resolution),
+		Items:      []*models.UtilizationSample{},
+		Total:      int64(len(samples)),
+	}
+	for _, sample := range samples {
+		history.Items = append(history.Items, &models.UtilizationSample{
+			SampledAt:          strfmt.DateTime(sample.SampledAt),
+			TotalAddresses:     sample.TotalAddresses,
+			AssignedAddresses:  sample.AssignedAddresses,
+			DeclinedAddresses:  sample.DeclinedAddresses,
+			TotalPds:           sample.TotalPds,
+			AssignedPds:        sample.AssignedPds,
+			AddrUtilization:    sample.AddrUtilization * 100,
+			PdUtilization:      sample.PdUtilization * 100,
+			MaxAddrUtilization: sample.MaxAddrUtilization * 100,
+			MaxPdUtilization:   sample.MaxPdUtilization * 100,
+		})
+	}
+	return history, http.StatusOK, ""
+}
+
+// Returns the utilization history of the subnet.
+func (r *RestAPI) GetSubnetUtilizationHistory(ctx context.Context, params dhcp.GetSubnetUtilizationHistoryParams) middleware.Responder {
+	subnet, err := dbmodel.GetSubnet(r.DB, params.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to get subnet %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem fetching subnet with ID %d from the database", params.ID)
+		rsp := dhcp.NewGetSubnetUtilizationHistoryDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if subnet == nil {
+		msg := fmt.Sprintf("Cannot find subnet with ID %d", params.ID)
+		rsp := dhcp.NewGetSubnetUtilizationHistoryDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	history, code, msg := r.getUtilizationHistory(params.Resolution, params.Start, params.End,
+		func(dbi dbops.DBI, resolution dbmodel.UtilizationResolution, start, end time.Time) ([]dbmodel.UtilizationSample, error) {
+			return dbmodel.GetSubnetUtilizationSamples(dbi, params.ID, resolution, start, end)
+		})
+	if code != http.StatusOK {
+		rsp := dhcp.NewGetSubnetUtilizationHistoryDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewGetSubnetUtilizationHistoryOK().WithPayload(history)
+	return rsp
+}
+
+// Returns the utilization history of the shared network.
+func (r *RestAPI) GetSharedNetworkUtilizationHistory(ctx context.Context, params dhcp.GetSharedNetworkUtilizationHistoryParams) middleware.Responder {
+	network, err := dbmodel.GetSharedNetwork(r.DB, params.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to get shared network %d from the database", params.ID)
+		msg := fmt.Sprintf("Problem fetching shared network with ID %d from the database", params.ID)
+		rsp := dhcp.NewGetSharedNetworkUtilizationHistoryDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if network == nil {
+		msg := fmt.Sprintf("Cannot find shared network with ID %d", params.ID)
+		rsp := dhcp.NewGetSharedNetworkUtilizationHistoryDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+
+	history, code, msg := r.getUtilizationHistory(params.Resolution, params.Start, params.End,
+		func(dbi dbops.DBI, resolution dbmodel.UtilizationResolution, start, end time.Time) ([]dbmodel.UtilizationSample, error) {
+			return dbmodel.GetSharedNetworkUtilizationSamples(dbi, params.ID, resolution, start, end)
+		})
+	if code != http.StatusOK {
+		rsp := dhcp.NewGetSharedNetworkUtilizationHistoryDefault(code).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := dhcp.NewGetSharedNetworkUtilizationHistoryOK().WithPayload(history)
+	return rsp
+}
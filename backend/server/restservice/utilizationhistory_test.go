@@ -0,0 +1,132 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/require"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	dhcp "isc.org/stork/server/gen/restapi/operations/d_h_c_p"
+	storktest "isc.org/stork/server/test/dbmodel"
+	storkutil "isc.org/stork/util"
+)
+
+// Test getting the subnet and shared network utilization history over
+// the REST API.
+func TestGetUtilizationHistory(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	settings := RestAPISettings{}
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	fec := &storktest.FakeEventCenter{}
+	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	network := &dbmodel.SharedNetwork{
+		Name:   "frog",
+		Family: 4,
+	}
+	err = dbmodel.AddSharedNetwork(db, network)
+	require.NoError(t, err)
+
+	subnet := &dbmodel.Subnet{
+		Prefix:          "192.0.2.0/24",
+		SharedNetworkID: network.ID,
+	}
+	err = dbmodel.AddSubnet(db, subnet)
+	require.NoError(t, err)
+
+	now := storkutil.UTCNow().Truncate(time.Second)
+	samples := []*dbmodel.UtilizationSample{}
+	for i := 0; i < 3; i++ {
+		sample := dbmodel.NewUtilizationSample(now.Add(-time.Duration(i)*time.Hour), 0.5, 0, dbmodel.SubnetStats{
+			"total-addresses":    uint64(256),
+			"assigned-addresses": uint64(128),
+		})
+		sample.SubnetID = subnet.ID
+		samples = append(samples, sample)
+	}
+	sample := dbmodel.NewUtilizationSample(now, 0.25, 0, dbmodel.SubnetStats{})
+	sample.SharedNetworkID = network.ID
+	samples = append(samples, sample)
+	err = dbmodel.AddUtilizationSamples(db, samples)
+	require.NoError(t, err)
+
+	// The raw samples are selected for the recent time range.
+	start := strfmt.DateTime(now.Add(-90 * time.Minute))
+	rsp := rapi.GetSubnetUtilizationHistory(ctx, dhcp.GetSubnetUtilizationHistoryParams{
+		ID:    subnet.ID,
+		Start: &start,
+	})
+	require.IsType(t, &dhcp.GetSubnetUtilizationHistoryOK{}, rsp)
+	history := rsp.(*dhcp.GetSubnetUtilizationHistoryOK).Payload
+	require.Equal(t, "raw", history.Resolution)
+	require.EqualValues(t, 2, history.Total)
+	require.Len(t, history.Items, 2)
+	require.EqualValues(t, 50, history.Items[0].AddrUtilization)
+	require.EqualValues(t, 128, history.Items[0].AssignedAddresses)
+	require.EqualValues(t, 256, history.Items[0].TotalAddresses)
+
+	// The daily samples are selected when the time range is not specified.
+	rsp = rapi.GetSubnetUtilizationHistory(ctx, dhcp.GetSubnetUtilizationHistoryParams{
+		ID: subnet.ID,
+	})
+	require.IsType(t, &dhcp.GetSubnetUtilizationHistoryOK{}, rsp)
+	history = rsp.(*dhcp.GetSubnetUtilizationHistoryOK).Payload
+	require.Equal(t, "daily", history.Resolution)
+	require.Empty(t, history.Items)
+
+	// Invalid resolution.
+	resolution := "weekly"
+	rsp = rapi.GetSubnetUtilizationHistory(ctx, dhcp.GetSubnetUtilizationHistoryParams{
+		ID:         subnet.ID,
+		Resolution: &resolution,
+	})
+	require.IsType(t, &dhcp.GetSubnetUtilizationHistoryDefault{}, rsp)
+	defaultRsp := rsp.(*dhcp.GetSubnetUtilizationHistoryDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+
+	// The end of the time range precedes its beginning.
+	end := strfmt.DateTime(now.Add(-2 * time.Hour))
+	rsp = rapi.GetSubnetUtilizationHistory(ctx, dhcp.GetSubnetUtilizationHistoryParams{
+		ID:    subnet.ID,
+		Start: &start,
+		End:   &end,
+	})
+	require.IsType(t, &dhcp.GetSubnetUtilizationHistoryDefault{}, rsp)
+	defaultRsp = rsp.(*dhcp.GetSubnetUtilizationHistoryDefault)
+	require.Equal(t, http.StatusBadRequest, getStatusCode(*defaultRsp))
+
+	// Non-existing subnet.
+	rsp = rapi.GetSubnetUtilizationHistory(ctx, dhcp.GetSubnetUtilizationHistoryParams{
+		ID: subnet.ID + 100,
+	})
+	require.IsType(t, &dhcp.GetSubnetUtilizationHistoryDefault{}, rsp)
+	defaultRsp = rsp.(*dhcp.GetSubnetUtilizationHistoryDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*defaultRsp))
+
+	// Shared network.
+	resolution = "raw"
+	rsp = rapi.GetSharedNetworkUtilizationHistory(ctx, dhcp.GetSharedNetworkUtilizationHistoryParams{
+		ID:         network.ID,
+		Resolution: &resolution,
+	})
+	require.IsType(t, &dhcp.GetSharedNetworkUtilizationHistoryOK{}, rsp)
+	history = rsp.(*dhcp.GetSharedNetworkUtilizationHistoryOK).Payload
+	require.Len(t, history.Items, 1)
+	require.EqualValues(t, 25, history.Items[0].AddrUtilization)
+
+	rsp = rapi.GetSharedNetworkUtilizationHistory(ctx, dhcp.GetSharedNetworkUtilizationHistoryParams{
+		ID: network.ID + 100,
+	})
+	require.IsType(t, &dhcp.GetSharedNetworkUtilizationHistoryDefault{}, rsp)
+	networkRsp := rsp.(*dhcp.GetSharedNetworkUtilizationHistoryDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*networkRsp))
+}
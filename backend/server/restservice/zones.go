@@ -0,0 +1,115 @@
+package restservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	log "github.com/sirupsen/logrus"
+
+	dbmodel "isc.org/stork/server/database/model"
+	"isc.org/stork/server/gen/models"
+	"isc.org/stork/server/gen/restapi/operations/services"
+)
+
+// Converts the BIND 9 zone from the database to the format used in REST API.
+func convertZoneToRestAPI(dbZone *dbmodel.Bind9Zone) *models.Bind9Zone {
+	zone := &models.Bind9Zone{
+		ID:             dbZone.ID,
+		DaemonID:       dbZone.DaemonID,
+		View:           dbZone.View,
+		Name:           dbZone.Name,
+		Class:          dbZone.Class,
+		ZoneType:       dbZone.ZoneType,
+		Serial:         dbZone.Serial,
+		LoadedAt:       convertToOptionalDatetime(dbZone.LoadedAt),
+		RefreshAt:      convertToOptionalDatetime(dbZone.RefreshAt),
+		ExpiresAt:      convertToOptionalDatetime(dbZone.ExpiresAt),
+		DnssecState:    dbZone.DNSSECState,
+		KeyMaintenance: dbZone.KeyMaintenance,
+		NextKeyEventAt: convertToOptionalDatetime(dbZone.NextKeyEventAt),
+		Primaries:      dbZone.Primaries,
+		UpdatedAt:      strfmt.DateTime(dbZone.UpdatedAt),
+	}
+	if dbZone.Daemon != nil && dbZone.Daemon.App != nil {
+		zone.AppID = dbZone.Daemon.App.ID
+		zone.AppName = dbZone.Daemon.App.Name
+		if dbZone.Daemon.App.Machine != nil {
+			zone.MachineAddress = dbZone.Daemon.App.Machine.Address
+		}
+	}
+	return zone
+}
+
+// Fetches a page of the zones matching the filters and converts them to the
+// format used in REST API.
+func (r *RestAPI) getZones(offset, limit int64, filters *dbmodel.Bind9ZonesByPageFilters, sortField string, sortDir dbmodel.SortDirEnum) (*models.Bind9Zones, error) {
+	dbZones, total, err := dbmodel.GetBind9ZonesByPage(r.DB, offset, limit, filters, sortField, sortDir)
+	if err != nil {
+		return nil, err
+	}
+	zones := &models.Bind9Zones{
+		Total: total,
+	}
+	for i := range dbZones {
+		zones.Items = append(zones.Items, convertZoneToRestAPI(&dbZones[i]))
+	}
+	return zones, nil
+}
+
+// Searches for BIND 9 zones that meet the given filter conditions. The
+// results are paginated.
+func (r *RestAPI) GetZones(ctx context.Context, params services.GetZonesParams) middleware.Responder {
+	var start int64
+	if params.Start != nil {
+		start = *params.Start
+	}
+
+	var limit int64 = 10
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	filters := &dbmodel.Bind9ZonesByPageFilters{
+		AppID:    params.AppID,
+		View:     params.View,
+		ZoneType: params.ZoneType,
+		Text:     params.Text,
+	}
+
+	zones, err := r.getZones(start, limit, filters, "", dbmodel.SortDirAny)
+	if err != nil {
+		log.Error(err)
+		msg := "Cannot get zones from db"
+		rsp := services.NewGetZonesDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := services.NewGetZonesOK().WithPayload(zones)
+	return rsp
+}
+
+// Returns the BIND 9 zone with the specified ID.
+func (r *RestAPI) GetZone(ctx context.Context, params services.GetZoneParams) middleware.Responder {
+	dbZone, err := dbmodel.GetBind9ZoneByID(r.DB, params.ID)
+	if err != nil {
+		log.Error(err)
+		msg := fmt.Sprintf("Cannot get zone with ID %d from db", params.ID)
+		rsp := services.NewGetZoneDefault(http.StatusInternalServerError).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	if dbZone == nil {
+		msg := fmt.Sprintf("Cannot find zone with ID %d", params.ID)
+		rsp := services.NewGetZoneDefault(http.StatusNotFound).WithPayload(&models.APIError{
+			Message: &msg,
+		})
+		return rsp
+	}
+	rsp := services.NewGetZoneOK().WithPayload(convertZoneToRestAPI(dbZone))
+	return rsp
+}
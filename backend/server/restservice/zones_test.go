@@ -0,0 +1,160 @@
+package restservice
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	agentcommtest "isc.org/stork/server/agentcomm/test"
+	dbops "isc.org/stork/server/database"
+	dbmodel "isc.org/stork/server/database/model"
+	dbtest "isc.org/stork/server/database/test"
+	"isc.org/stork/server/gen/restapi/operations/search"
+	"isc.org/stork/server/gen/restapi/operations/services"
+	storktest "isc.org/stork/server/test/dbmodel"
+)
+
+// Adds a BIND 9 app with two zones to the database.
+func addTestZones(t *testing.T, db *dbops.PgDB) (*dbmodel.App, []*dbmodel.Bind9Zone) {
+	m := &dbmodel.Machine{
+		Address:   "localhost",
+		AgentPort: 8080,
+	}
+	err := dbmodel.AddMachine(db, m)
+	require.NoError(t, err)
+
+	app := &dbmodel.App{
+		MachineID: m.ID,
+		Type:      dbmodel.AppTypeBind9,
+		Name:      "bind9@localhost",
+		Daemons: []*dbmodel.Daemon{
+			dbmodel.NewBind9Daemon(true),
+		},
+	}
+	_, err = dbmodel.AddApp(db, app)
+	require.NoError(t, err)
+
+	zones := []*dbmodel.Bind9Zone{
+		{
+			View:        "_default",
+			Name:        "example.com",
+			Class:       "IN",
+			ZoneType:    "primary",
+			Serial:      2023031401,
+			LoadedAt:    time.Date(2023, 3, 14, 9, 35, 10, 0, time.UTC),
+			DNSSECState: dbmodel.Bind9ZoneDNSSECInlineSigned,
+		},
+		{
+			View:      "internal",
+			Name:      "example.org",
+			Class:     "IN",
+			ZoneType:  "secondary",
+			Serial:    7,
+			Primaries: []string{"192.0.2.1"},
+		},
+	}
+	err = dbmodel.CommitBind9Zones(db, app.Daemons[0].ID, zones)
+	require.NoError(t, err)
+	return app, zones
+}
+
+// Test getting the list of zones with filtering.
+func TestGetZones(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	settings := RestAPISettings{}
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	fec := &storktest.FakeEventCenter{}
+	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	app, _ := addTestZones(t, db)
+
+	// Get all zones.
+	rsp := rapi.GetZones(ctx, services.GetZonesParams{})
+	require.IsType(t, &services.GetZonesOK{}, rsp)
+	okRsp := rsp.(*services.GetZonesOK)
+	require.EqualValues(t, 2, okRsp.Payload.Total)
+	require.Len(t, okRsp.Payload.Items, 2)
+	zone := okRsp.Payload.Items[0]
+	require.Equal(t, "example.com", zone.Name)
+	require.Equal(t, "_default", zone.View)
+	require.Equal(t, "primary", zone.ZoneType)
+	require.EqualValues(t, 2023031401, zone.Serial)
+	require.Equal(t, dbmodel.Bind9ZoneDNSSECInlineSigned, zone.DnssecState)
+	require.NotNil(t, zone.LoadedAt)
+	require.Nil(t, zone.ExpiresAt)
+	require.Equal(t, app.ID, zone.AppID)
+	require.Equal(t, app.Name, zone.AppName)
+	require.Equal(t, "localhost", zone.MachineAddress)
+
+	// Filter by zone type.
+	zoneType := "secondary"
+	rsp = rapi.GetZones(ctx, services.GetZonesParams{ZoneType: &zoneType})
+	require.IsType(t, &services.GetZonesOK{}, rsp)
+	okRsp = rsp.(*services.GetZonesOK)
+	require.EqualValues(t, 1, okRsp.Payload.Total)
+	require.Equal(t, "example.org", okRsp.Payload.Items[0].Name)
+	require.Equal(t, []string{"192.0.2.1"}, okRsp.Payload.Items[0].Primaries)
+
+	// Filter by non-matching app.
+	appID := app.ID + 1
+	rsp = rapi.GetZones(ctx, services.GetZonesParams{AppID: &appID})
+	require.IsType(t, &services.GetZonesOK{}, rsp)
+	okRsp = rsp.(*services.GetZonesOK)
+	require.Zero(t, okRsp.Payload.Total)
+	require.Empty(t, okRsp.Payload.Items)
+}
+
+// Test getting the zone by ID.
+func TestGetZone(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	settings := RestAPISettings{}
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	fec := &storktest.FakeEventCenter{}
+	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, zones := addTestZones(t, db)
+
+	rsp := rapi.GetZone(ctx, services.GetZoneParams{ID: zones[1].ID})
+	require.IsType(t, &services.GetZoneOK{}, rsp)
+	okRsp := rsp.(*services.GetZoneOK)
+	require.Equal(t, "example.org", okRsp.Payload.Name)
+	require.Equal(t, "internal", okRsp.Payload.View)
+
+	rsp = rapi.GetZone(ctx, services.GetZoneParams{ID: zones[1].ID + 100})
+	require.IsType(t, &services.GetZoneDefault{}, rsp)
+	defaultRsp := rsp.(*services.GetZoneDefault)
+	require.Equal(t, http.StatusNotFound, getStatusCode(*defaultRsp))
+}
+
+// Test that the zones are included in the search results.
+func TestSearchZones(t *testing.T) {
+	db, dbSettings, teardown := dbtest.SetupDatabaseTestCase(t)
+	defer teardown()
+
+	settings := RestAPISettings{}
+	fa := agentcommtest.NewFakeAgents(nil, nil)
+	fec := &storktest.FakeEventCenter{}
+	rapi, err := NewRestAPI(&settings, dbSettings, db, fa, fec)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	addTestZones(t, db)
+
+	text := "example.org"
+	rsp := rapi.SearchRecords(ctx, search.SearchRecordsParams{Text: &text})
+	require.IsType(t, &search.SearchRecordsOK{}, rsp)
+	okRsp := rsp.(*search.SearchRecordsOK)
+	require.NotNil(t, okRsp.Payload.Zones)
+	require.Len(t, okRsp.Payload.Zones.Items, 1)
+	require.Equal(t, "example.org", okRsp.Payload.Zones.Items[0].Name)
+}
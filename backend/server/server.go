@@ -233,6 +233,10 @@ func (ss *StorkServer) Bootstrap(reload bool) (err error) {
 	// Setup configuration review dispatcher.
 	ss.ReviewDispatcher = configreview.NewDispatcher(ss.DB)
 	configreview.RegisterDefaultCheckers(ss.ReviewDispatcher)
+	err = configreview.LoadConfigReviewRules(ss.DB, ss.ReviewDispatcher)
+	if err != nil {
+		return err
+	}
 	err = configreview.LoadAndValidateCheckerPreferences(ss.DB, ss.ReviewDispatcher)
 	if err != nil {
 		return err
@@ -263,8 +267,14 @@ func (ss *StorkServer) Bootstrap(reload bool) (err error) {
 		return err
 	}
 
+	// setup bind9 zones puller
+	ss.Pullers.Bind9ZonesPuller, err = bind9.NewZonesPuller(ss.DB, ss.Agents)
+	if err != nil {
+		return err
+	}
+
 	// setup kea stats puller
-	ss.Pullers.KeaStatsPuller, err = kea.NewStatsPuller(ss.DB, ss.Agents)
+	ss.Pullers.KeaStatsPuller, err = kea.NewStatsPuller(ss.DB, ss.Agents, ss.EventCenter)
 	if err != nil {
 		return err
 	}
@@ -281,6 +291,12 @@ func (ss *StorkServer) Bootstrap(reload bool) (err error) {
 		return err
 	}
 
+	// Setup Kea log events puller.
+	ss.Pullers.KeaLogEventsPuller, err = kea.NewLogEventsPuller(ss.DB, ss.Agents, ss.EventCenter)
+	if err != nil {
+		return err
+	}
+
 	if ss.GeneralSettings.EnableMetricsEndpoint {
 		ss.MetricsCollector, err = metrics.NewCollector(ss.DB)
 		if err != nil {
@@ -298,6 +314,7 @@ func (ss *StorkServer) Bootstrap(reload bool) (err error) {
 	// on the option definitions it returns. Indexing should be done only once at
 	// server startup.
 	ss.ConfigManager = apps.NewManager(ss)
+	ss.ConfigManager.Start()
 
 	// setup ReST API service
 	r, err := restservice.NewRestAPI(&ss.RestAPISettings, &ss.DBSettings,
@@ -305,9 +322,12 @@ func (ss *StorkServer) Bootstrap(reload bool) (err error) {
 		ss.Pullers, ss.ReviewDispatcher, ss.MetricsCollector, ss.ConfigManager,
 		ss.DHCPOptionDefinitionLookup, ss.HookManager)
 	if err != nil {
+		ss.ConfigManager.Shutdown()
+		ss.Pullers.KeaLogEventsPuller.Shutdown()
 		ss.Pullers.HAStatusPuller.Shutdown()
 		ss.Pullers.KeaHostsPuller.Shutdown()
 		ss.Pullers.KeaStatsPuller.Shutdown()
+		ss.Pullers.Bind9ZonesPuller.Shutdown()
 		ss.Pullers.Bind9StatsPuller.Shutdown()
 		ss.Pullers.AppsStatePuller.Shutdown()
 		if ss.MetricsCollector != nil {
@@ -353,9 +373,12 @@ func (ss *StorkServer) Shutdown(reload bool) {
 			log.Println("Shutting down Stork Server")
 		}
 		ss.RestAPI.Shutdown()
+		ss.ConfigManager.Shutdown()
+		ss.Pullers.KeaLogEventsPuller.Shutdown()
 		ss.Pullers.HAStatusPuller.Shutdown()
 		ss.Pullers.KeaHostsPuller.Shutdown()
 		ss.Pullers.KeaStatsPuller.Shutdown()
+		ss.Pullers.Bind9ZonesPuller.Shutdown()
 		ss.Pullers.Bind9StatsPuller.Shutdown()
 		ss.Pullers.AppsStatePuller.Shutdown()
 		ss.Agents.Shutdown()
@@ -388,3 +411,9 @@ func (ss *StorkServer) GetConnectedAgents() agentcomm.ConnectedAgents {
 func (ss *StorkServer) GetDHCPOptionDefinitionLookup() keaconfig.DHCPOptionDefinitionLookup {
 	return ss.DHCPOptionDefinitionLookup
 }
+
+// Returns an interface to the event center used to record the configuration
+// changes.
+func (ss *StorkServer) GetEventCenter() eventcenter.EventCenter {
+	return ss.EventCenter
+}
@@ -1,7 +1,12 @@
 package storkutil
 
 import (
+	"bytes"
 	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -31,3 +36,229 @@ func ExtractJSONInt64(container map[string]interface{}, key string) (int64, erro
 	}
 	return 0, errors.Errorf("value not found in the container for key %s", key)
 }
+
+// Describes a single difference between two JSON documents. The path
+// points to the differing value using the JSON pointer notation (RFC 6901).
+// The Before value is nil when the value has been added. The After value
+// is nil when the value has been removed.
+type JSONDifference struct {
+	Path   string `json:"path"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// Compares two values after converting them to the generic JSON form and
+// returns the differences between them. The maps are compared key by key.
+// The lists and scalar values are compared as a whole. The differences are
+// sorted by path.
+func CompareJSON(before, after any) ([]JSONDifference, error) {
+	normalizedBefore, err := normalizeJSON(before)
+	if err != nil {
+		return nil, err
+	}
+	normalizedAfter, err := normalizeJSON(after)
+	if err != nil {
+		return nil, err
+	}
+	differences := []JSONDifference{}
+	compareJSONValues("", normalizedBefore, normalizedAfter, &differences)
+	sort.Slice(differences, func(i, j int) bool {
+		return differences[i].Path < differences[j].Path
+	})
+	return differences, nil
+}
+
+// Converts the value to the generic JSON form, i.e., the maps, lists and
+// scalar values returned by the JSON decoder.
+func normalizeJSON(value any) (normalized any, err error) {
+	if value == nil {
+		return nil, nil
+	}
+	marshalled, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem marshalling value to JSON")
+	}
+	if err = json.Unmarshal(marshalled, &normalized); err != nil {
+		return nil, errors.Wrap(err, "problem unmarshalling JSON value")
+	}
+	return normalized, nil
+}
+
+// Recursively compares the normalized JSON values and appends the found
+// differences to the specified slice.
+func compareJSONValues(path string, before, after any, differences *[]JSONDifference) {
+	beforeMap, beforeIsMap := before.(map[string]any)
+	afterMap, afterIsMap := after.(map[string]any)
+	if beforeIsMap && afterIsMap {
+		for key, beforeValue := range beforeMap {
+			compareJSONValues(appendJSONPointer(path, key), beforeValue, afterMap[key], differences)
+		}
+		for key, afterValue := range afterMap {
+			if _, ok := beforeMap[key]; !ok {
+				compareJSONValues(appendJSONPointer(path, key), nil, afterValue, differences)
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(before, after) {
+		if path == "" {
+			path = "/"
+		}
+		*differences = append(*differences, JSONDifference{
+			Path:   path,
+			Before: before,
+			After:  after,
+		})
+	}
+}
+
+// Appends the escaped key to the JSON pointer.
+func appendJSONPointer(path, key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	key = strings.ReplaceAll(key, "/", "~1")
+	return path + "/" + key
+}
+
+// Describes a single operation modifying a JSON document. The operations
+// are a subset of the JSON patch (RFC 6902): add, remove and replace. The
+// path points to the modified value using the JSON pointer notation
+// (RFC 6901). The "-" path segment appends the value to a list.
+type JSONPatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+}
+
+// Applies the patch operations to the JSON document and returns the
+// modified document. The operations are applied in order. The function
+// returns an error if any of the operations can't be applied, e.g., the
+// value it removes does not exist.
+func ApplyJSONPatch(document []byte, operations []JSONPatchOperation) ([]byte, error) {
+	root, err := decodeJSONWithNumbers(document)
+	if err != nil {
+		return nil, errors.WithMessage(err, "problem parsing JSON document to patch")
+	}
+	for _, operation := range operations {
+		var value any
+		if operation.Value != nil {
+			marshalled, err := json.Marshal(operation.Value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "problem marshalling value of %s operation", operation.Op)
+			}
+			if value, err = decodeJSONWithNumbers(marshalled); err != nil {
+				return nil, err
+			}
+		}
+		root, err = applyJSONPatchOperation(root, operation.Op, parseJSONPointer(operation.Path), value)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "problem applying %s operation to %s", operation.Op, operation.Path)
+		}
+	}
+	patched, err := json.Marshal(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem marshalling patched JSON document")
+	}
+	return patched, nil
+}
+
+// Decodes the JSON document preserving the numbers as json.Number, so
+// the large integers are not converted to floats.
+func decodeJSONWithNumbers(document []byte) (decoded any, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	if err = decoder.Decode(&decoded); err != nil {
+		err = errors.Wrap(err, "problem unmarshalling JSON value")
+	}
+	return
+}
+
+// Splits the JSON pointer into the unescaped reference tokens.
+func parseJSONPointer(path string) []string {
+	if path == "" || path == "/" {
+		return nil
+	}
+	tokens := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i := range tokens {
+		tokens[i] = strings.ReplaceAll(tokens[i], "~1", "/")
+		tokens[i] = strings.ReplaceAll(tokens[i], "~0", "~")
+	}
+	return tokens
+}
+
+// Recursively applies a single patch operation to the value at the path
+// and returns the modified value.
+func applyJSONPatchOperation(node any, op string, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		switch op {
+		case "add", "replace":
+			return value, nil
+		default:
+			return nil, errors.Errorf("unsupported operation on the document root")
+		}
+	}
+	token := path[0]
+	last := len(path) == 1
+	switch typed := node.(type) {
+	case map[string]any:
+		child, exists := typed[token]
+		if !last {
+			if !exists {
+				return nil, errors.Errorf("missing key %s", token)
+			}
+			modified, err := applyJSONPatchOperation(child, op, path[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			typed[token] = modified
+			return typed, nil
+		}
+		switch op {
+		case "add":
+			typed[token] = value
+		case "replace":
+			if !exists {
+				return nil, errors.Errorf("missing key %s", token)
+			}
+			typed[token] = value
+		case "remove":
+			if !exists {
+				return nil, errors.Errorf("missing key %s", token)
+			}
+			delete(typed, token)
+		default:
+			return nil, errors.Errorf("unsupported operation %s", op)
+		}
+		return typed, nil
+	case []any:
+		if last && op == "add" && token == "-" {
+			return append(typed, value), nil
+		}
+		index, err := strconv.Atoi(token)
+		if err != nil || index < 0 || index > len(typed) || (index == len(typed) && (!last || op != "add")) {
+			return nil, errors.Errorf("invalid list index %s", token)
+		}
+		if !last {
+			modified, err := applyJSONPatchOperation(typed[index], op, path[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			typed[index] = modified
+			return typed, nil
+		}
+		switch op {
+		case "add":
+			typed = append(typed, nil)
+			copy(typed[index+1:], typed[index:])
+			typed[index] = value
+		case "replace":
+			typed[index] = value
+		case "remove":
+			typed = append(typed[:index], typed[index+1:]...)
+		default:
+			return nil, errors.Errorf("unsupported operation %s", op)
+		}
+		return typed, nil
+	default:
+		return nil, errors.Errorf("cannot reference %s in a value that is not a map or a list", token)
+	}
+}
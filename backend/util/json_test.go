@@ -52,3 +52,127 @@ func TestExtractJSONInt64(t *testing.T) {
 	require.Error(t, err)
 	require.Zero(t, v)
 }
+
+// Test comparing JSON documents.
+func TestCompareJSON(t *testing.T) {
+	before := map[string]any{
+		"valid-lifetime": 3600,
+		"renew-timer":    900,
+		"option-data": []any{
+			map[string]any{"code": 6, "data": "192.0.2.1"},
+		},
+		"nested": map[string]any{
+			"a/b": "foo",
+			"c":   "bar",
+		},
+	}
+	after := map[string]any{
+		"valid-lifetime": 3600,
+		"rebind-timer":   1800,
+		"option-data": []any{
+			map[string]any{"code": 6, "data": "192.0.2.2"},
+		},
+		"nested": map[string]any{
+			"a/b": "baz",
+			"c":   "bar",
+		},
+	}
+	differences, err := CompareJSON(before, after)
+	require.NoError(t, err)
+	require.Len(t, differences, 4)
+
+	require.Equal(t, "/nested/a~1b", differences[0].Path)
+	require.Equal(t, "foo", differences[0].Before)
+	require.Equal(t, "baz", differences[0].After)
+
+	require.Equal(t, "/option-data", differences[1].Path)
+
+	require.Equal(t, "/rebind-timer", differences[2].Path)
+	require.Nil(t, differences[2].Before)
+	require.EqualValues(t, 1800, differences[2].After)
+
+	require.Equal(t, "/renew-timer", differences[3].Path)
+	require.EqualValues(t, 900, differences[3].Before)
+	require.Nil(t, differences[3].After)
+}
+
+// Test comparing the structures and the nil values.
+func TestCompareJSONStructs(t *testing.T) {
+	type foo struct {
+		Name  string `json:"name"`
+		Value int    `json:"value"`
+	}
+	differences, err := CompareJSON(foo{Name: "a", Value: 1}, foo{Name: "a", Value: 1})
+	require.NoError(t, err)
+	require.Empty(t, differences)
+
+	differences, err = CompareJSON(nil, foo{Name: "a"})
+	require.NoError(t, err)
+	require.Len(t, differences, 1)
+	require.Equal(t, "/", differences[0].Path)
+	require.Nil(t, differences[0].Before)
+	require.NotNil(t, differences[0].After)
+
+	_, err = CompareJSON(make(chan int), nil)
+	require.Error(t, err)
+}
+
+// Test that the JSON patch operations are applied to the document.
+func TestApplyJSONPatch(t *testing.T) {
+	document := []byte(`{
+		"Dhcp4": {
+			"id": 18446744073709551615,
+			"subnet4": [ { "id": 1 }, { "id": 2 }, { "id": 3 } ],
+			"hooks-libraries": [],
+			"a/b": { "c~d": "foo" }
+		}
+	}`)
+	patched, err := ApplyJSONPatch(document, []JSONPatchOperation{
+		{Op: "remove", Path: "/Dhcp4/subnet4/1"},
+		{Op: "add", Path: "/Dhcp4/subnet4/0", Value: map[string]any{"id": 4}},
+		{Op: "add", Path: "/Dhcp4/subnet4/-", Value: map[string]any{"id": 5}},
+		{Op: "replace", Path: "/Dhcp4/subnet4/1/id", Value: 6},
+		{Op: "add", Path: "/Dhcp4/hooks-libraries/0", Value: map[string]any{"library": "libdhcp_stat_cmds.so"}},
+		{Op: "replace", Path: "/Dhcp4/a~1b/c~0d", Value: "bar"},
+		{Op: "add", Path: "/Dhcp4/valid-lifetime", Value: 3600},
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"Dhcp4": {
+			"id": 18446744073709551615,
+			"subnet4": [ { "id": 4 }, { "id": 6 }, { "id": 3 }, { "id": 5 } ],
+			"hooks-libraries": [ { "library": "libdhcp_stat_cmds.so" } ],
+			"a/b": { "c~d": "bar" },
+			"valid-lifetime": 3600
+		}
+	}`, string(patched))
+	require.Contains(t, string(patched), "18446744073709551615")
+}
+
+// Test that the JSON patch is rejected when any of its operations can't
+// be applied.
+func TestApplyJSONPatchError(t *testing.T) {
+	document := []byte(`{ "Dhcp4": { "subnet4": [ { "id": 1 } ], "valid-lifetime": 3600 } }`)
+
+	testCases := []JSONPatchOperation{
+		{Op: "remove", Path: "/Dhcp4/missing"},
+		{Op: "replace", Path: "/Dhcp4/missing", Value: 1},
+		{Op: "add", Path: "/Dhcp6/valid-lifetime", Value: 1},
+		{Op: "remove", Path: "/Dhcp4/subnet4/1"},
+		{Op: "remove", Path: "/Dhcp4/subnet4/-"},
+		{Op: "replace", Path: "/Dhcp4/subnet4/a", Value: 1},
+		{Op: "add", Path: "/Dhcp4/valid-lifetime/a", Value: 1},
+		{Op: "move", Path: "/Dhcp4/valid-lifetime"},
+		{Op: "remove", Path: ""},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Op+" "+tc.Path, func(t *testing.T) {
+			_, err := ApplyJSONPatch(document, []JSONPatchOperation{tc})
+			require.Error(t, err)
+		})
+	}
+
+	_, err := ApplyJSONPatch([]byte("{"), nil)
+	require.Error(t, err)
+}
@@ -147,6 +147,8 @@ export class ConfigCheckerPreferencePickerComponent {
                 return 'fa fa-dice-two'
             case 'bind9-daemon':
                 return 'fa fa-dot-circle'
+            case 'kea-ha-service':
+                return 'fa fa-link'
             default:
                 return null
         }
@@ -221,6 +223,28 @@ export class ConfigCheckerPreferencePickerComponent {
                 )
             case 'ca_control_sockets':
                 return 'The checker verifying if the Kea Control Agent configuration includes the control sockets.'
+            case 'ha_peers_consistency':
+                return (
+                    'The checker verifying if the HA peers use the same HA ' +
+                    'mode and the same names, URLs and roles of the peers.'
+                )
+            case 'ha_subnets_consistency':
+                return 'The checker verifying if the HA peers have the same subnets with the same IDs.'
+            case 'ha_pools_consistency':
+                return 'The checker verifying if the HA peers have the same pools in their subnets.'
+            case 'ha_reservations_consistency':
+                return (
+                    'The checker verifying if the HA peers have the same host ' +
+                    'reservations specified in their configurations.'
+                )
+            case 'ha_options_consistency':
+                return 'The checker verifying if the HA peers have the same DHCP options.'
+            case 'ha_client_classes_consistency':
+                return 'The checker verifying if the HA peers have the same client classes.'
+            case 'ha_lease_lifetimes_consistency':
+                return 'The checker verifying if the HA peers have the same valid and preferred lease lifetimes.'
+            case 'ha_hook_libraries_consistency':
+                return 'The checker verifying if the HA peers load the same hook libraries.'
             default:
                 return ''
         }
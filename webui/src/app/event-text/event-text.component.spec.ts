@@ -21,4 +21,12 @@ describe('EventTextComponent', () => {
     it('should create', () => {
         expect(component).toBeTruthy()
     })
+
+    it('should parse shared network tags', () => {
+        component.text = 'Addresses in shared network <shared-network id="2" name="frog"> are exhausted'
+        expect(component.textParts.length).toBe(3)
+        expect(component.textParts[0]).toEqual(['text', 'Addresses in shared network '])
+        expect(component.textParts[1]).toEqual(['shared-network', { id: '2', name: 'frog' }])
+        expect(component.textParts[2]).toEqual(['text', ' are exhausted'])
+    })
 })
@@ -28,8 +28,8 @@ export class EventTextComponent {
      * Store in textParts list of slices of the text and entity elements.
      */
     parseText() {
-        // match e.g. <daemon id="123" name="dhcp4">
-        const reEntity = /<(\w+) +((?:\w+="[^"]*" *){1,})>/g
+        // match e.g. <daemon id="123" name="dhcp4"> or <shared-network id="1" name="frog">
+        const reEntity = /<([\w-]+) +((?:\w+="[^"]*" *){1,})>/g
         // match e.g. name="dhcp4"
         const reAttrs = /(\w+)="([^"]+)"/g
         const matches = this._text.matchAll(reEntity)
@@ -130,4 +130,56 @@ describe('HaStatusComponent', () => {
             'High Availability is not enabled on this server.'
         )
     }))
+
+    it('should collect the statuses of all relationships of the daemon', fakeAsync(() => {
+        // Mock the API response with the statuses of the hub server belonging
+        // to two HA relationships.
+        spyOn(servicesApi, 'getAppServicesStatus').and.returnValue(
+            of({
+                items: [
+                    {
+                        status: {
+                            serviceId: 1,
+                            daemon: 'dhcp4',
+                            relationship: 'branch1,hub',
+                            haServers: {
+                                primaryServer: { appId: 1, scopes: ['branch1'], age: 0 },
+                                secondaryServer: { appId: 4, scopes: [], age: 0 },
+                            },
+                        },
+                    },
+                    {
+                        status: {
+                            serviceId: 2,
+                            daemon: 'dhcp4',
+                            relationship: 'branch2,hub',
+                            haServers: {
+                                primaryServer: { appId: 2, scopes: [], age: -1 },
+                                secondaryServer: { appId: 4, scopes: ['branch2'], age: 0 },
+                            },
+                        },
+                    },
+                ],
+            } as ServicesStatus & HttpEvent<ServicesStatus>)
+        )
+        component.daemonName = 'dhcp4'
+
+        // Execute ngOnInit hook.
+        fixture.detectChanges()
+        // Break the interval tasks manually. Otherwise, Jasmine crashes.
+        discardPeriodicTasks()
+
+        // Continue the API response processing.
+        tick()
+
+        expect(component.hasStatus()).toBeTrue()
+        const statuses = component.statuses()
+        expect(statuses.length).toBe(2)
+        expect(statuses[0].relationship).toBe('branch1,hub')
+        expect(component.localServer(statuses[0].haServers).appId).toBe(4)
+        expect(component.remoteServer(statuses[0].haServers).appId).toBe(1)
+        expect(component.footerInfo(statuses[0].haServers)).toBe('The remote server is responding to all DHCP traffic.')
+        expect(component.remoteServer(statuses[1].haServers).appId).toBe(2)
+        expect(component.footerInfo(statuses[1].haServers)).toBe('The local server is responding to all DHCP traffic.')
+    }))
 })
@@ -1,7 +1,7 @@
 import { Component, Input, OnDestroy, OnInit } from '@angular/core'
 import { interval, Subscription } from 'rxjs'
 import { ServicesService } from '../backend/api/api'
-import { KeaStatusHaServers } from '../backend'
+import { KeaStatus, KeaStatusHaServers } from '../backend'
 import { MessageService } from 'primeng/api'
 import { getErrorMessage } from '../utils'
 
@@ -22,7 +22,7 @@ export class HaStatusComponent implements OnInit, OnDestroy {
 
     private _appId: number
     private _daemonName: string
-    private _receivedStatus: Record<string, KeaStatusHaServers>
+    private _receivedStatus: Record<string, KeaStatus[]>
 
     /**
      * Indicates if the data were loaded at least once.
@@ -53,16 +53,16 @@ export class HaStatusComponent implements OnInit, OnDestroy {
         // ToDo: Check that it works as expected. Does the timer reset when the component is destroyed?
         this.subscriptions.add(
             interval(this._countUpInterval).subscribe((x) => {
-                if (this.hasStatus()) {
+                for (const status of this.statuses()) {
                     // Only increase the age counters if they are non-negative.
                     // Negative values indicate that the status age was unknown,
                     // probably because the server was down when attempted to get
                     // its status.
-                    if (this.localServer().age >= 0) {
-                        this.localServer().age += 1
+                    if (this.localServer(status.haServers).age >= 0) {
+                        this.localServer(status.haServers).age += 1
                     }
-                    if (this.remoteServer() && this.remoteServer().age >= 0) {
-                        this.remoteServer().age += 1
+                    if (this.remoteServer(status.haServers) && this.remoteServer(status.haServers).age >= 0) {
+                        this.remoteServer(status.haServers).age += 1
                     }
                 }
             })
@@ -107,27 +107,45 @@ export class HaStatusComponent implements OnInit, OnDestroy {
      * @returns true if the status has been fetched and is available for display.
      */
     hasStatus(): boolean {
-        return !!this._receivedStatus?.[this._daemonName]
+        return this.statuses().length > 0
+    }
+
+    /**
+     * Returns the statuses of the HA relationships the current daemon
+     * belongs to.
+     *
+     * A server may belong to multiple relationships, e.g., the hub server
+     * in the hub-and-spoke configuration.
+     *
+     * @returns array of the relationship statuses; empty if no status
+     *          was fetched.
+     */
+    statuses(): KeaStatus[] {
+        return this._receivedStatus?.[this._daemonName] ?? []
     }
 
     /**
      * Convenience function returning received status of the local server.
+     *
+     * @param haServers status of the servers in the HA relationship.
      */
-    localServer() {
-        if (this._receivedStatus[this._daemonName].primaryServer.appId === this.appId) {
-            return this._receivedStatus[this._daemonName].primaryServer
+    localServer(haServers: KeaStatusHaServers) {
+        if (haServers.primaryServer.appId === this.appId) {
+            return haServers.primaryServer
         }
-        return this._receivedStatus[this._daemonName].secondaryServer
+        return haServers.secondaryServer
     }
 
     /**
      * Convenience function returning received status of the remote server.
+     *
+     * @param haServers status of the servers in the HA relationship.
      */
-    remoteServer() {
-        if (this._receivedStatus[this._daemonName].primaryServer.appId !== this.appId) {
-            return this._receivedStatus[this._daemonName].primaryServer
+    remoteServer(haServers: KeaStatusHaServers) {
+        if (haServers.primaryServer.appId !== this.appId) {
+            return haServers.primaryServer
         }
-        return this._receivedStatus[this._daemonName].secondaryServer
+        return haServers.secondaryServer
     }
 
     /**
@@ -144,7 +162,10 @@ export class HaStatusComponent implements OnInit, OnDestroy {
                     this._receivedStatus = {}
                     for (const s of data.items) {
                         if (s.status.haServers && s.status.daemon) {
-                            this._receivedStatus[s.status.daemon] = s.status.haServers
+                            if (!this._receivedStatus[s.status.daemon]) {
+                                this._receivedStatus[s.status.daemon] = []
+                            }
+                            this._receivedStatus[s.status.daemon].push(s.status)
                         }
                     }
                 }
@@ -163,12 +184,13 @@ export class HaStatusComponent implements OnInit, OnDestroy {
     /**
      * Returns an array of scopes served by the local server.
      *
+     * @param haServers status of the servers in the HA relationship.
      * @returns array of strings including local server scopes.
      */
-    private localServerScopes(): string[] {
+    private localServerScopes(haServers: KeaStatusHaServers): string[] {
         let scopes: string[] = []
-        if (this.hasStatus() && !!this.localServer().scopes) {
-            scopes = this.localServer().scopes
+        if (!!this.localServer(haServers).scopes) {
+            scopes = this.localServer(haServers).scopes
         }
         return scopes
     }
@@ -176,12 +198,13 @@ export class HaStatusComponent implements OnInit, OnDestroy {
     /**
      * Returns an array of scopes served by the remote server.
      *
+     * @param haServers status of the servers in the HA relationship.
      * @returns array of strings including remote server scopes.
      */
-    private remoteServerScopes(): string[] {
+    private remoteServerScopes(haServers: KeaStatusHaServers): string[] {
         let scopes: string[] = []
-        if (this.hasStatus() && this.remoteServer().scopes) {
-            scopes = this.remoteServer().scopes
+        if (this.remoteServer(haServers)?.scopes) {
+            scopes = this.remoteServer(haServers).scopes
         }
         return scopes
     }
@@ -223,14 +246,16 @@ export class HaStatusComponent implements OnInit, OnDestroy {
      * HA partners. Depending on the state, the HA servers become responsible
      * for different scopes. The text displayed in the note box explains which
      * groups of clients are served by which DHCP servers.
+     *
+     * @param haServers status of the servers in the HA relationship.
      */
-    footerInfo(): string {
-        if (!this.hasStatus()) {
+    footerInfo(haServers: KeaStatusHaServers): string {
+        if (!haServers) {
             return 'No HA information available!'
         }
 
-        const localFailoverProgress = this.serverFailoverProgress(this.localServer())
-        const remoteFailoverProgress = this.serverFailoverProgress(this.remoteServer())
+        const localFailoverProgress = this.serverFailoverProgress(this.localServer(haServers))
+        const remoteFailoverProgress = this.serverFailoverProgress(this.remoteServer(haServers))
 
         if (localFailoverProgress >= 0 && remoteFailoverProgress >= 0) {
             return 'Each server failed to see the other and started failover procedure:'
@@ -250,25 +275,25 @@ export class HaStatusComponent implements OnInit, OnDestroy {
 
         // The local server serves no clients, so the remote serves all of them.
         // It may be a hot-standby case or partner-down case.
-        if (this.localServerScopes().length === 0 && this.remoteServerScopes().length > 0) {
+        if (this.localServerScopes(haServers).length === 0 && this.remoteServerScopes(haServers).length > 0) {
             return 'The remote server is responding to all DHCP traffic.'
         }
 
         // The remote server serves no clients, so the local serves all of them.
         // It may be a hot-standby case or partner-down case.
-        if (this.remoteServerScopes().length === 0 && this.localServerScopes().length > 0) {
+        if (this.remoteServerScopes(haServers).length === 0 && this.localServerScopes(haServers).length > 0) {
             return 'The local server is responding to all DHCP traffic.'
         }
 
         // This is the load-balancing case when both servers respond to some
         // DHCP traffic.
-        if (this.remoteServerScopes().length > 0 && this.localServerScopes().length > 0) {
+        if (this.remoteServerScopes(haServers).length > 0 && this.localServerScopes(haServers).length > 0) {
             return 'Both servers are responding to DHCP traffic.'
         }
 
         // If the HA service is being started, the servers synchronize their
         // databases and do not respond to any traffic.
-        if (this.remoteServerScopes().length === 0 && this.localServerScopes().length === 0) {
+        if (this.remoteServerScopes(haServers).length === 0 && this.localServerScopes(haServers).length === 0) {
             return 'No servers are responding to DHCP traffic.'
         }
     }
@@ -12,6 +12,9 @@ import { ProgressSpinnerModule } from 'primeng/progressspinner'
 import { SharedModule } from 'primeng/api'
 import { EntityLinkComponent } from '../entity-link/entity-link.component'
 import { RouterTestingModule } from '@angular/router/testing'
+import { FormsModule } from '@angular/forms'
+import { InputTextModule } from 'primeng/inputtext'
+import { TooltipModule } from 'primeng/tooltip'
 
 describe('LogViewPageComponent', () => {
     let component: LogViewPageComponent
@@ -37,6 +40,9 @@ describe('LogViewPageComponent', () => {
                 SharedModule,
                 RouterModule,
                 RouterTestingModule,
+                FormsModule,
+                InputTextModule,
+                TooltipModule,
             ],
             declarations: [LogViewPageComponent, EntityLinkComponent],
         }).compileComponents()
@@ -63,4 +69,41 @@ describe('LogViewPageComponent', () => {
         expect(appLinkComponent.attrs.hasOwnProperty('name')).toBeTrue()
         expect(appLinkComponent.attrs.name).toEqual('fantastic-app')
     })
+
+    it('should follow the log', () => {
+        const listeners = {}
+        const fakeEventSource = jasmine.createSpyObj('EventSource', ['addEventListener', 'close'])
+        fakeEventSource.addEventListener.and.callFake((name, listener) => {
+            listeners[name] = listener
+        })
+        const eventSourceSpy = spyOn(window, 'EventSource').and.returnValue(fakeEventSource)
+
+        component.filter = 'ERROR'
+        component.toggleFollow()
+        expect(component.following).toBeTrue()
+        expect(eventSourceSpy).toHaveBeenCalledOnceWith(
+            jasmine.stringMatching(/stream\?follow=true&maxLength=4000&filter=ERROR$/)
+        )
+
+        listeners['message']({ data: JSON.stringify({ lines: ['foo', 'bar'], rotated: false }) })
+        listeners['message']({ data: JSON.stringify({ lines: ['baz'], rotated: false }) })
+        expect(component.contents).toEqual(['foo', 'bar', 'baz'])
+
+        // The server ends the stream when the agent fails.
+        listeners['end']({ data: JSON.stringify({ error: 'access forbidden' }) })
+        expect(component.following).toBeFalse()
+        expect(component.loadingError).toBe('access forbidden')
+        expect(fakeEventSource.close).toHaveBeenCalled()
+    })
+
+    it('should stop following the log', () => {
+        const fakeEventSource = jasmine.createSpyObj('EventSource', ['addEventListener', 'close'])
+        spyOn(window, 'EventSource').and.returnValue(fakeEventSource)
+
+        component.toggleFollow()
+        expect(component.following).toBeTrue()
+        component.toggleFollow()
+        expect(component.following).toBeFalse()
+        expect(fakeEventSource.close).toHaveBeenCalled()
+    })
 })
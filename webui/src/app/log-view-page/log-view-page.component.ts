@@ -1,4 +1,4 @@
-import { Component, OnInit } from '@angular/core'
+import { Component, OnDestroy, OnInit } from '@angular/core'
 import { ActivatedRoute } from '@angular/router'
 import { Message } from 'primeng/api'
 import { ServicesService } from '../backend/api/api'
@@ -11,16 +11,17 @@ import { getErrorMessage } from '../utils'
  * ID. The tail of the returned log is shown in the text box. The
  * severities of the log messages are highlighted for each message.
  *
- * Currently, the log viewer is not following the changes in the file.
- * However, a refresh button is provided which sends a request to
- * get the updated log tail.
+ * A refresh button is provided which sends a request to get the updated
+ * log tail. The follow button opens the stream of server-sent events
+ * carrying the lines appended to the log file, optionally selected with
+ * the regular expression.
  */
 @Component({
     selector: 'app-log-view-page',
     templateUrl: './log-view-page.component.html',
     styleUrls: ['./log-view-page.component.sass'],
 })
-export class LogViewPageComponent implements OnInit {
+export class LogViewPageComponent implements OnInit, OnDestroy {
     maxLengthChunk = 4000
     maxLength = this.maxLengthChunk
 
@@ -40,6 +41,29 @@ export class LogViewPageComponent implements OnInit {
     loaded = false
     loadingError = null
 
+    /**
+     * Indicates if the viewer follows the log file, i.e., presents the
+     * lines as they are appended to the file.
+     */
+    following = false
+
+    /**
+     * Regular expression selecting the lines presented when following
+     * the log file.
+     */
+    filter = ''
+
+    /**
+     * Maximum number of the lines presented when following the log file.
+     * The oldest lines are discarded.
+     */
+    maxFollowedLines = 10000
+
+    /**
+     * Source of the server-sent events carrying the followed log lines.
+     */
+    private eventSource: EventSource = null
+
     /**
      * Constructor
      *
@@ -61,6 +85,13 @@ export class LogViewPageComponent implements OnInit {
         })
     }
 
+    /**
+     * Stops following the log file when the component is destroyed.
+     */
+    ngOnDestroy(): void {
+        this.stopFollowing()
+    }
+
     /**
      * Sends the request to the server to fetch the tail of the log file
      *
@@ -71,6 +102,7 @@ export class LogViewPageComponent implements OnInit {
      * disable the spinner.
      */
     private fetchLogTail() {
+        this.stopFollowing()
         this.loaded = false
         this.servicesApi.getLogTail(this._logId, this.maxLength).subscribe(
             (data) => {
@@ -146,6 +178,74 @@ export class LogViewPageComponent implements OnInit {
         }
     }
 
+    /**
+     * Starts or stops following the log file.
+     *
+     * This action is triggered when the follow button is clicked.
+     */
+    toggleFollow() {
+        if (this.following) {
+            this.stopFollowing()
+        } else {
+            this.startFollowing()
+        }
+    }
+
+    /**
+     * Opens the stream of the log lines.
+     *
+     * The stream starts with the tail of the log having the currently
+     * presented size. The browser reconnects when the server closes the
+     * stream and the server resumes it where it was interrupted. The
+     * server ends the stream with the end event when the agent fails.
+     */
+    startFollowing() {
+        this.stopFollowing()
+
+        const searchParams = new URLSearchParams()
+        searchParams.append('follow', 'true')
+        searchParams.append('maxLength', String(this.maxLength))
+        if (this.filter) {
+            searchParams.append('filter', this.filter)
+        }
+        this.contents = []
+        this.loadingError = null
+        this.following = true
+        this.eventSource = new EventSource(`/api/logs/${this._logId}/stream?` + searchParams.toString())
+
+        this.eventSource.addEventListener('message', (ev: MessageEvent) => {
+            const data = JSON.parse(ev.data)
+            this.contents = this.contents.concat(data.lines).slice(-this.maxFollowedLines)
+        })
+
+        this.eventSource.addEventListener('end', (ev: MessageEvent) => {
+            const data = JSON.parse(ev.data)
+            if (data.error) {
+                this.loadingError = data.error
+            }
+            this.stopFollowing()
+        })
+
+        this.eventSource.addEventListener('error', () => {
+            // The browser reconnects unless the server has refused the stream.
+            if (this.eventSource?.readyState === EventSource.CLOSED) {
+                this.loadingError = 'Failed to follow the log file.'
+                this.stopFollowing()
+            }
+        })
+    }
+
+    /**
+     * Closes the stream of the log lines.
+     */
+    stopFollowing() {
+        if (this.eventSource) {
+            this.eventSource.close()
+            this.eventSource = null
+        }
+        this.following = false
+    }
+
     /**
      * Parses a single line of the log
      *
@@ -19,11 +19,19 @@ export class SettingsPageComponent implements OnInit {
     constructor(private fb: UntypedFormBuilder, private settingsApi: SettingsService, private msgSrv: MessageService) {
         this.settingsForm = this.fb.group({
             bind9_stats_puller_interval: ['', [Validators.required, Validators.min(0)]],
+            bind9_zones_puller_interval: ['', [Validators.required, Validators.min(0)]],
             grafana_url: [''],
             kea_hosts_puller_interval: ['', [Validators.required, Validators.min(0)]],
             kea_stats_puller_interval: ['', [Validators.required, Validators.min(0)]],
             kea_status_puller_interval: ['', [Validators.required, Validators.min(0)]],
+            kea_log_events_puller_interval: ['', [Validators.required, Validators.min(0)]],
             prometheus_url: [''],
+            kea_two_phase_commit: [false],
+            utilization_raw_retention: ['', [Validators.required, Validators.min(1)]],
+            utilization_hourly_retention: ['', [Validators.required, Validators.min(1)]],
+            utilization_daily_retention: ['', [Validators.required, Validators.min(1)]],
+            utilization_forecast_window: ['', [Validators.required, Validators.min(1)]],
+            utilization_forecast_horizon: ['', [Validators.required, Validators.min(0)]],
         })
     }
 
@@ -32,9 +40,16 @@ export class SettingsPageComponent implements OnInit {
             (data) => {
                 const numericSettings = [
                     'bind9_stats_puller_interval',
+                    'bind9_zones_puller_interval',
                     'kea_hosts_puller_interval',
                     'kea_stats_puller_interval',
                     'kea_status_puller_interval',
+                    'kea_log_events_puller_interval',
+                    'utilization_raw_retention',
+                    'utilization_hourly_retention',
+                    'utilization_daily_retention',
+                    'utilization_forecast_window',
+                    'utilization_forecast_horizon',
                 ]
                 const stringSettings = ['grafana_url', 'prometheus_url']
 
@@ -48,6 +63,9 @@ export class SettingsPageComponent implements OnInit {
                         data[s] = ''
                     }
                 }
+                if (data.kea_two_phase_commit === undefined) {
+                    data.kea_two_phase_commit = false
+                }
 
                 this.settingsForm.patchValue(data)
             },
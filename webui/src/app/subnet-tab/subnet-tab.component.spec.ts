@@ -27,6 +27,7 @@ import { TagModule } from 'primeng/tag'
 import { CheckboxModule } from 'primeng/checkbox'
 import { FormsModule } from '@angular/forms'
 import { PlaceholderPipe } from '../pipes/placeholder.pipe'
+import { MessageModule } from 'primeng/message'
 
 describe('SubnetTabComponent', () => {
     let component: SubnetTabComponent
@@ -41,6 +42,7 @@ describe('SubnetTabComponent', () => {
                 DividerModule,
                 FieldsetModule,
                 FormsModule,
+                MessageModule,
                 NoopAnimationsModule,
                 OverlayPanelModule,
                 RouterTestingModule,
@@ -493,4 +495,38 @@ describe('SubnetTabComponent', () => {
             name: 'bar',
         })
     })
+
+    it('should display utilization alert banners', () => {
+        component.subnet = {
+            subnet: '2001:db8:1::/64',
+            addrUtilization: 85,
+            pdUtilization: 95.5,
+            addrUtilizationAlert: 'warning',
+            pdUtilizationAlert: 'critical',
+            localSubnets: [],
+        }
+        fixture.detectChanges()
+
+        expect(component.utilizationAlerts.length).toBe(2)
+        expect(component.utilizationAlerts[0].severity).toBe('warn')
+        expect(component.utilizationAlerts[1].severity).toBe('error')
+
+        const alerts = fixture.debugElement.query(By.css('#utilization-alerts'))
+        expect(alerts).toBeTruthy()
+        expect(alerts.nativeElement.innerText).toContain('Address utilization is high (85%)')
+        expect(alerts.nativeElement.innerText).toContain('Delegated prefix utilization is critically high (95.5%)')
+    })
+
+    it('should not display utilization alert banners when there are no alerts', () => {
+        component.subnet = {
+            subnet: '192.0.2.0/24',
+            addrUtilization: 30,
+            addrUtilizationAlert: 'none',
+            localSubnets: [],
+        }
+        fixture.detectChanges()
+
+        expect(component.utilizationAlerts.length).toBe(0)
+        expect(fixture.debugElement.query(By.css('#utilization-alerts'))).toBeFalsy()
+    })
 })
@@ -4,6 +4,14 @@ import { hasAddressPools, hasDifferentLocalSubnetOptions, hasPrefixPools } from
 import { hasDifferentLocalSubnetPools } from '../subnets'
 import { NamedCascadedParameters } from '../cascaded-parameters-board/cascaded-parameters-board.component'
 
+/**
+ * A banner describing a utilization alert.
+ */
+export interface UtilizationAlertBanner {
+    severity: 'warn' | 'error'
+    text: string
+}
+
 /**
  * A component displaying a tab for a selected subnet.
  */
@@ -85,6 +93,38 @@ export class SubnetTabComponent implements OnInit {
         return this.subnet.subnet.includes(':')
     }
 
+    /**
+     * Returns the banners describing the utilization alerts raised for the
+     * subnet.
+     *
+     * The alerts are raised by the server when the address or delegated
+     * prefix utilization crosses the configured thresholds.
+     *
+     * @returns a list of the alerts with the severities and texts to display.
+     */
+    get utilizationAlerts(): UtilizationAlertBanner[] {
+        const alerts: UtilizationAlertBanner[] = []
+        if (!this.subnet) {
+            return alerts
+        }
+        const kinds = [
+            { level: this.subnet.addrUtilizationAlert, utilization: this.subnet.addrUtilization, name: 'Address' },
+            { level: this.subnet.pdUtilizationAlert, utilization: this.subnet.pdUtilization, name: 'Delegated prefix' },
+        ]
+        for (const kind of kinds) {
+            if (kind.level !== 'warning' && kind.level !== 'critical') {
+                continue
+            }
+            alerts.push({
+                severity: kind.level === 'critical' ? 'error' : 'warn',
+                text: `${kind.name} utilization is ${kind.level === 'critical' ? 'critically ' : ''}high (${
+                    kind.utilization ?? 0
+                }%). The pools are nearing exhaustion.`,
+            })
+        }
+        return alerts
+    }
+
     /**
      * Returns attributes used in constructing a link to a shared network.
      *
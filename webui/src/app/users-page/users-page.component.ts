@@ -239,12 +239,17 @@ export class UsersPageComponent implements OnInit, OnDestroy {
         })
 
         if (this.groups.length > 0 && this.userTab.user.groups && this.userTab.user.groups.length > 0) {
-            userForm.patchValue({
-                userGroup: {
-                    id: this.groups[this.userTab.user.groups[0] - 1].id,
-                    name: this.groups[this.userTab.user.groups[0] - 1].name,
-                },
-            })
+            // Group IDs are not contiguous when custom groups are deleted,
+            // so the group must be looked up by ID.
+            const group = this.groups.find((g) => g.id === this.userTab.user.groups[0])
+            if (group) {
+                userForm.patchValue({
+                    userGroup: {
+                        id: group.id,
+                        name: group.name,
+                    },
+                })
+            }
         }
 
         this.userTab.userForm = userForm